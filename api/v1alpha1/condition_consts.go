@@ -0,0 +1,196 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// Reasons used on the PublishReady condition.
+const (
+	// PublishQueuedReason (Severity=Info) documents an ImageBuild waiting for a free publish slot
+	// because the controller-wide publish concurrency limit has been reached.
+	PublishQueuedReason = "PublishQueued"
+
+	// PublishingReason (Severity=Info) documents an ImageBuild whose publisher pod is running.
+	PublishingReason = "Publishing"
+
+	// PublishFailedReason (Severity=Error) documents an ImageBuild whose publisher pod failed.
+	PublishFailedReason = "PublishFailed"
+
+	// AMIAlreadyExistsReason (Severity=Error) documents an ImageBuild whose AMI name is already taken
+	// while spec.publish.aws.existingAMIPolicy is "Fail".
+	AMIAlreadyExistsReason = "AMIAlreadyExists"
+)
+
+// Reasons used by the preflight checks that run before the builder pod is created.
+const (
+	// InvalidSpecReason (Severity=Error) documents an ImageBuild whose spec fails validation that
+	// cannot be enforced by the CRD schema.
+	InvalidSpecReason = "InvalidSpec"
+
+	// SecretNotFoundReason (Severity=Error) documents an ImageBuild referencing a Secret that does not exist.
+	SecretNotFoundReason = "SecretNotFound"
+
+	// ConfigMapNotFoundReason (Severity=Error) documents an ImageBuild referencing a ConfigMap that does not exist.
+	ConfigMapNotFoundReason = "ConfigMapNotFound"
+
+	// InvalidConfigMapReason (Severity=Error) documents an ImageBuild referencing a ConfigMap that
+	// lacks a key required for its purpose, e.g. a known hosts ConfigMap without known_hosts.
+	InvalidConfigMapReason = "InvalidConfigMap"
+
+	// InvalidCredentialsSecretReason (Severity=Error) documents an ImageBuild referencing a Secret that
+	// does not satisfy the key contract for its purpose, e.g. an AWS credentials secret without
+	// AWS_SECRET_ACCESS_KEY.
+	InvalidCredentialsSecretReason = "InvalidCredentialsSecret"
+
+	// HostNetworkNotAllowedReason (Severity=Error) documents an ImageBuild requesting
+	// spec.build.hostNetwork while the controller does not allow host networking.
+	HostNetworkNotAllowedReason = "HostNetworkNotAllowed"
+
+	// PrivilegedNotAllowedReason (Severity=Error) documents an ImageBuild in a namespace whose builds may
+	// not run privileged, while the controller forbids privileged builder pods outside an allowlist.
+	PrivilegedNotAllowedReason = "PrivilegedNotAllowed"
+
+	// RepositoryCreationNotAllowedReason (Severity=Error) documents an ImageBuild requesting
+	// spec.output.registry.createRepository while the controller does not allow repository creation.
+	RepositoryCreationNotAllowedReason = "RepositoryCreationNotAllowed"
+
+	// BaseImageBuildPendingReason (Severity=Info) documents an ImageBuild waiting for the ImageBuild
+	// referenced by spec.baseImageFrom to be created or to succeed.
+	BaseImageBuildPendingReason = "BaseImageBuildPending"
+
+	// BaseImageBuildFailedReason (Severity=Error) documents an ImageBuild whose spec.baseImageFrom
+	// references a failed ImageBuild. It keeps waiting in case the base build is recreated.
+	BaseImageBuildFailedReason = "BaseImageBuildFailed"
+
+	// InvalidBaseImageBuildReason (Severity=Error) documents an ImageBuild whose spec.baseImageFrom
+	// cannot be built on: the referenced ImageBuild pushed no image, or references the build in turn.
+	InvalidBaseImageBuildReason = "InvalidBaseImageBuild"
+
+	// BaseImageArchMismatchReason (Severity=Error) documents an ImageBuild whose base image is not
+	// available for the architecture requested by spec.arch.
+	BaseImageArchMismatchReason = "BaseImageArchMismatch"
+)
+
+// Reasons used on the BuilderPodReady condition.
+const (
+	// BuilderPodStartingReason (Severity=Info) documents an ImageBuild whose builder pod has been
+	// created but whose builder container is not running yet, e.g. while it is scheduled or pulling images.
+	BuilderPodStartingReason = "BuilderPodStarting"
+
+	// BuildNamespacePendingReason (Severity=Info) documents an ImageBuild whose builder pod is not
+	// created yet because its build namespace is still being set up by the cluster, i.e. its
+	// ResourceQuota was not observed or its default ServiceAccount not created yet.
+	BuildNamespacePendingReason = "BuildNamespacePending"
+
+	// BuildNamespacesNotSupportedReason (Severity=Error) documents an ImageBuild mounting a
+	// PersistentVolumeClaim, which its builder pod cannot do from a build namespace.
+	BuildNamespacesNotSupportedReason = "BuildNamespacesNotSupported"
+
+	// NoNodesForArchReason (Severity=Warning) documents an ImageBuild whose builder pod is not created
+	// yet because no schedulable node in the cluster has the architecture requested by spec.arch.
+	NoNodesForArchReason = "NoNodesForArch"
+
+	// BuildFailedReason (Severity=Error) documents an ImageBuild whose builder pod failed.
+	BuildFailedReason = "BuildFailed"
+
+	// OOMKilledReason (Severity=Error) documents an ImageBuild whose builder container was killed
+	// for exceeding its memory limit.
+	OOMKilledReason = "OOMKilled"
+
+	// BuildTimedOutReason (Severity=Error) documents an ImageBuild whose builder pod was killed by
+	// the kubelet after running longer than spec.timeoutSeconds.
+	BuildTimedOutReason = "BuildTimedOut"
+
+	// InsufficientDiskSpaceReason (Severity=Error) documents an ImageBuild whose builder pod found less
+	// free space in its container storage than spec.build.minFreeDiskSpace before starting the build.
+	InsufficientDiskSpaceReason = "InsufficientDiskSpace"
+
+	// BuilderImagePullFailedReason (Severity=Error) documents an ImageBuild whose builder pod has failed
+	// to pull the builder image for longer than a grace period, e.g. ImagePullBackOff for a missing
+	// image. The kubelet keeps retrying the pull.
+	BuilderImagePullFailedReason = "BuilderImagePullFailed"
+
+	// PodCreationFailedReason documents an ImageBuild whose builder pod could not be constructed or
+	// created. It has Severity=Warning while the creation is retried with backoff, and Severity=Error
+	// once the build has failed after too many attempts; it is then retried when the spec changes.
+	PodCreationFailedReason = "PodCreationFailed"
+
+	// NamespaceTerminatingReason (Severity=Warning) documents an ImageBuild whose namespace is being
+	// deleted, so no builder or publisher pod can be created in it. Also used on PublishReady.
+	NamespaceTerminatingReason = "NamespaceTerminating"
+
+	// PodEvictedReason documents an ImageBuild whose builder pod was evicted, e.g. under node
+	// pressure or by a node drain. It has Severity=Warning while the pod is recreated, and Severity=Error once
+	// spec.retryLimit evictions have been retried and the build has failed.
+	PodEvictedReason = "PodEvicted"
+)
+
+// Reasons used on the OutputReady condition.
+const (
+	// OutputPVCInUseReason (Severity=Info) documents an ImageBuild waiting for its output PVC, which does
+	// not allow ReadWriteMany, to be released by another pod before the builder pod is created.
+	OutputPVCInUseReason = "OutputPVCInUse"
+
+	// OutputPVCRecreatedReason (Severity=Warning) documents an ImageBuild whose builder pod failed on a
+	// full or unwritable output PVC and that is retried on a fresh one, as requested by
+	// spec.output.pvc.recreateOnFailure.
+	OutputPVCRecreatedReason = "OutputPVCRecreated"
+)
+
+// Reasons used on the BaseImageReady and OutputReady conditions.
+const (
+	// RegistryRateLimitedReason (Severity=Warning) documents an ImageBuild whose builder pod failed
+	// because a registry rejected the base image pull (BaseImageReady) or the registry output push
+	// (OutputReady) with 429 Too Many Requests. The build is retried with exponential backoff, and
+	// fails with Severity=Error once the retries are exhausted.
+	RegistryRateLimitedReason = "RegistryRateLimited"
+)
+
+// Reasons used on the conditions builder stages map to.
+const (
+	// BuildStageFailedReason documents a stage reported as failed by the builder, on the condition the
+	// stage maps to, e.g. ProvisionerReady for "provision". It has Severity=Error if the builder pod
+	// failed, and Severity=Warning if the builder still succeeded, as the failure was not fatal.
+	BuildStageFailedReason = "BuildStageFailed"
+
+	// SourceCloneFailedReason (Severity=Error) documents a builder that failed to clone a git repository,
+	// e.g. for a missing branch, rejected credentials or an unreachable server. It is set on
+	// ProvisionerReady, or on VerifyReady for the repository of spec.verify.
+	SourceCloneFailedReason = "SourceCloneFailed"
+
+	// OutputDestinationFailedReason (Severity=Error) documents a builder that failed to push or upload
+	// to one or more output destinations, listed on the OutputReady condition.
+	OutputDestinationFailedReason = "OutputDestinationFailed"
+)
+
+// Reasons used on the VerifyReady condition.
+const (
+	// VerificationFailedReason (Severity=Error) documents an ImageBuild whose spec.verify step failed.
+	VerificationFailedReason = "VerificationFailed"
+)
+
+// Reasons used on the NotificationDelivered condition.
+const (
+	// NotificationFailedReason (Severity=Warning) documents a failed attempt to deliver the
+	// terminal-state notification. Delivery is retried up to a fixed number of attempts.
+	NotificationFailedReason = "NotificationFailed"
+)
+
+// Reasons used on the SpecBuilt condition.
+const (
+	// SpecChangeIgnoredReason (Severity=Warning) documents a change to the spec of a finished build.
+	// Finished builds are not built again; the ImageBuild has to be re-created to build the new spec.
+	SpecChangeIgnoredReason = "SpecChangeIgnored"
+)
@@ -17,23 +17,81 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
 const ImageBuildFinalizer = "bib.cluster.x-k8s.io/imagebuild"
 
+const (
+	// ImageBuildNameLabel is set on every pod created for an ImageBuild and holds its name.
+	ImageBuildNameLabel = "bib.cluster.x-k8s.io/imagebuild"
+	// ImageBuildNamespaceLabel is set on the build namespace of an ImageBuild, and on the objects the
+	// operator creates in it, and holds the namespace of the ImageBuild.
+	ImageBuildNamespaceLabel = "bib.cluster.x-k8s.io/imagebuild-namespace"
+	// ComponentLabel identifies which stage of the pipeline a pod belongs to.
+	ComponentLabel = "bib.cluster.x-k8s.io/component"
+
+	// ComponentBuilder is the ComponentLabel value for builder pods.
+	ComponentBuilder = "builder"
+	// ComponentPublisher is the ComponentLabel value for publisher pods.
+	ComponentPublisher = "publisher"
+	// ComponentOutput is the ComponentLabel value for output PVCs created by the operator.
+	ComponentOutput = "output"
+
+	// HasArtifactsLabel is set to "true" on ImageBuilds that succeeded and produced artifacts, so the
+	// artifact catalog of a cluster can be listed with a label selector.
+	HasArtifactsLabel = "bib.cluster.x-k8s.io/has-artifacts"
+	// ArtifactsAnnotation lists the artifacts of an ImageBuild labeled with HasArtifactsLabel as a
+	// JSON array of {"format", "name", "url", "checksum"} objects, so the catalog can be read from
+	// the object metadata alone.
+	ArtifactsAnnotation = "bib.cluster.x-k8s.io/artifacts"
+)
+
 // --- Provisioner Definitions ---
 
+// Verbosity defines how much the builder and the provisioners log.
+// +kubebuilder:validation:Enum=Normal;Verbose;Debug
+type Verbosity string
+
+const (
+	// VerbosityNormal keeps the default output of the builder and the provisioners.
+	VerbosityNormal Verbosity = "Normal"
+	// VerbosityVerbose shows more detail, e.g. the result of every Ansible task (ansible-playbook -v).
+	VerbosityVerbose Verbosity = "Verbose"
+	// VerbosityDebug shows everything that helps debugging a build, e.g. the connection details of
+	// Ansible (ansible-playbook -vvv) and the debug log of buildah.
+	VerbosityDebug Verbosity = "Debug"
+)
+
+// AnsibleMode defines how Ansible reaches the image being built.
+// +kubebuilder:validation:Enum=Chroot;Container
+type AnsibleMode string
+
+const (
+	// AnsibleModeChroot runs the playbook from the builder against the mounted root filesystem of the
+	// image, using the chroot connection. Nothing from the image runs, so it works offline and with
+	// minimal images, but services cannot be started.
+	AnsibleModeChroot AnsibleMode = "Chroot"
+	// AnsibleModeContainer runs the playbook inside a container started from the image, using the
+	// local connection. The image must ship ansible and python, and the playbook can start and talk
+	// to processes it launches itself.
+	AnsibleModeContainer AnsibleMode = "Container"
+)
+
 // AnsibleSpec defines the parameters for Ansible-based provisioning.
+// +kubebuilder:validation:XValidation:rule="!(has(self.pythonInterpreter) && has(self.virtualenv))",message="pythonInterpreter and virtualenv are mutually exclusive"
 type AnsibleSpec struct {
 	// Repo is the URL of a Git repository containing Ansible playbooks.
 	// +kubebuilder:validation:Required
 	Repo string `json:"repo"`
 
 	// CredentialsSecretName is the name of a Secret used for pulling the Git repository.
-	// The secret must be of type 'kubernetes.io/ssh-auth' or 'kubernetes.io/basic-auth'.
+	// The secret must be of type 'kubernetes.io/ssh-auth' or 'kubernetes.io/basic-auth', or an
+	// 'Opaque' secret holding an HTTPS access token under the "token" key.
 	// +optional
 	CredentialsSecretName string `json:"credentialsSecretName,omitempty"`
 
@@ -42,15 +100,88 @@ type AnsibleSpec struct {
 	// +optional
 	Branch string `json:"branch,omitempty"`
 
+	// Submodules checks out the submodules of the repository recursively after cloning it.
+	// +optional
+	Submodules bool `json:"submodules,omitempty"`
+
+	// SubmoduleCredentials are the Secrets used for pulling submodules, matched by their remote URL.
+	// Submodules matching none of them are pulled with CredentialsSecretName. Requires Submodules.
+	// +listType=map
+	// +listMapKey=url
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	SubmoduleCredentials []GitSubmoduleCredentials `json:"submoduleCredentials,omitempty"`
+
 	// Playbook is the path to the main playbook file within the repo.
 	// +kubebuilder:validation:Required
 	Playbook string `json:"playbook"`
 
+	// Mode selects how the playbook reaches the image: "Chroot" runs it against the mounted root
+	// filesystem, "Container" runs it inside a container started from the image. Defaults to "Chroot".
+	// +kubebuilder:default:="Chroot"
+	// +optional
+	Mode AnsibleMode `json:"mode,omitempty"`
+
 	// ExtraVars is a raw JSON object of key-value pairs to be passed as extra variables to the playbook.
 	// Corresponds to the --extra-vars or -e flag.
 	// +kubebuilder:pruning:PreserveUnknownFields
 	// +optional
 	ExtraVars *apiextensionsv1.JSON `json:"extraVars,omitempty"`
+
+	// PythonInterpreter is the Python interpreter of the image the Ansible modules run with, passed
+	// as the ansible_python_interpreter variable, e.g. "/usr/bin/python3.12". It must be an absolute
+	// path or one of Ansible's discovery modes. Defaults to Ansible's interpreter discovery ("auto"),
+	// which fails on images whose Python is not in a well-known location.
+	// +optional
+	PythonInterpreter string `json:"pythonInterpreter,omitempty"`
+
+	// Virtualenv creates a Python virtual environment in the image before the playbook runs, and runs
+	// the Ansible modules with its interpreter. The virtual environment remains in the image.
+	// +optional
+	Virtualenv *AnsibleVirtualenv `json:"virtualenv,omitempty"`
+
+	// Connection overrides the connection plugin the playbook runs with, which defaults to "chroot" in
+	// Chroot mode and "local" in Container mode, e.g. "ssh" for playbooks targeting other hosts.
+	// +optional
+	Connection string `json:"connection,omitempty"`
+
+	// Become runs the playbook with privilege escalation, as with --become.
+	// +optional
+	Become bool `json:"become,omitempty"`
+}
+
+// AnsibleVirtualenv defines a Python virtual environment provisioned in the image for Ansible.
+type AnsibleVirtualenv struct {
+	// Path is the absolute path of the virtual environment in the image, e.g. "/opt/ansible".
+	// +kubebuilder:validation:Required
+	Path string `json:"path"`
+
+	// Python is the interpreter of the image the virtual environment is created with. Defaults to "python3".
+	// +kubebuilder:default:="python3"
+	// +optional
+	Python string `json:"python,omitempty"`
+
+	// Packages are pip requirement specifiers installed into the virtual environment, e.g. Python
+	// libraries required by the modules the playbook uses.
+	// +optional
+	Packages []string `json:"packages,omitempty"`
+}
+
+// GitSubmoduleCredentials selects the Secret used for pulling the submodules with a matching remote URL.
+type GitSubmoduleCredentials struct {
+	// URL is the remote URL of the submodules, as resolved against the URL of the superproject, e.g.
+	// "git@github.com:example/roles.git". Shell patterns like "git@github.com:example/*" match several.
+	// The first matching entry is used.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	URL string `json:"url"`
+
+	// SecretName is the name of a Secret used for pulling the submodules, e.g. holding a deploy key
+	// of their repository. The secret must be of type 'kubernetes.io/ssh-auth' or
+	// 'kubernetes.io/basic-auth', or an 'Opaque' secret holding an HTTPS access token under the "token" key.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
 }
 
 // [Future Support] PackerSpec defines the parameters for Packer-based provisioning.
@@ -60,7 +191,8 @@ type PackerSpec struct {
 	Repo string `json:"repo"`
 
 	// CredentialsSecretName is the name of a Secret used for pulling the Git repository.
-	// The secret must be of type 'kubernetes.io/ssh-auth' or 'kubernetes.io/basic-auth'.
+	// The secret must be of type 'kubernetes.io/ssh-auth' or 'kubernetes.io/basic-auth', or an
+	// 'Opaque' secret holding an HTTPS access token under the "token" key.
 	// +optional
 	CredentialsSecretName string `json:"credentialsSecretName,omitempty"`
 
@@ -73,19 +205,122 @@ type PackerSpec struct {
 	TemplatePath string `json:"templatePath"`
 }
 
-// +kubebuilder:validation:XValidation:rule="(has(self.ansible) ? 1 : 0) + (has(self.packer) ? 1 : 0) <= 1",message="at most one of ansible or packer can be specified"
+// DockerfileSpec defines the parameters for provisioning with a Containerfile, built on top of
+// the base image: its FROM instruction is replaced by spec.baseImage.
+// +kubebuilder:validation:XValidation:rule="(has(self.repo) ? 1 : 0) + (has(self.configMapRef) ? 1 : 0) == 1",message="exactly one of repo or configMapRef must be specified"
+// +kubebuilder:validation:XValidation:rule="has(self.repo) || (!has(self.branch) && !has(self.credentialsSecretName))",message="branch and credentialsSecretName require repo"
+type DockerfileSpec struct {
+	// Repo is the URL of a Git repository containing the Containerfile and its build context.
+	// +optional
+	Repo string `json:"repo,omitempty"`
+
+	// CredentialsSecretName is the name of a Secret used for pulling the Git repository, like
+	// spec.provisioner.ansible.credentialsSecretName.
+	// +optional
+	CredentialsSecretName string `json:"credentialsSecretName,omitempty"`
+
+	// Branch is the Git branch to check out. Defaults to "main".
+	// +optional
+	Branch string `json:"branch,omitempty"`
+
+	// ConfigMapRef references a ConfigMap in the same namespace holding the Containerfile, e.g. kept
+	// in Git alongside the ImageBuild. Its keys are the files of the build context.
+	// +optional
+	ConfigMapRef *corev1.LocalObjectReference `json:"configMapRef,omitempty"`
+
+	// Path is the path of the Containerfile within the repo, or its key in the ConfigMap.
+	// Defaults to "Containerfile".
+	// +kubebuilder:default:="Containerfile"
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// KickstartSpec defines the parameters for provisioning a RHEL-family image with a kickstart file.
+// Its %packages section, services, timezone and lang commands and %post scripts are applied to the
+// base image; the commands setting up the installer, e.g. partitioning or the bootloader, and the
+// %pre scripts do not apply to an image built from a container and are ignored.
+// +kubebuilder:validation:XValidation:rule="(has(self.inline) ? 1 : 0) + (has(self.repo) ? 1 : 0) + (has(self.configMapRef) ? 1 : 0) == 1",message="exactly one of inline, repo or configMapRef must be specified"
+// +kubebuilder:validation:XValidation:rule="has(self.repo) || (!has(self.branch) && !has(self.credentialsSecretName))",message="branch and credentialsSecretName require repo"
+type KickstartSpec struct {
+	// Inline is the content of the kickstart file. It cannot %include other files.
+	// +optional
+	Inline string `json:"inline,omitempty"`
+
+	// Repo is the URL of a Git repository containing the kickstart file and the files it includes.
+	// +optional
+	Repo string `json:"repo,omitempty"`
+
+	// CredentialsSecretName is the name of a Secret used for pulling the Git repository, like
+	// spec.provisioner.ansible.credentialsSecretName.
+	// +optional
+	CredentialsSecretName string `json:"credentialsSecretName,omitempty"`
+
+	// Branch is the Git branch to check out. Defaults to "main".
+	// +optional
+	Branch string `json:"branch,omitempty"`
+
+	// ConfigMapRef references a ConfigMap in the same namespace holding the kickstart file. Its other
+	// keys are the files it can %include.
+	// +optional
+	ConfigMapRef *corev1.LocalObjectReference `json:"configMapRef,omitempty"`
+
+	// Path is the path of the kickstart file within the repo, or its key in the ConfigMap. Ignored
+	// with inline. Defaults to "ks.cfg".
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// BootcSpec defines the parameters for provisioning a bootable container (bootc) image. The image is
+// spec.baseImage, a bootc base image such as quay.io/fedora/fedora-bootc:41, optionally extended by a
+// Containerfile. Its qcow2 disk images are installed with `bootc install to-disk` rather than by
+// copying the rootfs, so they boot into the image and follow TargetImage for updates.
+type BootcSpec struct {
+	// Containerfile is built on top of spec.baseImage, like spec.provisioner.dockerfile. Without it the
+	// base image is used as is.
+	// +optional
+	Containerfile *DockerfileSpec `json:"containerfile,omitempty"`
+
+	// TargetImage is the image reference machines installed from the disk image track for
+	// `bootc upgrade`, e.g. the registry output destination. Defaults to the registry output
+	// destination if there is one, and to spec.baseImage otherwise.
+	// +optional
+	TargetImage string `json:"targetImage,omitempty"`
+
+	// KernelArgs are kernel arguments added to the image, written to /usr/lib/bootc/kargs.d, e.g.
+	// "console=ttyS0,115200n8".
+	// +optional
+	KernelArgs []string `json:"kernelArgs,omitempty"`
+
+	// Lint runs `bootc container lint` on the provisioned image, failing the build if the image is
+	// not a valid bootc image.
+	// +optional
+	Lint bool `json:"lint,omitempty"`
+}
+
+// +kubebuilder:validation:XValidation:rule="(has(self.ansible) ? 1 : 0) + (has(self.packer) ? 1 : 0) + (has(self.dockerfile) ? 1 : 0) + (has(self.kickstart) ? 1 : 0) + (has(self.bootc) ? 1 : 0) <= 1",message="at most one of ansible, packer, dockerfile, kickstart or bootc can be specified"
 // ProvisionerSpec defines the provisioning method and its parameters.
 type ProvisionerSpec struct {
 	// +optional
 	Ansible *AnsibleSpec `json:"ansible,omitempty"`
 	// +optional
 	Packer *PackerSpec `json:"packer,omitempty"`
+	// +optional
+	Dockerfile *DockerfileSpec `json:"dockerfile,omitempty"`
+	// +optional
+	Kickstart *KickstartSpec `json:"kickstart,omitempty"`
+	// +optional
+	Bootc *BootcSpec `json:"bootc,omitempty"`
+
+	// PostSteps are shell commands run in the image after the provisioner, one after the other,
+	// e.g. to truncate logs or clear /etc/machine-id before the image is exported. They run with
+	// /bin/sh of the image, and a failing command fails the build.
+	// +optional
+	PostSteps []string `json:"postSteps,omitempty"`
 }
 
 // --- Output Definitions ---
 
 // OutputFormat defines the supported artifact formats.
-// +kubebuilder:validation:Enum=tgz;qcow2
 type OutputFormat string
 
 const (
@@ -93,23 +328,174 @@ const (
 	FormatTGZ OutputFormat = "tgz"
 	// FormatQCOW2 specifies a QEMU Copy-On-Write v2 disk image.
 	FormatQCOW2 OutputFormat = "qcow2"
+	// FormatOCI is a container image pushed by the registry output. It is only reported in
+	// status.artifacts, never requested in spec.output.formats.
+	FormatOCI OutputFormat = "oci"
+)
+
+// Filesystem defines the root filesystem types of disk image artifacts.
+// +kubebuilder:validation:Enum=ext4;xfs;btrfs
+type Filesystem string
+
+const (
+	// FilesystemExt4 specifies an ext4 root filesystem.
+	FilesystemExt4 Filesystem = "ext4"
+	// FilesystemXFS specifies an XFS root filesystem.
+	FilesystemXFS Filesystem = "xfs"
+	// FilesystemBtrfs specifies a Btrfs root filesystem.
+	FilesystemBtrfs Filesystem = "btrfs"
+)
+
+// PartitionTable defines the partition table types of disk image artifacts.
+// +kubebuilder:validation:Enum=GPT;MBR
+type PartitionTable string
+
+const (
+	// PartitionTableGPT specifies a GUID partition table, as booted by UEFI firmware.
+	PartitionTableGPT PartitionTable = "GPT"
+	// PartitionTableMBR specifies a DOS (MBR) partition table, as booted by legacy BIOS firmware.
+	PartitionTableMBR PartitionTable = "MBR"
+)
+
+// Partitioning defines the partition layout of disk image artifacts.
+type Partitioning struct {
+	// Table is the partition table of the disk. Defaults to "GPT".
+	// +kubebuilder:default:="GPT"
+	// +optional
+	Table PartitionTable `json:"table,omitempty"`
+
+	// EFISystemPartition adds a 512MiB FAT EFI system partition in front of the root partition,
+	// mounted at /boot/efi while the rootfs is copied. Defaults to true.
+	// +kubebuilder:default:=true
+	// +optional
+	EFISystemPartition *bool `json:"efiSystemPartition,omitempty"`
+}
+
+// PartitionFilesystem defines the filesystems of the partitions of a custom disk layout.
+// +kubebuilder:validation:Enum=ext4;xfs;btrfs;vfat;swap
+type PartitionFilesystem string
+
+const (
+	// PartitionFilesystemExt4 specifies an ext4 filesystem.
+	PartitionFilesystemExt4 PartitionFilesystem = "ext4"
+	// PartitionFilesystemXFS specifies an XFS filesystem.
+	PartitionFilesystemXFS PartitionFilesystem = "xfs"
+	// PartitionFilesystemBtrfs specifies a Btrfs filesystem.
+	PartitionFilesystemBtrfs PartitionFilesystem = "btrfs"
+	// PartitionFilesystemVFAT specifies a FAT filesystem, e.g. for the EFI system partition.
+	PartitionFilesystemVFAT PartitionFilesystem = "vfat"
+	// PartitionFilesystemSwap specifies swap space, which is not mounted.
+	PartitionFilesystemSwap PartitionFilesystem = "swap"
 )
 
+// DiskLayout defines a custom partition layout of disk image artifacts.
+type DiskLayout struct {
+	// Table is the partition table of the disk. Defaults to "GPT". An MBR holds at most 4 partitions.
+	// +kubebuilder:default:="GPT"
+	// +optional
+	Table PartitionTable `json:"table,omitempty"`
+
+	// Size is the virtual size of the disk, e.g. "20Gi". Defaults to the size of the partitions, with
+	// a partition without a size made large enough for the whole rootfs plus 1GiB of free space.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// Partitions are the partitions of the disk, in disk order. Exactly one of them must be mounted
+	// at "/". A partition mounted at /boot/efi on a GPT disk is made the EFI system partition.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=16
+	Partitions []DiskPartition `json:"partitions"`
+}
+
+// DiskPartition defines a partition of a custom disk layout.
+type DiskPartition struct {
+	// Label is the filesystem label of the partition, e.g. "root". Defaults to none, or for vfat,
+	// which is mounted by label, to "ESP" at /boot/efi and "PART<number>" elsewhere.
+	// +kubebuilder:validation:MaxLength=11
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_-]*$`
+	// +optional
+	Label string `json:"label,omitempty"`
+
+	// Size is the size of the partition, e.g. "512Mi", rounded up to a whole MiB. Only the last
+	// partition may leave it unset, to take the rest of the disk.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// Filesystem is the filesystem the partition is formatted with.
+	// +kubebuilder:validation:Required
+	Filesystem PartitionFilesystem `json:"filesystem"`
+
+	// MountPoint is the absolute path the partition is mounted at while the rootfs is copied, e.g.
+	// "/boot". The files of the rootfs below it are written to the partition. Required unless the
+	// filesystem is swap, which is not mounted.
+	// +optional
+	MountPoint string `json:"mountPoint,omitempty"`
+}
+
 // PVCOutput defines a PersistentVolumeClaim as the output destination.
+// +kubebuilder:validation:XValidation:rule="!has(self.recreateOnFailure) || !self.recreateOnFailure || (has(self.createIfMissing) && self.createIfMissing)",message="recreateOnFailure requires createIfMissing"
 type PVCOutput struct {
 	// Name of the PersistentVolumeClaim in the same namespace.
+	// Builds sharing a claim that does not allow ReadWriteMany run one at a time.
 	// +kubebuilder:validation:Required
 	Name string `json:"name"`
 
 	// SubPath is an optional path within the PVC to store artifacts.
 	// If not specified, the operator will create a default path in the format "<namespace>/<imagebuild-name>".
+	// It must be a relative path and must not contain "..".
 	// +optional
 	SubPath string `json:"subPath,omitempty"`
 
-	// CreateIfMissing, if true, instructs the operator to create the PVC if it does not exist.
+	// CreateIfMissing, if true, instructs the operator to create the PVC if it does not exist. The
+	// claim is ReadWriteOnce, of StorageClassName and Size, and is kept when the ImageBuild is deleted.
 	// +kubebuilder:default:=false
 	// +optional
 	CreateIfMissing bool `json:"createIfMissing,omitempty"`
+
+	// StorageClassName is the storage class of a claim created by CreateIfMissing. The cluster's
+	// default storage class is used if it is not set.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// Size is the requested capacity of a claim created by CreateIfMissing, e.g. "100Gi".
+	// Defaults to 50Gi.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// RecreateOnFailure, if true, retries a build that failed because the claim ran full or could not
+	// be written to on a fresh PVC. The fresh claim, "<imagebuild>-output-<n>", copies the storage
+	// class, access modes and size of the failed one and is owned by the ImageBuild. The operator gives
+	// up after 3 fresh claims and deletes each claim it created once the build on it failed; the claim
+	// named here is kept. Requires CreateIfMissing.
+	// +optional
+	RecreateOnFailure bool `json:"recreateOnFailure,omitempty"`
+
+	// Retain, if set, keeps the artifacts of only the last Retain successful builds of
+	// spec.output.imageName on the claim, e.g. on a claim shared by periodic builds. Once a build has
+	// succeeded, the builder removes the artifacts of older builds with the same imageName anywhere on
+	// the claim, ordered by the creation time in their metadata. Unset keeps every build.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Retain *int32 `json:"retain,omitempty"`
+}
+
+// VolumeOutput defines a pre-provisioned volume, such as an NFS export, as the output destination.
+// Only the volume sources listed here are supported, so an ImageBuild cannot mount e.g. a host path.
+// +kubebuilder:validation:XValidation:rule="(has(self.nfs) ? 1 : 0) + (has(self.csi) ? 1 : 0) == 1",message="exactly one of nfs or csi must be specified"
+type VolumeOutput struct {
+	// NFS mounts an NFS export.
+	// +optional
+	NFS *corev1.NFSVolumeSource `json:"nfs,omitempty"`
+
+	// CSI mounts an inline volume provided by a CSI driver.
+	// +optional
+	CSI *corev1.CSIVolumeSource `json:"csi,omitempty"`
+
+	// SubPath is an optional path within the volume to store artifacts.
+	// If not specified, the operator will use a default path in the format "<namespace>/<imagebuild-name>".
+	// It must be a relative path and must not contain "..".
+	// +optional
+	SubPath string `json:"subPath,omitempty"`
 }
 
 // ObjectStorageOutput defines an S3-compatible bucket as the output destination.
@@ -126,8 +512,79 @@ type ObjectStorageOutput struct {
 	// The secret must contain keys `AWS_ACCESS_KEY_ID` and `AWS_SECRET_ACCESS_KEY`.
 	// +kubebuilder:validation:Required
 	CredentialsSecretName string `json:"credentialsSecretName"`
+
+	// ContentType is the Content-Type the objects are uploaded with, e.g. "application/octet-stream".
+	// If not set it is detected from the artifact format: "application/gzip" for tgz archives,
+	// "application/x-qemu-disk" for qcow2 disk images and "text/plain" for build logs. The artifact
+	// metadata document is always uploaded as "application/json".
+	// +optional
+	ContentType string `json:"contentType,omitempty"`
+
+	// StorageClass is the S3 storage class the objects are uploaded with, e.g. "STANDARD_IA" for
+	// rarely accessed artifacts. Defaults to the bucket's default, usually "STANDARD". The artifact
+	// metadata document is always uploaded as "STANDARD" so that it stays readable.
+	// +optional
+	StorageClass S3StorageClass `json:"storageClass,omitempty"`
+
+	// UploadConcurrency is the number of parts of a multipart upload sent at once, e.g. raised for
+	// large disk images on a fast link. Defaults to the AWS CLI's 10.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=64
+	// +optional
+	UploadConcurrency int32 `json:"uploadConcurrency,omitempty"`
+
+	// AWSAssumeRole optionally swaps the credentials for those of a role before the upload.
+	AWSAssumeRole `json:",inline"`
 }
 
+// S3StorageClass defines the S3 storage class objects are uploaded with.
+// +kubebuilder:validation:Enum=STANDARD;REDUCED_REDUNDANCY;STANDARD_IA;ONEZONE_IA;INTELLIGENT_TIERING;GLACIER_IR;GLACIER;DEEP_ARCHIVE
+type S3StorageClass string
+
+const (
+	// S3StorageClassStandard is the default storage class for frequently accessed objects.
+	S3StorageClassStandard S3StorageClass = "STANDARD"
+	// S3StorageClassReducedRedundancy is a legacy class for noncritical, reproducible objects.
+	S3StorageClassReducedRedundancy S3StorageClass = "REDUCED_REDUNDANCY"
+	// S3StorageClassStandardIA is cheaper storage for rarely accessed objects, with a retrieval fee.
+	S3StorageClassStandardIA S3StorageClass = "STANDARD_IA"
+	// S3StorageClassOneZoneIA is like STANDARD_IA, stored in a single availability zone.
+	S3StorageClassOneZoneIA S3StorageClass = "ONEZONE_IA"
+	// S3StorageClassIntelligentTiering moves objects between tiers by their access pattern.
+	S3StorageClassIntelligentTiering S3StorageClass = "INTELLIGENT_TIERING"
+	// S3StorageClassGlacierIR is archival storage with millisecond retrieval.
+	S3StorageClassGlacierIR S3StorageClass = "GLACIER_IR"
+	// S3StorageClassGlacier is archival storage whose objects must be restored before they can be read.
+	S3StorageClassGlacier S3StorageClass = "GLACIER"
+	// S3StorageClassDeepArchive is the cheapest archival storage, restored within hours.
+	S3StorageClassDeepArchive S3StorageClass = "DEEP_ARCHIVE"
+)
+
+// AWSAssumeRole defines an IAM role assumed with the static credentials before accessing AWS,
+// e.g. to upload to a bucket owned by another account.
+type AWSAssumeRole struct {
+	// RoleARN is the ARN of the IAM role to assume, e.g. "arn:aws:iam::111122223333:role/image-uploader".
+	// +kubebuilder:validation:Pattern=`^arn:aws[a-z-]*:iam::[0-9]{12}:role/[A-Za-z0-9+=,.@_/-]+$`
+	// +optional
+	RoleARN string `json:"roleARN,omitempty"`
+
+	// ExternalID is passed when assuming RoleARN, if the role's trust policy requires one.
+	// It can only be set together with RoleARN.
+	// +optional
+	ExternalID string `json:"externalID,omitempty"`
+}
+
+// CompressionFormat defines the layer compression used when pushing an image to a registry.
+// +kubebuilder:validation:Enum=gzip;zstd
+type CompressionFormat string
+
+const (
+	// CompressionGzip compresses layers with gzip, which every registry and runtime supports.
+	CompressionGzip CompressionFormat = "gzip"
+	// CompressionZstd compresses layers with zstd, which is faster to decompress but needs a recent runtime.
+	CompressionZstd CompressionFormat = "zstd"
+)
+
 // RegistryOutput defines a container image registry as the output destination.
 type RegistryOutput struct {
 	// Destination is the full destination path for the container image (e.g., "quay.io/my-org/my-image:latest").
@@ -137,10 +594,99 @@ type RegistryOutput struct {
 	// PullSecretName is the name of a 'kubernetes.io/dockerconfigjson' secret for registry authentication.
 	// +kubebuilder:validation:Required
 	PullSecretName string `json:"pullSecretName"`
+
+	// CompressionFormat is the layer compression used for the push. Defaults to "gzip".
+	// +kubebuilder:default:="gzip"
+	// +optional
+	CompressionFormat CompressionFormat `json:"compressionFormat,omitempty"`
+
+	// CreateRepository, if true, creates the repository of Destination before the push if it does
+	// not exist yet. Supported for Amazon ECR and Google Artifact Registry destinations, which are
+	// detected from the registry host. Requires the controller to run with --allow-repository-creation.
+	// +optional
+	CreateRepository bool `json:"createRepository,omitempty"`
+
+	// RepositoryCredentialsSecretName is the name of a Secret with the AWS_ACCESS_KEY_ID and
+	// AWS_SECRET_ACCESS_KEY used to create an ECR repository, as the short-lived ECR login in
+	// PullSecretName cannot create repositories. Required with CreateRepository for ECR.
+	// Artifact Registry repositories are created with the credentials of PullSecretName, which must
+	// be a service account key ("_json_key") or an access token ("oauth2accesstoken").
+	// +optional
+	RepositoryCredentialsSecretName string `json:"repositoryCredentialsSecretName,omitempty"`
+
+	// AdditionalDestinations are further image references the same image is pushed to after
+	// Destination, e.g. a mirror in another registry. Repositories are only created for Destination.
+	// +kubebuilder:validation:MaxItems=10
+	// +listType=map
+	// +listMapKey=destination
+	// +optional
+	AdditionalDestinations []RegistryDestination `json:"additionalDestinations,omitempty"`
+
+	// Config overrides fields of the OCI image config of the pushed image, which otherwise keeps
+	// the config of the base image, e.g. its entrypoint and labels.
+	// +optional
+	Config *ImageConfig `json:"config,omitempty"`
+}
+
+// ImageConfig defines fields of the OCI image config of a pushed container image. Fields that are
+// not set keep the value of the base image.
+type ImageConfig struct {
+	// Entrypoint replaces the entrypoint of the image, in exec form.
+	// +optional
+	Entrypoint []string `json:"entrypoint,omitempty"`
+
+	// Cmd replaces the default arguments of the entrypoint, in exec form.
+	// +optional
+	Cmd []string `json:"cmd,omitempty"`
+
+	// Env sets environment variables of the image, replacing variables of the same name of the
+	// base image and keeping the others.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Env []ImageConfigEnvVar `json:"env,omitempty"`
+
+	// ExposedPorts are ports exposed by the image in addition to those of the base image,
+	// as "<port>" or "<port>/<protocol>", e.g. "8080/tcp". The protocol defaults to tcp.
+	// +kubebuilder:validation:items:Pattern=`^[0-9]+(/(tcp|udp|sctp))?$`
+	// +listType=set
+	// +optional
+	ExposedPorts []string `json:"exposedPorts,omitempty"`
+
+	// Labels are set on the image, replacing labels of the same name of the base image.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// ImageConfigEnvVar is an environment variable of an OCI image config.
+type ImageConfigEnvVar struct {
+	// Name is the name of the variable.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Value is the value of the variable.
+	// +optional
+	Value string `json:"value,omitempty"`
+}
+
+// RegistryDestination is an additional image reference a registry output is pushed to.
+type RegistryDestination struct {
+	// Destination is the full destination path for the container image (e.g., "ghcr.io/my-org/my-image:latest").
+	// +kubebuilder:validation:Required
+	Destination string `json:"destination"`
+
+	// PullSecretName is the name of a 'kubernetes.io/dockerconfigjson' secret for the authentication
+	// to this destination's registry. Defaults to the PullSecretName of the registry output.
+	// +optional
+	PullSecretName string `json:"pullSecretName,omitempty"`
 }
 
-// +kubebuilder:validation:XValidation:rule="(has(self.pvc) ? 1 : 0) + (has(self.objectStorage) ? 1 : 0) + (has(self.registry) ? 1 : 0) == 1",message="exactly one of pvc, objectStorage, or registry must be specified"
-// OutputSpec defines the destination for the built artifacts.
+// +kubebuilder:validation:XValidation:rule="has(self.pvc) || has(self.volume) || has(self.objectStorage) || has(self.registry)",message="at least one of pvc, volume, objectStorage, or registry must be specified"
+// +kubebuilder:validation:XValidation:rule="!(has(self.pvc) && has(self.volume))",message="pvc and volume cannot be combined, as both are mounted at /output"
+// +kubebuilder:validation:XValidation:rule="has(self.pvc) || has(self.volume) || has(self.objectStorage) || (!has(self.filesystem) && !has(self.partitioning) && !has(self.diskLayout) && ((!has(self.formats) && !has(self.qcow2)) || (oldSelf.hasValue() && oldSelf.value().?formats == self.?formats && oldSelf.value().?qcow2 == self.?qcow2)))",message="formats, qcow2, filesystem, partitioning and diskLayout require a pvc, volume or objectStorage output; a registry output pushes the container image itself",optionalOldSelf=true
+// OutputSpec defines the destinations for the built artifacts. Several destinations can be
+// combined, e.g. a PVC copy for local consumption and an object storage copy for archival.
 type OutputSpec struct {
 	// ImageName is a base name for the output files (e.g., "ubuntu-2204-kube-1.29").
 	// Not used for the Registry output type, as the name is part of the destination.
@@ -150,20 +696,136 @@ type OutputSpec struct {
 	// +optional
 	PVC *PVCOutput `json:"pvc,omitempty"`
 	// +optional
+	Volume *VolumeOutput `json:"volume,omitempty"`
+	// +optional
 	ObjectStorage *ObjectStorageOutput `json:"objectStorage,omitempty"`
 	// +optional
 	Registry *RegistryOutput `json:"registry,omitempty"`
 
 	// Formats is the list of artifact formats to produce.
 	// Supported values are "tgz" (for a .tar.gz rootfs archive) and "qcow2".
-	// Defaults to ["tgz", "qcow2"] if not specified.
-	// +kubebuilder:default:={"tgz", "qcow2"}
+	// Defaults to ["tgz", "qcow2"] if not specified and a pvc, volume or objectStorage output is set.
+	// A registry-only output produces no file artifacts, so Formats must not be set then.
 	// +optional
+	// +kubebuilder:validation:items:Enum=tgz;qcow2
 	Formats []OutputFormat `json:"formats,omitempty"`
+
+	// QCOW2 defines options for the "qcow2" format.
+	// +optional
+	QCOW2 *QCOW2Options `json:"qcow2,omitempty"`
+
+	// Filesystem is the root filesystem of the disk image artifacts, e.g. "xfs" for cloud images
+	// whose tooling expects it. Supported values are "ext4", "xfs" and "btrfs". Defaults to "ext4".
+	// +optional
+	Filesystem Filesystem `json:"filesystem,omitempty"`
+
+	// Partitioning is the partition layout of the disk image artifacts. Defaults to a GPT with an
+	// EFI system partition, which UEFI firmware boots, e.g. AWS instances with the "uefi" boot mode.
+	// Targets booting with legacy BIOS, such as older MAAS machines, need an MBR.
+	// +optional
+	Partitioning *Partitioning `json:"partitioning,omitempty"`
+
+	// DiskLayout replaces the root partition of the disk image artifacts with custom partitions,
+	// e.g. separate /boot and /var partitions. It cannot be combined with Filesystem or Partitioning.
+	// +optional
+	DiskLayout *DiskLayout `json:"diskLayout,omitempty"`
+
+	// ParallelUploads pushes to the registry destinations concurrently, and uploads the artifacts to
+	// object storage concurrently, rather than one after the other, which shortens builds with several
+	// destinations. The pushes finish before the artifacts are converted, so they do not overlap with
+	// the uploads. The outcome of each destination is reported in status.outputDestinations either way.
+	// +optional
+	ParallelUploads bool `json:"parallelUploads,omitempty"`
+
+	// Sanitize removes state from the image that must not be shared by the machines booted from it.
+	// The cleanup runs after the provisioner and verification, before any artifact is written or
+	// pushed. If unset, all cleanups run for builds writing qcow2 artifacts and none run otherwise,
+	// e.g. for container images or tgz archives only; if set, cleanups not disabled in it run.
+	// +optional
+	Sanitize *OutputSanitize `json:"sanitize,omitempty"`
+}
+
+// OutputSanitize selects the cleanups run on the image before it is packaged.
+type OutputSanitize struct {
+	// ResetMachineID empties /etc/machine-id, so every machine generates its own ID on first boot
+	// instead of sharing the one of the build. Defaults to true.
+	// +kubebuilder:default:=true
+	// +optional
+	ResetMachineID *bool `json:"resetMachineID,omitempty"`
+
+	// CleanCloudInit removes the cloud-init state and logs, so cloud-init runs again on first boot.
+	// Defaults to true.
+	// +kubebuilder:default:=true
+	// +optional
+	CleanCloudInit *bool `json:"cleanCloudInit,omitempty"`
+
+	// TruncateLogs empties the files under /var/log, which would otherwise carry the logs of the
+	// build into every machine. Defaults to true.
+	// +kubebuilder:default:=true
+	// +optional
+	TruncateLogs *bool `json:"truncateLogs,omitempty"`
+}
+
+// QCOW2Preallocation defines how much of a qcow2 artifact is allocated up front, as with the
+// preallocation option of qemu-img.
+// +kubebuilder:validation:Enum=off;metadata;falloc;full
+type QCOW2Preallocation string
+
+const (
+	// QCOW2PreallocationOff allocates the image as it is written, leaving a sparse file.
+	QCOW2PreallocationOff QCOW2Preallocation = "off"
+	// QCOW2PreallocationMetadata allocates the qcow2 metadata only.
+	QCOW2PreallocationMetadata QCOW2Preallocation = "metadata"
+	// QCOW2PreallocationFalloc reserves the space of the whole disk with fallocate, without writing it.
+	QCOW2PreallocationFalloc QCOW2Preallocation = "falloc"
+	// QCOW2PreallocationFull allocates the whole disk by writing zeros to it.
+	QCOW2PreallocationFull QCOW2Preallocation = "full"
+)
+
+// QCOW2Options defines options for qcow2 artifacts.
+// +kubebuilder:validation:XValidation:rule="!has(self.compress) || !self.compress || !has(self.preallocation) || self.preallocation == 'off'",message="compress cannot be used with preallocation"
+type QCOW2Options struct {
+	// Compress produces a compressed qcow2 image. Compressed images are cheaper to store and
+	// transfer, at the cost of slower reads until the compressed clusters are rewritten.
+	// +optional
+	Compress bool `json:"compress,omitempty"`
+
+	// Preallocation allocates the qcow2 image up front: "metadata", "falloc" or "full". Preallocated
+	// images take the space of the whole disk, in exchange for more predictable I/O once deployed.
+	// Defaults to "off", a sparse image.
+	// +optional
+	Preallocation QCOW2Preallocation `json:"preallocation,omitempty"`
 }
 
 // --- Publish Definitions ---
 
+// ExistingAMIPolicy defines how to handle an AMI that already has the requested name.
+// +kubebuilder:validation:Enum=Fail;Skip;Overwrite
+type ExistingAMIPolicy string
+
+const (
+	// ExistingAMIPolicyFail fails the publish if the AMI already exists.
+	ExistingAMIPolicyFail ExistingAMIPolicy = "Fail"
+	// ExistingAMIPolicySkip keeps the existing AMI and marks the publish as done.
+	ExistingAMIPolicySkip ExistingAMIPolicy = "Skip"
+	// ExistingAMIPolicyOverwrite replaces the existing AMI with the newly built one once it is imported.
+	ExistingAMIPolicyOverwrite ExistingAMIPolicy = "Overwrite"
+)
+
+// AWSBootMode defines the boot mode an AMI is registered with.
+// +kubebuilder:validation:Enum=legacy-bios;uefi;uefi-preferred
+type AWSBootMode string
+
+const (
+	// AWSBootModeLegacyBIOS boots instances with legacy BIOS. Not supported by arm64 instances.
+	AWSBootModeLegacyBIOS AWSBootMode = "legacy-bios"
+	// AWSBootModeUEFI boots instances with UEFI.
+	AWSBootModeUEFI AWSBootMode = "uefi"
+	// AWSBootModeUEFIPreferred boots instances with UEFI where the instance type supports it,
+	// and with legacy BIOS otherwise.
+	AWSBootModeUEFIPreferred AWSBootMode = "uefi-preferred"
+)
+
 // AWSPublishSpec defines the parameters for publishing the image as an AMI in AWS.
 type AWSPublishSpec struct {
 	// Region is the AWS region where the AMI will be created.
@@ -181,13 +843,78 @@ type AWSPublishSpec struct {
 
 	// SourceS3Bucket is the name of an S3 bucket the operator can use to temporarily
 	// upload the qcow2 image for the AMI import process.
+	// The staged object is tagged "bib.cluster.x-k8s.io/temporary=true" so a bucket lifecycle rule
+	// can expire leftovers, and is deleted once the import finishes, successfully or not.
 	// +kubebuilder:validation:Required
 	SourceS3Bucket string `json:"sourceS3Bucket"`
 
+	// RetainSourceObject keeps the staged disk image in SourceS3Bucket after the import,
+	// e.g. to debug a failed import.
+	// +optional
+	RetainSourceObject bool `json:"retainSourceObject,omitempty"`
+
 	// CredentialsSecretName is the name of a Secret containing the AWS credentials.
 	// The secret must contain keys `AWS_ACCESS_KEY_ID` and `AWS_SECRET_ACCESS_KEY`.
 	// +kubebuilder:validation:Required
 	CredentialsSecretName string `json:"credentialsSecretName"`
+
+	// AWSAssumeRole optionally swaps the credentials for those of a role before publishing.
+	AWSAssumeRole `json:",inline"`
+
+	// Architecture is the EC2 architecture the AMI is registered with.
+	// It defaults to the AMI architecture matching spec.arch ("amd64" maps to "x86_64", "arm64" to "arm64")
+	// and, if set, must match it.
+	// +kubebuilder:validation:Enum=x86_64;arm64
+	// +optional
+	Architecture string `json:"architecture,omitempty"`
+
+	// ExistingAMIPolicy controls what happens if an AMI named AMIName already exists in the region.
+	// "Fail" fails the publish, "Skip" keeps the existing AMI and skips the import,
+	// and "Overwrite" replaces the existing AMI once the new snapshot is imported, deleting its
+	// snapshots after the new AMI is registered. A failed publish leaves the existing AMI in place.
+	// +kubebuilder:default:=Fail
+	// +optional
+	ExistingAMIPolicy ExistingAMIPolicy `json:"existingAMIPolicy,omitempty"`
+
+	// Tags are applied to the created AMI and its backing snapshot, e.g. for cost allocation.
+	// Keys must be 1-128 characters and must not start with "aws:"; values may be up to 256 characters.
+	// +kubebuilder:validation:MaxProperties=50
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// EnaSupport declares Elastic Network Adapter support on the AMI, which Nitro instance types require.
+	// +kubebuilder:default:=true
+	// +optional
+	EnaSupport *bool `json:"enaSupport,omitempty"`
+
+	// SriovNetSupport declares enhanced networking with the Intel 82599 VF interface on the AMI.
+	// +kubebuilder:default:=true
+	// +optional
+	SriovNetSupport *bool `json:"sriovNetSupport,omitempty"`
+
+	// BootMode is the boot mode the AMI is registered with. arm64 AMIs must use "uefi" or
+	// "uefi-preferred".
+	// +kubebuilder:default:=uefi-preferred
+	// +optional
+	BootMode AWSBootMode `json:"bootMode,omitempty"`
+
+	// LaunchPermissions shares the AMI with other AWS accounts, or makes it public, once it is registered.
+	// +optional
+	LaunchPermissions *AWSLaunchPermissions `json:"launchPermissions,omitempty"`
+}
+
+// AWSLaunchPermissions defines who, besides its owner, may launch instances from an AMI.
+type AWSLaunchPermissions struct {
+	// AccountIDs are the 12-digit IDs of the AWS accounts the AMI is shared with.
+	// +kubebuilder:validation:items:Pattern=`^[0-9]{12}$`
+	// +listType=set
+	// +optional
+	AccountIDs []string `json:"accountIDs,omitempty"`
+
+	// Public makes the AMI launchable by every AWS account. The account's block public access
+	// setting for AMIs must be disabled.
+	// +optional
+	Public bool `json:"public,omitempty"`
 }
 
 // MaaSPublishSpec defines the parameters for publishing the image to a MaaS server.
@@ -215,17 +942,354 @@ type PublishSpec struct {
 	MaaS *MaaSPublishSpec `json:"maas,omitempty"`
 }
 
+// SigningSpec defines how the image pushed by the registry output is signed.
+type SigningSpec struct {
+	// Keyless signs the image with a short-lived certificate issued by Fulcio for the builder
+	// pod's service account token, and records the signature in the Rekor transparency log.
+	// No signing key is needed.
+	// +kubebuilder:validation:Required
+	Keyless *KeylessSigning `json:"keyless"`
+}
+
+// KeylessSigning defines the Sigstore services keyless signing uses. Fulcio must trust the
+// cluster's service account token issuer, which the public instance does not.
+type KeylessSigning struct {
+	// FulcioURL is the URL of the Fulcio certificate authority issuing the signing certificate.
+	// +kubebuilder:default:="https://fulcio.sigstore.dev"
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +optional
+	FulcioURL string `json:"fulcioURL,omitempty"`
+
+	// RekorURL is the URL of the Rekor transparency log the signature is recorded in.
+	// +kubebuilder:default:="https://rekor.sigstore.dev"
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +optional
+	RekorURL string `json:"rekorURL,omitempty"`
+
+	// Audience is the audience of the service account token presented to Fulcio as the OIDC
+	// identity token. It must match the client ID Fulcio expects for the cluster's issuer.
+	// +kubebuilder:default:="sigstore"
+	// +kubebuilder:validation:MinLength=1
+	// +optional
+	Audience string `json:"audience,omitempty"`
+}
+
+// --- Build Definitions ---
+
+// BuildSpec defines settings of the builder pod.
+// +kubebuilder:validation:XValidation:rule="!(has(self.hostNetwork) && self.hostNetwork && has(self.hostUsers) && !self.hostUsers)",message="hostUsers: false cannot be combined with hostNetwork"
+type BuildSpec struct {
+	// LogsOutput is an object storage destination the full build log is uploaded to once the
+	// build finishes, successfully or not. The log is stored as "<namespace>/<name>/build.log".
+	// +optional
+	LogsOutput *ObjectStorageOutput `json:"logsOutput,omitempty"`
+
+	// HostNetwork runs the builder pod in the host's network namespace, e.g. to reach provisioning
+	// endpoints only routable from the nodes. It is rejected unless the controller runs with
+	// --allow-host-network.
+	// +optional
+	HostNetwork bool `json:"hostNetwork,omitempty"`
+
+	// HostUsers runs the builder pod in the host's user namespace. Set it to false on clusters with
+	// user namespace support to confine the privileged builder to a user namespace, where root in the
+	// container is unprivileged on the node. It cannot be combined with HostNetwork.
+	// +kubebuilder:default=true
+	// +optional
+	HostUsers *bool `json:"hostUsers,omitempty"`
+
+	// FSGroup is the supplemental group of the builder pod, set as its securityContext.fsGroup. Volumes
+	// that support ownership management, such as most PVC outputs, are made writable by the group,
+	// and the artifacts written to them are owned by it.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	FSGroup *int64 `json:"fsGroup,omitempty"`
+
+	// SupplementalGroups are further groups of the builder pod, set as its
+	// securityContext.supplementalGroups, e.g. the group an NFS export grants write access to,
+	// which does not support ownership management through FSGroup.
+	// +kubebuilder:validation:items:Minimum=0
+	// +optional
+	SupplementalGroups []int64 `json:"supplementalGroups,omitempty"`
+
+	// ShareProcessNamespace shares a single process namespace between the containers of the builder
+	// pod, e.g. so a provisioner sidecar or debug container can see and signal the build processes.
+	// +optional
+	ShareProcessNamespace bool `json:"shareProcessNamespace,omitempty"`
+
+	// WorkingDir is the working directory of the builder container, e.g. for provisioners that
+	// resolve configuration files such as ansible.cfg relative to it. Defaults to the checkout of
+	// the provisioning repository for a git-based provisioner, and to the builder image's default
+	// otherwise.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	WorkingDir string `json:"workingDir,omitempty"`
+
+	// EnvFrom populates the builder container's environment from Secrets or ConfigMaps, like a
+	// container's envFrom. Variables managed by the operator and spec.builderEnv take precedence.
+	// Non-optional sources must exist before the builder pod is created.
+	// +optional
+	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
+
+	// WorkVolume backs the builder's container storage, where the base image is unpacked and
+	// provisioned, with a generic ephemeral volume instead of an emptyDir on the node's disk.
+	// +optional
+	WorkVolume *WorkVolume `json:"workVolume,omitempty"`
+
+	// MinFreeDiskSpace is the free space the builder's container storage must have before the build
+	// starts, e.g. "50Gi". An init container checks it, so a build on a node short of disk fails
+	// right away with reason InsufficientDiskSpace rather than mid-way. Defaults to the controller's
+	// --min-free-disk-space; "0" disables the check.
+	// +optional
+	MinFreeDiskSpace *resource.Quantity `json:"minFreeDiskSpace,omitempty"`
+
+	// Cache mounts a PersistentVolumeClaim shared across ImageBuilds, where the builder keeps the
+	// package manager downloads of the provisioning step so repeated builds do not fetch them again.
+	// +optional
+	Cache *BuildCache `json:"cache,omitempty"`
+
+	// PackageMirrors points the package managers of the image at mirrors or a proxy while the
+	// playbooks run, e.g. in networks where the upstream repositories are slow or blocked.
+	// +optional
+	PackageMirrors *PackageMirrors `json:"packageMirrors,omitempty"`
+
+	// GitSSH configures how the builder verifies the host keys of the git servers the provisioner
+	// and verify repos are cloned from over SSH. Without it, the key of a server is trusted on first use.
+	// +optional
+	GitSSH *GitSSH `json:"gitSSH,omitempty"`
+
+	// SecurityProfile confines the builder container with seccomp and AppArmor profiles. The builder
+	// runs privileged, so whether the profiles are enforced depends on the container runtime.
+	// +optional
+	SecurityProfile *SecurityProfile `json:"securityProfile,omitempty"`
+
+	// Lifecycle overrides the lifecycle hooks of the builder container.
+	// +optional
+	Lifecycle *BuilderLifecycle `json:"lifecycle,omitempty"`
+
+	// SecretFiles projects keys of Secrets to files in the builder container. Unlike envFrom, the
+	// values do not show up in the environment of every process of the build, so it is preferred for
+	// sensitive provisioning data, e.g. vault tokens or license keys. The Secrets and keys must exist
+	// before the builder pod is created.
+	// +listType=map
+	// +listMapKey=path
+	// +optional
+	SecretFiles []SecretFileMount `json:"secretFiles,omitempty"`
+
+	// InitContainers are run in the builder pod after the init containers of the operator, such as
+	// the disk space check, and before the builder container, e.g. to prefetch assets. They can only
+	// mount the volumes of the builder pod, as the pod has no volumes of their own. The names of the
+	// operator's containers, "builder" and "disk-space-check", cannot be used.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	InitContainers []corev1.Container `json:"initContainers,omitempty"`
+}
+
+// SecretFileMount projects a key of a Secret to a read-only file in the builder container.
+type SecretFileMount struct {
+	// SecretName is the name of a Secret in the ImageBuild's namespace.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+
+	// Key is the key of the Secret whose value is the content of the file.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Key string `json:"key"`
+
+	// Path is the absolute path of the file in the builder container, e.g. "/run/secrets/vault-token".
+	// The other files of its directory are left in place.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^/`
+	Path string `json:"path"`
+}
+
+// BuilderLifecycle defines the lifecycle hooks of the builder container.
+// +kubebuilder:validation:XValidation:rule="!has(self.preStop) || (has(self.preStop.exec) ? 1 : 0) + (has(self.preStop.httpGet) ? 1 : 0) + (has(self.preStop.sleep) ? 1 : 0) == 1",message="preStop must specify exactly one of exec, httpGet or sleep"
+type BuilderLifecycle struct {
+	// PreStop runs before the builder container is stopped, e.g. when the pod is deleted or the build
+	// times out. By default the builder flushes its writes to disk and unmounts the containers it
+	// mounted, so a killed build leaves no corrupt artifacts or container storage behind.
+	// +optional
+	PreStop *corev1.LifecycleHandler `json:"preStop,omitempty"`
+}
+
+// WorkVolume defines a generic ephemeral volume, provisioned with the builder pod and deleted along with it.
+type WorkVolume struct {
+	// StorageClassName is the storage class of the volume. The cluster's default storage class is
+	// used if it is not set.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// Size is the requested capacity of the volume, e.g. "100Gi".
+	// +kubebuilder:validation:Required
+	Size resource.Quantity `json:"size"`
+}
+
+// BuildCache defines a cache volume shared by the builds referencing the same claim.
+type BuildCache struct {
+	// PVCName is the name of the PersistentVolumeClaim in the ImageBuild's namespace. Unless it allows
+	// ReadWriteMany, builds sharing it can only run on the node the claim is attached to.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	PVCName string `json:"pvcName"`
+}
+
+// PackageMirrors configures the apt and dnf/yum repositories the playbooks install packages from.
+// The package manager configuration of the image is restored once the playbooks have run, so the
+// artifacts keep their own repositories.
+// +kubebuilder:validation:XValidation:rule="has(self.configMapName) || has(self.proxy)",message="at least one of configMapName or proxy must be specified"
+type PackageMirrors struct {
+	// ConfigMapName is the name of a ConfigMap in the ImageBuild's namespace holding repository
+	// files that replace the repositories of the image: "*.list" and "*.sources" keys replace the
+	// apt sources, and "*.repo" keys the dnf/yum repositories. A package manager without a file in
+	// the ConfigMap keeps its repositories.
+	// +optional
+	ConfigMapName string `json:"configMapName,omitempty"`
+
+	// Proxy is the URL of an HTTP proxy apt and dnf/yum fetch packages through, e.g.
+	// "http://proxy.example.com:3128".
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +optional
+	Proxy string `json:"proxy,omitempty"`
+}
+
+// GitHostKeyChecking selects how ssh treats git servers whose host key is not known.
+// +kubebuilder:validation:Enum=Strict;AcceptNew
+type GitHostKeyChecking string
+
+const (
+	// GitHostKeyCheckingStrict rejects git servers whose host key is not in the known hosts.
+	GitHostKeyCheckingStrict GitHostKeyChecking = "Strict"
+	// GitHostKeyCheckingAcceptNew trusts the host key of a git server that is not in the known hosts
+	// on first use, but still rejects servers whose key has changed.
+	GitHostKeyCheckingAcceptNew GitHostKeyChecking = "AcceptNew"
+)
+
+// GitSSH configures the host key verification of git clones over SSH.
+// +kubebuilder:validation:XValidation:rule="!(has(self.knownHostsConfigMapName) && has(self.knownHostsSecretName))",message="knownHostsConfigMapName and knownHostsSecretName are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!has(self.hostKeyChecking) || self.hostKeyChecking != 'Strict' || has(self.knownHostsConfigMapName) || has(self.knownHostsSecretName)",message="Strict host key checking requires knownHostsConfigMapName or knownHostsSecretName"
+type GitSSH struct {
+	// KnownHostsConfigMapName is the name of a ConfigMap in the ImageBuild's namespace whose
+	// "known_hosts" key holds the host keys of the git servers in the OpenSSH known_hosts format,
+	// e.g. the output of ssh-keyscan.
+	// +optional
+	KnownHostsConfigMapName string `json:"knownHostsConfigMapName,omitempty"`
+
+	// KnownHostsSecretName is the name of a Secret in the ImageBuild's namespace whose "known_hosts"
+	// key holds the host keys of the git servers, for clusters where ConfigMaps are not trusted.
+	// +optional
+	KnownHostsSecretName string `json:"knownHostsSecretName,omitempty"`
+
+	// HostKeyChecking selects how servers whose host key is not in the known hosts are treated:
+	// "Strict" rejects them, "AcceptNew" trusts their key on first use. Defaults to "Strict" if known
+	// hosts are given, and to "AcceptNew" otherwise.
+	// +optional
+	HostKeyChecking GitHostKeyChecking `json:"hostKeyChecking,omitempty"`
+}
+
+// ProfileType selects a seccomp or AppArmor profile.
+// +kubebuilder:validation:Enum=RuntimeDefault;Localhost
+type ProfileType string
+
+const (
+	// ProfileTypeRuntimeDefault selects the default profile of the container runtime.
+	ProfileTypeRuntimeDefault ProfileType = "RuntimeDefault"
+	// ProfileTypeLocalhost selects a profile loaded on the node.
+	ProfileTypeLocalhost ProfileType = "Localhost"
+)
+
+// Profile references a seccomp or AppArmor profile.
+// +kubebuilder:validation:XValidation:rule="self.type == 'Localhost' ? has(self.localhostProfile) : !has(self.localhostProfile)",message="localhostProfile must be set if and only if type is Localhost"
+type Profile struct {
+	// Type is the kind of profile to apply.
+	// +kubebuilder:validation:Required
+	Type ProfileType `json:"type"`
+
+	// LocalhostProfile is the profile loaded on the node, required if and only if Type is "Localhost".
+	// For seccomp it is a path relative to the kubelet's seccomp directory; for AppArmor the profile name.
+	// +optional
+	LocalhostProfile string `json:"localhostProfile,omitempty"`
+}
+
+// SecurityProfile defines the seccomp and AppArmor profiles of the builder container.
+type SecurityProfile struct {
+	// SeccompProfile is the seccomp profile of the builder container.
+	// +optional
+	SeccompProfile *Profile `json:"seccompProfile,omitempty"`
+
+	// AppArmorProfile is the AppArmor profile of the builder container.
+	// +optional
+	AppArmorProfile *Profile `json:"appArmorProfile,omitempty"`
+}
+
+// --- Verification Definitions ---
+
+// VerifySpec defines a verification step run against the built filesystem after provisioning,
+// e.g. a smoke-test playbook. A failed verification fails the build.
+type VerifySpec struct {
+	// Ansible runs a playbook against the built filesystem. A non-zero exit fails the verification.
+	// +kubebuilder:validation:Required
+	Ansible *AnsibleSpec `json:"ansible"`
+}
+
+// --- Pod Metadata Definitions ---
+
+// PodMetadata defines labels and annotations added to the pods created for an ImageBuild.
+type PodMetadata struct {
+	// Labels are added to the pods. The operator-managed "bib.cluster.x-k8s.io/" labels cannot be set.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are added to the pods. They take precedence over the sidecar injection
+	// opt-out annotations the operator sets by default.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// --- Notification Definitions ---
+
+// NotifySpec defines an HTTP endpoint that is notified when the build reaches a terminal phase.
+type NotifySpec struct {
+	// URL is the endpoint the operator POSTs a JSON payload to once the build has Succeeded or Failed.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// AuthSecretName is the name of a Secret in the same namespace whose `token` key is sent
+	// as a bearer token in the Authorization header.
+	// +optional
+	AuthSecretName string `json:"authSecretName,omitempty"`
+}
+
+// ImageBuildReference references another ImageBuild in the same namespace.
+type ImageBuildReference struct {
+	// Name of the ImageBuild.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
 // ImageBuildSpec defines the desired state of ImageBuild.
+// +kubebuilder:validation:XValidation:rule="has(self.baseImage) != has(self.baseImageFrom)",message="exactly one of baseImage or baseImageFrom must be specified"
 type ImageBuildSpec struct {
 	// Architecture specifies the target architecture for the build.
-	// Supported values are "amd64" and "arm64".
-	// +kubebuilder:validation:Enum=amd64;arm64
+	// Supported values are "amd64", "arm64" and "riscv64". The builder pod is scheduled on nodes
+	// of the architecture, and the builder image must be available for it.
+	// +kubebuilder:validation:Enum=amd64;arm64;riscv64
 	// +kubebuilder:default:="amd64"
 	// +optional
 	Architecture string `json:"arch,omitempty"`
 
-	// BaseImage is the starting container image for the build.
-	BaseImage string `json:"baseImage"`
+	// BaseImage is the starting container image for the build. Exactly one of BaseImage and
+	// BaseImageFrom must be set.
+	// +optional
+	BaseImage string `json:"baseImage,omitempty"`
+
+	// BaseImageFrom builds on the image pushed by the registry output of another ImageBuild in the
+	// same namespace, e.g. a base layer shared by several variants. The build waits until that
+	// ImageBuild has succeeded, and pulls its image by digest.
+	// +optional
+	BaseImageFrom *ImageBuildReference `json:"baseImageFrom,omitempty"`
 
 	// BaseImagePullSecretName is the name of a 'kubernetes.io/dockerconfigjson' secret
 	// to use for pulling the BaseImage from a private registry.
@@ -237,6 +1301,10 @@ type ImageBuildSpec struct {
 	// +optional
 	Provisioner *ProvisionerSpec `json:"provisioner,omitempty"`
 
+	// Verify defines a verification step run after the provisioner. This is optional.
+	// +optional
+	Verify *VerifySpec `json:"verify,omitempty"`
+
 	// Output defines where the final artifacts should be stored.
 	Output OutputSpec `json:"output"`
 
@@ -244,6 +1312,51 @@ type ImageBuildSpec struct {
 	// If omitted, only the artifacts in 'output' will be created.
 	// +optional
 	Publish *PublishSpec `json:"publish,omitempty"`
+
+	// Signing defines how the image pushed by the registry output is signed. This is optional.
+	// +optional
+	Signing *SigningSpec `json:"signing,omitempty"`
+
+	// Notify defines an endpoint to notify when the build finishes. This is optional.
+	// Notification failures are retried and never change the outcome of the build.
+	// +optional
+	Notify *NotifySpec `json:"notify,omitempty"`
+
+	// Build defines settings of the builder pod. This is optional.
+	// +optional
+	Build *BuildSpec `json:"build,omitempty"`
+
+	// TimeoutSeconds bounds how long the builder pod may run. It is set as the pod's
+	// activeDeadlineSeconds, so the kubelet kills a stuck build even while the controller is down,
+	// and the build fails with reason BuildTimedOut. Publishing is not included. No limit if unset.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds *int64 `json:"timeoutSeconds,omitempty"`
+
+	// RetryLimit bounds how many times a builder pod evicted by the cluster, e.g. under node
+	// pressure, is recreated before the build fails with reason PodEvicted. Evictions are not
+	// build errors, so they do not fail the build until the limit is reached. 0 disables retries.
+	// +kubebuilder:default:=3
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RetryLimit *int32 `json:"retryLimit,omitempty"`
+
+	// Verbosity raises how much the builder and the provisioner log, to debug a build without
+	// baking verbose logging into every one: "Normal", "Verbose" or "Debug". Defaults to "Normal".
+	// +kubebuilder:default:="Normal"
+	// +optional
+	Verbosity Verbosity `json:"verbosity,omitempty"`
+
+	// BuilderEnv is a list of additional environment variables for the builder container, e.g. to
+	// experiment with builder features the operator does not expose yet. Variables managed by the
+	// operator take precedence over entries with the same name.
+	// +optional
+	BuilderEnv []corev1.EnvVar `json:"builderEnv,omitempty"`
+
+	// PodMetadata defines labels and annotations added to the builder and publisher pods,
+	// e.g. to control service mesh sidecar injection. This is optional.
+	// +optional
+	PodMetadata *PodMetadata `json:"podMetadata,omitempty"`
 }
 
 // ImageBuildPhase represents the high-level state of the build.
@@ -268,8 +1381,24 @@ const (
 	ProvisionerReady clusterv1beta1.ConditionType = "ProvisionerReady"
 	OutputReady      clusterv1beta1.ConditionType = "OutputReady"
 	PublishReady     clusterv1beta1.ConditionType = "PublishReady"
+
+	// VerifyReady reports the result of the spec.verify step. It is marked true once the build
+	// has succeeded, including when no verification is configured.
+	VerifyReady clusterv1beta1.ConditionType = "VerifyReady"
+
+	// SigningReady reports the signing of the pushed image configured in spec.signing. It is marked
+	// true once the build has succeeded, including when no signing is configured.
+	SigningReady clusterv1beta1.ConditionType = "SigningReady"
 )
 
+// NotificationDelivered reports the delivery of the terminal-state notification configured in spec.notify.
+// It is only set when spec.notify is specified and is not part of ImageBuildConditionTypes.
+const NotificationDelivered clusterv1beta1.ConditionType = "NotificationDelivered"
+
+// SpecBuilt reports whether a finished build ran with the current generation of its spec. It is
+// only set, to false, once the spec of a finished build changes, and is not part of ImageBuildConditionTypes.
+const SpecBuilt clusterv1beta1.ConditionType = "SpecBuilt"
+
 // ImageBuildContitionTypes is the list of all condition types.
 var ImageBuildConditionTypes = []clusterv1beta1.ConditionType{
 	BaseImageReady,
@@ -277,6 +1406,101 @@ var ImageBuildConditionTypes = []clusterv1beta1.ConditionType{
 	ProvisionerReady,
 	OutputReady,
 	PublishReady,
+	VerifyReady,
+	SigningReady,
+}
+
+// OutputPVCStatus records where the artifacts of a PVC output are kept.
+type OutputPVCStatus struct {
+	// ClaimName is the name of the PersistentVolumeClaim holding the artifacts.
+	ClaimName string `json:"claimName"`
+
+	// Path is the directory within the PVC holding the artifacts.
+	Path string `json:"path"`
+}
+
+// ArtifactStatus describes an artifact produced by the builder.
+type ArtifactStatus struct {
+	// Format is the format of the artifact, "oci" for a container image pushed by the registry output.
+	Format OutputFormat `json:"format"`
+
+	// Name is the file name of the artifact, e.g. "ubuntu-2404-golden.qcow2", or the image
+	// reference of a container image.
+	Name string `json:"name"`
+
+	// URL is the location of the artifact in the first output keeping it, e.g.
+	// "pvc://<claim>/<path>/<file>", "s3://<bucket>/<namespace>/<name>/<file>" or "docker://<reference>".
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Checksum is the SHA-256 digest of the artifact file, or the manifest digest of a container
+	// image, as "sha256:<hex>".
+	// +optional
+	Checksum string `json:"checksum,omitempty"`
+
+	// Signed is true if the artifact was signed as configured in spec.signing.
+	// +optional
+	Signed bool `json:"signed,omitempty"`
+
+	// SizeBytes is the size of the artifact file, i.e. what it costs to store and transfer.
+	// For sparse disk images it is typically much smaller than VirtualSizeBytes.
+	// +optional
+	SizeBytes int64 `json:"sizeBytes,omitempty"`
+
+	// VirtualSizeBytes is the size of the disk the image exposes to a virtual machine.
+	// Only set for disk image formats.
+	// +optional
+	VirtualSizeBytes *int64 `json:"virtualSizeBytes,omitempty"`
+
+	// Compressed is true if the artifact was written with compression.
+	// +optional
+	Compressed bool `json:"compressed,omitempty"`
+}
+
+// BuildStagePhase is the outcome of a stage of the builder.
+// +kubebuilder:validation:Enum=Succeeded;Failed;Skipped
+type BuildStagePhase string
+
+const (
+	BuildStageSucceeded BuildStagePhase = "Succeeded"
+	BuildStageFailed    BuildStagePhase = "Failed"
+	BuildStageSkipped   BuildStagePhase = "Skipped"
+)
+
+// BuildStageStatus reports the outcome of a stage of the builder, e.g. provisioning or the registry push.
+type BuildStageStatus struct {
+	// Name of the stage. The bundled builder reports "pull", "provision", "verify", "push", "convert"
+	// and "upload"; custom builders may report stages of their own, e.g. "sbom".
+	Name string `json:"name"`
+
+	// Phase is the outcome of the stage. A builder may report a Failed stage and still succeed if the
+	// failure was not fatal to the build.
+	Phase BuildStagePhase `json:"phase"`
+
+	// Message describes the outcome, e.g. why the stage failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// StartTime is when the stage started, if the builder reports it.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the stage finished, if the builder reports it.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// OutputDestinationStatus reports the outcome of the upload to an output destination.
+type OutputDestinationStatus struct {
+	// URL is the destination, as listed in status.outputURLs.
+	URL string `json:"url"`
+
+	// Phase is the outcome of the upload: Succeeded, or Failed if any part of it failed.
+	Phase BuildStagePhase `json:"phase"`
+
+	// Message describes why the upload failed.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // ImageBuildStatus defines the observed state of ImageBuild.
@@ -285,6 +1509,20 @@ type ImageBuildStatus struct {
 	// +optional
 	Phase ImageBuildPhase `json:"phase,omitempty"`
 
+	// ObservedGeneration is the metadata.generation of the spec the status was last reconciled
+	// against. Conditions failed against an earlier generation are re-evaluated once it changes.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastPhaseTransitionTime is the time the build last moved to a different phase.
+	// +optional
+	LastPhaseTransitionTime *metav1.Time `json:"lastPhaseTransitionTime,omitempty"`
+
+	// Summary outlines what the build does, derived from the spec: the provisioner, the outputs
+	// with the artifact formats, and the publish target, e.g. "ansible→s3(tgz,qcow2)→aws".
+	// +optional
+	Summary string `json:"summary,omitempty"`
+
 	// Conditions represent the latest available observations of an ImageBuild's state.
 	// +optional
 	// +patchMergeKey=type
@@ -295,6 +1533,11 @@ type ImageBuildStatus struct {
 	// +optional
 	StartTime *metav1.Time `json:"startTime,omitempty"`
 
+	// BuildDeadline is the time the kubelet kills the builder pod at if it is still running:
+	// spec.timeoutSeconds after the kubelet started the pod. It is unset until the pod has started.
+	// +optional
+	BuildDeadline *metav1.Time `json:"buildDeadline,omitempty"`
+
 	// CompletionTime is the time at which the build pod finished.
 	// +optional
 	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
@@ -303,14 +1546,147 @@ type ImageBuildStatus struct {
 	// +optional
 	BuilderPodName string `json:"builderPodName,omitempty"`
 
+	// BuilderPodNamespace is the namespace of the builder pod, if it runs in a build namespace of its
+	// own rather than in the namespace of the ImageBuild.
+	// +optional
+	BuilderPodNamespace string `json:"builderPodNamespace,omitempty"`
+
+	// BuildAttempts counts the builder pods created for this ImageBuild, including pods recreated
+	// after the previous one disappeared, whatever the reason, retries of builds rate limited by a
+	// registry, retries after an eviction, and retries on a fresh output PVC.
+	// +optional
+	BuildAttempts int32 `json:"buildAttempts,omitempty"`
+
+	// Evictions counts the builder pods of this ImageBuild that were evicted and recreated, bounded
+	// by spec.retryLimit.
+	// +optional
+	Evictions int32 `json:"evictions,omitempty"`
+
+	// RateLimitRetries counts the builds of this ImageBuild rate limited by a registry and retried.
+	// The build fails once a registry still rate limits it after the maximum number of retries.
+	// +optional
+	RateLimitRetries int32 `json:"rateLimitRetries,omitempty"`
+
+	// PodCreationFailures counts the consecutive failures to construct or create the builder pod.
+	// It is reset once a builder pod has been created.
+	// +optional
+	PodCreationFailures int32 `json:"podCreationFailures,omitempty"`
+
+	// PodCreationFailedGeneration is the metadata.generation the build failed at after too many
+	// pod creation failures. The build is retried once the spec is changed.
+	// +optional
+	PodCreationFailedGeneration int64 `json:"podCreationFailedGeneration,omitempty"`
+
+	// OutputPVCRecreations counts the fresh output PVCs created for failed builds with
+	// spec.output.pvc.recreateOnFailure. The build uses the latest, "<imagebuild>-output-<n>".
+	// +optional
+	OutputPVCRecreations int32 `json:"outputPVCRecreations,omitempty"`
+
+	// ResolvedBaseImage is the base image reference the builder pulls, after applying the
+	// controller's registry mirrors.
+	// +optional
+	ResolvedBaseImage string `json:"resolvedBaseImage,omitempty"`
+
+	// BaseImageMirror is the pull-through cache the base image is pulled from, or empty if the
+	// base image is pulled from its own registry.
+	// +optional
+	BaseImageMirror string `json:"baseImageMirror,omitempty"`
+
 	// OutputURL is the final location of the built artifact, such as an S3 URL or container image reference.
+	// It is the URL of the primary artifact, the first of Artifacts, or the first of OutputURLs if the
+	// builder reported no artifacts. It is kept for compatibility; Artifacts describes every artifact.
 	// +optional
 	OutputURL string `json:"outputURL,omitempty"`
+
+	// OutputURLs lists the location of the artifacts in every output, in the order pvc, volume,
+	// objectStorage, registry.
+	// +optional
+	OutputURLs []string `json:"outputURLs,omitempty"`
+
+	// OutputPVC records where the artifacts are kept for a PVC output, so they can be consumed
+	// by subsequent builds without being uploaded and downloaded again.
+	// +optional
+	OutputPVC *OutputPVCStatus `json:"outputPVC,omitempty"`
+
+	// LogURL is the location the full build log was uploaded to, if spec.build.logsOutput is set.
+	// +optional
+	LogURL string `json:"logURL,omitempty"`
+
+	// ExitCode is the exit code of the builder container once it has terminated.
+	// +optional
+	ExitCode *int32 `json:"exitCode,omitempty"`
+
+	// TerminationReason is the reason the builder container terminated, as reported by the kubelet,
+	// e.g. "Completed", "Error" or "OOMKilled".
+	// +optional
+	TerminationReason string `json:"terminationReason,omitempty"`
+
+	// PublishedImageID is the ID of the image registered with the publish target, e.g. an AMI ID.
+	// +optional
+	PublishedImageID string `json:"publishedImageID,omitempty"`
+
+	// PublishedImageLaunchPermissions records the launch permissions the publisher granted on the
+	// published AMI, as requested by spec.publish.aws.launchPermissions.
+	// +optional
+	PublishedImageLaunchPermissions *AWSLaunchPermissions `json:"publishedImageLaunchPermissions,omitempty"`
+
+	// Artifacts lists the artifacts reported by the builder once the build has succeeded: the file
+	// artifacts in the order of spec.output.formats, then the container images in the order of the
+	// registry destinations. The first is the primary artifact.
+	// +optional
+	Artifacts []ArtifactStatus `json:"artifacts,omitempty"`
+
+	// Stages lists the outcome of each stage reported by the builder once the builder pod has finished,
+	// in the order they ran.
+	// +optional
+	Stages []BuildStageStatus `json:"stages,omitempty"`
+
+	// OutputDestinations lists the outcome of the upload to each registry and object storage
+	// destination reported by the builder, in the order of status.outputURLs, however the uploads
+	// completed.
+	// +optional
+	OutputDestinations []OutputDestinationStatus `json:"outputDestinations,omitempty"`
+
+	// Notification records the delivery of the terminal-state notification configured in spec.notify.
+	// +optional
+	Notification *NotificationStatus `json:"notification,omitempty"`
+
+	// V1Beta2 groups the fields that use the metav1.Condition format.
+	// +optional
+	V1Beta2 *ImageBuildV1Beta2Status `json:"v1beta2,omitempty"`
+}
+
+// ImageBuildV1Beta2Status groups the fields that use the metav1.Condition format.
+type ImageBuildV1Beta2Status struct {
+	// Conditions mirror status.conditions. Each carries the observedGeneration of the spec it was
+	// last changed against, so a condition older than metadata.generation refers to an earlier spec.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	// +kubebuilder:validation:MaxItems=32
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// NotificationStatus records the delivery of the terminal-state notification.
+type NotificationStatus struct {
+	// Phase is the terminal phase reported by the notification.
+	// +optional
+	Phase ImageBuildPhase `json:"phase,omitempty"`
+
+	// Delivered is true once the endpoint acknowledged the notification with a 2xx response.
+	// +optional
+	Delivered bool `json:"delivered,omitempty"`
+
+	// Attempts is the number of delivery attempts made for Phase.
+	// +optional
+	Attempts int32 `json:"attempts,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="BaseImage",type="string",JSONPath=".spec.baseImage"
+// +kubebuilder:printcolumn:name="BaseImageFrom",type="string",JSONPath=".spec.baseImageFrom.name"
+// +kubebuilder:printcolumn:name="Summary",type="string",JSONPath=".status.summary"
 // +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].reason"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
@@ -342,6 +1718,22 @@ func (ib *ImageBuild) SetConditions(conditions clusterv1beta1.Conditions) {
 	ib.Status.Conditions = conditions
 }
 
+// GetV1Beta2Conditions returns the set of metav1.Conditions for an ImageBuild API object.
+func (ib *ImageBuild) GetV1Beta2Conditions() []metav1.Condition {
+	if ib.Status.V1Beta2 == nil {
+		return nil
+	}
+	return ib.Status.V1Beta2.Conditions
+}
+
+// SetV1Beta2Conditions sets the metav1.Conditions on an ImageBuild object.
+func (ib *ImageBuild) SetV1Beta2Conditions(conditions []metav1.Condition) {
+	if ib.Status.V1Beta2 == nil {
+		ib.Status.V1Beta2 = &ImageBuildV1Beta2Status{}
+	}
+	ib.Status.V1Beta2.Conditions = conditions
+}
+
 func init() {
 	SchemeBuilder.Register(&ImageBuild{}, &ImageBuildList{})
 }
@@ -0,0 +1,976 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"mime"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/zarcen/bib-operator/pkg/imageref"
+)
+
+// AWS tag limits, see https://docs.aws.amazon.com/tag-editor/latest/userguide/tagging.html
+const (
+	awsMaxTags           = 50
+	awsMaxTagKeyLength   = 128
+	awsMaxTagValueLength = 256
+	awsReservedTagPrefix = "aws:"
+)
+
+// roleARNRegexp matches IAM role ARNs in any AWS partition.
+var roleARNRegexp = regexp.MustCompile(`^arn:aws[a-z-]*:iam::[0-9]{12}:role/[A-Za-z0-9+=,.@_/-]+$`)
+
+// reservedBuilderPaths are the directories of the builder container that the operator mounts
+// volumes at, or that the builder and the container runtime need for themselves. Numbered variants,
+// e.g. /etc/registry-auth-1, are reserved along with them.
+var reservedBuilderPaths = []string{
+	"/dev", "/proc", "/sys", "/workspace",
+	"/output", "/output-root", "/source", "/verify-source",
+	"/etc/baseimage-pull-secret", "/etc/registry-auth", "/etc/git-credentials", "/etc/git-known-hosts",
+	"/etc/git-submodule-credentials", "/etc/verify-git-submodule-credentials", "/etc/package-mirrors", "/etc/post-steps",
+	"/var/cache/bib", "/var/lib/containers", "/var/run/sigstore", "/run/sigstore",
+}
+
+// reservedContainerNames are the names of the containers the operator adds to the builder pod: the
+// builder container and the disk space check init container.
+var reservedContainerNames = []string{"builder", "disk-space-check"}
+
+// reservedBuilderPath returns the reserved builder directory p is in, or "" if there is none.
+func reservedBuilderPath(p string) string {
+	for _, reserved := range reservedBuilderPaths {
+		if p == reserved || strings.HasPrefix(p, reserved+"/") || strings.HasPrefix(p, reserved+"-") {
+			return reserved
+		}
+	}
+	return ""
+}
+
+// filesystems are the supported root filesystems of disk image artifacts.
+var filesystems = []Filesystem{FilesystemExt4, FilesystemXFS, FilesystemBtrfs}
+
+// partitionFilesystems are the supported filesystems of the partitions of a custom disk layout.
+var partitionFilesystems = []PartitionFilesystem{
+	PartitionFilesystemExt4, PartitionFilesystemXFS, PartitionFilesystemBtrfs, PartitionFilesystemVFAT, PartitionFilesystemSwap,
+}
+
+const (
+	// mbrMaxPartitions is the number of primary partitions of an MBR.
+	mbrMaxPartitions = 4
+	// diskLayoutReservedBytes is the space of a disk not available to the partitions: the first MiB
+	// the partitions are aligned after, and the backup GPT at the end, rounded up to a MiB.
+	diskLayoutReservedBytes = 2 << 20
+	// efiMountPoint is the mount point of the EFI system partition.
+	efiMountPoint = "/boot/efi"
+)
+
+var qcow2Preallocations = []QCOW2Preallocation{
+	QCOW2PreallocationOff, QCOW2PreallocationMetadata, QCOW2PreallocationFalloc, QCOW2PreallocationFull,
+}
+
+// Supported values of spec.arch.
+const (
+	ArchitectureAMD64   = "amd64"
+	ArchitectureARM64   = "arm64"
+	ArchitectureRISCV64 = "riscv64"
+)
+
+// architecture describes how a supported spec.arch value maps onto nodes and publish targets.
+type architecture struct {
+	// nodeArch is the kubernetes.io/arch label value of the nodes that build the architecture.
+	nodeArch string
+	// amiArch is the EC2 AMI architecture, or empty if EC2 has no instances of the architecture.
+	amiArch string
+	// aliases are the names other tooling uses for the architecture, e.g. "x86_64" from uname
+	// or "aarch64" from RPM.
+	aliases []string
+}
+
+// architectures are the supported spec.arch values. Supporting another architecture takes an
+// entry here and in the enum of ImageBuildSpec.Architecture.
+var architectures = map[string]architecture{
+	ArchitectureAMD64:   {nodeArch: "amd64", amiArch: "x86_64", aliases: []string{"x86_64", "x86-64", "x64"}},
+	ArchitectureARM64:   {nodeArch: "arm64", amiArch: "arm64", aliases: []string{"aarch64", "armv8"}},
+	ArchitectureRISCV64: {nodeArch: "riscv64"},
+}
+
+// SupportedArchitectures returns the supported spec.arch values in ascending order.
+func SupportedArchitectures() []string {
+	return slices.Sorted(maps.Keys(architectures))
+}
+
+// lookupArchitecture returns the supported architecture for a spec.arch value.
+// An empty arch is treated as the CRD default, "amd64".
+func lookupArchitecture(arch string) (architecture, bool) {
+	if arch == "" {
+		arch = ArchitectureAMD64
+	}
+	a, ok := architectures[arch]
+	return a, ok
+}
+
+// NodeArchitecture returns the kubernetes.io/arch node label value for a build architecture.
+// An empty arch is treated as the CRD default, "amd64".
+func NodeArchitecture(arch string) (string, bool) {
+	a, ok := lookupArchitecture(arch)
+	return a.nodeArch, ok
+}
+
+// AMIArchitecture returns the EC2 AMI architecture for a build architecture.
+// An empty arch is treated as the CRD default, "amd64".
+func AMIArchitecture(arch string) (string, bool) {
+	a, ok := lookupArchitecture(arch)
+	return a.amiArch, ok && a.amiArch != ""
+}
+
+// amiArchitectures returns the spec.arch values that can be imported as an AMI, in ascending order.
+func amiArchitectures() []string {
+	var archs []string
+	for _, arch := range SupportedArchitectures() {
+		if architectures[arch].amiArch != "" {
+			archs = append(archs, arch)
+		}
+	}
+	return archs
+}
+
+// NormalizeArchitecture returns the spec.arch value for arch, accepting any casing and the
+// aliases of the supported architectures. Unknown values are returned unchanged, for the CRD
+// schema to reject them.
+func NormalizeArchitecture(arch string) string {
+	lower := strings.ToLower(arch)
+	for name, a := range architectures {
+		if lower == name || slices.Contains(a.aliases, lower) {
+			return name
+		}
+	}
+	return arch
+}
+
+// NormalizeSpec rewrites spec values the CRD schema would reject for their spelling only, such as
+// "x86_64" for spec.arch, to their canonical form.
+func (ib *ImageBuild) NormalizeSpec() {
+	ib.Spec.Architecture = NormalizeArchitecture(ib.Spec.Architecture)
+}
+
+// ValidateSpecChange validates the rules that only apply to values being set: those of a new
+// ImageBuild if old is nil, and those changed from old otherwise. ImageBuilds stored before the rules
+// existed keep their values, as the CRD ratchets the same rules with oldSelf.
+func (ib *ImageBuild) ValidateSpecChange(old *ImageBuild) field.ErrorList {
+	var oldOutput *OutputSpec
+	if old != nil {
+		oldOutput = &old.Spec.Output
+	}
+	return validateRegistryOnlyFormats(&ib.Spec.Output, oldOutput, field.NewPath("spec", "output"))
+}
+
+// ValidateSpec performs the static validation of the ImageBuild spec that cannot be expressed
+// as OpenAPI or CEL rules on the CRD.
+func (ib *ImageBuild) ValidateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if _, ok := lookupArchitecture(ib.Spec.Architecture); !ok {
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("arch"), ib.Spec.Architecture, SupportedArchitectures()))
+	}
+	if from := ib.Spec.BaseImageFrom; from != nil {
+		if ib.Spec.BaseImage != "" {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("baseImageFrom"), from.Name, "must not be set together with spec.baseImage"))
+		}
+		if from.Name == ib.Name {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("baseImageFrom", "name"), from.Name, "must not reference the ImageBuild itself"))
+		}
+	} else {
+		allErrs = append(allErrs, validateImageReference(ib.Spec.BaseImage, imageref.Parse, specPath.Child("baseImage"))...)
+	}
+	allErrs = append(allErrs, validateOutputDestinations(&ib.Spec.Output, specPath.Child("output"))...)
+	if registry := ib.Spec.Output.Registry; registry != nil && registry.Destination != "" {
+		allErrs = append(allErrs, validateImageReference(registry.Destination, imageref.ParseDestination,
+			specPath.Child("output", "registry", "destination"))...)
+		allErrs = append(allErrs, validateRepositoryCreation(registry, specPath.Child("output", "registry"))...)
+		allErrs = append(allErrs, validateAdditionalDestinations(registry, specPath.Child("output", "registry", "additionalDestinations"))...)
+		if registry.Config != nil {
+			allErrs = append(allErrs, validateImageConfig(registry.Config, specPath.Child("output", "registry", "config"))...)
+		}
+	}
+	if objectStorage := ib.Spec.Output.ObjectStorage; objectStorage != nil {
+		allErrs = append(allErrs, validateAWSAssumeRole(&objectStorage.AWSAssumeRole, specPath.Child("output", "objectStorage"))...)
+		allErrs = append(allErrs, validateContentType(objectStorage.ContentType, specPath.Child("output", "objectStorage", "contentType"))...)
+	}
+	if build := ib.Spec.Build; build != nil && build.LogsOutput != nil {
+		allErrs = append(allErrs, validateAWSAssumeRole(&build.LogsOutput.AWSAssumeRole, specPath.Child("build", "logsOutput"))...)
+		allErrs = append(allErrs, validateContentType(build.LogsOutput.ContentType, specPath.Child("build", "logsOutput", "contentType"))...)
+	}
+	if ib.Spec.Publish != nil && ib.Spec.Publish.AWS != nil {
+		allErrs = append(allErrs, validateAWSAssumeRole(&ib.Spec.Publish.AWS.AWSAssumeRole, specPath.Child("publish", "aws"))...)
+		allErrs = append(allErrs, validateAWSArchitecture(ib.Spec.Architecture, ib.Spec.Publish.AWS.Architecture, specPath)...)
+		allErrs = append(allErrs, validateAWSTags(ib.Spec.Publish.AWS.Tags, specPath.Child("publish", "aws", "tags"))...)
+		if ib.Spec.Architecture == ArchitectureARM64 && ib.Spec.Publish.AWS.BootMode == AWSBootModeLegacyBIOS {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("publish", "aws", "bootMode"), ib.Spec.Publish.AWS.BootMode,
+				"arm64 AMIs must boot with UEFI"))
+		}
+		if partitioning := ib.Spec.Output.Partitioning; ib.Spec.Publish.AWS.BootMode == AWSBootModeUEFI &&
+			partitioning != nil && partitioning.EFISystemPartition != nil && !*partitioning.EFISystemPartition {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("publish", "aws", "bootMode"), ib.Spec.Publish.AWS.BootMode,
+				"UEFI AMIs need an EFI system partition in spec.output.partitioning"))
+		}
+		if layout := ib.Spec.Output.DiskLayout; ib.Spec.Publish.AWS.BootMode == AWSBootModeUEFI && layout != nil && !layout.hasEFISystemPartition() {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("publish", "aws", "bootMode"), ib.Spec.Publish.AWS.BootMode,
+				"UEFI AMIs need an EFI system partition, a vfat partition mounted at /boot/efi of a GPT spec.output.diskLayout"))
+		}
+	}
+	if pvc := ib.Spec.Output.PVC; pvc != nil && pvc.SubPath != "" {
+		allErrs = append(allErrs, validateSubPath(pvc.SubPath, specPath.Child("output", "pvc", "subPath"))...)
+	}
+	if volume := ib.Spec.Output.Volume; volume != nil && volume.SubPath != "" {
+		allErrs = append(allErrs, validateSubPath(volume.SubPath, specPath.Child("output", "volume", "subPath"))...)
+	}
+	if ib.Spec.Provisioner != nil && ib.Spec.Provisioner.Ansible != nil {
+		allErrs = append(allErrs, validateAnsiblePython(ib.Spec.Provisioner.Ansible, specPath.Child("provisioner", "ansible"))...)
+		allErrs = append(allErrs, validateAnsibleConnection(ib.Spec.Provisioner.Ansible, specPath.Child("provisioner", "ansible"))...)
+		allErrs = append(allErrs, validateGitSubmodules(ib.Spec.Provisioner.Ansible.Submodules, ib.Spec.Provisioner.Ansible.SubmoduleCredentials,
+			specPath.Child("provisioner", "ansible"))...)
+	}
+	if ib.Spec.Provisioner != nil && ib.Spec.Provisioner.Dockerfile != nil {
+		allErrs = append(allErrs, validateDockerfile(ib.Spec.Provisioner.Dockerfile, specPath.Child("provisioner", "dockerfile"))...)
+	}
+	if ib.Spec.Provisioner != nil && ib.Spec.Provisioner.Kickstart != nil {
+		allErrs = append(allErrs, validateKickstart(ib.Spec.Provisioner.Kickstart, specPath.Child("provisioner", "kickstart"))...)
+	}
+	if ib.Spec.Provisioner != nil && ib.Spec.Provisioner.Bootc != nil {
+		allErrs = append(allErrs, validateBootc(ib.Spec.Provisioner.Bootc, &ib.Spec.Output, specPath)...)
+	}
+	if ib.Spec.Provisioner != nil {
+		allErrs = append(allErrs, validatePostSteps(ib.Spec.Provisioner.PostSteps, specPath.Child("provisioner", "postSteps"))...)
+	}
+	if ib.Spec.Verify != nil && ib.Spec.Verify.Ansible != nil {
+		allErrs = append(allErrs, validateAnsiblePython(ib.Spec.Verify.Ansible, specPath.Child("verify", "ansible"))...)
+		allErrs = append(allErrs, validateAnsibleConnection(ib.Spec.Verify.Ansible, specPath.Child("verify", "ansible"))...)
+		allErrs = append(allErrs, validateGitSubmodules(ib.Spec.Verify.Ansible.Submodules, ib.Spec.Verify.Ansible.SubmoduleCredentials,
+			specPath.Child("verify", "ansible"))...)
+	}
+	allErrs = append(allErrs, validateDiskLayout(&ib.Spec.Output, specPath.Child("output"))...)
+	if ib.Spec.Signing != nil && ib.Spec.Output.Registry == nil {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("signing"), "requires a registry output, as only pushed images are signed"))
+	}
+	allErrs = append(allErrs, validateRegistryOnlyOutput(&ib.Spec.Output, specPath.Child("output"))...)
+	allErrs = append(allErrs, validateDiskImageOptions(&ib.Spec.Output, specPath.Child("output"))...)
+	allErrs = append(allErrs, validateBuilderEnv(ib.Spec.BuilderEnv, specPath.Child("builderEnv"))...)
+	if ib.Spec.Build != nil {
+		allErrs = append(allErrs, validateSecretFiles(ib.Spec.Build.SecretFiles, specPath.Child("build", "secretFiles"))...)
+		allErrs = append(allErrs, validateInitContainers(ib.Spec.Build.InitContainers, specPath.Child("build", "initContainers"))...)
+	}
+	if ib.Spec.PodMetadata != nil {
+		allErrs = append(allErrs, validatePodMetadata(ib.Spec.PodMetadata, specPath.Child("podMetadata"))...)
+	}
+	if build := ib.Spec.Build; build != nil && build.HostUsers != nil && !*build.HostUsers && build.HostNetwork {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("build", "hostUsers"),
+			"a user namespace cannot be used with hostNetwork"))
+	}
+	if build := ib.Spec.Build; build != nil && build.WorkVolume != nil && build.WorkVolume.Size.Sign() <= 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("build", "workVolume", "size"), build.WorkVolume.Size.String(),
+			"must be greater than zero"))
+	}
+	if build := ib.Spec.Build; build != nil && build.MinFreeDiskSpace != nil {
+		minPath := specPath.Child("build", "minFreeDiskSpace")
+		if build.MinFreeDiskSpace.Sign() < 0 {
+			allErrs = append(allErrs, field.Invalid(minPath, build.MinFreeDiskSpace.String(), "must not be negative"))
+		} else if build.WorkVolume != nil && build.MinFreeDiskSpace.Cmp(build.WorkVolume.Size) > 0 {
+			allErrs = append(allErrs, field.Invalid(minPath, build.MinFreeDiskSpace.String(),
+				fmt.Sprintf("must not exceed the work volume size %s", build.WorkVolume.Size.String())))
+		}
+	}
+	if ib.Spec.Build != nil && ib.Spec.Build.SecurityProfile != nil {
+		profilePath := specPath.Child("build", "securityProfile")
+		if seccomp := ib.Spec.Build.SecurityProfile.SeccompProfile; seccomp != nil {
+			seccompPath := profilePath.Child("seccompProfile")
+			allErrs = append(allErrs, validateProfile(seccomp, seccompPath)...)
+			if seccomp.LocalhostProfile != "" {
+				allErrs = append(allErrs, validateSubPath(seccomp.LocalhostProfile, seccompPath.Child("localhostProfile"))...)
+			}
+		}
+		if appArmor := ib.Spec.Build.SecurityProfile.AppArmorProfile; appArmor != nil {
+			allErrs = append(allErrs, validateProfile(appArmor, profilePath.Child("appArmorProfile"))...)
+		}
+	}
+	return allErrs
+}
+
+// validateProfile checks the profile type and that a localhost profile is named exactly when required.
+func validateProfile(profile *Profile, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	switch profile.Type {
+	case ProfileTypeLocalhost:
+		if profile.LocalhostProfile == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("localhostProfile"), "must be set for a Localhost profile"))
+		}
+	case ProfileTypeRuntimeDefault:
+		if profile.LocalhostProfile != "" {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("localhostProfile"), "may only be set for a Localhost profile"))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("type"), profile.Type,
+			[]ProfileType{ProfileTypeRuntimeDefault, ProfileTypeLocalhost}))
+	}
+	return allErrs
+}
+
+// validateImageReference checks that ref can be parsed as an image reference by parse.
+func validateImageReference(ref string, parse func(string) (imageref.Reference, error), fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if _, err := parse(ref); err != nil {
+		// The field error already names the value, so only report the cause.
+		var refErr *imageref.Error
+		if errors.As(err, &refErr) {
+			err = refErr.Err
+		}
+		allErrs = append(allErrs, field.Invalid(fldPath, ref, err.Error()))
+	}
+	return allErrs
+}
+
+// validateOutputDestinations checks that an output is set and that none of the output destinations
+// lacks the fields identifying it, e.g. an empty "registry: {}", which would otherwise only fail
+// once the builder pod runs.
+func validateOutputDestinations(output *OutputSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if output.PVC == nil && output.Volume == nil && output.ObjectStorage == nil && output.Registry == nil {
+		allErrs = append(allErrs, field.Required(fldPath, "at least one of pvc, volume, objectStorage or registry must be set"))
+	}
+	if pvc := output.PVC; pvc != nil && pvc.Name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("pvc", "name"), "must name the PersistentVolumeClaim to write to"))
+	}
+	if volume := output.Volume; volume != nil {
+		volumePath := fldPath.Child("volume")
+		switch {
+		case volume.NFS == nil && volume.CSI == nil:
+			allErrs = append(allErrs, field.Required(volumePath, "exactly one of nfs or csi must be set"))
+		case volume.NFS != nil:
+			if volume.NFS.Server == "" {
+				allErrs = append(allErrs, field.Required(volumePath.Child("nfs", "server"), "must name the NFS server"))
+			}
+			if volume.NFS.Path == "" {
+				allErrs = append(allErrs, field.Required(volumePath.Child("nfs", "path"), "must name the exported path"))
+			}
+		case volume.CSI.Driver == "":
+			allErrs = append(allErrs, field.Required(volumePath.Child("csi", "driver"), "must name the CSI driver"))
+		}
+	}
+	if objectStorage := output.ObjectStorage; objectStorage != nil {
+		if objectStorage.Bucket == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("objectStorage", "bucket"), "must name the bucket to upload to"))
+		}
+		if objectStorage.CredentialsSecretName == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("objectStorage", "credentialsSecretName"),
+				"must name the Secret with the bucket credentials"))
+		}
+	}
+	if registry := output.Registry; registry != nil {
+		if registry.Destination == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("registry", "destination"), "must name the image to push"))
+		}
+		if registry.PullSecretName == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("registry", "pullSecretName"),
+				"must name the Secret with the registry credentials"))
+		}
+	}
+	return allErrs
+}
+
+// validateRegistryOnlyOutput rejects disk image settings when the registry is the only output.
+// The builder then only pushes the container image, so nothing would keep the files.
+func validateRegistryOnlyOutput(output *OutputSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if output.Registry == nil || output.PVC != nil || output.Volume != nil || output.ObjectStorage != nil {
+		return allErrs
+	}
+	if output.Filesystem != "" {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("filesystem"), "requires a pvc, volume or objectStorage output"))
+	}
+	if output.Partitioning != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("partitioning"), "requires a pvc, volume or objectStorage output"))
+	}
+	if output.DiskLayout != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("diskLayout"), "requires a pvc, volume or objectStorage output"))
+	}
+	return allErrs
+}
+
+// validateRegistryOnlyFormats rejects file artifact formats when the registry is the only output,
+// unless they are unchanged from oldOutput. The formats used to default to tgz and qcow2 for any
+// output, so stored ImageBuilds may have them; the builder ignores them there.
+func validateRegistryOnlyFormats(output, oldOutput *OutputSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if output.Registry == nil || output.PVC != nil || output.Volume != nil || output.ObjectStorage != nil {
+		return allErrs
+	}
+	if oldOutput == nil || !slices.Equal(output.Formats, oldOutput.Formats) {
+		for i, format := range output.Formats {
+			msg := "rootfs archives are not pushed to a registry; add a pvc, volume or objectStorage output to keep them"
+			if format == FormatQCOW2 {
+				msg = "disk images cannot be pushed to a registry; add a pvc, volume or objectStorage output to keep them"
+			}
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("formats").Index(i), msg))
+		}
+	}
+	if output.QCOW2 != nil && (oldOutput == nil || !reflect.DeepEqual(output.QCOW2, oldOutput.QCOW2)) {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("qcow2"), "requires a pvc, volume or objectStorage output"))
+	}
+	return allErrs
+}
+
+// validateDiskImageOptions checks that the disk image filesystem is supported and that a disk image
+// is produced to use it and the partition layout.
+func validateDiskImageOptions(output *OutputSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if output.Filesystem != "" && !slices.Contains(filesystems, output.Filesystem) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("filesystem"), output.Filesystem, filesystems))
+	}
+	if qcow2 := output.QCOW2; qcow2 != nil && qcow2.Preallocation != "" {
+		preallocationPath := fldPath.Child("qcow2", "preallocation")
+		switch {
+		case !slices.Contains(qcow2Preallocations, qcow2.Preallocation):
+			allErrs = append(allErrs, field.NotSupported(preallocationPath, qcow2.Preallocation, qcow2Preallocations))
+		case qcow2.Compress && qcow2.Preallocation != QCOW2PreallocationOff:
+			allErrs = append(allErrs, field.Forbidden(preallocationPath, "a compressed image cannot be preallocated"))
+		}
+	}
+	if len(output.Formats) == 0 || slices.Contains(output.Formats, FormatQCOW2) {
+		return allErrs
+	}
+	if output.Filesystem != "" {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("filesystem"), "only applies to disk images; add the qcow2 format"))
+	}
+	if output.Partitioning != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("partitioning"), "only applies to disk images; add the qcow2 format"))
+	}
+	if output.DiskLayout != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("diskLayout"), "only applies to disk images; add the qcow2 format"))
+	}
+	return allErrs
+}
+
+// validateDiskLayout checks that the partitions of a custom disk layout can be created and mounted:
+// exactly one of them is mounted at "/", the mount points are unique, and they fit the disk.
+func validateDiskLayout(output *OutputSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	layout := output.DiskLayout
+	if layout == nil {
+		return allErrs
+	}
+	if output.Filesystem != "" {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("filesystem"), "cannot be combined with diskLayout, set the filesystem of each partition instead"))
+	}
+	if output.Partitioning != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("partitioning"), "cannot be combined with diskLayout, which sets the partition table"))
+	}
+	layoutPath := fldPath.Child("diskLayout")
+	partitionsPath := layoutPath.Child("partitions")
+	if len(layout.Partitions) == 0 {
+		return append(allErrs, field.Required(partitionsPath, "must list the partitions of the disk"))
+	}
+	if layout.Table == PartitionTableMBR && len(layout.Partitions) > mbrMaxPartitions {
+		allErrs = append(allErrs, field.TooMany(partitionsPath, len(layout.Partitions), mbrMaxPartitions))
+	}
+
+	var partitionBytes int64
+	mountPoints := map[string]bool{}
+	for i, partition := range layout.Partitions {
+		partitionPath := partitionsPath.Index(i)
+		if !slices.Contains(partitionFilesystems, partition.Filesystem) {
+			allErrs = append(allErrs, field.NotSupported(partitionPath.Child("filesystem"), partition.Filesystem, partitionFilesystems))
+		}
+		switch {
+		case partition.Size == nil && i < len(layout.Partitions)-1:
+			allErrs = append(allErrs, field.Required(partitionPath.Child("size"), "only the last partition may take the rest of the disk"))
+		case partition.Size != nil && partition.Size.Sign() <= 0:
+			allErrs = append(allErrs, field.Invalid(partitionPath.Child("size"), partition.Size.String(), "must be greater than zero"))
+		case partition.Size != nil:
+			partitionBytes += roundUpMiB(partition.Size.Value())
+		}
+
+		mountPath := partitionPath.Child("mountPoint")
+		switch {
+		case partition.Filesystem == PartitionFilesystemSwap:
+			if partition.MountPoint != "" {
+				allErrs = append(allErrs, field.Forbidden(mountPath, "swap partitions are not mounted"))
+			}
+		case partition.MountPoint == "":
+			allErrs = append(allErrs, field.Required(mountPath, "must be set for a filesystem"))
+		case !path.IsAbs(partition.MountPoint) || path.Clean(partition.MountPoint) != partition.MountPoint:
+			allErrs = append(allErrs, field.Invalid(mountPath, partition.MountPoint, "must be a clean absolute path"))
+		case mountPoints[partition.MountPoint]:
+			allErrs = append(allErrs, field.Duplicate(mountPath, partition.MountPoint))
+		case partition.MountPoint == "/" && partition.Filesystem == PartitionFilesystemVFAT:
+			allErrs = append(allErrs, field.Invalid(partitionPath.Child("filesystem"), partition.Filesystem,
+				"the root filesystem must support Linux file ownership and permissions"))
+		}
+		if partition.MountPoint != "" {
+			mountPoints[partition.MountPoint] = true
+		}
+	}
+	if !mountPoints["/"] {
+		allErrs = append(allErrs, field.Required(partitionsPath, `one partition must be mounted at "/"`))
+	}
+
+	if layout.Size != nil {
+		sizePath := layoutPath.Child("size")
+		required := partitionBytes + diskLayoutReservedBytes
+		if layout.Partitions[len(layout.Partitions)-1].Size == nil {
+			// The last partition takes the rest of the disk, which must not be empty.
+			required++
+		}
+		switch {
+		case layout.Size.Sign() <= 0:
+			allErrs = append(allErrs, field.Invalid(sizePath, layout.Size.String(), "must be greater than zero"))
+		case roundUpMiB(layout.Size.Value()) < required:
+			allErrs = append(allErrs, field.Invalid(sizePath, layout.Size.String(),
+				fmt.Sprintf("must hold the partitions, which take %s including 2Mi for the partition table",
+					resource.NewQuantity(partitionBytes+diskLayoutReservedBytes, resource.BinarySI).String())))
+		}
+	}
+	return allErrs
+}
+
+// roundUpMiB rounds bytes up to a whole MiB, as the builder sizes the disk and its partitions in MiB.
+func roundUpMiB(bytes int64) int64 {
+	return (bytes + 1<<20 - 1) &^ (1<<20 - 1)
+}
+
+// hasEFISystemPartition returns true if the layout has a partition UEFI firmware boots from, a vfat
+// partition mounted at /boot/efi of a GPT.
+func (layout *DiskLayout) hasEFISystemPartition() bool {
+	if layout.Table == PartitionTableMBR {
+		return false
+	}
+	return slices.ContainsFunc(layout.Partitions, func(partition DiskPartition) bool {
+		return partition.MountPoint == efiMountPoint && partition.Filesystem == PartitionFilesystemVFAT
+	})
+}
+
+// validateRepositoryCreation checks that a registry output that creates its repository pushes to a
+// registry the builder knows how to create repositories in, with the credentials it needs.
+func validateRepositoryCreation(registry *RegistryOutput, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if !registry.CreateRepository {
+		if registry.RepositoryCredentialsSecretName != "" {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("repositoryCredentialsSecretName"), "requires createRepository"))
+		}
+		return allErrs
+	}
+	ref, err := imageref.ParseDestination(registry.Destination)
+	if err != nil {
+		// Reported on the destination.
+		return allErrs
+	}
+	repo, ok := ref.CloudRepository()
+	if !ok {
+		return append(allErrs, field.Invalid(fldPath.Child("createRepository"), registry.CreateRepository,
+			fmt.Sprintf("repositories can only be created in Amazon ECR and Google Artifact Registry, not %s", ref.Domain)))
+	}
+	if repo.Provider == imageref.ProviderECR && registry.RepositoryCredentialsSecretName == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("repositoryCredentialsSecretName"),
+			"AWS credentials are required to create an ECR repository"))
+	}
+	return allErrs
+}
+
+// validateAdditionalDestinations checks that the additional destinations of a registry output are
+// valid image references other than its destination.
+func validateAdditionalDestinations(registry *RegistryOutput, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, destination := range registry.AdditionalDestinations {
+		destinationPath := fldPath.Index(i).Child("destination")
+		if destination.Destination == "" {
+			allErrs = append(allErrs, field.Required(destinationPath, "must name the image to push"))
+			continue
+		}
+		allErrs = append(allErrs, validateImageReference(destination.Destination, imageref.ParseDestination, destinationPath)...)
+		if destination.Destination == registry.Destination {
+			allErrs = append(allErrs, field.Duplicate(destinationPath, destination.Destination))
+		}
+	}
+	return allErrs
+}
+
+// validateImageConfig checks the environment variables, ports and labels set on the config of a pushed image.
+func validateImageConfig(config *ImageConfig, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	names := map[string]bool{}
+	for i, env := range config.Env {
+		namePath := fldPath.Child("env").Index(i).Child("name")
+		switch {
+		case env.Name == "":
+			allErrs = append(allErrs, field.Required(namePath, "must name the variable"))
+		case strings.ContainsAny(env.Name, "= \t\n"):
+			allErrs = append(allErrs, field.Invalid(namePath, env.Name, "must not contain '=' or whitespace"))
+		case names[env.Name]:
+			allErrs = append(allErrs, field.Duplicate(namePath, env.Name))
+		}
+		names[env.Name] = true
+	}
+	ports := map[string]bool{}
+	for i, port := range config.ExposedPorts {
+		portPath := fldPath.Child("exposedPorts").Index(i)
+		number, protocol, _ := strings.Cut(port, "/")
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		if n, err := strconv.Atoi(number); err != nil || n < 1 || n > 65535 || !slices.Contains([]string{"tcp", "udp", "sctp"}, protocol) {
+			allErrs = append(allErrs, field.Invalid(portPath, port,
+				`must be "<port>" or "<port>/<protocol>", with a port between 1 and 65535 and a protocol of tcp, udp or sctp`))
+			continue
+		}
+		// "8080" and "8080/tcp" expose the same port.
+		if key := number + "/" + protocol; ports[key] {
+			allErrs = append(allErrs, field.Duplicate(portPath, port))
+		} else {
+			ports[key] = true
+		}
+	}
+	for _, key := range sortedKeys(config.Labels) {
+		if key == "" || strings.ContainsAny(key, "=\n") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("labels").Key(key), key, "must be non-empty and must not contain '=' or newlines"))
+		}
+	}
+	return allErrs
+}
+
+// ansibleInterpreterDiscoveryModes are the values of ansible_python_interpreter that let Ansible
+// discover the interpreter rather than naming one.
+var ansibleInterpreterDiscoveryModes = []string{"auto", "auto_legacy", "auto_silent", "auto_legacy_silent"}
+
+// validateAnsiblePython checks the interpreter and virtual environment settings of an Ansible run.
+func validateAnsiblePython(ansible *AnsibleSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if interpreter := ansible.PythonInterpreter; interpreter != "" &&
+		!path.IsAbs(interpreter) && !slices.Contains(ansibleInterpreterDiscoveryModes, interpreter) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("pythonInterpreter"), interpreter,
+			"must be an absolute path or one of "+strings.Join(ansibleInterpreterDiscoveryModes, ", ")))
+	}
+	if venv := ansible.Virtualenv; venv != nil {
+		venvPath := fldPath.Child("virtualenv")
+		if !path.IsAbs(venv.Path) || path.Clean(venv.Path) == "/" {
+			allErrs = append(allErrs, field.Invalid(venvPath.Child("path"), venv.Path, "must be an absolute path below /"))
+		}
+		for i, pkg := range venv.Packages {
+			if pkg == "" || strings.HasPrefix(pkg, "-") || strings.ContainsAny(pkg, " \t\n") {
+				allErrs = append(allErrs, field.Invalid(venvPath.Child("packages").Index(i), pkg,
+					"must be a pip requirement specifier, not an option"))
+			}
+		}
+	}
+	return allErrs
+}
+
+// ansibleConnections are the connection plugins an Ansible run can use from the builder.
+var ansibleConnections = []string{"local", "chroot", "community.general.chroot", "ssh", "paramiko_ssh"}
+
+// validateAnsibleConnection checks the connection plugin of an Ansible run.
+func validateAnsibleConnection(ansible *AnsibleSpec, fldPath *field.Path) field.ErrorList {
+	if ansible.Connection == "" || slices.Contains(ansibleConnections, ansible.Connection) {
+		return nil
+	}
+	return field.ErrorList{field.NotSupported(fldPath.Child("connection"), ansible.Connection, ansibleConnections)}
+}
+
+// validateGitSubmodules checks the credentials of the submodules checked out along with a Git repository.
+// Their URLs are passed to the builder one per line, followed by the directory of the Secret.
+func validateGitSubmodules(submodules bool, credentials []GitSubmoduleCredentials, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	credentialsPath := fldPath.Child("submoduleCredentials")
+	if len(credentials) > 0 && !submodules {
+		allErrs = append(allErrs, field.Forbidden(credentialsPath, "requires submodules"))
+	}
+	seen := map[string]bool{}
+	for i, creds := range credentials {
+		switch {
+		case creds.URL == "":
+			allErrs = append(allErrs, field.Required(credentialsPath.Index(i).Child("url"), "must match the remote URL of submodules"))
+		case strings.ContainsAny(creds.URL, " \t\n"):
+			allErrs = append(allErrs, field.Invalid(credentialsPath.Index(i).Child("url"), creds.URL, "must not contain whitespace"))
+		case seen[creds.URL]:
+			allErrs = append(allErrs, field.Duplicate(credentialsPath.Index(i).Child("url"), creds.URL))
+		}
+		seen[creds.URL] = true
+		if creds.SecretName == "" {
+			allErrs = append(allErrs, field.Required(credentialsPath.Index(i).Child("secretName"), "must name the credentials Secret"))
+		}
+	}
+	return allErrs
+}
+
+// validateSubPath checks that subPath stays within the volume it is mounted from.
+func validateSubPath(subPath string, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if path.IsAbs(subPath) {
+		allErrs = append(allErrs, field.Invalid(fldPath, subPath, "must be a relative path"))
+	}
+	for _, element := range strings.Split(subPath, "/") {
+		if element == ".." {
+			allErrs = append(allErrs, field.Invalid(fldPath, subPath, "must not contain '..'"))
+			break
+		}
+	}
+	return allErrs
+}
+
+// validateDockerfile checks that the Containerfile path stays within the build context, and names a
+// key of the ConfigMap holding it.
+func validateDockerfile(dockerfile *DockerfileSpec, fldPath *field.Path) field.ErrorList {
+	if dockerfile.Path == "" {
+		return nil
+	}
+	pathPath := fldPath.Child("path")
+	if dockerfile.ConfigMapRef == nil {
+		return validateSubPath(dockerfile.Path, pathPath)
+	}
+	var allErrs field.ErrorList
+	for _, msg := range validation.IsConfigMapKey(dockerfile.Path) {
+		allErrs = append(allErrs, field.Invalid(pathPath, dockerfile.Path, msg))
+	}
+	return allErrs
+}
+
+// validateKickstart checks that the kickstart path stays within the repo, names a key of the
+// ConfigMap holding it, and is not set for an inline kickstart.
+func validateKickstart(kickstart *KickstartSpec, fldPath *field.Path) field.ErrorList {
+	if kickstart.Path == "" {
+		return nil
+	}
+	pathPath := fldPath.Child("path")
+	switch {
+	case kickstart.Inline != "":
+		return field.ErrorList{field.Forbidden(pathPath, "cannot be used with inline")}
+	case kickstart.ConfigMapRef == nil:
+		return validateSubPath(kickstart.Path, pathPath)
+	}
+	var allErrs field.ErrorList
+	for _, msg := range validation.IsConfigMapKey(kickstart.Path) {
+		allErrs = append(allErrs, field.Invalid(pathPath, kickstart.Path, msg))
+	}
+	return allErrs
+}
+
+// validateBootc checks the options of a bootc provisioner, and that the disk image layout is left
+// to `bootc install`.
+func validateBootc(bootc *BootcSpec, output *OutputSpec, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	fldPath := specPath.Child("provisioner", "bootc")
+	if bootc.Containerfile != nil {
+		allErrs = append(allErrs, validateDockerfile(bootc.Containerfile, fldPath.Child("containerfile"))...)
+	}
+	if bootc.TargetImage != "" {
+		allErrs = append(allErrs, validateImageReference(bootc.TargetImage, imageref.Parse, fldPath.Child("targetImage"))...)
+	}
+	for i, arg := range bootc.KernelArgs {
+		if strings.TrimSpace(arg) == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("kernelArgs").Index(i), "must not be empty"))
+		}
+	}
+	if output.Partitioning != nil {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("output", "partitioning"), "bootc images are installed with the partition layout of bootc"))
+	}
+	if output.DiskLayout != nil {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("output", "diskLayout"), "bootc images are installed with the partition layout of bootc"))
+	}
+	return allErrs
+}
+
+// validatePostSteps checks that every post step has a command to run.
+func validatePostSteps(steps []string, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, step := range steps {
+		if strings.TrimSpace(step) == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Index(i), "must not be empty"))
+		}
+	}
+	return allErrs
+}
+
+// validateSecretFiles checks that the secret files have unique paths naming a file outside the
+// reserved builder directories, and keys a Secret can have.
+func validateSecretFiles(secretFiles []SecretFileMount, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	seen := map[string]bool{}
+	for i, secretFile := range secretFiles {
+		for _, msg := range validation.IsConfigMapKey(secretFile.Key) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i).Child("key"), secretFile.Key, msg))
+		}
+		pathPath := fldPath.Index(i).Child("path")
+		switch {
+		case strings.HasSuffix(secretFile.Path, "/") || path.Clean(secretFile.Path) != secretFile.Path:
+			allErrs = append(allErrs, field.Invalid(pathPath, secretFile.Path, "must be a clean absolute path to a file"))
+		case reservedBuilderPath(secretFile.Path) != "":
+			allErrs = append(allErrs, field.Invalid(pathPath, secretFile.Path,
+				fmt.Sprintf("must not be in %s, which the builder uses", reservedBuilderPath(secretFile.Path))))
+		case seen[secretFile.Path]:
+			allErrs = append(allErrs, field.Duplicate(pathPath, secretFile.Path))
+		}
+		seen[secretFile.Path] = true
+	}
+	return allErrs
+}
+
+// validateInitContainers checks that the init containers have unique container names and an image.
+// Whether their volume mounts name volumes of the builder pod is checked when the pod is created.
+func validateInitContainers(containers []corev1.Container, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	seen := map[string]bool{}
+	for i, container := range containers {
+		namePath := fldPath.Index(i).Child("name")
+		for _, msg := range validation.IsDNS1123Label(container.Name) {
+			allErrs = append(allErrs, field.Invalid(namePath, container.Name, msg))
+		}
+		if seen[container.Name] {
+			allErrs = append(allErrs, field.Duplicate(namePath, container.Name))
+		}
+		seen[container.Name] = true
+		if slices.Contains(reservedContainerNames, container.Name) {
+			allErrs = append(allErrs, field.Invalid(namePath, container.Name, "is used by a container of the operator"))
+		}
+		if strings.TrimSpace(container.Image) == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Index(i).Child("image"), "must name the image of the init container"))
+		}
+	}
+	return allErrs
+}
+
+// validateBuilderEnv checks that the builder env var names are valid and unique.
+func validateBuilderEnv(env []corev1.EnvVar, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	seen := map[string]bool{}
+	for i, envVar := range env {
+		namePath := fldPath.Index(i).Child("name")
+		for _, msg := range validation.IsEnvVarName(envVar.Name) {
+			allErrs = append(allErrs, field.Invalid(namePath, envVar.Name, msg))
+		}
+		if seen[envVar.Name] {
+			allErrs = append(allErrs, field.Duplicate(namePath, envVar.Name))
+		}
+		seen[envVar.Name] = true
+	}
+	return allErrs
+}
+
+// validatePodMetadata checks the syntax of the pod labels and annotations and rejects
+// labels that would collide with the ones the operator selects its pods by.
+func validatePodMetadata(podMetadata *PodMetadata, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	labelsPath := fldPath.Child("labels")
+	allErrs = append(allErrs, metav1validation.ValidateLabels(podMetadata.Labels, labelsPath)...)
+	for _, key := range sortedKeys(podMetadata.Labels) {
+		if key == ImageBuildNameLabel || key == ComponentLabel {
+			allErrs = append(allErrs, field.Forbidden(labelsPath.Key(key), "label is managed by the operator"))
+		}
+	}
+	allErrs = append(allErrs, apivalidation.ValidateAnnotations(podMetadata.Annotations, fldPath.Child("annotations"))...)
+	return allErrs
+}
+
+// validateAWSAssumeRole checks the role ARN format and that an external ID comes with a role.
+func validateAWSAssumeRole(role *AWSAssumeRole, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if role.RoleARN != "" && !roleARNRegexp.MatchString(role.RoleARN) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("roleARN"), role.RoleARN,
+			"must be an IAM role ARN, e.g. arn:aws:iam::111122223333:role/name"))
+	}
+	if role.ExternalID != "" && role.RoleARN == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("roleARN"), "must be set when externalID is set"))
+	}
+	return allErrs
+}
+
+// validateContentType checks that a content type override is a "type/subtype" MIME type, optionally
+// with parameters, e.g. "text/plain; charset=utf-8".
+func validateContentType(contentType string, fldPath *field.Path) field.ErrorList {
+	if contentType == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && !strings.Contains(mediaType, "/") {
+		err = errors.New("expected a type/subtype media type")
+	}
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, contentType, fmt.Sprintf("must be a MIME type: %v", err))}
+	}
+	return nil
+}
+
+// validateAWSArchitecture checks that the build architecture can be imported as an AMI and,
+// if an AMI architecture is set explicitly, that it matches the build.
+func validateAWSArchitecture(arch, amiArch string, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	expected, ok := AMIArchitecture(arch)
+	if !ok {
+		return append(allErrs, field.NotSupported(specPath.Child("arch"), arch, amiArchitectures()))
+	}
+	if amiArch != "" && amiArch != expected {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("publish", "aws", "architecture"), amiArch,
+			fmt.Sprintf("must be %q to match spec.arch %q", expected, arch)))
+	}
+	return allErrs
+}
+
+// validateAWSTags checks tags against the AWS tagging limits.
+func validateAWSTags(tags map[string]string, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if len(tags) > awsMaxTags {
+		allErrs = append(allErrs, field.TooMany(fldPath, len(tags), awsMaxTags))
+	}
+
+	// Iterate in a stable order so the reported errors are deterministic.
+	for _, key := range sortedKeys(tags) {
+		keyLen := utf8.RuneCountInString(key)
+		if keyLen == 0 || keyLen > awsMaxTagKeyLength {
+			allErrs = append(allErrs, field.Invalid(fldPath, key, "tag keys must be between 1 and 128 characters"))
+		}
+		if strings.HasPrefix(strings.ToLower(key), awsReservedTagPrefix) {
+			allErrs = append(allErrs, field.Invalid(fldPath, key, "tag keys must not use the reserved \"aws:\" prefix"))
+		}
+		if utf8.RuneCountInString(tags[key]) > awsMaxTagValueLength {
+			allErrs = append(allErrs, field.TooLong(fldPath.Key(key), tags[key], awsMaxTagValueLength))
+		}
+	}
+	return allErrs
+}
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -21,14 +21,74 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSAssumeRole) DeepCopyInto(out *AWSAssumeRole) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSAssumeRole.
+func (in *AWSAssumeRole) DeepCopy() *AWSAssumeRole {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSAssumeRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSLaunchPermissions) DeepCopyInto(out *AWSLaunchPermissions) {
+	*out = *in
+	if in.AccountIDs != nil {
+		in, out := &in.AccountIDs, &out.AccountIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSLaunchPermissions.
+func (in *AWSLaunchPermissions) DeepCopy() *AWSLaunchPermissions {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSLaunchPermissions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AWSPublishSpec) DeepCopyInto(out *AWSPublishSpec) {
 	*out = *in
+	out.AWSAssumeRole = in.AWSAssumeRole
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.EnaSupport != nil {
+		in, out := &in.EnaSupport, &out.EnaSupport
+		*out = new(bool)
+		**out = **in
+	}
+	if in.SriovNetSupport != nil {
+		in, out := &in.SriovNetSupport, &out.SriovNetSupport
+		*out = new(bool)
+		**out = **in
+	}
+	if in.LaunchPermissions != nil {
+		in, out := &in.LaunchPermissions, &out.LaunchPermissions
+		*out = new(AWSLaunchPermissions)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSPublishSpec.
@@ -44,11 +104,21 @@ func (in *AWSPublishSpec) DeepCopy() *AWSPublishSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AnsibleSpec) DeepCopyInto(out *AnsibleSpec) {
 	*out = *in
+	if in.SubmoduleCredentials != nil {
+		in, out := &in.SubmoduleCredentials, &out.SubmoduleCredentials
+		*out = make([]GitSubmoduleCredentials, len(*in))
+		copy(*out, *in)
+	}
 	if in.ExtraVars != nil {
 		in, out := &in.ExtraVars, &out.ExtraVars
 		*out = new(v1.JSON)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Virtualenv != nil {
+		in, out := &in.Virtualenv, &out.Virtualenv
+		*out = new(AnsibleVirtualenv)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AnsibleSpec.
@@ -62,100 +132,177 @@ func (in *AnsibleSpec) DeepCopy() *AnsibleSpec {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageBuild) DeepCopyInto(out *ImageBuild) {
+func (in *AnsibleVirtualenv) DeepCopyInto(out *AnsibleVirtualenv) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	if in.Packages != nil {
+		in, out := &in.Packages, &out.Packages
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBuild.
-func (in *ImageBuild) DeepCopy() *ImageBuild {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AnsibleVirtualenv.
+func (in *AnsibleVirtualenv) DeepCopy() *AnsibleVirtualenv {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageBuild)
+	out := new(AnsibleVirtualenv)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ImageBuild) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArtifactStatus) DeepCopyInto(out *ArtifactStatus) {
+	*out = *in
+	if in.VirtualSizeBytes != nil {
+		in, out := &in.VirtualSizeBytes, &out.VirtualSizeBytes
+		*out = new(int64)
+		**out = **in
 	}
-	return nil
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArtifactStatus.
+func (in *ArtifactStatus) DeepCopy() *ArtifactStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ArtifactStatus)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageBuildList) DeepCopyInto(out *ImageBuildList) {
+func (in *BootcSpec) DeepCopyInto(out *BootcSpec) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]ImageBuild, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.Containerfile != nil {
+		in, out := &in.Containerfile, &out.Containerfile
+		*out = new(DockerfileSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KernelArgs != nil {
+		in, out := &in.KernelArgs, &out.KernelArgs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBuildList.
-func (in *ImageBuildList) DeepCopy() *ImageBuildList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootcSpec.
+func (in *BootcSpec) DeepCopy() *BootcSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageBuildList)
+	out := new(BootcSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ImageBuildList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildCache) DeepCopyInto(out *BuildCache) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildCache.
+func (in *BuildCache) DeepCopy() *BuildCache {
+	if in == nil {
+		return nil
 	}
-	return nil
+	out := new(BuildCache)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageBuildSpec) DeepCopyInto(out *ImageBuildSpec) {
+func (in *BuildSpec) DeepCopyInto(out *BuildSpec) {
 	*out = *in
-	if in.Provisioner != nil {
-		in, out := &in.Provisioner, &out.Provisioner
-		*out = new(ProvisionerSpec)
+	if in.LogsOutput != nil {
+		in, out := &in.LogsOutput, &out.LogsOutput
+		*out = new(ObjectStorageOutput)
+		**out = **in
+	}
+	if in.HostUsers != nil {
+		in, out := &in.HostUsers, &out.HostUsers
+		*out = new(bool)
+		**out = **in
+	}
+	if in.FSGroup != nil {
+		in, out := &in.FSGroup, &out.FSGroup
+		*out = new(int64)
+		**out = **in
+	}
+	if in.SupplementalGroups != nil {
+		in, out := &in.SupplementalGroups, &out.SupplementalGroups
+		*out = make([]int64, len(*in))
+		copy(*out, *in)
+	}
+	if in.EnvFrom != nil {
+		in, out := &in.EnvFrom, &out.EnvFrom
+		*out = make([]corev1.EnvFromSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.WorkVolume != nil {
+		in, out := &in.WorkVolume, &out.WorkVolume
+		*out = new(WorkVolume)
 		(*in).DeepCopyInto(*out)
 	}
-	in.Output.DeepCopyInto(&out.Output)
-	if in.Publish != nil {
-		in, out := &in.Publish, &out.Publish
-		*out = new(PublishSpec)
+	if in.MinFreeDiskSpace != nil {
+		in, out := &in.MinFreeDiskSpace, &out.MinFreeDiskSpace
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Cache != nil {
+		in, out := &in.Cache, &out.Cache
+		*out = new(BuildCache)
+		**out = **in
+	}
+	if in.PackageMirrors != nil {
+		in, out := &in.PackageMirrors, &out.PackageMirrors
+		*out = new(PackageMirrors)
+		**out = **in
+	}
+	if in.GitSSH != nil {
+		in, out := &in.GitSSH, &out.GitSSH
+		*out = new(GitSSH)
+		**out = **in
+	}
+	if in.SecurityProfile != nil {
+		in, out := &in.SecurityProfile, &out.SecurityProfile
+		*out = new(SecurityProfile)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Lifecycle != nil {
+		in, out := &in.Lifecycle, &out.Lifecycle
+		*out = new(BuilderLifecycle)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.SecretFiles != nil {
+		in, out := &in.SecretFiles, &out.SecretFiles
+		*out = make([]SecretFileMount, len(*in))
+		copy(*out, *in)
+	}
+	if in.InitContainers != nil {
+		in, out := &in.InitContainers, &out.InitContainers
+		*out = make([]corev1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBuildSpec.
-func (in *ImageBuildSpec) DeepCopy() *ImageBuildSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildSpec.
+func (in *BuildSpec) DeepCopy() *BuildSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageBuildSpec)
+	out := new(BuildSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageBuildStatus) DeepCopyInto(out *ImageBuildStatus) {
+func (in *BuildStageStatus) DeepCopyInto(out *BuildStageStatus) {
 	*out = *in
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make(v1beta1.Conditions, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 	if in.StartTime != nil {
 		in, out := &in.StartTime, &out.StartTime
 		*out = (*in).DeepCopy()
@@ -166,166 +313,901 @@ func (in *ImageBuildStatus) DeepCopyInto(out *ImageBuildStatus) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBuildStatus.
-func (in *ImageBuildStatus) DeepCopy() *ImageBuildStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildStageStatus.
+func (in *BuildStageStatus) DeepCopy() *BuildStageStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageBuildStatus)
+	out := new(BuildStageStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *MaaSPublishSpec) DeepCopyInto(out *MaaSPublishSpec) {
+func (in *BuilderLifecycle) DeepCopyInto(out *BuilderLifecycle) {
 	*out = *in
+	if in.PreStop != nil {
+		in, out := &in.PreStop, &out.PreStop
+		*out = new(corev1.LifecycleHandler)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaaSPublishSpec.
-func (in *MaaSPublishSpec) DeepCopy() *MaaSPublishSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuilderLifecycle.
+func (in *BuilderLifecycle) DeepCopy() *BuilderLifecycle {
 	if in == nil {
 		return nil
 	}
-	out := new(MaaSPublishSpec)
+	out := new(BuilderLifecycle)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ObjectStorageOutput) DeepCopyInto(out *ObjectStorageOutput) {
+func (in *DiskLayout) DeepCopyInto(out *DiskLayout) {
 	*out = *in
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Partitions != nil {
+		in, out := &in.Partitions, &out.Partitions
+		*out = make([]DiskPartition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStorageOutput.
-func (in *ObjectStorageOutput) DeepCopy() *ObjectStorageOutput {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskLayout.
+func (in *DiskLayout) DeepCopy() *DiskLayout {
 	if in == nil {
 		return nil
 	}
-	out := new(ObjectStorageOutput)
+	out := new(DiskLayout)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OutputSpec) DeepCopyInto(out *OutputSpec) {
+func (in *DiskPartition) DeepCopyInto(out *DiskPartition) {
 	*out = *in
-	if in.PVC != nil {
-		in, out := &in.PVC, &out.PVC
-		*out = new(PVCOutput)
-		**out = **in
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
 	}
-	if in.ObjectStorage != nil {
-		in, out := &in.ObjectStorage, &out.ObjectStorage
-		*out = new(ObjectStorageOutput)
-		**out = **in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskPartition.
+func (in *DiskPartition) DeepCopy() *DiskPartition {
+	if in == nil {
+		return nil
 	}
-	if in.Registry != nil {
-		in, out := &in.Registry, &out.Registry
-		*out = new(RegistryOutput)
+	out := new(DiskPartition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DockerfileSpec) DeepCopyInto(out *DockerfileSpec) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(corev1.LocalObjectReference)
 		**out = **in
 	}
-	if in.Formats != nil {
-		in, out := &in.Formats, &out.Formats
-		*out = make([]OutputFormat, len(*in))
-		copy(*out, *in)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OutputSpec.
-func (in *OutputSpec) DeepCopy() *OutputSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DockerfileSpec.
+func (in *DockerfileSpec) DeepCopy() *DockerfileSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(OutputSpec)
+	out := new(DockerfileSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PVCOutput) DeepCopyInto(out *PVCOutput) {
+func (in *GitSSH) DeepCopyInto(out *GitSSH) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PVCOutput.
-func (in *PVCOutput) DeepCopy() *PVCOutput {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitSSH.
+func (in *GitSSH) DeepCopy() *GitSSH {
 	if in == nil {
 		return nil
 	}
-	out := new(PVCOutput)
+	out := new(GitSSH)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PackerSpec) DeepCopyInto(out *PackerSpec) {
+func (in *GitSubmoduleCredentials) DeepCopyInto(out *GitSubmoduleCredentials) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackerSpec.
-func (in *PackerSpec) DeepCopy() *PackerSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitSubmoduleCredentials.
+func (in *GitSubmoduleCredentials) DeepCopy() *GitSubmoduleCredentials {
 	if in == nil {
 		return nil
 	}
-	out := new(PackerSpec)
+	out := new(GitSubmoduleCredentials)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProvisionerSpec) DeepCopyInto(out *ProvisionerSpec) {
+func (in *ImageBuild) DeepCopyInto(out *ImageBuild) {
 	*out = *in
-	if in.Ansible != nil {
-		in, out := &in.Ansible, &out.Ansible
-		*out = new(AnsibleSpec)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Packer != nil {
-		in, out := &in.Packer, &out.Packer
-		*out = new(PackerSpec)
-		**out = **in
-	}
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionerSpec.
-func (in *ProvisionerSpec) DeepCopy() *ProvisionerSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBuild.
+func (in *ImageBuild) DeepCopy() *ImageBuild {
 	if in == nil {
 		return nil
 	}
-	out := new(ProvisionerSpec)
+	out := new(ImageBuild)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageBuild) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PublishSpec) DeepCopyInto(out *PublishSpec) {
+func (in *ImageBuildList) DeepCopyInto(out *ImageBuildList) {
 	*out = *in
-	if in.AWS != nil {
-		in, out := &in.AWS, &out.AWS
-		*out = new(AWSPublishSpec)
-		**out = **in
-	}
-	if in.MaaS != nil {
-		in, out := &in.MaaS, &out.MaaS
-		*out = new(MaaSPublishSpec)
-		**out = **in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImageBuild, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PublishSpec.
-func (in *PublishSpec) DeepCopy() *PublishSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBuildList.
+func (in *ImageBuildList) DeepCopy() *ImageBuildList {
 	if in == nil {
 		return nil
 	}
-	out := new(PublishSpec)
+	out := new(ImageBuildList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageBuildList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RegistryOutput) DeepCopyInto(out *RegistryOutput) {
+func (in *ImageBuildReference) DeepCopyInto(out *ImageBuildReference) {
 	*out = *in
 }
 
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBuildReference.
+func (in *ImageBuildReference) DeepCopy() *ImageBuildReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageBuildReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageBuildSpec) DeepCopyInto(out *ImageBuildSpec) {
+	*out = *in
+	if in.BaseImageFrom != nil {
+		in, out := &in.BaseImageFrom, &out.BaseImageFrom
+		*out = new(ImageBuildReference)
+		**out = **in
+	}
+	if in.Provisioner != nil {
+		in, out := &in.Provisioner, &out.Provisioner
+		*out = new(ProvisionerSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Verify != nil {
+		in, out := &in.Verify, &out.Verify
+		*out = new(VerifySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Output.DeepCopyInto(&out.Output)
+	if in.Publish != nil {
+		in, out := &in.Publish, &out.Publish
+		*out = new(PublishSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Signing != nil {
+		in, out := &in.Signing, &out.Signing
+		*out = new(SigningSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Notify != nil {
+		in, out := &in.Notify, &out.Notify
+		*out = new(NotifySpec)
+		**out = **in
+	}
+	if in.Build != nil {
+		in, out := &in.Build, &out.Build
+		*out = new(BuildSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.RetryLimit != nil {
+		in, out := &in.RetryLimit, &out.RetryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.BuilderEnv != nil {
+		in, out := &in.BuilderEnv, &out.BuilderEnv
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PodMetadata != nil {
+		in, out := &in.PodMetadata, &out.PodMetadata
+		*out = new(PodMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBuildSpec.
+func (in *ImageBuildSpec) DeepCopy() *ImageBuildSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageBuildSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageBuildStatus) DeepCopyInto(out *ImageBuildStatus) {
+	*out = *in
+	if in.LastPhaseTransitionTime != nil {
+		in, out := &in.LastPhaseTransitionTime, &out.LastPhaseTransitionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(v1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.BuildDeadline != nil {
+		in, out := &in.BuildDeadline, &out.BuildDeadline
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.OutputURLs != nil {
+		in, out := &in.OutputURLs, &out.OutputURLs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.OutputPVC != nil {
+		in, out := &in.OutputPVC, &out.OutputPVC
+		*out = new(OutputPVCStatus)
+		**out = **in
+	}
+	if in.ExitCode != nil {
+		in, out := &in.ExitCode, &out.ExitCode
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PublishedImageLaunchPermissions != nil {
+		in, out := &in.PublishedImageLaunchPermissions, &out.PublishedImageLaunchPermissions
+		*out = new(AWSLaunchPermissions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Artifacts != nil {
+		in, out := &in.Artifacts, &out.Artifacts
+		*out = make([]ArtifactStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Stages != nil {
+		in, out := &in.Stages, &out.Stages
+		*out = make([]BuildStageStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.OutputDestinations != nil {
+		in, out := &in.OutputDestinations, &out.OutputDestinations
+		*out = make([]OutputDestinationStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Notification != nil {
+		in, out := &in.Notification, &out.Notification
+		*out = new(NotificationStatus)
+		**out = **in
+	}
+	if in.V1Beta2 != nil {
+		in, out := &in.V1Beta2, &out.V1Beta2
+		*out = new(ImageBuildV1Beta2Status)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBuildStatus.
+func (in *ImageBuildStatus) DeepCopy() *ImageBuildStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageBuildStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageBuildV1Beta2Status) DeepCopyInto(out *ImageBuildV1Beta2Status) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBuildV1Beta2Status.
+func (in *ImageBuildV1Beta2Status) DeepCopy() *ImageBuildV1Beta2Status {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageBuildV1Beta2Status)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageConfig) DeepCopyInto(out *ImageConfig) {
+	*out = *in
+	if in.Entrypoint != nil {
+		in, out := &in.Entrypoint, &out.Entrypoint
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Cmd != nil {
+		in, out := &in.Cmd, &out.Cmd
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]ImageConfigEnvVar, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExposedPorts != nil {
+		in, out := &in.ExposedPorts, &out.ExposedPorts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageConfig.
+func (in *ImageConfig) DeepCopy() *ImageConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageConfigEnvVar) DeepCopyInto(out *ImageConfigEnvVar) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageConfigEnvVar.
+func (in *ImageConfigEnvVar) DeepCopy() *ImageConfigEnvVar {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageConfigEnvVar)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeylessSigning) DeepCopyInto(out *KeylessSigning) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeylessSigning.
+func (in *KeylessSigning) DeepCopy() *KeylessSigning {
+	if in == nil {
+		return nil
+	}
+	out := new(KeylessSigning)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KickstartSpec) DeepCopyInto(out *KickstartSpec) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KickstartSpec.
+func (in *KickstartSpec) DeepCopy() *KickstartSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KickstartSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaaSPublishSpec) DeepCopyInto(out *MaaSPublishSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaaSPublishSpec.
+func (in *MaaSPublishSpec) DeepCopy() *MaaSPublishSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MaaSPublishSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotificationStatus) DeepCopyInto(out *NotificationStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationStatus.
+func (in *NotificationStatus) DeepCopy() *NotificationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NotificationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotifySpec) DeepCopyInto(out *NotifySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotifySpec.
+func (in *NotifySpec) DeepCopy() *NotifySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NotifySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectStorageOutput) DeepCopyInto(out *ObjectStorageOutput) {
+	*out = *in
+	out.AWSAssumeRole = in.AWSAssumeRole
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStorageOutput.
+func (in *ObjectStorageOutput) DeepCopy() *ObjectStorageOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectStorageOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OutputDestinationStatus) DeepCopyInto(out *OutputDestinationStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OutputDestinationStatus.
+func (in *OutputDestinationStatus) DeepCopy() *OutputDestinationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OutputDestinationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OutputPVCStatus) DeepCopyInto(out *OutputPVCStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OutputPVCStatus.
+func (in *OutputPVCStatus) DeepCopy() *OutputPVCStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OutputPVCStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OutputSanitize) DeepCopyInto(out *OutputSanitize) {
+	*out = *in
+	if in.ResetMachineID != nil {
+		in, out := &in.ResetMachineID, &out.ResetMachineID
+		*out = new(bool)
+		**out = **in
+	}
+	if in.CleanCloudInit != nil {
+		in, out := &in.CleanCloudInit, &out.CleanCloudInit
+		*out = new(bool)
+		**out = **in
+	}
+	if in.TruncateLogs != nil {
+		in, out := &in.TruncateLogs, &out.TruncateLogs
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OutputSanitize.
+func (in *OutputSanitize) DeepCopy() *OutputSanitize {
+	if in == nil {
+		return nil
+	}
+	out := new(OutputSanitize)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OutputSpec) DeepCopyInto(out *OutputSpec) {
+	*out = *in
+	if in.PVC != nil {
+		in, out := &in.PVC, &out.PVC
+		*out = new(PVCOutput)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Volume != nil {
+		in, out := &in.Volume, &out.Volume
+		*out = new(VolumeOutput)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ObjectStorage != nil {
+		in, out := &in.ObjectStorage, &out.ObjectStorage
+		*out = new(ObjectStorageOutput)
+		**out = **in
+	}
+	if in.Registry != nil {
+		in, out := &in.Registry, &out.Registry
+		*out = new(RegistryOutput)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Formats != nil {
+		in, out := &in.Formats, &out.Formats
+		*out = make([]OutputFormat, len(*in))
+		copy(*out, *in)
+	}
+	if in.QCOW2 != nil {
+		in, out := &in.QCOW2, &out.QCOW2
+		*out = new(QCOW2Options)
+		**out = **in
+	}
+	if in.Partitioning != nil {
+		in, out := &in.Partitioning, &out.Partitioning
+		*out = new(Partitioning)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DiskLayout != nil {
+		in, out := &in.DiskLayout, &out.DiskLayout
+		*out = new(DiskLayout)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Sanitize != nil {
+		in, out := &in.Sanitize, &out.Sanitize
+		*out = new(OutputSanitize)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OutputSpec.
+func (in *OutputSpec) DeepCopy() *OutputSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OutputSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PVCOutput) DeepCopyInto(out *PVCOutput) {
+	*out = *in
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Retain != nil {
+		in, out := &in.Retain, &out.Retain
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PVCOutput.
+func (in *PVCOutput) DeepCopy() *PVCOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(PVCOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageMirrors) DeepCopyInto(out *PackageMirrors) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageMirrors.
+func (in *PackageMirrors) DeepCopy() *PackageMirrors {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageMirrors)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackerSpec) DeepCopyInto(out *PackerSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackerSpec.
+func (in *PackerSpec) DeepCopy() *PackerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PackerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Partitioning) DeepCopyInto(out *Partitioning) {
+	*out = *in
+	if in.EFISystemPartition != nil {
+		in, out := &in.EFISystemPartition, &out.EFISystemPartition
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Partitioning.
+func (in *Partitioning) DeepCopy() *Partitioning {
+	if in == nil {
+		return nil
+	}
+	out := new(Partitioning)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodMetadata) DeepCopyInto(out *PodMetadata) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodMetadata.
+func (in *PodMetadata) DeepCopy() *PodMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(PodMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Profile) DeepCopyInto(out *Profile) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Profile.
+func (in *Profile) DeepCopy() *Profile {
+	if in == nil {
+		return nil
+	}
+	out := new(Profile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisionerSpec) DeepCopyInto(out *ProvisionerSpec) {
+	*out = *in
+	if in.Ansible != nil {
+		in, out := &in.Ansible, &out.Ansible
+		*out = new(AnsibleSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Packer != nil {
+		in, out := &in.Packer, &out.Packer
+		*out = new(PackerSpec)
+		**out = **in
+	}
+	if in.Dockerfile != nil {
+		in, out := &in.Dockerfile, &out.Dockerfile
+		*out = new(DockerfileSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Kickstart != nil {
+		in, out := &in.Kickstart, &out.Kickstart
+		*out = new(KickstartSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Bootc != nil {
+		in, out := &in.Bootc, &out.Bootc
+		*out = new(BootcSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PostSteps != nil {
+		in, out := &in.PostSteps, &out.PostSteps
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionerSpec.
+func (in *ProvisionerSpec) DeepCopy() *ProvisionerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisionerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PublishSpec) DeepCopyInto(out *PublishSpec) {
+	*out = *in
+	if in.AWS != nil {
+		in, out := &in.AWS, &out.AWS
+		*out = new(AWSPublishSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaaS != nil {
+		in, out := &in.MaaS, &out.MaaS
+		*out = new(MaaSPublishSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PublishSpec.
+func (in *PublishSpec) DeepCopy() *PublishSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PublishSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QCOW2Options) DeepCopyInto(out *QCOW2Options) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QCOW2Options.
+func (in *QCOW2Options) DeepCopy() *QCOW2Options {
+	if in == nil {
+		return nil
+	}
+	out := new(QCOW2Options)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryDestination) DeepCopyInto(out *RegistryDestination) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryDestination.
+func (in *RegistryDestination) DeepCopy() *RegistryDestination {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryDestination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryOutput) DeepCopyInto(out *RegistryOutput) {
+	*out = *in
+	if in.AdditionalDestinations != nil {
+		in, out := &in.AdditionalDestinations, &out.AdditionalDestinations
+		*out = make([]RegistryDestination, len(*in))
+		copy(*out, *in)
+	}
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = new(ImageConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryOutput.
 func (in *RegistryOutput) DeepCopy() *RegistryOutput {
 	if in == nil {
@@ -335,3 +1217,129 @@ func (in *RegistryOutput) DeepCopy() *RegistryOutput {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretFileMount) DeepCopyInto(out *SecretFileMount) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretFileMount.
+func (in *SecretFileMount) DeepCopy() *SecretFileMount {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretFileMount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecurityProfile) DeepCopyInto(out *SecurityProfile) {
+	*out = *in
+	if in.SeccompProfile != nil {
+		in, out := &in.SeccompProfile, &out.SeccompProfile
+		*out = new(Profile)
+		**out = **in
+	}
+	if in.AppArmorProfile != nil {
+		in, out := &in.AppArmorProfile, &out.AppArmorProfile
+		*out = new(Profile)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecurityProfile.
+func (in *SecurityProfile) DeepCopy() *SecurityProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(SecurityProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SigningSpec) DeepCopyInto(out *SigningSpec) {
+	*out = *in
+	if in.Keyless != nil {
+		in, out := &in.Keyless, &out.Keyless
+		*out = new(KeylessSigning)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SigningSpec.
+func (in *SigningSpec) DeepCopy() *SigningSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SigningSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VerifySpec) DeepCopyInto(out *VerifySpec) {
+	*out = *in
+	if in.Ansible != nil {
+		in, out := &in.Ansible, &out.Ansible
+		*out = new(AnsibleSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VerifySpec.
+func (in *VerifySpec) DeepCopy() *VerifySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VerifySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeOutput) DeepCopyInto(out *VolumeOutput) {
+	*out = *in
+	if in.NFS != nil {
+		in, out := &in.NFS, &out.NFS
+		*out = new(corev1.NFSVolumeSource)
+		**out = **in
+	}
+	if in.CSI != nil {
+		in, out := &in.CSI, &out.CSI
+		*out = new(corev1.CSIVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeOutput.
+func (in *VolumeOutput) DeepCopy() *VolumeOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkVolume) DeepCopyInto(out *WorkVolume) {
+	*out = *in
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+	out.Size = in.Size.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkVolume.
+func (in *WorkVolume) DeepCopy() *WorkVolume {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkVolume)
+	in.DeepCopyInto(out)
+	return out
+}
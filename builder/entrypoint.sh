@@ -11,8 +11,580 @@ set -ex
 # - ANSIBLE_GIT_REPO:     (Optional) The Git repo for the Ansible provisioner.
 # - ANSIBLE_GIT_BRANCH:   (Optional) The Git branch to clone.
 # - ANSIBLE_PLAYBOOK:     (Optional) The path to the Ansible playbook.
+# - ANSIBLE_MODE, VERIFY_ANSIBLE_MODE:
+#                         (Optional) "chroot" (default) to run the playbook against the
+#                         mounted rootfs, or "container" to run it inside the working
+#                         container with the local connection.
+# - ANSIBLE_GIT_CREDENTIALS_DIR, VERIFY_ANSIBLE_GIT_CREDENTIALS_DIR:
+#                         (Optional) The directory the git credentials secret is mounted
+#                         at: "ssh-privatekey", "username" and "password", or "token"
+#                         with an optional "username".
+# - ANSIBLE_GIT_SUBMODULES, VERIFY_ANSIBLE_GIT_SUBMODULES:
+#                         (Optional) "true" to check out the submodules of the repository recursively.
+# - ANSIBLE_GIT_SUBMODULE_CREDENTIALS, VERIFY_ANSIBLE_GIT_SUBMODULE_CREDENTIALS:
+#                         (Optional) Lines of "<url pattern> <dir>": submodules whose remote URL
+#                         matches the shell pattern are fetched with the credentials mounted at dir,
+#                         laid out like ANSIBLE_GIT_CREDENTIALS_DIR. Others use the repository's.
+# - GIT_SSH_KNOWN_HOSTS:  (Optional) The known_hosts file the host keys of git servers cloned
+#                         from over SSH are verified against.
+# - GIT_SSH_HOST_KEY_CHECKING:
+#                         (Optional) The ssh StrictHostKeyChecking option of git clones, "yes"
+#                         or "accept-new" (default).
+# - ANSIBLE_PYTHON_INTERPRETER, VERIFY_ANSIBLE_PYTHON_INTERPRETER:
+#                         (Optional) The ansible_python_interpreter the modules run with, an
+#                         absolute path or a discovery mode. Defaults to "auto".
+# - ANSIBLE_VIRTUALENV, VERIFY_ANSIBLE_VIRTUALENV:
+#                         (Optional) A virtual environment created in the image before the
+#                         playbook runs, whose interpreter the modules then run with.
+# - ANSIBLE_VIRTUALENV_PYTHON, ANSIBLE_VIRTUALENV_PACKAGES (and their VERIFY_ variants):
+#                         (Optional) The interpreter creating the virtual environment, and
+#                         the space-separated pip requirements installed into it.
+# - ANSIBLE_TARGET_CONNECTION, VERIFY_ANSIBLE_TARGET_CONNECTION:
+#                         (Optional) The connection plugin the playbook runs with, instead of
+#                         "chroot" or "local" implied by the mode.
+# - ANSIBLE_TARGET_BECOME, VERIFY_ANSIBLE_TARGET_BECOME:
+#                         (Optional) "true" to run the playbook with --become.
+# - VERIFY_ANSIBLE_GIT_REPO, VERIFY_ANSIBLE_GIT_BRANCH, VERIFY_ANSIBLE_PLAYBOOK:
+#                         (Optional) A playbook run against the provisioned filesystem
+#                         to verify it. A failure fails the build.
+# - DOCKERFILE_PATH:      (Optional) The Containerfile built on top of BASE_IMAGE, relative to
+#                         the build context in /source, to provision the image with.
+# - DOCKERFILE_GIT_REPO, DOCKERFILE_GIT_BRANCH, DOCKERFILE_GIT_CREDENTIALS_DIR:
+#                         (Optional) The Git repository cloned into /source as the build
+#                         context. Without it /source is a mounted ConfigMap.
+# - KICKSTART_PATH:       (Optional) The kickstart file in /source whose packages, services,
+#                         timezone, lang and %post scripts are applied to a RHEL-family image.
+# - KICKSTART_INLINE:     (Optional) The content of the kickstart file, written to KICKSTART_PATH.
+# - KICKSTART_GIT_REPO, KICKSTART_GIT_BRANCH, KICKSTART_GIT_CREDENTIALS_DIR:
+#                         (Optional) The Git repository cloned into /source holding the kickstart
+#                         file. Without it or KICKSTART_INLINE /source is a mounted ConfigMap.
+# - BOOTC:                (Optional) "true" if the image is a bootc image, e.g. built from the
+#                         Containerfile of DOCKERFILE_PATH. Its qcow2 image is installed with
+#                         `bootc install to-disk` instead of the DISK_ variables below.
+# - BOOTC_TARGET_IMGREF:  The image reference machines installed from the qcow2 image update from.
+# - BOOTC_KERNEL_ARGS:    (Optional) A JSON array of kernel arguments written to
+#                         /usr/lib/bootc/kargs.d in the image.
+# - BOOTC_LINT:           (Optional) "true" to run `bootc container lint` on the image.
+# - POST_STEPS_SCRIPT:    (Optional) A shell script run in the image with its /bin/sh after the
+#                         provisioner, e.g. to clean up logs. A failure fails the build.
+# - OUTPUT_FORMATS:       (Optional) Comma-separated artifact formats, "tgz" and/or
+#                         "qcow2". Defaults to "tgz,qcow2" when unset; empty to write no
+#                         file artifacts.
+# - QCOW2_COMPRESS:       (Optional) "true" to write a compressed qcow2 image.
+# - QCOW2_PREALLOCATION:  (Optional) The qemu-img preallocation mode of the qcow2 image, "off"
+#                         (default), "metadata", "falloc" or "full".
+# - OUTPUT_FILESYSTEM:    (Optional) The root filesystem of the qcow2 image, "ext4" (default),
+#                         "xfs" or "btrfs".
+# - DISK_PARTITION_TABLE: (Optional) The partition table of the qcow2 image, "gpt" (default)
+#                         or "mbr".
+# - DISK_EFI_PARTITION:   (Optional) "false" to leave out the EFI system partition in front of
+#                         the root partition. Defaults to "true".
+# - DISK_LAYOUT:          (Optional) Custom partitions of the qcow2 image, replacing the variables
+#                         above, as JSON: {"table": "gpt"|"mbr", "sizeMiB": <disk size>,
+#                         "partitions": [{"label", "sizeMiB", "filesystem", "mountPoint"}]}.
+#                         The last partition may leave out sizeMiB to take the rest of the disk.
+# - REGISTRY_DESTINATION: (Optional) Commit the provisioned container and push it to this
+#                         image reference, before writing any file artifacts. Registry
+#                         credentials are read from /etc/registry-auth/.dockerconfigjson.
+# - REGISTRY_ADDITIONAL_DESTINATIONS:
+#                         (Optional) Further image references the image is pushed to after
+#                         REGISTRY_DESTINATION, one "<reference> <auth file>" pair per line.
+# - REGISTRY_IMAGE_CONFIG: (Optional) Fields of the OCI config of the pushed image, as JSON:
+#                         {"entrypoint", "cmd", "env": [{"name", "value"}], "exposedPorts",
+#                         "labels"}. Fields left out keep the config of the base image.
+# - REGISTRY_COMPRESSION_FORMAT:
+#                         (Optional) The layer compression for the push, "gzip" (default)
+#                         or "zstd".
+# - REGISTRY_CREATE_REPOSITORY:
+#                         (Optional) "ecr" or "gar" to create the repository of the
+#                         destination before the push if it does not exist yet.
+# - REGISTRY_REPOSITORY, REGISTRY_REPOSITORY_LOCATION, REGISTRY_REPOSITORY_OWNER:
+#                         The repository to create, its AWS region or Artifact Registry
+#                         location, and its AWS account ID or Google Cloud project.
+# - REGISTRY_AWS_ACCESS_KEY_ID, REGISTRY_AWS_SECRET_ACCESS_KEY:
+#                         The credentials an ECR repository is created with. Artifact Registry
+#                         repositories are created with the registry credentials, which must be
+#                         a "_json_key" service account key or an "oauth2accesstoken" token.
+# - SIGNING_IDENTITY_TOKEN_FILE:
+#                         (Optional) Sign the pushed images keyless with cosign, presenting this
+#                         OIDC token, the pod's projected service account token, to Fulcio.
+# - SIGNING_FULCIO_URL, SIGNING_REKOR_URL:
+#                         The Fulcio and Rekor instances keyless signing uses.
+# - ARTIFACT_IMAGEBUILD_NAME, ARTIFACT_IMAGEBUILD_NAMESPACE, ARTIFACT_IMAGEBUILD_UID:
+#                         The source ImageBuild, to be stamped on the artifacts.
+# - ARTIFACT_CREATED:     The RFC 3339 creation time, to be stamped on the artifacts.
+#                         Pushed images carry them as "io.x-k8s.cluster.bib.imagebuild.*" and
+#                         "org.opencontainers.image.created" labels, uploaded objects as
+#                         x-amz-meta-imagebuild-* and x-amz-meta-created metadata.
+# - OUTPUT_RETAIN:        (Optional) The number of successful builds of OUTPUT_FILENAME whose
+#                         artifacts are kept on the output PVC, mounted whole at /output-root.
+#                         The artifacts of older builds are removed once the build has succeeded.
+# - OUTPUT_S3_URL:        (Optional) The s3:// prefix the file artifacts and their metadata
+#                         are uploaded to, in addition to /output.
+# - SANITIZE_MACHINE_ID, SANITIZE_CLOUD_INIT, SANITIZE_LOGS:
+#                         (Optional) "true" to empty /etc/machine-id, remove the cloud-init state,
+#                         or truncate the files under /var/log before the image is packaged.
+# - OUTPUT_PARALLEL_UPLOADS:
+#                         (Optional) "true" to push to the registry destinations concurrently, and
+#                         then upload the artifacts to OUTPUT_S3_URL concurrently, rather than one
+#                         after the other. The pushes finish before the artifacts are converted.
+# - OUTPUT_S3_REGION, OUTPUT_AWS_ACCESS_KEY_ID, OUTPUT_AWS_SECRET_ACCESS_KEY:
+#                         (Optional) The region and credentials used for the artifact upload.
+# - OUTPUT_S3_CONTENT_TYPE: (Optional) The Content-Type the artifacts are uploaded with instead
+#                         of the one of their format. The metadata is always "application/json".
+# - OUTPUT_S3_STORAGE_CLASS: (Optional) The S3 storage class the artifacts are uploaded with, e.g.
+#                         "STANDARD_IA". The metadata is always uploaded as "STANDARD".
+# - OUTPUT_S3_UPLOAD_CONCURRENCY: (Optional) The number of parts of a multipart upload sent at
+#                         once, instead of the AWS CLI's default of 10.
+# - BUILD_CACHE_DIR:      (Optional) A directory shared across builds. The package manager
+#                         caches of the image (apt, dnf, yum) are kept there while the
+#                         playbooks run.
+# - PACKAGE_MIRRORS_DIR:  (Optional) A directory of repository files replacing the repositories
+#                         of the image while the playbooks run: "*.list" and "*.sources" files
+#                         replace the apt sources, "*.repo" files the dnf/yum repositories.
+# - PACKAGE_PROXY:        (Optional) The HTTP proxy apt and dnf/yum use while the playbooks run.
+# - LOGS_S3_URL:          (Optional) The s3:// URL the full build log is uploaded to
+#                         when the build finishes, successfully or not. The outcome of the
+#                         upload is listed in the "uploads" of the termination message.
+# - LOGS_S3_REGION, LOGS_AWS_ACCESS_KEY_ID, LOGS_AWS_SECRET_ACCESS_KEY:
+#                         (Optional) The region and credentials used for the log upload.
+# - LOGS_S3_CONTENT_TYPE: (Optional) The Content-Type the log is uploaded with instead of "text/plain".
+# - LOGS_S3_STORAGE_CLASS: (Optional) The S3 storage class the log is uploaded with.
+# - LOGS_S3_UPLOAD_CONCURRENCY: (Optional) The number of parts of the log upload sent at once.
+# - OUTPUT_AWS_ASSUME_ROLE_ARN, OUTPUT_AWS_ASSUME_ROLE_EXTERNAL_ID,
+#   LOGS_AWS_ASSUME_ROLE_ARN, LOGS_AWS_ASSUME_ROLE_EXTERNAL_ID:
+#                         (Optional) The IAM role assumed with the credentials above before
+#                         the upload, and the external ID the role requires.
+# - BUILDER_VERBOSITY:    (Optional) "verbose" or "debug" to log more, e.g. the debug log of buildah
+#                         when building a Containerfile at "debug".
+# - ANSIBLE_VERBOSITY:    (Optional) The verbosity of the Ansible runs, as a number of -v flags.
+#                         It is passed into the working container for the container mode too.
+# - TRACEPARENT:          The W3C trace context of the build's "Building" span. Builders exporting
+#                         OpenTelemetry spans of their own continue the operator's trace with it.
+#
+# On success it reports the produced artifacts as JSON in the termination message:
+#   {"artifacts":[{"format":"qcow2","name":"<file>","sizeBytes":<n>,"virtualSizeBytes":<n>,"compressed":<bool>,"checksum":"sha256:<hex>"},
+#                 {"format":"oci","name":"<destination>","checksum":"<digest>","signed":<bool>}],
+#    "verify":{"passed":true},
+#    "stages":[{"name":"pull","phase":"Succeeded"},{"name":"provision","phase":"Succeeded"},...]}
+# "artifacts" lists the file artifacts and the image pushed to each registry destination, in any
+# order; the operator orders them and derives their URLs.
+# "stages" lists the outcome of each stage that ran ("pull", "provision", "verify", "push", "sign",
+# "convert", "upload"), on failure too, where the last one is "Failed" with a message. Custom builders may report
+# stages of their own, and a "Failed" stage while exiting zero for a failure that is not fatal.
+# A stage may carry its RFC 3339 "startTime" and "completionTime", which the operator traces it with.
+# "uploads" lists the outcome of each registry push and object storage upload, in no particular
+# order, on failure too: [{"url":"docker://<ref>"|"s3://<bucket>/<key>","phase":"Failed","message":"<error>"}].
+# If the verification fails it reports {"verify":{"passed":false,"message":"<reason>"}} and exits non-zero.
+# If a registry rejects the base image pull or the registry output push with 429 Too Many Requests it
+# reports {"rateLimited":{"operation":"pull"|"push","image":"<ref>","message":"<error>"}} and exits
+# non-zero, and the operator retries the build later.
+# If cloning a git repository fails, e.g. for a missing branch, rejected credentials or an unreachable
+# server, it reports {"sourceClone":{"repo":"<url>","branch":"<branch>","message":"<git error>"}} and
+# exits non-zero, with the stage that cloned it failed.
+# If the build fails with the output volume full or unwritable it reports
+# {"outputStorage":{"message":"<reason>"}}, and the operator may retry the build on a fresh claim.
 # -----------------------------
 
+# Run a command with the AWS credentials of the given variable prefix ("OUTPUT_", "LOGS_" or "REGISTRY_"),
+# swapped for temporary credentials of the prefix's role if one is set.
+with_aws_credentials() (
+    set +x
+    prefix="$1"
+    shift
+    eval "AWS_ACCESS_KEY_ID=\${${prefix}AWS_ACCESS_KEY_ID} AWS_SECRET_ACCESS_KEY=\${${prefix}AWS_SECRET_ACCESS_KEY}"
+    eval "role_arn=\${${prefix}AWS_ASSUME_ROLE_ARN} external_id=\${${prefix}AWS_ASSUME_ROLE_EXTERNAL_ID}"
+    export AWS_ACCESS_KEY_ID AWS_SECRET_ACCESS_KEY
+    eval "concurrency=\${${prefix}S3_UPLOAD_CONCURRENCY}"
+    if [ -n "${concurrency}" ]; then
+        # The AWS CLI reads its transfer settings from its config file only.
+        AWS_CONFIG_FILE="/tmp/aws-config-${prefix%_}"
+        printf '[default]\ns3 =\n    max_concurrent_requests = %s\n' "${concurrency}" > "${AWS_CONFIG_FILE}"
+        export AWS_CONFIG_FILE
+    fi
+    if [ -n "${role_arn}" ]; then
+        creds=$(aws sts assume-role --role-arn "${role_arn}" --role-session-name bib-operator \
+            ${external_id:+--external-id "${external_id}"} --query Credentials --output json) || exit 1
+        AWS_ACCESS_KEY_ID=$(echo "${creds}" | jq -r .AccessKeyId)
+        AWS_SECRET_ACCESS_KEY=$(echo "${creds}" | jq -r .SecretAccessKey)
+        AWS_SESSION_TOKEN=$(echo "${creds}" | jq -r .SessionToken)
+        export AWS_SESSION_TOKEN
+    fi
+    "$@"
+)
+
+# Re-run this script with its output captured, then upload the log whatever the outcome.
+if [ -n "${LOGS_S3_URL}" ] && [ -z "${BUILD_LOG_CAPTURED}" ]; then
+    set +ex
+    { BUILD_LOG_CAPTURED=1 "$0" "$@"; echo $? > /tmp/build.status; } 2>&1 | tee /tmp/build.log
+    status=$(cat /tmp/build.status)
+    echo "Uploading build log to ${LOGS_S3_URL}..."
+    # The outcome of the upload is added to the uploads of the termination message, so the operator
+    # only records the log URL once the log is there.
+    if with_aws_credentials LOGS_ aws s3 cp ${LOGS_S3_REGION:+--region "${LOGS_S3_REGION}"} \
+        --content-type "${LOGS_S3_CONTENT_TYPE:-text/plain}" ${LOGS_S3_STORAGE_CLASS:+--storage-class "${LOGS_S3_STORAGE_CLASS}"} \
+        /tmp/build.log "${LOGS_S3_URL}" 2> /tmp/build.log.err; then
+        upload=$(jq -cn --arg url "${LOGS_S3_URL}" '{url: $url, phase: "Succeeded"}')
+    else
+        cat /tmp/build.log.err >&2
+        echo "Failed to upload the build log."
+        upload=$(jq -cn --arg url "${LOGS_S3_URL}" --arg message "$(tail -n 1 /tmp/build.log.err)" \
+            '{url: $url, phase: "Failed", message: $message}')
+    fi
+    result=$(cat /dev/termination-log 2>/dev/null || true)
+    [ -n "${result}" ] || result='{}'
+    echo "${result}" | jq -c --argjson upload "${upload}" '. + {uploads: ((.uploads // []) + [$upload])}' > /dev/termination-log
+    exit "${status}"
+fi
+
+# with_registry_rate_limit <operation> <image> <command...> runs a registry pull or push, reporting
+# a rejection with 429 Too Many Requests so the operator retries the build rather than failing it.
+with_registry_rate_limit() {
+    operation="$1"
+    image="$2"
+    shift 2
+    # Concurrent pushes each need their own error file.
+    registry_err=$(mktemp /tmp/registry.err.XXXXXX)
+    if "$@" 2> "${registry_err}"; then
+        cat "${registry_err}" >&2
+        rm -f "${registry_err}"
+        return 0
+    fi
+    cat "${registry_err}" >&2
+    # Only the HTTP status and the registry error code, as "429" alone also appears in digests and sizes.
+    if grep -qiE "429 too many requests|toomanyrequests" "${registry_err}"; then
+        jq -cn --arg operation "${operation}" --arg image "${image}" --arg message "$(tail -n 1 "${registry_err}")" \
+            '{rateLimited: {operation: $operation, image: $image, message: $message}}' > /dev/termination-log
+    fi
+    rm -f "${registry_err}"
+    return 1
+}
+
+# push_destination <destination> <authfile> pushes the committed image to a registry destination and
+# records its digest in /tmp/pushed for signing and reporting.
+push_destination() {
+    echo "Pushing image to ${1}..."
+    digest_file=$(mktemp /tmp/push.digest.XXXXXX)
+    with_registry_rate_limit push "$1" buildah push --authfile "$2" \
+        --compression-format "${REGISTRY_COMPRESSION_FORMAT:-gzip}" --digestfile "${digest_file}" \
+        "${REGISTRY_DESTINATION}" "docker://${1}" || return 1
+    echo "$1 $2 $(cat "${digest_file}")" >> /tmp/pushed
+}
+
+# Uploads to the output destinations, see upload_to.
+mkdir -p /tmp/uploads.d
+upload_count=0
+upload_pids=""
+
+# upload_to <url> <command...> pushes or uploads to an output destination, recording its outcome for
+# the termination message. With OUTPUT_PARALLEL_UPLOADS "true" it runs in the background until
+# wait_uploads; otherwise it is waited for, and a failure ends the build, right away.
+upload_to() {
+    upload_count=$((upload_count + 1))
+    record_upload "/tmp/uploads.d/${upload_count}" "$@" < /dev/null &
+    upload_pids="${upload_pids} $!"
+    if [ "${OUTPUT_PARALLEL_UPLOADS}" != "true" ]; then
+        wait_uploads
+    fi
+}
+
+# record_upload <result file> <url> <command...> runs an upload, writing its outcome to the result file.
+record_upload() (
+    result="$1"
+    url="$2"
+    shift 2
+    if "$@" 2> "${result}.err"; then
+        cat "${result}.err" >&2
+        jq -cn --arg url "${url}" '{url: $url, phase: "Succeeded"}' > "${result}.json"
+        return 0
+    fi
+    cat "${result}.err" >&2
+    jq -cn --arg url "${url}" --arg message "$(tail -n 1 "${result}.err")" \
+        '{url: $url, phase: "Failed", message: $message}' > "${result}.json"
+    return 1
+)
+
+# wait_uploads waits for the running uploads. If any failed, it adds the outcome of all uploads so
+# far to the termination message and fails the build.
+wait_uploads() {
+    uploads_failed=false
+    for pid in ${upload_pids}; do
+        wait "${pid}" || uploads_failed=true
+    done
+    upload_pids=""
+    [ "${uploads_failed}" = true ] || return 0
+    result=$(cat /dev/termination-log 2>/dev/null || true)
+    [ -n "${result}" ] || result='{}'
+    echo "${result}" | jq -c --argjson uploads "$(uploads)" '. + {uploads: $uploads}' > /dev/termination-log
+    exit 1
+}
+
+# uploads prints the outcome of the uploads so far as a JSON array, kept small enough for the 4KiB
+# termination message: the successful uploads below OUTPUT_S3_URL are reported once for the prefix,
+# failures are listed first with their message cut to 200 characters, and at most 16 are kept.
+uploads() {
+    { cat /tmp/uploads.d/*.json 2>/dev/null || true; } | jq -sc --arg prefix "${OUTPUT_S3_URL}" '
+        map(select($prefix != "" and (.url | startswith($prefix)))) as $files
+        | map(select($prefix == "" or (.url | startswith($prefix) | not)))
+          + if ($files | length) == 0 then []
+            elif all($files[]; .phase == "Succeeded") then [{url: $prefix, phase: "Succeeded"}]
+            else $files | map(select(.phase == "Failed")) end
+        | sort_by(.phase != "Failed")
+        | map(if .message then .message |= .[:200] else . end)
+        | .[:16]'
+}
+
+# gar_access_token prints an OAuth2 access token for Artifact Registry, taken from the registry
+# credentials of the repository's location.
+gar_access_token() (
+    set +x
+    host="${REGISTRY_REPOSITORY_LOCATION}-docker.pkg.dev"
+    credentials=$(jq -r --arg host "${host}" '[.auths | to_entries[] | select(.key | sub("^https?://"; "") | startswith($host))][0].value
+        | if .auth then .auth | @base64d else "\(.username):\(.password)" end' /etc/registry-auth/.dockerconfigjson)
+    username="${credentials%%:*}"
+    password="${credentials#*:}"
+    case "${username}" in
+    oauth2accesstoken)
+        echo "${password}"
+        ;;
+    _json_key | _json_key_base64)
+        if [ "${username}" = "_json_key_base64" ]; then
+            password=$(echo "${password}" | base64 -d)
+        fi
+        echo "${password}" | python3 -c 'import json, sys
+from google.auth.transport.requests import Request
+from google.oauth2 import service_account
+credentials = service_account.Credentials.from_service_account_info(
+    json.load(sys.stdin), scopes=["https://www.googleapis.com/auth/cloud-platform"])
+credentials.refresh(Request())
+print(credentials.token)'
+        ;;
+    *)
+        echo "The registry credentials for ${host} must be a _json_key service account key or an oauth2accesstoken token to create repositories." >&2
+        exit 1
+        ;;
+    esac
+)
+
+# sign_image <reference> <auth file> <digest> signs a pushed image keyless: Fulcio issues a signing
+# certificate for the pod's service account token, and the signature is recorded in Rekor.
+sign_image() (
+    set +x
+    repository="$1"
+    case "${repository##*/}" in
+        *:*) repository="${repository%:*}" ;;
+    esac
+    mkdir -p /tmp/cosign
+    cp "$2" /tmp/cosign/config.json
+    echo "Signing ${repository}@$3..."
+    DOCKER_CONFIG=/tmp/cosign cosign sign --yes --identity-token "${SIGNING_IDENTITY_TOKEN_FILE}" \
+        --fulcio-url "${SIGNING_FULCIO_URL}" --rekor-url "${SIGNING_REKOR_URL}" "${repository}@$3"
+)
+
+# apply_image_config <container> sets the fields of REGISTRY_IMAGE_CONFIG on the config of the container,
+# keeping the config of the base image for the fields it leaves out.
+apply_image_config() {
+    if echo "${REGISTRY_IMAGE_CONFIG}" | jq -e 'has("entrypoint")' > /dev/null; then
+        buildah config --entrypoint "$(echo "${REGISTRY_IMAGE_CONFIG}" | jq -c '.entrypoint')" "$1"
+    fi
+    if echo "${REGISTRY_IMAGE_CONFIG}" | jq -e 'has("cmd")' > /dev/null; then
+        buildah config --cmd "$(echo "${REGISTRY_IMAGE_CONFIG}" | jq -c '.cmd')" "$1"
+    fi
+    # One variable and label at a time, by index, as values may contain whitespace.
+    for i in $(echo "${REGISTRY_IMAGE_CONFIG}" | jq '.env // [] | keys[]'); do
+        buildah config --env "$(echo "${REGISTRY_IMAGE_CONFIG}" | jq -r --argjson i "$i" '.env[$i] | "\(.name)=\(.value // "")"')" "$1"
+    done
+    for port in $(echo "${REGISTRY_IMAGE_CONFIG}" | jq -r '.exposedPorts // [] | .[]'); do
+        buildah config --port "${port}" "$1"
+    done
+    for i in $(echo "${REGISTRY_IMAGE_CONFIG}" | jq '.labels // {} | to_entries | keys[]'); do
+        buildah config --label "$(echo "${REGISTRY_IMAGE_CONFIG}" | jq -r --argjson i "$i" '.labels | to_entries[$i] | "\(.key)=\(.value)"')" "$1"
+    done
+}
+
+# stamp_image <container> labels the config of the container with its source ImageBuild and creation
+# time, so external lifecycle rules can act on the pushed image.
+stamp_image() {
+    buildah config \
+        --label "io.x-k8s.cluster.bib.imagebuild.name=${ARTIFACT_IMAGEBUILD_NAME}" \
+        --label "io.x-k8s.cluster.bib.imagebuild.namespace=${ARTIFACT_IMAGEBUILD_NAMESPACE}" \
+        --label "io.x-k8s.cluster.bib.imagebuild.uid=${ARTIFACT_IMAGEBUILD_UID}" \
+        --label "org.opencontainers.image.created=${ARTIFACT_CREATED}" \
+        "$1"
+}
+
+# artifact_metadata prints the source ImageBuild and creation time as the object metadata of
+# "aws s3 cp --metadata", stored as x-amz-meta-<key>.
+artifact_metadata() {
+    echo "imagebuild-name=${ARTIFACT_IMAGEBUILD_NAME},imagebuild-namespace=${ARTIFACT_IMAGEBUILD_NAMESPACE},imagebuild-uid=${ARTIFACT_IMAGEBUILD_UID},created=${ARTIFACT_CREATED}"
+}
+
+# create_repository creates the repository of the registry destination if it does not exist yet.
+create_repository() (
+    set +x
+    case "${REGISTRY_CREATE_REPOSITORY}" in
+    ecr)
+        if with_aws_credentials REGISTRY_ aws ecr describe-repositories --region "${REGISTRY_REPOSITORY_LOCATION}" \
+                --registry-id "${REGISTRY_REPOSITORY_OWNER}" --repository-names "${REGISTRY_REPOSITORY}" > /dev/null 2>&1; then
+            return 0
+        fi
+        echo "Creating ECR repository ${REGISTRY_REPOSITORY}..."
+        with_aws_credentials REGISTRY_ aws ecr create-repository --region "${REGISTRY_REPOSITORY_LOCATION}" \
+            --registry-id "${REGISTRY_REPOSITORY_OWNER}" --repository-name "${REGISTRY_REPOSITORY}" > /dev/null
+        ;;
+    gar)
+        token=$(gar_access_token) || exit 1
+        api="https://artifactregistry.googleapis.com/v1/projects/${REGISTRY_REPOSITORY_OWNER}/locations/${REGISTRY_REPOSITORY_LOCATION}/repositories"
+        status=$(curl -s -o /dev/null -w '%{http_code}' -H "Authorization: Bearer ${token}" "${api}/${REGISTRY_REPOSITORY}")
+        case "${status}" in
+        200)
+            ;;
+        404)
+            echo "Creating Artifact Registry repository ${REGISTRY_REPOSITORY}..."
+            curl -sSf -X POST -H "Authorization: Bearer ${token}" -H "Content-Type: application/json" \
+                -d '{"format":"DOCKER"}' "${api}?repositoryId=${REGISTRY_REPOSITORY}" > /dev/null
+            ;;
+        *)
+            echo "Failed to look up Artifact Registry repository ${REGISTRY_REPOSITORY}: HTTP ${status}" >&2
+            exit 1
+            ;;
+        esac
+        ;;
+    esac
+)
+
+# The stages that ran, and the one running, for the termination message.
+stages='[]'
+current_stage=""
+
+# begin_stage <name> attributes failures of the script to the given stage.
+begin_stage() {
+    current_stage="$1"
+    stage_started=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+}
+
+# end_stage records the running stage as succeeded.
+end_stage() {
+    stages=$(echo "${stages}" | jq -c --arg name "${current_stage}" --arg started "${stage_started}" \
+        --arg completed "$(date -u +%Y-%m-%dT%H:%M:%SZ)" \
+        '. + [{name: $name, phase: "Succeeded", startTime: $started, completionTime: $completed}]')
+    current_stage=""
+}
+
+# check_output_storage <dir> reports an output volume that is full or cannot be written to, so the
+# operator can retry the build on a fresh claim.
+check_output_storage() {
+    [ -d "$1" ] || return 0
+    message=""
+    if ! touch "$1/.bib-write-check" 2>/dev/null; then
+        message="the output volume cannot be written to"
+    elif [ "$(df -Pk "$1" | awk 'NR == 2 {print $4}')" -lt 1024 ]; then
+        message="the output volume is full"
+    fi
+    rm -f "$1/.bib-write-check"
+    [ -n "${message}" ] || return 0
+    result=$(cat /dev/termination-log 2>/dev/null || true)
+    [ -n "${result}" ] || result='{}'
+    echo "${result}" | jq -c --arg message "${message}" '. + {outputStorage: {message: $message}}' > /dev/termination-log
+}
+
+# report_stages adds the stages to the termination message when the script fails, the running one as failed.
+report_stages() {
+    status=$?
+    [ "${status}" -ne 0 ] || return 0
+    check_output_storage /output
+    if [ -n "${current_stage}" ]; then
+        stages=$(echo "${stages}" | jq -c --arg name "${current_stage}" --arg message "exited with code ${status}" \
+            --arg started "${stage_started}" --arg completed "$(date -u +%Y-%m-%dT%H:%M:%SZ)" \
+            '. + [{name: $name, phase: "Failed", message: $message, startTime: $started, completionTime: $completed}]')
+    fi
+    result=$(cat /dev/termination-log 2>/dev/null || true)
+    [ -n "${result}" ] || result='{}'
+    echo "${result}" | jq -c --argjson stages "${stages}" '. + {stages: $stages}' > /dev/termination-log
+}
+trap report_stages EXIT
+
+# git_with_credentials <credentials dir> <git args...> runs git, authenticating with the mounted
+# credentials secret if one is given. Passwords and tokens are read by a credential helper so they
+# never show up in the trace output. Connections over SSH verify the host key against
+# GIT_SSH_KNOWN_HOSTS, if set.
+git_with_credentials() {
+    creds="$1"
+    shift
+    ssh_command="ssh -o StrictHostKeyChecking=${GIT_SSH_HOST_KEY_CHECKING:-accept-new}"
+    if [ -n "${GIT_SSH_KNOWN_HOSTS}" ]; then
+        ssh_command="${ssh_command} -o UserKnownHostsFile=${GIT_SSH_KNOWN_HOSTS}"
+    fi
+    if [ -n "${creds}" ] && [ -f "${creds}/ssh-privatekey" ]; then
+        GIT_SSH_COMMAND="${ssh_command} -i ${creds}/ssh-privatekey" git "$@"
+    elif [ -n "${creds}" ] && { [ -f "${creds}/token" ] || [ -f "${creds}/password" ]; }; then
+        git -c credential.helper= \
+            -c "credential.helper=!f() { test \"\$1\" = get || return 0; echo \"username=\$(cat ${creds}/username 2>/dev/null || echo x-access-token)\"; echo \"password=\$(cat ${creds}/token 2>/dev/null || cat ${creds}/password)\"; }; f" \
+            "$@"
+    else
+        GIT_SSH_COMMAND="${ssh_command}" git "$@"
+    fi
+}
+
+# git_submodule_update <dir> <credentials dir> <submodule credentials> checks out the submodules of the
+# repository in dir recursively. Each submodule is fetched with the credentials directory of the first
+# "<url pattern> <dir>" line of the submodule credentials its resolved remote URL matches, and with the
+# credentials of the superproject otherwise. The URL of a submodule that fails is left in
+# failed_submodule_url.
+git_submodule_update() {
+    git -C "$1" submodule init || return 1
+    names=$(git -C "$1" config -f .gitmodules --name-only --get-regexp '^submodule\..*\.path$' || true)
+    for name in ${names}; do
+        name=${name#submodule.}
+        name=${name%.path}
+        url=$(git -C "$1" config --get "submodule.${name}.url")
+        submodule_path=$(git -C "$1" config -f .gitmodules --get "submodule.${name}.path")
+        submodule_creds="$2"
+        matched=$(echo "$3" | while read -r pattern dir; do
+            case "${url}" in
+                ${pattern}) echo "${dir}"; break ;;
+            esac
+        done)
+        [ -z "${matched}" ] || submodule_creds="${matched}"
+        echo "Checking out submodule ${submodule_path} from ${url}..."
+        if ! git_with_credentials "${submodule_creds}" -C "$1" submodule update -- "${submodule_path}"; then
+            failed_submodule_url="${url}"
+            return 1
+        fi
+        git_submodule_update "$1/${submodule_path}" "$2" "$3" || return 1
+    done
+}
+
+# git_clone <repo> <branch> <dest> [credentials dir] [submodules] [submodule credentials] clones a
+# repository, authenticating with the mounted credentials secret if one is given, and checks out its
+# submodules recursively if submodules is "true". A failure is reported with the error of git, so the
+# operator can tell it from a failure of the build itself, and with the URL of the submodule that
+# failed, if any.
+git_clone() {
+    git_err=$(mktemp /tmp/git.err.XXXXXX)
+    failed_submodule_url=""
+    if git_with_credentials "$4" clone --branch "$2" "$1" "$3" 2> "${git_err}" &&
+        { [ "$5" != true ] || git_submodule_update "$3" "$4" "$6" 2>> "${git_err}"; }; then
+        cat "${git_err}" >&2
+        rm -f "${git_err}"
+        return 0
+    fi
+    cat "${git_err}" >&2
+    # The last error of git, skipping the trace of the shell.
+    message=$(grep -E '^(fatal|error):' "${git_err}" | tail -n 1)
+    [ -n "${message}" ] || message=$(grep -v '^+' "${git_err}" | tail -n 1)
+    if [ -n "${failed_submodule_url}" ]; then
+        # Submodules are checked out at the commit recorded in their superproject, not at a branch.
+        jq -cn --arg repo "${failed_submodule_url}" --arg superproject "$1" --arg message "${message}" \
+            '{sourceClone: {repo: $repo, submoduleOf: $superproject, message: $message}}' > /dev/termination-log
+    else
+        jq -cn --arg repo "$1" --arg branch "$2" --arg message "${message}" \
+            '{sourceClone: {repo: $repo, branch: $branch, message: $message}}' > /dev/termination-log
+    fi
+    rm -f "${git_err}"
+    return 1
+}
+
 echo "--- Starting image build ---"
 echo "Base Image: ${BASE_IMAGE}"
 echo "Architecture: ${ARCHITECTURE}"
@@ -21,14 +593,37 @@ echo "Architecture: ${ARCHITECTURE}"
 AUTH_FILE="/etc/baseimage-pull-secret/.dockerconfigjson"
 
 # Create a working container from the base image
+begin_stage pull
 if [ -f "$AUTH_FILE" ]; then
     echo "Auth file found, using it for buildah."
-    container=$(buildah from --authfile "${AUTH_FILE}" --arch "${ARCHITECTURE}" "${BASE_IMAGE}")
+    container=$(with_registry_rate_limit pull "${BASE_IMAGE}" buildah from --authfile "${AUTH_FILE}" --arch "${ARCHITECTURE}" "${BASE_IMAGE}")
 else
     echo "No auth file found, proceeding without authentication."
-    container=$(buildah from --arch "${ARCHITECTURE}" "${BASE_IMAGE}")
+    container=$(with_registry_rate_limit pull "${BASE_IMAGE}" buildah from --arch "${ARCHITECTURE}" "${BASE_IMAGE}")
 fi
 echo "Created container: $container"
+end_stage
+
+# Build the Containerfile of a Dockerfile provisioner on top of the base image and continue from
+# the result. The build context is the cloned repository or the mounted ConfigMap.
+if [ -n "$DOCKERFILE_PATH" ]; then
+    begin_stage provision
+    if [ -n "$DOCKERFILE_GIT_REPO" ]; then
+        echo "Cloning repository ${DOCKERFILE_GIT_REPO}..."
+        git_clone "${DOCKERFILE_GIT_REPO}" "${DOCKERFILE_GIT_BRANCH}" /tmp/source "${DOCKERFILE_GIT_CREDENTIALS_DIR}"
+        cp -a /tmp/source/. /source/
+        rm -rf /tmp/source
+    fi
+    echo "Building ${DOCKERFILE_PATH} on top of ${BASE_IMAGE}..."
+    log_level=""
+    [ "${BUILDER_VERBOSITY}" != debug ] || log_level="--log-level=debug"
+    buildah ${log_level} build --arch "${ARCHITECTURE}" --from "${BASE_IMAGE}" --pull=never \
+        -f "/source/${DOCKERFILE_PATH}" -t localhost/bib-provisioned /source
+    buildah rm "$container"
+    container=$(buildah from --pull=never localhost/bib-provisioned)
+    echo "Created container: $container"
+    end_stage
+fi
 
 # Mount the container's filesystem
 mount_path=$(buildah mount "$container")
@@ -37,31 +632,681 @@ echo "Container mounted at: $mount_path"
 echo "Preparing chroot environment with device nodes..."
 mount --bind /dev "${mount_path}/dev"
 
+# Keep the package manager downloads of the provisioning step in the shared build cache. The
+# directories are bind-mounted into the rootfs for chroot playbooks and passed as volumes to
+# container playbooks, and only for package managers the image actually has.
+cache_dirs=""
+cache_volumes=""
+if [ -n "${BUILD_CACHE_DIR}" ]; then
+    for dir in var/cache/apt/archives var/cache/dnf var/cache/yum; do
+        [ -d "${mount_path}/${dir}" ] || continue
+        mkdir -p "${BUILD_CACHE_DIR}/${dir}"
+        mount --bind "${BUILD_CACHE_DIR}/${dir}" "${mount_path}/${dir}"
+        cache_dirs="${cache_dirs} ${mount_path}/${dir}"
+        cache_volumes="${cache_volumes} --volume ${BUILD_CACHE_DIR}/${dir}:/${dir}"
+    done
+fi
+
+# The package manager configuration replaced for the playbooks, saved to be restored afterwards.
+package_config="etc/apt/sources.list etc/apt/sources.list.d etc/apt/apt.conf.d etc/yum.repos.d etc/dnf/dnf.conf etc/yum.conf"
+package_config_backup=""
+
+# use_package_mirrors points the package managers of the image at the repositories in
+# PACKAGE_MIRRORS_DIR and the proxy in PACKAGE_PROXY, saving their configuration first.
+use_package_mirrors() {
+    saved=""
+    for path in ${package_config}; do
+        [ -e "${mount_path}/${path}" ] && saved="${saved} ${path}"
+    done
+    package_config_backup=/tmp/package-config.tar
+    tar -C "${mount_path}" -cf "${package_config_backup}" --files-from=/dev/null ${saved}
+
+    apt_replaced=""
+    yum_replaced=""
+    for file in "${PACKAGE_MIRRORS_DIR:-/nonexistent}"/*; do
+        [ -f "${file}" ] || continue
+        name=$(basename "${file}")
+        case "${name}" in
+        *.list | *.sources)
+            [ -d "${mount_path}/etc/apt" ] || continue
+            if [ -z "${apt_replaced}" ]; then
+                rm -rf "${mount_path}/etc/apt/sources.list" "${mount_path}/etc/apt/sources.list.d"
+                mkdir -p "${mount_path}/etc/apt/sources.list.d"
+                apt_replaced=1
+            fi
+            cp -L "${file}" "${mount_path}/etc/apt/sources.list.d/${name}"
+            ;;
+        *.repo)
+            [ -d "${mount_path}/etc/yum.repos.d" ] || continue
+            if [ -z "${yum_replaced}" ]; then
+                rm -f "${mount_path}"/etc/yum.repos.d/*
+                yum_replaced=1
+            fi
+            cp -L "${file}" "${mount_path}/etc/yum.repos.d/${name}"
+            ;;
+        *)
+            echo "Ignoring package mirror file ${name}, expected a .list, .sources or .repo file."
+            ;;
+        esac
+    done
+
+    if [ -n "${PACKAGE_PROXY}" ]; then
+        if [ -d "${mount_path}/etc/apt/apt.conf.d" ]; then
+            printf 'Acquire::http::Proxy "%s";\nAcquire::https::Proxy "%s";\n' "${PACKAGE_PROXY}" "${PACKAGE_PROXY}" \
+                > "${mount_path}/etc/apt/apt.conf.d/99bib-package-proxy"
+        fi
+        for conf in etc/dnf/dnf.conf etc/yum.conf; do
+            [ -f "${mount_path}/${conf}" ] && sed -i "/^\[main\]/a proxy=${PACKAGE_PROXY}" "${mount_path}/${conf}"
+        done
+    fi
+    return 0
+}
+
+# restore_package_config puts the package manager configuration of the image back.
+restore_package_config() {
+    [ -n "${package_config_backup}" ] || return 0
+    for path in ${package_config}; do
+        rm -rf "${mount_path:?}/${path}"
+    done
+    tar -C "${mount_path}" -xf "${package_config_backup}"
+    package_config_backup=""
+}
+
+if [ -n "${PACKAGE_MIRRORS_DIR}" ] || [ -n "${PACKAGE_PROXY}" ]; then
+    echo "Using package mirrors for the playbooks..."
+    use_package_mirrors
+fi
+
+# unmount_chroot removes the mounts and package mirrors added to the rootfs for the playbooks.
+unmount_chroot() {
+    restore_package_config
+    for dir in ${cache_dirs}; do
+        umount "${dir}"
+    done
+    umount "${mount_path}/dev"
+}
+
+# ansible_python <prefix> prints the interpreter the modules of the "" (provisioner) or "VERIFY_"
+# Ansible run use, creating the requested virtual environment in the image first.
+ansible_python() {
+    eval "interpreter=\${$1ANSIBLE_PYTHON_INTERPRETER:-auto} venv=\${$1ANSIBLE_VIRTUALENV}"
+    eval "venv_python=\${$1ANSIBLE_VIRTUALENV_PYTHON} venv_packages=\${$1ANSIBLE_VIRTUALENV_PACKAGES}"
+    if [ -n "${venv}" ]; then
+        echo "Creating virtual environment ${venv}..." >&2
+        buildah run "$container" -- "${venv_python:-python3}" -m venv "${venv}" >&2
+        if [ -n "${venv_packages}" ]; then
+            buildah run "$container" -- "${venv}/bin/pip" install ${venv_packages} >&2
+        fi
+        interpreter="${venv}/bin/python"
+    fi
+    echo "${interpreter}"
+}
+
+# run_playbook <prefix> <mode> <source dir> <playbook> [python interpreter] runs the "" (provisioner)
+# or "VERIFY_" playbook against the image, either from the builder against the mounted rootfs or
+# from inside the working container.
+run_playbook() {
+    eval "connection=\${$1ANSIBLE_TARGET_CONNECTION} become=\${$1ANSIBLE_TARGET_BECOME}"
+    [ "${become}" = "true" ] || become=""
+    if [ "$2" = "container" ]; then
+        buildah run --volume "$3:/bib-playbook:ro" ${cache_volumes} \
+            ${ANSIBLE_VERBOSITY:+--env "ANSIBLE_VERBOSITY=${ANSIBLE_VERBOSITY}"} "$container" -- \
+            ansible-playbook --connection="${connection:-local}" --inventory=localhost, ${become:+--become} \
+            ${5:+--extra-vars "ansible_python_interpreter=$5"} "/bib-playbook/$4"
+    else
+        # The --connection=chroot tells Ansible to run against the mounted filesystem
+        ansible-playbook --connection="${connection:-chroot}" --inventory="${mount_path}," ${become:+--become} \
+            ${5:+--extra-vars "ansible_python_interpreter=$5"} "$3/$4"
+    fi
+}
+
 # Clone the provisioning repository
 # The git-sync init container will handle this in the final version.
 # For now, we'll do it here if the repo is specified.
 if [ -n "$ANSIBLE_GIT_REPO" ]; then
+    begin_stage provision
     echo "Cloning repository ${ANSIBLE_GIT_REPO}..."
-    git clone --branch "${ANSIBLE_GIT_BRANCH}" "${ANSIBLE_GIT_REPO}" /source
+    # The working directory may already have been created inside /source, which git will not
+    # clone into, so clone next to it and move the checkout in place.
+    git_clone "${ANSIBLE_GIT_REPO}" "${ANSIBLE_GIT_BRANCH}" /tmp/source "${ANSIBLE_GIT_CREDENTIALS_DIR}" \
+        "${ANSIBLE_GIT_SUBMODULES}" "${ANSIBLE_GIT_SUBMODULE_CREDENTIALS}"
+    cp -a /tmp/source/. /source/
+    rm -rf /tmp/source
 fi
 
 # Run Ansible provisioner if a playbook is specified
 if [ -n "$ANSIBLE_PLAYBOOK" ]; then
-    echo "Running Ansible playbook ${ANSIBLE_PLAYBOOK}..."
-    # The --connection=chroot tells Ansible to run against the mounted filesystem
-    ansible-playbook --connection=chroot --inventory="${mount_path}," "/source/${ANSIBLE_PLAYBOOK}"
+    begin_stage provision
+    echo "Running Ansible playbook ${ANSIBLE_PLAYBOOK} (${ANSIBLE_MODE:-chroot} mode)..."
+    python=$(ansible_python "")
+    run_playbook "" "${ANSIBLE_MODE:-chroot}" /source "${ANSIBLE_PLAYBOOK}" "${python}"
+fi
+
+# apply_kickstart applies the kickstart file KICKSTART_PATH in /source to the image. Only the parts
+# describing the content of the system apply to an image built from a container: the packages,
+# services, timezone, lang and %post scripts. The installer commands and %pre scripts are ignored.
+apply_kickstart() {
+    plan=$(python3 /workspace/kickstart.py "/source/${KICKSTART_PATH}" /tmp/kickstart)
+    ignored=$(echo "${plan}" | jq -r '.ignored | unique | join(" ")')
+    [ -z "${ignored}" ] || echo "Ignoring the kickstart installer commands and sections: ${ignored}"
+    if [ -x "${mount_path}/usr/bin/dnf" ]; then
+        dnf=dnf
+    elif [ -x "${mount_path}/usr/bin/yum" ]; then
+        dnf=yum
+    else
+        echo "A kickstart file requires a RHEL-family base image with dnf or yum." >&2
+        exit 1
+    fi
+
+    # Package specs may contain globs, which are for the package manager to expand.
+    set -f
+    install=$(echo "${plan}" | jq -r '.install | join(" ")')
+    dnf_options=$(echo "${plan}" | jq -r '.dnfOptions | join(" ")')
+    if [ -n "${install}" ]; then
+        echo "Installing the kickstart packages..."
+        buildah run ${cache_volumes} "$container" -- ${dnf} -y ${dnf_options} install ${install}
+    fi
+    # Excluded packages are removed from the base image if it has them.
+    remove=""
+    for package in $(echo "${plan}" | jq -r '.remove[]'); do
+        case "${package}" in
+        @*) remove="${remove} ${package}" ;;
+        *)
+            if buildah run "$container" -- rpm -q --quiet "${package}" </dev/null; then
+                remove="${remove} ${package}"
+            fi
+            ;;
+        esac
+    done
+    if [ -n "${remove}" ]; then
+        echo "Removing the packages excluded by the kickstart..."
+        buildah run "$container" -- ${dnf} -y remove ${remove}
+    fi
+    for service in $(echo "${plan}" | jq -r '.enabledServices[]'); do
+        buildah run "$container" -- systemctl enable "${service}"
+    done
+    for service in $(echo "${plan}" | jq -r '.disabledServices[]'); do
+        buildah run "$container" -- systemctl disable "${service}"
+    done
+    set +f
+
+    timezone=$(echo "${plan}" | jq -r .timezone)
+    if [ -n "${timezone}" ]; then
+        ln -sfn "../usr/share/zoneinfo/${timezone}" "${mount_path}/etc/localtime"
+    fi
+    lang=$(echo "${plan}" | jq -r .lang)
+    if [ -n "${lang}" ]; then
+        echo "LANG=${lang}" > "${mount_path}/etc/locale.conf"
+    fi
+
+    # The %post scripts run in the image, or in the builder with --nochroot, with the rootfs in
+    # ANA_INSTALL_PATH as the installer sets it. A failure only fails the build with --erroronfail.
+    scripts=$(echo "${plan}" | jq '.scripts | length')
+    i=0
+    while [ "${i}" -lt "${scripts}" ]; do
+        script=$(echo "${plan}" | jq -c ".scripts[${i}]")
+        file=$(echo "${script}" | jq -r .file)
+        interpreter=$(echo "${script}" | jq -r .interpreter)
+        echo "Running kickstart %post script ${i}..."
+        if [ "$(echo "${script}" | jq -r .chroot)" = true ]; then
+            buildah run --volume /tmp/kickstart:/tmp/kickstart:ro ${cache_volumes} "$container" -- \
+                "${interpreter}" "${file}" && status=0 || status=$?
+        else
+            ANA_INSTALL_PATH="${mount_path}" "${interpreter}" "${file}" && status=0 || status=$?
+        fi
+        if [ "${status}" -ne 0 ]; then
+            if [ "$(echo "${script}" | jq -r .errorOnFail)" = true ]; then
+                echo "Kickstart %post script ${i} failed with status ${status}." >&2
+                exit "${status}"
+            fi
+            echo "Warning: kickstart %post script ${i} failed with status ${status}, continuing without --erroronfail."
+        fi
+        i=$((i + 1))
+    done
+}
+
+# Apply a kickstart file, cloned, inline or from the mounted ConfigMap, if one is specified
+if [ -n "$KICKSTART_PATH" ]; then
+    begin_stage provision
+    if [ -n "$KICKSTART_GIT_REPO" ]; then
+        echo "Cloning repository ${KICKSTART_GIT_REPO}..."
+        git_clone "${KICKSTART_GIT_REPO}" "${KICKSTART_GIT_BRANCH}" /tmp/source "${KICKSTART_GIT_CREDENTIALS_DIR}"
+        cp -a /tmp/source/. /source/
+        rm -rf /tmp/source
+    elif [ -n "$KICKSTART_INLINE" ]; then
+        printf '%s\n' "${KICKSTART_INLINE}" > "/source/${KICKSTART_PATH}"
+    fi
+    echo "Applying kickstart file ${KICKSTART_PATH}..."
+    apply_kickstart
+fi
+
+# Run the post steps of the provisioner in the image, as part of the provision stage.
+if [ -n "$POST_STEPS_SCRIPT" ]; then
+    [ -n "${current_stage}" ] || begin_stage provision
+    echo "Running the post steps..."
+    buildah run --volume "$(dirname "${POST_STEPS_SCRIPT}"):/bib-post-steps:ro" ${cache_volumes} "$container" -- \
+        /bin/sh "/bib-post-steps/$(basename "${POST_STEPS_SCRIPT}")"
+fi
+
+# Add the kernel arguments of a bootc image and check that bootc can install it.
+if [ "${BOOTC}" = "true" ]; then
+    [ -n "${current_stage}" ] || begin_stage provision
+    if [ -n "${BOOTC_KERNEL_ARGS}" ]; then
+        echo "Adding kernel arguments ${BOOTC_KERNEL_ARGS}..."
+        mkdir -p "${mount_path}/usr/lib/bootc/kargs.d"
+        echo "${BOOTC_KERNEL_ARGS}" | jq -r '"kargs = \(tojson)"' > "${mount_path}/usr/lib/bootc/kargs.d/90-bib.toml"
+    fi
+    if [ "${BOOTC_LINT}" = "true" ]; then
+        echo "Linting the bootc image..."
+        buildah run "$container" -- bootc container lint
+    fi
+fi
+[ -z "${current_stage}" ] || end_stage
+
+# Run the verification playbook if one is specified
+verify_result=null
+if [ -n "$VERIFY_ANSIBLE_PLAYBOOK" ]; then
+    begin_stage verify
+    echo "Cloning verification repository ${VERIFY_ANSIBLE_GIT_REPO}..."
+    git_clone "${VERIFY_ANSIBLE_GIT_REPO}" "${VERIFY_ANSIBLE_GIT_BRANCH}" /verify-source "${VERIFY_ANSIBLE_GIT_CREDENTIALS_DIR}" \
+        "${VERIFY_ANSIBLE_GIT_SUBMODULES}" "${VERIFY_ANSIBLE_GIT_SUBMODULE_CREDENTIALS}"
+    echo "Running verification playbook ${VERIFY_ANSIBLE_PLAYBOOK} (${VERIFY_ANSIBLE_MODE:-chroot} mode)..."
+    python=$(ansible_python VERIFY_)
+    if ! run_playbook VERIFY_ "${VERIFY_ANSIBLE_MODE:-chroot}" /verify-source "${VERIFY_ANSIBLE_PLAYBOOK}" "${python}"; then
+        jq -cn --arg message "verification playbook ${VERIFY_ANSIBLE_PLAYBOOK} failed" \
+            '{verify: {passed: false, message: $message}}' > /dev/termination-log
+        unmount_chroot
+        exit 1
+    fi
+    verify_result='{"passed":true}'
+    end_stage
 fi
 
 echo "Cleaning up chroot environment..."
-umount "${mount_path}/dev"
+unmount_chroot
+
+# Remove the state every machine booted from the image must generate on its own.
+if [ "${SANITIZE_MACHINE_ID}" = "true" ] && [ -f "${mount_path}/etc/machine-id" ]; then
+    echo "Resetting the machine ID..."
+    : > "${mount_path}/etc/machine-id"
+    if [ -f "${mount_path}/var/lib/dbus/machine-id" ] && [ ! -L "${mount_path}/var/lib/dbus/machine-id" ]; then
+        ln -sf /etc/machine-id "${mount_path}/var/lib/dbus/machine-id"
+    fi
+fi
+if [ "${SANITIZE_CLOUD_INIT}" = "true" ] && [ -d "${mount_path}/var/lib/cloud" ]; then
+    echo "Cleaning the cloud-init state..."
+    rm -rf "${mount_path}"/var/lib/cloud/*
+    rm -f "${mount_path}"/var/log/cloud-init*.log
+fi
+if [ "${SANITIZE_LOGS}" = "true" ] && [ -d "${mount_path}/var/log" ]; then
+    echo "Truncating the logs..."
+    find "${mount_path}/var/log" -type f -exec truncate -s 0 {} +
+fi
+
+artifacts="[]"
+
+# A registry output ships the provisioned container itself rather than file artifacts.
+if [ -n "${REGISTRY_DESTINATION}" ]; then
+    begin_stage push
+    buildah umount "$container"
+    if [ -n "${REGISTRY_IMAGE_CONFIG}" ]; then
+        echo "Applying the image config..."
+        apply_image_config "$container"
+    fi
+    stamp_image "$container"
+    echo "Committing image ${REGISTRY_DESTINATION}..."
+    buildah commit --format oci "$container" "${REGISTRY_DESTINATION}"
+    if [ -n "${REGISTRY_CREATE_REPOSITORY}" ]; then
+        create_repository
+    fi
+    echo "Pushing image with ${REGISTRY_COMPRESSION_FORMAT:-gzip} compression..."
+    : > /tmp/pushed
+    upload_to "docker://${REGISTRY_DESTINATION}" \
+        push_destination "${REGISTRY_DESTINATION}" /etc/registry-auth/.dockerconfigjson
+    if [ -n "${REGISTRY_ADDITIONAL_DESTINATIONS}" ]; then
+        while read -r destination authfile; do
+            upload_to "docker://${destination}" push_destination "${destination}" "${authfile}"
+        done <<EOF
+${REGISTRY_ADDITIONAL_DESTINATIONS}
+EOF
+    fi
+    wait_uploads
+    end_stage
+    if [ -n "${SIGNING_IDENTITY_TOKEN_FILE}" ]; then
+        begin_stage sign
+        while read -r destination authfile digest; do
+            sign_image "${destination}" "${authfile}" "${digest}"
+        done < /tmp/pushed
+        end_stage
+    fi
+    # Report the pushed images, signed if the sign stage ran.
+    signed=false
+    [ -z "${SIGNING_IDENTITY_TOKEN_FILE}" ] || signed=true
+    while read -r destination authfile digest; do
+        artifacts=$(echo "${artifacts}" | jq -c --arg name "${destination}" --arg digest "${digest}" --argjson signed "${signed}" \
+            '. + [{format: "oci", name: $name, checksum: $digest, signed: $signed}]')
+    done < /tmp/pushed
+    if [ -z "${OUTPUT_FORMATS-tgz,qcow2}" ]; then
+        buildah rm "$container"
+        echo "${artifacts}" | jq -c --argjson verify "${verify_result}" --argjson stages "${stages}" --argjson uploads "$(uploads)" \
+            '{artifacts: ., stages: $stages, uploads: $uploads} + (if $verify == null then {} else {verify: $verify} end)' > /dev/termination-log
+        echo "--- Build complete! ---"
+        exit 0
+    fi
+    buildah mount "$container"
+fi
+
+OUTPUT_FORMATS="${OUTPUT_FORMATS-tgz,qcow2}"
+
+# prune_outputs <root> <metadata> <retain> removes the artifacts of all but the newest <retain>
+# builds of OUTPUT_FILENAME below <root>, ordered by the creation time stamped in their metadata,
+# along with their metadata and, if then empty, their directory. The build of <metadata> is kept.
+# Pruning is best effort: a build that cannot be pruned is left for the next one. As other builds
+# may share a ReadWriteMany claim, only the plain file names listed in a metadata file that parses
+# are removed, from its own directory.
+prune_outputs() {
+    case "$3" in
+        '' | *[!0-9]* | 0)
+            echo "Warning: not pruning older artifacts, retain must be a positive number: $3" >&2
+            return 0
+            ;;
+    esac
+    find "$1" -type f -name "${OUTPUT_FILENAME}.metadata.json" -exec \
+        jq -r '"\(.created) \(input_filename)"' {} \; 2>/dev/null \
+        | sort -r | tail -n +"$(( $3 + 1 ))" \
+        | while read -r created metadata; do
+            [ ! "${metadata}" -ef "$2" ] || continue
+            dir=$(dirname "${metadata}")
+            if ! files=$(jq -er '.artifacts[] | select(.format != "oci") | .name' "${metadata}"); then
+                echo "Warning: not pruning ${dir#"$1"/}, its metadata lists no artifacts" >&2
+                continue
+            fi
+            echo "Pruning the artifacts of the build created ${created} in ${dir#"$1"/}..."
+            echo "${files}" | while read -r file; do
+                case "${file}" in
+                    '' | . | .. | */*) echo "Warning: not pruning ${file} of ${dir#"$1"/}" >&2 ;;
+                    *) rm -f "${dir}/${file}" ;;
+                esac
+            done
+            rm -f "${metadata}"
+            rmdir "${dir}" 2>/dev/null || true
+        done
+}
 
-# Unmount, create tarball, and clean up
-echo "Creating TGZ archive at /output/${OUTPUT_FILENAME}.tgz"
+# has_format returns success if the given format was requested.
+has_format() {
+    case ",${OUTPUT_FORMATS}," in
+        *",$1,"*) return 0 ;;
+    esac
+    return 1
+}
+
+# add_artifact <format> <file> [virtual size] [compressed] records an artifact for the result.
+add_artifact() {
+    size=$(stat -c %s "/output/$2")
+    checksum=$(sha256sum "/output/$2" | cut -d ' ' -f 1)
+    artifacts=$(echo "${artifacts}" | jq -c \
+        --arg format "$1" --arg name "$2" --argjson size "${size}" --arg checksum "sha256:${checksum}" \
+        --argjson virtual "${3:-null}" --argjson compressed "${4:-false}" \
+        '. + [{format: $format, name: $name, sizeBytes: $size, checksum: $checksum, compressed: $compressed}
+              + (if $virtual == null then {} else {virtualSizeBytes: $virtual} end)]')
+}
+
+begin_stage convert
 buildah umount "$container"
-# We re-mount to ensure all changes are flushed to the filesystem before tarring.
+# We re-mount to ensure all changes are flushed to the filesystem before packaging.
 buildah mount "$container"
-tar -czf "/output/${OUTPUT_FILENAME}.tgz" -C "$mount_path" .
+
+# make_disk_image <rootfs dir> <qcow2 file> lays out a partitioned disk image with 1G of free space
+# and copies the rootfs into its root partition, behind an EFI system partition at /boot/efi unless
+# DISK_EFI_PARTITION is "false". The ESP is mounted "quiet" so ownership and modes that FAT cannot
+# represent do not fail the copy.
+make_disk_image() {
+    esp_mib=0
+    [ "${DISK_EFI_PARTITION:-true}" = "false" ] || esp_mib=512
+    if [ "${DISK_PARTITION_TABLE:-gpt}" = "mbr" ]; then
+        table=msdos last_sector=-1
+    else
+        # Leave room for the backup GPT at the end of the disk.
+        table=gpt last_sector=-34
+    fi
+    size_mib=$(( $(du -smx "$1" | cut -f1) + 1024 + esp_mib + 1 ))
+    qemu-img create -f qcow2 ${QCOW2_PREALLOCATION:+-o "preallocation=${QCOW2_PREALLOCATION}"} "$2" "${size_mib}M"
+    tar -C "$1" --one-file-system -cf /tmp/rootfs.tar .
+
+    root_start=2048
+    root=/dev/sda1
+    {
+        echo run
+        echo "part-init /dev/sda ${table}"
+        if [ "${esp_mib}" -gt 0 ]; then
+            root_start=$(( 2048 + esp_mib * 2048 ))
+            root=/dev/sda2
+            echo "part-add /dev/sda p 2048 $(( root_start - 1 ))"
+            if [ "${table}" = gpt ]; then
+                echo "part-set-gpt-type /dev/sda 1 C12A7328-F81F-11D2-BA4B-00A0C93EC93B"
+            else
+                echo "part-set-mbr-id /dev/sda 1 0xef"
+            fi
+            echo "mkfs vfat /dev/sda1 label:ESP"
+        fi
+        echo "part-add /dev/sda p ${root_start} ${last_sector}"
+        [ "${table}" = gpt ] || echo "part-set-bootable /dev/sda ${root#/dev/sda} true"
+        echo "mkfs ${OUTPUT_FILESYSTEM:-ext4} ${root} label:root"
+        echo "mount ${root} /"
+        if [ "${esp_mib}" -gt 0 ]; then
+            echo "mkdir-p /boot/efi"
+            echo "mount-options quiet /dev/sda1 /boot/efi"
+        fi
+        echo "tar-in /tmp/rootfs.tar / xattrs:true"
+    } | guestfish --format=qcow2 -a "$2"
+    rm -f /tmp/rootfs.tar
+}
+
+# layout_fstab <fstab> prints the fstab file without the entries for the mount points of the fstab
+# entries on stdin, or for swap if they include swap, followed by the entries on stdin.
+layout_fstab() {
+    entries=$(cat)
+    if [ -f "$1" ]; then
+        echo "${entries}" | awk 'NR == FNR { replaced[$3 == "swap" ? "swap" : $2] = 1; next }
+            !/^[[:space:]]*#/ && NF >= 3 && (replaced[$2] || ($3 == "swap" && replaced["swap"])) { next }
+            { print }' - "$1"
+    fi
+    [ -z "${entries}" ] || echo "${entries}"
+}
+
+# make_layout_disk_image <rootfs dir> <qcow2 file> lays out a disk image with the partitions of
+# DISK_LAYOUT and copies the rootfs into them, mounted at their mount points, shortest first so
+# nested mount points are mounted on their parents. Without a disk size, a partition without a
+# size is made large enough for the whole rootfs plus 1G of free space. A vfat partition mounted
+# at /boot/efi is made the EFI system partition, labeled ESP unless it has a label.
+# The partitions other than the root are added to /etc/fstab, replacing the image's entries for
+# their mount points and swap: by filesystem UUID, or by label for vfat, whose volume ID guestfish
+# cannot set, so an unlabeled vfat partition is labeled after its number.
+make_layout_disk_image() {
+    layout_table=$(echo "${DISK_LAYOUT}" | jq -r '.table')
+    if [ "${layout_table}" = "mbr" ]; then
+        table=msdos last_sector=-1
+    else
+        table=gpt last_sector=-34
+    fi
+    size_mib=$(echo "${DISK_LAYOUT}" | jq '.sizeMiB // 0')
+    if [ "${size_mib}" -eq 0 ]; then
+        # Reserve the first MiB and the backup GPT.
+        size_mib=$(( $(echo "${DISK_LAYOUT}" | jq '[.partitions[].sizeMiB // 0] | add') + 2 ))
+        if echo "${DISK_LAYOUT}" | jq -e 'any(.partitions[]; .sizeMiB == null)' > /dev/null; then
+            size_mib=$(( size_mib + $(du -smx "$1" | cut -f1) + 1024 ))
+        fi
+    fi
+    qemu-img create -f qcow2 ${QCOW2_PREALLOCATION:+-o "preallocation=${QCOW2_PREALLOCATION}"} "$2" "${size_mib}M"
+    tar -C "$1" --one-file-system -cf /tmp/rootfs.tar .
+
+    : > /tmp/fstab.layout
+    echo "${DISK_LAYOUT}" | jq -r '.partitions | to_entries[] |
+        "\(.key + 1) \(.value.sizeMiB // 0) \(.value.filesystem) \(.value.mountPoint // "-") \(.value.label // "")"' \
+        > /tmp/partitions
+    echo "${DISK_LAYOUT}" | jq -r '.partitions | to_entries | map(select(.value.mountPoint != null)) |
+        sort_by(.value.mountPoint | length)[] | "\(.key + 1) \(.value.filesystem) \(.value.mountPoint)"' \
+        > /tmp/mounts
+    # Legacy BIOS boots the MBR partition holding /boot, or the root partition without one.
+    boot=$(echo "${DISK_LAYOUT}" | jq '[.partitions[].mountPoint] | (index("/boot") // index("/")) + 1')
+    {
+        echo run
+        echo "part-init /dev/sda ${table}"
+        start=2048
+        while read -r number mib fs mount label; do
+            end=${last_sector}
+            [ "${mib}" -eq 0 ] || end=$(( start + mib * 2048 - 1 ))
+            echo "part-add /dev/sda p ${start} ${end}"
+            start=$(( end + 1 ))
+        done < /tmp/partitions
+        [ "${table}" = gpt ] || echo "part-set-bootable /dev/sda ${boot} true"
+        while read -r number mib fs mount label; do
+            uuid=$(cat /proc/sys/kernel/random/uuid)
+            if [ "${fs}" = vfat ] && [ "${mount}" = /boot/efi ]; then
+                if [ "${table}" = gpt ]; then
+                    echo "part-set-gpt-type /dev/sda ${number} C12A7328-F81F-11D2-BA4B-00A0C93EC93B"
+                else
+                    echo "part-set-mbr-id /dev/sda ${number} 0xef"
+                fi
+                label=${label:-ESP}
+            fi
+            case "${fs}" in
+            swap)
+                echo "mkswap /dev/sda${number}${label:+ label:${label}} uuid:${uuid}"
+                echo "UUID=${uuid} none swap defaults 0 0" >> /tmp/fstab.layout
+                ;;
+            vfat)
+                label=${label:-PART${number}}
+                echo "mkfs vfat /dev/sda${number} label:${label}"
+                echo "LABEL=${label} ${mount} vfat defaults 0 2" >> /tmp/fstab.layout
+                ;;
+            *)
+                echo "mkfs ${fs} /dev/sda${number}${label:+ label:${label}}"
+                echo "set-uuid /dev/sda${number} ${uuid}"
+                [ "${mount}" = / ] || echo "UUID=${uuid} ${mount} ${fs} defaults 0 2" >> /tmp/fstab.layout
+                ;;
+            esac
+        done < /tmp/partitions
+        while read -r number fs mount; do
+            [ "${mount}" = / ] || echo "mkdir-p ${mount}"
+            if [ "${fs}" = vfat ]; then
+                echo "mount-options quiet /dev/sda${number} ${mount}"
+            else
+                echo "mount /dev/sda${number} ${mount}"
+            fi
+        done < /tmp/mounts
+        echo "tar-in /tmp/rootfs.tar / xattrs:true"
+        layout_fstab "$1/etc/fstab" < /tmp/fstab.layout > /tmp/fstab
+        echo "upload /tmp/fstab /etc/fstab"
+    } | guestfish --format=qcow2 -a "$2"
+    rm -f /tmp/rootfs.tar /tmp/partitions /tmp/mounts /tmp/fstab.layout /tmp/fstab
+}
+
+# make_bootc_disk_image <rootfs dir> <qcow2 file> installs the container, a bootc image, to a disk
+# image with `bootc install to-disk`, run from the image itself as bootc expects, so the disk boots
+# into an ostree deployment of the image that follows BOOTC_TARGET_IMGREF for updates. The disk has
+# room for the rootfs and 2G for the boot partitions and free space.
+# bootc documents running it with `podman run --privileged --pid=host`. buildah run has no
+# --privileged, so its effect is spelled out: every capability, no seccomp filter or SELinux
+# confinement, and the devices of the privileged builder pod. --pid host lets bootc see the
+# processes of the pod, as it does on a host.
+make_bootc_disk_image() {
+    buildah commit --quiet "$container" localhost/bib-bootc > /dev/null
+    installer=$(buildah from --pull=never localhost/bib-bootc)
+    raw_file="$2.raw"
+    truncate -s "$(( $(du -smx "$1" | cut -f1) + 2048 ))M" "${raw_file}"
+    buildah run --pid host --cap-add all --security-opt seccomp=unconfined \
+        --security-opt label=type:unconfined_t --volume /dev:/dev \
+        --volume /var/lib/containers/storage:/var/lib/containers/storage \
+        --volume "$(dirname "${raw_file}"):/bib-disk" "${installer}" -- \
+        bootc install to-disk --via-loopback --generic-image --skip-fetch-check \
+        --source-imgref containers-storage:localhost/bib-bootc --target-imgref "${BOOTC_TARGET_IMGREF}" \
+        --filesystem "${OUTPUT_FILESYSTEM:-ext4}" "/bib-disk/$(basename "${raw_file}")"
+    buildah rm "${installer}"
+    qemu-img convert -f raw -O qcow2 ${QCOW2_PREALLOCATION:+-o "preallocation=${QCOW2_PREALLOCATION}"} "${raw_file}" "$2"
+    rm -f "${raw_file}"
+}
+
+if has_format tgz; then
+    echo "Creating TGZ archive at /output/${OUTPUT_FILENAME}.tgz"
+    tar -czf "/output/${OUTPUT_FILENAME}.tgz" -C "$mount_path" .
+    add_artifact tgz "${OUTPUT_FILENAME}.tgz"
+fi
+
+if has_format qcow2; then
+    qcow2_file="${OUTPUT_FILENAME}.qcow2"
+    echo "Creating qcow2 disk image at /output/${qcow2_file}"
+    if [ "${BOOTC}" = "true" ]; then
+        make_bootc_disk_image "$mount_path" "/output/${qcow2_file}"
+    elif [ -n "${DISK_LAYOUT}" ]; then
+        make_layout_disk_image "$mount_path" "/output/${qcow2_file}"
+    else
+        make_disk_image "$mount_path" "/output/${qcow2_file}"
+    fi
+    compressed=false
+    if [ "${QCOW2_COMPRESS}" = "true" ]; then
+        echo "Compressing qcow2 disk image..."
+        qemu-img convert -c -O qcow2 "/output/${qcow2_file}" "/output/${qcow2_file}.tmp"
+        mv "/output/${qcow2_file}.tmp" "/output/${qcow2_file}"
+        compressed=true
+    fi
+    virtual_size=$(qemu-img info --output=json "/output/${qcow2_file}" | jq '.["virtual-size"]')
+    add_artifact qcow2 "${qcow2_file}" "${virtual_size}" "${compressed}"
+fi
+
 buildah umount "$container"
 buildah rm "$container"
 
+# Stamp the artifacts with their source, so external lifecycle rules can act on them.
+echo "${artifacts}" | jq \
+    --arg name "${ARTIFACT_IMAGEBUILD_NAME}" --arg namespace "${ARTIFACT_IMAGEBUILD_NAMESPACE}" \
+    --arg uid "${ARTIFACT_IMAGEBUILD_UID}" --arg created "${ARTIFACT_CREATED}" \
+    '{imageBuild: {name: $name, namespace: $namespace, uid: $uid}, created: $created, artifacts: .}' \
+    > "/output/${OUTPUT_FILENAME}.metadata.json"
+end_stage
+
+# Copy the artifacts and their metadata to object storage, if requested.
+if [ -n "${OUTPUT_S3_URL}" ]; then
+    begin_stage upload
+    # Keep the credentials out of the trace.
+    set +x
+    echo "${artifacts}" | jq -r '.[] | "\(.format) \(.name)"' > /tmp/uploads
+    # The metadata is uploaded once all artifacts are, so it only ever describes complete uploads.
+    echo "metadata ${OUTPUT_FILENAME}.metadata.json" >> /tmp/uploads
+    while read -r format file; do
+        [ "${format}" != metadata ] || wait_uploads
+        case "${format}" in
+            metadata) content_type=application/json ;;
+            tgz) content_type="${OUTPUT_S3_CONTENT_TYPE:-application/gzip}" ;;
+            qcow2) content_type="${OUTPUT_S3_CONTENT_TYPE:-application/x-qemu-disk}" ;;
+            *) content_type="${OUTPUT_S3_CONTENT_TYPE:-application/octet-stream}" ;;
+        esac
+        storage_class="${OUTPUT_S3_STORAGE_CLASS}"
+        if [ "${format}" = metadata ] && [ -n "${storage_class}" ]; then
+            storage_class=STANDARD
+        fi
+        echo "Uploading /output/${file} to ${OUTPUT_S3_URL}${file} as ${content_type}${storage_class:+ (${storage_class})}..."
+        upload_to "${OUTPUT_S3_URL}${file}" \
+            with_aws_credentials OUTPUT_ aws s3 cp ${OUTPUT_S3_REGION:+--region "${OUTPUT_S3_REGION}"} \
+            --content-type "${content_type}" ${storage_class:+--storage-class "${storage_class}"} \
+            --metadata "$(artifact_metadata)" \
+            "/output/${file}" "${OUTPUT_S3_URL}${file}"
+    done < /tmp/uploads
+    wait_uploads
+    set -x
+    end_stage
+fi
+
+# Keep only the newest OUTPUT_RETAIN builds of OUTPUT_FILENAME on the output PVC. The artifacts of
+# this build are in place, so it is the newest.
+if [ -n "${OUTPUT_RETAIN}" ]; then
+    prune_outputs /output-root "/output/${OUTPUT_FILENAME}.metadata.json" "${OUTPUT_RETAIN}" \
+        || echo "Warning: failed to prune older artifacts" >&2
+fi
+
+# Report the artifacts to the operator.
+echo "${artifacts}" | jq -c --argjson verify "${verify_result}" --argjson stages "${stages}" --argjson uploads "$(uploads)" \
+    '{artifacts: ., stages: $stages, uploads: $uploads} + (if $verify == null then {} else {verify: $verify} end)' > /dev/termination-log
+
 echo "--- Build complete! ---"
\ No newline at end of file
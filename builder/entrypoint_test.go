@@ -0,0 +1,306 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// shellFunctions returns the definitions of the named functions of a builder script, so they can run
+// without the build the script performs at the top level. They write their termination message to
+// termination-log in the working directory rather than to /dev/termination-log.
+func shellFunctions(script string, names ...string) string {
+	content, err := os.ReadFile(script)
+	Expect(err).NotTo(HaveOccurred())
+	lines := strings.Split(string(content), "\n")
+	var definitions []string
+	for _, name := range names {
+		start := slices.IndexFunc(lines, func(line string) bool { return strings.HasPrefix(line, name+"() ") })
+		Expect(start).NotTo(Equal(-1), "function %s of %s", name, script)
+		end := start + slices.IndexFunc(lines[start:], func(line string) bool { return line == "}" || line == ")" })
+		definitions = append(definitions, strings.Join(lines[start:end+1], "\n"))
+	}
+	return strings.ReplaceAll(strings.Join(definitions, "\n"), "/dev/termination-log", "termination-log")
+}
+
+// builderRun is the outcome of a script run by runBuilder.
+type builderRun struct {
+	// Dir is the scratch directory the script ran in.
+	Dir string
+	// Output is the combined standard output and error of the script.
+	Output string
+	// Calls lists the invocations of the stubbed commands, as "<command> <args...>".
+	Calls []string
+	// Err is the error of a script that exited non-zero.
+	Err error
+}
+
+// runBuilder runs script with sh in a scratch directory, exiting on the first failing command like
+// the builder scripts, and with env added to the environment. Each
+// command of stubs is replaced by a script recording its invocation in Calls and then running the
+// body of the stub.
+func runBuilder(script string, env map[string]string, stubs map[string]string) builderRun {
+	dir := GinkgoT().TempDir()
+	bin := filepath.Join(dir, ".bin")
+	Expect(os.Mkdir(bin, 0o755)).To(Succeed())
+	calls := filepath.Join(dir, ".calls")
+	for name, body := range stubs {
+		stub := fmt.Sprintf("#!/bin/sh\necho \"%s $*\" >> %s\n%s\n", name, calls, body)
+		Expect(os.WriteFile(filepath.Join(bin, name), []byte(stub), 0o755)).To(Succeed())
+	}
+
+	cmd := exec.Command("sh", "-ec", script)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	for name, value := range env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+	output, err := cmd.CombinedOutput()
+	run := builderRun{Dir: dir, Output: string(output), Err: err}
+	if recorded, err := os.ReadFile(calls); err == nil {
+		run.Calls = strings.Split(strings.TrimSuffix(string(recorded), "\n"), "\n")
+	}
+	return run
+}
+
+var _ = Describe("Builder entrypoint", func() {
+	DescribeTable("reports an unusable output volume",
+		func(available string, message string) {
+			run := runBuilder(shellFunctions("entrypoint.sh", "check_output_storage")+
+				"\nmkdir output && check_output_storage output",
+				map[string]string{"AVAILABLE": available},
+				map[string]string{"df": `printf 'Filesystem 1024-blocks Used Available Capacity Mounted on\n/dev/sdb 1000 1000 %s 100%% /output\n' "${AVAILABLE}"`})
+			Expect(run.Err).NotTo(HaveOccurred(), run.Output)
+			Expect(filepath.Join(run.Dir, "output", ".bib-write-check")).NotTo(BeAnExistingFile())
+			result, err := os.ReadFile(filepath.Join(run.Dir, "termination-log"))
+			if message == "" {
+				Expect(err).To(MatchError(os.ErrNotExist))
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(result)).To(MatchJSON(`{"outputStorage":{"message":` + strconv.Quote(message) + `}}`))
+		},
+		Entry("a full volume", "12", "the output volume is full"),
+		Entry("a volume with free space", "1048576", ""),
+	)
+
+	DescribeTable("reports registry rate limiting",
+		func(stderr string, rateLimited bool) {
+			run := runBuilder(shellFunctions("entrypoint.sh", "with_registry_rate_limit")+
+				"\nwith_registry_rate_limit push quay.io/example/image:latest buildah push || true",
+				map[string]string{"STDERR": stderr}, map[string]string{"buildah": `echo "${STDERR}" >&2; exit 1`})
+			Expect(run.Err).NotTo(HaveOccurred(), run.Output)
+			result, err := os.ReadFile(filepath.Join(run.Dir, "termination-log"))
+			if !rateLimited {
+				Expect(err).To(MatchError(os.ErrNotExist))
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(result)).To(MatchJSON(`{"rateLimited":{"operation":"push","image":"quay.io/example/image:latest","message":` +
+				strconv.Quote(stderr) + `}}`))
+		},
+		Entry("the HTTP status", "received unexpected HTTP status: 429 Too Many Requests", true),
+		Entry("the registry error code", "toomanyrequests: You have reached your pull rate limit", true),
+		Entry("a digest containing 429", "blob sha256:4291f0c3 unknown to registry", false),
+		Entry("a size containing 429", "writing blob: 1429 bytes: connection reset by peer", false),
+	)
+
+	It("runs the Ansible modules with the interpreter set, or with Ansible's discovery", func() {
+		run := runBuilder(shellFunctions("entrypoint.sh", "ansible_python")+"\nansible_python VERIFY_; ansible_python ''",
+			map[string]string{"VERIFY_ANSIBLE_PYTHON_INTERPRETER": "/usr/bin/python3.12"}, nil)
+		Expect(run.Err).NotTo(HaveOccurred(), run.Output)
+		Expect(run.Output).To(Equal("/usr/bin/python3.12\nauto\n"))
+	})
+
+	DescribeTable("caps the reported uploads",
+		func(results []string, expected string) {
+			script := "mkdir uploads.d\n"
+			for i, result := range results {
+				script += fmt.Sprintf("echo '%s' > uploads.d/%d.json\n", result, i)
+			}
+			script += strings.ReplaceAll(shellFunctions("entrypoint.sh", "uploads"), "/tmp/uploads.d", "uploads.d") + "\nuploads"
+			run := runBuilder(script, map[string]string{"OUTPUT_S3_URL": "s3://bucket/build/"}, nil)
+			Expect(run.Err).NotTo(HaveOccurred(), run.Output)
+			Expect(run.Output).To(MatchJSON(expected))
+		},
+		Entry("successful files reported once for the prefix", []string{
+			`{"url":"s3://bucket/build/disk.qcow2","phase":"Succeeded"}`,
+			`{"url":"s3://bucket/build/disk.raw","phase":"Succeeded"}`,
+			`{"url":"quay.io/example/image:latest","phase":"Succeeded"}`,
+		}, `[{"url":"quay.io/example/image:latest","phase":"Succeeded"},{"url":"s3://bucket/build/","phase":"Succeeded"}]`),
+		Entry("failures first with their message cut", []string{
+			`{"url":"quay.io/example/image:latest","phase":"Succeeded"}`,
+			`{"url":"s3://bucket/build/disk.qcow2","phase":"Succeeded"}`,
+			`{"url":"s3://bucket/build/disk.raw","phase":"Failed","message":"` + strings.Repeat("x", 300) + `"}`,
+		}, `[{"url":"s3://bucket/build/disk.raw","phase":"Failed","message":"`+strings.Repeat("x", 200)+`"},`+
+			`{"url":"quay.io/example/image:latest","phase":"Succeeded"}]`),
+		Entry("at most 16 uploads", slices.Repeat([]string{`{"url":"quay.io/example/image:latest","phase":"Succeeded"}`}, 20),
+			`[`+strings.Repeat(`{"url":"quay.io/example/image:latest","phase":"Succeeded"},`, 15)+
+				`{"url":"quay.io/example/image:latest","phase":"Succeeded"}]`),
+	)
+
+	Context("stamping the artifacts", func() {
+		env := map[string]string{
+			"ARTIFACT_IMAGEBUILD_NAME":      "stamped",
+			"ARTIFACT_IMAGEBUILD_NAMESPACE": "default",
+			"ARTIFACT_IMAGEBUILD_UID":       "0b5c3e1a",
+			"ARTIFACT_CREATED":              "2025-06-01T12:00:00Z",
+		}
+
+		It("labels the pushed image with its source", func() {
+			run := runBuilder(shellFunctions("entrypoint.sh", "stamp_image")+"\nstamp_image working-container",
+				env, map[string]string{"buildah": ""})
+			Expect(run.Err).NotTo(HaveOccurred(), run.Output)
+			Expect(run.Calls).To(Equal([]string{"buildah config" +
+				" --label io.x-k8s.cluster.bib.imagebuild.name=stamped" +
+				" --label io.x-k8s.cluster.bib.imagebuild.namespace=default" +
+				" --label io.x-k8s.cluster.bib.imagebuild.uid=0b5c3e1a" +
+				" --label org.opencontainers.image.created=2025-06-01T12:00:00Z working-container"}))
+		})
+
+		It("passes the source as the metadata of uploaded objects", func() {
+			run := runBuilder(shellFunctions("entrypoint.sh", "artifact_metadata")+"\nartifact_metadata", env, nil)
+			Expect(run.Err).NotTo(HaveOccurred(), run.Output)
+			Expect(run.Output).To(Equal(
+				"imagebuild-name=stamped,imagebuild-namespace=default,imagebuild-uid=0b5c3e1a,created=2025-06-01T12:00:00Z\n"))
+		})
+	})
+
+	Context("pruning older builds from the output PVC", func() {
+		// build writes the artifacts and metadata of a build created at created to dir below root.
+		build := func(created string, dir string, files ...string) string {
+			script := "mkdir -p root/" + dir + "\n"
+			var artifacts []string
+			for _, file := range files {
+				script += fmt.Sprintf("touch 'root/%s/%s'\n", dir, file)
+				artifacts = append(artifacts, fmt.Sprintf(`{"format":"qcow2","name":%q}`, file))
+			}
+			return script + fmt.Sprintf("echo '{\"created\":%q,\"artifacts\":[%s]}' > root/%s/golden.metadata.json\n",
+				created, strings.Join(artifacts, ","), dir)
+		}
+		prune := func(setup string, retain string) builderRun {
+			return runBuilder(setup+shellFunctions("entrypoint.sh", "prune_outputs")+
+				"\nprune_outputs \"$PWD/root\" \"$PWD/root/default/current/golden.metadata.json\" "+retain,
+				map[string]string{"OUTPUT_FILENAME": "golden"}, nil)
+		}
+
+		It("keeps the newest builds, including the current one", func() {
+			run := prune(build("2025-03-01T00:00:00Z", "default/current", "golden.qcow2")+
+				build("2025-02-01T00:00:00Z", "default/previous", "golden.qcow2")+
+				build("2025-01-01T00:00:00Z", "default/oldest", "golden.qcow2", "golden.raw")+
+				build("2025-01-01T00:00:00Z", "default/other", "other.qcow2")+
+				"mv root/default/other/golden.metadata.json root/default/other/other.metadata.json\n", "2")
+			Expect(run.Err).NotTo(HaveOccurred(), run.Output)
+			Expect(filepath.Join(run.Dir, "root/default/current/golden.qcow2")).To(BeAnExistingFile())
+			Expect(filepath.Join(run.Dir, "root/default/previous/golden.qcow2")).To(BeAnExistingFile())
+			Expect(filepath.Join(run.Dir, "root/default/oldest")).NotTo(BeAnExistingFile())
+			// Other images are not counted.
+			Expect(filepath.Join(run.Dir, "root/default/other/other.qcow2")).To(BeAnExistingFile())
+		})
+
+		It("removes only the plain file names listed in a metadata file that parses", func() {
+			run := prune(build("2025-03-01T00:00:00Z", "default/current", "golden.qcow2")+
+				build("2025-01-01T00:00:00Z", "default/traversal", "golden.qcow2")+
+				"touch root/default/keep\n"+
+				"jq -c '.artifacts += [{\"format\":\"raw\",\"name\":\"../keep\"}]' root/default/traversal/golden.metadata.json > m\n"+
+				"mv m root/default/traversal/golden.metadata.json\n"+
+				build("2025-01-01T00:00:00Z", "default/broken", "golden.qcow2")+
+				"echo '{' > root/default/broken/golden.metadata.json\n", "1")
+			Expect(run.Err).NotTo(HaveOccurred(), run.Output)
+			Expect(filepath.Join(run.Dir, "root/default/current/golden.qcow2")).To(BeAnExistingFile())
+			Expect(filepath.Join(run.Dir, "root/default/traversal/golden.qcow2")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(run.Dir, "root/default/keep")).To(BeAnExistingFile())
+			Expect(filepath.Join(run.Dir, "root/default/broken/golden.qcow2")).To(BeAnExistingFile())
+			Expect(run.Output).To(ContainSubstring("not pruning ../keep of default/traversal"))
+		})
+
+		It("prunes nothing without a positive number to retain", func() {
+			run := prune(build("2025-03-01T00:00:00Z", "default/current", "golden.qcow2")+
+				build("2025-01-01T00:00:00Z", "default/oldest", "golden.qcow2"), "0")
+			Expect(run.Err).NotTo(HaveOccurred(), run.Output)
+			Expect(filepath.Join(run.Dir, "root/default/oldest/golden.qcow2")).To(BeAnExistingFile())
+		})
+	})
+
+	Context("laying out a disk image", func() {
+		layout := `{"table":"gpt","partitions":[
+			{"sizeMiB":512,"filesystem":"vfat","mountPoint":"/boot/efi"},
+			{"sizeMiB":1024,"filesystem":"swap"},
+			{"sizeMiB":2048,"filesystem":"xfs","mountPoint":"/var","label":"var"},
+			{"filesystem":"ext4","mountPoint":"/"}]}`
+
+		It("mounts the partitions other than the root through /etc/fstab", func() {
+			run := runBuilder("mkdir -p rootfs/etc\n"+
+				"printf 'LABEL=cloudimg-rootfs / ext4 defaults 0 1\\nLABEL=UEFI /boot/efi vfat umask=0077 0 1\\n"+
+				"/swap.img none swap sw 0 0\\n# /var is on the root\\n' > rootfs/etc/fstab\n"+
+				strings.ReplaceAll(shellFunctions("entrypoint.sh", "layout_fstab", "make_layout_disk_image"), "/tmp/", "./")+
+				"\nmake_layout_disk_image rootfs golden.qcow2",
+				map[string]string{"DISK_LAYOUT": layout},
+				map[string]string{
+					"qemu-img":  "",
+					"tar":       "",
+					"du":        `printf '100\t%s\n' "$3"`,
+					"guestfish": "cat > guestfish.script; cp fstab fstab.uploaded",
+				})
+			Expect(run.Err).NotTo(HaveOccurred(), run.Output)
+
+			script, err := os.ReadFile(filepath.Join(run.Dir, "guestfish.script"))
+			Expect(err).NotTo(HaveOccurred())
+			uuids := regexp.MustCompile(`(?m)^(?:mkswap /dev/sda2 uuid:|set-uuid /dev/sda[34] )(\S+)$`).FindAllStringSubmatch(string(script), -1)
+			Expect(uuids).To(HaveLen(3))
+			Expect(string(script)).To(ContainSubstring("mkfs vfat /dev/sda1 label:ESP\n"))
+			Expect(string(script)).To(HaveSuffix("upload ./fstab /etc/fstab\n"))
+
+			fstab, err := os.ReadFile(filepath.Join(run.Dir, "fstab.uploaded"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(fstab)).To(Equal("LABEL=cloudimg-rootfs / ext4 defaults 0 1\n" +
+				"# /var is on the root\n" +
+				"LABEL=ESP /boot/efi vfat defaults 0 2\n" +
+				"UUID=" + uuids[0][1] + " none swap defaults 0 0\n" +
+				"UUID=" + uuids[1][1] + " /var xfs defaults 0 2\n"))
+		})
+	})
+
+	It("reports the submodule that cannot be cloned rather than its superproject", func() {
+		run := runBuilder(`git init -q -b main super
+printf '[submodule "roles"]\n\tpath = roles\n\turl = %s/missing.git\n' "$PWD" > super/.gitmodules
+git -C super add .gitmodules
+git -C super update-index --add --cacheinfo 160000,1111111111111111111111111111111111111111,roles
+git -C super -c user.name=bib -c user.email=bib@example.com commit -qm init
+`+shellFunctions("entrypoint.sh", "git_with_credentials", "git_submodule_update", "git_clone")+`
+if git_clone "$PWD/super" main dest "" true ""; then exit 1; fi`, nil, nil)
+		Expect(run.Err).NotTo(HaveOccurred(), run.Output)
+		result, err := os.ReadFile(filepath.Join(run.Dir, "termination-log"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(result)).To(And(
+			ContainSubstring(`"repo":"`+run.Dir+`/missing.git"`),
+			ContainSubstring(`"submoduleOf":"`+run.Dir+`/super"`),
+			ContainSubstring(`"message":"fatal: `),
+			Not(ContainSubstring(`"branch"`)),
+		))
+	})
+})
@@ -0,0 +1,148 @@
+#!/usr/bin/env python3
+"""Parses a kickstart file into the plan the builder applies to the image.
+
+Usage: kickstart.py <kickstart file> <script dir>
+
+Only the parts of a kickstart describing the content of the system apply to an image built from a
+container: the %packages section, the services, timezone and lang commands, and the %post scripts.
+The other commands and sections, e.g. partitioning, the bootloader or %pre scripts, set up the
+installer; they are listed as ignored. %include directives are followed, relative to the including
+file. The plan is printed as JSON, and the %post scripts are written to the script dir in order.
+"""
+
+import json
+import os
+import shlex
+import sys
+
+# The options of the applied commands and sections that take a value, e.g. "--enabled sshd".
+VALUE_OPTIONS = ("--enabled", "--disabled", "--interpreter", "--log", "--addsupport")
+
+
+class KickstartError(Exception):
+    pass
+
+
+def read_lines(path, including=()):
+    """Yields the lines of a kickstart file, with %include directives replaced by the included file."""
+    path = os.path.abspath(path)
+    if path in including:
+        raise KickstartError(f"{path} includes itself")
+    with open(path, encoding="utf-8") as kickstart:
+        for line in kickstart:
+            line = line.rstrip("\n")
+            words = line.split()
+            if words and words[0] == "%include":
+                if len(words) != 2:
+                    raise KickstartError(f"{path}: %include takes exactly one file: {line}")
+                included = os.path.join(os.path.dirname(path), words[1])
+                yield from read_lines(included, including + (path,))
+            else:
+                yield line
+
+
+def parse_options(words):
+    """Splits the words following a command or section header into its options and arguments."""
+    options, arguments = {}, []
+    words = iter(words)
+    for word in words:
+        if not word.startswith("--"):
+            arguments.append(word)
+            continue
+        name, has_value, value = word.partition("=")
+        if not has_value and name in VALUE_OPTIONS:
+            value = next(words, "")
+        options[name] = value if has_value or name in VALUE_OPTIONS else True
+    return options, arguments
+
+
+def split_list(value):
+    return [item for item in value.split(",") if item] if isinstance(value, str) else []
+
+
+def parse(path, script_dir):
+    plan = {
+        "install": [],
+        "remove": [],
+        "dnfOptions": [],
+        "enabledServices": [],
+        "disabledServices": [],
+        "timezone": "",
+        "lang": "",
+        "scripts": [],
+        "ignored": [],
+    }
+    section, options, body = None, {}, []
+    for line in read_lines(path):
+        stripped = line.strip()
+        if section is not None:
+            if stripped != "%end":
+                body.append(line)
+                continue
+            if section == "%packages":
+                parse_packages(plan, options, body)
+            elif section == "%post":
+                script = os.path.join(script_dir, f"post-{len(plan['scripts'])}")
+                with open(script, "w", encoding="utf-8") as out:
+                    out.write("\n".join(body) + "\n")
+                plan["scripts"].append({
+                    "file": script,
+                    "interpreter": options.get("--interpreter") or "/bin/sh",
+                    "chroot": "--nochroot" not in options,
+                    "errorOnFail": "--erroronfail" in options,
+                })
+            else:
+                plan["ignored"].append(section)
+            section, options, body = None, {}, []
+            continue
+
+        words = shlex.split(stripped, comments=True)
+        if not words:
+            continue
+        name, (options, arguments) = words[0], parse_options(words[1:])
+        if name.startswith("%"):
+            section = name
+        elif name == "services":
+            plan["enabledServices"] += split_list(options.get("--enabled"))
+            plan["disabledServices"] += split_list(options.get("--disabled"))
+        elif name == "timezone" and arguments:
+            plan["timezone"] = arguments[0]
+        elif name == "lang" and arguments:
+            plan["lang"] = arguments[0]
+        else:
+            plan["ignored"].append(name)
+    if section is not None:
+        raise KickstartError(f"{section} section is missing its %end")
+    return plan
+
+
+def parse_packages(plan, options, body):
+    """Adds the packages, groups and modules of a %packages section to install, or to remove with "-"."""
+    for line in body:
+        for word in shlex.split(line, comments=True):
+            if word.startswith("-"):
+                plan["remove"].append(word[1:])
+            else:
+                plan["install"].append(word)
+    if "--ignoremissing" in options:
+        plan["dnfOptions"].append("--setopt=strict=False")
+    if "--excludedocs" in options:
+        plan["dnfOptions"].append("--setopt=tsflags=nodocs")
+
+
+def main():
+    if len(sys.argv) != 3:
+        print(__doc__, file=sys.stderr)
+        return 2
+    os.makedirs(sys.argv[2], exist_ok=True)
+    try:
+        plan = parse(sys.argv[1], sys.argv[2])
+    except (KickstartError, OSError, ValueError) as err:
+        print(f"Invalid kickstart file: {err}", file=sys.stderr)
+        return 1
+    json.dump(plan, sys.stdout)
+    return 0
+
+
+if __name__ == "__main__":
+    sys.exit(main())
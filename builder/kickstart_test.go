@@ -0,0 +1,205 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// kickstartPlan is the plan printed by kickstart.py.
+type kickstartPlan struct {
+	Install          []string `json:"install"`
+	Remove           []string `json:"remove"`
+	DNFOptions       []string `json:"dnfOptions"`
+	EnabledServices  []string `json:"enabledServices"`
+	DisabledServices []string `json:"disabledServices"`
+	Timezone         string   `json:"timezone"`
+	Lang             string   `json:"lang"`
+	Scripts          []struct {
+		File        string `json:"file"`
+		Interpreter string `json:"interpreter"`
+		Chroot      bool   `json:"chroot"`
+		ErrorOnFail bool   `json:"errorOnFail"`
+	} `json:"scripts"`
+	Ignored []string `json:"ignored"`
+}
+
+// writeKickstart writes files, by their path relative to a scratch directory, and returns the
+// directory.
+func writeKickstart(files map[string]string) string {
+	dir := GinkgoT().TempDir()
+	for name, content := range files {
+		Expect(os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)).To(Succeed())
+	}
+	return dir
+}
+
+// parseKickstart runs kickstart.py on ks.cfg of files, writing the scripts to the scripts directory
+// next to it. It returns the plan, or the error output of a kickstart that does not parse.
+func parseKickstart(files map[string]string) (string, *kickstartPlan, string) {
+	dir := writeKickstart(files)
+	cmd := exec.Command("python3", "kickstart.py", filepath.Join(dir, "ks.cfg"), filepath.Join(dir, "scripts"))
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return dir, nil, stderr.String()
+	}
+	plan := &kickstartPlan{}
+	Expect(json.Unmarshal(output, plan)).To(Succeed())
+	return dir, plan, ""
+}
+
+var _ = Describe("Builder kickstart", func() {
+	Context("parsing a kickstart file", func() {
+		It("plans the packages, services, timezone and lang, and ignores the installer commands", func() {
+			_, plan, errors := parseKickstart(map[string]string{"ks.cfg": `# Golden image
+lang en_US.UTF-8
+timezone Europe/Berlin --utc
+services --enabled=sshd,chronyd --disabled cups
+part / --fstype=xfs --grow
+bootloader --location=mbr
+%packages --ignoremissing --excludedocs
+@core
+vim-enhanced  # the editor
+kernel-*
+-plymouth
+%end
+%pre
+echo installer
+%end
+`})
+			Expect(errors).To(BeEmpty())
+			Expect(plan.Install).To(Equal([]string{"@core", "vim-enhanced", "kernel-*"}))
+			Expect(plan.Remove).To(Equal([]string{"plymouth"}))
+			Expect(plan.DNFOptions).To(Equal([]string{"--setopt=strict=False", "--setopt=tsflags=nodocs"}))
+			Expect(plan.EnabledServices).To(Equal([]string{"sshd", "chronyd"}))
+			Expect(plan.DisabledServices).To(Equal([]string{"cups"}))
+			Expect(plan.Timezone).To(Equal("Europe/Berlin"))
+			Expect(plan.Lang).To(Equal("en_US.UTF-8"))
+			Expect(plan.Ignored).To(Equal([]string{"part", "bootloader", "%pre"}))
+			Expect(plan.Scripts).To(BeEmpty())
+		})
+
+		It("writes the %post scripts in order, with their options", func() {
+			dir, plan, errors := parseKickstart(map[string]string{"ks.cfg": `%post --erroronfail
+echo first
+%end
+%post --nochroot --interpreter /usr/bin/python3
+print("second")
+%end
+`})
+			Expect(errors).To(BeEmpty())
+			Expect(plan.Scripts).To(HaveLen(2))
+			Expect(plan.Scripts[0].File).To(Equal(filepath.Join(dir, "scripts", "post-0")))
+			Expect(plan.Scripts[0].Interpreter).To(Equal("/bin/sh"))
+			Expect(plan.Scripts[0].Chroot).To(BeTrue())
+			Expect(plan.Scripts[0].ErrorOnFail).To(BeTrue())
+			Expect(plan.Scripts[1].Interpreter).To(Equal("/usr/bin/python3"))
+			Expect(plan.Scripts[1].Chroot).To(BeFalse())
+			Expect(plan.Scripts[1].ErrorOnFail).To(BeFalse())
+			Expect(os.ReadFile(plan.Scripts[0].File)).To(BeEquivalentTo("echo first\n"))
+			Expect(os.ReadFile(plan.Scripts[1].File)).To(BeEquivalentTo("print(\"second\")\n"))
+		})
+
+		It("follows %include relative to the including file", func() {
+			_, plan, errors := parseKickstart(map[string]string{
+				"ks.cfg":               "%include common/base.cfg\ntimezone UTC\n",
+				"common/base.cfg":      "lang de_DE.UTF-8\n%include packages.cfg\n",
+				"common/packages.cfg":  "%packages\ntmux\n%end\n",
+				"common/unrelated.cfg": "lang fr_FR.UTF-8\n",
+			})
+			Expect(errors).To(BeEmpty())
+			Expect(plan.Lang).To(Equal("de_DE.UTF-8"))
+			Expect(plan.Timezone).To(Equal("UTC"))
+			Expect(plan.Install).To(Equal([]string{"tmux"}))
+		})
+
+		DescribeTable("rejects an invalid kickstart file",
+			func(files map[string]string, message string) {
+				_, plan, errors := parseKickstart(files)
+				Expect(plan).To(BeNil())
+				Expect(errors).To(HavePrefix("Invalid kickstart file: "))
+				Expect(errors).To(ContainSubstring(message))
+			},
+			Entry("a section without %end", map[string]string{"ks.cfg": "%packages\nvim\n"}, "%packages section is missing its %end"),
+			Entry("a file including itself", map[string]string{"ks.cfg": "%include ks.cfg\n"}, "includes itself"),
+			Entry("an %include without a file", map[string]string{"ks.cfg": "%include\n"}, "%include takes exactly one file"),
+			Entry("a missing included file", map[string]string{"ks.cfg": "%include missing.cfg\n"}, "missing.cfg"),
+			Entry("an unterminated quote", map[string]string{"ks.cfg": "lang \"en_US\n"}, "quotation"),
+		)
+	})
+
+	Context("applying a kickstart file", func() {
+		// apply runs apply_kickstart for ks.cfg of files on an image whose package manager is dnf, in
+		// which the packages named by $INSTALLED are installed.
+		apply := func(files map[string]string) builderRun {
+			source := writeKickstart(files)
+			script, err := filepath.Abs("kickstart.py")
+			Expect(err).NotTo(HaveOccurred())
+			function := strings.NewReplacer("/workspace/kickstart.py", script, "/source/", source+"/",
+				"/tmp/kickstart", source+"/scripts").Replace(shellFunctions("entrypoint.sh", "apply_kickstart"))
+			return runBuilder("mkdir -p rootfs/usr/bin rootfs/etc && touch rootfs/usr/bin/dnf && chmod +x rootfs/usr/bin/dnf\n"+
+				"mount_path=$PWD/rootfs container=working-container cache_volumes=\n"+function+"\napply_kickstart",
+				map[string]string{"KICKSTART_PATH": "ks.cfg", "INSTALLED": "plymouth"},
+				map[string]string{"buildah": `case "$*" in *"rpm -q --quiet"*) [ "${INSTALLED}" = "${7}" ] ;; esac`})
+		}
+
+		It("installs and removes the packages, and configures the services, timezone and lang", func() {
+			run := apply(map[string]string{"ks.cfg": `lang en_US.UTF-8
+timezone Europe/Berlin
+services --enabled sshd --disabled cups
+%packages --excludedocs
+@core
+kernel-*
+-plymouth
+-absent
+-@guest-agents
+%end
+`})
+			Expect(run.Err).NotTo(HaveOccurred(), run.Output)
+			Expect(run.Calls).To(Equal([]string{
+				"buildah run working-container -- dnf -y --setopt=tsflags=nodocs install @core kernel-*",
+				"buildah run working-container -- rpm -q --quiet plymouth",
+				"buildah run working-container -- rpm -q --quiet absent",
+				"buildah run working-container -- dnf -y remove plymouth @guest-agents",
+				"buildah run working-container -- systemctl enable sshd",
+				"buildah run working-container -- systemctl disable cups",
+			}))
+			Expect(os.Readlink(filepath.Join(run.Dir, "rootfs/etc/localtime"))).To(Equal("../usr/share/zoneinfo/Europe/Berlin"))
+			Expect(os.ReadFile(filepath.Join(run.Dir, "rootfs/etc/locale.conf"))).To(BeEquivalentTo("LANG=en_US.UTF-8\n"))
+		})
+
+		It("fails the build on a failing %post script only with --erroronfail", func() {
+			run := apply(map[string]string{"ks.cfg": "%post --nochroot\nexit 3\n%end\n"})
+			Expect(run.Err).NotTo(HaveOccurred(), run.Output)
+			Expect(run.Output).To(ContainSubstring("Warning: kickstart %post script 0 failed with status 3"))
+
+			run = apply(map[string]string{"ks.cfg": "%post --nochroot --erroronfail\nexit 3\n%end\n"})
+			Expect(run.Err).To(HaveOccurred())
+			Expect(run.Output).To(ContainSubstring("Kickstart %post script 0 failed with status 3."))
+		})
+	})
+})
@@ -0,0 +1,194 @@
+#!/bin/sh
+set -ex
+
+# --- Publisher API Contract ---
+# This script publishes an artifact produced by entrypoint.sh to an
+# infrastructure provider. It receives its configuration from the following
+# environment variables:
+#
+# - PUBLISH_TARGET:        The provider to publish to ("aws" or "maas").
+# - OUTPUT_FILENAME:       The base filename of the artifacts under /output.
+# - ARCHITECTURE:          The architecture the artifact was built for.
+# - AWS_REGION:            (aws) The region where the AMI is registered.
+# - AWS_AMI_NAME:          (aws) The name of the AMI.
+# - AWS_AMI_ARCHITECTURE:  (aws) The EC2 architecture of the AMI ("x86_64" or "arm64").
+# - AWS_SOURCE_S3_BUCKET:  (aws) The bucket used to stage the disk image. The staged
+#                          object is tagged bib.cluster.x-k8s.io/temporary=true and
+#                          deleted once the import finishes, successfully or not.
+# - AWS_RETAIN_SOURCE_OBJECT:
+#                          (aws, optional) "true" to keep the staged object.
+# - AWS_ENA_SUPPORT:       (aws) "true" to declare ENA support on the AMI.
+# - AWS_SRIOV_NET_SUPPORT: (aws) "true" to declare Intel 82599 VF enhanced networking.
+# - AWS_BOOT_MODE:         (aws) "legacy-bios", "uefi" or "uefi-preferred".
+# - AWS_TAGS:              (aws, optional) A JSON list of {"Key","Value"} tags applied
+#                          to the AMI and its snapshot.
+# - AWS_LAUNCH_PERMISSION: (aws, optional) The JSON {"Add":[{"UserId"}|{"Group":"all"}]}
+#                          launch permissions granted on the AMI.
+# - AWS_ACCESS_KEY_ID:     (aws) From the credentials secret.
+# - AWS_SECRET_ACCESS_KEY: (aws) From the credentials secret.
+# - AWS_REPLACE_IMAGE_ID:  (aws, optional) The existing AMI named AWS_AMI_NAME to replace. It is
+#                          deregistered only once the snapshot is imported, re-registered should
+#                          the new AMI fail to register, and its snapshots are deleted last.
+# - AWS_ASSUME_ROLE_ARN:   (aws, optional) The IAM role assumed with the credentials above
+#                          before any other call.
+# - AWS_ASSUME_ROLE_EXTERNAL_ID:
+#                          (aws, optional) The external ID the role requires.
+# - MAAS_API_URL:          (maas) The MaaS API endpoint.
+# - MAAS_IMAGE_NAME:       (maas) The name of the boot resource.
+# - MAAS_API_KEY:          (maas) From the credentials secret.
+#
+# On success it reports the registered image as JSON in the termination message:
+#   {"imageID":"<ami id>","launchPermissions":{"accountIDs":["<id>"],"public":<bool>}}
+# -----------------------------
+
+echo "--- Starting publish to ${PUBLISH_TARGET} ---"
+
+publish_aws() {
+    if [ -n "${AWS_ASSUME_ROLE_ARN}" ]; then
+        echo "Assuming role ${AWS_ASSUME_ROLE_ARN}..."
+        # Keep the temporary credentials out of the trace.
+        set +x
+        creds=$(aws sts assume-role --role-arn "${AWS_ASSUME_ROLE_ARN}" --role-session-name bib-operator \
+            ${AWS_ASSUME_ROLE_EXTERNAL_ID:+--external-id "${AWS_ASSUME_ROLE_EXTERNAL_ID}"} \
+            --query Credentials --output json)
+        AWS_ACCESS_KEY_ID=$(echo "${creds}" | jq -r .AccessKeyId)
+        AWS_SECRET_ACCESS_KEY=$(echo "${creds}" | jq -r .SecretAccessKey)
+        AWS_SESSION_TOKEN=$(echo "${creds}" | jq -r .SessionToken)
+        export AWS_ACCESS_KEY_ID AWS_SECRET_ACCESS_KEY AWS_SESSION_TOKEN
+        set -x
+    fi
+
+    raw_image="/tmp/${OUTPUT_FILENAME}.raw"
+    s3_key="${OUTPUT_FILENAME}.raw"
+
+    echo "Converting /output/${OUTPUT_FILENAME}.qcow2 to raw..."
+    qemu-img convert -O raw "/output/${OUTPUT_FILENAME}.qcow2" "${raw_image}"
+
+    # Delete the staged object on exit, whatever fails from the upload on.
+    if [ "${AWS_RETAIN_SOURCE_OBJECT}" != "true" ]; then
+        trap 'echo "Deleting s3://${AWS_SOURCE_S3_BUCKET}/${s3_key}..."; aws s3 rm "s3://${AWS_SOURCE_S3_BUCKET}/${s3_key}" || true' EXIT
+    fi
+    echo "Uploading disk image to s3://${AWS_SOURCE_S3_BUCKET}/${s3_key}..."
+    aws s3 cp "${raw_image}" "s3://${AWS_SOURCE_S3_BUCKET}/${s3_key}"
+    # The tag lets a bucket lifecycle rule expire the object should the cleanup never run. Without
+    # it the import still works, e.g. with credentials lacking s3:PutObjectTagging.
+    aws s3api put-object-tagging --bucket "${AWS_SOURCE_S3_BUCKET}" --key "${s3_key}" \
+        --tagging 'TagSet=[{Key=bib.cluster.x-k8s.io/temporary,Value=true}]' \
+        || echo "Warning: failed to tag s3://${AWS_SOURCE_S3_BUCKET}/${s3_key} as temporary" >&2
+
+    echo "Importing snapshot..."
+    task_id=$(aws ec2 import-snapshot \
+        --region "${AWS_REGION}" \
+        --disk-container "Format=RAW,UserBucket={S3Bucket=${AWS_SOURCE_S3_BUCKET},S3Key=${s3_key}}" \
+        --query 'ImportTaskId' --output text)
+
+    while :; do
+        status=$(aws ec2 describe-import-snapshot-tasks --region "${AWS_REGION}" --import-task-ids "${task_id}" \
+            --query 'ImportSnapshotTasks[0].SnapshotTaskDetail.Status' --output text)
+        case "${status}" in
+            completed) break ;;
+            deleted|deleting) echo "Snapshot import ${task_id} failed"; exit 1 ;;
+        esac
+        sleep 15
+    done
+    snapshot_id=$(aws ec2 describe-import-snapshot-tasks --region "${AWS_REGION}" --import-task-ids "${task_id}" \
+        --query 'ImportSnapshotTasks[0].SnapshotTaskDetail.SnapshotId' --output text)
+
+    ena_flag=--no-ena-support
+    if [ "${AWS_ENA_SUPPORT}" = "true" ]; then
+        ena_flag=--ena-support
+    fi
+    sriov_args=""
+    if [ "${AWS_SRIOV_NET_SUPPORT}" = "true" ]; then
+        sriov_args="--sriov-net-support simple"
+    fi
+
+    # The name of the AMI to replace must be free before the new AMI can take it.
+    replaced=null
+    if [ -n "${AWS_REPLACE_IMAGE_ID}" ]; then
+        replaced=$(aws ec2 describe-images --region "${AWS_REGION}" --owners self \
+            --filters "Name=name,Values=${AWS_AMI_NAME}" --query 'Images[0]' --output json)
+        current_id=$(echo "${replaced}" | jq -r '.ImageId // empty')
+        if [ -n "${current_id}" ] && [ "${current_id}" != "${AWS_REPLACE_IMAGE_ID}" ]; then
+            echo "AMI ${AWS_AMI_NAME} is now ${current_id}, not ${AWS_REPLACE_IMAGE_ID}; not replacing it"
+            exit 1
+        fi
+        if [ -n "${current_id}" ]; then
+            echo "Deregistering AMI ${current_id}..."
+            aws ec2 deregister-image --region "${AWS_REGION}" --image-id "${current_id}"
+        fi
+    fi
+
+    echo "Registering AMI ${AWS_AMI_NAME} from snapshot ${snapshot_id}..."
+    if ! ami_id=$(aws ec2 register-image \
+        --region "${AWS_REGION}" \
+        --name "${AWS_AMI_NAME}" \
+        --architecture "${AWS_AMI_ARCHITECTURE}" \
+        --root-device-name /dev/sda1 \
+        --virtualization-type hvm \
+        "${ena_flag}" \
+        ${sriov_args} \
+        ${AWS_BOOT_MODE:+--boot-mode "${AWS_BOOT_MODE}"} \
+        --block-device-mappings "DeviceName=/dev/sda1,Ebs={SnapshotId=${snapshot_id}}" \
+        --query 'ImageId' --output text); then
+        if [ "${replaced}" != "null" ]; then
+            # Put the replaced AMI back from its snapshots, without its tags and launch permissions.
+            echo "Re-registering the replaced AMI ${AWS_AMI_NAME}..."
+            aws ec2 register-image --region "${AWS_REGION}" --cli-input-json "$(echo "${replaced}" | jq -c \
+                '{Name, Description, Architecture, RootDeviceName, VirtualizationType, BootMode, EnaSupport,
+                  SriovNetSupport, BlockDeviceMappings} | with_entries(select(.value != null))')"
+        fi
+        exit 1
+    fi
+    echo "Registered AMI ${ami_id}"
+
+    if [ "${replaced}" != "null" ]; then
+        for snapshot in $(echo "${replaced}" | jq -r '.BlockDeviceMappings[]?.Ebs.SnapshotId // empty'); do
+            echo "Deleting snapshot ${snapshot} of the replaced AMI..."
+            aws ec2 delete-snapshot --region "${AWS_REGION}" --snapshot-id "${snapshot}" \
+                || echo "Warning: failed to delete snapshot ${snapshot} of the replaced AMI" >&2
+        done
+    fi
+
+    if [ -n "${AWS_TAGS}" ]; then
+        echo "Tagging ${ami_id} and ${snapshot_id}..."
+        aws ec2 create-tags --region "${AWS_REGION}" --resources "${ami_id}" "${snapshot_id}" --tags "${AWS_TAGS}"
+    fi
+
+    launch_permissions=null
+    if [ -n "${AWS_LAUNCH_PERMISSION}" ]; then
+        echo "Granting launch permissions on ${ami_id}..."
+        aws ec2 modify-image-attribute --region "${AWS_REGION}" --image-id "${ami_id}" \
+            --launch-permission "${AWS_LAUNCH_PERMISSION}"
+        launch_permissions=$(echo "${AWS_LAUNCH_PERMISSION}" | jq -c \
+            '{accountIDs: [.Add[] | .UserId // empty], public: any(.Add[]; .Group == "all")}')
+    fi
+
+    # Report the AMI to the operator.
+    jq -cn --arg id "${ami_id}" --argjson permissions "${launch_permissions}" \
+        '{imageID: $id} + (if $permissions == null then {} else {launchPermissions: $permissions} end)' > /dev/termination-log
+}
+
+publish_maas() {
+    # The MaaS API key has the form <consumer_key>:<token_key>:<token_secret>.
+    consumer_key=$(echo "${MAAS_API_KEY}" | cut -d: -f1)
+    token_key=$(echo "${MAAS_API_KEY}" | cut -d: -f2)
+    token_secret=$(echo "${MAAS_API_KEY}" | cut -d: -f3)
+    auth="OAuth oauth_version=\"1.0\", oauth_signature_method=\"PLAINTEXT\", oauth_consumer_key=\"${consumer_key}\", oauth_token=\"${token_key}\", oauth_signature=\"&${token_secret}\", oauth_nonce=\"$(date +%s%N)\", oauth_timestamp=\"$(date +%s)\""
+
+    echo "Uploading /output/${OUTPUT_FILENAME}.tgz to MaaS as ${MAAS_IMAGE_NAME}..."
+    curl --fail -H "Authorization: ${auth}" \
+        -F "name=${MAAS_IMAGE_NAME}" \
+        -F "architecture=${ARCHITECTURE:-amd64}/generic" \
+        -F "filetype=tgz" \
+        -F "content=@/output/${OUTPUT_FILENAME}.tgz" \
+        "${MAAS_API_URL}/api/2.0/boot-resources/"
+}
+
+case "${PUBLISH_TARGET}" in
+    aws) publish_aws ;;
+    maas) publish_maas ;;
+    *) echo "Unknown publish target: ${PUBLISH_TARGET}"; exit 1 ;;
+esac
+
+echo "--- Publish complete! ---"
@@ -0,0 +1,127 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Builder publisher", func() {
+	Context("publishing to AWS", func() {
+		env := map[string]string{
+			"OUTPUT_FILENAME":      "golden",
+			"AWS_REGION":           "us-west-2",
+			"AWS_AMI_NAME":         "golden",
+			"AWS_AMI_ARCHITECTURE": "x86_64",
+			"AWS_SOURCE_S3_BUCKET": "staging",
+		}
+
+		// awsStub answers the calls of publish_aws, failing the calls whose arguments start with $FAIL.
+		// The AMI named golden is ami-old.
+		awsStub := `case "$*" in "${FAIL:-none}"*) exit 1 ;; esac
+case "$1 $2" in
+    "ec2 import-snapshot") echo import-1 ;;
+    "ec2 describe-import-snapshot-tasks")
+        case "$*" in *Status*) echo completed ;; *) echo snap-1 ;; esac ;;
+    "ec2 describe-images")
+        echo '{"ImageId":"ami-old","Name":"golden","Architecture":"x86_64","BlockDeviceMappings":[{"DeviceName":"/dev/sda1","Ebs":{"SnapshotId":"snap-old"}}]}' ;;
+    "ec2 register-image") echo ami-1 ;;
+esac`
+
+		publishWith := func(fail string, extraEnv map[string]string) builderRun {
+			failing := map[string]string{"FAIL": fail}
+			for name, value := range env {
+				failing[name] = value
+			}
+			for name, value := range extraEnv {
+				failing[name] = value
+			}
+			return runBuilder(shellFunctions("publish.sh", "publish_aws")+"\npublish_aws", failing,
+				map[string]string{"aws": awsStub, "qemu-img": ""})
+		}
+		publish := func(fail string) builderRun {
+			return publishWith(fail, nil)
+		}
+		replace := func(fail, imageID string) builderRun {
+			return publishWith(fail, map[string]string{"AWS_REPLACE_IMAGE_ID": imageID})
+		}
+		callIndex := func(run builderRun, prefix string) int {
+			return slices.IndexFunc(run.Calls, func(call string) bool { return strings.HasPrefix(call, prefix) })
+		}
+
+		It("publishes the AMI and deletes the staged object when tagging it fails", func() {
+			run := publish("s3api put-object-tagging")
+			Expect(run.Err).NotTo(HaveOccurred(), run.Output)
+			Expect(run.Output).To(ContainSubstring("Warning: failed to tag s3://staging/golden.raw as temporary"))
+			Expect(run.Calls).To(ContainElement(HavePrefix("aws ec2 register-image")))
+			Expect(run.Calls[len(run.Calls)-1]).To(Equal("aws s3 rm s3://staging/golden.raw"))
+			result, err := os.ReadFile(filepath.Join(run.Dir, "termination-log"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(result)).To(MatchJSON(`{"imageID":"ami-1"}`))
+		})
+
+		It("deletes the staged object when the import fails", func() {
+			run := publish("ec2 import-snapshot")
+			Expect(run.Err).To(HaveOccurred())
+			Expect(run.Calls[len(run.Calls)-1]).To(Equal("aws s3 rm s3://staging/golden.raw"))
+		})
+
+		It("deregisters the replaced AMI once the snapshot is imported and deletes its snapshots last", func() {
+			run := replace("", "ami-old")
+			Expect(run.Err).NotTo(HaveOccurred(), run.Output)
+			imported := callIndex(run, "aws ec2 describe-import-snapshot-tasks")
+			deregistered := callIndex(run, "aws ec2 deregister-image --region us-west-2 --image-id ami-old")
+			registered := callIndex(run, "aws ec2 register-image")
+			deleted := callIndex(run, "aws ec2 delete-snapshot --region us-west-2 --snapshot-id snap-old")
+			Expect(imported).To(BeNumerically(">=", 0))
+			Expect(deregistered).To(BeNumerically(">", imported))
+			Expect(registered).To(BeNumerically(">", deregistered))
+			Expect(deleted).To(BeNumerically(">", registered))
+		})
+
+		It("keeps the AMI to replace when the import fails", func() {
+			run := replace("ec2 import-snapshot", "ami-old")
+			Expect(run.Err).To(HaveOccurred())
+			Expect(callIndex(run, "aws ec2 deregister-image")).To(Equal(-1))
+			Expect(callIndex(run, "aws ec2 delete-snapshot")).To(Equal(-1))
+		})
+
+		It("does not replace an AMI another publish registered in the meantime", func() {
+			run := replace("", "ami-older")
+			Expect(run.Err).To(HaveOccurred())
+			Expect(run.Output).To(ContainSubstring("AMI golden is now ami-old, not ami-older; not replacing it"))
+			Expect(callIndex(run, "aws ec2 deregister-image")).To(Equal(-1))
+			Expect(callIndex(run, "aws ec2 register-image")).To(Equal(-1))
+		})
+
+		It("re-registers the replaced AMI and keeps its snapshots when the new AMI fails to register", func() {
+			run := replace("ec2 register-image --region us-west-2 --name", "ami-old")
+			Expect(run.Err).To(HaveOccurred())
+			restore := callIndex(run, "aws ec2 register-image --region us-west-2 --cli-input-json")
+			Expect(restore).To(BeNumerically(">", callIndex(run, "aws ec2 deregister-image")))
+			Expect(run.Calls[restore]).To(ContainSubstring(`"Name":"golden"`))
+			Expect(run.Calls[restore]).To(ContainSubstring(`"SnapshotId":"snap-old"`))
+			Expect(callIndex(run, "aws ec2 delete-snapshot")).To(Equal(-1))
+		})
+	})
+})
@@ -0,0 +1,169 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command bibctl applies build definitions as ImageBuilds and optionally waits for them to finish.
+//
+//	bibctl apply -f build.yaml [-n namespace] [--wait] [--timeout 2h]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+	"github.com/zarcen/bib-operator/internal/bibctl"
+)
+
+// waitInterval is how often --wait polls the ImageBuild.
+const waitInterval = 5 * time.Second
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(bibv1alpha1.AddToScheme(scheme))
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "apply":
+		err = apply(os.Args[2:])
+	case "-h", "--help", "help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: bibctl <command> [flags]
+
+Commands:
+  apply   Apply a build definition as an ImageBuild
+
+Run "bibctl apply -h" for the flags of apply.`)
+}
+
+func apply(args []string) error {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	var filename, namespace, kubeconfig, kubeContext string
+	var waitForBuild bool
+	var timeout time.Duration
+	fs.StringVar(&filename, "f", "", "The YAML or JSON build definition to apply, or - to read it from stdin.")
+	fs.StringVar(&namespace, "n", "", "The namespace of the ImageBuild, if the definition does not name one. "+
+		"Defaults to the namespace of the kubeconfig context.")
+	fs.StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file. Defaults to $KUBECONFIG or ~/.kube/config.")
+	fs.StringVar(&kubeContext, "context", "", "The kubeconfig context to use.")
+	fs.BoolVar(&waitForBuild, "wait", false, "If set, wait for the build to succeed or fail, and exit non-zero if it failed.")
+	fs.DurationVar(&timeout, "timeout", 0, "How long --wait waits for the build. Zero waits forever.")
+	_ = fs.Parse(args)
+	if filename == "" {
+		return errors.New("a build definition is required, see -f")
+	}
+
+	data, err := readDefinition(filename)
+	if err != nil {
+		return err
+	}
+	def, err := bibctl.ParseDefinition(data)
+	if err != nil {
+		return err
+	}
+
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig, Precedence: clientcmd.NewDefaultClientConfigLoadingRules().Precedence},
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext})
+	if namespace == "" {
+		if namespace, _, err = clientConfig.Namespace(); err != nil {
+			return err
+		}
+	}
+	ib, err := def.ImageBuild(namespace)
+	if err != nil {
+		return err
+	}
+
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	result, err := bibctl.Apply(ctx, c, ib)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("imagebuild.bib.cluster.x-k8s.io/%s %s\n", ib.Name, result)
+	if !waitForBuild {
+		return nil
+	}
+
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	finished, err := bibctl.Wait(ctx, c, client.ObjectKeyFromObject(ib), waitInterval, func(ib *bibv1alpha1.ImageBuild) {
+		if ib.Status.Phase != "" {
+			fmt.Printf("imagebuild.bib.cluster.x-k8s.io/%s %s\n", ib.Name, ib.Status.Phase)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if finished.Status.OutputURL != "" {
+		fmt.Println(finished.Status.OutputURL)
+	}
+	return nil
+}
+
+// readDefinition reads the build definition from filename, or from stdin if filename is "-".
+func readDefinition(filename string) ([]byte, error) {
+	if filename == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(filename)
+}
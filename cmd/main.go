@@ -17,17 +17,23 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
@@ -38,7 +44,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+	"github.com/zarcen/bib-operator/internal/ami"
 	"github.com/zarcen/bib-operator/internal/controller"
+	"github.com/zarcen/bib-operator/internal/notify"
+	"github.com/zarcen/bib-operator/internal/registry"
+	"github.com/zarcen/bib-operator/internal/tracing"
+	webhookv1alpha1 "github.com/zarcen/bib-operator/internal/webhook/v1alpha1"
+	"github.com/zarcen/bib-operator/pkg/imageref"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -64,6 +76,20 @@ func main() {
 	var secureMetrics bool
 	var enableHTTP2 bool
 	var builderImage string
+	var maxConcurrentPublishes int
+	var enableWebhooks bool
+	var allowHostNetwork bool
+	var allowRepositoryCreation bool
+	var forbidPrivileged bool
+	var privilegedNamespaces string
+	var statusServerSideApply bool
+	var registryMirrors string
+	var builderPrePullNodeSelector, builderPrePullNamespace string
+	var minFreeDiskSpace string
+	var finalizerName string
+	var collectOrphanedPods bool
+	var buildNamespaces bool
+	var buildNamespaceQuota string
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -84,6 +110,46 @@ func main() {
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
 	flag.StringVar(&builderImage, "builder-image", "ghcr.io/zarcen/bib-operator/builder:0.1.1",
 		"The image to use for the builder pod.")
+	flag.IntVar(&maxConcurrentPublishes, "max-concurrent-publishes", 0,
+		"The maximum number of publisher pods running at once across the cluster. 0 means unlimited.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"If set, the ImageBuild validating webhook is served. Requires webhook certificates, see --webhook-cert-path.")
+	flag.BoolVar(&allowHostNetwork, "allow-host-network", false,
+		"If set, ImageBuilds may run their builder pod in the host network namespace with spec.build.hostNetwork.")
+	flag.BoolVar(&allowRepositoryCreation, "allow-repository-creation", false,
+		"If set, ImageBuilds may create missing ECR and Artifact Registry repositories with spec.output.registry.createRepository.")
+	flag.BoolVar(&forbidPrivileged, "forbid-privileged", false,
+		"If set, ImageBuilds are rejected unless their namespace is listed in --privileged-namespaces, "+
+			"as the builder pod runs privileged.")
+	flag.StringVar(&privilegedNamespaces, "privileged-namespaces", "",
+		"Comma-separated namespaces whose ImageBuilds may run a privileged builder pod with --forbid-privileged.")
+	flag.BoolVar(&statusServerSideApply, "status-server-side-apply", false,
+		"If set, the ImageBuild status is persisted with server-side apply, so the controller only owns the status fields it sets.")
+	flag.StringVar(&registryMirrors, "registry-mirrors", "",
+		"Comma-separated <registry>=<mirror> pairs, e.g. docker.io=harbor.example.com/dockerhub. "+
+			"Base images from these registries are pulled through the mirror.")
+	flag.StringVar(&minFreeDiskSpace, "min-free-disk-space", "",
+		"The free space, e.g. 50Gi, the container storage of builds must have before they start, unless "+
+			"spec.build.minFreeDiskSpace overrides it. Builds are not checked if unset.")
+	flag.StringVar(&finalizerName, "finalizer-name", bibv1alpha1.ImageBuildFinalizer,
+		"The finalizer added to ImageBuilds to clean up their pods on deletion. Each controller instance "+
+			"of a multi-instance deployment needs its own.")
+	flag.StringVar(&builderPrePullNodeSelector, "builder-prepull-node-selector", "",
+		"Comma-separated <label>=<value> pairs selecting the build nodes the builder image is kept pulled on "+
+			"by a DaemonSet, e.g. bib.cluster.x-k8s.io/build-node=true. Builder pods prefer these nodes. "+
+			"The image is not pre-pulled if unset.")
+	flag.StringVar(&builderPrePullNamespace, "builder-prepull-namespace", "bib-operator-system",
+		"The namespace of the DaemonSet pre-pulling the builder image, usually the operator's.")
+	flag.BoolVar(&collectOrphanedPods, "collect-orphaned-pods", false,
+		"If set, builder and publisher pods whose ImageBuild no longer exists, e.g. because it was force-deleted "+
+			"with its finalizer removed, are looked for periodically and deleted.")
+	flag.BoolVar(&buildNamespaces, "build-namespaces", false,
+		"If set, the builder pod of each ImageBuild runs in a namespace of its own, created with a ResourceQuota "+
+			"and a NetworkPolicy and deleted along with the ImageBuild. Requires the cluster-wide permissions of "+
+			"config/rbac/build_namespaces_role.yaml.")
+	flag.StringVar(&buildNamespaceQuota, "build-namespace-quota", "",
+		"Comma-separated <resource>=<quantity> pairs, e.g. pods=1,requests.storage=200Gi, limiting the build "+
+			"namespaces of --build-namespaces with a ResourceQuota. Defaults to a single pod if unset.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -92,6 +158,50 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if _, err := imageref.Parse(builderImage); err != nil {
+		setupLog.Error(err, "invalid --builder-image")
+		os.Exit(1)
+	}
+
+	mirrors, err := controller.ParseRegistryMirrors(registryMirrors)
+	if err != nil {
+		setupLog.Error(err, "invalid --registry-mirrors")
+		os.Exit(1)
+	}
+
+	prePullNodeSelector, err := controller.ParseNodeSelector(builderPrePullNodeSelector)
+	if err != nil {
+		setupLog.Error(err, "invalid --builder-prepull-node-selector")
+		os.Exit(1)
+	}
+
+	var privilegedNamespaceList []string
+	for _, namespace := range strings.Split(privilegedNamespaces, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			privilegedNamespaceList = append(privilegedNamespaceList, namespace)
+		}
+	}
+
+	if errs := validation.IsQualifiedName(finalizerName); len(errs) > 0 || !strings.Contains(finalizerName, "/") {
+		setupLog.Error(fmt.Errorf("%q must be a domain-qualified name, e.g. example.com/imagebuild", finalizerName),
+			"invalid --finalizer-name", "Errors", errs)
+		os.Exit(1)
+	}
+
+	buildNamespaceQuotaList, err := controller.ParseResourceQuota(buildNamespaceQuota)
+	if err != nil {
+		setupLog.Error(err, "invalid --build-namespace-quota")
+		os.Exit(1)
+	}
+
+	var minFreeDiskSpaceQuantity resource.Quantity
+	if minFreeDiskSpace != "" {
+		if minFreeDiskSpaceQuantity, err = resource.ParseQuantity(minFreeDiskSpace); err != nil {
+			setupLog.Error(err, "invalid --min-free-disk-space")
+			os.Exit(1)
+		}
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -181,7 +291,10 @@ func main() {
 		})
 	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	restConfig := ctrl.GetConfigOrDie()
+	// The API server records the writes of the operator under the name of its user agent.
+	restConfig.UserAgent = controller.FieldManager
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                metricsServerOptions,
 		WebhookServer:          webhookServer,
@@ -209,10 +322,50 @@ func main() {
 		Client:       mgr.GetClient(),
 		Scheme:       mgr.GetScheme(),
 		BuilderImage: builderImage,
+
+		MaxConcurrentPublishes:  maxConcurrentPublishes,
+		AllowHostNetwork:        allowHostNetwork,
+		AllowRepositoryCreation: allowRepositoryCreation,
+		ForbidPrivileged:        forbidPrivileged,
+		PrivilegedNamespaces:    privilegedNamespaceList,
+		StatusServerSideApply:   statusServerSideApply,
+		RegistryMirrors:         mirrors,
+		MinFreeDiskSpace:        minFreeDiskSpaceQuantity,
+		Finalizer:               finalizerName,
+		Notifier:                notify.NewHTTPNotifier(),
+		NewEC2Client:            ami.NewEC2Client,
+		ImagePlatforms:          registry.Platforms,
+		PrePullNodeSelector:     prePullNodeSelector,
+		Recorder:                mgr.GetEventRecorderFor("imagebuild-controller"),
+		BuildNamespaces:         buildNamespaces,
+		BuildNamespaceQuota:     buildNamespaceQuotaList,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ImageBuild")
 		os.Exit(1)
 	}
+	if len(prePullNodeSelector) > 0 {
+		if err := mgr.Add(&controller.BuilderPrePuller{
+			Client:       mgr.GetClient(),
+			BuilderImage: builderImage,
+			Namespace:    builderPrePullNamespace,
+			NodeSelector: prePullNodeSelector,
+		}); err != nil {
+			setupLog.Error(err, "unable to add the builder image pre-puller to manager")
+			os.Exit(1)
+		}
+	}
+	if collectOrphanedPods {
+		if err := mgr.Add(&controller.OrphanedPodCollector{Client: mgr.GetClient()}); err != nil {
+			setupLog.Error(err, "unable to add the orphaned pod collector to manager")
+			os.Exit(1)
+		}
+	}
+	if enableWebhooks {
+		if err = webhookv1alpha1.SetupImageBuildWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ImageBuild")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if metricsCertWatcher != nil {
@@ -240,9 +393,23 @@ func main() {
 		os.Exit(1)
 	}
 
+	ctx := ctrl.SetupSignalHandler()
+	// Traces are exported if the standard OTEL_EXPORTER_OTLP_* variables configure an endpoint.
+	shutdownTracing, err := tracing.Setup(ctx)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		setupLog.Error(err, "unable to flush traces")
+	}
 }
@@ -0,0 +1,80 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ami looks up existing EC2 AMIs on behalf of the AWS publish flow.
+package ami
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// EC2API is the subset of the EC2 API used to look up AMIs.
+type EC2API interface {
+	DescribeImages(ctx context.Context, params *ec2.DescribeImagesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeImagesOutput, error)
+}
+
+// Credentials are the static AWS credentials read from an ImageBuild credentials secret, and the
+// role to assume with them, if any.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	RoleARN         string
+	ExternalID      string
+}
+
+// NewEC2ClientFunc builds an EC2API for a region.
+type NewEC2ClientFunc func(ctx context.Context, region string, creds Credentials) (EC2API, error)
+
+// NewEC2Client is the NewEC2ClientFunc backed by the AWS SDK.
+func NewEC2Client(_ context.Context, region string, creds Credentials) (EC2API, error) {
+	var provider aws.CredentialsProvider = credentials.NewStaticCredentialsProvider(creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken)
+	if creds.RoleARN != "" {
+		stsClient := sts.New(sts.Options{Region: region, Credentials: provider})
+		provider = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(stsClient, creds.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = "bib-operator"
+			if creds.ExternalID != "" {
+				o.ExternalID = aws.String(creds.ExternalID)
+			}
+		}))
+	}
+	return ec2.New(ec2.Options{Region: region, Credentials: provider}), nil
+}
+
+// FindByName returns the AMI owned by the caller with the given name, or nil if there is none.
+func FindByName(ctx context.Context, client EC2API, name string) (*ec2types.Image, error) {
+	out, err := client.DescribeImages(ctx, &ec2.DescribeImagesInput{
+		Owners:  []string{"self"},
+		Filters: []ec2types.Filter{{Name: aws.String("name"), Values: []string{name}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe AMIs named %q: %w", name, err)
+	}
+	// AMI names are unique per account and region.
+	if len(out.Images) == 0 {
+		return nil, nil
+	}
+	return &out.Images[0], nil
+}
+
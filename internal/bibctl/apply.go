@@ -0,0 +1,163 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bibctl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+// ApplyResult is what Apply did to the ImageBuild.
+type ApplyResult string
+
+const (
+	ApplyCreated    ApplyResult = "created"
+	ApplyConfigured ApplyResult = "configured"
+	ApplyUnchanged  ApplyResult = "unchanged"
+)
+
+// Apply creates the ImageBuild, or updates the spec of an existing ImageBuild of the same name and
+// adds the labels and annotations of ib to it. On success ib holds the ImageBuild as stored.
+func Apply(ctx context.Context, c client.Client, ib *bibv1alpha1.ImageBuild) (ApplyResult, error) {
+	existing := &bibv1alpha1.ImageBuild{}
+	err := c.Get(ctx, client.ObjectKeyFromObject(ib), existing)
+	if apierrors.IsNotFound(err) {
+		if err := c.Create(ctx, ib); err != nil {
+			return "", fmt.Errorf("failed to create ImageBuild %s: %w", client.ObjectKeyFromObject(ib), err)
+		}
+		return ApplyCreated, nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to get ImageBuild %s: %w", client.ObjectKeyFromObject(ib), err)
+	}
+
+	updated := existing.DeepCopy()
+	updated.Spec = ib.Spec
+	// Keep the labels and annotations set by others, e.g. the controller.
+	for k, v := range ib.Labels {
+		if updated.Labels == nil {
+			updated.Labels = map[string]string{}
+		}
+		updated.Labels[k] = v
+	}
+	for k, v := range ib.Annotations {
+		if updated.Annotations == nil {
+			updated.Annotations = map[string]string{}
+		}
+		updated.Annotations[k] = v
+	}
+	// The definition leaves out the fields the CRD defaults, so it is compared as the API server
+	// would store it.
+	defaulted := updated.DeepCopy()
+	if err := c.Update(ctx, defaulted, client.DryRunAll); err != nil {
+		return "", fmt.Errorf("failed to update ImageBuild %s: %w", client.ObjectKeyFromObject(ib), err)
+	}
+	if equality.Semantic.DeepEqual(existing.Spec, defaulted.Spec) &&
+		equality.Semantic.DeepEqual(existing.Labels, defaulted.Labels) &&
+		equality.Semantic.DeepEqual(existing.Annotations, defaulted.Annotations) {
+		existing.DeepCopyInto(ib)
+		return ApplyUnchanged, nil
+	}
+	if err := c.Update(ctx, updated); err != nil {
+		return "", fmt.Errorf("failed to update ImageBuild %s: %w", client.ObjectKeyFromObject(ib), err)
+	}
+	updated.DeepCopyInto(ib)
+	return ApplyConfigured, nil
+}
+
+// Wait polls the ImageBuild every interval until it has succeeded or failed, calling progress, if
+// not nil, whenever its phase changes. The status is only trusted once the controller observed the
+// current generation of the spec, so an update just applied is not mistaken for the outcome of the
+// previous spec; the controller does not build a spec changed after the build finished, so Wait
+// fails right away for such a build. It returns the finished ImageBuild, along with an error naming the failed
+// conditions if the build failed.
+func Wait(ctx context.Context, c client.Reader, key client.ObjectKey, interval time.Duration,
+	progress func(*bibv1alpha1.ImageBuild)) (*bibv1alpha1.ImageBuild, error) {
+	ib := &bibv1alpha1.ImageBuild{}
+	observed := false
+	var phase bibv1alpha1.ImageBuildPhase
+	err := wait.PollUntilContextCancel(ctx, interval, true, func(ctx context.Context) (bool, error) {
+		if err := c.Get(ctx, key, ib); apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("ImageBuild %s was deleted", key)
+		} else if err != nil {
+			// Retry transient API errors until the context is done.
+			return false, nil
+		}
+		if ib.Status.ObservedGeneration < ib.Generation {
+			if specChangeIgnored(ib) {
+				return false, fmt.Errorf("ImageBuild %s is %s; its spec change will not be built, delete and re-apply it",
+					key, ib.Status.Phase)
+			}
+			return false, nil
+		}
+		if !observed || ib.Status.Phase != phase {
+			observed = true
+			phase = ib.Status.Phase
+			if progress != nil {
+				progress(ib)
+			}
+		}
+		return phase == bibv1alpha1.PhaseSucceeded || phase == bibv1alpha1.PhaseFailed, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for ImageBuild %s: %w", key, err)
+	}
+	if phase == bibv1alpha1.PhaseFailed {
+		return ib, fmt.Errorf("ImageBuild %s failed: %s", key, failedConditions(ib))
+	}
+	return ib, nil
+}
+
+// specChangeIgnored returns true if ib finished before its current spec was applied. The controller
+// only builds such a spec for a build that failed to create its builder pod.
+func specChangeIgnored(ib *bibv1alpha1.ImageBuild) bool {
+	switch ib.Status.Phase {
+	case bibv1alpha1.PhaseSucceeded:
+		return true
+	case bibv1alpha1.PhaseFailed:
+		return conditions.GetReason(ib, bibv1alpha1.BuilderPodReady) != bibv1alpha1.PodCreationFailedReason ||
+			ib.Generation == ib.Status.PodCreationFailedGeneration
+	default:
+		return false
+	}
+}
+
+// failedConditions describes the conditions of ib that are False, in the order of
+// ImageBuildConditionTypes.
+func failedConditions(ib *bibv1alpha1.ImageBuild) string {
+	var failed []string
+	for _, conditionType := range bibv1alpha1.ImageBuildConditionTypes {
+		if !conditions.IsFalse(ib, conditionType) {
+			continue
+		}
+		failed = append(failed, fmt.Sprintf("%s (%s): %s", conditionType,
+			conditions.GetReason(ib, conditionType), conditions.GetMessage(ib, conditionType)))
+	}
+	if len(failed) == 0 {
+		return "no failed condition reported"
+	}
+	return strings.Join(failed, "; ")
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bibctl
+
+import (
+	"cmp"
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+func newFakeClient(objs ...client.Object) client.Client {
+	s := runtime.NewScheme()
+	Expect(bibv1alpha1.AddToScheme(s)).To(Succeed())
+	return fake.NewClientBuilder().
+		WithScheme(s).
+		WithObjects(objs...).
+		WithStatusSubresource(&bibv1alpha1.ImageBuild{}).
+		Build()
+}
+
+func newTestImageBuild(name string) *bibv1alpha1.ImageBuild {
+	return &bibv1alpha1.ImageBuild{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: bibv1alpha1.ImageBuildSpec{
+			BaseImage: "ubuntu:24.04",
+			Output:    bibv1alpha1.OutputSpec{PVC: &bibv1alpha1.PVCOutput{Name: "artifacts"}},
+		},
+	}
+}
+
+var _ = Describe("Apply", func() {
+	ctx := context.Background()
+
+	It("creates a missing ImageBuild", func() {
+		c := newFakeClient()
+
+		result, err := Apply(ctx, c, newTestImageBuild("new"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(ApplyCreated))
+
+		created := &bibv1alpha1.ImageBuild{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "new", Namespace: "default"}, created)).To(Succeed())
+		Expect(created.Spec.BaseImage).To(Equal("ubuntu:24.04"))
+	})
+
+	It("updates the spec of an existing ImageBuild and keeps the labels set by others", func() {
+		existing := newTestImageBuild("existing")
+		existing.Labels = map[string]string{"owner": "ci"}
+		c := newFakeClient(existing)
+
+		ib := newTestImageBuild("existing")
+		ib.Labels = map[string]string{"team": "platform"}
+		ib.Spec.BaseImage = "ubuntu:26.04"
+		result, err := Apply(ctx, c, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(ApplyConfigured))
+
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "existing", Namespace: "default"}, updated)).To(Succeed())
+		Expect(updated.Spec.BaseImage).To(Equal("ubuntu:26.04"))
+		Expect(updated.Labels).To(Equal(map[string]string{"owner": "ci", "team": "platform"}))
+
+		By("leaving an ImageBuild that already matches the definition alone")
+		result, err = Apply(ctx, c, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(ApplyUnchanged))
+	})
+
+	It("compares the definition with the defaults of the CRD applied", func() {
+		existing := newTestImageBuild("defaulted")
+		existing.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Ansible: &bibv1alpha1.AnsibleSpec{
+			Repo: "https://example.com/playbooks.git", Branch: "main", Playbook: "site.yml", Mode: bibv1alpha1.AnsibleModeChroot,
+		}}
+		// The fake client does not default, the API server defaults the branch and mode a dry run returns.
+		c := interceptor.NewClient(newFakeClient(existing).(client.WithWatch), interceptor.Funcs{
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				if ansible := obj.(*bibv1alpha1.ImageBuild).Spec.Provisioner.Ansible; ansible != nil {
+					ansible.Branch = cmp.Or(ansible.Branch, "main")
+					ansible.Mode = cmp.Or(ansible.Mode, bibv1alpha1.AnsibleModeChroot)
+				}
+				return c.Update(ctx, obj, opts...)
+			},
+		})
+
+		ib := newTestImageBuild("defaulted")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Ansible: &bibv1alpha1.AnsibleSpec{
+			Repo: "https://example.com/playbooks.git", Playbook: "site.yml",
+		}}
+		result, err := Apply(ctx, c, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(ApplyUnchanged))
+	})
+})
+
+var _ = Describe("Wait", func() {
+	ctx := context.Background()
+	key := client.ObjectKey{Name: "waited", Namespace: "default"}
+
+	finishedImageBuild := func(phase bibv1alpha1.ImageBuildPhase) *bibv1alpha1.ImageBuild {
+		ib := newTestImageBuild("waited")
+		ib.Status.Phase = phase
+		return ib
+	}
+
+	It("returns once the build succeeded", func() {
+		ib := finishedImageBuild(bibv1alpha1.PhaseSucceeded)
+		ib.Status.OutputURL = "pvc://artifacts/default/waited/"
+		c := newFakeClient(ib)
+
+		var phases []bibv1alpha1.ImageBuildPhase
+		finished, err := Wait(ctx, c, key, time.Millisecond, func(ib *bibv1alpha1.ImageBuild) {
+			phases = append(phases, ib.Status.Phase)
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(finished.Status.OutputURL).To(Equal("pvc://artifacts/default/waited/"))
+		Expect(phases).To(Equal([]bibv1alpha1.ImageBuildPhase{bibv1alpha1.PhaseSucceeded}))
+	})
+
+	It("reports the failed conditions of a failed build", func() {
+		ib := finishedImageBuild(bibv1alpha1.PhaseFailed)
+		conditions.MarkTrue(ib, bibv1alpha1.BaseImageReady)
+		conditions.MarkFalse(ib, bibv1alpha1.ProvisionerReady, bibv1alpha1.BuildStageFailedReason,
+			clusterv1beta1.ConditionSeverityError, "playbook site.yml failed")
+		c := newFakeClient(ib)
+
+		_, err := Wait(ctx, c, key, time.Millisecond, nil)
+		Expect(err).To(MatchError(ContainSubstring("ProvisionerReady (BuildStageFailed): playbook site.yml failed")))
+		Expect(err.Error()).NotTo(ContainSubstring("BaseImageReady"))
+	})
+
+	It("waits for the controller to observe the current spec", func() {
+		ib := finishedImageBuild(bibv1alpha1.PhaseBuilding)
+		ib.Generation = 2
+		ib.Status.ObservedGeneration = 1
+		c := newFakeClient(ib)
+		timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		var phases []bibv1alpha1.ImageBuildPhase
+		_, err := Wait(timeoutCtx, c, key, time.Millisecond, func(ib *bibv1alpha1.ImageBuild) {
+			phases = append(phases, ib.Status.Phase)
+		})
+		Expect(err).To(MatchError(ContainSubstring("failed to wait for ImageBuild default/waited")))
+		Expect(phases).To(BeEmpty())
+	})
+
+	It("fails right away for a build that finished before its spec changed", func() {
+		ib := finishedImageBuild(bibv1alpha1.PhaseSucceeded)
+		ib.Generation = 2
+		ib.Status.ObservedGeneration = 1
+		c := newFakeClient(ib)
+
+		var phases []bibv1alpha1.ImageBuildPhase
+		_, err := Wait(ctx, c, key, time.Millisecond, func(ib *bibv1alpha1.ImageBuild) {
+			phases = append(phases, ib.Status.Phase)
+		})
+		Expect(err).To(MatchError(ContainSubstring(
+			"ImageBuild default/waited is Succeeded; its spec change will not be built, delete and re-apply it")))
+		Expect(phases).To(BeEmpty())
+	})
+
+	It("waits for a build that failed to create its builder pod to be retried with the changed spec", func() {
+		ib := finishedImageBuild(bibv1alpha1.PhaseFailed)
+		ib.Generation = 2
+		ib.Status.ObservedGeneration = 1
+		ib.Status.PodCreationFailedGeneration = 1
+		conditions.MarkFalse(ib, bibv1alpha1.BuilderPodReady, bibv1alpha1.PodCreationFailedReason,
+			clusterv1beta1.ConditionSeverityError, "exceeded quota")
+		c := newFakeClient(ib)
+		timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		_, err := Wait(timeoutCtx, c, key, time.Millisecond, nil)
+		Expect(err).To(MatchError(ContainSubstring("context deadline exceeded")))
+	})
+
+	It("follows the phases of a running build", func() {
+		ib := finishedImageBuild(bibv1alpha1.PhaseBuilding)
+		c := newFakeClient(ib)
+
+		phases := make(chan bibv1alpha1.ImageBuildPhase, 2)
+		done := make(chan error)
+		go func() {
+			defer GinkgoRecover()
+			_, err := Wait(ctx, c, key, time.Millisecond, func(ib *bibv1alpha1.ImageBuild) {
+				phases <- ib.Status.Phase
+			})
+			done <- err
+		}()
+
+		Eventually(phases).Should(Receive(Equal(bibv1alpha1.PhaseBuilding)))
+		ib.Status.Phase = bibv1alpha1.PhaseSucceeded
+		Expect(c.Status().Update(ctx, ib)).To(Succeed())
+		Eventually(done).Should(Receive(BeNil()))
+		Expect(phases).To(Receive(Equal(bibv1alpha1.PhaseSucceeded)))
+	})
+
+	It("gives up when the context is done", func() {
+		c := newFakeClient(finishedImageBuild(bibv1alpha1.PhasePending))
+		timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		_, err := Wait(timeoutCtx, c, key, time.Millisecond, nil)
+		Expect(err).To(MatchError(ContainSubstring("failed to wait for ImageBuild default/waited")))
+	})
+
+	It("fails if the ImageBuild is deleted", func() {
+		_, err := Wait(ctx, newFakeClient(), key, time.Millisecond, nil)
+		Expect(err).To(MatchError(ContainSubstring("was deleted")))
+	})
+})
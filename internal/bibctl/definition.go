@@ -0,0 +1,88 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bibctl turns build definitions into ImageBuilds and applies them to a cluster.
+package bibctl
+
+import (
+	"errors"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+// Definition is a build definition: the fields of an ImageBuild spec at the top level, next to the
+// name and metadata of the ImageBuild applied for it.
+//
+//	name: ubuntu-2404-golden
+//	baseImage: ubuntu:24.04
+//	output:
+//	  pvc:
+//	    name: golden-images
+type Definition struct {
+	// Name is the name of the ImageBuild.
+	Name string `json:"name"`
+	// Namespace is the namespace of the ImageBuild. Defaults to the namespace given on the command
+	// line, or the one of the current kubeconfig context.
+	Namespace   string            `json:"namespace,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	bibv1alpha1.ImageBuildSpec `json:",inline"`
+}
+
+// ParseDefinition decodes a YAML or JSON build definition. Unknown fields are rejected, so a
+// misspelled field fails the parse rather than being silently dropped.
+func ParseDefinition(data []byte) (*Definition, error) {
+	def := &Definition{}
+	if err := yaml.UnmarshalStrict(data, def); err != nil {
+		return nil, fmt.Errorf("failed to parse build definition: %w", err)
+	}
+	if def.Name == "" {
+		return nil, errors.New("build definition has no name")
+	}
+	return def, nil
+}
+
+// ImageBuild returns the ImageBuild for the definition, in namespace unless the definition names
+// its own. The spec is normalized and checked like the admission webhooks do, so an invalid
+// definition is reported before anything is sent to the cluster.
+func (d *Definition) ImageBuild(namespace string) (*bibv1alpha1.ImageBuild, error) {
+	if d.Namespace != "" {
+		namespace = d.Namespace
+	}
+	ib := &bibv1alpha1.ImageBuild{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: bibv1alpha1.GroupVersion.String(),
+			Kind:       "ImageBuild",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        d.Name,
+			Namespace:   namespace,
+			Labels:      d.Labels,
+			Annotations: d.Annotations,
+		},
+		Spec: *d.ImageBuildSpec.DeepCopy(),
+	}
+	ib.NormalizeSpec()
+	if errs := append(ib.ValidateSpec(), ib.ValidateSpecChange(nil)...); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid build definition %s: %w", d.Name, errs.ToAggregate())
+	}
+	return ib, nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bibctl
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("ParseDefinition", func() {
+	It("parses a YAML definition with the spec at the top level", func() {
+		def, err := ParseDefinition([]byte(`
+name: ubuntu-2404-golden
+labels:
+  team: platform
+baseImage: ubuntu:24.04
+arch: arm64
+output:
+  pvc:
+    name: golden-images
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		ib, err := def.ImageBuild("builds")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ib.Name).To(Equal("ubuntu-2404-golden"))
+		Expect(ib.Namespace).To(Equal("builds"))
+		Expect(ib.Labels).To(HaveKeyWithValue("team", "platform"))
+		Expect(ib.Spec.BaseImage).To(Equal("ubuntu:24.04"))
+		Expect(ib.Spec.Architecture).To(Equal("arm64"))
+		Expect(ib.Spec.Output.PVC).To(Equal(&bibv1alpha1.PVCOutput{Name: "golden-images"}))
+	})
+
+	It("parses a JSON definition", func() {
+		def, err := ParseDefinition([]byte(`{"name":"json","baseImage":"ubuntu:24.04","output":{"pvc":{"name":"images"}}}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(def.BaseImage).To(Equal("ubuntu:24.04"))
+	})
+
+	It("prefers the namespace of the definition", func() {
+		def, err := ParseDefinition([]byte("name: pinned\nnamespace: golden\nbaseImage: ubuntu:24.04\noutput: {pvc: {name: images}}\n"))
+		Expect(err).NotTo(HaveOccurred())
+		ib, err := def.ImageBuild("default")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ib.Namespace).To(Equal("golden"))
+	})
+
+	DescribeTable("rejects invalid definitions",
+		func(data, message string) {
+			_, err := ParseDefinition([]byte(data))
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("a misspelled field", "name: typo\nbaseImge: ubuntu:24.04\n", `unknown field "baseImge"`),
+		Entry("a missing name", "baseImage: ubuntu:24.04\n", "has no name"),
+		Entry("malformed YAML", "name: [broken\n", "failed to parse build definition"),
+	)
+
+	It("normalizes the architecture like the webhook does", func() {
+		def, err := ParseDefinition([]byte("name: uname\narch: x86_64\nbaseImage: ubuntu:24.04\noutput: {pvc: {name: images}}\n"))
+		Expect(err).NotTo(HaveOccurred())
+		ib, err := def.ImageBuild("default")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ib.Spec.Architecture).To(Equal("amd64"))
+	})
+
+	It("rejects a spec the webhook would reject", func() {
+		def, err := ParseDefinition([]byte("name: invalid\nbaseImage: Ubuntu:24.04\noutput: {pvc: {name: images}}\n"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = def.ImageBuild("default")
+		Expect(err).To(MatchError(ContainSubstring("spec.baseImage")))
+	})
+})
@@ -0,0 +1,219 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+	"github.com/zarcen/bib-operator/internal/registry"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// baseImageInspectTimeout bounds the registry lookup of the base image platforms, so an unreachable
+// registry does not stall the reconcile. The builder pod reports pull failures on its own.
+var baseImageInspectTimeout = 30 * time.Second
+
+func (r *ImageBuildReconciler) imagePlatformsFunc() registry.PlatformsFunc {
+	if r.ImagePlatforms == nil {
+		return registry.Platforms
+	}
+	return r.ImagePlatforms
+}
+
+// reconcileBaseImageArch checks that the base image is available for the requested architecture,
+// which buildah would otherwise silently substitute with another one, producing a broken image.
+// It returns false if the check failed, in which case BaseImageReady has been marked false.
+// Registry errors skip the check rather than failing the build.
+func (r *ImageBuildReconciler) reconcileBaseImageArch(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild) (bool, error) {
+	logger := log.FromContext(ctx)
+	specBaseImage, err := r.specBaseImage(ctx, imageBuild)
+	if err != nil {
+		return false, err
+	}
+	baseImage, _ := r.resolveBaseImage(specBaseImage)
+
+	var dockerConfigJSON []byte
+	if name := imageBuild.Spec.BaseImagePullSecretName; name != "" {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: imageBuild.Namespace}, secret); err != nil {
+			return false, err
+		}
+		dockerConfigJSON = secret.Data[corev1.DockerConfigJsonKey]
+	}
+
+	inspectCtx, cancel := context.WithTimeout(ctx, baseImageInspectTimeout)
+	defer cancel()
+	platforms, err := r.imagePlatformsFunc()(inspectCtx, baseImage, dockerConfigJSON)
+	if err != nil {
+		logger.Info("Could not inspect the base image platforms, skipping the architecture check", "BaseImage", baseImage, "Error", err.Error())
+		return true, nil
+	}
+	if len(platforms) == 0 {
+		return true, nil
+	}
+
+	// OCI platforms name architectures like the kubernetes.io/arch node label.
+	arch, ok := bibv1alpha1.NodeArchitecture(imageBuild.Spec.Architecture)
+	if !ok {
+		return true, nil
+	}
+	available := make([]string, 0, len(platforms))
+	for _, platform := range platforms {
+		if platform.OS == "linux" && platform.Architecture == arch {
+			return true, nil
+		}
+		available = append(available, platform.String())
+	}
+
+	logger.Info("Base image is not available for the requested architecture", "BaseImage", baseImage, "Arch", arch, "Available", available)
+	conditions.MarkFalse(imageBuild, bibv1alpha1.BaseImageReady, bibv1alpha1.BaseImageArchMismatchReason, clusterv1beta1.ConditionSeverityError,
+		"base image %s is not available for linux/%s, it is available for %s", baseImage, arch, strings.Join(available, ", "))
+	return false, nil
+}
+
+// baseImageBuildNotReadyError reports why the ImageBuild referenced by spec.baseImageFrom cannot be
+// built on yet, as the reason and message of the BaseImageReady condition.
+type baseImageBuildNotReadyError struct {
+	reason   string
+	severity clusterv1beta1.ConditionSeverity
+	message  string
+}
+
+func (e *baseImageBuildNotReadyError) Error() string {
+	return e.message
+}
+
+// specBaseImage returns the base image of imageBuild before registry mirrors are applied:
+// spec.baseImage, or the image pushed by the ImageBuild referenced by spec.baseImageFrom, pinned to
+// its digest. While that ImageBuild cannot be built on, it returns a *baseImageBuildNotReadyError.
+func (r *ImageBuildReconciler) specBaseImage(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild) (string, error) {
+	from := imageBuild.Spec.BaseImageFrom
+	if from == nil {
+		return imageBuild.Spec.BaseImage, nil
+	}
+	base := &bibv1alpha1.ImageBuild{}
+	if err := r.Get(ctx, types.NamespacedName{Name: from.Name, Namespace: imageBuild.Namespace}, base); apierrors.IsNotFound(err) {
+		return "", &baseImageBuildNotReadyError{bibv1alpha1.BaseImageBuildPendingReason, clusterv1beta1.ConditionSeverityInfo,
+			fmt.Sprintf("waiting for ImageBuild %s to be created", from.Name)}
+	} else if err != nil {
+		return "", err
+	}
+
+	switch base.Status.Phase {
+	case bibv1alpha1.PhaseSucceeded:
+		if image := pushedImage(base); image != "" {
+			return image, nil
+		}
+		return "", &baseImageBuildNotReadyError{bibv1alpha1.InvalidBaseImageBuildReason, clusterv1beta1.ConditionSeverityError,
+			fmt.Sprintf("ImageBuild %s pushed no image to build on, it needs a registry output", from.Name)}
+	case bibv1alpha1.PhaseFailed:
+		return "", &baseImageBuildNotReadyError{bibv1alpha1.BaseImageBuildFailedReason, clusterv1beta1.ConditionSeverityError,
+			fmt.Sprintf("ImageBuild %s failed", from.Name)}
+	}
+
+	// A base build waiting, in turn, for this one would never start.
+	visited := map[string]bool{imageBuild.Name: true}
+	for next := base; next.Spec.BaseImageFrom != nil; {
+		visited[next.Name] = true
+		name := next.Spec.BaseImageFrom.Name
+		if visited[name] {
+			return "", &baseImageBuildNotReadyError{bibv1alpha1.InvalidBaseImageBuildReason, clusterv1beta1.ConditionSeverityError,
+				fmt.Sprintf("ImageBuild %s builds on ImageBuild %s, which is a cycle", next.Name, name)}
+		}
+		next = &bibv1alpha1.ImageBuild{}
+		if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: imageBuild.Namespace}, next); apierrors.IsNotFound(err) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+	}
+	return "", &baseImageBuildNotReadyError{bibv1alpha1.BaseImageBuildPendingReason, clusterv1beta1.ConditionSeverityInfo,
+		fmt.Sprintf("waiting for ImageBuild %s to succeed", from.Name)}
+}
+
+// pushedImage returns the image a succeeded build pushed to its primary registry destination, by
+// digest if the builder reported it, or an empty string without a registry output.
+func pushedImage(imageBuild *bibv1alpha1.ImageBuild) string {
+	for _, artifact := range imageBuild.Status.Artifacts {
+		if artifact.Format == bibv1alpha1.FormatOCI && artifact.Checksum != "" && !strings.Contains(artifact.Name, "@") {
+			return artifact.Name + "@" + artifact.Checksum
+		}
+	}
+	for _, url := range imageBuild.Status.OutputURLs {
+		if image, ok := strings.CutPrefix(url, "docker://"); ok {
+			return image
+		}
+	}
+	return ""
+}
+
+// reconcileBaseImageBuild checks that the ImageBuild referenced by spec.baseImageFrom has succeeded
+// and pushed an image to build on. It returns false otherwise, in which case BaseImageReady has been
+// marked false, and the build waits.
+func (r *ImageBuildReconciler) reconcileBaseImageBuild(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild) (bool, error) {
+	_, err := r.specBaseImage(ctx, imageBuild)
+	var notReady *baseImageBuildNotReadyError
+	if errors.As(err, &notReady) {
+		log.FromContext(ctx).Info("Base ImageBuild cannot be built on yet", "BaseImageBuild", imageBuild.Spec.BaseImageFrom.Name, "Reason", notReady.reason)
+		conditions.MarkFalse(imageBuild, bibv1alpha1.BaseImageReady, notReady.reason, notReady.severity, "%s", notReady.message)
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// baseImageFromField indexes ImageBuilds by the name of the ImageBuild they build on with
+// spec.baseImageFrom.
+const baseImageFromField = "spec.baseImageFrom.name"
+
+// indexBaseImageFrom returns the value of baseImageFromField for an ImageBuild.
+func indexBaseImageFrom(obj client.Object) []string {
+	if from := obj.(*bibv1alpha1.ImageBuild).Spec.BaseImageFrom; from != nil {
+		return []string{from.Name}
+	}
+	return nil
+}
+
+// dependentImageBuilds maps an ImageBuild to the unfinished ImageBuilds building on it with
+// spec.baseImageFrom, so they start as soon as it succeeds.
+func (r *ImageBuildReconciler) dependentImageBuilds(ctx context.Context, obj client.Object) []reconcile.Request {
+	imageBuilds := &bibv1alpha1.ImageBuildList{}
+	if err := r.List(ctx, imageBuilds, client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{baseImageFromField: obj.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list the ImageBuilds building on an ImageBuild", "ImageBuild", obj.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, imageBuild := range imageBuilds.Items {
+		if !isTerminalPhase(imageBuild.Status.Phase) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&imageBuild)})
+		}
+	}
+	return requests
+}
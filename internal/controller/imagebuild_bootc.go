@@ -0,0 +1,64 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+// bootcProvisioner returns the variables, volumes and mounts of a bootc provisioner. Its
+// Containerfile, if any, is built like the one of a Dockerfile provisioner. baseImage is the base
+// image as specified, which installed machines track for updates unless another target is set.
+func bootcProvisioner(imageBuild *bibv1alpha1.ImageBuild, baseImage string) ([]corev1.EnvVar, []corev1.Volume, []corev1.VolumeMount) {
+	bootc := imageBuild.Spec.Provisioner.Bootc
+	var (
+		envVars []corev1.EnvVar
+		volumes []corev1.Volume
+		mounts  []corev1.VolumeMount
+	)
+	if bootc.Containerfile != nil {
+		envVars, volumes, mounts = dockerfileProvisioner(bootc.Containerfile)
+	}
+	envVars = append(envVars,
+		corev1.EnvVar{Name: "BOOTC", Value: "true"},
+		corev1.EnvVar{Name: "BOOTC_TARGET_IMGREF", Value: bootcTargetImage(imageBuild, baseImage)},
+	)
+	if len(bootc.KernelArgs) > 0 {
+		kernelArgs, _ := json.Marshal(bootc.KernelArgs)
+		envVars = append(envVars, corev1.EnvVar{Name: "BOOTC_KERNEL_ARGS", Value: string(kernelArgs)})
+	}
+	if bootc.Lint {
+		envVars = append(envVars, corev1.EnvVar{Name: "BOOTC_LINT", Value: "true"})
+	}
+	return envVars, volumes, mounts
+}
+
+// bootcTargetImage returns the image machines installed from a bootc disk image track: the target
+// image if set, else the pushed image, else the base image.
+func bootcTargetImage(imageBuild *bibv1alpha1.ImageBuild, baseImage string) string {
+	if target := imageBuild.Spec.Provisioner.Bootc.TargetImage; target != "" {
+		return target
+	}
+	if registry := imageBuild.Spec.Output.Registry; registry != nil {
+		return registry.Destination
+	}
+	return baseImage
+}
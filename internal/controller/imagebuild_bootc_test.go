@@ -0,0 +1,108 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("ImageBuild bootc provisioner", func() {
+	ctx := context.Background()
+
+	newBootcImageBuild := func(name string) *bibv1alpha1.ImageBuild {
+		ib := newTestImageBuild(name)
+		ib.Spec.BaseImage = "quay.io/fedora/fedora-bootc:41"
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Bootc: &bibv1alpha1.BootcSpec{}}
+		return ib
+	}
+
+	It("installs the base image as is, tracking it for updates", func() {
+		ib := newBootcImageBuild("bootc-base")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		builder := pod.Spec.Containers[0]
+		Expect(builder.Env).To(ContainElements(
+			corev1.EnvVar{Name: "BOOTC", Value: "true"},
+			corev1.EnvVar{Name: "BOOTC_TARGET_IMGREF", Value: "quay.io/fedora/fedora-bootc:41"},
+		))
+		Expect(builder.Env).NotTo(ContainElement(HaveField("Name", "DOCKERFILE_PATH")))
+		Expect(builder.Env).NotTo(ContainElement(HaveField("Name", "BOOTC_KERNEL_ARGS")))
+		Expect(builder.Env).NotTo(ContainElement(HaveField("Name", "BOOTC_LINT")))
+		Expect(buildSummary(ib)).To(HavePrefix("bootc→"))
+	})
+
+	It("builds the Containerfile and passes the bootc options", func() {
+		ib := newBootcImageBuild("bootc-containerfile")
+		ib.Spec.Provisioner.Bootc = &bibv1alpha1.BootcSpec{
+			Containerfile: &bibv1alpha1.DockerfileSpec{Repo: "https://github.com/example/bootc-images.git"},
+			KernelArgs:    []string{"console=ttyS0,115200n8", "quiet"},
+			Lint:          true,
+		}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		builder := pod.Spec.Containers[0]
+		Expect(builder.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "source-repo", MountPath: "/source"}))
+		Expect(builder.Env).To(ContainElements(
+			corev1.EnvVar{Name: "DOCKERFILE_PATH", Value: "Containerfile"},
+			corev1.EnvVar{Name: "DOCKERFILE_GIT_REPO", Value: "https://github.com/example/bootc-images.git"},
+			corev1.EnvVar{Name: "BOOTC_KERNEL_ARGS", Value: `["console=ttyS0,115200n8","quiet"]`},
+			corev1.EnvVar{Name: "BOOTC_LINT", Value: "true"},
+		))
+	})
+
+	It("tracks the pushed image, or the target image if set", func() {
+		ib := newBootcImageBuild("bootc-target")
+		ib.Spec.Output.Registry = &bibv1alpha1.RegistryOutput{
+			Destination: "quay.io/example/bootc:latest", PullSecretName: "quay-push",
+		}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "BOOTC_TARGET_IMGREF", Value: "quay.io/example/bootc:latest"}))
+
+		ib.Spec.Provisioner.Bootc.TargetImage = "registry.example.com/fleet/bootc:stable"
+		pod, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "BOOTC_TARGET_IMGREF", Value: "registry.example.com/fleet/bootc:stable"}))
+	})
+
+	It("requires the ConfigMap of the Containerfile", func() {
+		ib := newBootcImageBuild("bootc-missing-configmap")
+		ib.Spec.Provisioner.Bootc.Containerfile = &bibv1alpha1.DockerfileSpec{
+			ConfigMapRef: &corev1.LocalObjectReference{Name: "bootc-containerfile"},
+		}
+		r := newFakeReconciler(ib)
+
+		ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.ProvisionerReady)).To(Equal(bibv1alpha1.ConfigMapNotFoundReason))
+		Expect(conditions.GetMessage(ib, bibv1alpha1.ProvisionerReady)).To(ContainSubstring("spec.provisioner.bootc.containerfile.configMapRef"))
+	})
+})
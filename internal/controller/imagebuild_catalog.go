@@ -0,0 +1,83 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+// catalogEntry describes an artifact in the ArtifactsAnnotation of an ImageBuild.
+type catalogEntry struct {
+	Format   bibv1alpha1.OutputFormat `json:"format"`
+	Name     string                   `json:"name"`
+	URL      string                   `json:"url,omitempty"`
+	Checksum string                   `json:"checksum,omitempty"`
+}
+
+// hasArtifacts returns true if imageBuild succeeded with artifacts, which belong in the catalog.
+func hasArtifacts(imageBuild *bibv1alpha1.ImageBuild) bool {
+	return imageBuild.Status.Phase == bibv1alpha1.PhaseSucceeded && len(imageBuild.Status.Artifacts) > 0
+}
+
+// artifactCatalog returns the ArtifactsAnnotation value listing the artifacts of imageBuild.
+func artifactCatalog(imageBuild *bibv1alpha1.ImageBuild) string {
+	entries := make([]catalogEntry, 0, len(imageBuild.Status.Artifacts))
+	for _, artifact := range imageBuild.Status.Artifacts {
+		entries = append(entries, catalogEntry{
+			Format:   artifact.Format,
+			Name:     artifact.Name,
+			URL:      artifact.URL,
+			Checksum: artifact.Checksum,
+		})
+	}
+	// Marshaling plain strings cannot fail.
+	data, _ := json.Marshal(entries)
+	return string(data)
+}
+
+// catalogRecorded returns true if the artifact catalog is up to date for imageBuild: a succeeded
+// build with artifacts carries the HasArtifactsLabel and lists its current artifacts in the
+// ArtifactsAnnotation.
+func catalogRecorded(imageBuild *bibv1alpha1.ImageBuild) bool {
+	if !hasArtifacts(imageBuild) {
+		return true
+	}
+	return imageBuild.Labels[bibv1alpha1.HasArtifactsLabel] == "true" &&
+		imageBuild.Annotations[bibv1alpha1.ArtifactsAnnotation] == artifactCatalog(imageBuild)
+}
+
+// recordArtifactCatalog adds a succeeded ImageBuild with artifacts to the artifact catalog: it sets
+// the HasArtifactsLabel, and lists the artifacts in the ArtifactsAnnotation. Both are removed from
+// any other build, e.g. one rebuilding after its spec changed.
+func recordArtifactCatalog(imageBuild *bibv1alpha1.ImageBuild) {
+	if !hasArtifacts(imageBuild) {
+		delete(imageBuild.Labels, bibv1alpha1.HasArtifactsLabel)
+		delete(imageBuild.Annotations, bibv1alpha1.ArtifactsAnnotation)
+		return
+	}
+
+	if imageBuild.Labels == nil {
+		imageBuild.Labels = map[string]string{}
+	}
+	if imageBuild.Annotations == nil {
+		imageBuild.Annotations = map[string]string{}
+	}
+	imageBuild.Labels[bibv1alpha1.HasArtifactsLabel] = "true"
+	imageBuild.Annotations[bibv1alpha1.ArtifactsAnnotation] = artifactCatalog(imageBuild)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("ImageBuild artifact catalog", func() {
+	ctx := context.Background()
+
+	artifacts := []bibv1alpha1.ArtifactStatus{
+		{Format: bibv1alpha1.FormatQCOW2, Name: "golden.qcow2", URL: "s3://images/default/golden/golden.qcow2", Checksum: "sha256:abc"},
+		{Format: bibv1alpha1.FormatTGZ, Name: "golden.tar.gz", SizeBytes: 1024},
+	}
+
+	It("lists the artifacts of succeeded builds only", func() {
+		ib := newTestImageBuild("catalogued")
+		ib.Status.Artifacts = artifacts
+
+		recordArtifactCatalog(ib)
+		Expect(ib.Labels).NotTo(HaveKey(bibv1alpha1.HasArtifactsLabel))
+		Expect(ib.Annotations).NotTo(HaveKey(bibv1alpha1.ArtifactsAnnotation))
+
+		ib.Status.Phase = bibv1alpha1.PhaseSucceeded
+		recordArtifactCatalog(ib)
+		Expect(ib.Labels).To(HaveKeyWithValue(bibv1alpha1.HasArtifactsLabel, "true"))
+		Expect(ib.Annotations[bibv1alpha1.ArtifactsAnnotation]).To(MatchJSON(`[
+			{"format": "qcow2", "name": "golden.qcow2", "url": "s3://images/default/golden/golden.qcow2", "checksum": "sha256:abc"},
+			{"format": "tgz", "name": "golden.tar.gz"}
+		]`))
+
+		By("removing the build from the catalog when it is rebuilt")
+		ib.Status.Phase = bibv1alpha1.PhasePending
+		recordArtifactCatalog(ib)
+		Expect(ib.Labels).NotTo(HaveKey(bibv1alpha1.HasArtifactsLabel))
+		Expect(ib.Annotations).NotTo(HaveKey(bibv1alpha1.ArtifactsAnnotation))
+	})
+
+	It("adds builds that succeeded before to the catalog", func() {
+		ib := newTestImageBuild("catalogued-late")
+		ib.Generation = 1
+		r := newFakeReconciler(ib)
+		key := types.NamespacedName{Name: "catalogued-late", Namespace: "default"}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		finished := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, finished)).To(Succeed())
+		completed := metav1.Now()
+		finished.Status.Phase, finished.Status.CompletionTime = bibv1alpha1.PhaseSucceeded, &completed
+		finished.Status.Artifacts = artifacts
+		Expect(r.Status().Update(ctx, finished)).To(Succeed())
+		Expect(r.settled(finished)).To(BeFalse())
+
+		_, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, updated)).To(Succeed())
+		Expect(updated.Labels).To(HaveKeyWithValue(bibv1alpha1.HasArtifactsLabel, "true"))
+		Expect(updated.Annotations).To(HaveKey(bibv1alpha1.ArtifactsAnnotation))
+		Expect(r.settled(updated)).To(BeTrue())
+
+		By("restoring an annotation removed or edited since")
+		for _, value := range []string{"", `[{"format": "qcow2", "name": "other.qcow2"}]`} {
+			if value == "" {
+				delete(updated.Annotations, bibv1alpha1.ArtifactsAnnotation)
+			} else {
+				updated.Annotations[bibv1alpha1.ArtifactsAnnotation] = value
+			}
+			Expect(r.Update(ctx, updated)).To(Succeed())
+			Expect(r.settled(updated)).To(BeFalse())
+
+			_, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(r.Get(ctx, key, updated)).To(Succeed())
+			Expect(updated.Annotations[bibv1alpha1.ArtifactsAnnotation]).To(Equal(artifactCatalog(updated)))
+			Expect(r.settled(updated)).To(BeTrue())
+		}
+
+		By("listing it with a label selector")
+		list := &bibv1alpha1.ImageBuildList{}
+		Expect(r.List(ctx, list, client.MatchingLabels{bibv1alpha1.HasArtifactsLabel: "true"})).To(Succeed())
+		Expect(list.Items).To(ConsistOf(HaveField("Name", "catalogued-late")))
+	})
+})
@@ -0,0 +1,118 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	v1beta2conditions "sigs.k8s.io/cluster-api/util/conditions/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("ImageBuild condition generations", func() {
+	ctx := context.Background()
+
+	It("stamps every condition with the generation it was evaluated against", func() {
+		ib := newTestImageBuild("generations")
+		ib.Generation = 1
+		r := newFakeReconciler(ib)
+
+		_, updated := reconcileBuild(r, "generations")
+		for _, conditionType := range bibv1alpha1.ImageBuildConditionTypes {
+			condition := v1beta2conditions.Get(updated, string(conditionType))
+			Expect(condition).NotTo(BeNil(), "condition %s", conditionType)
+			Expect(condition.ObservedGeneration).To(Equal(int64(1)))
+		}
+		builderPodReady := v1beta2conditions.Get(updated, string(bibv1alpha1.BuilderPodReady))
+		Expect(builderPodReady.Status).To(Equal(metav1.ConditionFalse))
+		Expect(builderPodReady.Reason).To(Equal(bibv1alpha1.BuilderPodStartingReason))
+
+		By("editing the spec mid-build and starting the builder container")
+		updated.Generation = 2
+		updated.Spec.BaseImage = "ubuntu:24.10"
+		Expect(r.Update(ctx, updated)).To(Succeed())
+		pod := &corev1.Pod{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: builderPodPrefix + "generations", Namespace: "default"}, pod)).To(Succeed())
+		pod.Status.Phase = corev1.PodRunning
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:  builderContainerName,
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+		}}
+		Expect(r.Status().Update(ctx, pod)).To(Succeed())
+
+		_, updated = reconcileBuild(r, "generations")
+		builderPodReady = v1beta2conditions.Get(updated, string(bibv1alpha1.BuilderPodReady))
+		Expect(builderPodReady.Status).To(Equal(metav1.ConditionTrue))
+		Expect(builderPodReady.Reason).To(Equal(v1beta2conditions.NoReasonReported))
+		Expect(builderPodReady.ObservedGeneration).To(Equal(int64(2)))
+		By("stamping the unchanged conditions too")
+		Expect(v1beta2conditions.Get(updated, string(bibv1alpha1.PublishReady)).ObservedGeneration).To(Equal(int64(2)))
+	})
+
+	It("re-evaluates the conditions that failed against an earlier generation", func() {
+		ib := newTestImageBuild("stale")
+		ib.Generation = 2
+		ib.Status.ObservedGeneration = 1
+		ib.Status.Phase = bibv1alpha1.PhasePending
+		conditions.MarkFalse(ib, bibv1alpha1.ProvisionerReady, bibv1alpha1.SecretNotFoundReason, clusterv1beta1.ConditionSeverityError,
+			"secret %q referenced by spec.provisioner.ansible.credentialsSecretName not found", "git")
+		conditions.MarkFalse(ib, bibv1alpha1.BuilderPodReady, bibv1alpha1.PodCreationFailedReason, clusterv1beta1.ConditionSeverityWarning,
+			"builder pod could not be created")
+		conditions.MarkUnknown(ib, bibv1alpha1.OutputReady, bibv1alpha1.OutputPVCInUseReason, "output PVC in use")
+
+		Expect(resetStaleConditions(ib)).To(BeTrue())
+		Expect(ib.Status.ObservedGeneration).To(Equal(int64(2)))
+		Expect(conditions.IsUnknown(ib, bibv1alpha1.ProvisionerReady)).To(BeTrue())
+		Expect(conditions.GetReason(ib, bibv1alpha1.ProvisionerReady)).To(Equal("Initializing"))
+		Expect(conditions.IsUnknown(ib, bibv1alpha1.BuilderPodReady)).To(BeTrue())
+		Expect(conditions.GetReason(ib, bibv1alpha1.OutputReady)).To(Equal(bibv1alpha1.OutputPVCInUseReason))
+
+		By("leaving the conditions alone while the generation is unchanged")
+		conditions.MarkFalse(ib, bibv1alpha1.ProvisionerReady, bibv1alpha1.SecretNotFoundReason, clusterv1beta1.ConditionSeverityError, "not found")
+		Expect(resetStaleConditions(ib)).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.ProvisionerReady)).To(Equal(bibv1alpha1.SecretNotFoundReason))
+
+		By("keeping the conditions and observed generation of a finished build")
+		ib.Generation = 3
+		ib.Status.Phase = bibv1alpha1.PhaseFailed
+		Expect(resetStaleConditions(ib)).To(BeFalse())
+		Expect(ib.Status.ObservedGeneration).To(Equal(int64(2)))
+		Expect(conditions.GetReason(ib, bibv1alpha1.ProvisionerReady)).To(Equal(bibv1alpha1.SecretNotFoundReason))
+	})
+
+	It("records the generation the status was reconciled against", func() {
+		ib := newTestImageBuild("observed")
+		ib.Generation = 4
+		r := newFakeReconciler(ib)
+		key := types.NamespacedName{Name: "observed", Namespace: "default"}
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, updated)).To(Succeed())
+		Expect(updated.Status.ObservedGeneration).To(Equal(int64(4)))
+	})
+})
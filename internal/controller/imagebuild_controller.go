@@ -20,37 +20,142 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+	"github.com/zarcen/bib-operator/internal/ami"
+	"github.com/zarcen/bib-operator/internal/notify"
+	"github.com/zarcen/bib-operator/internal/registry"
 	"github.com/zarcen/bib-operator/internal/scope"
+	"github.com/zarcen/bib-operator/internal/tracing"
 	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
 var builderPodPrefix = "imgbldr-"
 
+// FieldManager is the name the writes of the operator are recorded as in the managed fields: the
+// user agent of its client, and the field owner the status is applied as with StatusServerSideApply.
+const FieldManager = "bib-operator"
+
+// buildCacheMountPath is where the spec.build.cache claim is mounted in the builder container.
+const buildCacheMountPath = "/var/cache/bib"
+
+// sourceMountPath is where the provisioning repository is cloned in the builder container.
+const sourceMountPath = "/source"
+
+// packageMirrorsMountPath is where the spec.build.packageMirrors config map is mounted in the builder container.
+const packageMirrorsMountPath = "/etc/package-mirrors"
+
 // ImageBuildReconciler reconciles a ImageBuild object
 type ImageBuildReconciler struct {
 	client.Client
 	Scheme       *runtime.Scheme
 	BuilderImage string
+
+	// MaxConcurrentPublishes caps the number of publisher pods running at once across the cluster.
+	// Zero means unlimited.
+	MaxConcurrentPublishes int
+
+	// AllowHostNetwork permits ImageBuilds to run their builder pod with spec.build.hostNetwork.
+	AllowHostNetwork bool
+
+	// ForbidPrivileged rejects the builds of namespaces not listed in PrivilegedNamespaces. The builder
+	// pod runs privileged, to mount and modify the image's root filesystem.
+	ForbidPrivileged bool
+
+	// PrivilegedNamespaces lists the namespaces whose builds may run privileged with ForbidPrivileged.
+	PrivilegedNamespaces []string
+
+	// AllowRepositoryCreation permits ImageBuilds to create their registry repository with
+	// spec.output.registry.createRepository.
+	AllowRepositoryCreation bool
+
+	// StatusServerSideApply persists the ImageBuild status with server-side apply rather than
+	// merge patches, so the controller only owns the status fields it sets.
+	StatusServerSideApply bool
+
+	// Finalizer is the finalizer the controller adds to ImageBuilds to clean up after them, so that
+	// several controller instances can each finalize the builds they handle. Defaults to
+	// bibv1alpha1.ImageBuildFinalizer when empty.
+	Finalizer string
+
+	// MinFreeDiskSpace is the free space the container storage of builds must have before they start,
+	// unless spec.build.minFreeDiskSpace overrides it. Zero disables the check.
+	MinFreeDiskSpace resource.Quantity
+
+	// RegistryMirrors maps registry hosts, e.g. "docker.io", to pull-through cache prefixes the
+	// base images of builds are pulled from instead.
+	RegistryMirrors map[string]string
+
+	// Notifier delivers terminal-state notifications configured via spec.notify.
+	// Defaults to an HTTP notifier when nil.
+	Notifier notify.Notifier
+
+	// NewEC2Client builds the EC2 client used to apply spec.publish.aws.existingAMIPolicy.
+	// Defaults to an AWS SDK client when nil.
+	NewEC2Client ami.NewEC2ClientFunc
+
+	// ImagePlatforms lists the platforms a base image is available for, to catch an architecture
+	// mismatch before the builder pod is scheduled. Defaults to a registry API client when nil.
+	ImagePlatforms registry.PlatformsFunc
+
+	// PrePullNodeSelector selects the build nodes the builder image is pre-pulled on, see
+	// BuilderPrePuller. Builder pods prefer these nodes. Empty when the image is not pre-pulled.
+	PrePullNodeSelector map[string]string
+
+	// TracerProvider provides the tracer of the ImageBuild lifecycle spans. Its span IDs must be
+	// generated as by tracing.NewTracerProvider. Defaults to the global provider when nil.
+	TracerProvider trace.TracerProvider
+
+	// Recorder emits events on ImageBuilds. Events are dropped when nil.
+	Recorder record.EventRecorder
+
+	// BuildNamespaces runs the builder pod of each ImageBuild in a namespace of its own, isolated from
+	// the workloads of the ImageBuild's namespace, and deletes it along with the ImageBuild.
+	BuildNamespaces bool
+
+	// BuildNamespaceQuota is the hard limits of the ResourceQuota of build namespaces. Defaults to a
+	// single pod when empty.
+	BuildNamespaceQuota corev1.ResourceList
+}
+
+// recordEventf emits an event on imageBuild, if the reconciler has a recorder.
+func (r *ImageBuildReconciler) recordEventf(imageBuild *bibv1alpha1.ImageBuild, eventType, reason, messageFmt string, args ...any) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(imageBuild, eventType, reason, messageFmt, args...)
 }
 
 //+kubebuilder:rbac:groups=bib.cluster.x-k8s.io,resources=imagebuilds,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=bib.cluster.x-k8s.io,resources=imagebuilds/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=bib.cluster.x-k8s.io,resources=imagebuilds/finalizers,verbs=update
 //+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 func (r *ImageBuildReconciler) Reconcile(ctx context.Context, req ctrl.Request) (retRes ctrl.Result, reterr error) {
 	logger := log.FromContext(ctx)
@@ -65,26 +170,52 @@ func (r *ImageBuildReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		logger.Error(err, "Failed to get ImageBuild resource")
 		return ctrl.Result{}, err
 	}
-	// Add the finalizer if it doesn't exist
-	if !controllerutil.ContainsFinalizer(&ib, bibv1alpha1.ImageBuildFinalizer) {
-		controllerutil.AddFinalizer(&ib, bibv1alpha1.ImageBuildFinalizer)
+	// Finished builds are reconciled again on every resync and on events of their pods; most have
+	// nothing left to do.
+	if r.settled(&ib) {
+		logger.V(1).Info("Build has finished, nothing to reconcile", "Phase", ib.Status.Phase)
+		return ctrl.Result{}, nil
+	}
+	ctx, span := r.startReconcileSpan(ctx, &ib)
+	defer func() { endReconcileSpan(span, reterr) }()
+
+	// Add the finalizer if it doesn't exist. Deleted objects are only kept around by the
+	// finalizers they have, and the API server rejects new ones.
+	if ib.DeletionTimestamp.IsZero() && !controllerutil.ContainsFinalizer(&ib, r.finalizer()) {
+		controllerutil.AddFinalizer(&ib, r.finalizer())
 		if err := r.Update(ctx, &ib); err != nil {
 			return ctrl.Result{Requeue: true}, err
 		}
 	}
 
 	// Create a scope for the imagebuild
-	ibs, err := scope.NewImageBuildScope(r.Client, logger, &ib)
+	var scopeOpts []scope.ImageBuildScopeOption
+	if r.StatusServerSideApply {
+		scopeOpts = append(scopeOpts, scope.WithStatusApply(FieldManager))
+	}
+	ibs, err := scope.NewImageBuildScope(r.Client, logger, &ib, scopeOpts...)
 	if err != nil {
 		logger.Error(err, "Failed to create scope for imagebuild")
 		return ctrl.Result{}, err
 	}
+	// Remember the phase the build is in, to time it once the build moves on.
+	phase := ib.Status.Phase
+	phaseSince, phaseSinceOK := phaseStartTime(&ib)
 	// Always close the scope when exiting this function so we can persist any changes.
 	defer func() {
-		if err := ibs.Close(ctx); err != nil && reterr == nil {
-			reterr = err
-			retRes = ctrl.Result{}
+		now := time.Now()
+		r.recordPhaseSpans(ctx, &ib, phase, phaseSince, phaseSinceOK, now)
+		observePhase := recordPhaseTransition(&ib, phase, phaseSince, phaseSinceOK, now)
+		recordArtifactCatalog(&ib)
+		if err := ibs.Close(ctx); err != nil {
+			if reterr == nil {
+				reterr = err
+				retRes = ctrl.Result{}
+			}
+			return
 		}
+		// A transition that was not persisted is reconciled, and observed, again.
+		observePhase()
 	}()
 	ibs.InitializeConditions()
 
@@ -93,66 +224,778 @@ func (r *ImageBuildReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return r.reconcileDelete(ctx, ibs)
 	}
 
+	if resetPodCreationFailure(&ib) {
+		logger.Info("Spec changed, retrying build that failed to create its builder pod", "Generation", ib.Generation)
+	}
+	if resetUnrecordedCompletion(&ib) {
+		logger.Info("Phase was not recorded by the controller, deriving it from the pods again", "Phase", phase)
+	}
+	if resetStaleConditions(&ib) {
+		logger.V(1).Info("Reconciling a new generation of the spec", "Generation", ib.Generation)
+	}
+	if message := ignoredSpecChangeMessage(&ib); message != "" && !ignoredSpecChangeRecorded(&ib) {
+		logger.Info("Spec changed after the build finished, not building it again", "Generation", ib.Generation)
+		conditions.MarkFalse(&ib, bibv1alpha1.SpecBuilt, bibv1alpha1.SpecChangeIgnoredReason, clusterv1beta1.ConditionSeverityWarning, "%s", message)
+		r.recordEventf(&ib, corev1.EventTypeWarning, bibv1alpha1.SpecChangeIgnoredReason, "%s", message)
+	}
+
+	// Keep the summary of a finished build describing the spec it ran with, even if edited since.
+	if !isTerminalPhase(ib.Status.Phase) || ib.Status.Summary == "" {
+		ib.Status.Summary = buildSummary(&ib)
+	}
+
+	// Finished builds only have their notification left to deliver.
+	if isTerminalPhase(ib.Status.Phase) {
+		return r.reconcileNotify(ctx, ibs)
+	}
+
 	// Check if a builder pod already exists
 	builderPod := &corev1.Pod{}
 	builderPodName := fmt.Sprintf("%s%s", builderPodPrefix, ib.Name)
-	err = r.Get(ctx, types.NamespacedName{Name: builderPodName, Namespace: ib.Namespace}, builderPod)
+	err = r.Get(ctx, types.NamespacedName{Name: builderPodName, Namespace: r.builderPodNamespace(&ib)}, builderPod)
 
 	if err != nil && apierrors.IsNotFound(err) {
 		// Pod does not exist, create it
 		logger.Info("Builder pod not found. Creating a new one.")
 
+		if terminating, err := r.reconcileNamespaceTerminating(ctx, &ib, bibv1alpha1.BuilderPodReady); err != nil || terminating {
+			return ctrl.Result{}, err
+		}
+
+		// Validate referenced secrets up front rather than failing deep inside the build.
+		ok, err := r.reconcilePreflight(ctx, ibs)
+		if err != nil {
+			logger.Error(err, "Failed to run preflight checks")
+			return ctrl.Result{}, err
+		}
+		if !ok {
+			return ctrl.Result{RequeueAfter: preflightRequeueAfter}, nil
+		}
+
+		// A builder pod pinned to an architecture no node has would stay Pending.
+		noNodes, err := r.reconcileNodeArchitecture(ctx, &ib)
+		if err != nil {
+			logger.Error(err, "Failed to list nodes for the build architecture")
+			return ctrl.Result{}, err
+		}
+		if noNodes {
+			return ctrl.Result{}, nil
+		}
+
+		if err := r.reconcileOutputPVCCreate(ctx, &ib); err != nil {
+			logger.Error(err, "Failed to create the output PVC")
+			return ctrl.Result{}, err
+		}
+
+		// Builds sharing a ReadWriteOnce output PVC would leave the later builder pod unschedulable.
+		inUse, err := r.reconcileOutputPVCInUse(ctx, &ib)
+		if err != nil {
+			logger.Error(err, "Failed to check whether the output PVC is in use")
+			return ctrl.Result{}, err
+		}
+		if inUse {
+			return ctrl.Result{RequeueAfter: outputPVCInUseRequeueAfter}, nil
+		}
+
+		cacheNote, err := r.buildCacheSharingNote(ctx, &ib)
+		if err != nil {
+			logger.Error(err, "Failed to check whether the build cache PVC is in use")
+			return ctrl.Result{}, err
+		}
+
+		// The base image the pod is constructed with, recorded in the status once it is created. The
+		// base image checks above passed, so an error is transient, e.g. a base build being edited.
+		specBaseImage, err := r.specBaseImage(ctx, &ib)
+		if err != nil {
+			logger.Error(err, "Failed to get the base image")
+			return ctrl.Result{}, err
+		}
+
 		// Construct the desired pod object
+		// Failures are retried with a backoff of our own, up to a cap, rather than by returning the
+		// error, so a spec that can never produce a pod does not retry forever.
 		desiredPod, err := r.constructBuilderPod(ctx, &ib)
 		if err != nil {
 			logger.Error(err, "Failed to construct builder pod spec")
-			conditions.MarkFalse(&ib, bibv1alpha1.BuilderPodReady, "BuildPodNotReady", clusterv1beta1.ConditionSeverityError, "%s", err.Error())
-			return ctrl.Result{}, err
+			return reconcilePodCreationFailure(ctx, &ib, err)
 		}
 
-		if err := ctrl.SetControllerReference(&ib, desiredPod, r.Scheme); err != nil {
+		if r.BuildNamespaces {
+			// Owner references cannot cross namespaces; the build namespace is deleted with the ImageBuild instead.
+			ready, err := r.reconcileBuildNamespace(ctx, &ib, desiredPod)
+			if err != nil {
+				logger.Error(err, "Failed to prepare the build namespace", "Namespace", desiredPod.Namespace)
+				return reconcilePodCreationFailure(ctx, &ib, err)
+			}
+			if !ready {
+				logger.Info("Waiting for the build namespace to be set up", "Namespace", desiredPod.Namespace)
+				conditions.MarkFalse(&ib, bibv1alpha1.BuilderPodReady, bibv1alpha1.BuildNamespacePendingReason, clusterv1beta1.ConditionSeverityInfo,
+					"waiting for the ResourceQuota and default ServiceAccount of build namespace %s", desiredPod.Namespace)
+				return ctrl.Result{RequeueAfter: buildNamespacePendingRequeueAfter}, nil
+			}
+		} else if err := ctrl.SetControllerReference(&ib, desiredPod, r.Scheme); err != nil {
 			logger.Error(err, "Failed to set owner reference on builder pod")
-			return ctrl.Result{}, err
+			return reconcilePodCreationFailure(ctx, &ib, err)
 		}
 
 		// Create the pod in the cluster
 		if err := r.Create(ctx, desiredPod); err != nil {
 			logger.Error(err, "Failed to create builder pod")
-			// TODO: Update status to Failed
-			return ctrl.Result{}, err
+			return reconcilePodCreationFailure(ctx, &ib, err)
 		}
 
-		// TODO: Update status to Building
 		logger.Info("Successfully created builder pod", "PodName", desiredPod.Name)
+		now := metav1.Now()
+		ib.Status.StartTime = &now
+		// The deadline counts from when the kubelet starts the pod, not from its creation.
+		ib.Status.BuildDeadline = nil
+		ib.Status.BuilderPodName = desiredPod.Name
+		ib.Status.BuilderPodNamespace = ""
+		if desiredPod.Namespace != ib.Namespace {
+			ib.Status.BuilderPodNamespace = desiredPod.Namespace
+		}
+		ib.Status.BuildAttempts++
+		ib.Status.ExitCode, ib.Status.TerminationReason = nil, ""
+		ib.Status.Stages = nil
+		ib.Status.PodCreationFailures = 0
+		ib.Status.ResolvedBaseImage, ib.Status.BaseImageMirror = r.resolveBaseImage(specBaseImage)
+		ib.Status.Phase = bibv1alpha1.PhasePending
+		conditions.MarkFalse(&ib, bibv1alpha1.BuilderPodReady, bibv1alpha1.BuilderPodStartingReason, clusterv1beta1.ConditionSeverityInfo,
+			"builder pod %s created%s", desiredPod.Name, cacheNote)
 		return ctrl.Result{Requeue: true}, nil // Requeue to check pod status later
 	} else if err != nil {
 		logger.Error(err, "Failed to get builder pod")
 		return ctrl.Result{}, err
 	}
 
-	// 4. If pod exists, check its status (we will implement this logic next)
-	logger.Info("Builder pod already exists", "PodPhase", builderPod.Status.Phase)
-	// TODO: Handle Pod Succeeded, Failed, etc.
+	// The pod exists, mirror its status onto the ImageBuild.
+	logger.V(1).Info("Builder pod already exists", "PodPhase", builderPod.Status.Phase)
+	ib.Status.BuilderPodName = builderPod.Name
+	ib.Status.BuildDeadline = buildDeadline(builderPod)
+
+	// The builder uploads its log on exit, successful or not, and reports whether the upload succeeded.
+	if builderPod.Status.Phase == corev1.PodSucceeded || builderPod.Status.Phase == corev1.PodFailed {
+		ib.Status.LogURL = uploadedBuildLogURL(ctx, &ib, builderPod)
+		recordBuilderTermination(&ib, builderPod)
+		if err := r.cleanupBuildNamespaceSecrets(ctx, &ib); err != nil {
+			logger.Error(err, "Failed to delete the secrets of the build namespace")
+			return ctrl.Result{}, err
+		}
+	}
+
+	switch builderPod.Status.Phase {
+	case corev1.PodPending, corev1.PodRunning:
+		return reconcileBuilderPodRunning(&ib, builderPod), nil
+	case corev1.PodSucceeded:
+		r.recordBuilderResult(ctx, &ib, builderPod)
+		recordOutputLocation(&ib)
+		conditions.MarkTrue(&ib, bibv1alpha1.VerifyReady)
+		conditions.MarkTrue(&ib, bibv1alpha1.SigningReady)
+		// The artifacts exist once the builder pod succeeded, whether or not they are published next.
+		conditions.MarkTrue(&ib, bibv1alpha1.OutputReady)
+		// Stages that failed without failing the build are reported on top.
+		recordBuilderStages(ctx, &ib, builderPod)
+		recordBuilderDestinations(ctx, &ib, builderPod)
+		if ib.Spec.Publish != nil {
+			return r.reconcilePublish(ctx, ibs)
+		}
+		ib.Status.Phase = bibv1alpha1.PhaseSucceeded
+		markCompleted(&ib)
+	case corev1.PodFailed:
+		// An evicted pod says nothing about the build, which is retried.
+		if message, evicted := podEviction(builderPod); evicted {
+			return r.reconcileBuilderPodEvicted(ctx, &ib, builderPod, message)
+		}
+		// A build killed at its deadline would most likely run into it again, so it is not retried.
+		if builderPod.Status.Reason == podDeadlineExceededReason {
+			recordBuilderStages(ctx, &ib, builderPod)
+			conditions.MarkFalse(&ib, bibv1alpha1.BuilderPodReady, bibv1alpha1.BuildTimedOutReason, clusterv1beta1.ConditionSeverityError,
+				"builder pod %s was killed after running longer than its deadline of %ds", builderPod.Name, activeDeadlineSeconds(builderPod))
+			ib.Status.Phase = bibv1alpha1.PhaseFailed
+			markCompleted(&ib)
+			return ctrl.Result{}, nil
+		}
+		if message, ok := diskSpaceCheckFailure(builderPod); ok {
+			conditions.MarkFalse(&ib, bibv1alpha1.BuilderPodReady, bibv1alpha1.InsufficientDiskSpaceReason, clusterv1beta1.ConditionSeverityError,
+				"builder pod %s did not start the build: %s", builderPod.Name, message)
+			ib.Status.Phase = bibv1alpha1.PhaseFailed
+			markCompleted(&ib)
+			return ctrl.Result{}, nil
+		}
+		// More memory, not a retry, gets an OOMKilled build through.
+		if builderOOMKilled(builderPod) {
+			recordBuilderStages(ctx, &ib, builderPod)
+			conditions.MarkFalse(&ib, bibv1alpha1.BuilderPodReady, bibv1alpha1.OOMKilledReason, clusterv1beta1.ConditionSeverityError,
+				"builder pod %s ran out of memory%s; raise the memory limit of the builder container, e.g. the LimitRange default of namespace %s",
+				builderPod.Name, describeBuilderTermination(&ib), builderPod.Namespace)
+			r.recordEventf(&ib, corev1.EventTypeWarning, bibv1alpha1.OOMKilledReason,
+				"Builder pod %s was OOMKilled; raise the memory limit of the builder container", builderPod.Name)
+			ib.Status.Phase = bibv1alpha1.PhaseFailed
+			markCompleted(&ib)
+			return ctrl.Result{}, nil
+		}
+		// Registries recover from rate limiting, so the build is retried rather than failed.
+		if rateLimited, ok := registryRateLimit(builderPod); ok {
+			return r.reconcileRegistryRateLimited(ctx, &ib, builderPod, rateLimited)
+		}
+		message, verifyFailed := verificationFailure(builderPod)
+		sourceClone, cloneFailed := sourceCloneFailure(builderPod)
+		// A failed verification is a property of the image, and a failed clone one of the source, not
+		// of the claim either was written to.
+		if !verifyFailed && !cloneFailed {
+			recreated, err := r.reconcileOutputPVCRecreate(ctx, &ib, builderPod)
+			if err != nil || recreated {
+				return ctrl.Result{Requeue: recreated}, err
+			}
+		}
+		failedStage := recordBuilderStages(ctx, &ib, builderPod)
+		recordBuilderDestinations(ctx, &ib, builderPod)
+		if cloneFailed {
+			// The repository of spec.verify is cloned in the verify stage, all others in provision.
+			conditionType := bibv1alpha1.ProvisionerReady
+			if failedStage != nil && failedStage.Name == builderStageVerify {
+				conditionType = bibv1alpha1.VerifyReady
+			}
+			conditions.MarkFalse(&ib, conditionType, bibv1alpha1.SourceCloneFailedReason, clusterv1beta1.ConditionSeverityError,
+				"%s", describeSourceCloneFailure(sourceClone))
+		} else if verifyFailed {
+			conditions.MarkFalse(&ib, bibv1alpha1.VerifyReady, bibv1alpha1.VerificationFailedReason, clusterv1beta1.ConditionSeverityError,
+				"%s", message)
+		} else {
+			conditions.MarkFalse(&ib, bibv1alpha1.BuilderPodReady, bibv1alpha1.BuildFailedReason, clusterv1beta1.ConditionSeverityError,
+				"builder pod %s failed%s%s", builderPod.Name, describeFailedStage(failedStage), describeBuilderTermination(&ib))
+		}
+		ib.Status.Phase = bibv1alpha1.PhaseFailed
+		markCompleted(&ib)
+	}
 
 	return ctrl.Result{}, nil
 }
 
+// reconcileBuilderPodRunning reports a builder pod that has not finished yet. The build only counts
+// as started once the builder container is actually running, not while the pod is scheduled,
+// pulling images or running init containers. A builder image that cannot be pulled for longer than
+// builderImagePullGracePeriod is reported as an error; the kubelet keeps retrying the pull.
+func reconcileBuilderPodRunning(imageBuild *bibv1alpha1.ImageBuild, pod *corev1.Pod) ctrl.Result {
+	if message, ok := builderImagePullFailure(pod); ok {
+		imageBuild.Status.Phase = bibv1alpha1.PhasePending
+		if remaining := builderImagePullGracePeriod - time.Since(pod.CreationTimestamp.Time); remaining > 0 {
+			conditions.MarkFalse(imageBuild, bibv1alpha1.BuilderPodReady, bibv1alpha1.BuilderPodStartingReason, clusterv1beta1.ConditionSeverityInfo,
+				"builder pod %s is pulling the builder image: %s", pod.Name, message)
+			return ctrl.Result{RequeueAfter: remaining}
+		}
+		conditions.MarkFalse(imageBuild, bibv1alpha1.BuilderPodReady, bibv1alpha1.BuilderImagePullFailedReason, clusterv1beta1.ConditionSeverityError,
+			"builder pod %s cannot pull the builder image: %s", pod.Name, message)
+		return ctrl.Result{}
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != builderContainerName {
+			continue
+		}
+		if status.State.Running != nil {
+			conditions.MarkTrue(imageBuild, bibv1alpha1.BuilderPodReady)
+			imageBuild.Status.Phase = bibv1alpha1.PhaseBuilding
+			return ctrl.Result{}
+		}
+		if waiting := status.State.Waiting; waiting != nil && waiting.Reason != "" {
+			imageBuild.Status.Phase = bibv1alpha1.PhasePending
+			conditions.MarkFalse(imageBuild, bibv1alpha1.BuilderPodReady, bibv1alpha1.BuilderPodStartingReason, clusterv1beta1.ConditionSeverityInfo,
+				"builder container is waiting: %s", waiting.Reason)
+			return ctrl.Result{}
+		}
+	}
+	imageBuild.Status.Phase = bibv1alpha1.PhasePending
+	conditions.MarkFalse(imageBuild, bibv1alpha1.BuilderPodReady, bibv1alpha1.BuilderPodStartingReason, clusterv1beta1.ConditionSeverityInfo,
+		"builder pod %s is %s", pod.Name, pod.Status.Phase)
+	return ctrl.Result{}
+}
+
+// podDeadlineExceededReason is the pod status reason the kubelet sets on a pod it killed for
+// running longer than its activeDeadlineSeconds.
+const podDeadlineExceededReason = "DeadlineExceeded"
+
+// builderActiveDeadlineSeconds returns the activeDeadlineSeconds of the builder pod, spec.timeoutSeconds.
+func builderActiveDeadlineSeconds(imageBuild *bibv1alpha1.ImageBuild) *int64 {
+	if imageBuild.Spec.TimeoutSeconds == nil {
+		return nil
+	}
+	timeout := *imageBuild.Spec.TimeoutSeconds
+	return &timeout
+}
+
+// buildDeadline returns when the kubelet kills pod for running longer than its
+// activeDeadlineSeconds, which count from the time the kubelet started the pod, or nil if the pod
+// has no deadline or was not started yet.
+func buildDeadline(pod *corev1.Pod) *metav1.Time {
+	if pod.Spec.ActiveDeadlineSeconds == nil || pod.Status.StartTime == nil {
+		return nil
+	}
+	deadline := metav1.NewTime(pod.Status.StartTime.Add(time.Duration(*pod.Spec.ActiveDeadlineSeconds) * time.Second))
+	return &deadline
+}
+
+// activeDeadlineSeconds returns the activeDeadlineSeconds of pod, or 0 if it has none.
+func activeDeadlineSeconds(pod *corev1.Pod) int64 {
+	if pod.Spec.ActiveDeadlineSeconds == nil {
+		return 0
+	}
+	return *pod.Spec.ActiveDeadlineSeconds
+}
+
+// mergeBuilderEnv appends the entries of builderEnv whose names are not already set in managed.
+// Managed variables come first so user variables can reference them with $(VAR_NAME).
+func mergeBuilderEnv(managed, builderEnv []corev1.EnvVar) []corev1.EnvVar {
+	names := make(map[string]bool, len(managed))
+	for _, envVar := range managed {
+		names[envVar.Name] = true
+	}
+	for _, envVar := range builderEnv {
+		if !names[envVar.Name] {
+			managed = append(managed, envVar)
+		}
+	}
+	return managed
+}
+
+// reconcileNamespaceTerminating returns true if the namespace of the ImageBuild is being deleted, in which
+// case pod creation would be rejected and conditionType is marked false instead. The ImageBuild itself is
+// deleted along with the namespace, so there is no need to requeue.
+func (r *ImageBuildReconciler) reconcileNamespaceTerminating(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild,
+	conditionType clusterv1beta1.ConditionType) (bool, error) {
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Namespace}, namespace); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if namespace.Status.Phase != corev1.NamespaceTerminating && namespace.DeletionTimestamp.IsZero() {
+		return false, nil
+	}
+	log.FromContext(ctx).Info("Namespace is terminating, not creating pods", "Namespace", namespace.Name)
+	conditions.MarkFalse(imageBuild, conditionType, bibv1alpha1.NamespaceTerminatingReason, clusterv1beta1.ConditionSeverityWarning,
+		"namespace %s is terminating", namespace.Name)
+	return true, nil
+}
+
+// ansibleMode renders the mode of an Ansible step as the lower-case builder variable, defaulting to chroot.
+func ansibleMode(ansible *bibv1alpha1.AnsibleSpec) string {
+	if ansible.Mode == "" {
+		return strings.ToLower(string(bibv1alpha1.AnsibleModeChroot))
+	}
+	return strings.ToLower(string(ansible.Mode))
+}
+
+// defaultAnsiblePythonInterpreter leaves the interpreter of the Ansible modules to Ansible's
+// interpreter discovery.
+const defaultAnsiblePythonInterpreter = "auto"
+
+// ansiblePythonEnv returns the variables selecting the Python interpreter the modules of an Ansible
+// run use, "auto" unless pythonInterpreter is set, or the virtual environment to create for them.
+func ansiblePythonEnv(prefix string, ansible *bibv1alpha1.AnsibleSpec) []corev1.EnvVar {
+	if venv := ansible.Virtualenv; venv != nil {
+		python := venv.Python
+		if python == "" {
+			python = "python3"
+		}
+		env := []corev1.EnvVar{
+			{Name: prefix + "ANSIBLE_VIRTUALENV", Value: venv.Path},
+			{Name: prefix + "ANSIBLE_VIRTUALENV_PYTHON", Value: python},
+		}
+		if len(venv.Packages) > 0 {
+			env = append(env, corev1.EnvVar{Name: prefix + "ANSIBLE_VIRTUALENV_PACKAGES", Value: strings.Join(venv.Packages, " ")})
+		}
+		return env
+	}
+	interpreter := ansible.PythonInterpreter
+	if interpreter == "" {
+		interpreter = defaultAnsiblePythonInterpreter
+	}
+	return []corev1.EnvVar{{Name: prefix + "ANSIBLE_PYTHON_INTERPRETER", Value: interpreter}}
+}
+
+// ansibleConnectionEnv returns the variables overriding the connection plugin of an Ansible run and
+// enabling privilege escalation, if set.
+func ansibleConnectionEnv(prefix string, ansible *bibv1alpha1.AnsibleSpec) []corev1.EnvVar {
+	var env []corev1.EnvVar
+	if ansible.Connection != "" {
+		env = append(env, corev1.EnvVar{Name: prefix + "ANSIBLE_TARGET_CONNECTION", Value: ansible.Connection})
+	}
+	if ansible.Become {
+		env = append(env, corev1.EnvVar{Name: prefix + "ANSIBLE_TARGET_BECOME", Value: "true"})
+	}
+	return env
+}
+
+// verbosityEnv translates spec.verbosity into the variables raising the log level of the builder,
+// BUILDER_VERBOSITY, and of the provisioner: ANSIBLE_VERBOSITY for the Ansible runs (-v or -vvv).
+// The default "Normal" verbosity sets none.
+func verbosityEnv(imageBuild *bibv1alpha1.ImageBuild) []corev1.EnvVar {
+	var ansibleVerbosity string
+	switch imageBuild.Spec.Verbosity {
+	case bibv1alpha1.VerbosityVerbose:
+		ansibleVerbosity = "1"
+	case bibv1alpha1.VerbosityDebug:
+		ansibleVerbosity = "3"
+	default:
+		return nil
+	}
+	env := []corev1.EnvVar{{Name: "BUILDER_VERBOSITY", Value: strings.ToLower(string(imageBuild.Spec.Verbosity))}}
+	provisioner, verify := imageBuild.Spec.Provisioner, imageBuild.Spec.Verify
+	if (provisioner != nil && provisioner.Ansible != nil) || (verify != nil && verify.Ansible != nil) {
+		env = append(env, corev1.EnvVar{Name: "ANSIBLE_VERBOSITY", Value: ansibleVerbosity})
+	}
+	return env
+}
+
+// builderEnvFrom returns spec.build.envFrom, if any.
+func builderEnvFrom(imageBuild *bibv1alpha1.ImageBuild) []corev1.EnvFromSource {
+	if imageBuild.Spec.Build == nil {
+		return nil
+	}
+	return imageBuild.Spec.Build.EnvFrom
+}
+
+// builderSecretFiles returns the projected volume holding the keys of spec.build.secretFiles, if
+// any, and the mounts placing each key at its path with a subPath, so the other files of its
+// directory stay visible.
+func builderSecretFiles(imageBuild *bibv1alpha1.ImageBuild) ([]corev1.Volume, []corev1.VolumeMount) {
+	if imageBuild.Spec.Build == nil || len(imageBuild.Spec.Build.SecretFiles) == 0 {
+		return nil, nil
+	}
+	mode := int32(0400)
+	projected := &corev1.ProjectedVolumeSource{DefaultMode: &mode}
+	var mounts []corev1.VolumeMount
+	for i, secretFile := range imageBuild.Spec.Build.SecretFiles {
+		item := strconv.Itoa(i)
+		projected.Sources = append(projected.Sources, corev1.VolumeProjection{Secret: &corev1.SecretProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: secretFile.SecretName},
+			Items:                []corev1.KeyToPath{{Key: secretFile.Key, Path: item}},
+		}})
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      "secret-files",
+			MountPath: secretFile.Path,
+			SubPath:   item,
+			ReadOnly:  true,
+		})
+	}
+	volume := corev1.Volume{Name: "secret-files", VolumeSource: corev1.VolumeSource{Projected: projected}}
+	return []corev1.Volume{volume}, mounts
+}
+
+// defaultBuilderPreStop flushes the writes of the builder to disk and unmounts the containers it
+// mounted before the builder container is stopped.
+var defaultBuilderPreStop = corev1.LifecycleHandler{
+	Exec: &corev1.ExecAction{Command: []string{"/bin/sh", "-c", "sync; buildah umount --all; sync"}},
+}
+
+// builderLifecycle returns the lifecycle hooks of the builder container: spec.build.lifecycle.preStop
+// if set, else defaultBuilderPreStop.
+func builderLifecycle(imageBuild *bibv1alpha1.ImageBuild) *corev1.Lifecycle {
+	preStop := defaultBuilderPreStop.DeepCopy()
+	if build := imageBuild.Spec.Build; build != nil && build.Lifecycle != nil && build.Lifecycle.PreStop != nil {
+		preStop = build.Lifecycle.PreStop.DeepCopy()
+	}
+	return &corev1.Lifecycle{PreStop: preStop}
+}
+
+// builderSecurityContext returns the privileged security context of the builder container, confined by
+// spec.build.securityProfile if set.
+func builderSecurityContext(imageBuild *bibv1alpha1.ImageBuild) *corev1.SecurityContext {
+	privileged := true
+	securityContext := &corev1.SecurityContext{Privileged: &privileged}
+	if imageBuild.Spec.Build == nil || imageBuild.Spec.Build.SecurityProfile == nil {
+		return securityContext
+	}
+	if seccomp := imageBuild.Spec.Build.SecurityProfile.SeccompProfile; seccomp != nil {
+		securityContext.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileType(seccomp.Type)}
+		if seccomp.LocalhostProfile != "" {
+			securityContext.SeccompProfile.LocalhostProfile = &seccomp.LocalhostProfile
+		}
+	}
+	if appArmor := imageBuild.Spec.Build.SecurityProfile.AppArmorProfile; appArmor != nil {
+		securityContext.AppArmorProfile = &corev1.AppArmorProfile{Type: corev1.AppArmorProfileType(appArmor.Type)}
+		if appArmor.LocalhostProfile != "" {
+			securityContext.AppArmorProfile.LocalhostProfile = &appArmor.LocalhostProfile
+		}
+	}
+	return securityContext
+}
+
+// containersStorageVolumeSource returns the volume backing the builder's container storage: an emptyDir,
+// or a generic ephemeral volume if spec.build.workVolume is set.
+func containersStorageVolumeSource(imageBuild *bibv1alpha1.ImageBuild) corev1.VolumeSource {
+	if imageBuild.Spec.Build == nil || imageBuild.Spec.Build.WorkVolume == nil {
+		return corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}
+	}
+	workVolume := imageBuild.Spec.Build.WorkVolume
+	return corev1.VolumeSource{Ephemeral: &corev1.EphemeralVolumeSource{
+		VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				StorageClassName: workVolume.StorageClassName,
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: workVolume.Size},
+				},
+			},
+		},
+	}}
+}
+
+// builderWorkingDir returns the working directory of the builder container: spec.build.workingDir if
+// set, else the repository checkout of a git-based provisioner, so e.g. its ansible.cfg is picked up.
+// It is empty, i.e. the image's default, otherwise.
+func builderWorkingDir(imageBuild *bibv1alpha1.ImageBuild) string {
+	if imageBuild.Spec.Build != nil && imageBuild.Spec.Build.WorkingDir != "" {
+		return imageBuild.Spec.Build.WorkingDir
+	}
+	if imageBuild.Spec.Provisioner != nil && imageBuild.Spec.Provisioner.Ansible != nil {
+		return sourceMountPath
+	}
+	return ""
+}
+
+// builderFSGroup returns the fsGroup of the builder pod, spec.build.fsGroup.
+func builderFSGroup(imageBuild *bibv1alpha1.ImageBuild) *int64 {
+	if imageBuild.Spec.Build == nil || imageBuild.Spec.Build.FSGroup == nil {
+		return nil
+	}
+	fsGroup := *imageBuild.Spec.Build.FSGroup
+	return &fsGroup
+}
+
+// builderSupplementalGroups returns the supplemental groups of the builder pod, spec.build.supplementalGroups.
+func builderSupplementalGroups(imageBuild *bibv1alpha1.ImageBuild) []int64 {
+	if imageBuild.Spec.Build == nil {
+		return nil
+	}
+	return slices.Clone(imageBuild.Spec.Build.SupplementalGroups)
+}
+
+// builderHostUsers returns whether the builder pod runs in the host's user namespace, true unless
+// spec.build.hostUsers opts out.
+func builderHostUsers(imageBuild *bibv1alpha1.ImageBuild) *bool {
+	hostUsers := imageBuild.Spec.Build == nil || imageBuild.Spec.Build.HostUsers == nil || *imageBuild.Spec.Build.HostUsers
+	return &hostUsers
+}
+
+// appArmorAnnotation returns the deprecated AppArmor annotation value matching spec.build.securityProfile,
+// honored by clusters older than Kubernetes 1.30 which ignore the appArmorProfile field.
+func appArmorAnnotation(imageBuild *bibv1alpha1.ImageBuild) (string, bool) {
+	if imageBuild.Spec.Build == nil || imageBuild.Spec.Build.SecurityProfile == nil ||
+		imageBuild.Spec.Build.SecurityProfile.AppArmorProfile == nil {
+		return "", false
+	}
+	appArmor := imageBuild.Spec.Build.SecurityProfile.AppArmorProfile
+	if appArmor.Type == bibv1alpha1.ProfileTypeLocalhost {
+		return corev1.DeprecatedAppArmorBetaProfileNamePrefix + appArmor.LocalhostProfile, true
+	}
+	return corev1.DeprecatedAppArmorBetaProfileRuntimeDefault, true
+}
+
+// gitTokenKey is the key of an Opaque git credentials Secret holding an HTTPS access token.
+const gitTokenKey = "token"
+
+// gitCredentialsVolume returns the volume and read-only mount exposing a git credentials Secret to the builder.
+// The files are only readable by their owner, as ssh refuses keys with looser permissions.
+func gitCredentialsVolume(volumeName, secretName, mountPath string) (corev1.Volume, corev1.VolumeMount) {
+	mode := int32(0400)
+	volume := corev1.Volume{
+		Name: volumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: secretName, DefaultMode: &mode},
+		},
+	}
+	return volume, corev1.VolumeMount{Name: volumeName, MountPath: mountPath, ReadOnly: true}
+}
+
+// gitKnownHostsKey is the key of the spec.build.gitSSH ConfigMap or Secret holding the known hosts.
+const gitKnownHostsKey = "known_hosts"
+
+// gitKnownHostsMountPath is where the spec.build.gitSSH known hosts are mounted in the builder container.
+const gitKnownHostsMountPath = "/etc/git-known-hosts"
+
+// gitSSH returns the variables, volumes and mounts configuring the host key verification of git
+// clones over SSH, if spec.build.gitSSH is set.
+func gitSSH(imageBuild *bibv1alpha1.ImageBuild) ([]corev1.EnvVar, []corev1.Volume, []corev1.VolumeMount) {
+	if imageBuild.Spec.Build == nil || imageBuild.Spec.Build.GitSSH == nil {
+		return nil, nil, nil
+	}
+	config := imageBuild.Spec.Build.GitSSH
+	var source corev1.VolumeSource
+	switch {
+	case config.KnownHostsConfigMapName != "":
+		source.ConfigMap = &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: config.KnownHostsConfigMapName},
+		}
+	case config.KnownHostsSecretName != "":
+		source.Secret = &corev1.SecretVolumeSource{SecretName: config.KnownHostsSecretName}
+	}
+
+	checking := config.HostKeyChecking
+	if checking == "" {
+		checking = bibv1alpha1.GitHostKeyCheckingAcceptNew
+		if source.ConfigMap != nil || source.Secret != nil {
+			checking = bibv1alpha1.GitHostKeyCheckingStrict
+		}
+	}
+	// The values of ssh's StrictHostKeyChecking option.
+	strict := "accept-new"
+	if checking == bibv1alpha1.GitHostKeyCheckingStrict {
+		strict = "yes"
+	}
+	envVars := []corev1.EnvVar{{Name: "GIT_SSH_HOST_KEY_CHECKING", Value: strict}}
+	if source.ConfigMap == nil && source.Secret == nil {
+		return envVars, nil, nil
+	}
+	envVars = append(envVars, corev1.EnvVar{Name: "GIT_SSH_KNOWN_HOSTS", Value: gitKnownHostsMountPath + "/" + gitKnownHostsKey})
+	volume := corev1.Volume{Name: "git-known-hosts", VolumeSource: source}
+	mount := corev1.VolumeMount{Name: "git-known-hosts", MountPath: gitKnownHostsMountPath, ReadOnly: true}
+	return envVars, []corev1.Volume{volume}, []corev1.VolumeMount{mount}
+}
+
+// markCompleted records the completion time of a build that reached a terminal phase.
+func markCompleted(imageBuild *bibv1alpha1.ImageBuild) {
+	if imageBuild.Status.CompletionTime == nil {
+		now := metav1.Now()
+		imageBuild.Status.CompletionTime = &now
+	}
+}
+
+// resetUnrecordedCompletion clears a terminal phase the controller did not record, e.g. one set by
+// editing the status by hand, so the phase is derived from the builder and publisher pods again
+// rather than trusted. Likewise, a completion time left on an unfinished build is cleared, so it is
+// recorded afresh once the build finishes. Other phases are derived from the pods on every reconcile
+// anyway. It returns true if the phase was cleared.
+func resetUnrecordedCompletion(imageBuild *bibv1alpha1.ImageBuild) bool {
+	status := &imageBuild.Status
+	if !isTerminalPhase(status.Phase) {
+		status.CompletionTime = nil
+		return false
+	}
+	if completionRecorded(imageBuild) {
+		return false
+	}
+	status.Phase = ""
+	status.CompletionTime = nil
+	return true
+}
+
+// ignoredSpecChangeMessage returns the message reporting that the spec of imageBuild changed after
+// the build finished, or "" if the build ran with the current spec. Finished builds are not built
+// again, except for those that failed to create their builder pod, which resetPodCreationFailure
+// has already retried by then.
+func ignoredSpecChangeMessage(imageBuild *bibv1alpha1.ImageBuild) string {
+	if !isTerminalPhase(imageBuild.Status.Phase) || imageBuild.Generation <= imageBuild.Status.ObservedGeneration {
+		return ""
+	}
+	return fmt.Sprintf("generation %d of the spec was applied after the build finished with generation %d and is not built; "+
+		"delete and re-create the ImageBuild to build it", imageBuild.Generation, imageBuild.Status.ObservedGeneration)
+}
+
+// ignoredSpecChangeRecorded returns true if the SpecBuilt condition of imageBuild reports the
+// current spec change, if any, of the finished build.
+func ignoredSpecChangeRecorded(imageBuild *bibv1alpha1.ImageBuild) bool {
+	message := ignoredSpecChangeMessage(imageBuild)
+	return message == "" || (conditions.GetReason(imageBuild, bibv1alpha1.SpecBuilt) == bibv1alpha1.SpecChangeIgnoredReason &&
+		conditions.GetMessage(imageBuild, bibv1alpha1.SpecBuilt) == message)
+}
+
+// completionRecorded returns true if the terminal phase of imageBuild is consistent with what the
+// controller records along with it: a completion time no earlier than the start time, and, for a
+// Failed build only, a false condition with Severity=Error saying why.
+func completionRecorded(imageBuild *bibv1alpha1.ImageBuild) bool {
+	status := &imageBuild.Status
+	if status.CompletionTime == nil || (status.StartTime != nil && status.CompletionTime.Before(status.StartTime)) {
+		return false
+	}
+	failed := slices.ContainsFunc(status.Conditions, func(condition clusterv1beta1.Condition) bool {
+		return condition.Status == corev1.ConditionFalse && condition.Severity == clusterv1beta1.ConditionSeverityError
+	})
+	return failed == (status.Phase == bibv1alpha1.PhaseFailed)
+}
+
+// settled returns true if imageBuild has finished and nothing is left to do for it: the controller
+// recorded its completion, added it to the artifact catalog, and its notification, if any, was
+// delivered or given up on. Deleted builds, those still missing the finalizer, builds that failed
+// to create their builder pod and whose spec changed since, and builds whose spec change has not
+// been reported on the SpecBuilt condition yet are not settled.
+func (r *ImageBuildReconciler) settled(imageBuild *bibv1alpha1.ImageBuild) bool {
+	status := &imageBuild.Status
+	if !isTerminalPhase(status.Phase) || !completionRecorded(imageBuild) || status.Summary == "" ||
+		podCreationFailureSpecChanged(imageBuild) || !ignoredSpecChangeRecorded(imageBuild) ||
+		!imageBuild.DeletionTimestamp.IsZero() || !controllerutil.ContainsFinalizer(imageBuild, r.finalizer()) {
+		return false
+	}
+	// Builds that finished before the artifact catalog was introduced are added to it, and a label
+	// or annotation removed or edited since is restored.
+	if !catalogRecorded(imageBuild) {
+		return false
+	}
+	if imageBuild.Spec.Notify == nil {
+		return true
+	}
+	notification := status.Notification
+	return notification != nil && notification.Phase == status.Phase &&
+		(notification.Delivered || notification.Attempts >= maxNotificationAttempts)
+}
+
+// sidecarInjectionOptOutAnnotations disable the service mesh sidecar injection on the pods the
+// operator creates. The pods use RestartPolicy Never, so a sidecar that never exits would keep
+// them from ever completing.
+var sidecarInjectionOptOutAnnotations = map[string]string{
+	"sidecar.istio.io/inject": "false",
+	"linkerd.io/inject":       "disabled",
+}
+
+// podObjectMeta returns the metadata of a pod created for imageBuild: the operator-managed labels,
+// the sidecar injection opt-outs and the labels and annotations from spec.podMetadata.
+func podObjectMeta(imageBuild *bibv1alpha1.ImageBuild, podName, component string) metav1.ObjectMeta {
+	labels := map[string]string{}
+	annotations := map[string]string{}
+	for k, v := range sidecarInjectionOptOutAnnotations {
+		annotations[k] = v
+	}
+	if podMetadata := imageBuild.Spec.PodMetadata; podMetadata != nil {
+		for k, v := range podMetadata.Labels {
+			labels[k] = v
+		}
+		for k, v := range podMetadata.Annotations {
+			annotations[k] = v
+		}
+	}
+	// The operator selects its pods by these labels, so they always win.
+	labels[bibv1alpha1.ImageBuildNameLabel] = imageBuild.Name
+	labels[bibv1alpha1.ComponentLabel] = component
+
+	return metav1.ObjectMeta{
+		Name:        podName,
+		Namespace:   imageBuild.Namespace,
+		Labels:      labels,
+		Annotations: annotations,
+	}
+}
+
 // constructBuilderPod creates the Pod resource definition based on the ImageBuild spec.
-func (r *ImageBuildReconciler) constructBuilderPod(_ context.Context, imageBuild *bibv1alpha1.ImageBuild) (*corev1.Pod, error) {
+func (r *ImageBuildReconciler) constructBuilderPod(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild) (*corev1.Pod, error) {
 	podName := fmt.Sprintf("%s%s", builderPodPrefix, imageBuild.Name)
-	privileged := true
 	runAsUser := int64(0)
+	specBaseImage, err := r.specBaseImage(ctx, imageBuild)
+	if err != nil {
+		return nil, err
+	}
+	baseImage, _ := r.resolveBaseImage(specBaseImage)
 
 	// Initialize slices for env vars and mounts
 	envVars := []corev1.EnvVar{
-		{Name: "BASE_IMAGE", Value: imageBuild.Spec.BaseImage},
+		{Name: "BASE_IMAGE", Value: baseImage},
 		{Name: "ARCHITECTURE", Value: imageBuild.Spec.Architecture},
 	}
 	volumes := []corev1.Volume{
-		{Name: "containers-storage", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+		{Name: "containers-storage", VolumeSource: containersStorageVolumeSource(imageBuild)},
 	}
 	volumeMounts := []corev1.VolumeMount{
-		{Name: "containers-storage", MountPath: "/var/lib/containers/storage"},
+		{Name: "containers-storage", MountPath: containersStorageMountPath},
 	}
 
 	// Check if a pull secret is specified
@@ -183,7 +1026,10 @@ func (r *ImageBuildReconciler) constructBuilderPod(_ context.Context, imageBuild
 				corev1.EnvVar{Name: "ANSIBLE_GIT_REPO", Value: imageBuild.Spec.Provisioner.Ansible.Repo},
 				corev1.EnvVar{Name: "ANSIBLE_GIT_BRANCH", Value: imageBuild.Spec.Provisioner.Ansible.Branch},
 				corev1.EnvVar{Name: "ANSIBLE_PLAYBOOK", Value: imageBuild.Spec.Provisioner.Ansible.Playbook},
+				corev1.EnvVar{Name: "ANSIBLE_MODE", Value: ansibleMode(imageBuild.Spec.Provisioner.Ansible)},
 			)
+			envVars = append(envVars, ansiblePythonEnv("", imageBuild.Spec.Provisioner.Ansible)...)
+			envVars = append(envVars, ansibleConnectionEnv("", imageBuild.Spec.Provisioner.Ansible)...)
 			// Add a volume for the git repo
 			volumes = append(volumes, corev1.Volume{
 				Name:         "source-repo",
@@ -191,97 +1037,381 @@ func (r *ImageBuildReconciler) constructBuilderPod(_ context.Context, imageBuild
 			})
 			volumeMounts = append(volumeMounts, corev1.VolumeMount{
 				Name:      "source-repo",
-				MountPath: "/source",
+				MountPath: sourceMountPath,
 			})
+			if secretName := imageBuild.Spec.Provisioner.Ansible.CredentialsSecretName; secretName != "" {
+				volume, mount := gitCredentialsVolume("git-credentials", secretName, "/etc/git-credentials")
+				volumes = append(volumes, volume)
+				volumeMounts = append(volumeMounts, mount)
+				envVars = append(envVars, corev1.EnvVar{Name: "ANSIBLE_GIT_CREDENTIALS_DIR", Value: mount.MountPath})
+			}
+			submoduleEnv, submoduleVolumes, submoduleMounts := gitSubmodules("", "", imageBuild.Spec.Provisioner.Ansible)
+			envVars = append(envVars, submoduleEnv...)
+			volumes = append(volumes, submoduleVolumes...)
+			volumeMounts = append(volumeMounts, submoduleMounts...)
+		}
+		if dockerfile := imageBuild.Spec.Provisioner.Dockerfile; dockerfile != nil {
+			dockerfileEnv, dockerfileVolumes, dockerfileMounts := dockerfileProvisioner(dockerfile)
+			envVars = append(envVars, dockerfileEnv...)
+			volumes = append(volumes, dockerfileVolumes...)
+			volumeMounts = append(volumeMounts, dockerfileMounts...)
+		}
+		if kickstart := imageBuild.Spec.Provisioner.Kickstart; kickstart != nil {
+			kickstartEnv, kickstartVolumes, kickstartMounts := kickstartProvisioner(kickstart)
+			envVars = append(envVars, kickstartEnv...)
+			volumes = append(volumes, kickstartVolumes...)
+			volumeMounts = append(volumeMounts, kickstartMounts...)
+		}
+		if imageBuild.Spec.Provisioner.Bootc != nil {
+			bootcEnv, bootcVolumes, bootcMounts := bootcProvisioner(imageBuild, specBaseImage)
+			envVars = append(envVars, bootcEnv...)
+			volumes = append(volumes, bootcVolumes...)
+			volumeMounts = append(volumeMounts, bootcMounts...)
 		}
 		if imageBuild.Spec.Provisioner.Packer != nil {
 			// return not implemented error
 			return nil, errors.New("packer provisioner is not implemented yet")
 		}
 	}
+	postStepsAnnotations, postStepsEnv, postStepsVolumes, postStepsMounts := postSteps(imageBuild)
+	envVars = append(envVars, postStepsEnv...)
+	volumes = append(volumes, postStepsVolumes...)
+	volumeMounts = append(volumeMounts, postStepsMounts...)
+
+	// Check if the optional verification step is set
+	if verify := imageBuild.Spec.Verify; verify != nil && verify.Ansible != nil {
+		envVars = append(envVars,
+			corev1.EnvVar{Name: "VERIFY_ANSIBLE_GIT_REPO", Value: verify.Ansible.Repo},
+			corev1.EnvVar{Name: "VERIFY_ANSIBLE_GIT_BRANCH", Value: verify.Ansible.Branch},
+			corev1.EnvVar{Name: "VERIFY_ANSIBLE_PLAYBOOK", Value: verify.Ansible.Playbook},
+			corev1.EnvVar{Name: "VERIFY_ANSIBLE_MODE", Value: ansibleMode(verify.Ansible)},
+		)
+		envVars = append(envVars, ansiblePythonEnv("VERIFY_", verify.Ansible)...)
+		envVars = append(envVars, ansibleConnectionEnv("VERIFY_", verify.Ansible)...)
+		volumes = append(volumes, corev1.Volume{
+			Name:         "verify-repo",
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      "verify-repo",
+			MountPath: "/verify-source",
+		})
+		if secretName := verify.Ansible.CredentialsSecretName; secretName != "" {
+			volume, mount := gitCredentialsVolume("verify-git-credentials", secretName, "/etc/verify-git-credentials")
+			volumes = append(volumes, volume)
+			volumeMounts = append(volumeMounts, mount)
+			envVars = append(envVars, corev1.EnvVar{Name: "VERIFY_ANSIBLE_GIT_CREDENTIALS_DIR", Value: mount.MountPath})
+		}
+		submoduleEnv, submoduleVolumes, submoduleMounts := gitSubmodules("VERIFY_", "verify-", verify.Ansible)
+		envVars = append(envVars, submoduleEnv...)
+		volumes = append(volumes, submoduleVolumes...)
+		volumeMounts = append(volumeMounts, submoduleMounts...)
+	}
+
+	gitSSHEnv, gitSSHVolumes, gitSSHMounts := gitSSH(imageBuild)
+	envVars = append(envVars, gitSSHEnv...)
+	volumes = append(volumes, gitSSHVolumes...)
+	volumeMounts = append(volumeMounts, gitSSHMounts...)
+
+	envVars = append(envVars, verbosityEnv(imageBuild)...)
+	envVars = append(envVars, artifactMetadataEnv(imageBuild, time.Now())...)
+	// The pod is the next build attempt.
+	envVars = append(envVars, corev1.EnvVar{Name: "TRACEPARENT", Value: tracing.Traceparent(imageBuild.UID,
+		attemptSpanKey(string(bibv1alpha1.PhaseBuilding), imageBuild.Status.BuildAttempts+1))})
+	envVars = append(envVars, buildLogsEnv(imageBuild)...)
+	envVars = append(envVars, corev1.EnvVar{Name: "OUTPUT_FORMATS", Value: outputFormats(imageBuild)})
+	if qcow2 := imageBuild.Spec.Output.QCOW2; qcow2 != nil && qcow2.Compress && outputFormats(imageBuild) != "" {
+		envVars = append(envVars, corev1.EnvVar{Name: "QCOW2_COMPRESS", Value: "true"})
+	}
+	if qcow2 := imageBuild.Spec.Output.QCOW2; qcow2 != nil && qcow2.Preallocation != "" && outputFormats(imageBuild) != "" {
+		envVars = append(envVars, corev1.EnvVar{Name: "QCOW2_PREALLOCATION", Value: string(qcow2.Preallocation)})
+	}
+	if layout := imageBuild.Spec.Output.DiskLayout; layout != nil && outputFormats(imageBuild) != "" {
+		envVars = append(envVars, corev1.EnvVar{Name: "DISK_LAYOUT", Value: diskLayoutConfig(layout)})
+	} else if outputFormats(imageBuild) != "" {
+		table, efi := diskPartitioning(imageBuild)
+		envVars = append(envVars,
+			corev1.EnvVar{Name: "OUTPUT_FILESYSTEM", Value: string(outputFilesystem(imageBuild))},
+			corev1.EnvVar{Name: "DISK_PARTITION_TABLE", Value: table},
+			corev1.EnvVar{Name: "DISK_EFI_PARTITION", Value: strconv.FormatBool(efi)},
+		)
+	}
+
+	envVars = append(envVars, sanitizeEnv(imageBuild)...)
+	envVars = append(envVars, corev1.EnvVar{Name: "OUTPUT_FILENAME", Value: imageBuild.Spec.Output.ImageName})
+	if imageBuild.Spec.Output.ParallelUploads {
+		envVars = append(envVars, corev1.EnvVar{Name: "OUTPUT_PARALLEL_UPLOADS", Value: "true"})
+	}
 
 	// Check if the optional PVC output field is set
-	if imageBuild.Spec.Output.PVC != nil {
-		envVars = append(envVars, corev1.EnvVar{Name: "OUTPUT_FILENAME", Value: imageBuild.Spec.Output.ImageName})
+	if imageBuild.Spec.Output.PVC == nil && imageBuild.Spec.Output.Volume == nil && imageBuild.Spec.Output.ObjectStorage != nil {
+		// Object storage uploads still need scratch space for the artifacts.
+		volumes = append(volumes, corev1.Volume{
+			Name:         "output",
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: "output", MountPath: "/output"})
+	} else if imageBuild.Spec.Output.PVC != nil {
 		volumes = append(volumes, corev1.Volume{
 			Name: "output-pvc",
 			VolumeSource: corev1.VolumeSource{
 				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-					ClaimName: imageBuild.Spec.Output.PVC.Name,
+					ClaimName: outputPVCName(imageBuild),
 				},
 			},
 		})
 		volumeMounts = append(volumeMounts, corev1.VolumeMount{
 			Name:      "output-pvc",
 			MountPath: "/output",
+			SubPath:   outputPVCSubPath(imageBuild),
+		})
+		// Pruning older builds needs the whole claim, not just the directory of this build.
+		if retain := imageBuild.Spec.Output.PVC.Retain; retain != nil {
+			volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: "output-pvc", MountPath: "/output-root"})
+			envVars = append(envVars, corev1.EnvVar{Name: "OUTPUT_RETAIN", Value: strconv.Itoa(int(*retain))})
+		}
+	} else if volume := imageBuild.Spec.Output.Volume; volume != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: "output-volume",
+			VolumeSource: corev1.VolumeSource{
+				NFS: volume.NFS,
+				CSI: volume.CSI,
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      "output-volume",
+			MountPath: "/output",
+			SubPath:   outputVolumeSubPath(imageBuild),
+		})
+	}
+
+	// Check if the optional object storage output field is set
+	if imageBuild.Spec.Output.ObjectStorage != nil {
+		envVars = append(envVars, objectStorageEnv("OUTPUT_", objectStorageOutputURL(imageBuild), imageBuild.Spec.Output.ObjectStorage)...)
+	}
+
+	// Check if the optional registry output field is set
+	if registry := imageBuild.Spec.Output.Registry; registry != nil {
+		envVars = append(envVars,
+			corev1.EnvVar{Name: "REGISTRY_DESTINATION", Value: registry.Destination},
+			corev1.EnvVar{Name: "REGISTRY_COMPRESSION_FORMAT", Value: registryCompressionFormat(registry)},
+		)
+		if registry.Config != nil {
+			envVars = append(envVars, corev1.EnvVar{Name: "REGISTRY_IMAGE_CONFIG", Value: registryImageConfig(registry.Config)})
+		}
+		envVars = append(envVars, registryRepositoryEnv(registry)...)
+		volumes = append(volumes, corev1.Volume{
+			Name: "registry-auth",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: registry.PullSecretName},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      "registry-auth",
+			MountPath: "/etc/registry-auth",
+			ReadOnly:  true,
+		})
+
+		// Mount the credentials of additional destinations that do not share the output's secret.
+		var destinations []string
+		for i, destination := range registry.AdditionalDestinations {
+			authDir := "/etc/registry-auth"
+			if destination.PullSecretName != "" && destination.PullSecretName != registry.PullSecretName {
+				name := fmt.Sprintf("registry-auth-%d", i)
+				authDir = "/etc/" + name
+				volumes = append(volumes, corev1.Volume{
+					Name: name,
+					VolumeSource: corev1.VolumeSource{
+						Secret: &corev1.SecretVolumeSource{SecretName: destination.PullSecretName},
+					},
+				})
+				volumeMounts = append(volumeMounts, corev1.VolumeMount{
+					Name:      name,
+					MountPath: authDir,
+					ReadOnly:  true,
+				})
+			}
+			destinations = append(destinations, destination.Destination+" "+authDir+"/.dockerconfigjson")
+		}
+		if len(destinations) > 0 {
+			envVars = append(envVars, corev1.EnvVar{Name: "REGISTRY_ADDITIONAL_DESTINATIONS", Value: strings.Join(destinations, "\n")})
+		}
+	}
+
+	// Sign the pushed image with the pod's identity, if requested.
+	if signing := imageBuild.Spec.Signing; signing != nil && signing.Keyless != nil && imageBuild.Spec.Output.Registry != nil {
+		envVars = append(envVars, keylessSigningEnv(signing.Keyless)...)
+		volumes = append(volumes, keylessSigningVolume(signing.Keyless))
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      "sigstore-token",
+			MountPath: signingTokenMountPath,
+			ReadOnly:  true,
+		})
+	}
+
+	// Mount the build cache shared with other ImageBuilds, if any.
+	if imageBuild.Spec.Build != nil && imageBuild.Spec.Build.Cache != nil {
+		envVars = append(envVars, corev1.EnvVar{Name: "BUILD_CACHE_DIR", Value: buildCacheMountPath})
+		volumes = append(volumes, corev1.Volume{
+			Name: "build-cache",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: imageBuild.Spec.Build.Cache.PVCName,
+				},
+			},
 		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      "build-cache",
+			MountPath: buildCacheMountPath,
+		})
+	}
+
+	// Hand the package mirrors for the playbooks to the builder, if any.
+	if build := imageBuild.Spec.Build; build != nil && build.PackageMirrors != nil {
+		mirrors := build.PackageMirrors
+		if mirrors.Proxy != "" {
+			envVars = append(envVars, corev1.EnvVar{Name: "PACKAGE_PROXY", Value: mirrors.Proxy})
+		}
+		if name := mirrors.ConfigMapName; name != "" {
+			envVars = append(envVars, corev1.EnvVar{Name: "PACKAGE_MIRRORS_DIR", Value: packageMirrorsMountPath})
+			volumes = append(volumes, corev1.Volume{
+				Name: "package-mirrors",
+				VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: name}},
+				},
+			})
+			volumeMounts = append(volumeMounts, corev1.VolumeMount{
+				Name:      "package-mirrors",
+				MountPath: packageMirrorsMountPath,
+				ReadOnly:  true,
+			})
+		}
 	}
 
+	secretFileVolumes, secretFileMounts := builderSecretFiles(imageBuild)
+	volumes = append(volumes, secretFileVolumes...)
+	volumeMounts = append(volumeMounts, secretFileMounts...)
+
+	// Pass through the user-provided variables the operator does not manage itself.
+	envVars = mergeBuilderEnv(envVars, imageBuild.Spec.BuilderEnv)
+
 	// Create a nodeSelector map based on the requested architecture.
 	nodeSelector := make(map[string]string)
-	if imageBuild.Spec.Architecture != "" {
-		nodeSelector["kubernetes.io/arch"] = imageBuild.Spec.Architecture
+	if nodeArch, ok := builderNodeArch(imageBuild); ok {
+		nodeSelector[nodeArchLabel] = nodeArch
 	}
 
 	pod := &corev1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      podName,
-			Namespace: imageBuild.Namespace,
-		},
+		ObjectMeta: podObjectMeta(imageBuild, podName, bibv1alpha1.ComponentBuilder),
 		Spec: corev1.PodSpec{
-			NodeSelector:  nodeSelector,
-			RestartPolicy: corev1.RestartPolicyNever,
+			NodeSelector:          nodeSelector,
+			Affinity:              r.prePullAffinity(),
+			RestartPolicy:         corev1.RestartPolicyNever,
+			ActiveDeadlineSeconds: builderActiveDeadlineSeconds(imageBuild),
+			InitContainers:        r.diskSpaceCheckContainers(imageBuild),
 			SecurityContext: &corev1.PodSecurityContext{
-				RunAsUser: &runAsUser,
+				RunAsUser:          &runAsUser,
+				FSGroup:            builderFSGroup(imageBuild),
+				SupplementalGroups: builderSupplementalGroups(imageBuild),
 			},
 			Containers: []corev1.Container{
 				{
-					Name:  "builder",
-					Image: r.BuilderImage,
-					SecurityContext: &corev1.SecurityContext{
-						Privileged: &privileged,
-					},
-					Env:          envVars,
-					VolumeMounts: volumeMounts,
+					Name:            builderContainerName,
+					Image:           r.BuilderImage,
+					WorkingDir:      builderWorkingDir(imageBuild),
+					SecurityContext: builderSecurityContext(imageBuild),
+					Env:             envVars,
+					EnvFrom:         builderEnvFrom(imageBuild),
+					VolumeMounts:    volumeMounts,
+					Lifecycle:       builderLifecycle(imageBuild),
 				},
 			},
 			Volumes: volumes,
 		},
 	}
+	if r.BuildNamespaces {
+		pod.Namespace = r.builderPodNamespace(imageBuild)
+		pod.Labels[bibv1alpha1.ImageBuildNamespaceLabel] = imageBuild.Namespace
+	}
+	for key, value := range postStepsAnnotations {
+		pod.Annotations[key] = value
+	}
+	if annotation, ok := appArmorAnnotation(imageBuild); ok {
+		pod.Annotations[corev1.DeprecatedAppArmorBetaContainerAnnotationKeyPrefix+builderContainerName] = annotation
+	}
+	if imageBuild.Spec.Build != nil && imageBuild.Spec.Build.HostNetwork {
+		pod.Spec.HostNetwork = true
+		// Keep resolving cluster services from the host network.
+		pod.Spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
+	}
+	pod.Spec.HostUsers = builderHostUsers(imageBuild)
+	if imageBuild.Spec.Build != nil && imageBuild.Spec.Build.ShareProcessNamespace {
+		shareProcessNamespace := true
+		pod.Spec.ShareProcessNamespace = &shareProcessNamespace
+	}
+	initContainers, err := userInitContainers(imageBuild, pod)
+	if err != nil {
+		return nil, err
+	}
+	pod.Spec.InitContainers = append(pod.Spec.InitContainers, initContainers...)
 	return pod, nil
 }
 
 // cleanupBuilderPod deletes the builder Pod resource if it exists.
 func (r *ImageBuildReconciler) cleanupBuilderPod(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild) error {
 	podName := fmt.Sprintf("%s%s", builderPodPrefix, imageBuild.Name)
-	err := r.Delete(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: imageBuild.Namespace}})
+	err := r.Delete(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: r.builderPodNamespace(imageBuild)}})
 	if err != nil && !apierrors.IsNotFound(err) {
 		return err
 	}
 	return nil
 }
 
+// finalizer returns the finalizer the controller adds to ImageBuilds.
+func (r *ImageBuildReconciler) finalizer() string {
+	if r.Finalizer != "" {
+		return r.Finalizer
+	}
+	return bibv1alpha1.ImageBuildFinalizer
+}
+
 func (r *ImageBuildReconciler) reconcileDelete(ctx context.Context, ibs *scope.ImageBuildScope) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	imageBuild := ibs.ImageBuild
 
 	// redundant check the deletion timestamp
 	if !imageBuild.ObjectMeta.DeletionTimestamp.IsZero() {
-		if controllerutil.ContainsFinalizer(imageBuild, bibv1alpha1.ImageBuildFinalizer) {
-			logger.Info("Performing cleanup for ImageBuild resource")
+		if !controllerutil.ContainsFinalizer(imageBuild, r.finalizer()) {
+			// Cleaned up already; the finalizers of other controllers keep the object until they are done.
+			logger.V(1).Info("Waiting for other finalizers", "Finalizers", imageBuild.Finalizers)
+			return ctrl.Result{}, nil
+		}
+		logger.Info("Performing cleanup for ImageBuild resource")
 
-			// cleanup logic (e.g., delete the builder pod if it's running)
-			err := r.cleanupBuilderPod(ctx, imageBuild)
-			if err != nil {
-				logger.Error(err, "Failed to cleanup builder pod")
-				// TODO: Update status to Failed
-				return ctrl.Result{}, err
-			}
+		// cleanup logic (e.g., delete the builder pod if it's running)
+		err := r.cleanupBuilderPod(ctx, imageBuild)
+		if err != nil {
+			logger.Error(err, "Failed to cleanup builder pod")
+			// TODO: Update status to Failed
+			return ctrl.Result{}, err
+		}
+		if err := r.cleanupPublisherPod(ctx, imageBuild); err != nil {
+			logger.Error(err, "Failed to cleanup publisher pod")
+			return ctrl.Result{}, err
+		}
+		if err := r.cleanupBuildNamespace(ctx, imageBuild); err != nil {
+			logger.Error(err, "Failed to cleanup build namespace")
+			return ctrl.Result{}, err
+		}
 
-			controllerutil.RemoveFinalizer(imageBuild, bibv1alpha1.ImageBuildFinalizer)
-			if err := r.Update(ctx, imageBuild); err != nil {
-				return ctrl.Result{}, err
-			}
+		// Only remove our own finalizer. The update fails on a conflict rather than dropping
+		// finalizers added since the object was read.
+		controllerutil.RemoveFinalizer(imageBuild, r.finalizer())
+		if err := r.Update(ctx, imageBuild); err != nil {
+			return ctrl.Result{}, err
+		}
+		if len(imageBuild.Finalizers) > 0 {
+			logger.Info("Cleaned up, waiting for other finalizers", "Finalizers", imageBuild.Finalizers)
 		}
 		return ctrl.Result{}, nil
 	}
@@ -290,9 +1420,19 @@ func (r *ImageBuildReconciler) reconcileDelete(ctx context.Context, ibs *scope.I
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ImageBuildReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &bibv1alpha1.ImageBuild{}, baseImageFromField,
+		indexBaseImageFrom); err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&bibv1alpha1.ImageBuild{}).
 		Owns(&corev1.Pod{}). // watch Pods created by ImageBuild resources
+		// Pods in build namespaces are not owned by their ImageBuild, but labeled with it.
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(buildNamespacePodImageBuild)).
+		// Builds waiting for their spec.baseImageFrom start once it succeeds.
+		Watches(&bibv1alpha1.ImageBuild{}, handler.EnqueueRequestsFromMapFunc(r.dependentImageBuilds)).
+		// Builds waiting for a node of their architecture start once one becomes schedulable.
+		Watches(&corev1.Node{}, handler.EnqueueRequestsFromMapFunc(r.waitingImageBuilds), builder.WithPredicates(nodeSchedulable)).
 		Named("imagebuild").
 		Complete(r)
 }
@@ -0,0 +1,91 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+// diskSpaceCheckContainerName is the name of the init container checking the free space of the
+// builder's container storage.
+const diskSpaceCheckContainerName = "disk-space-check"
+
+// containersStorageMountPath is where the builder's container storage is mounted.
+const containersStorageMountPath = "/var/lib/containers/storage"
+
+// diskSpaceCheckScript fails with a message in the termination log if the container storage has
+// less than MIN_FREE_DISK_SPACE_BYTES available.
+const diskSpaceCheckScript = `set -e
+available=$(df -Pk "${STORAGE_DIR}" | awk 'NR == 2 { print $4 }')
+required=$(( (MIN_FREE_DISK_SPACE_BYTES + 1023) / 1024 ))
+if [ "${available}" -lt "${required}" ]; then
+    echo "only $(( available / 1024 ))Mi free in the container storage on node ${NODE_NAME}, $(( required / 1024 ))Mi required" | tee /dev/termination-log
+    exit 1
+fi
+echo "${available}Ki free in the container storage, ${required}Ki required"
+`
+
+// minFreeDiskSpace returns the free space the build requires in its container storage:
+// spec.build.minFreeDiskSpace if set, else the controller default.
+func (r *ImageBuildReconciler) minFreeDiskSpace(imageBuild *bibv1alpha1.ImageBuild) resource.Quantity {
+	if imageBuild.Spec.Build != nil && imageBuild.Spec.Build.MinFreeDiskSpace != nil {
+		return *imageBuild.Spec.Build.MinFreeDiskSpace
+	}
+	return r.MinFreeDiskSpace
+}
+
+// diskSpaceCheckContainers returns the init container checking the free space of the container
+// storage before the build starts, or nil if the build requires none.
+func (r *ImageBuildReconciler) diskSpaceCheckContainers(imageBuild *bibv1alpha1.ImageBuild) []corev1.Container {
+	minimum := r.minFreeDiskSpace(imageBuild)
+	if minimum.Sign() <= 0 {
+		return nil
+	}
+	return []corev1.Container{{
+		Name:    diskSpaceCheckContainerName,
+		Image:   r.BuilderImage,
+		Command: []string{"/bin/sh", "-c", diskSpaceCheckScript},
+		Env: []corev1.EnvVar{
+			{Name: "STORAGE_DIR", Value: containersStorageMountPath},
+			{Name: "MIN_FREE_DISK_SPACE_BYTES", Value: strconv.FormatInt(minimum.Value(), 10)},
+			{Name: "NODE_NAME", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"}}},
+		},
+		VolumeMounts: []corev1.VolumeMount{{Name: "containers-storage", MountPath: containersStorageMountPath}},
+	}}
+}
+
+// diskSpaceCheckFailure returns the message of a disk space check that failed in pod.
+func diskSpaceCheckFailure(pod *corev1.Pod) (string, bool) {
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.Name != diskSpaceCheckContainerName {
+			continue
+		}
+		if terminated := status.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+			if terminated.Message == "" {
+				return "the free space of the container storage could not be checked", true
+			}
+			return strings.TrimSpace(terminated.Message), true
+		}
+	}
+	return "", false
+}
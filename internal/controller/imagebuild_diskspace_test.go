@@ -0,0 +1,108 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("ImageBuild disk space check", func() {
+	ctx := context.Background()
+
+	It("does not check the disk space by default", func() {
+		ib := newTestImageBuild("unchecked")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.InitContainers).To(BeEmpty())
+	})
+
+	It("checks the controller's minimum in the container storage before the build", func() {
+		ib := newTestImageBuild("checked")
+		r := newFakeReconciler(ib)
+		r.MinFreeDiskSpace = resource.MustParse("20Gi")
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.InitContainers).To(HaveLen(1))
+		check := pod.Spec.InitContainers[0]
+		Expect(check.Name).To(Equal("disk-space-check"))
+		Expect(check.Image).To(Equal("builder:test"))
+		Expect(check.Env).To(ContainElement(corev1.EnvVar{Name: "MIN_FREE_DISK_SPACE_BYTES", Value: "21474836480"}))
+		Expect(check.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "containers-storage", MountPath: "/var/lib/containers/storage"}))
+	})
+
+	It("uses spec.build.minFreeDiskSpace over the controller's minimum", func() {
+		ib := newTestImageBuild("overridden")
+		minimum := resource.MustParse("100Gi")
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{MinFreeDiskSpace: &minimum}
+		r := newFakeReconciler(ib)
+		r.MinFreeDiskSpace = resource.MustParse("20Gi")
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.InitContainers).To(HaveLen(1))
+		Expect(pod.Spec.InitContainers[0].Env).To(ContainElement(corev1.EnvVar{Name: "MIN_FREE_DISK_SPACE_BYTES", Value: "107374182400"}))
+
+		By("disabling the check with a zero minimum")
+		minimum = resource.MustParse("0")
+		pod, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.InitContainers).To(BeEmpty())
+	})
+
+	It("fails the build on a node short of disk space", func() {
+		ib := newTestImageBuild("short")
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: builderPodPrefix + "short", Namespace: "default"},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodFailed,
+				InitContainerStatuses: []corev1.ContainerStatus{{
+					Name: "disk-space-check",
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+						ExitCode: 1,
+						Message:  "only 5120Mi free in the container storage on node worker-1, 20480Mi required\n",
+					}},
+				}},
+			},
+		}
+		r := newFakeReconciler(ib, pod)
+
+		key := types.NamespacedName{Name: "short", Namespace: "default"}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, updated)).To(Succeed())
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+		Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.InsufficientDiskSpaceReason))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.BuilderPodReady)).To(Equal(
+			"builder pod imgbldr-short did not start the build: only 5120Mi free in the container storage on node worker-1, 20480Mi required"))
+	})
+})
@@ -0,0 +1,70 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+// defaultDockerfilePath is the Containerfile built when spec.provisioner.dockerfile.path is unset.
+const defaultDockerfilePath = "Containerfile"
+
+// dockerfileProvisioner returns the variables, volumes and mounts of a Dockerfile provisioner. Its
+// build context in sourceMountPath is either an emptyDir the builder clones the repository into, or
+// the ConfigMap mounted read-only.
+func dockerfileProvisioner(dockerfile *bibv1alpha1.DockerfileSpec) ([]corev1.EnvVar, []corev1.Volume, []corev1.VolumeMount) {
+	path := dockerfile.Path
+	if path == "" {
+		path = defaultDockerfilePath
+	}
+	envVars := []corev1.EnvVar{{Name: "DOCKERFILE_PATH", Value: path}}
+	mount := corev1.VolumeMount{Name: "source-repo", MountPath: sourceMountPath}
+
+	if dockerfile.ConfigMapRef != nil {
+		mount.ReadOnly = true
+		volume := corev1.Volume{
+			Name: "source-repo",
+			VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: *dockerfile.ConfigMapRef,
+			}},
+		}
+		return envVars, []corev1.Volume{volume}, []corev1.VolumeMount{mount}
+	}
+
+	branch := dockerfile.Branch
+	if branch == "" {
+		branch = "main"
+	}
+	envVars = append(envVars,
+		corev1.EnvVar{Name: "DOCKERFILE_GIT_REPO", Value: dockerfile.Repo},
+		corev1.EnvVar{Name: "DOCKERFILE_GIT_BRANCH", Value: branch},
+	)
+	volumes := []corev1.Volume{{
+		Name:         "source-repo",
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}}
+	mounts := []corev1.VolumeMount{mount}
+	if dockerfile.CredentialsSecretName != "" {
+		volume, credentialsMount := gitCredentialsVolume("git-credentials", dockerfile.CredentialsSecretName, "/etc/git-credentials")
+		volumes = append(volumes, volume)
+		mounts = append(mounts, credentialsMount)
+		envVars = append(envVars, corev1.EnvVar{Name: "DOCKERFILE_GIT_CREDENTIALS_DIR", Value: credentialsMount.MountPath})
+	}
+	return envVars, volumes, mounts
+}
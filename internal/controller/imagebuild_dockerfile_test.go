@@ -0,0 +1,101 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("ImageBuild Dockerfile provisioner", func() {
+	ctx := context.Background()
+
+	It("mounts the ConfigMap read-only as the build context", func() {
+		ib := newTestImageBuild("from-configmap")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Dockerfile: &bibv1alpha1.DockerfileSpec{
+			ConfigMapRef: &corev1.LocalObjectReference{Name: "hardening"},
+			Path:         "Dockerfile",
+		}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Volumes).To(ContainElement(corev1.Volume{
+			Name: "source-repo",
+			VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "hardening"},
+			}},
+		}))
+		builder := pod.Spec.Containers[0]
+		Expect(builder.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "source-repo", MountPath: "/source", ReadOnly: true}))
+		Expect(builder.Env).To(ContainElement(corev1.EnvVar{Name: "DOCKERFILE_PATH", Value: "Dockerfile"}))
+		Expect(builder.Env).NotTo(ContainElement(HaveField("Name", "DOCKERFILE_GIT_REPO")))
+	})
+
+	It("clones the repository into the build context", func() {
+		ib := newTestImageBuild("from-git")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Dockerfile: &bibv1alpha1.DockerfileSpec{
+			Repo:                  "https://github.com/example/images.git",
+			CredentialsSecretName: "git-token",
+		}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Volumes).To(ContainElement(corev1.Volume{
+			Name:         "source-repo",
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		}))
+		builder := pod.Spec.Containers[0]
+		Expect(builder.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "source-repo", MountPath: "/source"}))
+		Expect(builder.Env).To(ContainElements(
+			corev1.EnvVar{Name: "DOCKERFILE_PATH", Value: "Containerfile"},
+			corev1.EnvVar{Name: "DOCKERFILE_GIT_REPO", Value: "https://github.com/example/images.git"},
+			corev1.EnvVar{Name: "DOCKERFILE_GIT_BRANCH", Value: "main"},
+			corev1.EnvVar{Name: "DOCKERFILE_GIT_CREDENTIALS_DIR", Value: "/etc/git-credentials"},
+		))
+	})
+
+	It("requires the referenced ConfigMap", func() {
+		ib := newTestImageBuild("missing-configmap")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Dockerfile: &bibv1alpha1.DockerfileSpec{
+			ConfigMapRef: &corev1.LocalObjectReference{Name: "hardening"},
+		}}
+		r := newFakeReconciler(ib)
+
+		ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.ProvisionerReady)).To(Equal(bibv1alpha1.ConfigMapNotFoundReason))
+		Expect(conditions.GetMessage(ib, bibv1alpha1.ProvisionerReady)).To(ContainSubstring("spec.provisioner.dockerfile.configMapRef"))
+
+		By("passing once it exists")
+		hardening := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "hardening", Namespace: "default"}}
+		r = newFakeReconciler(ib, hardening)
+		ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(buildSummary(ib)).To(HavePrefix("dockerfile→"))
+	})
+})
@@ -0,0 +1,51 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// builderImagePullGracePeriod is how long a builder pod may fail to pull the builder image, e.g.
+// while a registry recovers from an outage, before BuilderPodReady reports the pull as failed.
+var builderImagePullGracePeriod = 5 * time.Minute
+
+// imagePullWaitingReasons are the waiting reasons of a container whose image cannot be pulled.
+var imagePullWaitingReasons = map[string]bool{
+	"ErrImagePull":     true,
+	"ImagePullBackOff": true,
+}
+
+// builderImagePullFailure returns the message of the builder image failing to pull for the builder
+// container, or for the disk space check init container running the same image.
+func builderImagePullFailure(pod *corev1.Pod) (string, bool) {
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if status.Name != builderContainerName && status.Name != diskSpaceCheckContainerName {
+			continue
+		}
+		if waiting := status.State.Waiting; waiting != nil && imagePullWaitingReasons[waiting.Reason] {
+			if waiting.Message == "" {
+				return waiting.Reason, true
+			}
+			return waiting.Reason + ": " + waiting.Message, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+// userInitContainers returns spec.build.initContainers, to run after the init containers the operator
+// added to pod. They may only reference the volumes of pod, and their names must not clash with the
+// containers of the operator.
+func userInitContainers(imageBuild *bibv1alpha1.ImageBuild, pod *corev1.Pod) ([]corev1.Container, error) {
+	if imageBuild.Spec.Build == nil || len(imageBuild.Spec.Build.InitContainers) == 0 {
+		return nil, nil
+	}
+	var volumes []string
+	for _, volume := range pod.Spec.Volumes {
+		volumes = append(volumes, volume.Name)
+	}
+	var reserved []string
+	for _, container := range slices.Concat(pod.Spec.InitContainers, pod.Spec.Containers) {
+		reserved = append(reserved, container.Name)
+	}
+
+	var containers []corev1.Container
+	for i, container := range imageBuild.Spec.Build.InitContainers {
+		fldPath := fmt.Sprintf("spec.build.initContainers[%d]", i)
+		if slices.Contains(reserved, container.Name) {
+			return nil, fmt.Errorf("%s: container name %q is used by the operator", fldPath, container.Name)
+		}
+		for _, mount := range container.VolumeMounts {
+			if !slices.Contains(volumes, mount.Name) {
+				return nil, fmt.Errorf("%s: volume mount %q does not name a volume of the builder pod (%s)",
+					fldPath, mount.Name, strings.Join(volumes, ", "))
+			}
+		}
+		for _, device := range container.VolumeDevices {
+			if !slices.Contains(volumes, device.Name) {
+				return nil, fmt.Errorf("%s: volume device %q does not name a volume of the builder pod (%s)",
+					fldPath, device.Name, strings.Join(volumes, ", "))
+			}
+		}
+		containers = append(containers, *container.DeepCopy())
+	}
+	return containers, nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("ImageBuild init containers", func() {
+	ctx := context.Background()
+
+	prefetch := corev1.Container{
+		Name:         "prefetch",
+		Image:        "curlimages/curl:8.10.1",
+		Command:      []string{"curl", "-fsSLo", "/output/assets.tgz", "https://assets.example.com/assets.tgz"},
+		VolumeMounts: []corev1.VolumeMount{{Name: "output-pvc", MountPath: "/output"}},
+	}
+
+	It("runs spec.build.initContainers after the operator's init containers", func() {
+		ib := newTestImageBuild("init")
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{InitContainers: []corev1.Container{
+			prefetch,
+			{Name: "unpack", Image: "busybox:1.36", Command: []string{"tar", "-xzf", "/output/assets.tgz", "-C", "/output"}},
+		}}
+		r := newFakeReconciler(ib)
+		r.MinFreeDiskSpace = resource.MustParse("20Gi")
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.InitContainers).To(HaveLen(3))
+		Expect(pod.Spec.InitContainers[0].Name).To(Equal(diskSpaceCheckContainerName))
+		Expect(pod.Spec.InitContainers[1]).To(Equal(prefetch))
+		Expect(pod.Spec.InitContainers[2].Name).To(Equal("unpack"))
+
+		By("not sharing the containers with the spec")
+		pod.Spec.InitContainers[1].Command[0] = "wget"
+		Expect(ib.Spec.Build.InitContainers[0].Command[0]).To(Equal("curl"))
+	})
+
+	It("restricts the volume references to the volumes of the builder pod", func() {
+		ib := newTestImageBuild("init-volume")
+		undeclared := *prefetch.DeepCopy()
+		undeclared.VolumeMounts = []corev1.VolumeMount{{Name: "assets", MountPath: "/assets"}}
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{InitContainers: []corev1.Container{undeclared}}
+		r := newFakeReconciler(ib)
+
+		_, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).To(MatchError(ContainSubstring(`spec.build.initContainers[0]: volume mount "assets" does not name a volume of the builder pod`)))
+
+		By("rejecting block devices of undeclared volumes")
+		undeclared.VolumeMounts = nil
+		undeclared.VolumeDevices = []corev1.VolumeDevice{{Name: "scratch", DevicePath: "/dev/xvdb"}}
+		ib.Spec.Build.InitContainers = []corev1.Container{undeclared}
+		_, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).To(MatchError(ContainSubstring(`volume device "scratch" does not name a volume of the builder pod`)))
+	})
+
+	It("rejects names used by the operator's containers", func() {
+		ib := newTestImageBuild("init-name")
+		builder := *prefetch.DeepCopy()
+		builder.Name = builderContainerName
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{InitContainers: []corev1.Container{builder}}
+		r := newFakeReconciler(ib)
+
+		_, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).To(MatchError(ContainSubstring(`container name "builder" is used by the operator`)))
+	})
+})
@@ -0,0 +1,75 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+// defaultKickstartPath is the kickstart file applied when spec.provisioner.kickstart.path is unset.
+const defaultKickstartPath = "ks.cfg"
+
+// kickstartProvisioner returns the variables, volumes and mounts of a kickstart provisioner. The
+// kickstart file is in sourceMountPath, either an emptyDir the builder clones the repository into or
+// writes the inline kickstart to, or the ConfigMap mounted read-only.
+func kickstartProvisioner(kickstart *bibv1alpha1.KickstartSpec) ([]corev1.EnvVar, []corev1.Volume, []corev1.VolumeMount) {
+	path := kickstart.Path
+	if path == "" || kickstart.Inline != "" {
+		path = defaultKickstartPath
+	}
+	envVars := []corev1.EnvVar{{Name: "KICKSTART_PATH", Value: path}}
+	mount := corev1.VolumeMount{Name: "source-repo", MountPath: sourceMountPath}
+
+	if kickstart.ConfigMapRef != nil {
+		mount.ReadOnly = true
+		volume := corev1.Volume{
+			Name: "source-repo",
+			VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: *kickstart.ConfigMapRef,
+			}},
+		}
+		return envVars, []corev1.Volume{volume}, []corev1.VolumeMount{mount}
+	}
+
+	volumes := []corev1.Volume{{
+		Name:         "source-repo",
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}}
+	mounts := []corev1.VolumeMount{mount}
+	if kickstart.Inline != "" {
+		envVars = append(envVars, corev1.EnvVar{Name: "KICKSTART_INLINE", Value: kickstart.Inline})
+		return envVars, volumes, mounts
+	}
+
+	branch := kickstart.Branch
+	if branch == "" {
+		branch = "main"
+	}
+	envVars = append(envVars,
+		corev1.EnvVar{Name: "KICKSTART_GIT_REPO", Value: kickstart.Repo},
+		corev1.EnvVar{Name: "KICKSTART_GIT_BRANCH", Value: branch},
+	)
+	if kickstart.CredentialsSecretName != "" {
+		volume, credentialsMount := gitCredentialsVolume("git-credentials", kickstart.CredentialsSecretName, "/etc/git-credentials")
+		volumes = append(volumes, volume)
+		mounts = append(mounts, credentialsMount)
+		envVars = append(envVars, corev1.EnvVar{Name: "KICKSTART_GIT_CREDENTIALS_DIR", Value: credentialsMount.MountPath})
+	}
+	return envVars, volumes, mounts
+}
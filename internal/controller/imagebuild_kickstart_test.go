@@ -0,0 +1,123 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("ImageBuild kickstart provisioner", func() {
+	ctx := context.Background()
+
+	It("mounts the ConfigMap holding the kickstart file read-only", func() {
+		ib := newTestImageBuild("ks-configmap")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Kickstart: &bibv1alpha1.KickstartSpec{
+			ConfigMapRef: &corev1.LocalObjectReference{Name: "rhel-ks"},
+			Path:         "server.ks",
+		}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Volumes).To(ContainElement(corev1.Volume{
+			Name: "source-repo",
+			VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "rhel-ks"},
+			}},
+		}))
+		builder := pod.Spec.Containers[0]
+		Expect(builder.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "source-repo", MountPath: "/source", ReadOnly: true}))
+		Expect(builder.Env).To(ContainElement(corev1.EnvVar{Name: "KICKSTART_PATH", Value: "server.ks"}))
+		Expect(builder.Env).NotTo(ContainElement(HaveField("Name", "KICKSTART_GIT_REPO")))
+		Expect(builder.Env).NotTo(ContainElement(HaveField("Name", "KICKSTART_INLINE")))
+	})
+
+	It("clones the repository holding the kickstart file", func() {
+		ib := newTestImageBuild("ks-git")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Kickstart: &bibv1alpha1.KickstartSpec{
+			Repo:                  "https://github.com/example/kickstarts.git",
+			CredentialsSecretName: "git-token",
+		}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Volumes).To(ContainElement(corev1.Volume{
+			Name:         "source-repo",
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		}))
+		builder := pod.Spec.Containers[0]
+		Expect(builder.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "source-repo", MountPath: "/source"}))
+		Expect(builder.Env).To(ContainElements(
+			corev1.EnvVar{Name: "KICKSTART_PATH", Value: "ks.cfg"},
+			corev1.EnvVar{Name: "KICKSTART_GIT_REPO", Value: "https://github.com/example/kickstarts.git"},
+			corev1.EnvVar{Name: "KICKSTART_GIT_BRANCH", Value: "main"},
+			corev1.EnvVar{Name: "KICKSTART_GIT_CREDENTIALS_DIR", Value: "/etc/git-credentials"},
+		))
+	})
+
+	It("passes an inline kickstart to the builder", func() {
+		ib := newTestImageBuild("ks-inline")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Kickstart: &bibv1alpha1.KickstartSpec{
+			Inline: "%packages\nvim-enhanced\n%end\n",
+		}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Volumes).To(ContainElement(corev1.Volume{
+			Name:         "source-repo",
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		}))
+		builder := pod.Spec.Containers[0]
+		Expect(builder.Env).To(ContainElements(
+			corev1.EnvVar{Name: "KICKSTART_PATH", Value: "ks.cfg"},
+			corev1.EnvVar{Name: "KICKSTART_INLINE", Value: "%packages\nvim-enhanced\n%end\n"},
+		))
+		Expect(builder.Env).NotTo(ContainElement(HaveField("Name", "KICKSTART_GIT_REPO")))
+	})
+
+	It("requires the referenced ConfigMap", func() {
+		ib := newTestImageBuild("ks-missing-configmap")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Kickstart: &bibv1alpha1.KickstartSpec{
+			ConfigMapRef: &corev1.LocalObjectReference{Name: "rhel-ks"},
+		}}
+		r := newFakeReconciler(ib)
+
+		ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.ProvisionerReady)).To(Equal(bibv1alpha1.ConfigMapNotFoundReason))
+		Expect(conditions.GetMessage(ib, bibv1alpha1.ProvisionerReady)).To(ContainSubstring("spec.provisioner.kickstart.configMapRef"))
+
+		By("passing once it exists")
+		kickstart := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "rhel-ks", Namespace: "default"}}
+		r = newFakeReconciler(ib, kickstart)
+		ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(buildSummary(ib)).To(HavePrefix("kickstart→"))
+	})
+})
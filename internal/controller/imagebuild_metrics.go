@@ -0,0 +1,75 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+// phaseDuration observes how long builds spent in a non-terminal phase once they leave it, e.g. how
+// long they were queued as Pending before the builder container started.
+var phaseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name: "bib_phase_duration_seconds",
+	Help: "Time ImageBuilds spent in a phase, observed when they leave it.",
+	// 1s to about 9h.
+	Buckets: prometheus.ExponentialBuckets(1, 2, 16),
+}, []string{"phase"})
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(phaseDuration)
+}
+
+// phaseStartTime returns when the build entered its current phase. Builds that last changed phase
+// before status.lastPhaseTransitionTime was recorded fall back to the creation time for Pending and
+// to the transition of BuilderPodReady to True for Building.
+func phaseStartTime(imageBuild *bibv1alpha1.ImageBuild) (time.Time, bool) {
+	if t := imageBuild.Status.LastPhaseTransitionTime; t != nil {
+		return t.Time, true
+	}
+	switch imageBuild.Status.Phase {
+	case bibv1alpha1.PhasePending:
+		return imageBuild.CreationTimestamp.Time, !imageBuild.CreationTimestamp.IsZero()
+	case bibv1alpha1.PhaseBuilding:
+		if conditions.IsTrue(imageBuild, bibv1alpha1.BuilderPodReady) {
+			return conditions.GetLastTransitionTime(imageBuild, bibv1alpha1.BuilderPodReady).Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// recordPhaseTransition stamps status.lastPhaseTransitionTime if the build moved on from the phase
+// it was in at the start of the reconcile. It returns the observation of the time spent in that
+// phase, to be made once the status is persisted so a transition is observed only once.
+func recordPhaseTransition(imageBuild *bibv1alpha1.ImageBuild, phase bibv1alpha1.ImageBuildPhase, since time.Time, ok bool, now time.Time) func() {
+	if imageBuild.Status.Phase == phase {
+		return func() {}
+	}
+	imageBuild.Status.LastPhaseTransitionTime = &metav1.Time{Time: now}
+	if !ok || phase == "" || isTerminalPhase(phase) {
+		return func() {}
+	}
+	return func() {
+		phaseDuration.WithLabelValues(string(phase)).Observe(now.Sub(since).Seconds())
+	}
+}
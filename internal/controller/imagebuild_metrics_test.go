@@ -0,0 +1,164 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("ImageBuild phase duration metric", func() {
+	ctx := context.Background()
+
+	// phaseObservations returns the number and sum of the observations recorded for phase.
+	phaseObservations := func(phase bibv1alpha1.ImageBuildPhase) (uint64, float64) {
+		metric := &dto.Metric{}
+		Expect(phaseDuration.WithLabelValues(string(phase)).(prometheus.Histogram).Write(metric)).To(Succeed())
+		return metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum()
+	}
+
+	builderPod := func(name string, status corev1.PodStatus) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: builderPodPrefix + name, Namespace: "default"},
+			Status:     status,
+		}
+	}
+
+	It("observes the time spent Pending once the builder container runs", func() {
+		ib := newTestImageBuild("queued")
+		ib.Status.Phase = bibv1alpha1.PhasePending
+		ib.Status.LastPhaseTransitionTime = &metav1.Time{Time: time.Now().Add(-90 * time.Second)}
+		r := newFakeReconciler(ib, builderPod("queued", corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  builderContainerName,
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			}},
+		}))
+		count, sum := phaseObservations(bibv1alpha1.PhasePending)
+
+		_, updated := reconcileBuild(r, "queued")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseBuilding))
+		Expect(updated.Status.LastPhaseTransitionTime.Time).To(BeTemporally("~", time.Now(), 2*time.Second))
+		newCount, newSum := phaseObservations(bibv1alpha1.PhasePending)
+		Expect(newCount).To(Equal(count + 1))
+		Expect(newSum - sum).To(BeNumerically("~", 90, 2))
+	})
+
+	It("observes the time spent Building once the builder pod has finished", func() {
+		ib := newTestImageBuild("built")
+		ib.Status.Phase = bibv1alpha1.PhaseBuilding
+		ib.Status.LastPhaseTransitionTime = &metav1.Time{Time: time.Now().Add(-10 * time.Minute)}
+		r := newFakeReconciler(ib, builderPod("built", corev1.PodStatus{
+			Phase: corev1.PodSucceeded,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  builderContainerName,
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: `{"artifacts":[]}`}},
+			}},
+		}))
+		count, sum := phaseObservations(bibv1alpha1.PhaseBuilding)
+
+		_, updated := reconcileBuild(r, "built")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseSucceeded))
+		newCount, newSum := phaseObservations(bibv1alpha1.PhaseBuilding)
+		Expect(newCount).To(Equal(count + 1))
+		Expect(newSum - sum).To(BeNumerically("~", 600, 2))
+	})
+
+	It("observes a transition only once the status is persisted", func() {
+		ib := newTestImageBuild("conflicted")
+		ib.Status.Phase = bibv1alpha1.PhasePending
+		ib.Status.LastPhaseTransitionTime = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+		r := newFakeReconciler(ib, builderPod("conflicted", corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  builderContainerName,
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			}},
+		}))
+		persisted := r.Client
+		r.Client = interceptor.NewClient(persisted.(client.WithWatch), interceptor.Funcs{
+			SubResourcePatch: func(context.Context, client.Client, string, client.Object, client.Patch, ...client.SubResourcePatchOption) error {
+				return apierrors.NewConflict(bibv1alpha1.GroupVersion.WithResource("imagebuilds").GroupResource(), "conflicted",
+					errors.New("the object has been modified"))
+			},
+		})
+		count, _ := phaseObservations(bibv1alpha1.PhasePending)
+
+		key := types.NamespacedName{Name: "conflicted", Namespace: "default"}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).To(HaveOccurred())
+		newCount, _ := phaseObservations(bibv1alpha1.PhasePending)
+		Expect(newCount).To(Equal(count))
+
+		r.Client = persisted
+		_, updated := reconcileBuild(r, "conflicted")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseBuilding))
+		newCount, _ = phaseObservations(bibv1alpha1.PhasePending)
+		Expect(newCount).To(Equal(count + 1))
+	})
+
+	It("observes nothing while the build stays in its phase", func() {
+		ib := newTestImageBuild("still-pending")
+		ib.Status.Phase = bibv1alpha1.PhasePending
+		since := metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second))
+		ib.Status.LastPhaseTransitionTime = &since
+		r := newFakeReconciler(ib, builderPod("still-pending", corev1.PodStatus{Phase: corev1.PodPending}))
+		count, _ := phaseObservations(bibv1alpha1.PhasePending)
+
+		_, updated := reconcileBuild(r, "still-pending")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhasePending))
+		Expect(updated.Status.LastPhaseTransitionTime.Equal(&since)).To(BeTrue())
+		newCount, _ := phaseObservations(bibv1alpha1.PhasePending)
+		Expect(newCount).To(Equal(count))
+	})
+
+	It("falls back to the condition transition times for builds without a recorded phase transition", func() {
+		ib := newTestImageBuild("upgraded")
+		ib.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+		ib.Status.Phase = bibv1alpha1.PhasePending
+		start, ok := phaseStartTime(ib)
+		Expect(ok).To(BeTrue())
+		Expect(start).To(Equal(ib.CreationTimestamp.Time))
+
+		ib.Status.Phase = bibv1alpha1.PhaseBuilding
+		conditions.MarkTrue(ib, bibv1alpha1.BuilderPodReady)
+		start, ok = phaseStartTime(ib)
+		Expect(ok).To(BeTrue())
+		Expect(start).To(Equal(conditions.GetLastTransitionTime(ib, bibv1alpha1.BuilderPodReady).Time))
+
+		ib.Status.Phase = bibv1alpha1.PhasePublishing
+		_, ok = phaseStartTime(ib)
+		Expect(ok).To(BeFalse())
+	})
+})
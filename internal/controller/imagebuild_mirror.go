@@ -0,0 +1,61 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zarcen/bib-operator/pkg/imageref"
+)
+
+// ParseRegistryMirrors parses a comma-separated list of "<registry>=<mirror>" pairs, e.g.
+// "docker.io=harbor.example.com/dockerhub", into a map from registry host to mirror prefix.
+func ParseRegistryMirrors(value string) (map[string]string, error) {
+	mirrors := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		registry, mirror, ok := strings.Cut(pair, "=")
+		registry, mirror = strings.TrimSpace(registry), strings.TrimSuffix(strings.TrimSpace(mirror), "/")
+		if !ok || registry == "" || mirror == "" {
+			return nil, fmt.Errorf("invalid registry mirror %q, expected <registry>=<mirror>", pair)
+		}
+		mirrors[registry] = mirror
+	}
+	return mirrors, nil
+}
+
+// resolveBaseImage rewrites the base image to be pulled through the mirror configured for its
+// registry. It returns the image to pull and the mirror used, or the image unchanged and "".
+// References that cannot be parsed are rejected by the preflight checks.
+func (r *ImageBuildReconciler) resolveBaseImage(image string) (string, string) {
+	if len(r.RegistryMirrors) == 0 {
+		return image, ""
+	}
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return image, ""
+	}
+	mirror, ok := r.RegistryMirrors[ref.Domain]
+	if !ok {
+		return image, ""
+	}
+	return ref.WithDomain(mirror).String(), mirror
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("ImageBuild registry mirrors", func() {
+	ctx := context.Background()
+
+	It("parses the registry mirrors flag", func() {
+		mirrors, err := ParseRegistryMirrors("docker.io=harbor.example.com/dockerhub/, quay.io=harbor.example.com/quay")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mirrors).To(Equal(map[string]string{
+			"docker.io": "harbor.example.com/dockerhub",
+			"quay.io":   "harbor.example.com/quay",
+		}))
+
+		mirrors, err = ParseRegistryMirrors("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mirrors).To(BeEmpty())
+
+		_, err = ParseRegistryMirrors("harbor.example.com/dockerhub")
+		Expect(err).To(HaveOccurred())
+	})
+
+	DescribeTable("rewrites base images to pull through the mirror of their registry",
+		func(image, resolved, mirror string) {
+			r := newFakeReconciler()
+			r.RegistryMirrors = map[string]string{"docker.io": "harbor.example.com/dockerhub"}
+
+			gotResolved, gotMirror := r.resolveBaseImage(image)
+			Expect(gotResolved).To(Equal(resolved))
+			Expect(gotMirror).To(Equal(mirror))
+		},
+		Entry("an official image", "ubuntu:24.04", "harbor.example.com/dockerhub/library/ubuntu:24.04", "harbor.example.com/dockerhub"),
+		Entry("a fully qualified image", "docker.io/rockylinux/rockylinux:9", "harbor.example.com/dockerhub/rockylinux/rockylinux:9", "harbor.example.com/dockerhub"),
+		Entry("a registry without a mirror", "quay.io/centos/centos:stream9", "quay.io/centos/centos:stream9", ""),
+		Entry("an unparsable reference", "Not A Reference", "Not A Reference", ""),
+	)
+
+	It("pulls the base image through the mirror and records it in status", func() {
+		ib := newTestImageBuild("mirrored")
+		r := newFakeReconciler(ib)
+		r.RegistryMirrors = map[string]string{"docker.io": "harbor.example.com/dockerhub"}
+		key := types.NamespacedName{Name: "mirrored", Namespace: "default"}
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		pod := &corev1.Pod{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: builderPodPrefix + "mirrored", Namespace: "default"}, pod)).To(Succeed())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElement(
+			corev1.EnvVar{Name: "BASE_IMAGE", Value: "harbor.example.com/dockerhub/library/ubuntu:24.04"}))
+
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, updated)).To(Succeed())
+		Expect(updated.Status.ResolvedBaseImage).To(Equal("harbor.example.com/dockerhub/library/ubuntu:24.04"))
+		Expect(updated.Status.BaseImageMirror).To(Equal("harbor.example.com/dockerhub"))
+	})
+
+	It("records the base image unchanged without a mirror", func() {
+		ib := newTestImageBuild("direct")
+		r := newFakeReconciler(ib)
+		key := types.NamespacedName{Name: "direct", Namespace: "default"}
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, updated)).To(Succeed())
+		Expect(updated.Status.ResolvedBaseImage).To(Equal("ubuntu:24.04"))
+		Expect(updated.Status.BaseImageMirror).To(BeEmpty())
+	})
+})
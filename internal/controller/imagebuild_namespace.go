@@ -0,0 +1,348 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+// The build namespaces are created, and deleted, with the cluster-wide permissions of
+// config/rbac/build_namespaces_role.yaml rather than kubebuilder markers, so that the operator is
+// only granted them when --build-namespaces is enabled.
+
+// buildNamespaceObjectName is the name of the ResourceQuota and NetworkPolicy of a build namespace.
+const buildNamespaceObjectName = "bib-build"
+
+// podSecurityEnforceLabel is the namespace label selecting the Pod Security Standard enforced in it.
+// The builder pod runs privileged.
+const podSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+
+// buildNamespacePendingRequeueAfter is how long a build waits before checking again whether its
+// build namespace is set up.
+var buildNamespacePendingRequeueAfter = 2 * time.Second
+
+// defaultBuildNamespaceQuota is the ResourceQuota of build namespaces unless --build-namespace-quota
+// overrides it: the builder pod alone.
+var defaultBuildNamespaceQuota = corev1.ResourceList{corev1.ResourcePods: resource.MustParse("1")}
+
+// ParseResourceQuota parses a comma-separated list of "<resource>=<quantity>" pairs, e.g.
+// "pods=1,requests.storage=200Gi", into the hard limits of a ResourceQuota.
+func ParseResourceQuota(value string) (corev1.ResourceList, error) {
+	quota := corev1.ResourceList{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, quantity, ok := strings.Cut(pair, "=")
+		name, quantity = strings.TrimSpace(name), strings.TrimSpace(quantity)
+		if !ok || name == "" || quantity == "" {
+			return nil, fmt.Errorf("invalid resource quota %q, expected <resource>=<quantity>", pair)
+		}
+		parsed, err := resource.ParseQuantity(quantity)
+		if err != nil {
+			return nil, fmt.Errorf("invalid resource quota %q: %w", pair, err)
+		}
+		quota[corev1.ResourceName(name)] = parsed
+	}
+	return quota, nil
+}
+
+// buildNamespaceName returns the name of the build namespace of an ImageBuild,
+// "bib-<namespace>-<name>-<hash>". The hash of the namespace and name keeps apart ImageBuilds whose
+// names join the same, e.g. "b-c" in namespace "a" and "c" in namespace "a-b", and those whose names
+// are shortened to fit a namespace.
+func buildNamespaceName(imageBuild *bibv1alpha1.ImageBuild) string {
+	name := strings.ReplaceAll(fmt.Sprintf("bib-%s-%s", imageBuild.Namespace, imageBuild.Name), ".", "-")
+	sum := sha256.Sum256([]byte(imageBuild.Namespace + "/" + imageBuild.Name))
+	suffix := "-" + hex.EncodeToString(sum[:4])
+	if len(name) > validation.DNS1123LabelMaxLength-len(suffix) {
+		name = strings.TrimRight(name[:validation.DNS1123LabelMaxLength-len(suffix)], "-")
+	}
+	return name + suffix
+}
+
+// buildNamespaceClaimField returns the spec field of an ImageBuild mounting a PersistentVolumeClaim
+// in the builder pod, which a build namespace cannot, or "" if there is none.
+func buildNamespaceClaimField(imageBuild *bibv1alpha1.ImageBuild) string {
+	switch {
+	case imageBuild.Spec.Output.PVC != nil:
+		return "spec.output.pvc"
+	case imageBuild.Spec.Build != nil && imageBuild.Spec.Build.Cache != nil:
+		return "spec.build.cache"
+	}
+	return ""
+}
+
+// builderPodNamespace returns the namespace the builder pod of an ImageBuild runs in: its build
+// namespace with BuildNamespaces, else the namespace of the ImageBuild.
+func (r *ImageBuildReconciler) builderPodNamespace(imageBuild *bibv1alpha1.ImageBuild) string {
+	if r.BuildNamespaces {
+		return buildNamespaceName(imageBuild)
+	}
+	return imageBuild.Namespace
+}
+
+// buildNamespaceQuota returns the hard limits of the ResourceQuota of build namespaces.
+func (r *ImageBuildReconciler) buildNamespaceQuota() corev1.ResourceList {
+	if len(r.BuildNamespaceQuota) == 0 {
+		return defaultBuildNamespaceQuota.DeepCopy()
+	}
+	return r.BuildNamespaceQuota.DeepCopy()
+}
+
+// buildNamespaceLabels returns the labels of the build namespace of an ImageBuild and of the
+// objects the operator creates in it.
+func buildNamespaceLabels(imageBuild *bibv1alpha1.ImageBuild) map[string]string {
+	return map[string]string{
+		bibv1alpha1.ImageBuildNameLabel:      imageBuild.Name,
+		bibv1alpha1.ImageBuildNamespaceLabel: imageBuild.Namespace,
+	}
+}
+
+// reconcileBuildNamespace prepares the build namespace of an ImageBuild for its builder pod: it
+// creates the namespace with a ResourceQuota and a NetworkPolicy denying all ingress, and copies the
+// Secrets and ConfigMaps the pod references from the namespace of the ImageBuild. It returns false
+// while the namespace is not ready for the pod yet: until the quota controller has observed the
+// ResourceQuota, which admission rejects pods without, and the service account controller has
+// created the default ServiceAccount the pod runs as.
+func (r *ImageBuildReconciler) reconcileBuildNamespace(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild, pod *corev1.Pod) (bool, error) {
+	// The preflight checks reject builds mounting a PersistentVolumeClaim, which cannot be shared
+	// across namespaces.
+	for _, volume := range pod.Spec.Volumes {
+		if claim := volume.PersistentVolumeClaim; claim != nil {
+			return false, fmt.Errorf("PersistentVolumeClaim %s cannot be mounted in build namespace %s, claims are not shared across namespaces",
+				claim.ClaimName, pod.Namespace)
+		}
+	}
+	labels := buildNamespaceLabels(imageBuild)
+
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: pod.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, namespace, func() error {
+		if namespace.ResourceVersion != "" && (namespace.Labels[bibv1alpha1.ImageBuildNameLabel] != imageBuild.Name ||
+			namespace.Labels[bibv1alpha1.ImageBuildNamespaceLabel] != imageBuild.Namespace) {
+			return fmt.Errorf("namespace %s exists and is not the build namespace of ImageBuild %s/%s",
+				namespace.Name, imageBuild.Namespace, imageBuild.Name)
+		}
+		if !namespace.DeletionTimestamp.IsZero() {
+			return fmt.Errorf("build namespace %s is terminating", namespace.Name)
+		}
+		if namespace.Labels == nil {
+			namespace.Labels = map[string]string{}
+		}
+		maps.Copy(namespace.Labels, labels)
+		namespace.Labels[podSecurityEnforceLabel] = "privileged"
+		return nil
+	}); err != nil {
+		return false, fmt.Errorf("failed to create build namespace %s: %w", pod.Namespace, err)
+	}
+
+	quota := &corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: buildNamespaceObjectName, Namespace: pod.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, quota, func() error {
+		quota.Labels = maps.Clone(labels)
+		quota.Spec.Hard = r.buildNamespaceQuota()
+		return nil
+	}); err != nil {
+		return false, fmt.Errorf("failed to create the ResourceQuota of build namespace %s: %w", pod.Namespace, err)
+	}
+
+	// The builder only connects out, to registries, git servers and object storage.
+	policy := &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: buildNamespaceObjectName, Namespace: pod.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, policy, func() error {
+		policy.Labels = maps.Clone(labels)
+		policy.Spec = networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		}
+		return nil
+	}); err != nil {
+		return false, fmt.Errorf("failed to create the NetworkPolicy of build namespace %s: %w", pod.Namespace, err)
+	}
+
+	secrets, configMaps := podReferences(pod)
+	for _, name := range secrets {
+		if err := r.copyToBuildNamespace(ctx, imageBuild, pod.Namespace, name, &corev1.Secret{}); err != nil {
+			return false, err
+		}
+	}
+	for _, name := range configMaps {
+		if err := r.copyToBuildNamespace(ctx, imageBuild, pod.Namespace, name, &corev1.ConfigMap{}); err != nil {
+			return false, err
+		}
+	}
+
+	for name := range quota.Spec.Hard {
+		if _, ok := quota.Status.Hard[name]; !ok {
+			return false, nil
+		}
+	}
+	serviceAccountName := pod.Spec.ServiceAccountName
+	if serviceAccountName == "" {
+		serviceAccountName = "default"
+	}
+	err := r.Get(ctx, types.NamespacedName{Name: serviceAccountName, Namespace: pod.Namespace}, &corev1.ServiceAccount{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// copyToBuildNamespace copies the Secret or ConfigMap name of the namespace of an ImageBuild into
+// its build namespace, keeping an existing copy up to date. Objects that do not exist are skipped;
+// the references the pod requires were checked by the preflight checks.
+func (r *ImageBuildReconciler) copyToBuildNamespace(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild,
+	namespace, name string, source client.Object) error {
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: imageBuild.Namespace}, source); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	var target client.Object
+	var mutate func()
+	switch source := source.(type) {
+	case *corev1.Secret:
+		secret := &corev1.Secret{}
+		target, mutate = secret, func() {
+			secret.Type, secret.Data = source.Type, source.Data
+		}
+	case *corev1.ConfigMap:
+		configMap := &corev1.ConfigMap{}
+		target, mutate = configMap, func() {
+			configMap.Data, configMap.BinaryData = source.Data, source.BinaryData
+		}
+	}
+	target.SetName(name)
+	target.SetNamespace(namespace)
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, target, func() error {
+		target.SetLabels(buildNamespaceLabels(imageBuild))
+		mutate()
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to copy %s into build namespace %s: %w", name, namespace, err)
+	}
+	return nil
+}
+
+// cleanupBuildNamespaceSecrets deletes the Secrets copied into the build namespace of an ImageBuild
+// once its builder pod has finished, so credentials are not kept around longer than the pod needs
+// them. A builder pod created again, e.g. for a retry, copies them again.
+func (r *ImageBuildReconciler) cleanupBuildNamespaceSecrets(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild) error {
+	if !r.BuildNamespaces {
+		return nil
+	}
+	return r.DeleteAllOf(ctx, &corev1.Secret{}, client.InNamespace(buildNamespaceName(imageBuild)),
+		client.MatchingLabels(buildNamespaceLabels(imageBuild)))
+}
+
+// podReferences returns the names of the Secrets and ConfigMaps a pod mounts or reads variables from.
+func podReferences(pod *corev1.Pod) ([]string, []string) {
+	secrets, configMaps := map[string]bool{}, map[string]bool{}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Secret != nil {
+			secrets[volume.Secret.SecretName] = true
+		}
+		if volume.ConfigMap != nil {
+			configMaps[volume.ConfigMap.Name] = true
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.Secret != nil {
+					secrets[source.Secret.Name] = true
+				}
+				if source.ConfigMap != nil {
+					configMaps[source.ConfigMap.Name] = true
+				}
+			}
+		}
+	}
+	for _, container := range slices.Concat(pod.Spec.InitContainers, pod.Spec.Containers) {
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.SecretRef != nil {
+				secrets[envFrom.SecretRef.Name] = true
+			}
+			if envFrom.ConfigMapRef != nil {
+				configMaps[envFrom.ConfigMapRef.Name] = true
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if env.ValueFrom.SecretKeyRef != nil {
+				secrets[env.ValueFrom.SecretKeyRef.Name] = true
+			}
+			if env.ValueFrom.ConfigMapKeyRef != nil {
+				configMaps[env.ValueFrom.ConfigMapKeyRef.Name] = true
+			}
+		}
+	}
+	return slices.Sorted(maps.Keys(secrets)), slices.Sorted(maps.Keys(configMaps))
+}
+
+// cleanupBuildNamespace deletes the build namespace of an ImageBuild, and everything in it, if it exists.
+func (r *ImageBuildReconciler) cleanupBuildNamespace(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild) error {
+	if !r.BuildNamespaces {
+		return nil
+	}
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: buildNamespaceName(imageBuild)}, namespace); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	// Never delete a namespace the operator did not create for this ImageBuild.
+	if namespace.Labels[bibv1alpha1.ImageBuildNameLabel] != imageBuild.Name ||
+		namespace.Labels[bibv1alpha1.ImageBuildNamespaceLabel] != imageBuild.Namespace {
+		return nil
+	}
+	if !namespace.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	log.FromContext(ctx).Info("Deleting build namespace", "Namespace", namespace.Name)
+	return client.IgnoreNotFound(r.Delete(ctx, namespace, client.Preconditions{UID: &namespace.UID}))
+}
+
+// buildNamespacePodImageBuild maps a pod in a build namespace to its ImageBuild. The pods of build
+// namespaces have no owner reference, which cannot cross namespaces.
+func buildNamespacePodImageBuild(_ context.Context, obj client.Object) []reconcile.Request {
+	namespace, ok := obj.GetLabels()[bibv1alpha1.ImageBuildNamespaceLabel]
+	name := obj.GetLabels()[bibv1alpha1.ImageBuildNameLabel]
+	if !ok || name == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}}
+}
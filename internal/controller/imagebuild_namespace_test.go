@@ -0,0 +1,197 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("ImageBuild build namespaces", func() {
+	ctx := context.Background()
+
+	// newIsolatedImageBuild returns an ImageBuild writing to an NFS volume, which can be mounted from
+	// any namespace unlike the PVC of newTestImageBuild.
+	newIsolatedImageBuild := func(name string) *bibv1alpha1.ImageBuild {
+		ib := newTestImageBuild(name)
+		ib.Generation = 1
+		ib.Spec.Output.PVC = nil
+		ib.Spec.Output.Volume = &bibv1alpha1.VolumeOutput{NFS: &corev1.NFSVolumeSource{Server: "nfs.example.com", Path: "/images"}}
+		return ib
+	}
+
+	// setUpBuildNamespace does what the quota and service account controllers of a cluster do for a
+	// new build namespace.
+	setUpBuildNamespace := func(r *ImageBuildReconciler, namespace string) {
+		quota := &corev1.ResourceQuota{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: buildNamespaceObjectName, Namespace: namespace}, quota)).To(Succeed())
+		quota.Status.Hard = quota.Spec.Hard
+		Expect(r.Update(ctx, quota)).To(Succeed())
+		Expect(r.Create(ctx, &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: namespace}})).To(Succeed())
+	}
+
+	It("runs the builder pod in a namespace of its own", func() {
+		ib := newIsolatedImageBuild("isolated")
+		ib.Spec.BaseImagePullSecretName = "pull-secret"
+		pullSecret := newTestSecret("pull-secret", corev1.SecretTypeDockerConfigJson, corev1.DockerConfigJsonKey)
+		r := newFakeReconciler(ib, pullSecret)
+		r.BuildNamespaces = true
+		key := types.NamespacedName{Name: "isolated", Namespace: "default"}
+		buildNamespace := buildNamespaceName(ib)
+
+		By("waiting for the cluster to set up the namespace")
+		result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(buildNamespacePendingRequeueAfter))
+		Expect(r.Get(ctx, types.NamespacedName{Name: builderPodPrefix + "isolated", Namespace: buildNamespace}, &corev1.Pod{})).
+			To(Satisfy(apierrors.IsNotFound))
+		pending := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, pending)).To(Succeed())
+		Expect(conditions.GetReason(pending, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.BuildNamespacePendingReason))
+		Expect(pending.Status.PodCreationFailures).To(BeZero())
+
+		setUpBuildNamespace(r, buildNamespace)
+		_, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		namespace := &corev1.Namespace{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: buildNamespace}, namespace)).To(Succeed())
+		Expect(namespace.Labels).To(HaveKeyWithValue(bibv1alpha1.ImageBuildNameLabel, "isolated"))
+		Expect(namespace.Labels).To(HaveKeyWithValue(bibv1alpha1.ImageBuildNamespaceLabel, "default"))
+		Expect(namespace.Labels).To(HaveKeyWithValue(podSecurityEnforceLabel, "privileged"))
+
+		pod := &corev1.Pod{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: builderPodPrefix + "isolated", Namespace: buildNamespace}, pod)).To(Succeed())
+		Expect(pod.OwnerReferences).To(BeEmpty())
+		Expect(buildNamespacePodImageBuild(ctx, pod)).To(ConsistOf(reconcile.Request{NamespacedName: key}))
+		Expect(r.Get(ctx, types.NamespacedName{Name: builderPodPrefix + "isolated", Namespace: "default"}, &corev1.Pod{})).
+			To(Satisfy(apierrors.IsNotFound))
+
+		quota := &corev1.ResourceQuota{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: buildNamespaceObjectName, Namespace: buildNamespace}, quota)).To(Succeed())
+		Expect(quota.Spec.Hard).To(HaveKeyWithValue(corev1.ResourcePods, resource.MustParse("1")))
+		policy := &networkingv1.NetworkPolicy{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: buildNamespaceObjectName, Namespace: buildNamespace}, policy)).To(Succeed())
+		Expect(policy.Spec.PolicyTypes).To(ConsistOf(networkingv1.PolicyTypeIngress))
+		Expect(policy.Spec.Ingress).To(BeEmpty())
+
+		By("copying the referenced secrets")
+		copied := &corev1.Secret{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: "pull-secret", Namespace: buildNamespace}, copied)).To(Succeed())
+		Expect(copied.Type).To(Equal(corev1.SecretTypeDockerConfigJson))
+		Expect(copied.Data).To(Equal(pullSecret.Data))
+
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, updated)).To(Succeed())
+		Expect(updated.Status.BuilderPodNamespace).To(Equal(buildNamespace))
+
+		By("deleting the copied secrets once the builder pod has finished")
+		pod.Status.Phase = corev1.PodFailed
+		Expect(r.Status().Update(ctx, pod)).To(Succeed())
+		_, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Get(ctx, types.NamespacedName{Name: "pull-secret", Namespace: buildNamespace}, &corev1.Secret{})).
+			To(Satisfy(apierrors.IsNotFound))
+		Expect(r.Get(ctx, types.NamespacedName{Name: "pull-secret", Namespace: "default"}, &corev1.Secret{})).To(Succeed())
+
+		By("deleting the namespace along with the ImageBuild")
+		Expect(r.cleanupBuildNamespace(ctx, updated)).To(Succeed())
+		Expect(r.Get(ctx, types.NamespacedName{Name: buildNamespace}, &corev1.Namespace{})).To(Satisfy(apierrors.IsNotFound))
+	})
+
+	It("rejects builds mounting a PVC", func() {
+		ib := newTestImageBuild("pvc-output")
+		r := newFakeReconciler(ib)
+		r.BuildNamespaces = true
+
+		ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.BuildNamespacesNotSupportedReason))
+		Expect(conditions.GetMessage(ib, bibv1alpha1.BuilderPodReady)).To(ContainSubstring("spec.output.pvc"))
+
+		By("rejecting a build cache")
+		ib = newIsolatedImageBuild("cached")
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{Cache: &bibv1alpha1.BuildCache{PVCName: "cache"}}
+		ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetMessage(ib, bibv1alpha1.BuilderPodReady)).To(ContainSubstring("spec.build.cache"))
+	})
+
+	It("leaves namespaces it did not create alone", func() {
+		ib := newIsolatedImageBuild("squatted")
+		squatter := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: buildNamespaceName(ib)}}
+		r := newFakeReconciler(ib, squatter)
+		r.BuildNamespaces = true
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = r.reconcileBuildNamespace(ctx, ib, pod)
+		Expect(err).To(MatchError(ContainSubstring("is not the build namespace")))
+		Expect(r.cleanupBuildNamespace(ctx, ib)).To(Succeed())
+		Expect(r.Get(ctx, types.NamespacedName{Name: squatter.Name}, &corev1.Namespace{})).To(Succeed())
+	})
+
+	It("shortens long namespace names", func() {
+		ib := newTestImageBuild(strings.Repeat("nightly.", 8) + "golden")
+		name := buildNamespaceName(ib)
+		Expect(len(name)).To(BeNumerically("<=", 63))
+		Expect(name).To(HavePrefix("bib-default-nightly-nightly-"))
+		Expect(name).NotTo(ContainSubstring("."))
+
+		other := newTestImageBuild(strings.Repeat("nightly.", 8) + "silver")
+		Expect(buildNamespaceName(other)).NotTo(Equal(name))
+	})
+
+	It("keeps apart the namespaces of ImageBuilds whose names join the same", func() {
+		ib := newTestImageBuild("b-c")
+		ib.Namespace = "a"
+		other := newTestImageBuild("c")
+		other.Namespace = "a-b"
+		Expect(buildNamespaceName(ib)).To(MatchRegexp(`^bib-a-b-c-[0-9a-f]{8}$`))
+		Expect(buildNamespaceName(other)).To(MatchRegexp(`^bib-a-b-c-[0-9a-f]{8}$`))
+		Expect(buildNamespaceName(ib)).NotTo(Equal(buildNamespaceName(other)))
+	})
+
+	It("parses the resource quota", func() {
+		quota, err := ParseResourceQuota("pods=1, requests.storage=200Gi")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(quota).To(Equal(corev1.ResourceList{
+			corev1.ResourcePods:            resource.MustParse("1"),
+			corev1.ResourceRequestsStorage: resource.MustParse("200Gi"),
+		}))
+
+		_, err = ParseResourceQuota("pods")
+		Expect(err).To(HaveOccurred())
+		_, err = ParseResourceQuota("requests.storage=lots")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,113 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// nodeArchLabel is the well-known node label the builder pod selects its architecture by.
+const nodeArchLabel = "kubernetes.io/arch"
+
+// builderNodeArch returns the kubernetes.io/arch value the builder pod of an ImageBuild is pinned to.
+// The builder does not emulate other architectures, so the pod must run on a node of the requested one.
+func builderNodeArch(imageBuild *bibv1alpha1.ImageBuild) (string, bool) {
+	if imageBuild.Spec.Architecture == "" {
+		return "", false
+	}
+	return bibv1alpha1.NodeArchitecture(imageBuild.Spec.Architecture)
+}
+
+// reconcileNodeArchitecture checks that the cluster has a schedulable node of the architecture the builder
+// pod selects, as the pod would otherwise stay Pending with nothing but a FailedScheduling event to show
+// for it. It returns true if there is none, in which case BuilderPodReady has been marked false; the
+// build is reconciled again once a node of the architecture becomes schedulable, see waitingImageBuilds.
+func (r *ImageBuildReconciler) reconcileNodeArchitecture(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild) (bool, error) {
+	nodeArch, ok := builderNodeArch(imageBuild)
+	if !ok {
+		return false, nil
+	}
+	nodes := &corev1.NodeList{}
+	if err := r.List(ctx, nodes, client.MatchingLabels{nodeArchLabel: nodeArch}); err != nil {
+		return false, err
+	}
+	for _, node := range nodes.Items {
+		if !node.Spec.Unschedulable {
+			// Clear the wait reported by an earlier reconcile now that a node has joined.
+			if conditions.GetReason(imageBuild, bibv1alpha1.BuilderPodReady) == bibv1alpha1.NoNodesForArchReason {
+				conditions.MarkUnknown(imageBuild, bibv1alpha1.BuilderPodReady, "Initializing", "Unknown")
+			}
+			return false, nil
+		}
+	}
+	log.FromContext(ctx).Info("No schedulable nodes for the build architecture, waiting", "Arch", nodeArch)
+	conditions.MarkFalse(imageBuild, bibv1alpha1.BuilderPodReady, bibv1alpha1.NoNodesForArchReason, clusterv1beta1.ConditionSeverityWarning,
+		"no schedulable node is labelled %s=%s for spec.arch %s; the builder does not emulate other architectures, "+
+			"so add %s nodes to the cluster or build for another architecture",
+		nodeArchLabel, nodeArch, imageBuild.Spec.Architecture, nodeArch)
+	return true, nil
+}
+
+// waitingImageBuilds maps a schedulable node to the unfinished ImageBuilds waiting for a node of its
+// architecture, so their builder pods are created as soon as it joins or is uncordoned.
+func (r *ImageBuildReconciler) waitingImageBuilds(ctx context.Context, obj client.Object) []reconcile.Request {
+	node := obj.(*corev1.Node)
+	nodeArch := node.Labels[nodeArchLabel]
+	if nodeArch == "" || node.Spec.Unschedulable {
+		return nil
+	}
+	imageBuilds := &bibv1alpha1.ImageBuildList{}
+	if err := r.List(ctx, imageBuilds); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list the ImageBuilds waiting for a node", "Node", node.Name)
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, imageBuild := range imageBuilds.Items {
+		if isTerminalPhase(imageBuild.Status.Phase) ||
+			conditions.GetReason(&imageBuild, bibv1alpha1.BuilderPodReady) != bibv1alpha1.NoNodesForArchReason {
+			continue
+		}
+		if arch, ok := builderNodeArch(&imageBuild); ok && arch == nodeArch {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&imageBuild)})
+		}
+	}
+	return requests
+}
+
+// nodeSchedulable passes the node events that may give a waiting build a node to run on: a node
+// joining, or one whose architecture label or cordon changed. Status updates of nodes, by far the
+// most frequent events, are ignored.
+var nodeSchedulable = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldNode, newNode := e.ObjectOld.(*corev1.Node), e.ObjectNew.(*corev1.Node)
+		return oldNode.Spec.Unschedulable != newNode.Spec.Unschedulable ||
+			oldNode.Labels[nodeArchLabel] != newNode.Labels[nodeArchLabel]
+	},
+	DeleteFunc:  func(event.DeleteEvent) bool { return false },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("ImageBuild node architecture", func() {
+	ctx := context.Background()
+
+	It("waits for a schedulable node of the build architecture", func() {
+		ib := newTestImageBuild("arm")
+		ib.Spec.Architecture = bibv1alpha1.ArchitectureARM64
+		cordoned := newTestNode("cordoned", bibv1alpha1.ArchitectureARM64)
+		cordoned.Spec.Unschedulable = true
+		r := newFakeReconciler(ib)
+		Expect(r.Delete(ctx, newTestNode(bibv1alpha1.ArchitectureARM64+"-node", bibv1alpha1.ArchitectureARM64))).To(Succeed())
+		Expect(r.Create(ctx, cordoned)).To(Succeed())
+		key := types.NamespacedName{Name: "arm", Namespace: "default"}
+		podKey := types.NamespacedName{Name: builderPodPrefix + "arm", Namespace: "default"}
+
+		res, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(reconcile.Result{}))
+		Expect(r.Get(ctx, podKey, &corev1.Pod{})).To(Satisfy(apierrors.IsNotFound))
+
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, updated)).To(Succeed())
+		Expect(conditions.IsFalse(updated, bibv1alpha1.BuilderPodReady)).To(BeTrue())
+		Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.NoNodesForArchReason))
+		Expect(conditions.GetSeverity(updated, bibv1alpha1.BuilderPodReady)).To(HaveValue(Equal(clusterv1beta1.ConditionSeverityWarning)))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.BuilderPodReady)).To(ContainSubstring("kubernetes.io/arch=arm64"))
+
+		By("reconciling the build once a node of its architecture joins")
+		joined := newTestNode("joined", bibv1alpha1.ArchitectureARM64)
+		Expect(r.Create(ctx, joined)).To(Succeed())
+		Expect(r.waitingImageBuilds(ctx, joined)).To(ConsistOf(reconcile.Request{NamespacedName: key}))
+		Expect(r.waitingImageBuilds(ctx, newTestNode("other", bibv1alpha1.ArchitectureAMD64))).To(BeEmpty())
+		Expect(r.waitingImageBuilds(ctx, cordoned)).To(BeEmpty())
+		_, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Get(ctx, podKey, &corev1.Pod{})).To(Succeed())
+		Expect(r.Get(ctx, key, updated)).To(Succeed())
+		Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).NotTo(Equal(bibv1alpha1.NoNodesForArchReason))
+	})
+
+	It("only maps node events that may make a node schedulable", func() {
+		node := newTestNode("node", bibv1alpha1.ArchitectureARM64)
+		heartbeat := node.DeepCopy()
+		heartbeat.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}
+		uncordoned := node.DeepCopy()
+		node.Spec.Unschedulable = true
+
+		Expect(nodeSchedulable.Create(event.CreateEvent{Object: node})).To(BeTrue())
+		Expect(nodeSchedulable.Update(event.UpdateEvent{ObjectOld: node, ObjectNew: uncordoned})).To(BeTrue())
+		Expect(nodeSchedulable.Update(event.UpdateEvent{ObjectOld: uncordoned, ObjectNew: heartbeat})).To(BeFalse())
+		Expect(nodeSchedulable.Delete(event.DeleteEvent{Object: node})).To(BeFalse())
+	})
+
+	It("does not check nodes without an explicit architecture", func() {
+		ib := newTestImageBuild("default-arch")
+		ib.Spec.Architecture = ""
+		r := newFakeReconciler(ib)
+		Expect(r.Delete(ctx, newTestNode(bibv1alpha1.ArchitectureAMD64+"-node", bibv1alpha1.ArchitectureAMD64))).To(Succeed())
+
+		noNodes, err := r.reconcileNodeArchitecture(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(noNodes).To(BeFalse())
+	})
+})
@@ -0,0 +1,108 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+	"github.com/zarcen/bib-operator/internal/notify"
+	"github.com/zarcen/bib-operator/internal/scope"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// maxNotificationAttempts bounds the delivery attempts of a single terminal-state notification.
+const maxNotificationAttempts = 5
+
+// notificationRetryBase is the delay before the first retry; it doubles on every further attempt.
+var notificationRetryBase = 10 * time.Second
+
+// notifyAuthTokenKey is the key of the spec.notify.authSecretName Secret holding the bearer token.
+const notifyAuthTokenKey = "token"
+
+// isTerminalPhase returns true if the build has finished, successfully or not.
+func isTerminalPhase(phase bibv1alpha1.ImageBuildPhase) bool {
+	return phase == bibv1alpha1.PhaseSucceeded || phase == bibv1alpha1.PhaseFailed
+}
+
+func (r *ImageBuildReconciler) notifier() notify.Notifier {
+	if r.Notifier == nil {
+		r.Notifier = notify.NewHTTPNotifier()
+	}
+	return r.Notifier
+}
+
+// reconcileNotify delivers the terminal-state notification of a finished build.
+// Delivery failures only affect the NotificationDelivered condition, never the build phase.
+func (r *ImageBuildReconciler) reconcileNotify(ctx context.Context, ibs *scope.ImageBuildScope) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	imageBuild := ibs.ImageBuild
+	if imageBuild.Spec.Notify == nil {
+		return ctrl.Result{}, nil
+	}
+
+	status := imageBuild.Status.Notification
+	if status == nil || status.Phase != imageBuild.Status.Phase {
+		status = &bibv1alpha1.NotificationStatus{Phase: imageBuild.Status.Phase}
+		imageBuild.Status.Notification = status
+	}
+	if status.Delivered || status.Attempts >= maxNotificationAttempts {
+		return ctrl.Result{}, nil
+	}
+
+	status.Attempts++
+	err := r.sendNotification(ctx, imageBuild)
+	if err != nil {
+		logger.Error(err, "Failed to deliver notification", "Attempt", status.Attempts)
+		conditions.MarkFalse(imageBuild, bibv1alpha1.NotificationDelivered, bibv1alpha1.NotificationFailedReason, clusterv1beta1.ConditionSeverityWarning,
+			"attempt %d/%d: %s", status.Attempts, maxNotificationAttempts, err.Error())
+		if status.Attempts >= maxNotificationAttempts {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{RequeueAfter: notificationRetryBase << (status.Attempts - 1)}, nil
+	}
+
+	logger.Info("Delivered notification", "Phase", status.Phase)
+	status.Delivered = true
+	conditions.MarkTrue(imageBuild, bibv1alpha1.NotificationDelivered)
+	return ctrl.Result{}, nil
+}
+
+// sendNotification resolves the auth token, if any, and POSTs the notification payload.
+func (r *ImageBuildReconciler) sendNotification(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild) error {
+	var token string
+	if name := imageBuild.Spec.Notify.AuthSecretName; name != "" {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: imageBuild.Namespace}, secret); err != nil {
+			return fmt.Errorf("failed to get notify auth secret %q: %w", name, err)
+		}
+		value, ok := secret.Data[notifyAuthTokenKey]
+		if !ok {
+			return fmt.Errorf("notify auth secret %q is missing required key %q", name, notifyAuthTokenKey)
+		}
+		token = string(value)
+	}
+	return r.notifier().Notify(ctx, imageBuild.Spec.Notify.URL, token, notify.NewPayload(imageBuild))
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+	"github.com/zarcen/bib-operator/internal/notify"
+)
+
+// fakeNotifier records delivered payloads and fails the first failures calls.
+type fakeNotifier struct {
+	failures int
+	calls    int
+	tokens   []string
+	payloads []notify.Payload
+}
+
+func (f *fakeNotifier) Notify(_ context.Context, _ string, token string, payload notify.Payload) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return errors.New("connection refused")
+	}
+	f.tokens = append(f.tokens, token)
+	f.payloads = append(f.payloads, payload)
+	return nil
+}
+
+var _ = Describe("ImageBuild notifications", func() {
+	ctx := context.Background()
+
+	newFinishedImageBuild := func(name string, phase bibv1alpha1.ImageBuildPhase) *bibv1alpha1.ImageBuild {
+		ib := newTestImageBuild(name)
+		ib.Spec.Notify = &bibv1alpha1.NotifySpec{URL: "https://hooks.example.com/builds"}
+		ib.Status.Phase = phase
+		completed := metav1.Now()
+		ib.Status.CompletionTime = &completed
+		return ib
+	}
+
+	It("notifies once when the build reaches a terminal phase", func() {
+		ib := newFinishedImageBuild("succeeded", bibv1alpha1.PhaseSucceeded)
+		notifier := &fakeNotifier{}
+		r := newFakeReconciler(ib)
+		r.Notifier = notifier
+
+		_, err := r.reconcileNotify(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(notifier.payloads).To(HaveLen(1))
+		Expect(notifier.payloads[0].Phase).To(Equal(bibv1alpha1.PhaseSucceeded))
+		Expect(ib.Status.Notification.Delivered).To(BeTrue())
+		Expect(conditions.IsTrue(ib, bibv1alpha1.NotificationDelivered)).To(BeTrue())
+
+		By("not notifying again on subsequent reconciles")
+		_, err = r.reconcileNotify(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(notifier.calls).To(Equal(1))
+	})
+
+	It("retries failed deliveries without changing the build phase", func() {
+		ib := newFinishedImageBuild("retried", bibv1alpha1.PhaseFailed)
+		notifier := &fakeNotifier{failures: 1}
+		r := newFakeReconciler(ib)
+		r.Notifier = notifier
+
+		res, err := r.reconcileNotify(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(notificationRetryBase))
+		Expect(ib.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+		Expect(conditions.GetReason(ib, bibv1alpha1.NotificationDelivered)).To(Equal(bibv1alpha1.NotificationFailedReason))
+
+		_, err = r.reconcileNotify(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ib.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+		Expect(ib.Status.Notification.Attempts).To(BeEquivalentTo(2))
+		Expect(ib.Status.Notification.Delivered).To(BeTrue())
+	})
+
+	It("gives up after the maximum number of attempts", func() {
+		ib := newFinishedImageBuild("unreachable", bibv1alpha1.PhaseSucceeded)
+		notifier := &fakeNotifier{failures: maxNotificationAttempts + 1}
+		r := newFakeReconciler(ib)
+		r.Notifier = notifier
+
+		for range maxNotificationAttempts + 2 {
+			_, err := r.reconcileNotify(ctx, newTestScope(r, ib))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(notifier.calls).To(Equal(maxNotificationAttempts))
+		Expect(ib.Status.Phase).To(Equal(bibv1alpha1.PhaseSucceeded))
+		Expect(conditions.IsFalse(ib, bibv1alpha1.NotificationDelivered)).To(BeTrue())
+	})
+
+	It("sends the token from the auth secret", func() {
+		ib := newFinishedImageBuild("authenticated", bibv1alpha1.PhaseSucceeded)
+		ib.Spec.Notify.AuthSecretName = "hook-token"
+		notifier := &fakeNotifier{}
+		r := newFakeReconciler(ib, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "hook-token", Namespace: "default"},
+			Data:       map[string][]byte{notifyAuthTokenKey: []byte("s3cr3t")},
+		})
+		r.Notifier = notifier
+
+		_, err := r.reconcileNotify(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(notifier.tokens).To(ConsistOf("s3cr3t"))
+	})
+})
@@ -0,0 +1,104 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+// orphanedPodCollectionPeriod is how often the pods of deleted ImageBuilds are looked for.
+var orphanedPodCollectionPeriod = 10 * time.Minute
+
+// OrphanedPodCollector deletes the builder and publisher pods whose ImageBuild no longer exists. The
+// pods are normally deleted along with their ImageBuild, by its finalizer and the garbage collector,
+// but not when the ImageBuild was force-deleted with its finalizer removed and the pods orphaned,
+// e.g. with --cascade=orphan. It runs with the manager.
+type OrphanedPodCollector struct {
+	client.Client
+}
+
+// Start collects the orphaned pods periodically until ctx is done.
+func (c *OrphanedPodCollector) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("orphaned-pod-collector")
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := c.collect(ctx); err != nil {
+			logger.Error(err, "Failed to collect orphaned pods")
+		}
+	}, orphanedPodCollectionPeriod)
+	return nil
+}
+
+// collect deletes the orphaned pods across all namespaces.
+func (c *OrphanedPodCollector) collect(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("orphaned-pod-collector")
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.HasLabels{bibv1alpha1.ImageBuildNameLabel}); err != nil {
+		return fmt.Errorf("failed to list the ImageBuild pods: %w", err)
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		orphaned, err := c.orphaned(ctx, pod)
+		if err != nil {
+			return err
+		}
+		if !orphaned {
+			continue
+		}
+		logger.Info("Deleting pod of a deleted ImageBuild", "Pod", pod.Name, "Namespace", pod.Namespace,
+			"ImageBuild", pod.Labels[bibv1alpha1.ImageBuildNameLabel])
+		// The precondition keeps a pod recreated under the same name since the list.
+		if err := c.Delete(ctx, pod, client.Preconditions{UID: &pod.UID}); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete orphaned pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+	}
+	return nil
+}
+
+// orphaned returns true if pod is a builder or publisher pod whose ImageBuild no longer exists. An
+// ImageBuild created again under the same name does not own the pods of the deleted one.
+func (c *OrphanedPodCollector) orphaned(ctx context.Context, pod *corev1.Pod) (bool, error) {
+	if !strings.HasPrefix(pod.Name, builderPodPrefix) && !strings.HasPrefix(pod.Name, publisherPodPrefix) {
+		return false, nil
+	}
+	imageBuild := &bibv1alpha1.ImageBuild{}
+	key := types.NamespacedName{Name: pod.Labels[bibv1alpha1.ImageBuildNameLabel], Namespace: pod.Namespace}
+	// The pods of build namespaces are labeled with the namespace of their ImageBuild.
+	if namespace, ok := pod.Labels[bibv1alpha1.ImageBuildNamespaceLabel]; ok {
+		key.Namespace = namespace
+	}
+	if err := c.Get(ctx, key, imageBuild); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to get ImageBuild %s: %w", key, err)
+	}
+	owner := metav1.GetControllerOf(pod)
+	return owner != nil && owner.Kind == "ImageBuild" && owner.UID != imageBuild.UID, nil
+}
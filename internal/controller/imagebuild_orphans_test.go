@@ -0,0 +1,85 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("ImageBuild orphaned pod collection", func() {
+	ctx := context.Background()
+
+	// newOwnedPod returns a pod of the ImageBuild with the given name and UID.
+	newOwnedPod := func(podName, imageBuild string, uid types.UID) *corev1.Pod {
+		controller := true
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      podName,
+			Namespace: "default",
+			Labels:    map[string]string{bibv1alpha1.ImageBuildNameLabel: imageBuild},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: bibv1alpha1.GroupVersion.String(), Kind: "ImageBuild",
+				Name: imageBuild, UID: uid, Controller: &controller,
+			}},
+		}}
+	}
+
+	exists := func(r *ImageBuildReconciler, name string) bool {
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, &corev1.Pod{})
+		if apierrors.IsNotFound(err) {
+			return false
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return true
+	}
+
+	It("deletes the pods of deleted ImageBuilds and leaves the others alone", func() {
+		live := newTestImageBuild("live")
+		live.UID = "live-uid"
+		recreated := newTestImageBuild("recreated")
+		recreated.UID = "recreated-uid"
+		unrelated := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: builderPodPrefix + "unrelated", Namespace: "default"}}
+		r := newFakeReconciler(live, recreated, unrelated,
+			newOwnedPod(builderPodPrefix+"live", "live", "live-uid"),
+			newOwnedPod(publisherPodPrefix+"live", "live", "live-uid"),
+			newOwnedPod(builderPodPrefix+"gone", "gone", "gone-uid"),
+			newOwnedPod(publisherPodPrefix+"gone", "gone", "gone-uid"),
+			newOwnedPod(builderPodPrefix+"recreated", "recreated", "previous-uid"),
+			newOwnedPod("debug-gone", "gone", "gone-uid"),
+		)
+
+		collector := &OrphanedPodCollector{Client: r.Client}
+		Expect(collector.collect(ctx)).To(Succeed())
+
+		Expect(exists(r, builderPodPrefix+"live")).To(BeTrue())
+		Expect(exists(r, publisherPodPrefix+"live")).To(BeTrue())
+		Expect(exists(r, builderPodPrefix+"gone")).To(BeFalse())
+		Expect(exists(r, publisherPodPrefix+"gone")).To(BeFalse())
+		Expect(exists(r, builderPodPrefix+"recreated")).To(BeFalse())
+		By("leaving pods the operator did not create")
+		Expect(exists(r, builderPodPrefix+"unrelated")).To(BeTrue())
+		Expect(exists(r, "debug-gone")).To(BeTrue())
+	})
+})
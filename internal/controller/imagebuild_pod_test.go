@@ -0,0 +1,1110 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("ImageBuild pod metadata", func() {
+	ctx := context.Background()
+
+	It("opts the builder pod out of sidecar injection by default", func() {
+		ib := newTestImageBuild("meshed")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Annotations).To(HaveKeyWithValue("sidecar.istio.io/inject", "false"))
+		Expect(pod.Annotations).To(HaveKeyWithValue("linkerd.io/inject", "disabled"))
+	})
+
+	It("applies spec.podMetadata to the builder and publisher pods", func() {
+		ib := newTestImageBuild("custom")
+		ib.Spec.Publish = &bibv1alpha1.PublishSpec{MaaS: &bibv1alpha1.MaaSPublishSpec{
+			APIURL: "http://maas.example.com/MAAS", ImageName: "custom", CredentialsSecretName: "maas-credentials",
+		}}
+		ib.Spec.PodMetadata = &bibv1alpha1.PodMetadata{
+			Labels:      map[string]string{"team": "platform", bibv1alpha1.ComponentLabel: "ignored"},
+			Annotations: map[string]string{"sidecar.istio.io/inject": "true", "example.com/owner": "platform"},
+		}
+		r := newFakeReconciler(ib)
+
+		builder, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		publisher, err := r.constructPublisherPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, pod := range []struct {
+			labels, annotations map[string]string
+			component           string
+		}{
+			{builder.Labels, builder.Annotations, bibv1alpha1.ComponentBuilder},
+			{publisher.Labels, publisher.Annotations, bibv1alpha1.ComponentPublisher},
+		} {
+			Expect(pod.labels).To(HaveKeyWithValue("team", "platform"))
+			By("keeping the operator-managed labels")
+			Expect(pod.labels).To(HaveKeyWithValue(bibv1alpha1.ComponentLabel, pod.component))
+			Expect(pod.labels).To(HaveKeyWithValue(bibv1alpha1.ImageBuildNameLabel, "custom"))
+			By("letting user annotations override the defaults")
+			Expect(pod.annotations).To(HaveKeyWithValue("sidecar.istio.io/inject", "true"))
+			Expect(pod.annotations).To(HaveKeyWithValue("linkerd.io/inject", "disabled"))
+			Expect(pod.annotations).To(HaveKeyWithValue("example.com/owner", "platform"))
+		}
+	})
+})
+
+var _ = Describe("ImageBuild builder security profile", func() {
+	ctx := context.Background()
+
+	It("runs the builder container privileged and unconfined by default", func() {
+		ib := newTestImageBuild("unconfined")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		securityContext := pod.Spec.Containers[0].SecurityContext
+		Expect(*securityContext.Privileged).To(BeTrue())
+		Expect(securityContext.SeccompProfile).To(BeNil())
+		Expect(securityContext.AppArmorProfile).To(BeNil())
+		Expect(pod.Annotations).NotTo(HaveKey(corev1.DeprecatedAppArmorBetaContainerAnnotationKeyPrefix + builderContainerName))
+	})
+
+	It("applies the seccomp and AppArmor profiles to the builder container", func() {
+		ib := newTestImageBuild("confined")
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{SecurityProfile: &bibv1alpha1.SecurityProfile{
+			SeccompProfile:  &bibv1alpha1.Profile{Type: bibv1alpha1.ProfileTypeLocalhost, LocalhostProfile: "profiles/builder.json"},
+			AppArmorProfile: &bibv1alpha1.Profile{Type: bibv1alpha1.ProfileTypeLocalhost, LocalhostProfile: "bib-builder"},
+		}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		securityContext := pod.Spec.Containers[0].SecurityContext
+		Expect(*securityContext.Privileged).To(BeTrue())
+		Expect(securityContext.SeccompProfile.Type).To(Equal(corev1.SeccompProfileTypeLocalhost))
+		Expect(*securityContext.SeccompProfile.LocalhostProfile).To(Equal("profiles/builder.json"))
+		Expect(securityContext.AppArmorProfile.Type).To(Equal(corev1.AppArmorProfileTypeLocalhost))
+		Expect(*securityContext.AppArmorProfile.LocalhostProfile).To(Equal("bib-builder"))
+		Expect(pod.Annotations).To(HaveKeyWithValue(
+			corev1.DeprecatedAppArmorBetaContainerAnnotationKeyPrefix+builderContainerName, "localhost/bib-builder"))
+
+		By("using the runtime defaults")
+		ib.Spec.Build.SecurityProfile.SeccompProfile = &bibv1alpha1.Profile{Type: bibv1alpha1.ProfileTypeRuntimeDefault}
+		ib.Spec.Build.SecurityProfile.AppArmorProfile = &bibv1alpha1.Profile{Type: bibv1alpha1.ProfileTypeRuntimeDefault}
+		pod, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		securityContext = pod.Spec.Containers[0].SecurityContext
+		Expect(securityContext.SeccompProfile).To(Equal(&corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}))
+		Expect(securityContext.AppArmorProfile).To(Equal(&corev1.AppArmorProfile{Type: corev1.AppArmorProfileTypeRuntimeDefault}))
+		Expect(pod.Annotations).To(HaveKeyWithValue(
+			corev1.DeprecatedAppArmorBetaContainerAnnotationKeyPrefix+builderContainerName, "runtime/default"))
+	})
+})
+
+var _ = Describe("ImageBuild privileged builds", func() {
+	ctx := context.Background()
+
+	It("rejects builds outside the allowed namespaces when the controller forbids privileged builds", func() {
+		ib := newTestImageBuild("privileged-gated")
+		r := newFakeReconciler(ib)
+		r.ForbidPrivileged = true
+		r.PrivilegedNamespaces = []string{"builds"}
+
+		ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.PrivilegedNotAllowedReason))
+		Expect(conditions.GetMessage(ib, bibv1alpha1.BuilderPodReady)).To(ContainSubstring("namespace default"))
+
+		By("accepting it once the namespace is allowed")
+		r.PrivilegedNamespaces = append(r.PrivilegedNamespaces, "default")
+		ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(conditions.IsUnknown(ib, bibv1alpha1.BuilderPodReady)).To(BeTrue())
+	})
+
+	It("accepts builds in any namespace by default", func() {
+		ib := newTestImageBuild("privileged-default")
+		r := newFakeReconciler(ib)
+
+		ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+	})
+})
+
+var _ = Describe("ImageBuild builder env", func() {
+	ctx := context.Background()
+
+	It("passes spec.builderEnv through with operator-managed variables winning", func() {
+		ib := newTestImageBuild("passthrough")
+		ib.Spec.BuilderEnv = []corev1.EnvVar{
+			{Name: "BUILDER_EXPERIMENTAL_FEATURE", Value: "on"},
+			{Name: "BASE_IMAGE", Value: "evil:latest"},
+			{Name: "TOKEN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "tokens"}, Key: "token",
+			}}},
+		}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		env := pod.Spec.Containers[0].Env
+		Expect(env).To(ContainElement(corev1.EnvVar{Name: "BUILDER_EXPERIMENTAL_FEATURE", Value: "on"}))
+		Expect(env).To(ContainElement(HaveField("Name", "TOKEN")))
+		Expect(env).To(ContainElement(corev1.EnvVar{Name: "BASE_IMAGE", Value: "ubuntu:24.04"}))
+		Expect(env).NotTo(ContainElement(corev1.EnvVar{Name: "BASE_IMAGE", Value: "evil:latest"}))
+	})
+
+	It("populates the builder environment from spec.build.envFrom", func() {
+		ib := newTestImageBuild("env-from")
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{EnvFrom: []corev1.EnvFromSource{
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "proxy-settings"}}},
+			{Prefix: "MIRROR_", SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "mirror-credentials"}}},
+		}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].EnvFrom).To(Equal(ib.Spec.Build.EnvFrom))
+	})
+
+	It("projects spec.build.secretFiles to their paths in the builder container", func() {
+		ib := newTestImageBuild("secret-files")
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{SecretFiles: []bibv1alpha1.SecretFileMount{
+			{SecretName: "vault", Key: "token", Path: "/run/secrets/vault-token"},
+			{SecretName: "licenses", Key: "rhel.pem", Path: "/etc/pki/entitlement/rhel.pem"},
+		}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		mode := int32(0400)
+		Expect(pod.Spec.Volumes).To(ContainElement(corev1.Volume{
+			Name: "secret-files",
+			VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+				DefaultMode: &mode,
+				Sources: []corev1.VolumeProjection{
+					{Secret: &corev1.SecretProjection{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "vault"},
+						Items:                []corev1.KeyToPath{{Key: "token", Path: "0"}},
+					}},
+					{Secret: &corev1.SecretProjection{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "licenses"},
+						Items:                []corev1.KeyToPath{{Key: "rhel.pem", Path: "1"}},
+					}},
+				},
+			}},
+		}))
+		builder := pod.Spec.Containers[0]
+		Expect(builder.VolumeMounts).To(ContainElements(
+			corev1.VolumeMount{Name: "secret-files", MountPath: "/run/secrets/vault-token", SubPath: "0", ReadOnly: true},
+			corev1.VolumeMount{Name: "secret-files", MountPath: "/etc/pki/entitlement/rhel.pem", SubPath: "1", ReadOnly: true},
+		))
+	})
+
+	It("passes the Ansible provisioning mode to the builder", func() {
+		ib := newTestImageBuild("ansible-mode")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Ansible: &bibv1alpha1.AnsibleSpec{
+			Repo: "https://example.com/org/playbooks.git", Playbook: "site.yml", Mode: bibv1alpha1.AnsibleModeContainer,
+		}}
+		ib.Spec.Verify = &bibv1alpha1.VerifySpec{Ansible: &bibv1alpha1.AnsibleSpec{
+			Repo: "https://example.com/org/tests.git", Playbook: "smoke.yml",
+		}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+			corev1.EnvVar{Name: "ANSIBLE_MODE", Value: "container"},
+			corev1.EnvVar{Name: "VERIFY_ANSIBLE_MODE", Value: "chroot"},
+		))
+	})
+
+	It("passes the connection and privilege escalation of each Ansible run to the builder", func() {
+		ib := newTestImageBuild("ansible-connection")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Ansible: &bibv1alpha1.AnsibleSpec{
+			Repo: "https://example.com/org/playbooks.git", Playbook: "site.yml", Connection: "ssh", Become: true,
+		}}
+		ib.Spec.Verify = &bibv1alpha1.VerifySpec{Ansible: &bibv1alpha1.AnsibleSpec{
+			Repo: "https://example.com/org/tests.git", Playbook: "smoke.yml", Become: true,
+		}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		env := pod.Spec.Containers[0].Env
+		Expect(env).To(ContainElements(
+			corev1.EnvVar{Name: "ANSIBLE_TARGET_CONNECTION", Value: "ssh"},
+			corev1.EnvVar{Name: "ANSIBLE_TARGET_BECOME", Value: "true"},
+			corev1.EnvVar{Name: "VERIFY_ANSIBLE_TARGET_BECOME", Value: "true"},
+		))
+		Expect(env).NotTo(ContainElement(HaveField("Name", "VERIFY_ANSIBLE_TARGET_CONNECTION")))
+
+		By("leaving the connection to the mode by default")
+		ib.Spec.Provisioner.Ansible.Connection, ib.Spec.Provisioner.Ansible.Become = "", false
+		pod, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", HavePrefix("ANSIBLE_TARGET_"))))
+	})
+
+	It("passes the Python interpreter or virtual environment of each Ansible run to the builder", func() {
+		ib := newTestImageBuild("ansible-python")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Ansible: &bibv1alpha1.AnsibleSpec{
+			Repo: "https://example.com/org/playbooks.git", Playbook: "site.yml",
+			Virtualenv: &bibv1alpha1.AnsibleVirtualenv{Path: "/opt/ansible", Packages: []string{"requests>=2", "docker"}},
+		}}
+		ib.Spec.Verify = &bibv1alpha1.VerifySpec{Ansible: &bibv1alpha1.AnsibleSpec{
+			Repo: "https://example.com/org/tests.git", Playbook: "smoke.yml", PythonInterpreter: "/usr/bin/python3.12",
+		}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		env := pod.Spec.Containers[0].Env
+		Expect(env).To(ContainElements(
+			corev1.EnvVar{Name: "ANSIBLE_VIRTUALENV", Value: "/opt/ansible"},
+			corev1.EnvVar{Name: "ANSIBLE_VIRTUALENV_PYTHON", Value: "python3"},
+			corev1.EnvVar{Name: "ANSIBLE_VIRTUALENV_PACKAGES", Value: "requests>=2 docker"},
+			corev1.EnvVar{Name: "VERIFY_ANSIBLE_PYTHON_INTERPRETER", Value: "/usr/bin/python3.12"},
+		))
+		Expect(env).NotTo(ContainElement(HaveField("Name", "ANSIBLE_PYTHON_INTERPRETER")))
+		Expect(env).NotTo(ContainElement(HaveField("Name", "VERIFY_ANSIBLE_VIRTUALENV")))
+
+		By("leaving interpreter discovery to Ansible by default")
+		ib.Spec.Provisioner.Ansible.Virtualenv = nil
+		pod, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", HavePrefix("ANSIBLE_VIRTUALENV"))))
+		Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "ANSIBLE_PYTHON_INTERPRETER", Value: "auto"}))
+
+		By("passing the interpreter of the provisioner")
+		ib.Spec.Provisioner.Ansible.PythonInterpreter = "/usr/libexec/platform-python"
+		pod, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+			corev1.EnvVar{Name: "ANSIBLE_PYTHON_INTERPRETER", Value: "/usr/libexec/platform-python"},
+			corev1.EnvVar{Name: "VERIFY_ANSIBLE_PYTHON_INTERPRETER", Value: "/usr/bin/python3.12"},
+		))
+	})
+
+	It("translates spec.verbosity into the log level of the builder and the provisioner", func() {
+		ib := newTestImageBuild("verbose")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Ansible: &bibv1alpha1.AnsibleSpec{
+			Repo: "https://example.com/org/playbooks.git", Playbook: "site.yml",
+		}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "BUILDER_VERBOSITY")))
+		Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "ANSIBLE_VERBOSITY")))
+
+		ib.Spec.Verbosity = bibv1alpha1.VerbosityVerbose
+		pod, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+			corev1.EnvVar{Name: "BUILDER_VERBOSITY", Value: "verbose"},
+			corev1.EnvVar{Name: "ANSIBLE_VERBOSITY", Value: "1"},
+		))
+
+		ib.Spec.Verbosity = bibv1alpha1.VerbosityDebug
+		pod, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+			corev1.EnvVar{Name: "BUILDER_VERBOSITY", Value: "debug"},
+			corev1.EnvVar{Name: "ANSIBLE_VERBOSITY", Value: "3"},
+		))
+	})
+
+	It("mounts the git credentials secrets for the builder to clone with", func() {
+		ib := newTestImageBuild("git-token")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Ansible: &bibv1alpha1.AnsibleSpec{
+			Repo: "https://gitlab.example.com/org/playbooks.git", Playbook: "site.yml", CredentialsSecretName: "gitlab-token",
+		}}
+		ib.Spec.Verify = &bibv1alpha1.VerifySpec{Ansible: &bibv1alpha1.AnsibleSpec{
+			Repo: "git@example.com:org/tests.git", Playbook: "smoke.yml", CredentialsSecretName: "deploy-key",
+		}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+			corev1.EnvVar{Name: "ANSIBLE_GIT_CREDENTIALS_DIR", Value: "/etc/git-credentials"},
+			corev1.EnvVar{Name: "VERIFY_ANSIBLE_GIT_CREDENTIALS_DIR", Value: "/etc/verify-git-credentials"},
+		))
+		Expect(pod.Spec.Volumes).To(ContainElements(
+			HaveField("Secret.SecretName", "gitlab-token"),
+			HaveField("Secret.SecretName", "deploy-key"),
+		))
+		Expect(pod.Spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name: "git-credentials", MountPath: "/etc/git-credentials", ReadOnly: true,
+		}))
+	})
+})
+
+var _ = Describe("ImageBuild builder pod status", func() {
+	ctx := context.Background()
+
+	builderPod := func(name string, phase corev1.PodPhase, state corev1.ContainerState) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: builderPodPrefix + name, Namespace: "default"},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+		if state != (corev1.ContainerState{}) {
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: builderContainerName, State: state}}
+		}
+		return pod
+	}
+
+	It("reports a newly created builder pod as starting", func() {
+		ib := newTestImageBuild("created")
+		r := newFakeReconciler(ib)
+
+		_, updated := reconcileBuild(r, "created")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhasePending))
+		Expect(updated.Status.BuilderPodName).To(Equal(builderPodPrefix + "created"))
+		Expect(updated.Status.StartTime).NotTo(BeNil())
+		Expect(conditions.IsFalse(updated, bibv1alpha1.BuilderPodReady)).To(BeTrue())
+		Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.BuilderPodStartingReason))
+	})
+
+	It("counts every builder pod created, including recreations", func() {
+		ib := newTestImageBuild("attempts")
+		r := newFakeReconciler(ib)
+
+		_, updated := reconcileBuild(r, "attempts")
+		Expect(updated.Status.BuildAttempts).To(Equal(int32(1)))
+
+		By("not counting a pod that already exists")
+		_, updated = reconcileBuild(r, "attempts")
+		Expect(updated.Status.BuildAttempts).To(Equal(int32(1)))
+
+		By("counting the pod recreated after the previous one disappeared")
+		pod := &corev1.Pod{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: builderPodPrefix + "attempts", Namespace: "default"}, pod)).To(Succeed())
+		Expect(r.Delete(ctx, pod)).To(Succeed())
+		_, updated = reconcileBuild(r, "attempts")
+		Expect(updated.Status.BuildAttempts).To(Equal(int32(2)))
+	})
+
+	It("stays pending while the builder container is starting", func() {
+		ib := newTestImageBuild("pulling")
+		r := newFakeReconciler(ib, builderPod("pulling", corev1.PodPending,
+			corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}))
+
+		_, updated := reconcileBuild(r, "pulling")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhasePending))
+		Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.BuilderPodStartingReason))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.BuilderPodReady)).To(ContainSubstring("ContainerCreating"))
+	})
+
+	It("reports a builder image that cannot be pulled once the grace period has passed", func() {
+		ib := newTestImageBuild("image-pull")
+		pod := builderPod("image-pull", corev1.PodPending, corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{
+			Reason:  "ImagePullBackOff",
+			Message: `Back-off pulling image "builder:test"`,
+		}})
+		pod.CreationTimestamp = metav1.Now()
+		r := newFakeReconciler(ib, pod)
+
+		By("waiting for the pull to be retried during the grace period")
+		key := types.NamespacedName{Name: "image-pull", Namespace: "default"}
+		result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically("~", builderImagePullGracePeriod, time.Minute))
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, updated)).To(Succeed())
+		Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.BuilderPodStartingReason))
+
+		By("reporting the pull as failed afterwards")
+		pod.CreationTimestamp = metav1.NewTime(time.Now().Add(-builderImagePullGracePeriod - time.Minute))
+		r = newFakeReconciler(ib, pod)
+		_, updated = reconcileBuild(r, "image-pull")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhasePending))
+		Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.BuilderImagePullFailedReason))
+		Expect(conditions.GetSeverity(updated, bibv1alpha1.BuilderPodReady)).To(HaveValue(Equal(clusterv1beta1.ConditionSeverityError)))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.BuilderPodReady)).To(Equal(
+			`builder pod imgbldr-image-pull cannot pull the builder image: ImagePullBackOff: Back-off pulling image "builder:test"`))
+	})
+
+	It("stays pending while a running pod has not started the builder container", func() {
+		ib := newTestImageBuild("init")
+		r := newFakeReconciler(ib, builderPod("init", corev1.PodRunning, corev1.ContainerState{}))
+
+		_, updated := reconcileBuild(r, "init")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhasePending))
+		Expect(conditions.IsFalse(updated, bibv1alpha1.BuilderPodReady)).To(BeTrue())
+	})
+
+	It("reports the build as started once the builder container runs", func() {
+		ib := newTestImageBuild("running")
+		r := newFakeReconciler(ib, builderPod("running", corev1.PodRunning,
+			corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}))
+
+		_, updated := reconcileBuild(r, "running")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseBuilding))
+		Expect(updated.Status.BuilderPodName).To(Equal(builderPodPrefix + "running"))
+		Expect(conditions.IsTrue(updated, bibv1alpha1.BuilderPodReady)).To(BeTrue())
+		Expect(updated.Status.ExitCode).To(BeNil())
+	})
+
+	It("reports the exit code and termination reason of a failed builder container", func() {
+		ib := newTestImageBuild("errored")
+		r := newFakeReconciler(ib, builderPod("errored", corev1.PodFailed,
+			corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 2, Reason: "Error"}}))
+
+		_, updated := reconcileBuild(r, "errored")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+		Expect(updated.Status.ExitCode).To(HaveValue(Equal(int32(2))))
+		Expect(updated.Status.TerminationReason).To(Equal("Error"))
+		Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.BuildFailedReason))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.BuilderPodReady)).To(Equal(
+			"builder pod " + builderPodPrefix + "errored failed: builder container exited with code 2 (Error)"))
+	})
+
+	It("reports an OOMKilled builder container with a hint to raise its memory limit", func() {
+		ib := newTestImageBuild("oom")
+		r := newFakeReconciler(ib, builderPod("oom", corev1.PodFailed,
+			corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"}}))
+		recorder := record.NewFakeRecorder(10)
+		r.Recorder = recorder
+
+		_, updated := reconcileBuild(r, "oom")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+		Expect(updated.Status.ExitCode).To(HaveValue(Equal(int32(137))))
+		Expect(updated.Status.TerminationReason).To(Equal("OOMKilled"))
+		Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.OOMKilledReason))
+		Expect(conditions.GetSeverity(updated, bibv1alpha1.BuilderPodReady)).To(HaveValue(Equal(clusterv1beta1.ConditionSeverityError)))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.BuilderPodReady)).To(Equal(
+			"builder pod " + builderPodPrefix + "oom ran out of memory: builder container exited with code 137 (OOMKilled); " +
+				"raise the memory limit of the builder container, e.g. the LimitRange default of namespace default"))
+		Expect(recorder.Events).To(Receive(Equal(
+			"Warning OOMKilled Builder pod " + builderPodPrefix + "oom was OOMKilled; raise the memory limit of the builder container")))
+	})
+
+	It("reports the exit code of a succeeded builder container", func() {
+		ib := newTestImageBuild("completed")
+		r := newFakeReconciler(ib, builderPod("completed", corev1.PodSucceeded,
+			corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0, Reason: "Completed"}}))
+
+		_, updated := reconcileBuild(r, "completed")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseSucceeded))
+		Expect(updated.Status.ExitCode).To(HaveValue(BeZero()))
+		Expect(updated.Status.TerminationReason).To(Equal("Completed"))
+	})
+
+	It("describes a failure without a terminated builder container", func() {
+		ib := newTestImageBuild("evicted")
+		r := newFakeReconciler(ib, builderPod("evicted", corev1.PodFailed, corev1.ContainerState{}))
+
+		_, updated := reconcileBuild(r, "evicted")
+		Expect(updated.Status.ExitCode).To(BeNil())
+		Expect(conditions.GetMessage(updated, bibv1alpha1.BuilderPodReady)).To(Equal("builder pod " + builderPodPrefix + "evicted failed"))
+	})
+})
+
+var _ = Describe("ImageBuild architecture", func() {
+	ctx := context.Background()
+
+	DescribeTable("schedules the builder pod on nodes of the build architecture",
+		func(arch string) {
+			ib := newTestImageBuild("arch")
+			ib.Spec.Architecture = arch
+			r := newFakeReconciler(ib)
+
+			pod, err := r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.NodeSelector).To(Equal(map[string]string{"kubernetes.io/arch": arch}))
+		},
+		Entry("amd64", bibv1alpha1.ArchitectureAMD64),
+		Entry("arm64", bibv1alpha1.ArchitectureARM64),
+		Entry("riscv64", bibv1alpha1.ArchitectureRISCV64),
+	)
+})
+
+var _ = Describe("ImageBuild host networking", func() {
+	ctx := context.Background()
+
+	newHostNetworkImageBuild := func(name string) *bibv1alpha1.ImageBuild {
+		ib := newTestImageBuild(name)
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{HostNetwork: true}
+		return ib
+	}
+
+	It("runs the builder pod in the host network namespace", func() {
+		ib := newHostNetworkImageBuild("host-network")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.HostNetwork).To(BeTrue())
+		Expect(pod.Spec.DNSPolicy).To(Equal(corev1.DNSClusterFirstWithHostNet))
+
+		pod, err = r.constructBuilderPod(ctx, newTestImageBuild("pod-network"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.HostNetwork).To(BeFalse())
+	})
+
+	It("rejects host networking unless the controller allows it", func() {
+		ib := newHostNetworkImageBuild("host-network-gated")
+		r := newFakeReconciler(ib)
+
+		ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.HostNetworkNotAllowedReason))
+
+		By("accepting it once the controller allows host networking")
+		r.AllowHostNetwork = true
+		ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(conditions.IsUnknown(ib, bibv1alpha1.BuilderPodReady)).To(BeTrue())
+	})
+})
+
+var _ = Describe("ImageBuild user namespaces", func() {
+	ctx := context.Background()
+
+	It("runs the builder pod in the host user namespace by default", func() {
+		ib := newTestImageBuild("host-users")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.HostUsers).To(HaveValue(BeTrue()))
+	})
+
+	It("runs the builder pod in its own user namespace when hostUsers is false", func() {
+		hostUsers := false
+		ib := newTestImageBuild("user-namespace")
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{HostUsers: &hostUsers}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.HostUsers).To(HaveValue(BeFalse()))
+		Expect(pod.Spec.Containers[0].SecurityContext.Privileged).To(HaveValue(BeTrue()))
+	})
+})
+
+var _ = Describe("ImageBuild fsGroup", func() {
+	ctx := context.Background()
+
+	It("sets no fsGroup on the builder pod by default", func() {
+		ib := newTestImageBuild("no-fsgroup")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.SecurityContext.FSGroup).To(BeNil())
+		Expect(pod.Spec.SecurityContext.RunAsUser).To(HaveValue(BeZero()))
+	})
+
+	It("sets spec.build.fsGroup as the fsGroup of the builder pod", func() {
+		fsGroup := int64(2000)
+		ib := newTestImageBuild("fsgroup")
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{FSGroup: &fsGroup}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.SecurityContext.FSGroup).To(HaveValue(Equal(int64(2000))))
+		Expect(pod.Spec.SecurityContext.RunAsUser).To(HaveValue(BeZero()))
+	})
+
+	It("sets spec.build.supplementalGroups as the supplemental groups of the builder pod", func() {
+		fsGroup := int64(2000)
+		ib := newTestImageBuild("supplemental-groups")
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{FSGroup: &fsGroup, SupplementalGroups: []int64{3000, 4000}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.SecurityContext.FSGroup).To(HaveValue(Equal(int64(2000))))
+		Expect(pod.Spec.SecurityContext.SupplementalGroups).To(Equal([]int64{3000, 4000}))
+	})
+})
+
+var _ = Describe("ImageBuild work volume", func() {
+	ctx := context.Background()
+
+	containersStorage := func(pod *corev1.Pod) corev1.Volume {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.Name == "containers-storage" {
+				return volume
+			}
+		}
+		Fail("containers-storage volume not found")
+		return corev1.Volume{}
+	}
+
+	It("keeps the container storage on an emptyDir by default", func() {
+		ib := newTestImageBuild("empty-dir")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(containersStorage(pod).EmptyDir).NotTo(BeNil())
+	})
+
+	It("backs the container storage with a generic ephemeral volume", func() {
+		storageClass := "fast-ssd"
+		ib := newTestImageBuild("ephemeral")
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{WorkVolume: &bibv1alpha1.WorkVolume{
+			StorageClassName: &storageClass, Size: resource.MustParse("200Gi"),
+		}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		volume := containersStorage(pod)
+		Expect(volume.EmptyDir).To(BeNil())
+		Expect(volume.Ephemeral).NotTo(BeNil())
+		claim := volume.Ephemeral.VolumeClaimTemplate.Spec
+		Expect(claim.StorageClassName).To(HaveValue(Equal("fast-ssd")))
+		Expect(claim.AccessModes).To(ConsistOf(corev1.ReadWriteOnce))
+		Expect(claim.Resources.Requests[corev1.ResourceStorage]).To(Equal(resource.MustParse("200Gi")))
+	})
+})
+
+var _ = Describe("ImageBuild process namespace sharing", func() {
+	ctx := context.Background()
+
+	It("shares the process namespace of the builder pod only when requested", func() {
+		ib := newTestImageBuild("shared-pid")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.ShareProcessNamespace).To(BeNil())
+
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{ShareProcessNamespace: true}
+		pod, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.ShareProcessNamespace).To(HaveValue(BeTrue()))
+	})
+})
+
+var _ = Describe("ImageBuild working directory", func() {
+	ctx := context.Background()
+
+	It("keeps the builder image's working directory without a git provisioner", func() {
+		ib := newTestImageBuild("default-workdir")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].WorkingDir).To(BeEmpty())
+	})
+
+	It("defaults to the repository checkout of the Ansible provisioner", func() {
+		ib := newTestImageBuild("source-workdir")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Ansible: &bibv1alpha1.AnsibleSpec{
+			Repo: "https://example.com/org/playbooks.git", Playbook: "site.yml",
+		}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].WorkingDir).To(Equal("/source"))
+		Expect(pod.Spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "source-repo", MountPath: "/source"}))
+	})
+
+	It("uses spec.build.workingDir over the default", func() {
+		ib := newTestImageBuild("custom-workdir")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Ansible: &bibv1alpha1.AnsibleSpec{
+			Repo: "https://example.com/org/playbooks.git", Playbook: "site.yml",
+		}}
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{WorkingDir: "/source/ansible"}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].WorkingDir).To(Equal("/source/ansible"))
+	})
+})
+
+var _ = Describe("ImageBuild builder lifecycle", func() {
+	ctx := context.Background()
+
+	It("flushes and unmounts the builder storage before stopping by default", func() {
+		ib := newTestImageBuild("default-lifecycle")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Lifecycle).NotTo(BeNil())
+		Expect(pod.Spec.Containers[0].Lifecycle.PreStop).To(Equal(&defaultBuilderPreStop))
+		Expect(pod.Spec.Containers[0].Lifecycle.PreStop.Exec.Command).To(ContainElement(ContainSubstring("sync")))
+	})
+
+	It("uses spec.build.lifecycle.preStop over the default", func() {
+		ib := newTestImageBuild("custom-lifecycle")
+		preStop := &corev1.LifecycleHandler{Exec: &corev1.ExecAction{Command: []string{"/usr/local/bin/flush"}}}
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{Lifecycle: &bibv1alpha1.BuilderLifecycle{PreStop: preStop}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Lifecycle.PreStop).To(Equal(preStop))
+	})
+})
+
+var _ = Describe("ImageBuild timeout", func() {
+	ctx := context.Background()
+
+	It("sets spec.timeoutSeconds as the active deadline of the builder pod", func() {
+		ib := newTestImageBuild("unbounded")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.ActiveDeadlineSeconds).To(BeNil())
+
+		timeout := int64(3600)
+		ib.Spec.TimeoutSeconds = &timeout
+		pod, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.ActiveDeadlineSeconds).To(HaveValue(Equal(int64(3600))))
+	})
+
+	It("records the deadline of the builder pod once the kubelet started it", func() {
+		ib := newTestImageBuild("deadlined")
+		timeout := int64(3600)
+		ib.Spec.TimeoutSeconds = &timeout
+		r := newFakeReconciler(ib)
+
+		key := types.NamespacedName{Name: "deadlined", Namespace: "default"}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, updated)).To(Succeed())
+		Expect(updated.Status.StartTime).NotTo(BeNil())
+		Expect(updated.Status.BuildDeadline).To(BeNil())
+
+		By("counting the deadline from the start of the pod rather than its creation")
+		pod := &corev1.Pod{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: builderPodPrefix + "deadlined", Namespace: "default"}, pod)).To(Succeed())
+		started := metav1.NewTime(time.Now().Add(5 * time.Minute).Truncate(time.Second))
+		pod.Status = corev1.PodStatus{Phase: corev1.PodPending, StartTime: &started}
+		Expect(r.Status().Update(ctx, pod)).To(Succeed())
+		_, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Get(ctx, key, updated)).To(Succeed())
+		Expect(updated.Status.BuildDeadline).NotTo(BeNil())
+		Expect(updated.Status.BuildDeadline.Time).To(Equal(started.Add(time.Hour)))
+	})
+
+	It("fails a build killed at its deadline without retrying it", func() {
+		ib := newTestImageBuild("timed-out")
+		ib.Spec.Output.PVC.RecreateOnFailure = true
+		timeout := int64(60)
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: builderPodPrefix + "timed-out", Namespace: "default"},
+			Spec:       corev1.PodSpec{ActiveDeadlineSeconds: &timeout},
+			Status:     corev1.PodStatus{Phase: corev1.PodFailed, Reason: "DeadlineExceeded"},
+		}
+		r := newFakeReconciler(ib, pod)
+
+		key := types.NamespacedName{Name: "timed-out", Namespace: "default"}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, updated)).To(Succeed())
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+		Expect(updated.Status.OutputPVCRecreations).To(BeZero())
+		Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.BuildTimedOutReason))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.BuilderPodReady)).To(ContainSubstring("deadline of 60s"))
+	})
+})
+
+var _ = Describe("ImageBuild package mirrors", func() {
+	ctx := context.Background()
+
+	newMirroredImageBuild := func(name string) *bibv1alpha1.ImageBuild {
+		ib := newTestImageBuild(name)
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{PackageMirrors: &bibv1alpha1.PackageMirrors{
+			ConfigMapName: "internal-mirrors",
+			Proxy:         "http://proxy.example.com:3128",
+		}}
+		return ib
+	}
+
+	It("mounts the mirror repositories and passes the proxy to the builder", func() {
+		ib := newMirroredImageBuild("mirrored")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Volumes).To(ContainElement(And(
+			HaveField("Name", "package-mirrors"),
+			HaveField("ConfigMap.Name", "internal-mirrors"),
+		)))
+		Expect(pod.Spec.Containers[0].VolumeMounts).To(ContainElement(
+			corev1.VolumeMount{Name: "package-mirrors", MountPath: packageMirrorsMountPath, ReadOnly: true},
+		))
+		Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+			corev1.EnvVar{Name: "PACKAGE_MIRRORS_DIR", Value: packageMirrorsMountPath},
+			corev1.EnvVar{Name: "PACKAGE_PROXY", Value: "http://proxy.example.com:3128"},
+		))
+
+		By("passing only the proxy when no repositories are given")
+		ib.Spec.Build.PackageMirrors.ConfigMapName = ""
+		pod, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Volumes).NotTo(ContainElement(HaveField("Name", "package-mirrors")))
+		Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "PACKAGE_MIRRORS_DIR")))
+	})
+
+	It("requires the mirror config map to exist", func() {
+		ib := newMirroredImageBuild("mirrors-missing")
+		r := newFakeReconciler(ib)
+
+		ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.ConfigMapNotFoundReason))
+		Expect(conditions.GetMessage(ib, bibv1alpha1.BuilderPodReady)).To(ContainSubstring("spec.build.packageMirrors.configMapName"))
+
+		mirrors := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "internal-mirrors", Namespace: "default"}}
+		r = newFakeReconciler(ib, mirrors)
+		ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+	})
+})
+
+var _ = Describe("ImageBuild git SSH host key verification", func() {
+	ctx := context.Background()
+
+	It("trusts host keys on first use by default", func() {
+		ib := newTestImageBuild("git-ssh-default")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "GIT_SSH_HOST_KEY_CHECKING")))
+		Expect(pod.Spec.Volumes).NotTo(ContainElement(HaveField("Name", "git-known-hosts")))
+	})
+
+	It("mounts the known hosts and checks host keys strictly", func() {
+		ib := newTestImageBuild("git-ssh-known-hosts")
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{GitSSH: &bibv1alpha1.GitSSH{KnownHostsConfigMapName: "git-known-hosts"}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Volumes).To(ContainElement(And(
+			HaveField("Name", "git-known-hosts"),
+			HaveField("ConfigMap.Name", "git-known-hosts"),
+		)))
+		Expect(pod.Spec.Containers[0].VolumeMounts).To(ContainElement(
+			corev1.VolumeMount{Name: "git-known-hosts", MountPath: gitKnownHostsMountPath, ReadOnly: true},
+		))
+		Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+			corev1.EnvVar{Name: "GIT_SSH_KNOWN_HOSTS", Value: gitKnownHostsMountPath + "/known_hosts"},
+			corev1.EnvVar{Name: "GIT_SSH_HOST_KEY_CHECKING", Value: "yes"},
+		))
+
+		By("accepting new hosts on request")
+		ib.Spec.Build.GitSSH.HostKeyChecking = bibv1alpha1.GitHostKeyCheckingAcceptNew
+		pod, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "GIT_SSH_HOST_KEY_CHECKING", Value: "accept-new"}))
+	})
+
+	It("mounts known hosts from a Secret", func() {
+		ib := newTestImageBuild("git-ssh-secret")
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{GitSSH: &bibv1alpha1.GitSSH{KnownHostsSecretName: "git-known-hosts"}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Volumes).To(ContainElement(And(
+			HaveField("Name", "git-known-hosts"),
+			HaveField("Secret.SecretName", "git-known-hosts"),
+		)))
+	})
+
+	It("requires the known hosts to exist", func() {
+		ib := newTestImageBuild("git-ssh-missing")
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{GitSSH: &bibv1alpha1.GitSSH{KnownHostsSecretName: "git-known-hosts"}}
+		r := newFakeReconciler(ib, newTestSecret("git-known-hosts", corev1.SecretTypeOpaque))
+
+		ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.InvalidCredentialsSecretReason))
+		Expect(conditions.GetMessage(ib, bibv1alpha1.BuilderPodReady)).To(ContainSubstring("known_hosts"))
+
+		By("requiring the config map")
+		ib.Spec.Build.GitSSH = &bibv1alpha1.GitSSH{KnownHostsConfigMapName: "git-known-hosts"}
+		ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.ConfigMapNotFoundReason))
+
+		By("requiring the known_hosts key of the config map")
+		knownHosts := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "git-known-hosts", Namespace: "default"},
+			Data:       map[string]string{"ssh_known_hosts": "github.com ssh-ed25519 AAAA"},
+		}
+		r = newFakeReconciler(ib, knownHosts)
+		ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.InvalidConfigMapReason))
+		Expect(conditions.GetMessage(ib, bibv1alpha1.BuilderPodReady)).To(ContainSubstring("known_hosts"))
+
+		By("accepting a config map with the known_hosts key")
+		knownHosts.Data = map[string]string{"known_hosts": "github.com ssh-ed25519 AAAA"}
+		r = newFakeReconciler(ib, knownHosts)
+		ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+	})
+})
+
+var _ = Describe("ImageBuild in a terminating namespace", func() {
+	ctx := context.Background()
+
+	terminatingNamespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Status:     corev1.NamespaceStatus{Phase: corev1.NamespaceTerminating},
+	}
+
+	It("does not try to create the builder pod", func() {
+		ib := newTestImageBuild("doomed")
+		r := newFakeReconciler(ib, terminatingNamespace.DeepCopy())
+		key := types.NamespacedName{Name: "doomed", Namespace: "default"}
+
+		res, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.IsZero()).To(BeTrue())
+
+		pods := &corev1.PodList{}
+		Expect(r.List(ctx, pods)).To(Succeed())
+		Expect(pods.Items).To(BeEmpty())
+
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, updated)).To(Succeed())
+		Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.NamespaceTerminatingReason))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.BuilderPodReady)).To(ContainSubstring("namespace default is terminating"))
+	})
+
+	It("does not try to create the publisher pod", func() {
+		ib := newTestImageBuild("doomed-publish")
+		ib.Spec.Publish = &bibv1alpha1.PublishSpec{MaaS: &bibv1alpha1.MaaSPublishSpec{
+			APIURL: "http://maas.example.com/MAAS", ImageName: "doomed", CredentialsSecretName: "maas-credentials",
+		}}
+		r := newFakeReconciler(ib, terminatingNamespace.DeepCopy())
+
+		_, err := r.reconcilePublish(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		err = r.Get(ctx, types.NamespacedName{Name: publisherPodPrefix + "doomed-publish", Namespace: "default"}, &corev1.Pod{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		Expect(conditions.GetReason(ib, bibv1alpha1.PublishReady)).To(Equal(bibv1alpha1.NamespaceTerminatingReason))
+	})
+})
+
+var _ = Describe("ImageBuild deletion", func() {
+	ctx := context.Background()
+
+	const foreignFinalizer = "backup.example.com/imagebuild"
+
+	// rejectNewFinalizersOnDeletion makes r's client reject new finalizers on ImageBuilds being
+	// deleted, like the API server does and the fake client does not.
+	rejectNewFinalizersOnDeletion := func(r *ImageBuildReconciler) {
+		r.Client = interceptor.NewClient(r.Client.(client.WithWatch), interceptor.Funcs{
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				current := &bibv1alpha1.ImageBuild{}
+				if err := c.Get(ctx, client.ObjectKeyFromObject(obj), current); err != nil {
+					return err
+				}
+				for _, finalizer := range obj.GetFinalizers() {
+					if !current.DeletionTimestamp.IsZero() && !slices.Contains(current.Finalizers, finalizer) {
+						return apierrors.NewForbidden(bibv1alpha1.GroupVersion.WithResource("imagebuilds").GroupResource(), obj.GetName(),
+							errors.New("no new finalizers can be added if the object is being deleted"))
+					}
+				}
+				return c.Update(ctx, obj, opts...)
+			},
+		})
+	}
+
+	// deletedImageBuild returns an ImageBuild being deleted, with the given finalizers keeping it.
+	deletedImageBuild := func(name string, finalizers ...string) *bibv1alpha1.ImageBuild {
+		ib := newTestImageBuild(name)
+		ib.Finalizers = finalizers
+		ib.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		return ib
+	}
+
+	It("only removes its own finalizer and leaves the build to the other finalizers", func() {
+		ib := deletedImageBuild("shared", bibv1alpha1.ImageBuildFinalizer, foreignFinalizer)
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: builderPodPrefix + "shared", Namespace: "default"}}
+		r := newFakeReconciler(ib, pod)
+		rejectNewFinalizersOnDeletion(r)
+
+		_, updated := reconcileBuild(r, "shared")
+		Expect(updated.Finalizers).To(Equal([]string{foreignFinalizer}))
+		err := r.Get(ctx, types.NamespacedName{Name: pod.Name, Namespace: "default"}, &corev1.Pod{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		By("not adding its finalizer back while the other finalizers are pending")
+		_, updated = reconcileBuild(r, "shared")
+		Expect(updated.Finalizers).To(Equal([]string{foreignFinalizer}))
+	})
+
+	It("uses the configured finalizer name", func() {
+		ib := newTestImageBuild("instanced")
+		other := deletedImageBuild("instanced-deleted", "bib-a.example.com/imagebuild", bibv1alpha1.ImageBuildFinalizer)
+		r := newFakeReconciler(ib, other)
+		r.Finalizer = "bib-a.example.com/imagebuild"
+
+		_, updated := reconcileBuild(r, "instanced")
+		Expect(updated.Finalizers).To(Equal([]string{"bib-a.example.com/imagebuild"}))
+
+		By("leaving the finalizer of another instance on deletion")
+		_, updated = reconcileBuild(r, "instanced-deleted")
+		Expect(updated.Finalizers).To(Equal([]string{bibv1alpha1.ImageBuildFinalizer}))
+	})
+})
@@ -0,0 +1,71 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+const (
+	// postStepsAnnotation holds the script of spec.provisioner.postSteps on the builder pod, which
+	// the downward API mounts into the builder container.
+	postStepsAnnotation = "bib.cluster.x-k8s.io/post-steps"
+	// postStepsMountPath is where the post steps script is mounted in the builder container.
+	postStepsMountPath = "/etc/post-steps"
+	// postStepsFile is the file name of the post steps script in postStepsMountPath.
+	postStepsFile = "post-steps.sh"
+)
+
+// postStepsScript returns the shell script running the post steps one after the other. It stops
+// at the first failing step and traces each step to the build log.
+func postStepsScript(steps []string) string {
+	var script strings.Builder
+	script.WriteString("#!/bin/sh\nset -ex\n")
+	for _, step := range steps {
+		script.WriteString(strings.TrimSpace(step))
+		script.WriteString("\n")
+	}
+	return script.String()
+}
+
+// postSteps returns the pod annotations, variables, volumes and mounts handing the post steps of
+// the provisioner to the builder, if any. The script is passed in an annotation rather than an
+// object of its own, so it lives and dies with the builder pod.
+func postSteps(imageBuild *bibv1alpha1.ImageBuild) (map[string]string, []corev1.EnvVar, []corev1.Volume, []corev1.VolumeMount) {
+	if imageBuild.Spec.Provisioner == nil || len(imageBuild.Spec.Provisioner.PostSteps) == 0 {
+		return nil, nil, nil, nil
+	}
+	annotations := map[string]string{postStepsAnnotation: postStepsScript(imageBuild.Spec.Provisioner.PostSteps)}
+	envVars := []corev1.EnvVar{{Name: "POST_STEPS_SCRIPT", Value: postStepsMountPath + "/" + postStepsFile}}
+	mode := int32(0555)
+	volume := corev1.Volume{
+		Name: "post-steps",
+		VolumeSource: corev1.VolumeSource{DownwardAPI: &corev1.DownwardAPIVolumeSource{
+			Items: []corev1.DownwardAPIVolumeFile{{
+				Path:     postStepsFile,
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.annotations['" + postStepsAnnotation + "']"},
+				Mode:     &mode,
+			}},
+		}},
+	}
+	mount := corev1.VolumeMount{Name: "post-steps", MountPath: postStepsMountPath, ReadOnly: true}
+	return annotations, envVars, []corev1.Volume{volume}, []corev1.VolumeMount{mount}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("ImageBuild post steps", func() {
+	ctx := context.Background()
+
+	It("generates a script stopping at the first failing step", func() {
+		Expect(postStepsScript([]string{"truncate -s 0 /etc/machine-id", "  rm -rf /var/log/*.log\n"})).To(Equal(
+			"#!/bin/sh\nset -ex\ntruncate -s 0 /etc/machine-id\nrm -rf /var/log/*.log\n"))
+		Expect(postStepsScript([]string{"for f in /var/log/*.log; do\n  : > \"$f\"\ndone"})).To(Equal(
+			"#!/bin/sh\nset -ex\nfor f in /var/log/*.log; do\n  : > \"$f\"\ndone\n"))
+	})
+
+	It("mounts the script into the builder", func() {
+		ib := newTestImageBuild("post-steps")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Annotations).NotTo(HaveKey(postStepsAnnotation))
+		Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "POST_STEPS_SCRIPT")))
+
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{PostSteps: []string{"truncate -s 0 /etc/machine-id"}}
+		pod, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Annotations).To(HaveKeyWithValue(postStepsAnnotation, "#!/bin/sh\nset -ex\ntruncate -s 0 /etc/machine-id\n"))
+		Expect(pod.Spec.Volumes).To(ContainElement(And(
+			HaveField("Name", "post-steps"),
+			HaveField("DownwardAPI.Items", ConsistOf(And(
+				HaveField("Path", postStepsFile),
+				HaveField("FieldRef.FieldPath", "metadata.annotations['"+postStepsAnnotation+"']"),
+			))),
+		)))
+		Expect(pod.Spec.Containers[0].VolumeMounts).To(ContainElement(
+			corev1.VolumeMount{Name: "post-steps", MountPath: postStepsMountPath, ReadOnly: true},
+		))
+		Expect(pod.Spec.Containers[0].Env).To(ContainElement(
+			corev1.EnvVar{Name: "POST_STEPS_SCRIPT", Value: postStepsMountPath + "/" + postStepsFile},
+		))
+	})
+
+	It("keeps the script when pod metadata sets the same annotation", func() {
+		ib := newTestImageBuild("post-steps-metadata")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{PostSteps: []string{"rm -f /etc/ssh/ssh_host_*"}}
+		ib.Spec.PodMetadata = &bibv1alpha1.PodMetadata{Annotations: map[string]string{postStepsAnnotation: "exit 1"}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Annotations[postStepsAnnotation]).To(ContainSubstring("rm -f /etc/ssh/ssh_host_*"))
+	})
+})
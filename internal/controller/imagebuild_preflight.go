@@ -0,0 +1,535 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+	"github.com/zarcen/bib-operator/internal/scope"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// preflightRequeueAfter is how long to wait before re-running failed preflight checks.
+// Secrets are not watched, so a fixed interval picks up fixes made by the user.
+var preflightRequeueAfter = time.Minute
+
+var (
+	awsCredentialsKeys       = []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"}
+	maasCredentialsKeys      = []string{"MAAS_API_KEY"}
+	dockerConfigJSONKeys     = []string{corev1.DockerConfigJsonKey}
+	sshAuthCredentialsKeys   = []string{corev1.SSHAuthPrivateKey}
+	basicAuthCredentialsKeys = []string{corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey}
+	gitTokenCredentialsKeys  = []string{gitTokenKey}
+)
+
+// secretRequirement describes a Secret referenced by an ImageBuild and the keys it must contain.
+type secretRequirement struct {
+	// field is the spec path of the reference, used in condition messages.
+	field string
+	// name is the name of the referenced Secret.
+	name string
+	// condition is the condition marked false when the Secret is invalid.
+	condition clusterv1beta1.ConditionType
+	// requiredKeys returns the keys the Secret must contain, or an error if the Secret cannot be used at all.
+	requiredKeys func(secret *corev1.Secret) ([]string, error)
+}
+
+// staticKeys returns a requiredKeys func that always requires keys.
+func staticKeys(keys []string) func(*corev1.Secret) ([]string, error) {
+	return func(*corev1.Secret) ([]string, error) { return keys, nil }
+}
+
+// gitCredentialsKeys returns the keys required for a git credentials Secret based on its type.
+func gitCredentialsKeys(secret *corev1.Secret) ([]string, error) {
+	switch secret.Type {
+	case corev1.SecretTypeSSHAuth:
+		return sshAuthCredentialsKeys, nil
+	case corev1.SecretTypeBasicAuth:
+		return basicAuthCredentialsKeys, nil
+	case corev1.SecretTypeOpaque:
+		return gitTokenCredentialsKeys, nil
+	default:
+		return nil, fmt.Errorf("secret %q has type %q, expected %q, %q or %q",
+			secret.Name, secret.Type, corev1.SecretTypeSSHAuth, corev1.SecretTypeBasicAuth, corev1.SecretTypeOpaque)
+	}
+}
+
+// submoduleCredentialsRequirements returns the requirements of the submodule credentials Secrets of a
+// Git repository at fldPath.
+func submoduleCredentialsRequirements(fldPath string, credentials []bibv1alpha1.GitSubmoduleCredentials,
+	condition clusterv1beta1.ConditionType) []secretRequirement {
+	reqs := make([]secretRequirement, 0, len(credentials))
+	for i, creds := range credentials {
+		reqs = append(reqs, secretRequirement{
+			field:        fmt.Sprintf("%s.submoduleCredentials[%d].secretName", fldPath, i),
+			name:         creds.SecretName,
+			condition:    condition,
+			requiredKeys: gitCredentialsKeys,
+		})
+	}
+	return reqs
+}
+
+// secretRequirements lists every Secret referenced by the ImageBuild spec along with its key contract.
+func secretRequirements(imageBuild *bibv1alpha1.ImageBuild) []secretRequirement {
+	spec := imageBuild.Spec
+	var reqs []secretRequirement
+
+	if spec.BaseImagePullSecretName != "" {
+		reqs = append(reqs, secretRequirement{
+			field:        "spec.baseImagePullSecretName",
+			name:         spec.BaseImagePullSecretName,
+			condition:    bibv1alpha1.BaseImageReady,
+			requiredKeys: staticKeys(dockerConfigJSONKeys),
+		})
+	}
+	if spec.Provisioner != nil && spec.Provisioner.Ansible != nil && spec.Provisioner.Ansible.CredentialsSecretName != "" {
+		reqs = append(reqs, secretRequirement{
+			field:        "spec.provisioner.ansible.credentialsSecretName",
+			name:         spec.Provisioner.Ansible.CredentialsSecretName,
+			condition:    bibv1alpha1.ProvisionerReady,
+			requiredKeys: gitCredentialsKeys,
+		})
+	}
+	if spec.Provisioner != nil && spec.Provisioner.Ansible != nil && spec.Provisioner.Ansible.Submodules {
+		reqs = append(reqs, submoduleCredentialsRequirements("spec.provisioner.ansible",
+			spec.Provisioner.Ansible.SubmoduleCredentials, bibv1alpha1.ProvisionerReady)...)
+	}
+	if spec.Provisioner != nil && spec.Provisioner.Dockerfile != nil && spec.Provisioner.Dockerfile.CredentialsSecretName != "" {
+		reqs = append(reqs, secretRequirement{
+			field:        "spec.provisioner.dockerfile.credentialsSecretName",
+			name:         spec.Provisioner.Dockerfile.CredentialsSecretName,
+			condition:    bibv1alpha1.ProvisionerReady,
+			requiredKeys: gitCredentialsKeys,
+		})
+	}
+	if spec.Provisioner != nil && spec.Provisioner.Bootc != nil && spec.Provisioner.Bootc.Containerfile != nil &&
+		spec.Provisioner.Bootc.Containerfile.CredentialsSecretName != "" {
+		reqs = append(reqs, secretRequirement{
+			field:        "spec.provisioner.bootc.containerfile.credentialsSecretName",
+			name:         spec.Provisioner.Bootc.Containerfile.CredentialsSecretName,
+			condition:    bibv1alpha1.ProvisionerReady,
+			requiredKeys: gitCredentialsKeys,
+		})
+	}
+	if spec.Provisioner != nil && spec.Provisioner.Kickstart != nil && spec.Provisioner.Kickstart.CredentialsSecretName != "" {
+		reqs = append(reqs, secretRequirement{
+			field:        "spec.provisioner.kickstart.credentialsSecretName",
+			name:         spec.Provisioner.Kickstart.CredentialsSecretName,
+			condition:    bibv1alpha1.ProvisionerReady,
+			requiredKeys: gitCredentialsKeys,
+		})
+	}
+	if spec.Verify != nil && spec.Verify.Ansible != nil && spec.Verify.Ansible.CredentialsSecretName != "" {
+		reqs = append(reqs, secretRequirement{
+			field:        "spec.verify.ansible.credentialsSecretName",
+			name:         spec.Verify.Ansible.CredentialsSecretName,
+			condition:    bibv1alpha1.VerifyReady,
+			requiredKeys: gitCredentialsKeys,
+		})
+	}
+	if spec.Verify != nil && spec.Verify.Ansible != nil && spec.Verify.Ansible.Submodules {
+		reqs = append(reqs, submoduleCredentialsRequirements("spec.verify.ansible",
+			spec.Verify.Ansible.SubmoduleCredentials, bibv1alpha1.VerifyReady)...)
+	}
+	if spec.Build != nil && spec.Build.LogsOutput != nil {
+		reqs = append(reqs, secretRequirement{
+			field:        "spec.build.logsOutput.credentialsSecretName",
+			name:         spec.Build.LogsOutput.CredentialsSecretName,
+			condition:    bibv1alpha1.BuilderPodReady,
+			requiredKeys: staticKeys(awsCredentialsKeys),
+		})
+	}
+	for i, source := range builderEnvFrom(imageBuild) {
+		if ref := source.SecretRef; ref != nil && (ref.Optional == nil || !*ref.Optional) {
+			reqs = append(reqs, secretRequirement{
+				field:        fmt.Sprintf("spec.build.envFrom[%d].secretRef", i),
+				name:         ref.Name,
+				condition:    bibv1alpha1.BuilderPodReady,
+				requiredKeys: staticKeys(nil),
+			})
+		}
+	}
+	if spec.Build != nil {
+		for i, secretFile := range spec.Build.SecretFiles {
+			reqs = append(reqs, secretRequirement{
+				field:        fmt.Sprintf("spec.build.secretFiles[%d].secretName", i),
+				name:         secretFile.SecretName,
+				condition:    bibv1alpha1.BuilderPodReady,
+				requiredKeys: staticKeys([]string{secretFile.Key}),
+			})
+		}
+	}
+	if spec.Build != nil && spec.Build.GitSSH != nil && spec.Build.GitSSH.KnownHostsSecretName != "" {
+		reqs = append(reqs, secretRequirement{
+			field:        "spec.build.gitSSH.knownHostsSecretName",
+			name:         spec.Build.GitSSH.KnownHostsSecretName,
+			condition:    bibv1alpha1.BuilderPodReady,
+			requiredKeys: staticKeys([]string{gitKnownHostsKey}),
+		})
+	}
+	if spec.Output.ObjectStorage != nil {
+		reqs = append(reqs, secretRequirement{
+			field:        "spec.output.objectStorage.credentialsSecretName",
+			name:         spec.Output.ObjectStorage.CredentialsSecretName,
+			condition:    bibv1alpha1.OutputReady,
+			requiredKeys: staticKeys(awsCredentialsKeys),
+		})
+	}
+	if spec.Output.Registry != nil {
+		reqs = append(reqs, secretRequirement{
+			field:        "spec.output.registry.pullSecretName",
+			name:         spec.Output.Registry.PullSecretName,
+			condition:    bibv1alpha1.OutputReady,
+			requiredKeys: staticKeys(dockerConfigJSONKeys),
+		})
+		for i, destination := range spec.Output.Registry.AdditionalDestinations {
+			if destination.PullSecretName != "" {
+				reqs = append(reqs, secretRequirement{
+					field:        fmt.Sprintf("spec.output.registry.additionalDestinations[%d].pullSecretName", i),
+					name:         destination.PullSecretName,
+					condition:    bibv1alpha1.OutputReady,
+					requiredKeys: staticKeys(dockerConfigJSONKeys),
+				})
+			}
+		}
+		if spec.Output.Registry.RepositoryCredentialsSecretName != "" {
+			reqs = append(reqs, secretRequirement{
+				field:        "spec.output.registry.repositoryCredentialsSecretName",
+				name:         spec.Output.Registry.RepositoryCredentialsSecretName,
+				condition:    bibv1alpha1.OutputReady,
+				requiredKeys: staticKeys(awsCredentialsKeys),
+			})
+		}
+	}
+	if spec.Publish != nil && spec.Publish.AWS != nil {
+		reqs = append(reqs, secretRequirement{
+			field:        "spec.publish.aws.credentialsSecretName",
+			name:         spec.Publish.AWS.CredentialsSecretName,
+			condition:    bibv1alpha1.PublishReady,
+			requiredKeys: staticKeys(awsCredentialsKeys),
+		})
+	}
+	if spec.Publish != nil && spec.Publish.MaaS != nil {
+		reqs = append(reqs, secretRequirement{
+			field:        "spec.publish.maas.credentialsSecretName",
+			name:         spec.Publish.MaaS.CredentialsSecretName,
+			condition:    bibv1alpha1.PublishReady,
+			requiredKeys: staticKeys(maasCredentialsKeys),
+		})
+	}
+	return reqs
+}
+
+// configMapRequirement describes a ConfigMap referenced by an ImageBuild that must exist.
+type configMapRequirement struct {
+	// field is the spec path of the reference, used in condition messages.
+	field string
+	// name is the name of the referenced ConfigMap.
+	name string
+	// condition is the condition marked false when the ConfigMap is missing or invalid.
+	condition clusterv1beta1.ConditionType
+	// requiredKeys are the keys the ConfigMap must contain.
+	requiredKeys []string
+}
+
+// configMapRequirements lists every non-optional ConfigMap referenced by the ImageBuild spec.
+func configMapRequirements(imageBuild *bibv1alpha1.ImageBuild) []configMapRequirement {
+	var reqs []configMapRequirement
+	for i, source := range builderEnvFrom(imageBuild) {
+		if ref := source.ConfigMapRef; ref != nil && (ref.Optional == nil || !*ref.Optional) {
+			reqs = append(reqs, configMapRequirement{
+				field:     fmt.Sprintf("spec.build.envFrom[%d].configMapRef", i),
+				name:      ref.Name,
+				condition: bibv1alpha1.BuilderPodReady,
+			})
+		}
+	}
+	if provisioner := imageBuild.Spec.Provisioner; provisioner != nil && provisioner.Dockerfile != nil && provisioner.Dockerfile.ConfigMapRef != nil {
+		reqs = append(reqs, configMapRequirement{
+			field:     "spec.provisioner.dockerfile.configMapRef",
+			name:      provisioner.Dockerfile.ConfigMapRef.Name,
+			condition: bibv1alpha1.ProvisionerReady,
+		})
+	}
+	if provisioner := imageBuild.Spec.Provisioner; provisioner != nil && provisioner.Bootc != nil &&
+		provisioner.Bootc.Containerfile != nil && provisioner.Bootc.Containerfile.ConfigMapRef != nil {
+		reqs = append(reqs, configMapRequirement{
+			field:     "spec.provisioner.bootc.containerfile.configMapRef",
+			name:      provisioner.Bootc.Containerfile.ConfigMapRef.Name,
+			condition: bibv1alpha1.ProvisionerReady,
+		})
+	}
+	if provisioner := imageBuild.Spec.Provisioner; provisioner != nil && provisioner.Kickstart != nil && provisioner.Kickstart.ConfigMapRef != nil {
+		reqs = append(reqs, configMapRequirement{
+			field:     "spec.provisioner.kickstart.configMapRef",
+			name:      provisioner.Kickstart.ConfigMapRef.Name,
+			condition: bibv1alpha1.ProvisionerReady,
+		})
+	}
+	if build := imageBuild.Spec.Build; build != nil && build.PackageMirrors != nil && build.PackageMirrors.ConfigMapName != "" {
+		reqs = append(reqs, configMapRequirement{
+			field:     "spec.build.packageMirrors.configMapName",
+			name:      build.PackageMirrors.ConfigMapName,
+			condition: bibv1alpha1.BuilderPodReady,
+		})
+	}
+	if build := imageBuild.Spec.Build; build != nil && build.GitSSH != nil && build.GitSSH.KnownHostsConfigMapName != "" {
+		reqs = append(reqs, configMapRequirement{
+			field:        "spec.build.gitSSH.knownHostsConfigMapName",
+			name:         build.GitSSH.KnownHostsConfigMapName,
+			condition:    bibv1alpha1.BuilderPodReady,
+			requiredKeys: []string{gitKnownHostsKey},
+		})
+	}
+	return reqs
+}
+
+// missingKeys returns the sorted list of keys absent from the Secret.
+func missingKeys(secret *corev1.Secret, keys []string) []string {
+	var missing []string
+	for _, key := range keys {
+		if _, ok := secret.Data[key]; ok {
+			continue
+		}
+		if _, ok := secret.StringData[key]; ok {
+			continue
+		}
+		missing = append(missing, key)
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// missingConfigMapKeys returns the sorted list of keys absent from the ConfigMap.
+func missingConfigMapKeys(configMap *corev1.ConfigMap, keys []string) []string {
+	var missing []string
+	for _, key := range keys {
+		if _, ok := configMap.Data[key]; ok {
+			continue
+		}
+		if _, ok := configMap.BinaryData[key]; ok {
+			continue
+		}
+		missing = append(missing, key)
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// conditionForField returns the condition that reports problems with the given spec field.
+func conditionForField(fieldPath string) clusterv1beta1.ConditionType {
+	switch {
+	case strings.HasPrefix(fieldPath, "spec.publish"):
+		return bibv1alpha1.PublishReady
+	case strings.HasPrefix(fieldPath, "spec.output"):
+		return bibv1alpha1.OutputReady
+	case strings.HasPrefix(fieldPath, "spec.provisioner"):
+		return bibv1alpha1.ProvisionerReady
+	case strings.HasPrefix(fieldPath, "spec.verify"):
+		return bibv1alpha1.VerifyReady
+	case strings.HasPrefix(fieldPath, "spec.signing"):
+		return bibv1alpha1.SigningReady
+	case strings.HasPrefix(fieldPath, "spec.baseImage"), strings.HasPrefix(fieldPath, "spec.arch"):
+		return bibv1alpha1.BaseImageReady
+	default:
+		return bibv1alpha1.BuilderPodReady
+	}
+}
+
+// reconcilePreflight validates the ImageBuild spec and the Secrets it references before any pod is created.
+// It returns false if a check failed, in which case the relevant condition has been marked false.
+func (r *ImageBuildReconciler) reconcilePreflight(ctx context.Context, ibs *scope.ImageBuildScope) (bool, error) {
+	logger := log.FromContext(ctx)
+	imageBuild := ibs.ImageBuild
+
+	failed := map[clusterv1beta1.ConditionType]bool{}
+	for _, fieldErr := range imageBuild.ValidateSpec() {
+		conditionType := conditionForField(fieldErr.Field)
+		if failed[conditionType] {
+			continue
+		}
+		logger.Info("ImageBuild spec is invalid", "Field", fieldErr.Field, "Error", fieldErr.Error())
+		conditions.MarkFalse(imageBuild, conditionType, bibv1alpha1.InvalidSpecReason, clusterv1beta1.ConditionSeverityError,
+			"%s", fieldErr.Error())
+		failed[conditionType] = true
+	}
+
+	if imageBuild.Spec.Build != nil && imageBuild.Spec.Build.HostNetwork && !r.AllowHostNetwork && !failed[bibv1alpha1.BuilderPodReady] {
+		logger.Info("Host networking requested but not allowed by the controller")
+		conditions.MarkFalse(imageBuild, bibv1alpha1.BuilderPodReady, bibv1alpha1.HostNetworkNotAllowedReason, clusterv1beta1.ConditionSeverityError,
+			"spec.build.hostNetwork requires the controller to run with --allow-host-network")
+		failed[bibv1alpha1.BuilderPodReady] = true
+	}
+
+	if r.ForbidPrivileged && !slices.Contains(r.PrivilegedNamespaces, imageBuild.Namespace) && !failed[bibv1alpha1.BuilderPodReady] {
+		logger.Info("Privileged builder pods are forbidden in the namespace")
+		conditions.MarkFalse(imageBuild, bibv1alpha1.BuilderPodReady, bibv1alpha1.PrivilegedNotAllowedReason, clusterv1beta1.ConditionSeverityError,
+			"the builder pod runs privileged, which the controller forbids in namespace %s; it must be listed in --privileged-namespaces",
+			imageBuild.Namespace)
+		failed[bibv1alpha1.BuilderPodReady] = true
+	}
+
+	if field := buildNamespaceClaimField(imageBuild); r.BuildNamespaces && field != "" && !failed[bibv1alpha1.BuilderPodReady] {
+		logger.Info("PersistentVolumeClaims cannot be mounted in build namespaces", "Field", field)
+		conditions.MarkFalse(imageBuild, bibv1alpha1.BuilderPodReady, bibv1alpha1.BuildNamespacesNotSupportedReason, clusterv1beta1.ConditionSeverityError,
+			"%s mounts a PersistentVolumeClaim, which the builder pod cannot do from its build namespace with --build-namespaces; use spec.output.volume or spec.output.objectStorage instead",
+			field)
+		failed[bibv1alpha1.BuilderPodReady] = true
+	}
+
+	if registry := imageBuild.Spec.Output.Registry; registry != nil && registry.CreateRepository && !r.AllowRepositoryCreation && !failed[bibv1alpha1.OutputReady] {
+		logger.Info("Repository creation requested but not allowed by the controller")
+		conditions.MarkFalse(imageBuild, bibv1alpha1.OutputReady, bibv1alpha1.RepositoryCreationNotAllowedReason, clusterv1beta1.ConditionSeverityError,
+			"spec.output.registry.createRepository requires the controller to run with --allow-repository-creation")
+		failed[bibv1alpha1.OutputReady] = true
+	}
+
+	for _, req := range secretRequirements(imageBuild) {
+		if failed[req.condition] {
+			continue
+		}
+		secret := &corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Name: req.name, Namespace: imageBuild.Namespace}, secret)
+		if apierrors.IsNotFound(err) {
+			logger.Info("Referenced secret not found", "Field", req.field, "Secret", req.name)
+			conditions.MarkFalse(imageBuild, req.condition, bibv1alpha1.SecretNotFoundReason, clusterv1beta1.ConditionSeverityError,
+				"secret %q referenced by %s not found", req.name, req.field)
+			failed[req.condition] = true
+			continue
+		} else if err != nil {
+			return false, err
+		}
+
+		keys, err := req.requiredKeys(secret)
+		if err != nil {
+			conditions.MarkFalse(imageBuild, req.condition, bibv1alpha1.InvalidCredentialsSecretReason, clusterv1beta1.ConditionSeverityError,
+				"%s referenced by %s", err.Error(), req.field)
+			failed[req.condition] = true
+			continue
+		}
+		if missing := missingKeys(secret, keys); len(missing) > 0 {
+			logger.Info("Referenced secret is missing required keys", "Field", req.field, "Secret", req.name, "MissingKeys", missing)
+			conditions.MarkFalse(imageBuild, req.condition, bibv1alpha1.InvalidCredentialsSecretReason, clusterv1beta1.ConditionSeverityError,
+				"secret %q referenced by %s is missing required keys: %s", req.name, req.field, strings.Join(missing, ", "))
+			failed[req.condition] = true
+		}
+	}
+
+	for _, req := range configMapRequirements(imageBuild) {
+		if failed[req.condition] {
+			continue
+		}
+		configMap := &corev1.ConfigMap{}
+		err := r.Get(ctx, types.NamespacedName{Name: req.name, Namespace: imageBuild.Namespace}, configMap)
+		if apierrors.IsNotFound(err) {
+			logger.Info("Referenced config map not found", "Field", req.field, "ConfigMap", req.name)
+			conditions.MarkFalse(imageBuild, req.condition, bibv1alpha1.ConfigMapNotFoundReason, clusterv1beta1.ConditionSeverityError,
+				"config map %q referenced by %s not found", req.name, req.field)
+			failed[req.condition] = true
+			continue
+		} else if err != nil {
+			return false, err
+		}
+		if missing := missingConfigMapKeys(configMap, req.requiredKeys); len(missing) > 0 {
+			logger.Info("Referenced config map is missing required keys", "Field", req.field, "ConfigMap", req.name, "MissingKeys", missing)
+			conditions.MarkFalse(imageBuild, req.condition, bibv1alpha1.InvalidConfigMapReason, clusterv1beta1.ConditionSeverityError,
+				"config map %q referenced by %s is missing required keys: %s", req.name, req.field, strings.Join(missing, ", "))
+			failed[req.condition] = true
+		}
+	}
+
+	if !failed[bibv1alpha1.BaseImageReady] {
+		ok, err := r.reconcileBaseImageBuild(ctx, imageBuild)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			failed[bibv1alpha1.BaseImageReady] = true
+		}
+	}
+
+	if !failed[bibv1alpha1.BaseImageReady] {
+		ok, err := r.reconcileBaseImageArch(ctx, imageBuild)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			failed[bibv1alpha1.BaseImageReady] = true
+		}
+	}
+
+	// Reset conditions left over from an earlier failed preflight once the referenced Secrets are fixed.
+	for _, conditionType := range bibv1alpha1.ImageBuildConditionTypes {
+		if failed[conditionType] {
+			continue
+		}
+		if preflightReasons[conditions.GetReason(imageBuild, conditionType)] {
+			conditions.MarkUnknown(imageBuild, conditionType, "Initializing", "Unknown")
+		}
+	}
+
+	return len(failed) == 0, nil
+}
+
+// preflightReasons are the condition reasons set by a failed preflight check.
+var preflightReasons = map[string]bool{
+	bibv1alpha1.InvalidSpecReason:                  true,
+	bibv1alpha1.SecretNotFoundReason:               true,
+	bibv1alpha1.InvalidCredentialsSecretReason:     true,
+	bibv1alpha1.HostNetworkNotAllowedReason:        true,
+	bibv1alpha1.PrivilegedNotAllowedReason:         true,
+	bibv1alpha1.BuildNamespacesNotSupportedReason:  true,
+	bibv1alpha1.ConfigMapNotFoundReason:            true,
+	bibv1alpha1.InvalidConfigMapReason:             true,
+	bibv1alpha1.BaseImageArchMismatchReason:        true,
+	bibv1alpha1.BaseImageBuildPendingReason:        true,
+	bibv1alpha1.BaseImageBuildFailedReason:         true,
+	bibv1alpha1.InvalidBaseImageBuildReason:        true,
+	bibv1alpha1.RepositoryCreationNotAllowedReason: true,
+}
+
+// resetStaleConditions marks the conditions that failed against an earlier generation of the spec,
+// in a preflight check or creating the builder pod, Unknown again once the spec has changed, so
+// they do not linger until re-evaluated against the new one. It returns true if the spec changed
+// since the status was last reconciled, and records the generation as observed. Finished builds
+// keep their conditions and observed generation, as they describe the spec the build ran with.
+func resetStaleConditions(imageBuild *bibv1alpha1.ImageBuild) bool {
+	if imageBuild.Status.ObservedGeneration == imageBuild.Generation || isTerminalPhase(imageBuild.Status.Phase) {
+		return false
+	}
+	imageBuild.Status.ObservedGeneration = imageBuild.Generation
+	for _, conditionType := range bibv1alpha1.ImageBuildConditionTypes {
+		reason := conditions.GetReason(imageBuild, conditionType)
+		if conditions.IsFalse(imageBuild, conditionType) && (preflightReasons[reason] || reason == bibv1alpha1.PodCreationFailedReason) {
+			conditions.MarkUnknown(imageBuild, conditionType, "Initializing", "Unknown")
+		}
+	}
+	return true
+}
@@ -0,0 +1,392 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+	"github.com/zarcen/bib-operator/internal/registry"
+)
+
+var _ = Describe("ImageBuild preflight checks", func() {
+	ctx := context.Background()
+
+	withAWSPublish := func(ib *bibv1alpha1.ImageBuild) *bibv1alpha1.ImageBuild {
+		ib.Spec.Publish = &bibv1alpha1.PublishSpec{AWS: &bibv1alpha1.AWSPublishSpec{
+			Region: "us-east-1", AMIName: "ami", InstanceType: "t3.small", SourceS3Bucket: "staging",
+			CredentialsSecretName: "aws-credentials",
+		}}
+		return ib
+	}
+
+	It("lists the keys missing from an AWS credentials secret", func() {
+		ib := withAWSPublish(newTestImageBuild("missing-keys"))
+		r := newFakeReconciler(ib, newTestSecret("aws-credentials", corev1.SecretTypeOpaque, "AWS_ACCESS_KEY_ID"))
+
+		ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.IsFalse(ib, bibv1alpha1.PublishReady)).To(BeTrue())
+		Expect(conditions.GetReason(ib, bibv1alpha1.PublishReady)).To(Equal(bibv1alpha1.InvalidCredentialsSecretReason))
+		Expect(conditions.GetMessage(ib, bibv1alpha1.PublishReady)).To(ContainSubstring("AWS_SECRET_ACCESS_KEY"))
+		Expect(conditions.GetMessage(ib, bibv1alpha1.PublishReady)).NotTo(ContainSubstring("AWS_ACCESS_KEY_ID"))
+	})
+
+	It("reports a referenced secret that does not exist", func() {
+		ib := newTestImageBuild("missing-secret")
+		ib.Spec.BaseImagePullSecretName = "pull-secret"
+		r := newFakeReconciler(ib)
+
+		ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.BaseImageReady)).To(Equal(bibv1alpha1.SecretNotFoundReason))
+	})
+
+	It("validates git credentials secrets by type", func() {
+		ib := newTestImageBuild("git-credentials")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Ansible: &bibv1alpha1.AnsibleSpec{
+			Repo: "git@example.com:org/repo.git", Playbook: "site.yml", CredentialsSecretName: "git-credentials",
+		}}
+
+		By("rejecting an ssh-auth secret without a private key")
+		r := newFakeReconciler(ib, newTestSecret("git-credentials", corev1.SecretTypeSSHAuth))
+		ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetMessage(ib, bibv1alpha1.ProvisionerReady)).To(ContainSubstring(corev1.SSHAuthPrivateKey))
+
+		By("rejecting an unsupported secret type")
+		r = newFakeReconciler(ib, newTestSecret("git-credentials", corev1.SecretTypeDockerConfigJson, corev1.DockerConfigJsonKey))
+		ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.ProvisionerReady)).To(Equal(bibv1alpha1.InvalidCredentialsSecretReason))
+
+		By("accepting a complete basic-auth secret and clearing the stale condition")
+		r = newFakeReconciler(ib, newTestSecret("git-credentials", corev1.SecretTypeBasicAuth,
+			corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey))
+		ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(conditions.IsUnknown(ib, bibv1alpha1.ProvisionerReady)).To(BeTrue())
+
+		By("rejecting an opaque secret without a token")
+		r = newFakeReconciler(ib, newTestSecret("git-credentials", corev1.SecretTypeOpaque, corev1.BasicAuthUsernameKey))
+		ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetMessage(ib, bibv1alpha1.ProvisionerReady)).To(ContainSubstring(gitTokenKey))
+
+		By("accepting an opaque secret with an HTTPS access token")
+		r = newFakeReconciler(ib, newTestSecret("git-credentials", corev1.SecretTypeOpaque, gitTokenKey))
+		ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+	})
+
+	It("rejects AWS tags that exceed the AWS limits", func() {
+		ib := withAWSPublish(newTestImageBuild("bad-tags"))
+		ib.Spec.Publish.AWS.Tags = map[string]string{"aws:reserved": "x"}
+		r := newFakeReconciler(ib, newTestSecret("aws-credentials", corev1.SecretTypeOpaque, awsCredentialsKeys...))
+
+		ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.PublishReady)).To(Equal(bibv1alpha1.InvalidSpecReason))
+		Expect(conditions.GetMessage(ib, bibv1alpha1.PublishReady)).To(ContainSubstring("spec.publish.aws.tags"))
+
+		By("accepting tags within the limits")
+		ib.Spec.Publish.AWS.Tags = map[string]string{"team": strings.Repeat("v", 256)}
+		ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+	})
+
+	It("rejects an AWS architecture that does not match spec.arch", func() {
+		ib := withAWSPublish(newTestImageBuild("arch-mismatch"))
+		ib.Spec.Publish.AWS.Architecture = "arm64"
+		r := newFakeReconciler(ib, newTestSecret("aws-credentials", corev1.SecretTypeOpaque, awsCredentialsKeys...))
+
+		ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.PublishReady)).To(Equal(bibv1alpha1.InvalidSpecReason))
+		Expect(conditions.GetMessage(ib, bibv1alpha1.PublishReady)).To(ContainSubstring("spec.publish.aws.architecture"))
+	})
+
+	It("rejects legacy BIOS boot for arm64 AMIs", func() {
+		ib := withAWSPublish(newTestImageBuild("arm-bios"))
+		ib.Spec.Architecture = "arm64"
+		ib.Spec.Publish.AWS.BootMode = bibv1alpha1.AWSBootModeLegacyBIOS
+		r := newFakeReconciler(ib, newTestSecret("aws-credentials", corev1.SecretTypeOpaque, awsCredentialsKeys...))
+
+		ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.PublishReady)).To(Equal(bibv1alpha1.InvalidSpecReason))
+		Expect(conditions.GetMessage(ib, bibv1alpha1.PublishReady)).To(ContainSubstring("spec.publish.aws.bootMode"))
+	})
+
+	It("rejects an invalid base image reference", func() {
+		ib := newTestImageBuild("bad-base-image")
+		ib.Spec.BaseImage = "Ubuntu:24.04"
+		r := newFakeReconciler(ib)
+
+		ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.BaseImageReady)).To(Equal(bibv1alpha1.InvalidSpecReason))
+		Expect(conditions.GetMessage(ib, bibv1alpha1.BaseImageReady)).To(ContainSubstring("must be lowercase"))
+	})
+
+	It("requires the non-optional spec.build.envFrom sources to exist", func() {
+		optional := true
+		ib := newTestImageBuild("env-from")
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{EnvFrom: []corev1.EnvFromSource{
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "proxy-settings"}}},
+			{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "mirror-credentials"}}},
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "extras"}, Optional: &optional}},
+		}}
+
+		By("reporting a missing config map")
+		r := newFakeReconciler(ib, newTestSecret("mirror-credentials", corev1.SecretTypeOpaque))
+		ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.ConfigMapNotFoundReason))
+		Expect(conditions.GetMessage(ib, bibv1alpha1.BuilderPodReady)).To(ContainSubstring("spec.build.envFrom[0].configMapRef"))
+
+		By("reporting a missing secret")
+		proxySettings := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "proxy-settings", Namespace: "default"}}
+		r = newFakeReconciler(ib, proxySettings)
+		ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.SecretNotFoundReason))
+
+		By("ignoring missing optional sources")
+		r = newFakeReconciler(ib, proxySettings, newTestSecret("mirror-credentials", corev1.SecretTypeOpaque))
+		ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(conditions.IsUnknown(ib, bibv1alpha1.BuilderPodReady)).To(BeTrue())
+	})
+
+	It("requires the Secrets and keys of spec.build.secretFiles", func() {
+		ib := newTestImageBuild("secret-files")
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{SecretFiles: []bibv1alpha1.SecretFileMount{
+			{SecretName: "vault", Key: "token", Path: "/run/secrets/vault-token"},
+		}}
+
+		By("reporting a missing secret")
+		r := newFakeReconciler(ib)
+		ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.SecretNotFoundReason))
+		Expect(conditions.GetMessage(ib, bibv1alpha1.BuilderPodReady)).To(ContainSubstring("spec.build.secretFiles[0].secretName"))
+
+		By("reporting a missing key")
+		r = newFakeReconciler(ib, newTestSecret("vault", corev1.SecretTypeOpaque, "role-id"))
+		ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetMessage(ib, bibv1alpha1.BuilderPodReady)).To(ContainSubstring("token"))
+
+		By("passing once the key exists")
+		r = newFakeReconciler(ib, newTestSecret("vault", corev1.SecretTypeOpaque, "token"))
+		ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+	})
+
+	Describe("base image architecture", func() {
+		// withPlatforms makes the reconciler report the base image as available for platforms,
+		// recording the pull credentials it was inspected with.
+		withPlatforms := func(r *ImageBuildReconciler, dockerConfigJSON *[]byte, platforms ...registry.Platform) *ImageBuildReconciler {
+			r.ImagePlatforms = func(_ context.Context, _ string, config []byte) ([]registry.Platform, error) {
+				if dockerConfigJSON != nil {
+					*dockerConfigJSON = config
+				}
+				return platforms, nil
+			}
+			return r
+		}
+
+		It("rejects a base image that is not available for spec.arch", func() {
+			ib := newTestImageBuild("arch-mismatch")
+			ib.Spec.Architecture = "arm64"
+			r := withPlatforms(newFakeReconciler(ib), nil, registry.Platform{OS: "linux", Architecture: "amd64"})
+
+			ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+			Expect(conditions.GetReason(ib, bibv1alpha1.BaseImageReady)).To(Equal(bibv1alpha1.BaseImageArchMismatchReason))
+			Expect(conditions.GetMessage(ib, bibv1alpha1.BaseImageReady)).To(Equal(
+				"base image ubuntu:24.04 is not available for linux/arm64, it is available for linux/amd64"))
+
+			By("clearing the condition once the base image is fixed")
+			r = withPlatforms(r, nil, registry.Platform{OS: "linux", Architecture: "amd64"}, registry.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"})
+			ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(conditions.IsUnknown(ib, bibv1alpha1.BaseImageReady)).To(BeTrue())
+		})
+
+		It("inspects the base image with its pull secret", func() {
+			ib := newTestImageBuild("arch-pull-secret")
+			ib.Spec.BaseImagePullSecretName = "pull-secret"
+			var dockerConfigJSON []byte
+			r := withPlatforms(newFakeReconciler(ib, newTestSecret("pull-secret", corev1.SecretTypeDockerConfigJson, corev1.DockerConfigJsonKey)),
+				&dockerConfigJSON, registry.Platform{OS: "linux", Architecture: "amd64"})
+
+			ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(string(dockerConfigJSON)).To(Equal("value"))
+		})
+
+		It("skips the check when the base image cannot be inspected", func() {
+			ib := newTestImageBuild("arch-unreachable")
+			ib.Spec.Architecture = "arm64"
+			r := newFakeReconciler(ib)
+			r.ImagePlatforms = func(context.Context, string, []byte) ([]registry.Platform, error) {
+				return nil, errors.New("connection refused")
+			}
+
+			ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+		})
+	})
+
+	Describe("base ImageBuild", func() {
+		// newLayeredBuild returns an ImageBuild building on the ImageBuild named base.
+		newLayeredBuild := func(name, base string) *bibv1alpha1.ImageBuild {
+			ib := newTestImageBuild(name)
+			ib.Spec.BaseImage = ""
+			ib.Spec.BaseImageFrom = &bibv1alpha1.ImageBuildReference{Name: base}
+			return ib
+		}
+
+		It("waits for the base ImageBuild to exist and succeed", func() {
+			ib := newLayeredBuild("app", "base")
+			r := newFakeReconciler(ib)
+
+			ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+			Expect(conditions.GetReason(ib, bibv1alpha1.BaseImageReady)).To(Equal(bibv1alpha1.BaseImageBuildPendingReason))
+			Expect(conditions.GetMessage(ib, bibv1alpha1.BaseImageReady)).To(Equal("waiting for ImageBuild base to be created"))
+
+			base := newTestImageBuild("base")
+			base.Status.Phase = bibv1alpha1.PhaseBuilding
+			Expect(r.Create(ctx, base)).To(Succeed())
+			ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+			Expect(conditions.GetMessage(ib, bibv1alpha1.BaseImageReady)).To(Equal("waiting for ImageBuild base to succeed"))
+			Expect(r.dependentImageBuilds(ctx, base)).To(ConsistOf(
+				reconcile.Request{NamespacedName: types.NamespacedName{Name: "app", Namespace: "default"}}))
+		})
+
+		It("builds on the image pushed by the base ImageBuild, by digest", func() {
+			base := newTestImageBuild("base")
+			base.Status.Phase = bibv1alpha1.PhaseSucceeded
+			base.Status.OutputURLs = []string{"pvc://artifacts/default/base", "docker://registry.example.com/base:v1"}
+			base.Status.Artifacts = []bibv1alpha1.ArtifactStatus{
+				{Name: "disk.qcow2", Format: bibv1alpha1.FormatQCOW2},
+				{Name: "registry.example.com/base:v1", Format: bibv1alpha1.FormatOCI, Checksum: "sha256:0123"},
+			}
+			ib := newLayeredBuild("app", "base")
+			r := newFakeReconciler(ib, base)
+
+			ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			pod, err := r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(
+				corev1.EnvVar{Name: "BASE_IMAGE", Value: "registry.example.com/base:v1@sha256:0123"}))
+
+			By("falling back to the pushed tag without a reported digest")
+			base.Status.Artifacts = nil
+			Expect(pushedImage(base)).To(Equal("registry.example.com/base:v1"))
+		})
+
+		It("rejects a base ImageBuild that failed or pushed no image", func() {
+			base := newTestImageBuild("base")
+			base.Status.Phase = bibv1alpha1.PhaseFailed
+			ib := newLayeredBuild("app", "base")
+			r := newFakeReconciler(ib, base)
+
+			ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+			Expect(conditions.GetReason(ib, bibv1alpha1.BaseImageReady)).To(Equal(bibv1alpha1.BaseImageBuildFailedReason))
+
+			base.Status.Phase = bibv1alpha1.PhaseSucceeded
+			Expect(r.Status().Update(ctx, base)).To(Succeed())
+			ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+			Expect(conditions.GetReason(ib, bibv1alpha1.BaseImageReady)).To(Equal(bibv1alpha1.InvalidBaseImageBuildReason))
+			Expect(r.dependentImageBuilds(ctx, base)).To(ConsistOf(
+				reconcile.Request{NamespacedName: types.NamespacedName{Name: "app", Namespace: "default"}}))
+		})
+
+		It("rejects ImageBuilds building on each other", func() {
+			a, b := newLayeredBuild("a", "b"), newLayeredBuild("b", "a")
+			r := newFakeReconciler(a, b)
+
+			ok, err := r.reconcilePreflight(ctx, newTestScope(r, a))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+			Expect(conditions.GetReason(a, bibv1alpha1.BaseImageReady)).To(Equal(bibv1alpha1.InvalidBaseImageBuildReason))
+			Expect(conditions.GetMessage(a, bibv1alpha1.BaseImageReady)).To(Equal("ImageBuild b builds on ImageBuild a, which is a cycle"))
+		})
+	})
+
+	It("does not create the builder pod while preflight fails", func() {
+		ib := withAWSPublish(newTestImageBuild("blocked"))
+		r := newFakeReconciler(ib)
+
+		res, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "blocked", Namespace: "default"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(preflightRequeueAfter))
+
+		err = r.Get(ctx, types.NamespacedName{Name: builderPodPrefix + "blocked", Namespace: "default"}, &corev1.Pod{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: "blocked", Namespace: "default"}, updated)).To(Succeed())
+		Expect(conditions.GetReason(updated, bibv1alpha1.PublishReady)).To(Equal(bibv1alpha1.SecretNotFoundReason))
+	})
+})
@@ -0,0 +1,150 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+//+kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch;create;update;patch
+
+// builderPrePullName is the name of the DaemonSet keeping the builder image pulled, and of its container.
+const builderPrePullName = "bib-builder-prepull"
+
+// builderPrePullResyncPeriod is how often the pre-pull DaemonSet is checked, so that it is recreated
+// if deleted and follows a changed builder image.
+var builderPrePullResyncPeriod = 10 * time.Minute
+
+// ParseNodeSelector parses a comma-separated list of "<label>=<value>" pairs, e.g.
+// "bib.cluster.x-k8s.io/build-node=true", into a node selector.
+func ParseNodeSelector(value string) (map[string]string, error) {
+	selector, err := labels.ConvertSelectorToLabelsMap(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node selector %q, expected <label>=<value> pairs: %w", value, err)
+	}
+	return selector, nil
+}
+
+// BuilderPrePuller keeps the builder image pulled on the build nodes with a DaemonSet, so builder
+// pods scheduled there start without waiting for the image pull. It runs with the manager.
+type BuilderPrePuller struct {
+	client.Client
+
+	// BuilderImage is the image kept pulled.
+	BuilderImage string
+
+	// Namespace is where the DaemonSet is created, usually the namespace of the operator.
+	Namespace string
+
+	// NodeSelector selects the build nodes the image is pulled on.
+	NodeSelector map[string]string
+}
+
+// Start keeps the DaemonSet in place until ctx is done.
+func (p *BuilderPrePuller) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithValues("DaemonSet", builderPrePullName, "Namespace", p.Namespace)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := p.ensureDaemonSet(ctx); err != nil {
+			logger.Error(err, "Failed to ensure the builder image pre-pull DaemonSet")
+		}
+	}, builderPrePullResyncPeriod)
+	return nil
+}
+
+// ensureDaemonSet creates or updates the DaemonSet running the builder image on the build nodes.
+// Its container only sleeps; being scheduled is what keeps the image on the node.
+func (p *BuilderPrePuller) ensureDaemonSet(ctx context.Context) error {
+	daemonSet := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: builderPrePullName, Namespace: p.Namespace}}
+	selectorLabels := map[string]string{
+		"app.kubernetes.io/name":      builderPrePullName,
+		"app.kubernetes.io/component": "builder-prepull",
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, p.Client, daemonSet, func() error {
+		daemonSet.Labels = maps.Clone(selectorLabels)
+		// The selector is immutable, and never changes.
+		if daemonSet.Spec.Selector == nil {
+			daemonSet.Spec.Selector = &metav1.LabelSelector{MatchLabels: maps.Clone(selectorLabels)}
+		}
+		runAsNonRoot, allowPrivilegeEscalation := true, false
+		runAsUser := int64(65534)
+		daemonSet.Spec.Template = corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: maps.Clone(selectorLabels)},
+			Spec: corev1.PodSpec{
+				NodeSelector: p.NodeSelector,
+				// Build nodes are often tainted for builds only.
+				Tolerations: []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+				SecurityContext: &corev1.PodSecurityContext{
+					RunAsNonRoot:   &runAsNonRoot,
+					RunAsUser:      &runAsUser,
+					SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+				},
+				Containers: []corev1.Container{{
+					Name:    builderPrePullName,
+					Image:   p.BuilderImage,
+					Command: []string{"/bin/sh", "-c", `trap "exit 0" TERM; while :; do sleep 3600 & wait $!; done`},
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("1m"),
+							corev1.ResourceMemory: resource.MustParse("8Mi"),
+						},
+						Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("16Mi")},
+					},
+					SecurityContext: &corev1.SecurityContext{
+						AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+						Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+					},
+				}},
+			},
+		}
+		return nil
+	})
+	return err
+}
+
+// prePullAffinity returns the affinity preferring the build nodes the builder image is pre-pulled
+// on, or nil without pre-pulling.
+func (r *ImageBuildReconciler) prePullAffinity() *corev1.Affinity {
+	if len(r.PrePullNodeSelector) == 0 {
+		return nil
+	}
+	var requirements []corev1.NodeSelectorRequirement
+	for _, key := range slices.Sorted(maps.Keys(r.PrePullNodeSelector)) {
+		requirements = append(requirements, corev1.NodeSelectorRequirement{
+			Key: key, Operator: corev1.NodeSelectorOpIn, Values: []string{r.PrePullNodeSelector[key]},
+		})
+	}
+	return &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{{
+			Weight:     100,
+			Preference: corev1.NodeSelectorTerm{MatchExpressions: requirements},
+		}},
+	}}
+}
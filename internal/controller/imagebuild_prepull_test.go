@@ -0,0 +1,88 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("ImageBuild builder image pre-pull", func() {
+	ctx := context.Background()
+
+	It("parses the node selector of the build nodes", func() {
+		selector, err := ParseNodeSelector("bib.cluster.x-k8s.io/build-node=true, pool=builds")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selector).To(Equal(map[string]string{"bib.cluster.x-k8s.io/build-node": "true", "pool": "builds"}))
+
+		selector, err = ParseNodeSelector("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selector).To(BeEmpty())
+
+		_, err = ParseNodeSelector("build-node")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("keeps the builder image on the build nodes with a DaemonSet", func() {
+		r := newFakeReconciler()
+		prePuller := &BuilderPrePuller{
+			Client:       r.Client,
+			BuilderImage: "builder:test",
+			Namespace:    "bib-operator-system",
+			NodeSelector: map[string]string{"bib.cluster.x-k8s.io/build-node": "true"},
+		}
+		Expect(prePuller.ensureDaemonSet(ctx)).To(Succeed())
+
+		key := types.NamespacedName{Name: builderPrePullName, Namespace: "bib-operator-system"}
+		daemonSet := &appsv1.DaemonSet{}
+		Expect(r.Get(ctx, key, daemonSet)).To(Succeed())
+		Expect(daemonSet.Spec.Selector.MatchLabels).To(Equal(daemonSet.Spec.Template.Labels))
+		Expect(daemonSet.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{"bib.cluster.x-k8s.io/build-node": "true"}))
+		Expect(daemonSet.Spec.Template.Spec.Containers).To(HaveLen(1))
+		Expect(daemonSet.Spec.Template.Spec.Containers[0].Image).To(Equal("builder:test"))
+
+		By("following a changed builder image")
+		prePuller.BuilderImage = "builder:next"
+		Expect(prePuller.ensureDaemonSet(ctx)).To(Succeed())
+		Expect(r.Get(ctx, key, daemonSet)).To(Succeed())
+		Expect(daemonSet.Spec.Template.Spec.Containers[0].Image).To(Equal("builder:next"))
+	})
+
+	It("schedules builder pods on the build nodes preferably", func() {
+		ib := newTestImageBuild("warm")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Affinity).To(BeNil())
+
+		r.PrePullNodeSelector = map[string]string{"bib.cluster.x-k8s.io/build-node": "true"}
+		pod, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.NodeSelector).To(Equal(map[string]string{"kubernetes.io/arch": "amd64"}))
+		preferred := pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+		Expect(preferred).To(HaveLen(1))
+		Expect(preferred[0].Preference.MatchExpressions).To(ConsistOf(corev1.NodeSelectorRequirement{
+			Key: "bib.cluster.x-k8s.io/build-node", Operator: corev1.NodeSelectorOpIn, Values: []string{"true"},
+		}))
+	})
+})
@@ -0,0 +1,417 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+	"github.com/zarcen/bib-operator/internal/ami"
+	"github.com/zarcen/bib-operator/internal/scope"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+var publisherPodPrefix = "imgpub-"
+
+// publisherContainerName is the name of the container running the publish in the publisher pod.
+const publisherContainerName = "publisher"
+
+// publishQueuedRequeueAfter is how long a queued publish waits before checking for a free slot again.
+var publishQueuedRequeueAfter = 30 * time.Second
+
+// reconcilePublish drives the publisher pod of an ImageBuild whose builder pod has succeeded.
+func (r *ImageBuildReconciler) reconcilePublish(ctx context.Context, ibs *scope.ImageBuildScope) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	imageBuild := ibs.ImageBuild
+
+	publisherPod := &corev1.Pod{}
+	publisherPodName := fmt.Sprintf("%s%s", publisherPodPrefix, imageBuild.Name)
+	err := r.Get(ctx, types.NamespacedName{Name: publisherPodName, Namespace: imageBuild.Namespace}, publisherPod)
+	if err == nil {
+		return r.reconcilePublisherPodStatus(ctx, ibs, publisherPod)
+	}
+	if !apierrors.IsNotFound(err) {
+		logger.Error(err, "Failed to get publisher pod")
+		return ctrl.Result{}, err
+	}
+
+	if terminating, err := r.reconcileNamespaceTerminating(ctx, imageBuild, bibv1alpha1.PublishReady); err != nil || terminating {
+		return ctrl.Result{}, err
+	}
+
+	// Gate publisher pod creation on the controller-wide concurrency limit.
+	if r.MaxConcurrentPublishes > 0 {
+		inFlight, err := r.countInFlightPublishes(ctx)
+		if err != nil {
+			logger.Error(err, "Failed to count in-flight publisher pods")
+			return ctrl.Result{}, err
+		}
+		if inFlight >= r.MaxConcurrentPublishes {
+			logger.Info("Publish concurrency limit reached, queueing", "InFlight", inFlight, "Limit", r.MaxConcurrentPublishes)
+			conditions.MarkUnknown(imageBuild, bibv1alpha1.PublishReady, bibv1alpha1.PublishQueuedReason,
+				"waiting for a free publish slot (%d/%d in flight)", inFlight, r.MaxConcurrentPublishes)
+			return ctrl.Result{RequeueAfter: publishQueuedRequeueAfter}, nil
+		}
+	}
+
+	var replaceImageID string
+	if imageBuild.Spec.Publish.AWS != nil {
+		var proceed bool
+		replaceImageID, proceed, err = r.reconcileExistingAMI(ctx, ibs)
+		if err != nil {
+			logger.Error(err, "Failed to check for an existing AMI")
+			return ctrl.Result{}, err
+		}
+		if !proceed {
+			return ctrl.Result{}, nil
+		}
+	}
+
+	desiredPod, err := r.constructPublisherPod(ctx, imageBuild)
+	if err != nil {
+		logger.Error(err, "Failed to construct publisher pod spec")
+		conditions.MarkFalse(imageBuild, bibv1alpha1.PublishReady, bibv1alpha1.PublishFailedReason, clusterv1beta1.ConditionSeverityError, "%s", err.Error())
+		imageBuild.Status.Phase = bibv1alpha1.PhaseFailed
+		markCompleted(imageBuild)
+		return ctrl.Result{}, nil
+	}
+	if replaceImageID != "" {
+		desiredPod.Spec.Containers[0].Env = append(desiredPod.Spec.Containers[0].Env,
+			corev1.EnvVar{Name: "AWS_REPLACE_IMAGE_ID", Value: replaceImageID})
+	}
+	if err := ctrl.SetControllerReference(imageBuild, desiredPod, r.Scheme); err != nil {
+		logger.Error(err, "Failed to set owner reference on publisher pod")
+		return ctrl.Result{}, err
+	}
+	if err := r.Create(ctx, desiredPod); err != nil {
+		logger.Error(err, "Failed to create publisher pod")
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Successfully created publisher pod", "PodName", desiredPod.Name)
+	imageBuild.Status.Phase = bibv1alpha1.PhasePublishing
+	conditions.MarkFalse(imageBuild, bibv1alpha1.PublishReady, bibv1alpha1.PublishingReason, clusterv1beta1.ConditionSeverityInfo,
+		"publisher pod %s created", desiredPod.Name)
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// reconcileExistingAMI applies spec.publish.aws.existingAMIPolicy to an AMI that already has the
+// requested name. It returns false if the publisher pod must not be created, and the ID of the AMI
+// the publisher replaces with the Overwrite policy. The publisher deregisters that AMI only once the
+// new snapshot is imported, so a failed publish leaves it in place.
+func (r *ImageBuildReconciler) reconcileExistingAMI(ctx context.Context, ibs *scope.ImageBuildScope) (string, bool, error) {
+	logger := log.FromContext(ctx)
+	imageBuild := ibs.ImageBuild
+	awsSpec := imageBuild.Spec.Publish.AWS
+
+	creds, err := r.awsCredentials(ctx, imageBuild.Namespace, awsSpec.CredentialsSecretName)
+	if err != nil {
+		return "", false, err
+	}
+	creds.RoleARN, creds.ExternalID = awsSpec.RoleARN, awsSpec.ExternalID
+	client, err := r.ec2ClientFunc()(ctx, awsSpec.Region, creds)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to create EC2 client: %w", err)
+	}
+	existing, err := ami.FindByName(ctx, client, awsSpec.AMIName)
+	if err != nil {
+		return "", false, err
+	}
+	if existing == nil {
+		return "", true, nil
+	}
+
+	imageID := aws.ToString(existing.ImageId)
+	switch awsSpec.ExistingAMIPolicy {
+	case bibv1alpha1.ExistingAMIPolicySkip:
+		logger.Info("AMI already exists, skipping publish", "AMIName", awsSpec.AMIName, "ImageID", imageID)
+		imageBuild.Status.PublishedImageID = imageID
+		conditions.MarkTrue(imageBuild, bibv1alpha1.PublishReady)
+		imageBuild.Status.Phase = bibv1alpha1.PhaseSucceeded
+		markCompleted(imageBuild)
+		return "", false, nil
+	case bibv1alpha1.ExistingAMIPolicyOverwrite:
+		logger.Info("AMI already exists, replacing it once the new one is imported", "AMIName", awsSpec.AMIName, "ImageID", imageID)
+		return imageID, true, nil
+	default:
+		conditions.MarkFalse(imageBuild, bibv1alpha1.PublishReady, bibv1alpha1.AMIAlreadyExistsReason, clusterv1beta1.ConditionSeverityError,
+			"AMI %q already exists as %s", awsSpec.AMIName, imageID)
+		imageBuild.Status.Phase = bibv1alpha1.PhaseFailed
+		markCompleted(imageBuild)
+		return "", false, nil
+	}
+}
+
+func (r *ImageBuildReconciler) ec2ClientFunc() ami.NewEC2ClientFunc {
+	if r.NewEC2Client == nil {
+		return ami.NewEC2Client
+	}
+	return r.NewEC2Client
+}
+
+// awsCredentials reads the static AWS credentials from the named secret.
+func (r *ImageBuildReconciler) awsCredentials(ctx context.Context, namespace, name string) (ami.Credentials, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, secret); err != nil {
+		return ami.Credentials{}, fmt.Errorf("failed to get AWS credentials secret %q: %w", name, err)
+	}
+	return ami.Credentials{
+		AccessKeyID:     string(secret.Data["AWS_ACCESS_KEY_ID"]),
+		SecretAccessKey: string(secret.Data["AWS_SECRET_ACCESS_KEY"]),
+		SessionToken:    string(secret.Data["AWS_SESSION_TOKEN"]),
+	}, nil
+}
+
+// publisherResult is the JSON document a publisher writes to its termination message on success.
+type publisherResult struct {
+	ImageID           string                            `json:"imageID,omitempty"`
+	LaunchPermissions *bibv1alpha1.AWSLaunchPermissions `json:"launchPermissions,omitempty"`
+}
+
+// reconcilePublisherPodStatus mirrors the publisher pod phase onto the ImageBuild status.
+func (r *ImageBuildReconciler) reconcilePublisherPodStatus(ctx context.Context, ibs *scope.ImageBuildScope, publisherPod *corev1.Pod) (ctrl.Result, error) {
+	imageBuild := ibs.ImageBuild
+
+	switch publisherPod.Status.Phase {
+	case corev1.PodSucceeded:
+		if message := terminationMessage(publisherPod, publisherContainerName); message != "" {
+			result := publisherResult{}
+			if err := json.Unmarshal([]byte(message), &result); err != nil {
+				log.FromContext(ctx).Error(err, "Ignoring publisher result", "PodName", publisherPod.Name)
+			} else {
+				imageBuild.Status.PublishedImageID = result.ImageID
+				imageBuild.Status.PublishedImageLaunchPermissions = result.LaunchPermissions
+			}
+		}
+		conditions.MarkTrue(imageBuild, bibv1alpha1.PublishReady)
+		imageBuild.Status.Phase = bibv1alpha1.PhaseSucceeded
+		markCompleted(imageBuild)
+	case corev1.PodFailed:
+		conditions.MarkFalse(imageBuild, bibv1alpha1.PublishReady, bibv1alpha1.PublishFailedReason, clusterv1beta1.ConditionSeverityError,
+			"publisher pod %s failed", publisherPod.Name)
+		imageBuild.Status.Phase = bibv1alpha1.PhaseFailed
+		markCompleted(imageBuild)
+	default:
+		imageBuild.Status.Phase = bibv1alpha1.PhasePublishing
+		conditions.MarkFalse(imageBuild, bibv1alpha1.PublishReady, bibv1alpha1.PublishingReason, clusterv1beta1.ConditionSeverityInfo,
+			"publisher pod %s is %s", publisherPod.Name, publisherPod.Status.Phase)
+	}
+	return ctrl.Result{}, nil
+}
+
+// countInFlightPublishes returns the number of publisher pods across the cluster that have not finished yet.
+func (r *ImageBuildReconciler) countInFlightPublishes(ctx context.Context) (int, error) {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.MatchingLabels{bibv1alpha1.ComponentLabel: bibv1alpha1.ComponentPublisher}); err != nil {
+		return 0, err
+	}
+	inFlight := 0
+	for i := range pods.Items {
+		phase := pods.Items[i].Status.Phase
+		if phase != corev1.PodSucceeded && phase != corev1.PodFailed {
+			inFlight++
+		}
+	}
+	return inFlight, nil
+}
+
+// constructPublisherPod creates the Pod resource definition that publishes the built artifact.
+func (r *ImageBuildReconciler) constructPublisherPod(_ context.Context, imageBuild *bibv1alpha1.ImageBuild) (*corev1.Pod, error) {
+	podName := fmt.Sprintf("%s%s", publisherPodPrefix, imageBuild.Name)
+	publish := imageBuild.Spec.Publish
+
+	// The publisher reads the artifact the builder left on the output PVC.
+	if imageBuild.Spec.Output.PVC == nil {
+		return nil, errors.New("publishing requires a pvc output to read the built artifact from")
+	}
+
+	envVars := []corev1.EnvVar{
+		{Name: "OUTPUT_FILENAME", Value: imageBuild.Spec.Output.ImageName},
+		{Name: "ARCHITECTURE", Value: imageBuild.Spec.Architecture},
+	}
+	var credentialsSecretName string
+	switch {
+	case publish.AWS != nil:
+		amiArch, ok := bibv1alpha1.AMIArchitecture(imageBuild.Spec.Architecture)
+		if !ok {
+			return nil, fmt.Errorf("architecture %q cannot be published as an AMI", imageBuild.Spec.Architecture)
+		}
+		envVars = append(envVars,
+			corev1.EnvVar{Name: "PUBLISH_TARGET", Value: "aws"},
+			corev1.EnvVar{Name: "AWS_REGION", Value: publish.AWS.Region},
+			corev1.EnvVar{Name: "AWS_AMI_NAME", Value: publish.AWS.AMIName},
+			corev1.EnvVar{Name: "AWS_AMI_ARCHITECTURE", Value: amiArch},
+			corev1.EnvVar{Name: "AWS_INSTANCE_TYPE", Value: publish.AWS.InstanceType},
+			corev1.EnvVar{Name: "AWS_SOURCE_S3_BUCKET", Value: publish.AWS.SourceS3Bucket},
+			corev1.EnvVar{Name: "AWS_ENA_SUPPORT", Value: strconv.FormatBool(boolOrDefault(publish.AWS.EnaSupport, true))},
+			corev1.EnvVar{Name: "AWS_SRIOV_NET_SUPPORT", Value: strconv.FormatBool(boolOrDefault(publish.AWS.SriovNetSupport, true))},
+			corev1.EnvVar{Name: "AWS_BOOT_MODE", Value: awsBootMode(publish.AWS)},
+		)
+		envVars = append(envVars, assumeRoleEnv("", &publish.AWS.AWSAssumeRole)...)
+		if permissions := publish.AWS.LaunchPermissions; permissions != nil && (len(permissions.AccountIDs) > 0 || permissions.Public) {
+			launchPermissions, err := awsLaunchPermissionJSON(permissions)
+			if err != nil {
+				return nil, err
+			}
+			envVars = append(envVars, corev1.EnvVar{Name: "AWS_LAUNCH_PERMISSION", Value: launchPermissions})
+		}
+		if publish.AWS.RetainSourceObject {
+			envVars = append(envVars, corev1.EnvVar{Name: "AWS_RETAIN_SOURCE_OBJECT", Value: "true"})
+		}
+		if len(publish.AWS.Tags) > 0 {
+			tags, err := awsTagsJSON(publish.AWS.Tags)
+			if err != nil {
+				return nil, err
+			}
+			envVars = append(envVars, corev1.EnvVar{Name: "AWS_TAGS", Value: tags})
+		}
+		credentialsSecretName = publish.AWS.CredentialsSecretName
+	case publish.MaaS != nil:
+		envVars = append(envVars,
+			corev1.EnvVar{Name: "PUBLISH_TARGET", Value: "maas"},
+			corev1.EnvVar{Name: "MAAS_API_URL", Value: publish.MaaS.APIURL},
+			corev1.EnvVar{Name: "MAAS_IMAGE_NAME", Value: publish.MaaS.ImageName},
+		)
+		credentialsSecretName = publish.MaaS.CredentialsSecretName
+	default:
+		return nil, errors.New("no publish target specified")
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: podObjectMeta(imageBuild, podName, bibv1alpha1.ComponentPublisher),
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:    publisherContainerName,
+					Image:   r.BuilderImage,
+					Command: []string{"/workspace/publish.sh"},
+					Env:     envVars,
+					// The credentials secret keys are exposed as-is, e.g. AWS_ACCESS_KEY_ID or MAAS_API_KEY.
+					EnvFrom: []corev1.EnvFromSource{
+						{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: credentialsSecretName}}},
+					},
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "output-pvc", MountPath: "/output", SubPath: outputPVCSubPath(imageBuild), ReadOnly: true},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: "output-pvc",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: outputPVCName(imageBuild),
+							ReadOnly:  true,
+						},
+					},
+				},
+			},
+		},
+	}
+	return pod, nil
+}
+
+// awsTag mirrors the EC2 Tag structure accepted by the aws CLI.
+type awsTag struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"`
+}
+
+// awsTagsJSON renders tags as the JSON list accepted by `aws ec2 create-tags --tags`, sorted by key.
+func awsTagsJSON(tags map[string]string) (string, error) {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	list := make([]awsTag, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, awsTag{Key: key, Value: tags[key]})
+	}
+	raw, err := json.Marshal(list)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// boolOrDefault returns *value, or def if value is nil.
+func boolOrDefault(value *bool, def bool) bool {
+	if value == nil {
+		return def
+	}
+	return *value
+}
+
+// awsBootMode returns the boot mode the AMI is registered with, defaulting to uefi-preferred.
+func awsBootMode(awsSpec *bibv1alpha1.AWSPublishSpec) string {
+	if awsSpec.BootMode == "" {
+		return string(bibv1alpha1.AWSBootModeUEFIPreferred)
+	}
+	return string(awsSpec.BootMode)
+}
+
+// awsLaunchPermission mirrors the EC2 LaunchPermission structure accepted by the aws CLI.
+type awsLaunchPermission struct {
+	UserID string `json:"UserId,omitempty"`
+	Group  string `json:"Group,omitempty"`
+}
+
+// awsLaunchPermissionJSON renders permissions as the JSON accepted by
+// `aws ec2 modify-image-attribute --launch-permission`.
+func awsLaunchPermissionJSON(permissions *bibv1alpha1.AWSLaunchPermissions) (string, error) {
+	add := make([]awsLaunchPermission, 0, len(permissions.AccountIDs)+1)
+	for _, accountID := range permissions.AccountIDs {
+		add = append(add, awsLaunchPermission{UserID: accountID})
+	}
+	if permissions.Public {
+		add = append(add, awsLaunchPermission{Group: "all"})
+	}
+	raw, err := json.Marshal(map[string][]awsLaunchPermission{"Add": add})
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// cleanupPublisherPod deletes the publisher Pod resource if it exists.
+func (r *ImageBuildReconciler) cleanupPublisherPod(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild) error {
+	podName := fmt.Sprintf("%s%s", publisherPodPrefix, imageBuild.Name)
+	err := r.Delete(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: imageBuild.Namespace}})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
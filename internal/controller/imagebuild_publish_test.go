@@ -0,0 +1,527 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+	"github.com/zarcen/bib-operator/internal/ami"
+	"github.com/zarcen/bib-operator/internal/registry"
+	"github.com/zarcen/bib-operator/internal/scope"
+)
+
+// newFakeReconciler returns an ImageBuildReconciler backed by a fake client seeded with objs.
+func newFakeReconciler(objs ...client.Object) *ImageBuildReconciler {
+	s := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+	Expect(bibv1alpha1.AddToScheme(s)).To(Succeed())
+	// Builder pods can be scheduled for every architecture unless a test removes its node.
+	for _, arch := range bibv1alpha1.SupportedArchitectures() {
+		objs = append(objs, newTestNode(arch+"-node", arch))
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(s).
+		WithObjects(objs...).
+		WithStatusSubresource(&bibv1alpha1.ImageBuild{}).
+		WithIndex(&bibv1alpha1.ImageBuild{}, baseImageFromField, indexBaseImageFrom).
+		Build()
+	return &ImageBuildReconciler{
+		Client:       c,
+		Scheme:       s,
+		BuilderImage: "builder:test",
+		NewEC2Client: (&fakeEC2{}).newClient,
+		// Tests must not reach a registry; the architecture check is skipped for unknown platforms.
+		ImagePlatforms: func(context.Context, string, []byte) ([]registry.Platform, error) { return nil, nil },
+	}
+}
+
+// reconcileBuild reconciles the ImageBuild name of the default namespace with r, expecting no
+// error, and returns the result along with the ImageBuild as stored afterwards.
+func reconcileBuild(r *ImageBuildReconciler, name string) (ctrl.Result, *bibv1alpha1.ImageBuild) {
+	key := types.NamespacedName{Name: name, Namespace: "default"}
+	result, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+	Expect(err).NotTo(HaveOccurred())
+	updated := &bibv1alpha1.ImageBuild{}
+	Expect(r.Get(context.Background(), key, updated)).To(Succeed())
+	return result, updated
+}
+
+// newTestNode returns a schedulable node of the given build architecture.
+func newTestNode(name, arch string) *corev1.Node {
+	nodeArch, _ := bibv1alpha1.NodeArchitecture(arch)
+	return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{nodeArchLabel: nodeArch}}}
+}
+
+// newTestSecret returns a secret in the default namespace holding the given keys.
+func newTestSecret(name string, secretType corev1.SecretType, keys ...string) *corev1.Secret {
+	data := map[string][]byte{}
+	for _, key := range keys {
+		data[key] = []byte("value")
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Type:       secretType,
+		Data:       data,
+	}
+}
+
+// fakeEC2 is an in-memory ami.EC2API holding the AMIs owned by the caller.
+type fakeEC2 struct {
+	images      []ec2types.Image
+	credentials ami.Credentials
+}
+
+func (f *fakeEC2) newClient(_ context.Context, _ string, creds ami.Credentials) (ami.EC2API, error) {
+	f.credentials = creds
+	return f, nil
+}
+
+func (f *fakeEC2) DescribeImages(_ context.Context, in *ec2.DescribeImagesInput, _ ...func(*ec2.Options)) (*ec2.DescribeImagesOutput, error) {
+	out := &ec2.DescribeImagesOutput{}
+	for _, image := range f.images {
+		for _, filter := range in.Filters {
+			if aws.ToString(filter.Name) == "name" && slices.Contains(filter.Values, aws.ToString(image.Name)) {
+				out.Images = append(out.Images, image)
+			}
+		}
+	}
+	return out, nil
+}
+
+// newTestImageBuild returns a minimal ImageBuild with a PVC output.
+func newTestImageBuild(name string) *bibv1alpha1.ImageBuild {
+	return &bibv1alpha1.ImageBuild{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name)},
+		Spec: bibv1alpha1.ImageBuildSpec{
+			Architecture: "amd64",
+			BaseImage:    "ubuntu:24.04",
+			Output: bibv1alpha1.OutputSpec{
+				ImageName: name,
+				PVC:       &bibv1alpha1.PVCOutput{Name: "artifacts"},
+			},
+		},
+	}
+}
+
+// newTestScope wraps imageBuild in a scope backed by r's client.
+func newTestScope(r *ImageBuildReconciler, imageBuild *bibv1alpha1.ImageBuild) *scope.ImageBuildScope {
+	ibs, err := scope.NewImageBuildScope(r.Client, logf.Log, imageBuild)
+	Expect(err).NotTo(HaveOccurred())
+	ibs.InitializeConditions()
+	return ibs
+}
+
+var _ = Describe("ImageBuild publishing", func() {
+	ctx := context.Background()
+
+	newPublishingImageBuild := func(name string) *bibv1alpha1.ImageBuild {
+		ib := newTestImageBuild(name)
+		ib.Spec.Publish = &bibv1alpha1.PublishSpec{
+			AWS: &bibv1alpha1.AWSPublishSpec{
+				Region:                "us-east-1",
+				AMIName:               name,
+				InstanceType:          "t3.small",
+				SourceS3Bucket:        "staging",
+				CredentialsSecretName: "aws-credentials",
+			},
+		}
+		return ib
+	}
+
+	awsCredentials := newTestSecret("aws-credentials", corev1.SecretTypeOpaque, awsCredentialsKeys...)
+
+	inFlightPublisherPod := func(name string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      publisherPodPrefix + name,
+				Namespace: "default",
+				Labels: map[string]string{
+					bibv1alpha1.ImageBuildNameLabel: name,
+					bibv1alpha1.ComponentLabel:      bibv1alpha1.ComponentPublisher,
+				},
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+
+	It("creates a publisher pod when under the concurrency limit", func() {
+		ib := newPublishingImageBuild("first")
+		r := newFakeReconciler(ib, awsCredentials)
+		r.MaxConcurrentPublishes = 1
+
+		_, err := r.reconcilePublish(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+
+		pod := &corev1.Pod{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: publisherPodPrefix + "first", Namespace: "default"}, pod)).To(Succeed())
+		Expect(pod.Labels).To(HaveKeyWithValue(bibv1alpha1.ComponentLabel, bibv1alpha1.ComponentPublisher))
+		Expect(ib.Status.Phase).To(Equal(bibv1alpha1.PhasePublishing))
+	})
+
+	It("queues the N+1th publish until a slot frees up", func() {
+		const limit = 2
+		objs := []client.Object{}
+		for i := range limit {
+			objs = append(objs, inFlightPublisherPod(fmt.Sprintf("running-%d", i), corev1.PodRunning))
+		}
+		// Finished publisher pods do not count against the limit.
+		objs = append(objs, inFlightPublisherPod("done", corev1.PodSucceeded))
+
+		ib := newPublishingImageBuild("queued")
+		r := newFakeReconciler(append(objs, ib, awsCredentials)...)
+		r.MaxConcurrentPublishes = limit
+
+		res, err := r.reconcilePublish(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(publishQueuedRequeueAfter))
+		Expect(conditions.IsUnknown(ib, bibv1alpha1.PublishReady)).To(BeTrue())
+		Expect(conditions.GetReason(ib, bibv1alpha1.PublishReady)).To(Equal(bibv1alpha1.PublishQueuedReason))
+
+		err = r.Get(ctx, types.NamespacedName{Name: publisherPodPrefix + "queued", Namespace: "default"}, &corev1.Pod{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		By("finishing one of the in-flight publishes")
+		running := &corev1.Pod{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: publisherPodPrefix + "running-0", Namespace: "default"}, running)).To(Succeed())
+		running.Status.Phase = corev1.PodSucceeded
+		Expect(r.Status().Update(ctx, running)).To(Succeed())
+
+		_, err = r.reconcilePublish(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Get(ctx, types.NamespacedName{Name: publisherPodPrefix + "queued", Namespace: "default"}, &corev1.Pod{})).To(Succeed())
+		Expect(conditions.GetReason(ib, bibv1alpha1.PublishReady)).To(Equal(bibv1alpha1.PublishingReason))
+	})
+
+	It("passes AWS tags to the publisher as a JSON list sorted by key", func() {
+		ib := newPublishingImageBuild("tagged")
+		ib.Spec.Publish.AWS.Tags = map[string]string{"team": "platform", "cost-center": "1234"}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructPublisherPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+			Name:  "AWS_TAGS",
+			Value: `[{"Key":"cost-center","Value":"1234"},{"Key":"team","Value":"platform"}]`,
+		}))
+	})
+
+	It("passes the AMI architecture mapped from spec.arch to the publisher", func() {
+		ib := newPublishingImageBuild("arm")
+		ib.Spec.Architecture = "arm64"
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructPublisherPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "AWS_AMI_ARCHITECTURE", Value: "arm64"}))
+
+		ib.Spec.Architecture = "amd64"
+		pod, err = r.constructPublisherPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "AWS_AMI_ARCHITECTURE", Value: "x86_64"}))
+	})
+
+	It("passes the AMI networking and boot mode flags to the publisher", func() {
+		ib := newPublishingImageBuild("nitro")
+		r := newFakeReconciler(ib)
+
+		By("defaulting to ENA, SR-IOV and uefi-preferred")
+		pod, err := r.constructPublisherPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+			corev1.EnvVar{Name: "AWS_ENA_SUPPORT", Value: "true"},
+			corev1.EnvVar{Name: "AWS_SRIOV_NET_SUPPORT", Value: "true"},
+			corev1.EnvVar{Name: "AWS_BOOT_MODE", Value: "uefi-preferred"},
+		))
+
+		By("honoring explicit values")
+		disabled := false
+		ib.Spec.Publish.AWS.EnaSupport = &disabled
+		ib.Spec.Publish.AWS.SriovNetSupport = &disabled
+		ib.Spec.Publish.AWS.BootMode = bibv1alpha1.AWSBootModeLegacyBIOS
+		pod, err = r.constructPublisherPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+			corev1.EnvVar{Name: "AWS_ENA_SUPPORT", Value: "false"},
+			corev1.EnvVar{Name: "AWS_SRIOV_NET_SUPPORT", Value: "false"},
+			corev1.EnvVar{Name: "AWS_BOOT_MODE", Value: "legacy-bios"},
+		))
+	})
+
+	It("omits AWS_TAGS when no tags are set", func() {
+		ib := newPublishingImageBuild("untagged")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructPublisherPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		for _, env := range pod.Spec.Containers[0].Env {
+			Expect(env.Name).NotTo(Equal("AWS_TAGS"))
+		}
+	})
+
+	It("asks the publisher to keep the staged disk image only when requested", func() {
+		ib := newPublishingImageBuild("retained")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructPublisherPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "AWS_RETAIN_SOURCE_OBJECT")))
+
+		ib.Spec.Publish.AWS.RetainSourceObject = true
+		pod, err = r.constructPublisherPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "AWS_RETAIN_SOURCE_OBJECT", Value: "true"}))
+	})
+
+	It("asks the publisher to assume the configured role", func() {
+		ib := newPublishingImageBuild("cross-account")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructPublisherPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "AWS_ASSUME_ROLE_ARN")))
+
+		ib.Spec.Publish.AWS.RoleARN = "arn:aws:iam::111122223333:role/ami-publisher"
+		ib.Spec.Publish.AWS.ExternalID = "bib"
+		pod, err = r.constructPublisherPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+			corev1.EnvVar{Name: "AWS_ASSUME_ROLE_ARN", Value: "arn:aws:iam::111122223333:role/ami-publisher"},
+			corev1.EnvVar{Name: "AWS_ASSUME_ROLE_EXTERNAL_ID", Value: "bib"},
+		))
+	})
+
+	Context("when an AMI with the requested name already exists", func() {
+		existingAMI := func() ec2types.Image {
+			return ec2types.Image{
+				ImageId: aws.String("ami-existing"),
+				Name:    aws.String("golden"),
+				BlockDeviceMappings: []ec2types.BlockDeviceMapping{
+					{DeviceName: aws.String("/dev/sda1"), Ebs: &ec2types.EbsBlockDevice{SnapshotId: aws.String("snap-existing")}},
+				},
+			}
+		}
+
+		reconcileWithPolicy := func(policy bibv1alpha1.ExistingAMIPolicy) (*ImageBuildReconciler, *fakeEC2, *bibv1alpha1.ImageBuild) {
+			ib := newPublishingImageBuild("golden")
+			ib.Spec.Publish.AWS.ExistingAMIPolicy = policy
+			ec2Client := &fakeEC2{images: []ec2types.Image{existingAMI()}}
+			r := newFakeReconciler(ib, awsCredentials)
+			r.NewEC2Client = ec2Client.newClient
+
+			_, err := r.reconcilePublish(ctx, newTestScope(r, ib))
+			Expect(err).NotTo(HaveOccurred())
+			return r, ec2Client, ib
+		}
+
+		publisherPodExists := func(r *ImageBuildReconciler) bool {
+			err := r.Get(ctx, types.NamespacedName{Name: publisherPodPrefix + "golden", Namespace: "default"}, &corev1.Pod{})
+			return err == nil
+		}
+
+		It("fails the publish with the Fail policy", func() {
+			r, ec2Client, ib := reconcileWithPolicy(bibv1alpha1.ExistingAMIPolicyFail)
+			Expect(publisherPodExists(r)).To(BeFalse())
+			Expect(ec2Client.images).To(HaveLen(1))
+			Expect(ib.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+			Expect(conditions.GetReason(ib, bibv1alpha1.PublishReady)).To(Equal(bibv1alpha1.AMIAlreadyExistsReason))
+		})
+
+		It("fails the publish when no policy is set", func() {
+			r, _, ib := reconcileWithPolicy("")
+			Expect(publisherPodExists(r)).To(BeFalse())
+			Expect(ib.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+		})
+
+		It("keeps the existing AMI with the Skip policy", func() {
+			r, ec2Client, ib := reconcileWithPolicy(bibv1alpha1.ExistingAMIPolicySkip)
+			Expect(publisherPodExists(r)).To(BeFalse())
+			Expect(ec2Client.images).To(HaveLen(1))
+			Expect(ib.Status.Phase).To(Equal(bibv1alpha1.PhaseSucceeded))
+			Expect(ib.Status.PublishedImageID).To(Equal("ami-existing"))
+			Expect(conditions.IsTrue(ib, bibv1alpha1.PublishReady)).To(BeTrue())
+		})
+
+		It("asks the publisher to replace the existing AMI with the Overwrite policy", func() {
+			r, ec2Client, ib := reconcileWithPolicy(bibv1alpha1.ExistingAMIPolicyOverwrite)
+			Expect(ec2Client.images).To(HaveLen(1))
+			Expect(ib.Status.Phase).To(Equal(bibv1alpha1.PhasePublishing))
+			pod := &corev1.Pod{}
+			Expect(r.Get(ctx, types.NamespacedName{Name: publisherPodPrefix + "golden", Namespace: "default"}, pod)).To(Succeed())
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "AWS_REPLACE_IMAGE_ID", Value: "ami-existing"}))
+		})
+
+		It("keeps the existing AMI when the publisher fails with the Overwrite policy", func() {
+			r, ec2Client, ib := reconcileWithPolicy(bibv1alpha1.ExistingAMIPolicyOverwrite)
+			pod := &corev1.Pod{}
+			Expect(r.Get(ctx, types.NamespacedName{Name: publisherPodPrefix + "golden", Namespace: "default"}, pod)).To(Succeed())
+			pod.Status.Phase = corev1.PodFailed
+			Expect(r.Status().Update(ctx, pod)).To(Succeed())
+
+			_, err := r.reconcilePublish(ctx, newTestScope(r, ib))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ib.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+			Expect(ec2Client.images).To(ConsistOf(HaveField("ImageId", HaveValue(Equal("ami-existing")))))
+		})
+
+		It("looks the AMI up with the assumed role", func() {
+			ib := newPublishingImageBuild("golden")
+			ib.Spec.Publish.AWS.RoleARN = "arn:aws:iam::111122223333:role/ami-publisher"
+			ib.Spec.Publish.AWS.ExternalID = "bib"
+			ec2Client := &fakeEC2{images: []ec2types.Image{existingAMI()}}
+			r := newFakeReconciler(ib, awsCredentials)
+			r.NewEC2Client = ec2Client.newClient
+
+			_, err := r.reconcilePublish(ctx, newTestScope(r, ib))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ec2Client.credentials.RoleARN).To(Equal("arn:aws:iam::111122223333:role/ami-publisher"))
+			Expect(ec2Client.credentials.ExternalID).To(Equal("bib"))
+		})
+	})
+
+	It("marks the output ready once the builder pod succeeded, before publishing it", func() {
+		ib := newPublishingImageBuild("published")
+		builderPod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: builderPodPrefix + "published", Namespace: "default"},
+			Status:     corev1.PodStatus{Phase: corev1.PodSucceeded},
+		}
+		r := newFakeReconciler(ib, builderPod, awsCredentials)
+		r.NewEC2Client = (&fakeEC2{}).newClient
+
+		_, updated := reconcileBuild(r, "published")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhasePublishing))
+		Expect(conditions.IsTrue(updated, bibv1alpha1.OutputReady)).To(BeTrue())
+
+		By("keeping it ready once the publish succeeded")
+		publisherPod := &corev1.Pod{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: publisherPodPrefix + "published", Namespace: "default"}, publisherPod)).To(Succeed())
+		publisherPod.Status.Phase = corev1.PodSucceeded
+		Expect(r.Status().Update(ctx, publisherPod)).To(Succeed())
+		_, updated = reconcileBuild(r, "published")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseSucceeded))
+		Expect(conditions.IsTrue(updated, bibv1alpha1.OutputReady)).To(BeTrue())
+		Expect(conditions.IsTrue(updated, bibv1alpha1.PublishReady)).To(BeTrue())
+	})
+
+	It("marks the output ready when the existing AMI is kept", func() {
+		ib := newPublishingImageBuild("kept")
+		ib.Spec.Publish.AWS.ExistingAMIPolicy = bibv1alpha1.ExistingAMIPolicySkip
+		builderPod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: builderPodPrefix + "kept", Namespace: "default"},
+			Status:     corev1.PodStatus{Phase: corev1.PodSucceeded},
+		}
+		r := newFakeReconciler(ib, builderPod, awsCredentials)
+		r.NewEC2Client = (&fakeEC2{images: []ec2types.Image{{ImageId: aws.String("ami-kept"), Name: aws.String("kept")}}}).newClient
+
+		_, updated := reconcileBuild(r, "kept")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseSucceeded))
+		Expect(updated.Status.PublishedImageID).To(Equal("ami-kept"))
+		Expect(conditions.IsTrue(updated, bibv1alpha1.OutputReady)).To(BeTrue())
+	})
+
+	It("records the AMI ID reported by the publisher", func() {
+		ib := newPublishingImageBuild("reported")
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: publisherPodPrefix + "reported", Namespace: "default"},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodSucceeded,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  publisherContainerName,
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: `{"imageID":"ami-0123"}`}},
+				}},
+			},
+		}
+		r := newFakeReconciler(ib, pod)
+
+		_, err := r.reconcilePublish(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ib.Status.Phase).To(Equal(bibv1alpha1.PhaseSucceeded))
+		Expect(ib.Status.PublishedImageID).To(Equal("ami-0123"))
+	})
+
+	It("passes the AMI launch permissions to the publisher", func() {
+		ib := newPublishingImageBuild("shared")
+		ib.Spec.Publish.AWS.LaunchPermissions = &bibv1alpha1.AWSLaunchPermissions{
+			AccountIDs: []string{"111122223333", "444455556666"}, Public: true,
+		}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructPublisherPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+			Name:  "AWS_LAUNCH_PERMISSION",
+			Value: `{"Add":[{"UserId":"111122223333"},{"UserId":"444455556666"},{"Group":"all"}]}`,
+		}))
+
+		By("omitting empty launch permissions")
+		ib.Spec.Publish.AWS.LaunchPermissions = &bibv1alpha1.AWSLaunchPermissions{}
+		pod, err = r.constructPublisherPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "AWS_LAUNCH_PERMISSION")))
+	})
+
+	It("records the launch permissions reported by the publisher", func() {
+		ib := newPublishingImageBuild("shared-reported")
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: publisherPodPrefix + "shared-reported", Namespace: "default"},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodSucceeded,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name: publisherContainerName,
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+						Message: `{"imageID":"ami-0123","launchPermissions":{"accountIDs":["111122223333"],"public":false}}`,
+					}},
+				}},
+			},
+		}
+		r := newFakeReconciler(ib, pod)
+
+		_, err := r.reconcilePublish(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ib.Status.PublishedImageID).To(Equal("ami-0123"))
+		Expect(ib.Status.PublishedImageLaunchPermissions).To(Equal(&bibv1alpha1.AWSLaunchPermissions{AccountIDs: []string{"111122223333"}}))
+	})
+
+	It("does not limit publishes when the limit is zero", func() {
+		ib := newPublishingImageBuild("unlimited")
+		r := newFakeReconciler(inFlightPublisherPod("other", corev1.PodRunning), ib, awsCredentials)
+
+		_, err := r.reconcilePublish(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Get(ctx, types.NamespacedName{Name: publisherPodPrefix + "unlimited", Namespace: "default"}, &corev1.Pod{})).To(Succeed())
+	})
+})
@@ -0,0 +1,253 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// outputPVCInUseRequeueAfter is how long a build waits before checking whether its output PVC was released.
+var outputPVCInUseRequeueAfter = 30 * time.Second
+
+// maxOutputPVCRecreations bounds the fresh output PVCs created for a build with
+// spec.output.pvc.recreateOnFailure, so a build failing for other reasons does not fill the storage.
+var maxOutputPVCRecreations int32 = 3
+
+// defaultOutputPVCSize is the capacity of an output PVC created by spec.output.pvc.createIfMissing
+// without a size.
+var defaultOutputPVCSize = resource.MustParse("50Gi")
+
+// outputPVCName returns the claim the build writes its artifacts to: spec.output.pvc.name, or the
+// latest fresh claim created by reconcileOutputPVCRecreate.
+func outputPVCName(imageBuild *bibv1alpha1.ImageBuild) string {
+	return recreatedOutputPVCName(imageBuild, imageBuild.Status.OutputPVCRecreations)
+}
+
+// recreatedOutputPVCName returns the name of the n-th fresh output claim, or spec.output.pvc.name for n = 0.
+func recreatedOutputPVCName(imageBuild *bibv1alpha1.ImageBuild, n int32) string {
+	if n == 0 {
+		return imageBuild.Spec.Output.PVC.Name
+	}
+	return fmt.Sprintf("%s-output-%d", imageBuild.Name, n)
+}
+
+// reconcileOutputPVCCreate creates the output PVC named in the spec if spec.output.pvc.createIfMissing
+// is set and it does not exist. The claim has no owner, so the artifacts outlive the ImageBuild.
+func (r *ImageBuildReconciler) reconcileOutputPVCCreate(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild) error {
+	output := imageBuild.Spec.Output.PVC
+	if output == nil || !output.CreateIfMissing || imageBuild.Status.OutputPVCRecreations > 0 {
+		return nil
+	}
+	key := types.NamespacedName{Name: output.Name, Namespace: imageBuild.Namespace}
+	if err := r.Get(ctx, key, &corev1.PersistentVolumeClaim{}); !apierrors.IsNotFound(err) {
+		return err
+	}
+	size := defaultOutputPVCSize
+	if output.Size != nil {
+		size = *output.Size
+	}
+	claim := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      output.Name,
+			Namespace: imageBuild.Namespace,
+			Labels:    map[string]string{bibv1alpha1.ComponentLabel: bibv1alpha1.ComponentOutput},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			StorageClassName: output.StorageClassName,
+			Resources: corev1.VolumeResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceStorage: size,
+			}},
+		},
+	}
+	log.FromContext(ctx).Info("Creating output PVC", "PVC", claim.Name, "Size", size.String())
+	if err := r.Create(ctx, claim); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// reconcileOutputPVCRecreate retries a build that failed on a full or unwritable output PVC on a fresh
+// claim if spec.output.pvc.recreateOnFailure is set. The fresh claim, owned by the ImageBuild, copies the
+// storage class, access modes and size of the claim the build failed on, which is deleted if the operator
+// created it. It returns true if the build is retried, or false for other failures and once
+// maxOutputPVCRecreations is reached, in which case the build fails as usual.
+func (r *ImageBuildReconciler) reconcileOutputPVCRecreate(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild,
+	pod *corev1.Pod) (bool, error) {
+	logger := log.FromContext(ctx)
+	if imageBuild.Spec.Output.PVC == nil || !imageBuild.Spec.Output.PVC.RecreateOnFailure ||
+		imageBuild.Status.OutputPVCRecreations >= maxOutputPVCRecreations {
+		return false, nil
+	}
+	// Only a failure of the claim itself is fixed by a fresh one.
+	storageFailure, ok := outputStorageFailure(pod)
+	if !ok {
+		return false, nil
+	}
+
+	failedName := outputPVCName(imageBuild)
+	failed := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, types.NamespacedName{Name: failedName, Namespace: imageBuild.Namespace}, failed); err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Info("Output PVC not found, not recreating it", "PVC", failedName)
+			return false, nil
+		}
+		return false, err
+	}
+
+	n := imageBuild.Status.OutputPVCRecreations + 1
+	fresh := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      recreatedOutputPVCName(imageBuild, n),
+			Namespace: imageBuild.Namespace,
+			Labels: map[string]string{
+				bibv1alpha1.ImageBuildNameLabel: imageBuild.Name,
+				bibv1alpha1.ComponentLabel:      bibv1alpha1.ComponentOutput,
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      failed.Spec.AccessModes,
+			Resources:        failed.Spec.Resources,
+			StorageClassName: failed.Spec.StorageClassName,
+			VolumeMode:       failed.Spec.VolumeMode,
+		},
+	}
+	if err := ctrl.SetControllerReference(imageBuild, fresh, r.Scheme); err != nil {
+		return false, err
+	}
+	if err := r.Create(ctx, fresh); err != nil && !apierrors.IsAlreadyExists(err) {
+		return false, err
+	}
+
+	if err := r.cleanupBuilderPod(ctx, imageBuild); err != nil {
+		return false, err
+	}
+	// The claim the build failed on is presumably full or corrupt; only claims of our own are deleted.
+	if imageBuild.Status.OutputPVCRecreations > 0 {
+		if err := r.Delete(ctx, failed); err != nil && !apierrors.IsNotFound(err) {
+			return false, err
+		}
+	}
+
+	logger.Info("Retrying failed build on a fresh output PVC", "FailedPVC", failedName, "PVC", fresh.Name)
+	imageBuild.Status.OutputPVCRecreations = n
+	imageBuild.Status.Phase = bibv1alpha1.PhasePending
+	conditions.MarkFalse(imageBuild, bibv1alpha1.OutputReady, bibv1alpha1.OutputPVCRecreatedReason, clusterv1beta1.ConditionSeverityWarning,
+		"builder pod %s failed, %s, retrying on fresh PVC %s (%d/%d)", pod.Name, storageFailure, fresh.Name, n, maxOutputPVCRecreations)
+	conditions.MarkUnknown(imageBuild, bibv1alpha1.BuilderPodReady, "Initializing", "Unknown")
+	return true, nil
+}
+
+// reconcileOutputPVCInUse serializes builds sharing an output PVC that cannot be mounted by several nodes.
+// It returns true if another pod still uses the claim, in which case OutputReady reports the wait.
+// Claims allowing ReadWriteMany, and claims that do not exist yet, never block the build.
+func (r *ImageBuildReconciler) reconcileOutputPVCInUse(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild) (bool, error) {
+	if imageBuild.Spec.Output.PVC == nil {
+		return false, nil
+	}
+	claimName := outputPVCName(imageBuild)
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, types.NamespacedName{Name: claimName, Namespace: imageBuild.Namespace}, pvc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if slices.Contains(pvc.Spec.AccessModes, corev1.ReadWriteMany) {
+		return false, nil
+	}
+
+	user, err := r.findActivePodUsingClaim(ctx, imageBuild.Namespace, claimName)
+	if err != nil {
+		return false, err
+	}
+	if user == "" {
+		// Clear the wait reported by an earlier reconcile now that the claim is free.
+		if conditions.GetReason(imageBuild, bibv1alpha1.OutputReady) == bibv1alpha1.OutputPVCInUseReason {
+			conditions.MarkUnknown(imageBuild, bibv1alpha1.OutputReady, "Initializing", "Unknown")
+		}
+		return false, nil
+	}
+	log.FromContext(ctx).Info("Output PVC is in use by another pod, waiting", "PVC", claimName, "Pod", user)
+	conditions.MarkUnknown(imageBuild, bibv1alpha1.OutputReady, bibv1alpha1.OutputPVCInUseReason,
+		"output PVC %s is in use by pod %s and does not allow ReadWriteMany", claimName, user)
+	return true, nil
+}
+
+// buildCacheSharingNote returns a note for the BuilderPodStarting condition if the spec.build.cache
+// claim does not allow ReadWriteMany and another pod still uses it. Unlike the output PVC this does
+// not hold the build back: the builder pod can still run on the node the claim is attached to, and
+// the package managers lock the cache against concurrent writers, but the builds may wait on each other.
+func (r *ImageBuildReconciler) buildCacheSharingNote(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild) (string, error) {
+	if imageBuild.Spec.Build == nil || imageBuild.Spec.Build.Cache == nil {
+		return "", nil
+	}
+	claimName := imageBuild.Spec.Build.Cache.PVCName
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, types.NamespacedName{Name: claimName, Namespace: imageBuild.Namespace}, pvc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if slices.Contains(pvc.Spec.AccessModes, corev1.ReadWriteMany) {
+		return "", nil
+	}
+
+	user, err := r.findActivePodUsingClaim(ctx, imageBuild.Namespace, claimName)
+	if err != nil || user == "" {
+		return "", err
+	}
+	log.FromContext(ctx).Info("Build cache PVC does not allow ReadWriteMany and is in use by another pod", "PVC", claimName, "Pod", user)
+	return fmt.Sprintf(", sharing build cache PVC %s, which does not allow ReadWriteMany, with pod %s", claimName, user), nil
+}
+
+// findActivePodUsingClaim returns the name of a pod that mounts the claim and has not finished yet, or "".
+func (r *ImageBuildReconciler) findActivePodUsingClaim(ctx context.Context, namespace, claimName string) (string, error) {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(namespace)); err != nil {
+		return "", err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == claimName {
+				return pod.Name, nil
+			}
+		}
+	}
+	return "", nil
+}
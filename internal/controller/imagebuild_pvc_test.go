@@ -0,0 +1,377 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("ImageBuild shared output PVC", func() {
+	ctx := context.Background()
+
+	newClaim := func(accessMode corev1.PersistentVolumeAccessMode) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "artifacts", Namespace: "default"},
+			Spec:       corev1.PersistentVolumeClaimSpec{AccessModes: []corev1.PersistentVolumeAccessMode{accessMode}},
+		}
+	}
+
+	podUsingClaim := func(name string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+				Name: "output-pvc",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "artifacts"},
+				},
+			}}},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+
+	builderPodExists := func(r *ImageBuildReconciler, name string) bool {
+		err := r.Get(ctx, types.NamespacedName{Name: builderPodPrefix + name, Namespace: "default"}, &corev1.Pod{})
+		if apierrors.IsNotFound(err) {
+			return false
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return true
+	}
+
+	It("waits while another build uses a ReadWriteOnce output PVC", func() {
+		ib := newTestImageBuild("second")
+		r := newFakeReconciler(ib, newClaim(corev1.ReadWriteOnce), podUsingClaim(builderPodPrefix+"first", corev1.PodRunning))
+
+		res, updated := reconcileBuild(r, "second")
+		Expect(res.RequeueAfter).To(Equal(outputPVCInUseRequeueAfter))
+		Expect(builderPodExists(r, "second")).To(BeFalse())
+		Expect(conditions.IsUnknown(updated, bibv1alpha1.OutputReady)).To(BeTrue())
+		Expect(conditions.GetReason(updated, bibv1alpha1.OutputReady)).To(Equal(bibv1alpha1.OutputPVCInUseReason))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.OutputReady)).To(ContainSubstring(builderPodPrefix + "first"))
+
+		By("starting once the other build has finished")
+		first := &corev1.Pod{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: builderPodPrefix + "first", Namespace: "default"}, first)).To(Succeed())
+		first.Status.Phase = corev1.PodSucceeded
+		Expect(r.Status().Update(ctx, first)).To(Succeed())
+
+		_, updated = reconcileBuild(r, "second")
+		Expect(builderPodExists(r, "second")).To(BeTrue())
+		Expect(conditions.GetReason(updated, bibv1alpha1.OutputReady)).NotTo(Equal(bibv1alpha1.OutputPVCInUseReason))
+	})
+
+	It("does not serialize builds sharing a ReadWriteMany output PVC", func() {
+		ib := newTestImageBuild("parallel")
+		r := newFakeReconciler(ib, newClaim(corev1.ReadWriteMany), podUsingClaim(builderPodPrefix+"other", corev1.PodRunning))
+
+		reconcileBuild(r, "parallel")
+		Expect(builderPodExists(r, "parallel")).To(BeTrue())
+	})
+})
+
+var _ = Describe("ImageBuild build cache", func() {
+	ctx := context.Background()
+
+	newCachedImageBuild := func(name string) *bibv1alpha1.ImageBuild {
+		ib := newTestImageBuild(name)
+		ib.Spec.Build = &bibv1alpha1.BuildSpec{Cache: &bibv1alpha1.BuildCache{PVCName: "package-cache"}}
+		return ib
+	}
+
+	newCacheClaim := func(accessMode corev1.PersistentVolumeAccessMode) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "package-cache", Namespace: "default"},
+			Spec:       corev1.PersistentVolumeClaimSpec{AccessModes: []corev1.PersistentVolumeAccessMode{accessMode}},
+		}
+	}
+
+	runningBuilderUsingCache := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: builderPodPrefix + name, Namespace: "default"},
+			Spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+				Name: "build-cache",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "package-cache"},
+				},
+			}}},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+
+	It("mounts the cache PVC and tells the builder where it is", func() {
+		ib := newCachedImageBuild("cached")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Volumes).To(ContainElement(And(
+			HaveField("Name", "build-cache"),
+			HaveField("PersistentVolumeClaim.ClaimName", "package-cache"),
+		)))
+		Expect(pod.Spec.Containers[0].VolumeMounts).To(ContainElement(
+			corev1.VolumeMount{Name: "build-cache", MountPath: buildCacheMountPath},
+		))
+		Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "BUILD_CACHE_DIR", Value: buildCacheMountPath}))
+	})
+
+	It("mounts no cache unless requested", func() {
+		ib := newTestImageBuild("uncached")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Volumes).NotTo(ContainElement(HaveField("Name", "build-cache")))
+		Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "BUILD_CACHE_DIR")))
+	})
+
+	It("notes a ReadWriteOnce cache shared with another build without waiting for it", func() {
+		ib := newCachedImageBuild("second-cached")
+		r := newFakeReconciler(ib, newCacheClaim(corev1.ReadWriteOnce), runningBuilderUsingCache("first-cached"))
+
+		_, updated := reconcileBuild(r, "second-cached")
+		Expect(r.Get(ctx, types.NamespacedName{Name: builderPodPrefix + "second-cached", Namespace: "default"}, &corev1.Pod{})).To(Succeed())
+		Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.BuilderPodStartingReason))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.BuilderPodReady)).To(ContainSubstring(
+			"sharing build cache PVC package-cache, which does not allow ReadWriteMany, with pod " + builderPodPrefix + "first-cached"))
+	})
+
+	It("does not note a ReadWriteMany cache", func() {
+		ib := newCachedImageBuild("shared-cached")
+		r := newFakeReconciler(ib, newCacheClaim(corev1.ReadWriteMany), runningBuilderUsingCache("other-cached"))
+
+		_, updated := reconcileBuild(r, "shared-cached")
+		Expect(conditions.GetMessage(updated, bibv1alpha1.BuilderPodReady)).To(Equal("builder pod " + builderPodPrefix + "shared-cached created"))
+	})
+})
+
+var _ = Describe("ImageBuild output PVC recreation", func() {
+	ctx := context.Background()
+
+	newRecreatingImageBuild := func(name string) *bibv1alpha1.ImageBuild {
+		ib := newTestImageBuild(name)
+		ib.Spec.Output.PVC.CreateIfMissing = true
+		ib.Spec.Output.PVC.RecreateOnFailure = true
+		return ib
+	}
+
+	storageClass := "fast"
+	newClaim := func(name string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				StorageClassName: &storageClass,
+				Resources: corev1.VolumeResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse("20Gi"),
+				}},
+			},
+		}
+	}
+
+	failBuilderPod := func(r *ImageBuildReconciler, name, message string) {
+		pod := &corev1.Pod{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: builderPodPrefix + name, Namespace: "default"}, pod)).To(Succeed())
+		pod.Status = corev1.PodStatus{
+			Phase: corev1.PodFailed,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name: builderContainerName,
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: 1, Message: message,
+				}},
+			}},
+		}
+		Expect(r.Status().Update(ctx, pod)).To(Succeed())
+	}
+
+	const fullVolume = `{"outputStorage":{"message":"the output volume is full"}}`
+
+	claimExists := func(r *ImageBuildReconciler, name string) bool {
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, &corev1.PersistentVolumeClaim{})
+		if apierrors.IsNotFound(err) {
+			return false
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return true
+	}
+
+	It("retries a failed build on fresh claims, up to a limit", func() {
+		ib := newRecreatingImageBuild("full-disk")
+		r := newFakeReconciler(ib, newClaim("artifacts"))
+		reconcileBuild(r, "full-disk")
+		failBuilderPod(r, "full-disk", fullVolume)
+
+		By("creating a fresh claim like the failed one")
+		res, updated := reconcileBuild(r, "full-disk")
+		Expect(res.Requeue).To(BeTrue())
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhasePending))
+		Expect(updated.Status.OutputPVCRecreations).To(Equal(int32(1)))
+		Expect(conditions.GetReason(updated, bibv1alpha1.OutputReady)).To(Equal(bibv1alpha1.OutputPVCRecreatedReason))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.OutputReady)).To(ContainSubstring("full-disk-output-1 (1/3)"))
+		fresh := &corev1.PersistentVolumeClaim{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: "full-disk-output-1", Namespace: "default"}, fresh)).To(Succeed())
+		Expect(fresh.Spec.StorageClassName).To(HaveValue(Equal("fast")))
+		Expect(fresh.Spec.Resources.Requests).To(HaveKeyWithValue(corev1.ResourceStorage, resource.MustParse("20Gi")))
+		Expect(fresh.Labels).To(HaveKeyWithValue(bibv1alpha1.ImageBuildNameLabel, "full-disk"))
+		Expect(metav1.GetControllerOf(fresh)).To(HaveField("Name", "full-disk"))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.OutputReady)).To(ContainSubstring("the output volume is full"))
+		Expect(claimExists(r, "artifacts")).To(BeTrue())
+
+		By("building on the fresh claim")
+		reconcileBuild(r, "full-disk")
+		pod := &corev1.Pod{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: builderPodPrefix + "full-disk", Namespace: "default"}, pod)).To(Succeed())
+		Expect(pod.Spec.Volumes).To(ContainElement(HaveField("PersistentVolumeClaim.ClaimName", "full-disk-output-1")))
+
+		By("replacing a fresh claim the build failed on again")
+		failBuilderPod(r, "full-disk", fullVolume)
+		_, updated = reconcileBuild(r, "full-disk")
+		Expect(updated.Status.OutputPVCRecreations).To(Equal(int32(2)))
+		Expect(claimExists(r, "full-disk-output-2")).To(BeTrue())
+		Expect(claimExists(r, "full-disk-output-1")).To(BeFalse())
+
+		By("failing the build once the limit is reached")
+		updated.Status.OutputPVCRecreations = maxOutputPVCRecreations
+		Expect(r.Status().Update(ctx, updated)).To(Succeed())
+		Expect(r.Create(ctx, newClaim("full-disk-output-3"))).To(Succeed())
+		reconcileBuild(r, "full-disk")
+		failBuilderPod(r, "full-disk", fullVolume)
+		_, updated = reconcileBuild(r, "full-disk")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+		Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.BuildFailedReason))
+		Expect(claimExists(r, "full-disk-output-4")).To(BeFalse())
+	})
+
+	It("does not recreate the claim for a failed verification", func() {
+		ib := newRecreatingImageBuild("bad-image")
+		r := newFakeReconciler(ib, newClaim("artifacts"))
+		reconcileBuild(r, "bad-image")
+		failBuilderPod(r, "bad-image", `{"verify":{"passed":false,"message":"smoke test failed"}}`)
+
+		_, updated := reconcileBuild(r, "bad-image")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+		Expect(updated.Status.OutputPVCRecreations).To(BeZero())
+		Expect(claimExists(r, "bad-image-output-1")).To(BeFalse())
+	})
+
+	It("does not recreate the claim for a failure unrelated to it", func() {
+		ib := newRecreatingImageBuild("broken-playbook")
+		r := newFakeReconciler(ib, newClaim("artifacts"))
+		reconcileBuild(r, "broken-playbook")
+		failBuilderPod(r, "broken-playbook", `{"stages":[{"name":"provision","phase":"Failed","message":"exited with code 2"}]}`)
+
+		_, updated := reconcileBuild(r, "broken-playbook")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+		Expect(updated.Status.OutputPVCRecreations).To(BeZero())
+		Expect(claimExists(r, "broken-playbook-output-1")).To(BeFalse())
+	})
+
+	It("does not recreate the claim unless requested", func() {
+		ib := newTestImageBuild("plain")
+		r := newFakeReconciler(ib, newClaim("artifacts"))
+		reconcileBuild(r, "plain")
+		failBuilderPod(r, "plain", fullVolume)
+
+		_, updated := reconcileBuild(r, "plain")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+		Expect(claimExists(r, "plain-output-1")).To(BeFalse())
+	})
+})
+
+var _ = Describe("ImageBuild missing output PVC", func() {
+	ctx := context.Background()
+
+	It("creates the claim with createIfMissing", func() {
+		ib := newTestImageBuild("new-claim")
+		ib.Spec.Output.PVC.CreateIfMissing = true
+		storageClass := "fast"
+		ib.Spec.Output.PVC.StorageClassName = &storageClass
+		size := resource.MustParse("80Gi")
+		ib.Spec.Output.PVC.Size = &size
+		r := newFakeReconciler(ib)
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "new-claim", Namespace: "default"}})
+		Expect(err).NotTo(HaveOccurred())
+		claim := &corev1.PersistentVolumeClaim{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: ib.Spec.Output.PVC.Name, Namespace: "default"}, claim)).To(Succeed())
+		Expect(claim.OwnerReferences).To(BeEmpty())
+		Expect(claim.Spec.AccessModes).To(ConsistOf(corev1.ReadWriteOnce))
+		Expect(claim.Spec.StorageClassName).To(HaveValue(Equal("fast")))
+		Expect(claim.Spec.Resources.Requests).To(HaveKeyWithValue(corev1.ResourceStorage, size))
+		Expect(r.Get(ctx, types.NamespacedName{Name: builderPodPrefix + "new-claim", Namespace: "default"}, &corev1.Pod{})).To(Succeed())
+	})
+
+	It("defaults the size of the claim", func() {
+		ib := newTestImageBuild("default-size")
+		ib.Spec.Output.PVC.CreateIfMissing = true
+		r := newFakeReconciler(ib)
+
+		Expect(r.reconcileOutputPVCCreate(ctx, ib)).To(Succeed())
+		claim := &corev1.PersistentVolumeClaim{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: ib.Spec.Output.PVC.Name, Namespace: "default"}, claim)).To(Succeed())
+		Expect(claim.Spec.Resources.Requests).To(HaveKeyWithValue(corev1.ResourceStorage, defaultOutputPVCSize))
+	})
+
+	It("leaves a missing claim alone without createIfMissing", func() {
+		ib := newTestImageBuild("no-claim")
+		r := newFakeReconciler(ib)
+
+		Expect(r.reconcileOutputPVCCreate(ctx, ib)).To(Succeed())
+		err := r.Get(ctx, types.NamespacedName{Name: ib.Spec.Output.PVC.Name, Namespace: "default"}, &corev1.PersistentVolumeClaim{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
+
+var _ = Describe("ImageBuild output PVC retention", func() {
+	ctx := context.Background()
+
+	It("mounts the whole claim for the builder to prune older builds", func() {
+		ib := newTestImageBuild("nightly")
+		retain := int32(3)
+		ib.Spec.Output.PVC.Retain = &retain
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].VolumeMounts).To(ContainElements(
+			corev1.VolumeMount{Name: "output-pvc", MountPath: "/output", SubPath: "default/nightly"},
+			corev1.VolumeMount{Name: "output-pvc", MountPath: "/output-root"},
+		))
+		Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "OUTPUT_RETAIN", Value: "3"}))
+	})
+
+	It("keeps every build unless requested", func() {
+		ib := newTestImageBuild("kept")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].VolumeMounts).NotTo(ContainElement(HaveField("MountPath", "/output-root")))
+		Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "OUTPUT_RETAIN")))
+	})
+})
@@ -0,0 +1,829 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+	"github.com/zarcen/bib-operator/pkg/imageref"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// builderContainerName is the name of the container running the build in the builder pod.
+const builderContainerName = "builder"
+
+// defaultOutputFormats are produced for file outputs if spec.output.formats is not set.
+var defaultOutputFormats = []bibv1alpha1.OutputFormat{bibv1alpha1.FormatTGZ, bibv1alpha1.FormatQCOW2}
+
+// hasFileOutput returns true if an output keeps file artifacts, as opposed to a registry-only output.
+func hasFileOutput(imageBuild *bibv1alpha1.ImageBuild) bool {
+	output := imageBuild.Spec.Output
+	return output.PVC != nil || output.Volume != nil || output.ObjectStorage != nil
+}
+
+// outputFormats renders spec.output.formats as the comma-separated OUTPUT_FORMATS builder variable.
+// It is empty when the registry is the only output, as no file artifacts are written then.
+func outputFormats(imageBuild *bibv1alpha1.ImageBuild) string {
+	if !hasFileOutput(imageBuild) {
+		return ""
+	}
+	formats := requestedFormats(imageBuild)
+	names := make([]string, 0, len(formats))
+	for _, format := range formats {
+		names = append(names, string(format))
+	}
+	return strings.Join(names, ",")
+}
+
+// requestedFormats returns spec.output.formats, or the default formats if it is not set.
+func requestedFormats(imageBuild *bibv1alpha1.ImageBuild) []bibv1alpha1.OutputFormat {
+	if len(imageBuild.Spec.Output.Formats) == 0 {
+		return defaultOutputFormats
+	}
+	return imageBuild.Spec.Output.Formats
+}
+
+// hasDiskOutput returns true if imageBuild writes disk image artifacts.
+func hasDiskOutput(imageBuild *bibv1alpha1.ImageBuild) bool {
+	return hasFileOutput(imageBuild) && slices.Contains(requestedFormats(imageBuild), bibv1alpha1.FormatQCOW2)
+}
+
+// outputFilesystem returns the root filesystem of the disk image artifacts, defaulting to ext4.
+func outputFilesystem(imageBuild *bibv1alpha1.ImageBuild) bibv1alpha1.Filesystem {
+	if imageBuild.Spec.Output.Filesystem == "" {
+		return bibv1alpha1.FilesystemExt4
+	}
+	return imageBuild.Spec.Output.Filesystem
+}
+
+// diskPartitioning returns the partition table of the disk image artifacts, as the lower-case
+// DISK_PARTITION_TABLE builder variable, and whether they have an EFI system partition. Both
+// default to a GPT with an EFI system partition.
+func diskPartitioning(imageBuild *bibv1alpha1.ImageBuild) (string, bool) {
+	table, efi := bibv1alpha1.PartitionTableGPT, true
+	if partitioning := imageBuild.Spec.Output.Partitioning; partitioning != nil {
+		if partitioning.Table != "" {
+			table = partitioning.Table
+		}
+		if partitioning.EFISystemPartition != nil {
+			efi = *partitioning.EFISystemPartition
+		}
+	}
+	return strings.ToLower(string(table)), efi
+}
+
+// diskLayout is the DISK_LAYOUT builder variable, spec.output.diskLayout with the sizes in MiB.
+type diskLayout struct {
+	Table      string          `json:"table"`
+	SizeMiB    int64           `json:"sizeMiB,omitempty"`
+	Partitions []diskPartition `json:"partitions"`
+}
+
+// diskPartition is a partition of the DISK_LAYOUT builder variable. A partition without SizeMiB
+// takes the rest of the disk.
+type diskPartition struct {
+	Label      string `json:"label,omitempty"`
+	SizeMiB    int64  `json:"sizeMiB,omitempty"`
+	Filesystem string `json:"filesystem"`
+	MountPoint string `json:"mountPoint,omitempty"`
+}
+
+// diskLayoutConfig renders a custom disk layout as the JSON DISK_LAYOUT builder variable. Sizes are
+// rounded up to whole MiB, as the builder aligns the partitions to them.
+func diskLayoutConfig(layout *bibv1alpha1.DiskLayout) string {
+	config := diskLayout{Table: "gpt", Partitions: []diskPartition{}}
+	if layout.Table != "" {
+		config.Table = strings.ToLower(string(layout.Table))
+	}
+	if layout.Size != nil {
+		config.SizeMiB = mebibytes(layout.Size.Value())
+	}
+	for _, partition := range layout.Partitions {
+		p := diskPartition{Label: partition.Label, Filesystem: string(partition.Filesystem), MountPoint: partition.MountPoint}
+		if partition.Size != nil {
+			p.SizeMiB = mebibytes(partition.Size.Value())
+		}
+		config.Partitions = append(config.Partitions, p)
+	}
+	data, _ := json.Marshal(config)
+	return string(data)
+}
+
+// mebibytes returns the number of MiB needed to hold the given number of bytes.
+func mebibytes(bytes int64) int64 {
+	return (bytes + 1<<20 - 1) >> 20
+}
+
+// sanitizeEnv returns the variables selecting the cleanups the builder runs on the image before
+// packaging it. Without spec.output.sanitize, the cleanups only run for builds writing disk images,
+// which boot as machines; with it, cleanups not disabled there are enabled.
+func sanitizeEnv(imageBuild *bibv1alpha1.ImageBuild) []corev1.EnvVar {
+	sanitize := imageBuild.Spec.Output.Sanitize
+	enabled := sanitize != nil || hasDiskOutput(imageBuild)
+	machineID, cloudInit, logs := enabled, enabled, enabled
+	if sanitize != nil {
+		if sanitize.ResetMachineID != nil {
+			machineID = *sanitize.ResetMachineID
+		}
+		if sanitize.CleanCloudInit != nil {
+			cloudInit = *sanitize.CleanCloudInit
+		}
+		if sanitize.TruncateLogs != nil {
+			logs = *sanitize.TruncateLogs
+		}
+	}
+	return []corev1.EnvVar{
+		{Name: "SANITIZE_MACHINE_ID", Value: strconv.FormatBool(machineID)},
+		{Name: "SANITIZE_CLOUD_INIT", Value: strconv.FormatBool(cloudInit)},
+		{Name: "SANITIZE_LOGS", Value: strconv.FormatBool(logs)},
+	}
+}
+
+// buildSummary outlines the build for status.summary as "<provisioner>→<outputs>[→<publish>]",
+// e.g. "ansible→pvc,s3(tgz,qcow2)→aws". The artifact formats follow the file outputs; a registry
+// output is listed last, without formats.
+func buildSummary(imageBuild *bibv1alpha1.ImageBuild) string {
+	spec := imageBuild.Spec
+	provisioner := "none"
+	if spec.Provisioner != nil {
+		switch {
+		case spec.Provisioner.Ansible != nil:
+			provisioner = "ansible"
+		case spec.Provisioner.Packer != nil:
+			provisioner = "packer"
+		case spec.Provisioner.Dockerfile != nil:
+			provisioner = "dockerfile"
+		case spec.Provisioner.Kickstart != nil:
+			provisioner = "kickstart"
+		case spec.Provisioner.Bootc != nil:
+			provisioner = "bootc"
+		}
+	}
+
+	var outputs []string
+	if spec.Output.PVC != nil {
+		outputs = append(outputs, "pvc")
+	}
+	if spec.Output.Volume != nil {
+		outputs = append(outputs, "volume")
+	}
+	if spec.Output.ObjectStorage != nil {
+		outputs = append(outputs, "s3")
+	}
+	summary := provisioner + "→" + strings.Join(outputs, ",")
+	if formats := outputFormats(imageBuild); formats != "" {
+		summary += "(" + formats + ")"
+	}
+	if spec.Output.Registry != nil {
+		if len(outputs) > 0 {
+			summary += ","
+		}
+		summary += "registry"
+	}
+
+	if spec.Publish != nil {
+		switch {
+		case spec.Publish.AWS != nil:
+			summary += "→aws"
+		case spec.Publish.MaaS != nil:
+			summary += "→maas"
+		}
+	}
+	return summary
+}
+
+// artifactMetadataEnv returns the variables the builder stamps on the artifacts it creates, e.g. as
+// object metadata or OCI labels, so external garbage collection and lifecycle rules can act on them.
+func artifactMetadataEnv(imageBuild *bibv1alpha1.ImageBuild, created time.Time) []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{Name: "ARTIFACT_IMAGEBUILD_NAME", Value: imageBuild.Name},
+		{Name: "ARTIFACT_IMAGEBUILD_NAMESPACE", Value: imageBuild.Namespace},
+		{Name: "ARTIFACT_IMAGEBUILD_UID", Value: string(imageBuild.UID)},
+		{Name: "ARTIFACT_CREATED", Value: created.UTC().Format(time.RFC3339)},
+	}
+}
+
+// buildLogURL returns the location the builder uploads the build log to, or "" if it is not uploaded.
+func buildLogURL(imageBuild *bibv1alpha1.ImageBuild) string {
+	if imageBuild.Spec.Build == nil || imageBuild.Spec.Build.LogsOutput == nil {
+		return ""
+	}
+	return fmt.Sprintf("s3://%s/%s/%s/build.log", imageBuild.Spec.Build.LogsOutput.Bucket, imageBuild.Namespace, imageBuild.Name)
+}
+
+// uploadedBuildLogURL returns the location of the build log if the finished builder pod reported its
+// upload as succeeded, and "" otherwise.
+func uploadedBuildLogURL(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild, pod *corev1.Pod) string {
+	url := buildLogURL(imageBuild)
+	if url == "" {
+		return ""
+	}
+	result, err := parseBuilderResult(pod)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Ignoring the build log upload", "PodName", pod.Name)
+		return ""
+	}
+	if result == nil {
+		return ""
+	}
+	for _, upload := range result.Uploads {
+		if upload.URL == url && upload.Phase == bibv1alpha1.BuildStageSucceeded {
+			return url
+		}
+	}
+	return ""
+}
+
+// buildLogsEnv returns the variables instructing the builder to upload its log.
+func buildLogsEnv(imageBuild *bibv1alpha1.ImageBuild) []corev1.EnvVar {
+	url := buildLogURL(imageBuild)
+	if url == "" {
+		return nil
+	}
+	return objectStorageEnv("LOGS_", url, imageBuild.Spec.Build.LogsOutput)
+}
+
+// objectStorageOutputURL returns the prefix the builder uploads the artifacts of an object storage output to.
+func objectStorageOutputURL(imageBuild *bibv1alpha1.ImageBuild) string {
+	return fmt.Sprintf("s3://%s/%s/%s/", imageBuild.Spec.Output.ObjectStorage.Bucket, imageBuild.Namespace, imageBuild.Name)
+}
+
+// objectStorageEnv returns the variables describing an upload to url. They are prefixed so the
+// credentials of different uploads, e.g. the artifacts and the build log, do not clash.
+func objectStorageEnv(prefix, url string, output *bibv1alpha1.ObjectStorageOutput) []corev1.EnvVar {
+	secretKeyRef := func(key string) *corev1.EnvVarSource {
+		return &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: output.CredentialsSecretName},
+			Key:                  key,
+		}}
+	}
+	env := []corev1.EnvVar{
+		{Name: prefix + "S3_URL", Value: url},
+		{Name: prefix + "S3_REGION", Value: output.Region},
+		{Name: prefix + "AWS_ACCESS_KEY_ID", ValueFrom: secretKeyRef("AWS_ACCESS_KEY_ID")},
+		{Name: prefix + "AWS_SECRET_ACCESS_KEY", ValueFrom: secretKeyRef("AWS_SECRET_ACCESS_KEY")},
+	}
+	if output.ContentType != "" {
+		env = append(env, corev1.EnvVar{Name: prefix + "S3_CONTENT_TYPE", Value: output.ContentType})
+	}
+	if output.StorageClass != "" {
+		env = append(env, corev1.EnvVar{Name: prefix + "S3_STORAGE_CLASS", Value: string(output.StorageClass)})
+	}
+	if output.UploadConcurrency > 0 {
+		env = append(env, corev1.EnvVar{Name: prefix + "S3_UPLOAD_CONCURRENCY", Value: strconv.Itoa(int(output.UploadConcurrency))})
+	}
+	return append(env, assumeRoleEnv(prefix, &output.AWSAssumeRole)...)
+}
+
+// assumeRoleEnv returns the variables describing the IAM role to assume with the static credentials, if any.
+func assumeRoleEnv(prefix string, role *bibv1alpha1.AWSAssumeRole) []corev1.EnvVar {
+	if role.RoleARN == "" {
+		return nil
+	}
+	env := []corev1.EnvVar{{Name: prefix + "AWS_ASSUME_ROLE_ARN", Value: role.RoleARN}}
+	if role.ExternalID != "" {
+		env = append(env, corev1.EnvVar{Name: prefix + "AWS_ASSUME_ROLE_EXTERNAL_ID", Value: role.ExternalID})
+	}
+	return env
+}
+
+// registryCompressionFormat returns the layer compression of a registry push, defaulting to gzip.
+func registryCompressionFormat(registry *bibv1alpha1.RegistryOutput) string {
+	if registry.CompressionFormat == "" {
+		return string(bibv1alpha1.CompressionGzip)
+	}
+	return string(registry.CompressionFormat)
+}
+
+// registryImageConfig renders spec.output.registry.config as the JSON REGISTRY_IMAGE_CONFIG builder variable,
+// with the fields named as in the spec.
+func registryImageConfig(config *bibv1alpha1.ImageConfig) string {
+	data, _ := json.Marshal(config)
+	return string(data)
+}
+
+// registryRepositoryEnv returns the variables describing the cloud registry repository the builder
+// creates before the push, if the registry output asks for it.
+func registryRepositoryEnv(registry *bibv1alpha1.RegistryOutput) []corev1.EnvVar {
+	if !registry.CreateRepository {
+		return nil
+	}
+	ref, err := imageref.ParseDestination(registry.Destination)
+	if err != nil {
+		return nil
+	}
+	repo, ok := ref.CloudRepository()
+	if !ok {
+		return nil
+	}
+	env := []corev1.EnvVar{
+		{Name: "REGISTRY_CREATE_REPOSITORY", Value: string(repo.Provider)},
+		{Name: "REGISTRY_REPOSITORY", Value: repo.Repository},
+		{Name: "REGISTRY_REPOSITORY_LOCATION", Value: repo.Location},
+		{Name: "REGISTRY_REPOSITORY_OWNER", Value: repo.Owner},
+	}
+	if registry.RepositoryCredentialsSecretName != "" {
+		secretKeyRef := func(key string) *corev1.EnvVarSource {
+			return &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: registry.RepositoryCredentialsSecretName},
+				Key:                  key,
+			}}
+		}
+		env = append(env,
+			corev1.EnvVar{Name: "REGISTRY_AWS_ACCESS_KEY_ID", ValueFrom: secretKeyRef("AWS_ACCESS_KEY_ID")},
+			corev1.EnvVar{Name: "REGISTRY_AWS_SECRET_ACCESS_KEY", ValueFrom: secretKeyRef("AWS_SECRET_ACCESS_KEY")},
+		)
+	}
+	return env
+}
+
+// outputPVCSubPath returns the directory within the output PVC that holds the artifacts of imageBuild.
+func outputPVCSubPath(imageBuild *bibv1alpha1.ImageBuild) string {
+	return outputSubPath(imageBuild, imageBuild.Spec.Output.PVC.SubPath)
+}
+
+// outputVolumeSubPath returns the directory within the output volume that holds the artifacts of imageBuild.
+func outputVolumeSubPath(imageBuild *bibv1alpha1.ImageBuild) string {
+	return outputSubPath(imageBuild, imageBuild.Spec.Output.Volume.SubPath)
+}
+
+// outputSubPath returns subPath, or the per-build "<namespace>/<name>" directory if it is not set.
+func outputSubPath(imageBuild *bibv1alpha1.ImageBuild, subPath string) string {
+	if subPath != "" {
+		return path.Clean(subPath)
+	}
+	return path.Join(imageBuild.Namespace, imageBuild.Name)
+}
+
+// outputVolumeURL returns the location of the artifacts on the output volume, as nfs://<server>/<path>
+// or csi://<driver>/<path>.
+func outputVolumeURL(imageBuild *bibv1alpha1.ImageBuild) string {
+	volume := imageBuild.Spec.Output.Volume
+	subPath := outputVolumeSubPath(imageBuild)
+	if volume.NFS != nil {
+		return fmt.Sprintf("nfs://%s%s", volume.NFS.Server, path.Join("/", volume.NFS.Path, subPath))
+	}
+	return fmt.Sprintf("csi://%s/%s", volume.CSI.Driver, subPath)
+}
+
+// recordOutputLocation records where the artifacts of a succeeded build are kept, for every output.
+func recordOutputLocation(imageBuild *bibv1alpha1.ImageBuild) {
+	var urls []string
+	if imageBuild.Spec.Output.PVC != nil {
+		claimName, subPath := outputPVCName(imageBuild), outputPVCSubPath(imageBuild)
+		imageBuild.Status.OutputPVC = &bibv1alpha1.OutputPVCStatus{ClaimName: claimName, Path: subPath}
+		urls = append(urls, fmt.Sprintf("pvc://%s/%s", claimName, subPath))
+	}
+	if imageBuild.Spec.Output.Volume != nil {
+		urls = append(urls, outputVolumeURL(imageBuild))
+	}
+	if imageBuild.Spec.Output.ObjectStorage != nil {
+		urls = append(urls, objectStorageOutputURL(imageBuild))
+	}
+	// The file artifacts are reported in the first output keeping them.
+	var fileOutputURL string
+	if len(urls) > 0 {
+		fileOutputURL = urls[0]
+	}
+	urls = append(urls, registryOutputURLs(imageBuild)...)
+	imageBuild.Status.OutputURLs = urls
+	recordArtifactURLs(imageBuild, fileOutputURL)
+	if artifacts := imageBuild.Status.Artifacts; len(artifacts) > 0 && artifacts[0].URL != "" {
+		imageBuild.Status.OutputURL = artifacts[0].URL
+	} else if len(urls) > 0 {
+		imageBuild.Status.OutputURL = urls[0]
+	}
+}
+
+// recordArtifactURLs orders the artifacts reported by the builder, the file artifacts in the order of
+// spec.output.formats, formats the operator does not know next, then the container images, and sets
+// the URL of each: its location under fileOutputURL, the first output keeping file artifacts, or its
+// image reference.
+func recordArtifactURLs(imageBuild *bibv1alpha1.ImageBuild, fileOutputURL string) {
+	formats, destinations := requestedFormats(imageBuild), registryOutputURLs(imageBuild)
+	rank := func(artifact bibv1alpha1.ArtifactStatus) int {
+		if artifact.Format == bibv1alpha1.FormatOCI {
+			// The builder may push to the destinations concurrently, and report them in any order.
+			if i := slices.Index(destinations, "docker://"+artifact.Name); i >= 0 {
+				return len(formats) + 1 + i
+			}
+			return len(formats) + 1 + len(destinations)
+		}
+		if i := slices.Index(formats, artifact.Format); i >= 0 {
+			return i
+		}
+		return len(formats)
+	}
+	slices.SortStableFunc(imageBuild.Status.Artifacts, func(a, b bibv1alpha1.ArtifactStatus) int {
+		return cmp.Compare(rank(a), rank(b))
+	})
+	for i := range imageBuild.Status.Artifacts {
+		artifact := &imageBuild.Status.Artifacts[i]
+		switch {
+		case artifact.Format == bibv1alpha1.FormatOCI:
+			artifact.URL = "docker://" + artifact.Name
+		case fileOutputURL != "":
+			artifact.URL = strings.TrimSuffix(fileOutputURL, "/") + "/" + artifact.Name
+		}
+	}
+}
+
+// uploadDestinationURLs returns the outputs the builder uploads to, rather than writes in place, in
+// the order of status.outputURLs: the object storage prefix, then the registry destinations.
+func uploadDestinationURLs(imageBuild *bibv1alpha1.ImageBuild) []string {
+	var urls []string
+	if imageBuild.Spec.Output.ObjectStorage != nil {
+		urls = append(urls, objectStorageOutputURL(imageBuild))
+	}
+	return append(urls, registryOutputURLs(imageBuild)...)
+}
+
+// registryOutputURLs returns the destinations of the registry output, the primary one first.
+func registryOutputURLs(imageBuild *bibv1alpha1.ImageBuild) []string {
+	var urls []string
+	if registry := imageBuild.Spec.Output.Registry; registry != nil {
+		urls = append(urls, "docker://"+registry.Destination)
+		for _, destination := range registry.AdditionalDestinations {
+			urls = append(urls, "docker://"+destination.Destination)
+		}
+	}
+	return urls
+}
+
+// builderResult is the JSON document a builder writes to its termination message. On success it
+// lists the artifacts; if the verification step fails, or a registry rate limits a pull or push,
+// it reports the failure before exiting. Either way it may list the outcome of each of its stages
+// and of each of its uploads.
+type builderResult struct {
+	Artifacts     []bibv1alpha1.ArtifactStatus   `json:"artifacts,omitempty"`
+	Verify        *verifyResult                  `json:"verify,omitempty"`
+	RateLimited   *rateLimitResult               `json:"rateLimited,omitempty"`
+	Stages        []bibv1alpha1.BuildStageStatus `json:"stages,omitempty"`
+	Uploads       []uploadResult                 `json:"uploads,omitempty"`
+	SourceClone   *sourceCloneResult             `json:"sourceClone,omitempty"`
+	OutputStorage *outputStorageResult           `json:"outputStorage,omitempty"`
+}
+
+// outputStorageResult describes why the output volume of a failed build could not take the artifacts.
+type outputStorageResult struct {
+	Message string `json:"message"`
+}
+
+// uploadResult reports the outcome of a single push or upload, e.g. one artifact file of an object
+// storage output. The builder lists them in no particular order, e.g.
+// the order concurrent uploads completed in.
+type uploadResult struct {
+	// URL is where the upload went, e.g. "docker://<destination>" or "s3://<bucket>/<key>".
+	URL     string                      `json:"url"`
+	Phase   bibv1alpha1.BuildStagePhase `json:"phase"`
+	Message string                      `json:"message,omitempty"`
+}
+
+// verifyResult reports the outcome of the spec.verify step.
+type verifyResult struct {
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	rateLimitOperationPull = "pull"
+	rateLimitOperationPush = "push"
+)
+
+// rateLimitResult reports a registry operation rejected with 429 Too Many Requests.
+type rateLimitResult struct {
+	// Operation is "pull" for the base image or "push" for the registry output.
+	Operation string `json:"operation"`
+	// Image is the image reference that was pulled or pushed.
+	Image string `json:"image"`
+	// Message is the error reported by the registry client.
+	Message string `json:"message,omitempty"`
+}
+
+// sourceCloneResult reports a git repository the builder failed to clone, e.g. for a missing branch,
+// rejected credentials or an unreachable server.
+type sourceCloneResult struct {
+	// Repo is the URL of the repository.
+	Repo string `json:"repo"`
+	// Branch is the branch that was checked out.
+	Branch string `json:"branch,omitempty"`
+	// SubmoduleOf is the URL of the superproject if Repo is one of its submodules.
+	SubmoduleOf string `json:"submoduleOf,omitempty"`
+	// Message is the error reported by git.
+	Message string `json:"message,omitempty"`
+}
+
+// terminatedState returns the terminated state of the named container of pod, or nil if it has not terminated.
+func terminatedState(pod *corev1.Pod, containerName string) *corev1.ContainerStateTerminated {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == containerName {
+			return status.State.Terminated
+		}
+	}
+	return nil
+}
+
+// oomKilledReason is the termination reason of a container killed for exceeding its memory limit.
+const oomKilledReason = "OOMKilled"
+
+// builderOOMKilled returns true if the builder container of pod was killed for exceeding its memory limit.
+func builderOOMKilled(pod *corev1.Pod) bool {
+	terminated := terminatedState(pod, builderContainerName)
+	return terminated != nil && terminated.Reason == oomKilledReason
+}
+
+// terminationMessage returns the termination message of the named, terminated container of pod.
+func terminationMessage(pod *corev1.Pod, containerName string) string {
+	if terminated := terminatedState(pod, containerName); terminated != nil {
+		return strings.TrimSpace(terminated.Message)
+	}
+	return ""
+}
+
+// recordBuilderTermination copies the exit code and reason of the terminated builder container into the status.
+func recordBuilderTermination(imageBuild *bibv1alpha1.ImageBuild, pod *corev1.Pod) {
+	terminated := terminatedState(pod, builderContainerName)
+	if terminated == nil {
+		return
+	}
+	exitCode := terminated.ExitCode
+	imageBuild.Status.ExitCode = &exitCode
+	imageBuild.Status.TerminationReason = terminated.Reason
+}
+
+// describeBuilderTermination returns how the builder container terminated, e.g.
+// ": builder container exited with code 137 (OOMKilled)", to complete a failure message.
+func describeBuilderTermination(imageBuild *bibv1alpha1.ImageBuild) string {
+	if imageBuild.Status.ExitCode == nil {
+		return ""
+	}
+	description := fmt.Sprintf(": %s container exited with code %d", builderContainerName, *imageBuild.Status.ExitCode)
+	if imageBuild.Status.TerminationReason != "" {
+		description += fmt.Sprintf(" (%s)", imageBuild.Status.TerminationReason)
+	}
+	return description
+}
+
+// parseBuilderResult reads the builder result from the terminated builder container of pod.
+// It returns nil if the builder did not report a result, which is allowed for custom builders.
+func parseBuilderResult(pod *corev1.Pod) (*builderResult, error) {
+	message := terminationMessage(pod, builderContainerName)
+	if message == "" {
+		return nil, nil
+	}
+	result := &builderResult{}
+	if err := json.Unmarshal([]byte(message), result); err != nil {
+		return nil, fmt.Errorf("failed to decode builder result: %w", err)
+	}
+	return result, nil
+}
+
+// recordBuilderResult copies the artifacts reported by a succeeded builder pod into the status.
+// A malformed result is logged but does not fail the build, the artifacts have been written regardless.
+func (r *ImageBuildReconciler) recordBuilderResult(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild, pod *corev1.Pod) {
+	result, err := parseBuilderResult(pod)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Ignoring builder result", "PodName", pod.Name)
+		return
+	}
+	if result != nil {
+		imageBuild.Status.Artifacts = result.Artifacts
+	}
+}
+
+// Stages reported by the bundled builder.
+const (
+	builderStagePull      = "pull"
+	builderStageProvision = "provision"
+	builderStageVerify    = "verify"
+	builderStagePush      = "push"
+	builderStageSign      = "sign"
+	builderStageConvert   = "convert"
+	builderStageUpload    = "upload"
+)
+
+// stageCondition returns the condition reporting the outcome of a builder stage. Stages the
+// operator does not know, e.g. an SBOM generation step of a custom builder, are reported on BuilderPodReady.
+func stageCondition(name string) clusterv1beta1.ConditionType {
+	switch name {
+	case builderStagePull:
+		return bibv1alpha1.BaseImageReady
+	case builderStageProvision:
+		return bibv1alpha1.ProvisionerReady
+	case builderStageVerify:
+		return bibv1alpha1.VerifyReady
+	case builderStageSign:
+		return bibv1alpha1.SigningReady
+	case builderStagePush, builderStageConvert, builderStageUpload:
+		return bibv1alpha1.OutputReady
+	default:
+		return bibv1alpha1.BuilderPodReady
+	}
+}
+
+// recordBuilderStages copies the stages reported by a finished builder pod into the status and marks the
+// conditions they map to: true if all of their stages succeeded, false with BuildStageFailedReason for the
+// first failed one. The failure has Severity=Error if the pod failed, and Severity=Warning if the builder
+// still succeeded. It returns the first failed stage, or nil. Builders reporting no stages change nothing.
+func recordBuilderStages(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild, pod *corev1.Pod) *bibv1alpha1.BuildStageStatus {
+	result, err := parseBuilderResult(pod)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Ignoring builder stages", "PodName", pod.Name)
+		return nil
+	}
+	if result == nil || len(result.Stages) == 0 {
+		return nil
+	}
+	imageBuild.Status.Stages = result.Stages
+
+	severity := clusterv1beta1.ConditionSeverityWarning
+	if pod.Status.Phase == corev1.PodFailed {
+		severity = clusterv1beta1.ConditionSeverityError
+	}
+	var firstFailed *bibv1alpha1.BuildStageStatus
+	failed := map[clusterv1beta1.ConditionType]bool{}
+	for i, stage := range result.Stages {
+		if stage.Phase != bibv1alpha1.BuildStageFailed {
+			continue
+		}
+		if firstFailed == nil {
+			firstFailed = &result.Stages[i]
+		}
+		conditionType := stageCondition(stage.Name)
+		if failed[conditionType] {
+			continue
+		}
+		failed[conditionType] = true
+		conditions.MarkFalse(imageBuild, conditionType, bibv1alpha1.BuildStageFailedReason, severity,
+			"stage %s failed%s", stage.Name, describeStageMessage(stage))
+	}
+	for _, stage := range result.Stages {
+		if conditionType := stageCondition(stage.Name); stage.Phase == bibv1alpha1.BuildStageSucceeded && !failed[conditionType] {
+			conditions.MarkTrue(imageBuild, conditionType)
+		}
+	}
+	return firstFailed
+}
+
+// recordBuilderDestinations aggregates the uploads reported by a finished builder pod per output
+// destination into the status, in the order of status.outputURLs, independent of the order the
+// uploads completed in. A destination failed if any of its uploads did, and the failed ones are
+// listed on OutputReady. Uploads outside the outputs are ignored.
+func recordBuilderDestinations(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild, pod *corev1.Pod) {
+	result, err := parseBuilderResult(pod)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Ignoring builder uploads", "PodName", pod.Name)
+		return
+	}
+	if result == nil || len(result.Uploads) == 0 {
+		return
+	}
+
+	var destinations []bibv1alpha1.OutputDestinationStatus
+	var failures []string
+	for _, url := range uploadDestinationURLs(imageBuild) {
+		destination := bibv1alpha1.OutputDestinationStatus{URL: url}
+		var messages []string
+		for _, upload := range result.Uploads {
+			// Object storage uploads are reported per file below the output prefix.
+			if upload.URL != url && !(strings.HasSuffix(url, "/") && strings.HasPrefix(upload.URL, url)) {
+				continue
+			}
+			if destination.Phase == "" {
+				destination.Phase = bibv1alpha1.BuildStageSucceeded
+			}
+			if upload.Phase != bibv1alpha1.BuildStageFailed {
+				continue
+			}
+			destination.Phase = bibv1alpha1.BuildStageFailed
+			message := upload.Message
+			if upload.URL != url {
+				message = strings.TrimPrefix(upload.URL, url) + describeUploadMessage(upload)
+			}
+			messages = append(messages, message)
+		}
+		if destination.Phase == "" {
+			continue
+		}
+		if destination.Phase == bibv1alpha1.BuildStageFailed {
+			sort.Strings(messages)
+			destination.Message = strings.Join(messages, "; ")
+			failures = append(failures, url)
+		}
+		destinations = append(destinations, destination)
+	}
+	imageBuild.Status.OutputDestinations = destinations
+
+	if len(failures) > 0 {
+		conditions.MarkFalse(imageBuild, bibv1alpha1.OutputReady, bibv1alpha1.OutputDestinationFailedReason, clusterv1beta1.ConditionSeverityError,
+			"upload to %s failed", strings.Join(failures, ", "))
+	}
+}
+
+// describeUploadMessage returns ": <message>" to complete an upload failure message, or "" without a message.
+func describeUploadMessage(upload uploadResult) string {
+	if upload.Message == "" {
+		return ""
+	}
+	return ": " + upload.Message
+}
+
+// describeFailedStage returns " in stage <name>" to complete a build failure message, or "" without a failed stage.
+func describeFailedStage(stage *bibv1alpha1.BuildStageStatus) string {
+	if stage == nil {
+		return ""
+	}
+	return " in stage " + stage.Name
+}
+
+// describeStageMessage returns ": <message>" to complete a stage failure message, or "" without a message.
+func describeStageMessage(stage bibv1alpha1.BuildStageStatus) string {
+	if stage.Message == "" {
+		return ""
+	}
+	return ": " + stage.Message
+}
+
+// describeSourceCloneFailure returns the message of a git repository the builder failed to clone.
+func describeSourceCloneFailure(clone *sourceCloneResult) string {
+	message := "failed to clone " + clone.Repo
+	if clone.SubmoduleOf != "" {
+		message = "failed to clone submodule " + clone.Repo + " of " + clone.SubmoduleOf
+	}
+	if clone.Branch != "" {
+		message += " at branch " + clone.Branch
+	}
+	if clone.Message != "" {
+		message += ": " + clone.Message
+	}
+	return message
+}
+
+// verificationFailure returns the message of a failed verification reported by a failed builder pod.
+func verificationFailure(pod *corev1.Pod) (string, bool) {
+	result, err := parseBuilderResult(pod)
+	if err != nil || result == nil || result.Verify == nil || result.Verify.Passed {
+		return "", false
+	}
+	message := result.Verify.Message
+	if message == "" {
+		message = "verification failed"
+	}
+	return message, true
+}
+
+// sourceCloneFailure returns the git repository a failed builder pod reported it failed to clone.
+func sourceCloneFailure(pod *corev1.Pod) (*sourceCloneResult, bool) {
+	result, err := parseBuilderResult(pod)
+	if err != nil || result == nil || result.SourceClone == nil {
+		return nil, false
+	}
+	return result.SourceClone, true
+}
+
+// outputStorageFailure returns why a failed builder pod reported its output volume unusable, e.g.
+// "the output volume is full".
+func outputStorageFailure(pod *corev1.Pod) (string, bool) {
+	result, err := parseBuilderResult(pod)
+	if err != nil || result == nil || result.OutputStorage == nil {
+		return "", false
+	}
+	return result.OutputStorage.Message, true
+}
+
+// registryRateLimit returns the rate-limited registry operation reported by a failed builder pod.
+func registryRateLimit(pod *corev1.Pod) (*rateLimitResult, bool) {
+	result, err := parseBuilderResult(pod)
+	if err != nil || result == nil || result.RateLimited == nil {
+		return nil, false
+	}
+	return result.RateLimited, true
+}
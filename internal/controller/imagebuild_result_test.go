@@ -0,0 +1,1020 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+var _ = Describe("ImageBuild builder result", func() {
+	ctx := context.Background()
+
+	succeededBuilderPod := func(name, message string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: builderPodPrefix + name, Namespace: "default"},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodSucceeded,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  builderContainerName,
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: message}},
+				}},
+			},
+		}
+	}
+
+	It("passes the output formats and disk image options to the builder", func() {
+		ib := newTestImageBuild("formats")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+			corev1.EnvVar{Name: "OUTPUT_FORMATS", Value: "tgz,qcow2"},
+			corev1.EnvVar{Name: "OUTPUT_FILESYSTEM", Value: "ext4"},
+			corev1.EnvVar{Name: "DISK_PARTITION_TABLE", Value: "gpt"},
+			corev1.EnvVar{Name: "DISK_EFI_PARTITION", Value: "true"},
+		))
+		for _, env := range pod.Spec.Containers[0].Env {
+			Expect(env.Name).NotTo(Equal("QCOW2_COMPRESS"))
+		}
+
+		ib.Spec.Output.Formats = []bibv1alpha1.OutputFormat{bibv1alpha1.FormatQCOW2}
+		ib.Spec.Output.QCOW2 = &bibv1alpha1.QCOW2Options{Compress: true}
+		ib.Spec.Output.Filesystem = bibv1alpha1.FilesystemXFS
+		efi := false
+		ib.Spec.Output.Partitioning = &bibv1alpha1.Partitioning{Table: bibv1alpha1.PartitionTableMBR, EFISystemPartition: &efi}
+		pod, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+			corev1.EnvVar{Name: "OUTPUT_FORMATS", Value: "qcow2"},
+			corev1.EnvVar{Name: "QCOW2_COMPRESS", Value: "true"},
+			corev1.EnvVar{Name: "OUTPUT_FILESYSTEM", Value: "xfs"},
+			corev1.EnvVar{Name: "DISK_PARTITION_TABLE", Value: "mbr"},
+			corev1.EnvVar{Name: "DISK_EFI_PARTITION", Value: "false"},
+		))
+		Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "QCOW2_PREALLOCATION")))
+
+		ib.Spec.Output.QCOW2 = &bibv1alpha1.QCOW2Options{Preallocation: bibv1alpha1.QCOW2PreallocationFalloc}
+		pod, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "QCOW2_PREALLOCATION", Value: "falloc"}))
+	})
+
+	It("passes a custom disk layout as JSON instead of the partitioning options", func() {
+		ib := newTestImageBuild("disk-layout")
+		diskSize := resource.MustParse("20Gi")
+		espSize, bootSize := resource.MustParse("512Mi"), resource.MustParse("1G")
+		ib.Spec.Output.DiskLayout = &bibv1alpha1.DiskLayout{
+			Size: &diskSize,
+			Partitions: []bibv1alpha1.DiskPartition{
+				{Size: &espSize, Filesystem: bibv1alpha1.PartitionFilesystemVFAT, MountPoint: "/boot/efi"},
+				{Label: "boot", Size: &bootSize, Filesystem: bibv1alpha1.PartitionFilesystemExt4, MountPoint: "/boot"},
+				{Label: "root", Filesystem: bibv1alpha1.PartitionFilesystemXFS, MountPoint: "/"},
+			},
+		}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		env := pod.Spec.Containers[0].Env
+		Expect(env).To(ContainElement(corev1.EnvVar{Name: "DISK_LAYOUT", Value: `{"table":"gpt","sizeMiB":20480,"partitions":[` +
+			`{"sizeMiB":512,"filesystem":"vfat","mountPoint":"/boot/efi"},` +
+			// 1G is rounded up to whole MiB.
+			`{"label":"boot","sizeMiB":954,"filesystem":"ext4","mountPoint":"/boot"},` +
+			`{"label":"root","filesystem":"xfs","mountPoint":"/"}]}`}))
+		Expect(env).NotTo(ContainElement(HaveField("Name", "DISK_PARTITION_TABLE")))
+		Expect(env).NotTo(ContainElement(HaveField("Name", "OUTPUT_FILESYSTEM")))
+
+		ib.Spec.Output.DiskLayout = &bibv1alpha1.DiskLayout{
+			Table:      bibv1alpha1.PartitionTableMBR,
+			Partitions: []bibv1alpha1.DiskPartition{{Filesystem: bibv1alpha1.PartitionFilesystemExt4, MountPoint: "/"}},
+		}
+		Expect(diskLayoutConfig(ib.Spec.Output.DiskLayout)).To(Equal(`{"table":"mbr","partitions":[{"filesystem":"ext4","mountPoint":"/"}]}`))
+	})
+
+	It("enables the image sanitization for disk images unless disabled", func() {
+		ib := newTestImageBuild("sanitize")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+			corev1.EnvVar{Name: "SANITIZE_MACHINE_ID", Value: "true"},
+			corev1.EnvVar{Name: "SANITIZE_CLOUD_INIT", Value: "true"},
+			corev1.EnvVar{Name: "SANITIZE_LOGS", Value: "true"},
+		))
+
+		disabled := false
+		ib.Spec.Output.Sanitize = &bibv1alpha1.OutputSanitize{CleanCloudInit: &disabled, TruncateLogs: &disabled}
+		pod, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+			corev1.EnvVar{Name: "SANITIZE_MACHINE_ID", Value: "true"},
+			corev1.EnvVar{Name: "SANITIZE_CLOUD_INIT", Value: "false"},
+			corev1.EnvVar{Name: "SANITIZE_LOGS", Value: "false"},
+		))
+
+		By("leaving images without disk artifacts alone")
+		ib.Spec.Output.Sanitize = nil
+		ib.Spec.Output.Formats = []bibv1alpha1.OutputFormat{bibv1alpha1.FormatTGZ}
+		pod, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+			corev1.EnvVar{Name: "SANITIZE_MACHINE_ID", Value: "false"},
+			corev1.EnvVar{Name: "SANITIZE_CLOUD_INIT", Value: "false"},
+			corev1.EnvVar{Name: "SANITIZE_LOGS", Value: "false"},
+		))
+
+		By("sanitizing them on request")
+		ib.Spec.Output.Sanitize = &bibv1alpha1.OutputSanitize{TruncateLogs: &disabled}
+		pod, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+			corev1.EnvVar{Name: "SANITIZE_MACHINE_ID", Value: "true"},
+			corev1.EnvVar{Name: "SANITIZE_CLOUD_INIT", Value: "true"},
+			corev1.EnvVar{Name: "SANITIZE_LOGS", Value: "false"},
+		))
+	})
+
+	It("summarizes the provisioner, outputs and publish target", func() {
+		ib := newTestImageBuild("summary")
+		Expect(buildSummary(ib)).To(Equal("none→pvc(tgz,qcow2)"))
+
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Ansible: &bibv1alpha1.AnsibleSpec{
+			Repo: "https://example.com/org/playbooks.git", Playbook: "site.yml",
+		}}
+		ib.Spec.Output.PVC = nil
+		ib.Spec.Output.ObjectStorage = &bibv1alpha1.ObjectStorageOutput{Bucket: "images", CredentialsSecretName: "s3-creds"}
+		ib.Spec.Output.Formats = []bibv1alpha1.OutputFormat{bibv1alpha1.FormatQCOW2}
+		ib.Spec.Publish = &bibv1alpha1.PublishSpec{AWS: &bibv1alpha1.AWSPublishSpec{Region: "us-east-1"}}
+		Expect(buildSummary(ib)).To(Equal("ansible→s3(qcow2)→aws"))
+
+		ib.Spec.Output.Registry = &bibv1alpha1.RegistryOutput{Destination: "ghcr.io/org/image:latest"}
+		Expect(buildSummary(ib)).To(Equal("ansible→s3(qcow2),registry→aws"))
+
+		ib.Spec.Output.ObjectStorage = nil
+		ib.Spec.Publish = nil
+		Expect(buildSummary(ib)).To(Equal("ansible→registry"))
+	})
+
+	It("records the summary in the status", func() {
+		ib := newTestImageBuild("summarized")
+		r := newFakeReconciler(ib)
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "summarized", Namespace: "default"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: "summarized", Namespace: "default"}, updated)).To(Succeed())
+		Expect(updated.Status.Summary).To(Equal("none→pvc(tgz,qcow2)"))
+	})
+
+	It("passes the source ImageBuild metadata for the builder to stamp on the artifacts", func() {
+		ib := newTestImageBuild("stamped")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		env := map[string]string{}
+		for _, e := range pod.Spec.Containers[0].Env {
+			env[e.Name] = e.Value
+		}
+		Expect(env).To(HaveKeyWithValue("ARTIFACT_IMAGEBUILD_NAME", "stamped"))
+		Expect(env).To(HaveKeyWithValue("ARTIFACT_IMAGEBUILD_NAMESPACE", "default"))
+		Expect(env).To(HaveKeyWithValue("ARTIFACT_IMAGEBUILD_UID", "stamped"))
+		created, err := time.Parse(time.RFC3339, env["ARTIFACT_CREATED"])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(created).To(BeTemporally("~", time.Now(), time.Minute))
+	})
+
+	It("records the reported artifact sizes once the build succeeds", func() {
+		ib := newTestImageBuild("sizes")
+		pod := succeededBuilderPod("sizes", `{"artifacts":[`+
+			`{"format":"tgz","name":"sizes.tgz","sizeBytes":100},`+
+			`{"format":"qcow2","name":"sizes.qcow2","sizeBytes":300,"virtualSizeBytes":2000,"compressed":true}]}`)
+		r := newFakeReconciler(ib, pod)
+		virtualSize := int64(2000)
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "sizes", Namespace: "default"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: "sizes", Namespace: "default"}, updated)).To(Succeed())
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseSucceeded))
+		Expect(updated.Status.OutputPVC).To(Equal(&bibv1alpha1.OutputPVCStatus{ClaimName: "artifacts", Path: "default/sizes"}))
+		Expect(updated.Status.OutputURL).To(Equal("pvc://artifacts/default/sizes/sizes.tgz"))
+		Expect(updated.Status.Artifacts).To(Equal([]bibv1alpha1.ArtifactStatus{
+			{Format: bibv1alpha1.FormatTGZ, Name: "sizes.tgz", URL: "pvc://artifacts/default/sizes/sizes.tgz", SizeBytes: 100},
+			{Format: bibv1alpha1.FormatQCOW2, Name: "sizes.qcow2", URL: "pvc://artifacts/default/sizes/sizes.qcow2",
+				SizeBytes: 300, VirtualSizeBytes: &virtualSize, Compressed: true},
+		}))
+	})
+
+	It("keeps the artifacts in a per-build directory of the output PVC", func() {
+		ib := newTestImageBuild("chained")
+		ib.Spec.Publish = &bibv1alpha1.PublishSpec{MaaS: &bibv1alpha1.MaaSPublishSpec{
+			APIURL: "http://maas.example.com/MAAS", ImageName: "chained", CredentialsSecretName: "maas-credentials",
+		}}
+		r := newFakeReconciler(ib)
+
+		builder, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		publisher, err := r.constructPublisherPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		for _, mounts := range [][]corev1.VolumeMount{builder.Spec.Containers[0].VolumeMounts, publisher.Spec.Containers[0].VolumeMounts} {
+			Expect(mounts).To(ContainElement(HaveField("SubPath", "default/chained")))
+		}
+
+		By("honoring an explicit subPath")
+		ib.Spec.Output.PVC.SubPath = "pipelines/stage-1/"
+		builder, err = r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(builder.Spec.Containers[0].VolumeMounts).To(ContainElement(HaveField("SubPath", "pipelines/stage-1")))
+	})
+
+	Context("with a volume output", func() {
+		newNFSImageBuild := func(name string) *bibv1alpha1.ImageBuild {
+			ib := newTestImageBuild(name)
+			ib.Spec.Output.PVC = nil
+			ib.Spec.Output.Volume = &bibv1alpha1.VolumeOutput{
+				NFS: &corev1.NFSVolumeSource{Server: "nfs.example.com", Path: "/exports/images"},
+			}
+			return ib
+		}
+
+		It("mounts the NFS export at /output", func() {
+			ib := newNFSImageBuild("nfs-mount")
+			r := newFakeReconciler(ib)
+
+			pod, err := r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Volumes).To(ContainElement(And(
+				HaveField("Name", "output-volume"),
+				HaveField("NFS.Server", "nfs.example.com"),
+				HaveField("NFS.Path", "/exports/images"),
+			)))
+			Expect(pod.Spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+				Name: "output-volume", MountPath: "/output", SubPath: "default/nfs-mount",
+			}))
+			Expect(pod.Spec.Volumes).NotTo(ContainElement(HaveField("Name", "output")))
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "OUTPUT_FORMATS", Value: "tgz,qcow2"}))
+		})
+
+		It("records the location on the export once the build succeeds", func() {
+			ib := newNFSImageBuild("nfs-built")
+			ib.Spec.Output.Volume.SubPath = "golden/"
+			r := newFakeReconciler(ib, succeededBuilderPod("nfs-built", `{"artifacts":[]}`))
+
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "nfs-built", Namespace: "default"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			updated := &bibv1alpha1.ImageBuild{}
+			Expect(r.Get(ctx, types.NamespacedName{Name: "nfs-built", Namespace: "default"}, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseSucceeded))
+			Expect(updated.Status.OutputURL).To(Equal("nfs://nfs.example.com/exports/images/golden"))
+			Expect(updated.Status.OutputPVC).To(BeNil())
+		})
+
+		It("mounts a CSI volume", func() {
+			ib := newTestImageBuild("csi-mount")
+			ib.Spec.Output.PVC = nil
+			ib.Spec.Output.Volume = &bibv1alpha1.VolumeOutput{CSI: &corev1.CSIVolumeSource{Driver: "files.csi.example.com"}}
+			r := newFakeReconciler(ib)
+
+			pod, err := r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Volumes).To(ContainElement(HaveField("CSI.Driver", "files.csi.example.com")))
+			Expect(outputVolumeURL(ib)).To(Equal("csi://files.csi.example.com/default/csi-mount"))
+		})
+	})
+
+	Context("with a registry output", func() {
+		newRegistryImageBuild := func(name string) *bibv1alpha1.ImageBuild {
+			ib := newTestImageBuild(name)
+			ib.Spec.Output.PVC = nil
+			ib.Spec.Output.Registry = &bibv1alpha1.RegistryOutput{
+				Destination: "quay.io/example/" + name + ":latest", PullSecretName: "quay-push",
+			}
+			return ib
+		}
+
+		It("passes the destination and layer compression to the builder", func() {
+			ib := newRegistryImageBuild("registry-env")
+			r := newFakeReconciler(ib)
+
+			pod, err := r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+				corev1.EnvVar{Name: "REGISTRY_DESTINATION", Value: "quay.io/example/registry-env:latest"},
+				corev1.EnvVar{Name: "REGISTRY_COMPRESSION_FORMAT", Value: "gzip"},
+				corev1.EnvVar{Name: "OUTPUT_FORMATS", Value: ""},
+			))
+			Expect(pod.Spec.Volumes).To(ContainElement(HaveField("Secret.SecretName", "quay-push")))
+			Expect(pod.Spec.Containers[0].VolumeMounts).To(ContainElement(HaveField("MountPath", "/etc/registry-auth")))
+
+			By("honoring zstd compression")
+			ib.Spec.Output.Registry.CompressionFormat = bibv1alpha1.CompressionZstd
+			pod, err = r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "REGISTRY_COMPRESSION_FORMAT", Value: "zstd"}))
+			Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "REGISTRY_CREATE_REPOSITORY")))
+		})
+
+		It("passes the image config to the builder", func() {
+			ib := newRegistryImageBuild("registry-config")
+			r := newFakeReconciler(ib)
+
+			pod, err := r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "REGISTRY_IMAGE_CONFIG")))
+
+			ib.Spec.Output.Registry.Config = &bibv1alpha1.ImageConfig{
+				Entrypoint:   []string{"/usr/bin/app"},
+				Env:          []bibv1alpha1.ImageConfigEnvVar{{Name: "APP_MODE", Value: "production"}},
+				ExposedPorts: []string{"8080/tcp"},
+				Labels:       map[string]string{"org.opencontainers.image.source": "https://github.com/example/app"},
+			}
+			pod, err = r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name: "REGISTRY_IMAGE_CONFIG",
+				Value: `{"entrypoint":["/usr/bin/app"],"env":[{"name":"APP_MODE","value":"production"}],` +
+					`"exposedPorts":["8080/tcp"],"labels":{"org.opencontainers.image.source":"https://github.com/example/app"}}`,
+			}))
+		})
+
+		It("pushes to additional destinations with their own credentials", func() {
+			ib := newRegistryImageBuild("registry-mirrored")
+			ib.Spec.Output.Registry.AdditionalDestinations = []bibv1alpha1.RegistryDestination{
+				{Destination: "ghcr.io/example/registry-mirrored:latest", PullSecretName: "ghcr-push"},
+				{Destination: "quay.io/mirror/registry-mirrored:latest"},
+			}
+			r := newFakeReconciler(ib)
+
+			pod, err := r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name: "REGISTRY_ADDITIONAL_DESTINATIONS",
+				Value: "ghcr.io/example/registry-mirrored:latest /etc/registry-auth-0/.dockerconfigjson\n" +
+					"quay.io/mirror/registry-mirrored:latest /etc/registry-auth/.dockerconfigjson",
+			}))
+			Expect(pod.Spec.Volumes).To(ContainElement(And(
+				HaveField("Name", "registry-auth-0"),
+				HaveField("Secret.SecretName", "ghcr-push"),
+			)))
+			Expect(pod.Spec.Containers[0].VolumeMounts).To(ContainElement(HaveField("MountPath", "/etc/registry-auth-0")))
+			Expect(pod.Spec.Volumes).NotTo(ContainElement(HaveField("Name", "registry-auth-1")))
+
+			By("recording every destination once the build succeeds")
+			recordOutputLocation(ib)
+			Expect(ib.Status.OutputURLs).To(Equal([]string{
+				"docker://quay.io/example/registry-mirrored:latest",
+				"docker://ghcr.io/example/registry-mirrored:latest",
+				"docker://quay.io/mirror/registry-mirrored:latest",
+			}))
+		})
+
+		It("requires the credentials of additional destinations before the build", func() {
+			ib := newRegistryImageBuild("registry-mirror-secret")
+			ib.Spec.Output.Registry.AdditionalDestinations = []bibv1alpha1.RegistryDestination{
+				{Destination: "ghcr.io/example/registry-mirror-secret:latest", PullSecretName: "ghcr-push"},
+			}
+			r := newFakeReconciler(ib, newTestSecret("quay-push", corev1.SecretTypeDockerConfigJson, corev1.DockerConfigJsonKey))
+
+			ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+			Expect(conditions.GetMessage(ib, bibv1alpha1.OutputReady)).To(ContainSubstring("spec.output.registry.additionalDestinations[0].pullSecretName"))
+		})
+
+		It("passes the repository to create to the builder", func() {
+			ib := newRegistryImageBuild("registry-create")
+			ib.Spec.Output.Registry.Destination = "123456789012.dkr.ecr.us-west-2.amazonaws.com/team/app:1"
+			ib.Spec.Output.Registry.CreateRepository = true
+			ib.Spec.Output.Registry.RepositoryCredentialsSecretName = "ecr-admin"
+			r := newFakeReconciler(ib)
+
+			pod, err := r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+				corev1.EnvVar{Name: "REGISTRY_CREATE_REPOSITORY", Value: "ecr"},
+				corev1.EnvVar{Name: "REGISTRY_REPOSITORY", Value: "team/app"},
+				corev1.EnvVar{Name: "REGISTRY_REPOSITORY_LOCATION", Value: "us-west-2"},
+				corev1.EnvVar{Name: "REGISTRY_REPOSITORY_OWNER", Value: "123456789012"},
+				HaveField("ValueFrom.SecretKeyRef.Name", "ecr-admin"),
+			))
+
+			By("creating Artifact Registry repositories with the push credentials")
+			ib.Spec.Output.Registry.Destination = "europe-west1-docker.pkg.dev/my-project/images/app:1"
+			ib.Spec.Output.Registry.RepositoryCredentialsSecretName = ""
+			pod, err = r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+				corev1.EnvVar{Name: "REGISTRY_CREATE_REPOSITORY", Value: "gar"},
+				corev1.EnvVar{Name: "REGISTRY_REPOSITORY", Value: "images"},
+				corev1.EnvVar{Name: "REGISTRY_REPOSITORY_LOCATION", Value: "europe-west1"},
+				corev1.EnvVar{Name: "REGISTRY_REPOSITORY_OWNER", Value: "my-project"},
+			))
+			Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "REGISTRY_AWS_ACCESS_KEY_ID")))
+		})
+
+		It("rejects repository creation unless the controller allows it", func() {
+			ib := newRegistryImageBuild("registry-create-gated")
+			ib.Spec.Output.Registry.Destination = "us-docker.pkg.dev/my-project/images/app:1"
+			ib.Spec.Output.Registry.CreateRepository = true
+			r := newFakeReconciler(ib, newTestSecret("quay-push", corev1.SecretTypeDockerConfigJson, corev1.DockerConfigJsonKey))
+
+			ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+			Expect(conditions.GetReason(ib, bibv1alpha1.OutputReady)).To(Equal(bibv1alpha1.RepositoryCreationNotAllowedReason))
+
+			By("accepting it once the controller allows repository creation")
+			r.AllowRepositoryCreation = true
+			ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(conditions.IsUnknown(ib, bibv1alpha1.OutputReady)).To(BeTrue())
+		})
+
+		It("records the pushed image once the build succeeds", func() {
+			ib := newRegistryImageBuild("registry-pushed")
+			r := newFakeReconciler(ib, succeededBuilderPod("registry-pushed", `{"artifacts":[]}`))
+
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "registry-pushed", Namespace: "default"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			updated := &bibv1alpha1.ImageBuild{}
+			Expect(r.Get(ctx, types.NamespacedName{Name: "registry-pushed", Namespace: "default"}, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseSucceeded))
+			Expect(updated.Status.OutputURL).To(Equal("docker://quay.io/example/registry-pushed:latest"))
+		})
+	})
+
+	Context("with several outputs", func() {
+		newArchivedImageBuild := func(name string) *bibv1alpha1.ImageBuild {
+			ib := newTestImageBuild(name)
+			ib.Spec.Output.ObjectStorage = &bibv1alpha1.ObjectStorageOutput{
+				Bucket: "archive", Region: "eu-west-1", CredentialsSecretName: "s3-credentials",
+			}
+			return ib
+		}
+
+		It("passes the object storage destination next to the output PVC", func() {
+			ib := newArchivedImageBuild("archived-env")
+			r := newFakeReconciler(ib)
+
+			pod, err := r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+				corev1.EnvVar{Name: "OUTPUT_S3_URL", Value: "s3://archive/default/archived-env/"},
+				corev1.EnvVar{Name: "OUTPUT_S3_REGION", Value: "eu-west-1"},
+				HaveField("ValueFrom.SecretKeyRef.Name", "s3-credentials"),
+			))
+			Expect(pod.Spec.Volumes).To(ContainElement(HaveField("PersistentVolumeClaim.ClaimName", "artifacts")))
+
+			By("writing to scratch space without an output PVC")
+			ib.Spec.Output.PVC = nil
+			pod, err = r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Volumes).To(ContainElement(And(HaveField("Name", "output"), HaveField("EmptyDir", Not(BeNil())))))
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "OUTPUT_FORMATS", Value: "tgz,qcow2"}))
+		})
+
+		It("passes the content type override for the upload", func() {
+			ib := newArchivedImageBuild("archived-type")
+			r := newFakeReconciler(ib)
+
+			pod, err := r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "OUTPUT_S3_CONTENT_TYPE")))
+
+			ib.Spec.Output.ObjectStorage.ContentType = "application/octet-stream"
+			ib.Spec.Build = &bibv1alpha1.BuildSpec{LogsOutput: &bibv1alpha1.ObjectStorageOutput{
+				Bucket: "build-logs", CredentialsSecretName: "logs-credentials", ContentType: "text/plain; charset=utf-8",
+			}}
+			pod, err = r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+				corev1.EnvVar{Name: "OUTPUT_S3_CONTENT_TYPE", Value: "application/octet-stream"},
+				corev1.EnvVar{Name: "LOGS_S3_CONTENT_TYPE", Value: "text/plain; charset=utf-8"},
+			))
+		})
+
+		It("passes the storage class for the upload", func() {
+			ib := newArchivedImageBuild("archived-class")
+			r := newFakeReconciler(ib)
+
+			pod, err := r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "OUTPUT_S3_STORAGE_CLASS")))
+
+			ib.Spec.Output.ObjectStorage.StorageClass = bibv1alpha1.S3StorageClassGlacierIR
+			ib.Spec.Build = &bibv1alpha1.BuildSpec{LogsOutput: &bibv1alpha1.ObjectStorageOutput{
+				Bucket: "build-logs", CredentialsSecretName: "logs-credentials", StorageClass: bibv1alpha1.S3StorageClassStandardIA,
+			}}
+			pod, err = r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+				corev1.EnvVar{Name: "OUTPUT_S3_STORAGE_CLASS", Value: "GLACIER_IR"},
+				corev1.EnvVar{Name: "LOGS_S3_STORAGE_CLASS", Value: "STANDARD_IA"},
+			))
+		})
+
+		It("passes the upload concurrency", func() {
+			ib := newArchivedImageBuild("archived-concurrency")
+			r := newFakeReconciler(ib)
+
+			pod, err := r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "OUTPUT_S3_UPLOAD_CONCURRENCY")))
+
+			ib.Spec.Output.ObjectStorage.UploadConcurrency = 32
+			ib.Spec.Build = &bibv1alpha1.BuildSpec{LogsOutput: &bibv1alpha1.ObjectStorageOutput{
+				Bucket: "build-logs", CredentialsSecretName: "logs-credentials", UploadConcurrency: 2,
+			}}
+			pod, err = r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+				corev1.EnvVar{Name: "OUTPUT_S3_UPLOAD_CONCURRENCY", Value: "32"},
+				corev1.EnvVar{Name: "LOGS_S3_UPLOAD_CONCURRENCY", Value: "2"},
+			))
+		})
+
+		It("passes the role to assume for the upload", func() {
+			ib := newArchivedImageBuild("archived-role")
+			r := newFakeReconciler(ib)
+
+			pod, err := r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "OUTPUT_AWS_ASSUME_ROLE_ARN")))
+
+			ib.Spec.Output.ObjectStorage.RoleARN = "arn:aws:iam::111122223333:role/archive-writer"
+			pod, err = r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(
+				corev1.EnvVar{Name: "OUTPUT_AWS_ASSUME_ROLE_ARN", Value: "arn:aws:iam::111122223333:role/archive-writer"},
+			))
+			Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "OUTPUT_AWS_ASSUME_ROLE_EXTERNAL_ID")))
+
+			ib.Spec.Output.ObjectStorage.ExternalID = "bib"
+			pod, err = r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(
+				corev1.EnvVar{Name: "OUTPUT_AWS_ASSUME_ROLE_EXTERNAL_ID", Value: "bib"},
+			))
+		})
+
+		It("records the location in every output once the build succeeds", func() {
+			ib := newArchivedImageBuild("archived")
+			ib.Spec.Output.Registry = &bibv1alpha1.RegistryOutput{
+				Destination: "quay.io/example/archived:latest", PullSecretName: "quay-push",
+			}
+			r := newFakeReconciler(ib, succeededBuilderPod("archived", `{"artifacts":[]}`))
+
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "archived", Namespace: "default"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			updated := &bibv1alpha1.ImageBuild{}
+			Expect(r.Get(ctx, types.NamespacedName{Name: "archived", Namespace: "default"}, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseSucceeded))
+			Expect(updated.Status.OutputURLs).To(Equal([]string{
+				"pvc://artifacts/default/archived",
+				"s3://archive/default/archived/",
+				"docker://quay.io/example/archived:latest",
+			}))
+			Expect(updated.Status.OutputURL).To(Equal("pvc://artifacts/default/archived"))
+			Expect(updated.Status.OutputPVC).NotTo(BeNil())
+		})
+
+		It("lists the artifacts of every output with the primary artifact first", func() {
+			ib := newArchivedImageBuild("listed")
+			ib.Spec.Output.PVC = nil
+			ib.Spec.Output.Formats = []bibv1alpha1.OutputFormat{bibv1alpha1.FormatQCOW2, bibv1alpha1.FormatTGZ}
+			ib.Spec.Output.Registry = &bibv1alpha1.RegistryOutput{
+				Destination: "quay.io/example/listed:latest", PullSecretName: "quay-push",
+				AdditionalDestinations: []bibv1alpha1.RegistryDestination{{Destination: "ghcr.io/example/listed:latest"}},
+			}
+			// Reported in the order the concurrent pushes completed in.
+			r := newFakeReconciler(ib, succeededBuilderPod("listed", `{"artifacts":[`+
+				`{"format":"oci","name":"ghcr.io/example/listed:latest","checksum":"sha256:1111","signed":true},`+
+				`{"format":"raw","name":"listed.raw","sizeBytes":400,"checksum":"sha256:4444"},`+
+				`{"format":"tgz","name":"listed.tgz","sizeBytes":100,"checksum":"sha256:2222"},`+
+				`{"format":"oci","name":"quay.io/example/listed:latest","checksum":"sha256:1111","signed":true},`+
+				`{"format":"qcow2","name":"listed.qcow2","sizeBytes":300,"checksum":"sha256:3333"}]}`))
+
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "listed", Namespace: "default"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			updated := &bibv1alpha1.ImageBuild{}
+			Expect(r.Get(ctx, types.NamespacedName{Name: "listed", Namespace: "default"}, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseSucceeded))
+			Expect(updated.Status.Artifacts).To(Equal([]bibv1alpha1.ArtifactStatus{
+				{Format: bibv1alpha1.FormatQCOW2, Name: "listed.qcow2", URL: "s3://archive/default/listed/listed.qcow2", SizeBytes: 300, Checksum: "sha256:3333"},
+				{Format: bibv1alpha1.FormatTGZ, Name: "listed.tgz", URL: "s3://archive/default/listed/listed.tgz", SizeBytes: 100, Checksum: "sha256:2222"},
+				{Format: "raw", Name: "listed.raw", URL: "s3://archive/default/listed/listed.raw", SizeBytes: 400, Checksum: "sha256:4444"},
+				{Format: bibv1alpha1.FormatOCI, Name: "quay.io/example/listed:latest", URL: "docker://quay.io/example/listed:latest",
+					Checksum: "sha256:1111", Signed: true},
+				{Format: bibv1alpha1.FormatOCI, Name: "ghcr.io/example/listed:latest", URL: "docker://ghcr.io/example/listed:latest",
+					Checksum: "sha256:1111", Signed: true},
+			}))
+			Expect(updated.Status.OutputURL).To(Equal("s3://archive/default/listed/listed.qcow2"))
+		})
+
+		It("points the output URL at the pushed image of a registry-only build", func() {
+			ib := newTestImageBuild("image-only")
+			ib.Spec.Output.PVC = nil
+			ib.Spec.Output.Registry = &bibv1alpha1.RegistryOutput{
+				Destination: "quay.io/example/image-only:latest", PullSecretName: "quay-push",
+			}
+			r := newFakeReconciler(ib, succeededBuilderPod("image-only",
+				`{"artifacts":[{"format":"oci","name":"quay.io/example/image-only:latest","checksum":"sha256:1111"}]}`))
+
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "image-only", Namespace: "default"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			updated := &bibv1alpha1.ImageBuild{}
+			Expect(r.Get(ctx, types.NamespacedName{Name: "image-only", Namespace: "default"}, updated)).To(Succeed())
+			Expect(updated.Status.Artifacts).To(HaveLen(1))
+			Expect(updated.Status.Artifacts[0].URL).To(Equal("docker://quay.io/example/image-only:latest"))
+			Expect(updated.Status.OutputURL).To(Equal("docker://quay.io/example/image-only:latest"))
+		})
+	})
+
+	Context("with a verification step", func() {
+		newVerifiedImageBuild := func(name string) *bibv1alpha1.ImageBuild {
+			ib := newTestImageBuild(name)
+			ib.Spec.Verify = &bibv1alpha1.VerifySpec{Ansible: &bibv1alpha1.AnsibleSpec{
+				Repo: "https://example.com/org/tests.git", Branch: "main", Playbook: "smoke.yml",
+			}}
+			return ib
+		}
+
+		It("passes the verification playbook to the builder", func() {
+			ib := newVerifiedImageBuild("verify-env")
+			r := newFakeReconciler(ib)
+
+			pod, err := r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+				corev1.EnvVar{Name: "VERIFY_ANSIBLE_GIT_REPO", Value: "https://example.com/org/tests.git"},
+				corev1.EnvVar{Name: "VERIFY_ANSIBLE_PLAYBOOK", Value: "smoke.yml"},
+			))
+			Expect(pod.Spec.Containers[0].VolumeMounts).To(ContainElement(HaveField("MountPath", "/verify-source")))
+		})
+
+		It("marks VerifyReady true when the verification passes", func() {
+			ib := newVerifiedImageBuild("verify-pass")
+			r := newFakeReconciler(ib, succeededBuilderPod("verify-pass", `{"artifacts":[],"verify":{"passed":true}}`))
+
+			_, updated := reconcileBuild(r, "verify-pass")
+			Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseSucceeded))
+			Expect(conditions.IsTrue(updated, bibv1alpha1.VerifyReady)).To(BeTrue())
+		})
+
+		It("fails the build when the verification fails", func() {
+			ib := newVerifiedImageBuild("verify-fail")
+			pod := succeededBuilderPod("verify-fail", `{"verify":{"passed":false,"message":"verification playbook smoke.yml failed"}}`)
+			pod.Status.Phase = corev1.PodFailed
+			r := newFakeReconciler(ib, pod)
+
+			_, updated := reconcileBuild(r, "verify-fail")
+			Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+			Expect(conditions.IsFalse(updated, bibv1alpha1.VerifyReady)).To(BeTrue())
+			Expect(conditions.GetReason(updated, bibv1alpha1.VerifyReady)).To(Equal(bibv1alpha1.VerificationFailedReason))
+			Expect(conditions.GetMessage(updated, bibv1alpha1.VerifyReady)).To(ContainSubstring("smoke.yml"))
+			Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).NotTo(Equal(bibv1alpha1.BuildFailedReason))
+		})
+
+		It("reports a verification repository that cannot be cloned on VerifyReady", func() {
+			ib := newVerifiedImageBuild("verify-clone")
+			pod := succeededBuilderPod("verify-clone", `{"sourceClone":{"repo":"https://example.com/org/tests.git","branch":"main",`+
+				`"message":"fatal: Authentication failed for 'https://example.com/org/tests.git/'"},`+
+				`"stages":[{"name":"provision","phase":"Succeeded"},{"name":"verify","phase":"Failed","message":"exited with code 1"}]}`)
+			pod.Status.Phase = corev1.PodFailed
+			r := newFakeReconciler(ib, pod)
+
+			_, updated := reconcileBuild(r, "verify-clone")
+			Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+			Expect(conditions.GetReason(updated, bibv1alpha1.VerifyReady)).To(Equal(bibv1alpha1.SourceCloneFailedReason))
+			Expect(conditions.GetMessage(updated, bibv1alpha1.VerifyReady)).To(ContainSubstring("Authentication failed"))
+			Expect(conditions.IsTrue(updated, bibv1alpha1.ProvisionerReady)).To(BeTrue())
+		})
+	})
+
+	Context("with a provisioner repository that cannot be cloned", func() {
+		It("marks ProvisionerReady false with the error of git", func() {
+			ib := newTestImageBuild("clone-fail")
+			pod := succeededBuilderPod("clone-fail", `{"sourceClone":{"repo":"https://example.com/org/playbooks.git","branch":"nope",`+
+				`"message":"fatal: Remote branch nope not found in upstream origin"},`+
+				`"stages":[{"name":"pull","phase":"Succeeded"},{"name":"provision","phase":"Failed","message":"exited with code 1"}]}`)
+			pod.Status.Phase = corev1.PodFailed
+			r := newFakeReconciler(ib, pod)
+
+			_, updated := reconcileBuild(r, "clone-fail")
+			Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+			Expect(conditions.IsFalse(updated, bibv1alpha1.ProvisionerReady)).To(BeTrue())
+			Expect(conditions.GetReason(updated, bibv1alpha1.ProvisionerReady)).To(Equal(bibv1alpha1.SourceCloneFailedReason))
+			Expect(conditions.GetMessage(updated, bibv1alpha1.ProvisionerReady)).To(Equal(
+				"failed to clone https://example.com/org/playbooks.git at branch nope: fatal: Remote branch nope not found in upstream origin"))
+			Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).NotTo(Equal(bibv1alpha1.BuildFailedReason))
+		})
+
+		It("names the submodule that failed", func() {
+			ib := newTestImageBuild("submodule-fail")
+			pod := succeededBuilderPod("submodule-fail", `{"sourceClone":{"repo":"git@github.com:example/roles.git",`+
+				`"submoduleOf":"https://example.com/org/playbooks.git","message":"fatal: Could not read from remote repository."},`+
+				`"stages":[{"name":"pull","phase":"Succeeded"},{"name":"provision","phase":"Failed","message":"exited with code 1"}]}`)
+			pod.Status.Phase = corev1.PodFailed
+			r := newFakeReconciler(ib, pod)
+
+			_, updated := reconcileBuild(r, "submodule-fail")
+			Expect(conditions.GetReason(updated, bibv1alpha1.ProvisionerReady)).To(Equal(bibv1alpha1.SourceCloneFailedReason))
+			Expect(conditions.GetMessage(updated, bibv1alpha1.ProvisionerReady)).To(Equal(
+				"failed to clone submodule git@github.com:example/roles.git of https://example.com/org/playbooks.git: " +
+					"fatal: Could not read from remote repository."))
+		})
+	})
+
+	Context("with a build log destination", func() {
+		newLoggedImageBuild := func(name string) *bibv1alpha1.ImageBuild {
+			ib := newTestImageBuild(name)
+			ib.Spec.Build = &bibv1alpha1.BuildSpec{LogsOutput: &bibv1alpha1.ObjectStorageOutput{
+				Bucket: "build-logs", Region: "us-west-2", CredentialsSecretName: "logs-credentials",
+			}}
+			return ib
+		}
+
+		It("passes the log destination and its credentials to the builder", func() {
+			ib := newLoggedImageBuild("logs-env")
+			r := newFakeReconciler(ib)
+
+			pod, err := r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			env := pod.Spec.Containers[0].Env
+			Expect(env).To(ContainElements(
+				corev1.EnvVar{Name: "LOGS_S3_URL", Value: "s3://build-logs/default/logs-env/build.log"},
+				corev1.EnvVar{Name: "LOGS_S3_REGION", Value: "us-west-2"},
+			))
+			Expect(env).To(ContainElement(And(
+				HaveField("Name", "LOGS_AWS_SECRET_ACCESS_KEY"),
+				HaveField("ValueFrom.SecretKeyRef.Name", "logs-credentials"),
+			)))
+
+			By("passing the role to assume for the log upload")
+			ib.Spec.Build.LogsOutput.RoleARN = "arn:aws:iam::111122223333:role/log-writer"
+			ib.Spec.Build.LogsOutput.ExternalID = "bib"
+			pod, err = r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+				corev1.EnvVar{Name: "LOGS_AWS_ASSUME_ROLE_ARN", Value: "arn:aws:iam::111122223333:role/log-writer"},
+				corev1.EnvVar{Name: "LOGS_AWS_ASSUME_ROLE_EXTERNAL_ID", Value: "bib"},
+			))
+		})
+
+		It("records the log URL whether the build succeeds or fails", func() {
+			for _, phase := range []corev1.PodPhase{corev1.PodSucceeded, corev1.PodFailed} {
+				name := "logs-" + strings.ToLower(string(phase))
+				ib := newLoggedImageBuild(name)
+				pod := succeededBuilderPod(name, `{"uploads":[{"url":"s3://build-logs/default/`+name+`/build.log","phase":"Succeeded"}]}`)
+				pod.Status.Phase = phase
+				r := newFakeReconciler(ib, pod, newTestSecret("logs-credentials", corev1.SecretTypeOpaque, awsCredentialsKeys...))
+
+				_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: "default"}})
+				Expect(err).NotTo(HaveOccurred())
+
+				updated := &bibv1alpha1.ImageBuild{}
+				Expect(r.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, updated)).To(Succeed())
+				Expect(updated.Status.LogURL).To(Equal("s3://build-logs/default/" + name + "/build.log"))
+			}
+		})
+
+		It("does not record a log URL when the log upload failed", func() {
+			for _, message := range []string{
+				`{"uploads":[{"url":"s3://build-logs/default/logs-upload-failed/build.log","phase":"Failed","message":"Access Denied"}]}`,
+				"",
+			} {
+				ib := newLoggedImageBuild("logs-upload-failed")
+				r := newFakeReconciler(ib, succeededBuilderPod("logs-upload-failed", message),
+					newTestSecret("logs-credentials", corev1.SecretTypeOpaque, awsCredentialsKeys...))
+
+				_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "logs-upload-failed", Namespace: "default"}})
+				Expect(err).NotTo(HaveOccurred())
+
+				updated := &bibv1alpha1.ImageBuild{}
+				Expect(r.Get(ctx, types.NamespacedName{Name: "logs-upload-failed", Namespace: "default"}, updated)).To(Succeed())
+				Expect(updated.Status.LogURL).To(BeEmpty())
+			}
+		})
+
+		It("does not record a log URL while the build is running", func() {
+			ib := newLoggedImageBuild("logs-running")
+			pod := succeededBuilderPod("logs-running", "")
+			pod.Status.Phase = corev1.PodRunning
+			r := newFakeReconciler(ib, pod, newTestSecret("logs-credentials", corev1.SecretTypeOpaque, awsCredentialsKeys...))
+
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "logs-running", Namespace: "default"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			updated := &bibv1alpha1.ImageBuild{}
+			Expect(r.Get(ctx, types.NamespacedName{Name: "logs-running", Namespace: "default"}, updated)).To(Succeed())
+			Expect(updated.Status.LogURL).To(BeEmpty())
+		})
+	})
+
+	Context("with builder stages", func() {
+		It("marks the conditions of the reported stages", func() {
+			ib := newTestImageBuild("stages")
+			r := newFakeReconciler(ib, succeededBuilderPod("stages", `{"artifacts":[],"stages":[`+
+				`{"name":"pull","phase":"Succeeded"},{"name":"provision","phase":"Succeeded"},{"name":"convert","phase":"Succeeded"}]}`))
+
+			_, updated := reconcileBuild(r, "stages")
+			Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseSucceeded))
+			Expect(updated.Status.Stages).To(HaveLen(3))
+			Expect(updated.Status.Stages[1]).To(Equal(bibv1alpha1.BuildStageStatus{Name: "provision", Phase: bibv1alpha1.BuildStageSucceeded}))
+			Expect(conditions.IsTrue(updated, bibv1alpha1.BaseImageReady)).To(BeTrue())
+			Expect(conditions.IsTrue(updated, bibv1alpha1.ProvisionerReady)).To(BeTrue())
+			Expect(conditions.IsTrue(updated, bibv1alpha1.OutputReady)).To(BeTrue())
+		})
+
+		It("reports a stage that failed without failing the build as a warning", func() {
+			ib := newTestImageBuild("sbom")
+			r := newFakeReconciler(ib, succeededBuilderPod("sbom", `{"artifacts":[],"stages":[`+
+				`{"name":"convert","phase":"Succeeded"},{"name":"sbom","phase":"Failed","message":"syft not found"}]}`))
+
+			_, updated := reconcileBuild(r, "sbom")
+			Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseSucceeded))
+			Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.BuildStageFailedReason))
+			Expect(conditions.GetSeverity(updated, bibv1alpha1.BuilderPodReady)).To(HaveValue(Equal(clusterv1beta1.ConditionSeverityWarning)))
+			Expect(conditions.GetMessage(updated, bibv1alpha1.BuilderPodReady)).To(Equal("stage sbom failed: syft not found"))
+		})
+
+		It("attributes a failed build to the failed stage", func() {
+			ib := newTestImageBuild("upload-failed")
+			pod := succeededBuilderPod("upload-failed", `{"stages":[`+
+				`{"name":"convert","phase":"Succeeded"},{"name":"upload","phase":"Failed","message":"exited with code 1"}]}`)
+			pod.Status.Phase = corev1.PodFailed
+			pod.Status.ContainerStatuses[0].State.Terminated.ExitCode = 1
+			r := newFakeReconciler(ib, pod)
+
+			_, updated := reconcileBuild(r, "upload-failed")
+			Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+			Expect(conditions.GetReason(updated, bibv1alpha1.OutputReady)).To(Equal(bibv1alpha1.BuildStageFailedReason))
+			Expect(conditions.GetSeverity(updated, bibv1alpha1.OutputReady)).To(HaveValue(Equal(clusterv1beta1.ConditionSeverityError)))
+			Expect(conditions.GetMessage(updated, bibv1alpha1.BuilderPodReady)).To(Equal(
+				"builder pod " + builderPodPrefix + "upload-failed failed in stage upload: builder container exited with code 1"))
+		})
+	})
+
+	Context("with uploads to several destinations", func() {
+		newMirroredImageBuild := func(name string) *bibv1alpha1.ImageBuild {
+			ib := newTestImageBuild(name)
+			ib.Spec.Output.ObjectStorage = &bibv1alpha1.ObjectStorageOutput{
+				Bucket: "archive", Region: "eu-west-1", CredentialsSecretName: "s3-credentials",
+			}
+			ib.Spec.Output.Registry = &bibv1alpha1.RegistryOutput{
+				Destination: "quay.io/example/mirrored:latest", PullSecretName: "quay-push",
+				AdditionalDestinations: []bibv1alpha1.RegistryDestination{
+					{Destination: "ghcr.io/example/mirrored:latest", PullSecretName: "ghcr-push"},
+				},
+			}
+			ib.Spec.Output.ParallelUploads = true
+			return ib
+		}
+
+		uploadsMessage := func(uploads ...string) string {
+			return `{"artifacts":[],"uploads":[` + strings.Join(uploads, ",") + `]}`
+		}
+
+		It("asks the builder to upload in parallel only if requested", func() {
+			ib := newMirroredImageBuild("parallel-env")
+			r := newFakeReconciler(ib)
+
+			pod, err := r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "OUTPUT_PARALLEL_UPLOADS", Value: "true"}))
+
+			ib.Spec.Output.ParallelUploads = false
+			pod, err = r.constructBuilderPod(ctx, ib)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "OUTPUT_PARALLEL_UPLOADS")))
+		})
+
+		It("aggregates the uploads per destination regardless of their completion order", func() {
+			uploads := []string{
+				`{"url":"docker://ghcr.io/example/mirrored:latest","phase":"Succeeded"}`,
+				`{"url":"s3://archive/default/mirrored/mirrored.qcow2","phase":"Failed","message":"connection reset"}`,
+				`{"url":"docker://quay.io/example/mirrored:latest","phase":"Succeeded"}`,
+				`{"url":"s3://archive/default/mirrored/mirrored.tgz","phase":"Failed","message":"access denied"}`,
+				`{"url":"s3://archive/default/mirrored/mirrored.metadata.json","phase":"Succeeded"}`,
+			}
+			expected := []bibv1alpha1.OutputDestinationStatus{
+				{
+					URL: "s3://archive/default/mirrored/", Phase: bibv1alpha1.BuildStageFailed,
+					Message: "mirrored.qcow2: connection reset; mirrored.tgz: access denied",
+				},
+				{URL: "docker://quay.io/example/mirrored:latest", Phase: bibv1alpha1.BuildStageSucceeded},
+				{URL: "docker://ghcr.io/example/mirrored:latest", Phase: bibv1alpha1.BuildStageSucceeded},
+			}
+
+			for i := range uploads {
+				By(fmt.Sprintf("completing the uploads in order %d", i))
+				order := append(append([]string{}, uploads[i:]...), uploads[:i]...)
+				if i%2 == 1 {
+					slices.Reverse(order)
+				}
+				ib := newMirroredImageBuild("mirrored")
+				pod := succeededBuilderPod("mirrored", uploadsMessage(order...))
+				pod.Status.Phase = corev1.PodFailed
+
+				recordBuilderDestinations(ctx, ib, pod)
+				Expect(ib.Status.OutputDestinations).To(Equal(expected))
+				Expect(conditions.GetReason(ib, bibv1alpha1.OutputReady)).To(Equal(bibv1alpha1.OutputDestinationFailedReason))
+				Expect(conditions.GetMessage(ib, bibv1alpha1.OutputReady)).To(Equal("upload to s3://archive/default/mirrored/ failed"))
+			}
+		})
+
+		It("records the destinations once the build succeeds", func() {
+			ib := newMirroredImageBuild("uploaded")
+			r := newFakeReconciler(ib, succeededBuilderPod("uploaded", uploadsMessage(
+				`{"url":"docker://quay.io/example/mirrored:latest","phase":"Succeeded"}`,
+				`{"url":"s3://archive/default/uploaded/uploaded.qcow2","phase":"Succeeded"}`,
+			)))
+
+			key := types.NamespacedName{Name: "uploaded", Namespace: "default"}
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			updated := &bibv1alpha1.ImageBuild{}
+			Expect(r.Get(ctx, key, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseSucceeded))
+			Expect(conditions.IsTrue(updated, bibv1alpha1.OutputReady)).To(BeTrue())
+			// The second registry destination reported no upload.
+			Expect(updated.Status.OutputDestinations).To(Equal([]bibv1alpha1.OutputDestinationStatus{
+				{URL: "s3://archive/default/uploaded/", Phase: bibv1alpha1.BuildStageSucceeded},
+				{URL: "docker://quay.io/example/mirrored:latest", Phase: bibv1alpha1.BuildStageSucceeded},
+			}))
+		})
+
+		It("lists the failed destinations on a failed build", func() {
+			ib := newMirroredImageBuild("push-failed")
+			pod := succeededBuilderPod("push-failed", uploadsMessage(
+				`{"url":"docker://ghcr.io/example/mirrored:latest","phase":"Failed","message":"unauthorized"}`,
+				`{"url":"docker://quay.io/example/mirrored:latest","phase":"Failed","message":"unauthorized"}`,
+			))
+			pod.Status.Phase = corev1.PodFailed
+			pod.Status.ContainerStatuses[0].State.Terminated.ExitCode = 1
+			r := newFakeReconciler(ib, pod)
+
+			key := types.NamespacedName{Name: "push-failed", Namespace: "default"}
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			updated := &bibv1alpha1.ImageBuild{}
+			Expect(r.Get(ctx, key, updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+			Expect(conditions.GetReason(updated, bibv1alpha1.OutputReady)).To(Equal(bibv1alpha1.OutputDestinationFailedReason))
+			Expect(conditions.GetSeverity(updated, bibv1alpha1.OutputReady)).To(HaveValue(Equal(clusterv1beta1.ConditionSeverityError)))
+			Expect(conditions.GetMessage(updated, bibv1alpha1.OutputReady)).To(Equal(
+				"upload to docker://quay.io/example/mirrored:latest, docker://ghcr.io/example/mirrored:latest failed"))
+			Expect(updated.Status.OutputDestinations).To(HaveEach(HaveField("Message", "unauthorized")))
+		})
+	})
+
+	It("tolerates builders that do not report a result", func() {
+		result, err := parseBuilderResult(succeededBuilderPod("custom", ""))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(BeNil())
+
+		_, err = parseBuilderResult(succeededBuilderPod("custom", "build finished"))
+		Expect(err).To(HaveOccurred())
+	})
+})
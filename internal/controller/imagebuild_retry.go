@@ -0,0 +1,245 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+var (
+	// podCreationRetryBase is the delay before retrying after the builder pod could not be
+	// constructed or created for the first time. It doubles with every consecutive failure.
+	podCreationRetryBase = 30 * time.Second
+	// maxPodCreationAttempts bounds the consecutive attempts to create the builder pod before the
+	// build is failed, so a resource that can never produce a pod does not load the API server.
+	maxPodCreationAttempts int32 = 5
+
+	// registryRateLimitRetryBase is the delay before retrying a build rate limited by a registry for
+	// the first time. It doubles with every further attempt, up to registryRateLimitRetryMax.
+	registryRateLimitRetryBase = time.Minute
+	registryRateLimitRetryMax  = 30 * time.Minute
+	// maxRegistryRateLimitRetries bounds the retries of a build rate limited by a registry, so a
+	// registry that keeps rejecting the build, e.g. for an exhausted quota, eventually fails it.
+	maxRegistryRateLimitRetries int32 = 8
+)
+
+// registryRateLimitBackoff returns how long to wait after the given number of build attempts before
+// creating the next builder pod.
+func registryRateLimitBackoff(attempts int32) time.Duration {
+	delay := registryRateLimitRetryBase
+	for i := int32(1); i < attempts && delay < registryRateLimitRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, registryRateLimitRetryMax)
+}
+
+// rateLimitCondition returns the condition reporting a rate-limited registry operation: pulls of the
+// base image affect BaseImageReady, pushes to the registry output OutputReady.
+func rateLimitCondition(result *rateLimitResult) clusterv1beta1.ConditionType {
+	if result.Operation == rateLimitOperationPush {
+		return bibv1alpha1.OutputReady
+	}
+	return bibv1alpha1.BaseImageReady
+}
+
+// reconcileRegistryRateLimited retries a build whose builder pod failed because a registry rejected
+// a pull or push with 429 Too Many Requests. The failed pod is kept until the backoff has elapsed,
+// so it can be inspected and no new pod is created early, then deleted to let the next reconcile
+// create a fresh one. After maxRegistryRateLimitRetries retries the build fails.
+func (r *ImageBuildReconciler) reconcileRegistryRateLimited(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild,
+	pod *corev1.Pod, result *rateLimitResult) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	conditionType := rateLimitCondition(result)
+	if imageBuild.Status.RateLimitRetries >= maxRegistryRateLimitRetries {
+		logger.Info("Registry rate limited the build too often, failing it", "Image", result.Image,
+			"Retries", imageBuild.Status.RateLimitRetries)
+		conditions.MarkFalse(imageBuild, conditionType, bibv1alpha1.RegistryRateLimitedReason, clusterv1beta1.ConditionSeverityError,
+			"%s of %s was still rate limited by the registry after %d retries: %s", result.Operation, result.Image,
+			imageBuild.Status.RateLimitRetries, result.Message)
+		imageBuild.Status.Phase = bibv1alpha1.PhaseFailed
+		markCompleted(imageBuild)
+		return ctrl.Result{}, nil
+	}
+	delay := registryRateLimitBackoff(imageBuild.Status.BuildAttempts)
+	imageBuild.Status.Phase = bibv1alpha1.PhasePending
+	conditions.MarkFalse(imageBuild, conditionType, bibv1alpha1.RegistryRateLimitedReason, clusterv1beta1.ConditionSeverityWarning,
+		"%s of %s was rate limited by the registry, retrying after %s: %s", result.Operation, result.Image, delay, result.Message)
+
+	if remaining := delay - time.Since(builderFinishedAt(pod)); remaining > 0 {
+		logger.Info("Registry rate limited the build, waiting before retrying", "Image", result.Image, "RequeueAfter", remaining)
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	logger.Info("Retrying build rate limited by the registry", "Image", result.Image, "Attempt", imageBuild.Status.BuildAttempts+1)
+	if err := r.cleanupBuilderPod(ctx, imageBuild); err != nil {
+		return ctrl.Result{}, err
+	}
+	imageBuild.Status.RateLimitRetries++
+	conditions.MarkUnknown(imageBuild, conditionType, "Initializing", "Unknown")
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// builderFinishedAt returns when the builder container of a finished pod terminated. It falls back
+// to the zero time, so a pod without a termination time is retried right away.
+func builderFinishedAt(pod *corev1.Pod) time.Time {
+	if terminated := terminatedState(pod, builderContainerName); terminated != nil {
+		return terminated.FinishedAt.Time
+	}
+	return time.Time{}
+}
+
+const (
+	// podEvictedReason is the pod status reason of a pod evicted by the kubelet under node pressure.
+	// Pods evicted through the eviction API, e.g. by a node drain, or preempted have the
+	// DisruptionTarget condition instead.
+	podEvictedReason = "Evicted"
+	// defaultRetryLimit is the number of evictions retried if spec.retryLimit is unset.
+	defaultRetryLimit int32 = 3
+)
+
+// podEviction returns the message of the eviction of a failed pod, and whether it was evicted:
+// either by the kubelet under node pressure, or by any of the disruptions setting the
+// DisruptionTarget condition, e.g. the eviction API, preemption or a graceful node shutdown.
+func podEviction(pod *corev1.Pod) (string, bool) {
+	if pod.Status.Reason == podEvictedReason {
+		return pod.Status.Message, true
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.DisruptionTarget && condition.Status == corev1.ConditionTrue {
+			return condition.Message, true
+		}
+	}
+	return "", false
+}
+
+// retryLimit returns how many evicted builder pods are recreated before the build fails.
+func retryLimit(imageBuild *bibv1alpha1.ImageBuild) int32 {
+	if imageBuild.Spec.RetryLimit != nil {
+		return *imageBuild.Spec.RetryLimit
+	}
+	return defaultRetryLimit
+}
+
+// reconcileBuilderPodEvicted retries a build whose builder pod was evicted. An eviction is a
+// condition of the cluster rather than of the build, so the pod is recreated right away until
+// spec.retryLimit evictions have been retried; only then is the build failed.
+func (r *ImageBuildReconciler) reconcileBuilderPodEvicted(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild,
+	pod *corev1.Pod, message string) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	status := &imageBuild.Status
+	limit := retryLimit(imageBuild)
+	if status.Evictions >= limit {
+		logger.Info("Builder pod evicted too often, failing the build", "Evictions", status.Evictions+1)
+		conditions.MarkFalse(imageBuild, bibv1alpha1.BuilderPodReady, bibv1alpha1.PodEvictedReason, clusterv1beta1.ConditionSeverityError,
+			"builder pod %s was evicted after %d retries: %s", pod.Name, status.Evictions, message)
+		status.Phase = bibv1alpha1.PhaseFailed
+		markCompleted(imageBuild)
+		return ctrl.Result{}, nil
+	}
+
+	logger.Info("Retrying build whose builder pod was evicted", "Attempt", status.BuildAttempts+1, "Message", message)
+	if err := r.cleanupBuilderPod(ctx, imageBuild); err != nil {
+		return ctrl.Result{}, err
+	}
+	status.Evictions++
+	r.recordEventf(imageBuild, corev1.EventTypeWarning, bibv1alpha1.PodEvictedReason,
+		"Builder pod %s was evicted, recreating it (%d/%d): %s", pod.Name, status.Evictions, limit, message)
+	status.Phase = bibv1alpha1.PhasePending
+	conditions.MarkFalse(imageBuild, bibv1alpha1.BuilderPodReady, bibv1alpha1.PodEvictedReason, clusterv1beta1.ConditionSeverityWarning,
+		"builder pod %s was evicted, retry %d/%d: %s", pod.Name, status.Evictions, limit, message)
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// reconcilePodCreationFailure records a failure to construct or create the builder pod. Terminal
+// failures requeue with exponential backoff until maxPodCreationAttempts is reached, then fail the
+// build, which is only retried once the spec changes. A pod that already exists is picked up by the
+// next reconcile, and transient API errors are returned to be retried without counting.
+func reconcilePodCreationFailure(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild, err error) (ctrl.Result, error) {
+	if apierrors.IsAlreadyExists(err) {
+		log.FromContext(ctx).Info("Builder pod already exists, waiting for it to be observed")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	if !isTerminalPodCreationError(err) {
+		return ctrl.Result{}, err
+	}
+	status := &imageBuild.Status
+	status.PodCreationFailures++
+	if status.PodCreationFailures >= maxPodCreationAttempts {
+		log.FromContext(ctx).Info("Giving up creating the builder pod until the spec changes", "Attempts", status.PodCreationFailures)
+		conditions.MarkFalse(imageBuild, bibv1alpha1.BuilderPodReady, bibv1alpha1.PodCreationFailedReason, clusterv1beta1.ConditionSeverityError,
+			"failed to create builder pod after %d attempts, update the spec to retry: %s", status.PodCreationFailures, err.Error())
+		status.PodCreationFailedGeneration = imageBuild.Generation
+		status.Phase = bibv1alpha1.PhaseFailed
+		markCompleted(imageBuild)
+		return ctrl.Result{}, nil
+	}
+	conditions.MarkFalse(imageBuild, bibv1alpha1.BuilderPodReady, bibv1alpha1.PodCreationFailedReason, clusterv1beta1.ConditionSeverityWarning,
+		"attempt %d/%d: %s", status.PodCreationFailures, maxPodCreationAttempts, err.Error())
+	return ctrl.Result{RequeueAfter: podCreationRetryBase << (status.PodCreationFailures - 1)}, nil
+}
+
+// isTerminalPodCreationError reports whether err will keep the builder pod from being created until
+// something changes: the spec cannot be turned into a pod, or the API server rejected the pod, e.g.
+// by validation, a quota or an admission policy. Other API errors, such as timeouts, conflicts or
+// an unavailable API server, are transient.
+func isTerminalPodCreationError(err error) bool {
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) {
+		return true
+	}
+	if apierrors.HasStatusCause(err, corev1.NamespaceTerminatingCause) {
+		return false
+	}
+	return apierrors.IsInvalid(err) || apierrors.IsBadRequest(err) || apierrors.IsForbidden(err) ||
+		apierrors.IsRequestEntityTooLargeError(err)
+}
+
+// podCreationFailureSpecChanged returns true if imageBuild was failed by reconcilePodCreationFailure
+// and its spec has changed since, so the build is to be retried.
+func podCreationFailureSpecChanged(imageBuild *bibv1alpha1.ImageBuild) bool {
+	return imageBuild.Status.Phase == bibv1alpha1.PhaseFailed &&
+		conditions.GetReason(imageBuild, bibv1alpha1.BuilderPodReady) == bibv1alpha1.PodCreationFailedReason &&
+		imageBuild.Generation != imageBuild.Status.PodCreationFailedGeneration
+}
+
+// resetPodCreationFailure restarts a build failed by reconcilePodCreationFailure once its spec has
+// changed. It returns true if the build was reset.
+func resetPodCreationFailure(imageBuild *bibv1alpha1.ImageBuild) bool {
+	if !podCreationFailureSpecChanged(imageBuild) {
+		return false
+	}
+	status := &imageBuild.Status
+	status.Phase = ""
+	status.CompletionTime = nil
+	status.PodCreationFailures = 0
+	status.PodCreationFailedGeneration = 0
+	// The failure may have been notified already, the outcome of the retry is notified again.
+	status.Notification = nil
+	conditions.MarkUnknown(imageBuild, bibv1alpha1.BuilderPodReady, "Initializing", "Unknown")
+	return true
+}
@@ -0,0 +1,334 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+var _ = Describe("ImageBuild registry rate limiting", func() {
+	ctx := context.Background()
+
+	failedBuilderPod := func(name, message string, finishedAt time.Time) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: builderPodPrefix + name, Namespace: "default"},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodFailed,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name: builderContainerName,
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+						ExitCode: 1, Message: message, FinishedAt: metav1.NewTime(finishedAt),
+					}},
+				}},
+			},
+		}
+	}
+
+	builderPodExists := func(r *ImageBuildReconciler, name string) bool {
+		err := r.Get(ctx, types.NamespacedName{Name: builderPodPrefix + name, Namespace: "default"}, &corev1.Pod{})
+		if apierrors.IsNotFound(err) {
+			return false
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return true
+	}
+
+	It("doubles the backoff with every attempt up to a maximum", func() {
+		Expect(registryRateLimitBackoff(0)).To(Equal(registryRateLimitRetryBase))
+		Expect(registryRateLimitBackoff(1)).To(Equal(registryRateLimitRetryBase))
+		Expect(registryRateLimitBackoff(2)).To(Equal(2 * registryRateLimitRetryBase))
+		Expect(registryRateLimitBackoff(3)).To(Equal(4 * registryRateLimitRetryBase))
+		Expect(registryRateLimitBackoff(100)).To(Equal(registryRateLimitRetryMax))
+	})
+
+	It("waits out the backoff instead of failing a build rate limited on pull", func() {
+		ib := newTestImageBuild("pull-limited")
+		ib.Status.BuildAttempts = 2
+		pod := failedBuilderPod("pull-limited",
+			`{"rateLimited":{"operation":"pull","image":"ubuntu:24.04","message":"toomanyrequests: You have reached your pull rate limit"}}`,
+			time.Now())
+		r := newFakeReconciler(ib, pod)
+
+		result, updated := reconcileBuild(r, "pull-limited")
+		Expect(result.RequeueAfter).To(BeNumerically(">", registryRateLimitRetryBase))
+		Expect(result.RequeueAfter).To(BeNumerically("<=", 2*registryRateLimitRetryBase))
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhasePending))
+		Expect(updated.Status.CompletionTime).To(BeNil())
+		Expect(conditions.GetReason(updated, bibv1alpha1.BaseImageReady)).To(Equal(bibv1alpha1.RegistryRateLimitedReason))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.BaseImageReady)).To(ContainSubstring("toomanyrequests"))
+		Expect(builderPodExists(r, "pull-limited")).To(BeTrue())
+	})
+
+	It("recreates the builder pod once the backoff has elapsed", func() {
+		ib := newTestImageBuild("push-limited")
+		ib.Status.BuildAttempts = 1
+		pod := failedBuilderPod("push-limited",
+			`{"rateLimited":{"operation":"push","image":"quay.io/example/image:latest","message":"429 Too Many Requests"}}`,
+			time.Now().Add(-registryRateLimitRetryBase))
+		r := newFakeReconciler(ib, pod)
+
+		By("deleting the failed pod")
+		result, updated := reconcileBuild(r, "push-limited")
+		Expect(result.Requeue).To(BeTrue())
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhasePending))
+		Expect(builderPodExists(r, "push-limited")).To(BeFalse())
+		Expect(updated.Status.RateLimitRetries).To(Equal(int32(1)))
+
+		By("creating a new one on the next reconcile")
+		_, updated = reconcileBuild(r, "push-limited")
+		Expect(builderPodExists(r, "push-limited")).To(BeTrue())
+		Expect(updated.Status.BuildAttempts).To(Equal(int32(2)))
+		Expect(conditions.GetReason(updated, bibv1alpha1.OutputReady)).NotTo(Equal(bibv1alpha1.RegistryRateLimitedReason))
+	})
+
+	It("fails the build once the retries are exhausted", func() {
+		ib := newTestImageBuild("limited-too-often")
+		ib.Status.RateLimitRetries = maxRegistryRateLimitRetries
+		pod := failedBuilderPod("limited-too-often",
+			`{"rateLimited":{"operation":"pull","image":"ubuntu:24.04","message":"toomanyrequests: You have reached your pull rate limit"}}`,
+			time.Now())
+		r := newFakeReconciler(ib, pod)
+
+		result, updated := reconcileBuild(r, "limited-too-often")
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+		Expect(conditions.GetReason(updated, bibv1alpha1.BaseImageReady)).To(Equal(bibv1alpha1.RegistryRateLimitedReason))
+		Expect(conditions.GetSeverity(updated, bibv1alpha1.BaseImageReady)).To(HaveValue(Equal(clusterv1beta1.ConditionSeverityError)))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.BaseImageReady)).To(ContainSubstring("after 8 retries"))
+	})
+
+	It("still fails builds that were not rate limited", func() {
+		ib := newTestImageBuild("broken")
+		r := newFakeReconciler(ib, failedBuilderPod("broken", "", time.Now()))
+
+		result, updated := reconcileBuild(r, "broken")
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+		Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.BuildFailedReason))
+	})
+})
+
+var _ = Describe("ImageBuild builder pod eviction", func() {
+	ctx := context.Background()
+
+	evictedBuilderPod := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: builderPodPrefix + name, Namespace: "default"},
+			Status: corev1.PodStatus{
+				Phase:   corev1.PodFailed,
+				Reason:  podEvictedReason,
+				Message: "The node was low on resource: ephemeral-storage.",
+			},
+		}
+	}
+
+	It("recreates an evicted builder pod instead of failing the build", func() {
+		ib := newTestImageBuild("evicted")
+		ib.Status.BuildAttempts = 1
+		r := newFakeReconciler(ib, evictedBuilderPod("evicted"))
+
+		By("deleting the evicted pod")
+		result, updated := reconcileBuild(r, "evicted")
+		Expect(result.Requeue).To(BeTrue())
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhasePending))
+		Expect(updated.Status.CompletionTime).To(BeNil())
+		Expect(updated.Status.Evictions).To(Equal(int32(1)))
+		Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.PodEvictedReason))
+		Expect(conditions.GetSeverity(updated, bibv1alpha1.BuilderPodReady)).To(HaveValue(Equal(clusterv1beta1.ConditionSeverityWarning)))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.BuilderPodReady)).To(ContainSubstring("ephemeral-storage"))
+		err := r.Get(ctx, types.NamespacedName{Name: builderPodPrefix + "evicted", Namespace: "default"}, &corev1.Pod{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		By("creating a new one on the next reconcile")
+		_, updated = reconcileBuild(r, "evicted")
+		Expect(r.Get(ctx, types.NamespacedName{Name: builderPodPrefix + "evicted", Namespace: "default"}, &corev1.Pod{})).To(Succeed())
+		Expect(updated.Status.BuildAttempts).To(Equal(int32(2)))
+	})
+
+	It("recreates a builder pod evicted through the eviction API", func() {
+		ib := newTestImageBuild("drained")
+		pod := evictedBuilderPod("drained")
+		pod.Status.Reason, pod.Status.Message = "", ""
+		pod.Status.Conditions = []corev1.PodCondition{{
+			Type:    corev1.DisruptionTarget,
+			Status:  corev1.ConditionTrue,
+			Reason:  "EvictionByEvictionAPI",
+			Message: "Eviction API: evicting",
+		}}
+		r := newFakeReconciler(ib, pod)
+
+		result, updated := reconcileBuild(r, "drained")
+		Expect(result.Requeue).To(BeTrue())
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhasePending))
+		Expect(updated.Status.Evictions).To(Equal(int32(1)))
+		Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.PodEvictedReason))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.BuilderPodReady)).To(HaveSuffix("Eviction API: evicting"))
+	})
+
+	It("fails the build once spec.retryLimit evictions have been retried", func() {
+		ib := newTestImageBuild("evicted-often")
+		limit := int32(2)
+		ib.Spec.RetryLimit = &limit
+		ib.Status.Evictions = 2
+		r := newFakeReconciler(ib, evictedBuilderPod("evicted-often"))
+
+		result, updated := reconcileBuild(r, "evicted-often")
+		Expect(result.Requeue).To(BeFalse())
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+		Expect(updated.Status.CompletionTime).NotTo(BeNil())
+		Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.PodEvictedReason))
+		Expect(conditions.GetSeverity(updated, bibv1alpha1.BuilderPodReady)).To(HaveValue(Equal(clusterv1beta1.ConditionSeverityError)))
+	})
+
+	It("does not retry evictions with a retryLimit of 0", func() {
+		ib := newTestImageBuild("evicted-no-retry")
+		limit := int32(0)
+		ib.Spec.RetryLimit = &limit
+		r := newFakeReconciler(ib, evictedBuilderPod("evicted-no-retry"))
+
+		_, updated := reconcileBuild(r, "evicted-no-retry")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+		Expect(updated.Status.Evictions).To(BeZero())
+	})
+})
+
+var _ = Describe("ImageBuild builder pod creation failures", func() {
+	ctx := context.Background()
+
+	// A packer provisioner passes validation but cannot be turned into a builder pod yet.
+	newUnbuildableImageBuild := func(name string) *bibv1alpha1.ImageBuild {
+		ib := newTestImageBuild(name)
+		ib.Generation = 1
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Packer: &bibv1alpha1.PackerSpec{
+			Repo: "https://example.com/org/templates.git", TemplatePath: "image.pkr.hcl",
+		}}
+		return ib
+	}
+
+	It("backs off exponentially, then fails the build after the attempt cap", func() {
+		ib := newUnbuildableImageBuild("unbuildable")
+		r := newFakeReconciler(ib)
+
+		for attempt := int32(1); attempt < maxPodCreationAttempts; attempt++ {
+			result, updated := reconcileBuild(r, "unbuildable")
+			Expect(result.RequeueAfter).To(Equal(podCreationRetryBase << (attempt - 1)))
+			Expect(updated.Status.PodCreationFailures).To(Equal(attempt))
+			Expect(updated.Status.Phase).NotTo(Equal(bibv1alpha1.PhaseFailed))
+			Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).To(Equal(bibv1alpha1.PodCreationFailedReason))
+			Expect(conditions.GetSeverity(updated, bibv1alpha1.BuilderPodReady)).To(HaveValue(Equal(clusterv1beta1.ConditionSeverityWarning)))
+		}
+
+		result, updated := reconcileBuild(r, "unbuildable")
+		Expect(result).To(Equal(ctrl.Result{}))
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+		Expect(updated.Status.CompletionTime).NotTo(BeNil())
+		Expect(updated.Status.PodCreationFailedGeneration).To(Equal(int64(1)))
+		Expect(conditions.GetSeverity(updated, bibv1alpha1.BuilderPodReady)).To(HaveValue(Equal(clusterv1beta1.ConditionSeverityError)))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.BuilderPodReady)).To(ContainSubstring("packer provisioner is not implemented"))
+
+		By("staying failed while the spec is unchanged")
+		result, updated = reconcileBuild(r, "unbuildable")
+		Expect(result).To(Equal(ctrl.Result{}))
+		Expect(updated.Status.PodCreationFailures).To(Equal(maxPodCreationAttempts))
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+	})
+
+	// failPodCreation makes r's client fail every creation of a pod with err.
+	failPodCreation := func(r *ImageBuildReconciler, err error) {
+		r.Client = interceptor.NewClient(r.Client.(client.WithWatch), interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				if _, ok := obj.(*corev1.Pod); ok {
+					return err
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+		})
+	}
+
+	It("counts pods rejected by the API server", func() {
+		ib := newTestImageBuild("rejected")
+		r := newFakeReconciler(ib)
+		failPodCreation(r, apierrors.NewForbidden(corev1.Resource("pods"), builderPodPrefix+"rejected",
+			errors.New("exceeded quota: compute")))
+
+		result, updated := reconcileBuild(r, "rejected")
+		Expect(result.RequeueAfter).To(Equal(podCreationRetryBase))
+		Expect(updated.Status.PodCreationFailures).To(Equal(int32(1)))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.BuilderPodReady)).To(ContainSubstring("exceeded quota"))
+	})
+
+	It("returns transient API errors without counting them", func() {
+		ib := newTestImageBuild("timed-out")
+		r := newFakeReconciler(ib)
+		failPodCreation(r, apierrors.NewServerTimeout(corev1.Resource("pods"), "create", 1))
+
+		key := types.NamespacedName{Name: "timed-out", Namespace: "default"}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(apierrors.IsServerTimeout(err)).To(BeTrue())
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, updated)).To(Succeed())
+		Expect(updated.Status.PodCreationFailures).To(BeZero())
+		Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).NotTo(Equal(bibv1alpha1.PodCreationFailedReason))
+	})
+
+	It("requeues without counting a builder pod that already exists", func() {
+		ib := newTestImageBuild("created")
+		r := newFakeReconciler(ib)
+		failPodCreation(r, apierrors.NewAlreadyExists(corev1.Resource("pods"), builderPodPrefix+"created"))
+
+		result, updated := reconcileBuild(r, "created")
+		Expect(result.Requeue).To(BeTrue())
+		Expect(updated.Status.PodCreationFailures).To(BeZero())
+		Expect(conditions.GetReason(updated, bibv1alpha1.BuilderPodReady)).NotTo(Equal(bibv1alpha1.PodCreationFailedReason))
+	})
+
+	It("retries the build once the spec changes", func() {
+		ib := newUnbuildableImageBuild("fixed")
+		ib.Generation = 2
+		ib.Spec.Provisioner = nil
+		ib.Status = bibv1alpha1.ImageBuildStatus{
+			Phase:                       bibv1alpha1.PhaseFailed,
+			PodCreationFailures:         maxPodCreationAttempts,
+			PodCreationFailedGeneration: 1,
+		}
+		conditions.MarkFalse(ib, bibv1alpha1.BuilderPodReady, bibv1alpha1.PodCreationFailedReason, clusterv1beta1.ConditionSeverityError, "gave up")
+		r := newFakeReconciler(ib)
+
+		_, updated := reconcileBuild(r, "fixed")
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhasePending))
+		Expect(updated.Status.PodCreationFailures).To(BeZero())
+		Expect(updated.Status.CompletionTime).To(BeNil())
+		Expect(r.Get(ctx, types.NamespacedName{Name: builderPodPrefix + "fixed", Namespace: "default"}, &corev1.Pod{})).To(Succeed())
+	})
+})
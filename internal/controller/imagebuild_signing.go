@@ -0,0 +1,53 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+// signingTokenMountPath is where the service account token used as the keyless signing identity is mounted.
+const signingTokenMountPath = "/var/run/sigstore"
+
+// keylessSigningVolume returns the projected service account token the builder presents to Fulcio.
+// The kubelet refreshes it, so it stays valid however long the build runs before signing.
+func keylessSigningVolume(keyless *bibv1alpha1.KeylessSigning) corev1.Volume {
+	return corev1.Volume{
+		Name: "sigstore-token",
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{{
+					ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+						Audience: keyless.Audience,
+						Path:     "token",
+					},
+				}},
+			},
+		},
+	}
+}
+
+// keylessSigningEnv returns the variables telling the builder to sign the pushed image keyless.
+func keylessSigningEnv(keyless *bibv1alpha1.KeylessSigning) []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{Name: "SIGNING_IDENTITY_TOKEN_FILE", Value: signingTokenMountPath + "/token"},
+		{Name: "SIGNING_FULCIO_URL", Value: keyless.FulcioURL},
+		{Name: "SIGNING_REKOR_URL", Value: keyless.RekorURL},
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("ImageBuild keyless signing", func() {
+	ctx := context.Background()
+
+	newSignedImageBuild := func(name string) *bibv1alpha1.ImageBuild {
+		ib := newTestImageBuild(name)
+		ib.Spec.Output.PVC = nil
+		ib.Spec.Output.Registry = &bibv1alpha1.RegistryOutput{
+			Destination: "quay.io/example/" + name + ":latest", PullSecretName: "quay-push",
+		}
+		ib.Spec.Signing = &bibv1alpha1.SigningSpec{Keyless: &bibv1alpha1.KeylessSigning{
+			FulcioURL: "https://fulcio.example.com",
+			RekorURL:  "https://rekor.example.com",
+			Audience:  "sigstore",
+		}}
+		return ib
+	}
+
+	It("does not sign by default", func() {
+		ib := newSignedImageBuild("unsigned")
+		ib.Spec.Signing = nil
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Volumes).NotTo(ContainElement(HaveField("Name", "sigstore-token")))
+		Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "SIGNING_IDENTITY_TOKEN_FILE")))
+	})
+
+	It("projects a service account token for Fulcio into the builder", func() {
+		ib := newSignedImageBuild("signed")
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Volumes).To(ContainElement(And(
+			HaveField("Name", "sigstore-token"),
+			HaveField("Projected.Sources", ConsistOf(HaveField("ServiceAccountToken", &corev1.ServiceAccountTokenProjection{
+				Audience: "sigstore",
+				Path:     "token",
+			}))),
+		)))
+		Expect(pod.Spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name: "sigstore-token", MountPath: "/var/run/sigstore", ReadOnly: true,
+		}))
+		Expect(pod.Spec.Containers[0].Env).To(ContainElements(
+			corev1.EnvVar{Name: "SIGNING_IDENTITY_TOKEN_FILE", Value: "/var/run/sigstore/token"},
+			corev1.EnvVar{Name: "SIGNING_FULCIO_URL", Value: "https://fulcio.example.com"},
+			corev1.EnvVar{Name: "SIGNING_REKOR_URL", Value: "https://rekor.example.com"},
+		))
+	})
+
+	It("reports a failed signing on SigningReady", func() {
+		ib := newSignedImageBuild("sign-failed")
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: builderPodPrefix + "sign-failed", Namespace: "default"},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodFailed,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name: builderContainerName,
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+						ExitCode: 1,
+						Message: `{"stages":[{"name":"pull","phase":"Succeeded"},{"name":"push","phase":"Succeeded"},` +
+							`{"name":"sign","phase":"Failed","message":"fulcio rejected the identity token"}]}`,
+					}},
+				}},
+			},
+		}
+		r := newFakeReconciler(ib, pod)
+
+		key := types.NamespacedName{Name: "sign-failed", Namespace: "default"}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, updated)).To(Succeed())
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+		Expect(conditions.IsTrue(updated, bibv1alpha1.OutputReady)).To(BeTrue())
+		Expect(conditions.GetReason(updated, bibv1alpha1.SigningReady)).To(Equal(bibv1alpha1.BuildStageFailedReason))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.SigningReady)).To(Equal("stage sign failed: fulcio rejected the identity token"))
+	})
+})
@@ -0,0 +1,378 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+	"github.com/zarcen/bib-operator/internal/scope"
+)
+
+var _ = Describe("ImageBuild status server-side apply", func() {
+	// withStatusApplyRecorder makes r record the status applied by the scope. The fake client does
+	// not support server-side apply, so the applied status is merged instead.
+	withStatusApplyRecorder := func(r *ImageBuildReconciler) (*[]*unstructured.Unstructured, *client.SubResourcePatchOptions) {
+		var applied []*unstructured.Unstructured
+		options := &client.SubResourcePatchOptions{}
+		r.Client = interceptor.NewClient(r.Client.(client.WithWatch), interceptor.Funcs{
+			SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object,
+				patch client.Patch, opts ...client.SubResourcePatchOption) error {
+				if patch.Type() != types.ApplyPatchType {
+					return c.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+				}
+				applied = append(applied, obj.(*unstructured.Unstructured).DeepCopy())
+				options.ApplyOptions(opts)
+				data, err := patch.Data(obj)
+				if err != nil {
+					return err
+				}
+				return c.SubResource(subResourceName).Patch(ctx, obj, client.RawPatch(types.MergePatchType, data))
+			},
+		})
+		return &applied, options
+	}
+
+	It("applies the status as the controller, without the spec or a resource version", func() {
+		ctx := context.Background()
+		ib := newTestImageBuild("status-apply")
+		r := newFakeReconciler(ib)
+		r.StatusServerSideApply = true
+		applied, options := withStatusApplyRecorder(r)
+		key := types.NamespacedName{Name: "status-apply", Namespace: "default"}
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(*applied).To(HaveLen(1))
+		obj := (*applied)[0]
+		Expect(obj.GetKind()).To(Equal("ImageBuild"))
+		Expect(obj.GetResourceVersion()).To(BeEmpty())
+		Expect(obj.Object).To(HaveKey("status"))
+		Expect(obj.Object).NotTo(HaveKey("spec"))
+		Expect(options.FieldManager).To(Equal(FieldManager))
+		Expect(options.Force).To(HaveValue(BeTrue()))
+
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, updated)).To(Succeed())
+		Expect(updated.Finalizers).To(ContainElement(bibv1alpha1.ImageBuildFinalizer))
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhasePending))
+		Expect(conditions.Has(updated, bibv1alpha1.BuilderPodReady)).To(BeTrue())
+	})
+
+	It("patches the status by default", func() {
+		ctx := context.Background()
+		r := newFakeReconciler(newTestImageBuild("status-patch"))
+		applied, _ := withStatusApplyRecorder(r)
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "status-patch", Namespace: "default"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*applied).To(BeEmpty())
+	})
+
+	Context("against the API server", func() {
+		transition := metav1.NewTime(time.Now().UTC().Truncate(time.Second))
+
+		// reconcileStatus persists the status a reconcile of the builder pod computes, from the copy
+		// of the ImageBuild it read.
+		reconcileStatus := func(c client.Client, read *bibv1alpha1.ImageBuild, opts ...scope.ImageBuildScopeOption) {
+			ibs, err := scope.NewImageBuildScope(c, logf.Log, read, opts...)
+			Expect(err).NotTo(HaveOccurred())
+			read.Status.Phase = bibv1alpha1.PhaseBuilding
+			read.Status.BuilderPodName = builderPodPrefix + read.Name
+			conditions.Set(read, &clusterv1beta1.Condition{
+				Type: bibv1alpha1.BuilderPodReady, Status: corev1.ConditionTrue, LastTransitionTime: transition,
+			})
+			Expect(ibs.Close(ctx)).To(Succeed())
+		}
+
+		createImageBuild := func(name string) *bibv1alpha1.ImageBuild {
+			ib := newTestImageBuild(name)
+			Expect(k8sClient.Create(ctx, ib)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, ib))).To(Succeed())
+			})
+			return ib
+		}
+
+		It("does not thrash the status when concurrent reconciles apply it", func() {
+			ib := createImageBuild("status-concurrent")
+			key := client.ObjectKeyFromObject(ib)
+
+			By("applying the same status from two reconciles that read the same version")
+			var wg sync.WaitGroup
+			for range 2 {
+				wg.Add(1)
+				go func(read *bibv1alpha1.ImageBuild) {
+					defer GinkgoRecover()
+					defer wg.Done()
+					reconcileStatus(k8sClient, read, scope.WithStatusApply(FieldManager))
+				}(ib.DeepCopy())
+			}
+			wg.Wait()
+
+			stored := &bibv1alpha1.ImageBuild{}
+			Expect(k8sClient.Get(ctx, key, stored)).To(Succeed())
+			Expect(stored.Status.Phase).To(Equal(bibv1alpha1.PhaseBuilding))
+			Expect(conditions.IsTrue(stored, bibv1alpha1.BuilderPodReady)).To(BeTrue())
+			Expect(stored.ManagedFields).To(ContainElement(And(
+				HaveField("Manager", FieldManager),
+				HaveField("Operation", metav1.ManagedFieldsOperationApply),
+				HaveField("Subresource", "status"),
+			)))
+
+			By("applying it again from a stale copy without changing the object")
+			reconcileStatus(k8sClient, ib.DeepCopy(), scope.WithStatusApply(FieldManager))
+			again := &bibv1alpha1.ImageBuild{}
+			Expect(k8sClient.Get(ctx, key, again)).To(Succeed())
+			Expect(again.ResourceVersion).To(Equal(stored.ResourceVersion))
+		})
+
+		It("removes the status fields the controller stops setting, including ones it patched before", func() {
+			ib := createImageBuild("status-migrated")
+			key := client.ObjectKeyFromObject(ib)
+
+			By("patching the status as the controller did before server-side apply was enabled")
+			reconcileStatus(client.WithFieldOwner(k8sClient, "manager"), ib)
+
+			By("clearing the builder pod name with server-side apply")
+			read := &bibv1alpha1.ImageBuild{}
+			Expect(k8sClient.Get(ctx, key, read)).To(Succeed())
+			ibs, err := scope.NewImageBuildScope(k8sClient, logf.Log, read, scope.WithStatusApply(FieldManager))
+			Expect(err).NotTo(HaveOccurred())
+			read.Status.BuilderPodName = ""
+			Expect(ibs.Close(ctx)).To(Succeed())
+
+			stored := &bibv1alpha1.ImageBuild{}
+			Expect(k8sClient.Get(ctx, key, stored)).To(Succeed())
+			Expect(stored.Status.BuilderPodName).To(BeEmpty())
+			Expect(stored.Status.Phase).To(Equal(bibv1alpha1.PhaseBuilding))
+		})
+
+		It("removes the status fields the controller stops setting, including ones it patched under its own name", func() {
+			ib := createImageBuild("status-own-patch")
+			key := client.ObjectKeyFromObject(ib)
+
+			By("patching the status as the controller does with server-side apply disabled")
+			reconcileStatus(client.WithFieldOwner(k8sClient, FieldManager), ib)
+
+			By("clearing the builder pod name with server-side apply")
+			read := &bibv1alpha1.ImageBuild{}
+			Expect(k8sClient.Get(ctx, key, read)).To(Succeed())
+			ibs, err := scope.NewImageBuildScope(k8sClient, logf.Log, read, scope.WithStatusApply(FieldManager))
+			Expect(err).NotTo(HaveOccurred())
+			read.Status.BuilderPodName = ""
+			Expect(ibs.Close(ctx)).To(Succeed())
+
+			stored := &bibv1alpha1.ImageBuild{}
+			Expect(k8sClient.Get(ctx, key, stored)).To(Succeed())
+			Expect(stored.Status.BuilderPodName).To(BeEmpty())
+			Expect(stored.Status.Phase).To(Equal(bibv1alpha1.PhaseBuilding))
+		})
+	})
+})
+
+var _ = Describe("ImageBuild status edited by hand", func() {
+	ctx := context.Background()
+
+	reconcileEdited := func(r *ImageBuildReconciler, name string, edit func(*bibv1alpha1.ImageBuildStatus)) *bibv1alpha1.ImageBuild {
+		key := types.NamespacedName{Name: name, Namespace: "default"}
+		edited := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, edited)).To(Succeed())
+		edit(&edited.Status)
+		Expect(r.Status().Update(ctx, edited)).To(Succeed())
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, updated)).To(Succeed())
+		return updated
+	}
+
+	It("derives a phase set by hand from the builder pod again", func() {
+		ib := newTestImageBuild("edited")
+		ib.Status.Phase = bibv1alpha1.PhaseBuilding
+		r := newFakeReconciler(ib, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: builderPodPrefix + "edited", Namespace: "default"},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  builderContainerName,
+					State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+				}},
+			},
+		})
+
+		updated := reconcileEdited(r, "edited", func(status *bibv1alpha1.ImageBuildStatus) {
+			status.Phase = bibv1alpha1.PhaseSucceeded
+		})
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseBuilding))
+		Expect(updated.Status.CompletionTime).To(BeNil())
+
+		updated = reconcileEdited(r, "edited", func(status *bibv1alpha1.ImageBuildStatus) {
+			status.Phase = bibv1alpha1.PhasePending
+			status.BuilderPodName = "someone-elses-pod"
+		})
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseBuilding))
+		Expect(updated.Status.BuilderPodName).To(Equal(builderPodPrefix + "edited"))
+	})
+
+	DescribeTable("derives a completion inconsistent with the rest of the status from the builder pod again",
+		func(edit func(*bibv1alpha1.ImageBuildStatus)) {
+			ib := newTestImageBuild("inconsistent")
+			ib.Status.Phase = bibv1alpha1.PhaseBuilding
+			r := newFakeReconciler(ib, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: builderPodPrefix + "inconsistent", Namespace: "default"},
+				Status: corev1.PodStatus{
+					Phase: corev1.PodRunning,
+					ContainerStatuses: []corev1.ContainerStatus{{
+						Name:  builderContainerName,
+						State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+					}},
+				},
+			})
+
+			updated := reconcileEdited(r, "inconsistent", edit)
+			Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseBuilding))
+			Expect(updated.Status.CompletionTime).To(BeNil())
+		},
+		Entry("a failure without a failed condition", func(status *bibv1alpha1.ImageBuildStatus) {
+			completed := metav1.Now()
+			status.Phase, status.CompletionTime = bibv1alpha1.PhaseFailed, &completed
+		}),
+		Entry("a success with a failed condition", func(status *bibv1alpha1.ImageBuildStatus) {
+			completed := metav1.Now()
+			status.Phase, status.CompletionTime = bibv1alpha1.PhaseSucceeded, &completed
+			status.Conditions = clusterv1beta1.Conditions{{Type: bibv1alpha1.OutputReady, Status: corev1.ConditionFalse,
+				Severity: clusterv1beta1.ConditionSeverityError, Reason: bibv1alpha1.OutputDestinationFailedReason}}
+		}),
+		Entry("a completion before the start", func(status *bibv1alpha1.ImageBuildStatus) {
+			started := metav1.Now()
+			completed := metav1.NewTime(started.Add(-time.Hour))
+			status.Phase, status.StartTime, status.CompletionTime = bibv1alpha1.PhaseSucceeded, &started, &completed
+		}),
+		Entry("a completion time on an unfinished build", func(status *bibv1alpha1.ImageBuildStatus) {
+			completed := metav1.Now()
+			status.CompletionTime = &completed
+		}),
+	)
+
+	It("keeps the phase of a build the controller completed", func() {
+		ib := newTestImageBuild("completed")
+		r := newFakeReconciler(ib)
+
+		updated := reconcileEdited(r, "completed", func(status *bibv1alpha1.ImageBuildStatus) {
+			completed := metav1.Now()
+			status.Phase, status.CompletionTime = bibv1alpha1.PhaseFailed, &completed
+			status.Conditions = clusterv1beta1.Conditions{{Type: bibv1alpha1.BuilderPodReady, Status: corev1.ConditionFalse,
+				Severity: clusterv1beta1.ConditionSeverityError, Reason: bibv1alpha1.BuildFailedReason}}
+		})
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseFailed))
+		Expect(r.Get(ctx, types.NamespacedName{Name: builderPodPrefix + "completed", Namespace: "default"}, &corev1.Pod{})).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("ImageBuild finished", func() {
+	ctx := context.Background()
+
+	It("is not reconciled again, and reports a change of its spec instead of building it", func() {
+		ib := newTestImageBuild("settled")
+		ib.Generation = 1
+		r := newFakeReconciler(ib)
+		key := types.NamespacedName{Name: "settled", Namespace: "default"}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		finished := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, finished)).To(Succeed())
+		completed := metav1.Now()
+		finished.Status.Phase, finished.Status.CompletionTime = bibv1alpha1.PhaseSucceeded, &completed
+		Expect(r.Status().Update(ctx, finished)).To(Succeed())
+
+		// The status is applied on every reconcile with server-side apply, changed or not.
+		r.StatusServerSideApply = true
+		var calls []string
+		r.Client = interceptor.NewClient(r.Client.(client.WithWatch), interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				calls = append(calls, fmt.Sprintf("get %T", obj))
+				return c.Get(ctx, key, obj, opts...)
+			},
+			List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				calls = append(calls, fmt.Sprintf("list %T", list))
+				return c.List(ctx, list, opts...)
+			},
+			SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object,
+				patch client.Patch, opts ...client.SubResourcePatchOption) error {
+				calls = append(calls, "patch "+subResourceName)
+				if patch.Type() == types.ApplyPatchType {
+					return nil
+				}
+				return c.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+			},
+		})
+		_, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal([]string{"get *v1alpha1.ImageBuild"}))
+
+		By("reporting a new generation of the spec of a finished build without building it")
+		r.StatusServerSideApply = false
+		recorder := record.NewFakeRecorder(10)
+		r.Recorder = recorder
+		Expect(r.Get(ctx, key, finished)).To(Succeed())
+		finished.Generation = 2
+		Expect(r.Update(ctx, finished)).To(Succeed())
+		calls = nil
+		_, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).NotTo(ContainElement("get *v1.Pod"))
+		updated := &bibv1alpha1.ImageBuild{}
+		Expect(r.Get(ctx, key, updated)).To(Succeed())
+		Expect(updated.Status.ObservedGeneration).To(Equal(int64(1)))
+		Expect(updated.Status.Phase).To(Equal(bibv1alpha1.PhaseSucceeded))
+		message := "generation 2 of the spec was applied after the build finished with generation 1 and is not built; " +
+			"delete and re-create the ImageBuild to build it"
+		Expect(conditions.IsFalse(updated, bibv1alpha1.SpecBuilt)).To(BeTrue())
+		Expect(conditions.GetReason(updated, bibv1alpha1.SpecBuilt)).To(Equal(bibv1alpha1.SpecChangeIgnoredReason))
+		Expect(conditions.GetSeverity(updated, bibv1alpha1.SpecBuilt)).To(HaveValue(Equal(clusterv1beta1.ConditionSeverityWarning)))
+		Expect(conditions.GetMessage(updated, bibv1alpha1.SpecBuilt)).To(Equal(message))
+		Expect(recorder.Events).To(Receive(Equal("Warning SpecChangeIgnored " + message)))
+
+		By("leaving it alone once the change is reported")
+		calls = nil
+		_, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal([]string{"get *v1alpha1.ImageBuild"}))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+})
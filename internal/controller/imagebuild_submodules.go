@@ -0,0 +1,53 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+// gitSubmodules returns the variables, volumes and mounts checking out the submodules of the repository
+// of an Ansible run. envPrefix and namePrefix are prepended to the variables and to the volume names and
+// mount paths, e.g. "VERIFY_" and "verify-" for the verification step. Each submodule credentials Secret
+// is mounted in its own directory, which the builder picks by matching the remote URL of a submodule
+// against the URLs in ANSIBLE_GIT_SUBMODULE_CREDENTIALS, one "<url> <directory>" pair per line.
+func gitSubmodules(envPrefix, namePrefix string, ansible *bibv1alpha1.AnsibleSpec) ([]corev1.EnvVar, []corev1.Volume, []corev1.VolumeMount) {
+	if !ansible.Submodules {
+		return nil, nil, nil
+	}
+	envVars := []corev1.EnvVar{{Name: envPrefix + "ANSIBLE_GIT_SUBMODULES", Value: "true"}}
+	if len(ansible.SubmoduleCredentials) == 0 {
+		return envVars, nil, nil
+	}
+	var volumes []corev1.Volume
+	var mounts []corev1.VolumeMount
+	var lines []string
+	for i, creds := range ansible.SubmoduleCredentials {
+		volume, mount := gitCredentialsVolume(fmt.Sprintf("%sgit-submodule-credentials-%d", namePrefix, i), creds.SecretName,
+			fmt.Sprintf("/etc/%sgit-submodule-credentials/%d", namePrefix, i))
+		volumes = append(volumes, volume)
+		mounts = append(mounts, mount)
+		lines = append(lines, creds.URL+" "+mount.MountPath)
+	}
+	envVars = append(envVars, corev1.EnvVar{Name: envPrefix + "ANSIBLE_GIT_SUBMODULE_CREDENTIALS", Value: strings.Join(lines, "\n")})
+	return envVars, volumes, mounts
+}
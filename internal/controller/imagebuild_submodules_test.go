@@ -0,0 +1,108 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("ImageBuild git submodules", func() {
+	ctx := context.Background()
+
+	It("leaves the submodules alone unless enabled", func() {
+		ib := newTestImageBuild("no-submodules")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Ansible: &bibv1alpha1.AnsibleSpec{
+			Repo: "git@github.com:example/playbooks.git", Playbook: "site.yml",
+		}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "ANSIBLE_GIT_SUBMODULES")))
+	})
+
+	It("mounts a credentials secret per submodule URL", func() {
+		ib := newTestImageBuild("submodules")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Ansible: &bibv1alpha1.AnsibleSpec{
+			Repo: "git@github.com:example/playbooks.git", Playbook: "site.yml", CredentialsSecretName: "playbooks-key",
+			Submodules: true,
+			SubmoduleCredentials: []bibv1alpha1.GitSubmoduleCredentials{
+				{URL: "git@github.com:example/roles.git", SecretName: "roles-key"},
+				{URL: "https://gitlab.example.com/*", SecretName: "gitlab-token"},
+			},
+		}}
+		ib.Spec.Verify = &bibv1alpha1.VerifySpec{Ansible: &bibv1alpha1.AnsibleSpec{
+			Repo: "git@github.com:example/tests.git", Playbook: "smoke.yml", Submodules: true,
+			SubmoduleCredentials: []bibv1alpha1.GitSubmoduleCredentials{
+				{URL: "git@github.com:example/fixtures.git", SecretName: "fixtures-key"},
+			},
+		}}
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		builder := pod.Spec.Containers[0]
+		Expect(builder.Env).To(ContainElements(
+			corev1.EnvVar{Name: "ANSIBLE_GIT_SUBMODULES", Value: "true"},
+			corev1.EnvVar{Name: "ANSIBLE_GIT_SUBMODULE_CREDENTIALS", Value: "git@github.com:example/roles.git /etc/git-submodule-credentials/0\n" +
+				"https://gitlab.example.com/* /etc/git-submodule-credentials/1"},
+			corev1.EnvVar{Name: "VERIFY_ANSIBLE_GIT_SUBMODULES", Value: "true"},
+			corev1.EnvVar{Name: "VERIFY_ANSIBLE_GIT_SUBMODULE_CREDENTIALS",
+				Value: "git@github.com:example/fixtures.git /etc/verify-git-submodule-credentials/0"},
+		))
+		Expect(pod.Spec.Volumes).To(ContainElements(
+			HaveField("Secret.SecretName", "roles-key"),
+			HaveField("Secret.SecretName", "gitlab-token"),
+			HaveField("Secret.SecretName", "fixtures-key"),
+		))
+		Expect(builder.VolumeMounts).To(ContainElements(
+			corev1.VolumeMount{Name: "git-submodule-credentials-1", MountPath: "/etc/git-submodule-credentials/1", ReadOnly: true},
+			corev1.VolumeMount{Name: "verify-git-submodule-credentials-0", MountPath: "/etc/verify-git-submodule-credentials/0", ReadOnly: true},
+		))
+	})
+
+	It("requires the submodule credentials secrets", func() {
+		ib := newTestImageBuild("missing-submodule-key")
+		ib.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Ansible: &bibv1alpha1.AnsibleSpec{
+			Repo: "git@github.com:example/playbooks.git", Playbook: "site.yml", Submodules: true,
+			SubmoduleCredentials: []bibv1alpha1.GitSubmoduleCredentials{
+				{URL: "git@github.com:example/roles.git", SecretName: "roles-key"},
+			},
+		}}
+		r := newFakeReconciler(ib)
+
+		ok, err := r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(ib, bibv1alpha1.ProvisionerReady)).To(Equal(bibv1alpha1.SecretNotFoundReason))
+		Expect(conditions.GetMessage(ib, bibv1alpha1.ProvisionerReady)).To(
+			ContainSubstring("spec.provisioner.ansible.submoduleCredentials[0].secretName"))
+
+		By("passing once a deploy key exists")
+		r = newFakeReconciler(ib, newTestSecret("roles-key", corev1.SecretTypeSSHAuth, corev1.SSHAuthPrivateKey))
+		ok, err = r.reconcilePreflight(ctx, newTestScope(r, ib))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+	})
+})
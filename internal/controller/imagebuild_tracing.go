@@ -0,0 +1,146 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+	"github.com/zarcen/bib-operator/internal/tracing"
+)
+
+// tracerName is the instrumentation scope of the ImageBuild lifecycle spans.
+const tracerName = "github.com/zarcen/bib-operator/internal/controller"
+
+// lifecycleSpanName is the span covering an ImageBuild from its creation to its completion. The
+// spans of its phases, and the reconciles, are its children.
+const lifecycleSpanName = "ImageBuild"
+
+// stageSpanName returns the name of the span of a builder stage, a child of the phase it ran in.
+func stageSpanName(stage string) string {
+	return "stage " + stage
+}
+
+// attemptSpanKey returns the key the ID of the named span of a build attempt is derived from. A
+// retried build goes through its phases and stages again, so their spans are told apart by the
+// attempt, status.buildAttempts.
+func attemptSpanKey(name string, attempt int32) string {
+	return fmt.Sprintf("%s/%d", name, attempt)
+}
+
+// tracer returns the tracer of the ImageBuild lifecycle spans.
+func (r *ImageBuildReconciler) tracer() trace.Tracer {
+	provider := r.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(tracerName)
+}
+
+// buildAttributes returns the attributes identifying imageBuild on its spans.
+func buildAttributes(imageBuild *bibv1alpha1.ImageBuild) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("k8s.namespace.name", imageBuild.Namespace),
+		attribute.String("imagebuild.name", imageBuild.Name),
+	}
+}
+
+// startReconcileSpan starts the span of a reconcile of imageBuild, within its lifecycle trace.
+func (r *ImageBuildReconciler) startReconcileSpan(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild) (context.Context, trace.Span) {
+	return r.tracer().Start(tracing.ContextWithParent(ctx, imageBuild.UID, lifecycleSpanName), "Reconcile",
+		trace.WithAttributes(buildAttributes(imageBuild)...),
+		trace.WithAttributes(attribute.String("imagebuild.phase", string(imageBuild.Status.Phase))))
+}
+
+// endReconcileSpan ends the span of a reconcile, recording the error it returned, if any.
+func endReconcileSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// recordPhaseSpans records the span of the phase the build left during the reconcile, with the
+// spans of the builder stages that ran in it, and the lifecycle span once the build has finished.
+// The spans are recorded after the fact, with the IDs their children already refer to.
+func (r *ImageBuildReconciler) recordPhaseSpans(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild,
+	phase bibv1alpha1.ImageBuildPhase, since time.Time, ok bool, now time.Time) {
+	if imageBuild.Status.Phase == phase || isTerminalPhase(phase) {
+		return
+	}
+	if ok && phase != "" {
+		phaseKey := attemptSpanKey(string(phase), imageBuild.Status.BuildAttempts)
+		r.startSpan(ctx, imageBuild, string(phase), phaseKey, lifecycleSpanName, since).End(trace.WithTimestamp(now))
+		// The builder runs while the build is Building, or still Pending if it finished before the
+		// operator saw its container running.
+		if phase == bibv1alpha1.PhasePending || phase == bibv1alpha1.PhaseBuilding {
+			for _, stage := range imageBuild.Status.Stages {
+				if stage.StartTime == nil || stage.CompletionTime == nil {
+					continue
+				}
+				name := stageSpanName(stage.Name)
+				span := r.startSpan(ctx, imageBuild, name, attemptSpanKey(name, imageBuild.Status.BuildAttempts), phaseKey,
+					stage.StartTime.Time)
+				if stage.Phase == bibv1alpha1.BuildStageFailed {
+					span.SetStatus(codes.Error, stage.Message)
+				}
+				span.End(trace.WithTimestamp(stage.CompletionTime.Time))
+			}
+		}
+	}
+	if isTerminalPhase(imageBuild.Status.Phase) && !imageBuild.CreationTimestamp.IsZero() {
+		span := r.startSpan(ctx, imageBuild, lifecycleSpanName, lifecycleSpanName, "", imageBuild.CreationTimestamp.Time)
+		span.SetAttributes(attribute.String("imagebuild.phase", string(imageBuild.Status.Phase)))
+		if imageBuild.Status.Phase == bibv1alpha1.PhaseFailed {
+			span.SetStatus(codes.Error, failureMessage(imageBuild))
+		}
+		span.End(trace.WithTimestamp(now))
+	}
+}
+
+// startSpan starts the named lifecycle span of imageBuild at start, with the ID derived from key, as
+// a child of the span with the parent key, or as the root of the trace without one.
+func (r *ImageBuildReconciler) startSpan(ctx context.Context, imageBuild *bibv1alpha1.ImageBuild,
+	name, key, parent string, start time.Time) trace.Span {
+	opts := []trace.SpanStartOption{trace.WithTimestamp(start), trace.WithAttributes(buildAttributes(imageBuild)...)}
+	if parent == "" {
+		opts = append(opts, trace.WithNewRoot())
+	} else {
+		ctx = tracing.ContextWithParent(ctx, imageBuild.UID, parent)
+	}
+	_, span := r.tracer().Start(tracing.WithSpanID(ctx, imageBuild.UID, key), name, opts...)
+	return span
+}
+
+// failureMessage returns the message of the first false condition of a failed build.
+func failureMessage(imageBuild *bibv1alpha1.ImageBuild) string {
+	for _, condition := range imageBuild.Status.Conditions {
+		if condition.Status == corev1.ConditionFalse && condition.Message != "" {
+			return condition.Message
+		}
+	}
+	return ""
+}
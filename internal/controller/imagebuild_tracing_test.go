@@ -0,0 +1,113 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+	"github.com/zarcen/bib-operator/internal/tracing"
+)
+
+var _ = Describe("ImageBuild tracing", func() {
+	ctx := context.Background()
+
+	// spanNamed returns the recorded span with the given name.
+	spanNamed := func(recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+		for _, span := range recorder.Ended() {
+			if span.Name() == name {
+				return span
+			}
+		}
+		Fail("no span " + name)
+		return nil
+	}
+
+	It("passes the trace context of the Building span of the next attempt to the builder", func() {
+		ib := newTestImageBuild("traced")
+		ib.UID = "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+		ib.Status.BuildAttempts = 1
+		r := newFakeReconciler(ib)
+
+		pod, err := r.constructBuilderPod(ctx, ib)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+			Name:  "TRACEPARENT",
+			Value: "00-7c9e6679742540de944be07fc1f90ae7-" + tracing.SpanID(ib.UID, "Building/2").String() + "-01",
+		}))
+	})
+
+	It("traces the phases and builder stages of a finished build", func() {
+		ib := newTestImageBuild("finished")
+		ib.UID = "0b6c3f4e-27f1-4c1a-9a59-3f2d7f1e5a10"
+		ib.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+		ib.Status.Phase = bibv1alpha1.PhaseBuilding
+		ib.Status.BuildAttempts = 1
+		building := time.Now().Add(-50 * time.Minute).Truncate(time.Second)
+		ib.Status.LastPhaseTransitionTime = &metav1.Time{Time: building}
+		r := newFakeReconciler(ib, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: builderPodPrefix + "finished", Namespace: "default"},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodSucceeded,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name: builderContainerName,
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: `{"artifacts":[],"stages":[` +
+						`{"name":"provision","phase":"Succeeded","startTime":"2025-06-01T10:00:00Z","completionTime":"2025-06-01T10:20:00Z"},` +
+						`{"name":"convert","phase":"Succeeded"}]}`}},
+				}},
+			},
+		})
+		recorder := tracetest.NewSpanRecorder()
+		r.TracerProvider = tracing.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "finished", Namespace: "default"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		traceID := tracing.TraceID(ib.UID)
+		for _, span := range recorder.Ended() {
+			Expect(span.SpanContext().TraceID()).To(Equal(traceID))
+		}
+		root := spanNamed(recorder, "ImageBuild")
+		Expect(root.SpanContext().SpanID()).To(Equal(tracing.SpanID(ib.UID, "ImageBuild")))
+		Expect(root.Parent().IsValid()).To(BeFalse())
+		Expect(root.StartTime()).To(Equal(ib.CreationTimestamp.Time))
+
+		phase := spanNamed(recorder, "Building")
+		Expect(phase.SpanContext().SpanID()).To(Equal(tracing.SpanID(ib.UID, "Building/1")))
+		Expect(phase.Parent().SpanID()).To(Equal(root.SpanContext().SpanID()))
+		Expect(phase.StartTime()).To(Equal(building))
+
+		stage := spanNamed(recorder, "stage provision")
+		Expect(stage.SpanContext().SpanID()).To(Equal(tracing.SpanID(ib.UID, "stage provision/1")))
+		Expect(stage.Parent().SpanID()).To(Equal(phase.SpanContext().SpanID()))
+		Expect(stage.EndTime().Sub(stage.StartTime())).To(Equal(20 * time.Minute))
+		// Stages without timestamps are not traced.
+		Expect(recorder.Ended()).NotTo(ContainElement(HaveField("Name()", "stage convert")))
+
+		Expect(spanNamed(recorder, "Reconcile").Parent().SpanID()).To(Equal(root.SpanContext().SpanID()))
+	})
+})
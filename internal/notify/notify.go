@@ -0,0 +1,127 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package notify delivers ImageBuild terminal-state notifications to external HTTP endpoints.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+// defaultTimeout bounds a single delivery attempt so a slow endpoint cannot stall reconciliation.
+const defaultTimeout = 10 * time.Second
+
+// Condition is the subset of an ImageBuild condition included in a notification.
+type Condition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Payload is the JSON document POSTed to the notification endpoint.
+type Payload struct {
+	Name           string                      `json:"name"`
+	Namespace      string                      `json:"namespace"`
+	Phase          bibv1alpha1.ImageBuildPhase `json:"phase"`
+	OutputURL      string                      `json:"outputURL,omitempty"`
+	OutputURLs     []string                    `json:"outputURLs,omitempty"`
+	BuilderPodName string                      `json:"builderPodName,omitempty"`
+	StartTime      *time.Time                  `json:"startTime,omitempty"`
+	CompletionTime *time.Time                  `json:"completionTime,omitempty"`
+	Conditions     []Condition                 `json:"conditions,omitempty"`
+}
+
+// NewPayload builds the notification payload from the current state of an ImageBuild.
+func NewPayload(ib *bibv1alpha1.ImageBuild) Payload {
+	p := Payload{
+		Name:           ib.Name,
+		Namespace:      ib.Namespace,
+		Phase:          ib.Status.Phase,
+		OutputURL:      ib.Status.OutputURL,
+		OutputURLs:     ib.Status.OutputURLs,
+		BuilderPodName: ib.Status.BuilderPodName,
+	}
+	if ib.Status.StartTime != nil {
+		t := ib.Status.StartTime.Time
+		p.StartTime = &t
+	}
+	if ib.Status.CompletionTime != nil {
+		t := ib.Status.CompletionTime.Time
+		p.CompletionTime = &t
+	}
+	for _, c := range ib.Status.Conditions {
+		p.Conditions = append(p.Conditions, Condition{
+			Type:    string(c.Type),
+			Status:  string(c.Status),
+			Reason:  c.Reason,
+			Message: c.Message,
+		})
+	}
+	return p
+}
+
+// Notifier delivers a notification payload to an endpoint.
+type Notifier interface {
+	// Notify POSTs payload to url. If token is non-empty it is sent as a bearer token.
+	Notify(ctx context.Context, url, token string, payload Payload) error
+}
+
+// HTTPNotifier is a Notifier backed by an http.Client.
+type HTTPNotifier struct {
+	Client *http.Client
+}
+
+// NewHTTPNotifier returns an HTTPNotifier with a bounded request timeout.
+func NewHTTPNotifier() *HTTPNotifier {
+	return &HTTPNotifier{Client: &http.Client{Timeout: defaultTimeout}}
+}
+
+// Notify implements Notifier.
+func (n *HTTPNotifier) Notify(ctx context.Context, url, token string, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build notification request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver notification: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notification endpoint returned %s", resp.Status)
+	}
+	return nil
+}
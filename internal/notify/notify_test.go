@@ -0,0 +1,72 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("HTTPNotifier", func() {
+	ctx := context.Background()
+
+	payload := NewPayload(&bibv1alpha1.ImageBuild{
+		ObjectMeta: metav1.ObjectMeta{Name: "golden", Namespace: "builds"},
+		Status: bibv1alpha1.ImageBuildStatus{
+			Phase:     bibv1alpha1.PhaseSucceeded,
+			OutputURL: "s3://bucket/golden.qcow2",
+		},
+	})
+
+	It("POSTs the payload as JSON with the bearer token", func() {
+		var received Payload
+		var authorization string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.Method).To(Equal(http.MethodPost))
+			Expect(req.Header.Get("Content-Type")).To(Equal("application/json"))
+			authorization = req.Header.Get("Authorization")
+			Expect(json.NewDecoder(req.Body).Decode(&received)).To(Succeed())
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		Expect(NewHTTPNotifier().Notify(ctx, server.URL, "s3cr3t", payload)).To(Succeed())
+		Expect(authorization).To(Equal("Bearer s3cr3t"))
+		Expect(received.Name).To(Equal("golden"))
+		Expect(received.Namespace).To(Equal("builds"))
+		Expect(received.Phase).To(Equal(bibv1alpha1.PhaseSucceeded))
+		Expect(received.OutputURL).To(Equal("s3://bucket/golden.qcow2"))
+	})
+
+	It("returns an error for non-2xx responses", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		err := NewHTTPNotifier().Notify(ctx, server.URL, "", payload)
+		Expect(err).To(MatchError(ContainSubstring("503")))
+	})
+})
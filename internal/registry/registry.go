@@ -0,0 +1,139 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package registry inspects base images in their container registry before a build is scheduled.
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// Platform is an operating system and CPU architecture an image is available for.
+type Platform struct {
+	OS           string
+	Architecture string
+	Variant      string
+}
+
+// String returns the platform in the "os/arch[/variant]" notation, e.g. "linux/arm64/v8".
+func (p Platform) String() string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// PlatformsFunc returns the platforms an image reference is available for, authenticating with the
+// given .dockerconfigjson document if it is not empty.
+type PlatformsFunc func(ctx context.Context, ref string, dockerConfigJSON []byte) ([]Platform, error)
+
+// Platforms is the PlatformsFunc backed by the registry API. For a multi-platform image it lists the
+// platforms of the index, skipping attestation manifests; for a single-platform image it reads the
+// platform from the image config.
+func Platforms(ctx context.Context, ref string, dockerConfigJSON []byte) ([]Platform, error) {
+	parsed, err := name.ParseReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	keychain, err := newDockerConfigKeychain(dockerConfigJSON)
+	if err != nil {
+		return nil, err
+	}
+	desc, err := remote.Get(parsed, remote.WithContext(ctx), remote.WithAuthFromKeychain(keychain))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest of %s: %w", ref, err)
+	}
+
+	if desc.MediaType.IsIndex() {
+		index, err := desc.ImageIndex()
+		if err != nil {
+			return nil, err
+		}
+		manifest, err := index.IndexManifest()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image index of %s: %w", ref, err)
+		}
+		var platforms []Platform
+		for _, m := range manifest.Manifests {
+			if m.Platform == nil || m.Platform.OS == "unknown" {
+				continue
+			}
+			platforms = append(platforms, Platform{OS: m.Platform.OS, Architecture: m.Platform.Architecture, Variant: m.Platform.Variant})
+		}
+		return platforms, nil
+	}
+
+	image, err := desc.Image()
+	if err != nil {
+		return nil, err
+	}
+	config, err := image.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image config of %s: %w", ref, err)
+	}
+	return []Platform{{OS: config.OS, Architecture: config.Architecture, Variant: config.Variant}}, nil
+}
+
+// dockerConfigKeychain resolves registry credentials from a .dockerconfigjson document, falling back
+// to anonymous access for registries it has no entry for.
+type dockerConfigKeychain map[string]authn.AuthConfig
+
+func newDockerConfigKeychain(dockerConfigJSON []byte) (dockerConfigKeychain, error) {
+	keychain := dockerConfigKeychain{}
+	if len(dockerConfigJSON) == 0 {
+		return keychain, nil
+	}
+	var config struct {
+		Auths map[string]authn.AuthConfig `json:"auths"`
+	}
+	if err := json.Unmarshal(dockerConfigJSON, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode .dockerconfigjson: %w", err)
+	}
+	for server, auth := range config.Auths {
+		keychain[registryHost(server)] = auth
+	}
+	return keychain, nil
+}
+
+// Resolve implements authn.Keychain.
+func (k dockerConfigKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	if auth, ok := k[registryHost(target.RegistryStr())]; ok {
+		return authn.FromConfig(auth), nil
+	}
+	return authn.Anonymous, nil
+}
+
+// registryHost normalizes a .dockerconfigjson server key, which may be a URL such as
+// "https://index.docker.io/v1/", to the registry host, folding the Docker Hub aliases.
+func registryHost(server string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	switch host {
+	case "docker.io", "registry-1.docker.io":
+		return name.DefaultRegistry
+	}
+	return host
+}
+
+var _ authn.Keychain = dockerConfigKeychain{}
@@ -0,0 +1,128 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Platforms", func() {
+	ctx := context.Background()
+	var host string
+
+	BeforeEach(func() {
+		server := httptest.NewServer(ggcrregistry.New())
+		DeferCleanup(server.Close)
+		host = strings.TrimPrefix(server.URL, "http://")
+	})
+
+	imageFor := func(platform v1.Platform) v1.Image {
+		image, err := random.Image(256, 1)
+		Expect(err).NotTo(HaveOccurred())
+		config, err := image.ConfigFile()
+		Expect(err).NotTo(HaveOccurred())
+		config.OS, config.Architecture, config.Variant = platform.OS, platform.Architecture, platform.Variant
+		image, err = mutate.ConfigFile(image, config)
+		Expect(err).NotTo(HaveOccurred())
+		return image
+	}
+
+	It("lists the platforms of a multi-platform image", func() {
+		index := v1.ImageIndex(empty.Index)
+		for _, platform := range []v1.Platform{
+			{OS: "linux", Architecture: "amd64"},
+			{OS: "linux", Architecture: "arm64", Variant: "v8"},
+			{OS: "unknown", Architecture: "unknown"},
+		} {
+			index = mutate.AppendManifests(index, mutate.IndexAddendum{
+				Add:        imageFor(platform),
+				Descriptor: v1.Descriptor{Platform: &platform},
+			})
+		}
+		ref, err := name.ParseReference(host + "/library/ubuntu:24.04")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remote.WriteIndex(ref, index)).To(Succeed())
+
+		platforms, err := Platforms(ctx, ref.String(), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(platforms).To(Equal([]Platform{
+			{OS: "linux", Architecture: "amd64"},
+			{OS: "linux", Architecture: "arm64", Variant: "v8"},
+		}))
+		Expect(platforms[1].String()).To(Equal("linux/arm64/v8"))
+	})
+
+	It("reads the platform of a single-platform image from its config", func() {
+		ref, err := name.ParseReference(host + "/example/base:1.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remote.Write(ref, imageFor(v1.Platform{OS: "linux", Architecture: "amd64"}))).To(Succeed())
+
+		platforms, err := Platforms(ctx, ref.String(), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(platforms).To(Equal([]Platform{{OS: "linux", Architecture: "amd64"}}))
+	})
+
+	It("fails for images that do not exist", func() {
+		_, err := Platforms(ctx, host+"/example/missing:1.0", nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects a malformed .dockerconfigjson", func() {
+		_, err := Platforms(ctx, host+"/example/base:1.0", []byte("{"))
+		Expect(err).To(MatchError(ContainSubstring(".dockerconfigjson")))
+	})
+})
+
+var _ = Describe("dockerConfigKeychain", func() {
+	resolve := func(keychain dockerConfigKeychain, ref string) authn.Authenticator {
+		parsed, err := name.ParseReference(ref)
+		Expect(err).NotTo(HaveOccurred())
+		auth, err := keychain.Resolve(parsed.Context().Registry)
+		Expect(err).NotTo(HaveOccurred())
+		return auth
+	}
+
+	It("matches registries by host, folding the Docker Hub aliases", func() {
+		keychain, err := newDockerConfigKeychain([]byte(`{"auths":{
+			"https://index.docker.io/v1/":{"username":"hub","password":"hub-secret"},
+			"quay.io":{"auth":"cXVheTpxdWF5LXNlY3JldA=="}}}`))
+		Expect(err).NotTo(HaveOccurred())
+
+		config, err := resolve(keychain, "ubuntu:24.04").Authorization()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Username).To(Equal("hub"))
+
+		config, err = resolve(keychain, "quay.io/example/base:1.0").Authorization()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Auth).To(Equal("cXVheTpxdWF5LXNlY3JldA=="))
+
+		Expect(resolve(keychain, "ghcr.io/example/base:1.0")).To(Equal(authn.Anonymous))
+	})
+})
@@ -2,10 +2,18 @@ package scope
 
 import (
 	"context"
+	"encoding/json"
+	"slices"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/cluster-api/util/conditions"
+	v1beta2conditions "sigs.k8s.io/cluster-api/util/conditions/v1beta2"
 	"sigs.k8s.io/cluster-api/util/patch"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -19,9 +27,32 @@ type ImageBuildScope struct {
 	Logger      logr.Logger
 
 	ImageBuild *bibv1alpha1.ImageBuild
+
+	// statusFieldOwner is the field manager the status is applied as with server-side apply, or
+	// empty if the status is patched by the patch helper along with the rest of the object.
+	statusFieldOwner string
+	// statusBefore is the status as read at the start of the reconcile, if it is applied.
+	statusBefore *bibv1alpha1.ImageBuildStatus
+}
+
+// legacyFieldManager is the field manager the API server recorded for the patches of the
+// controller before it set a user agent of its own, derived from the name of the manager binary.
+const legacyFieldManager = "manager"
+
+// ImageBuildScopeOption configures an ImageBuildScope.
+type ImageBuildScopeOption func(*ImageBuildScope)
+
+// WithStatusApply persists the status with server-side apply as fieldOwner rather than with the
+// patch helper. The controller then owns exactly the status fields it sets: other writers of the
+// status keep their fields, and repeated reconciles computing the same status do not change the
+// object, whatever the resource version they read.
+func WithStatusApply(fieldOwner string) ImageBuildScopeOption {
+	return func(s *ImageBuildScope) {
+		s.statusFieldOwner = fieldOwner
+	}
 }
 
-func NewImageBuildScope(client client.Client, logger logr.Logger, ib *bibv1alpha1.ImageBuild) (*ImageBuildScope, error) {
+func NewImageBuildScope(client client.Client, logger logr.Logger, ib *bibv1alpha1.ImageBuild, opts ...ImageBuildScopeOption) (*ImageBuildScope, error) {
 	if client == nil {
 		return nil, errors.New("invalid arguments: client is nil")
 	}
@@ -34,23 +65,128 @@ func NewImageBuildScope(client client.Client, logger logr.Logger, ib *bibv1alpha
 		return nil, errors.Errorf("failed to initialize the patch helper: %v", err)
 	}
 
-	return &ImageBuildScope{
+	s := &ImageBuildScope{
 		Client:      client,
 		patchHelper: helper,
 		Logger:      logger,
 		ImageBuild:  ib,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.statusFieldOwner != "" {
+		s.statusBefore = ib.Status.DeepCopy()
+	}
+	return s, nil
 }
 
 func (s *ImageBuildScope) Close(ctx context.Context) error {
+	s.mirrorConditions()
 	return s.PatchObject(ctx)
 }
 
+// mirrorConditions copies the conditions to status.v1beta2.conditions, stamping all of them with the
+// current generation, as every reconcile evaluates them against the current spec.
+func (s *ImageBuildScope) mirrorConditions() {
+	for _, condition := range s.ImageBuild.Status.Conditions {
+		reason := condition.Reason
+		if reason == "" {
+			reason = v1beta2conditions.NoReasonReported
+		}
+		v1beta2conditions.Set(s.ImageBuild, metav1.Condition{
+			Type:               string(condition.Type),
+			Status:             metav1.ConditionStatus(condition.Status),
+			Reason:             reason,
+			Message:            condition.Message,
+			LastTransitionTime: condition.LastTransitionTime,
+		})
+	}
+}
+
 // PatchObject persists the machine spec and status.
 func (s *ImageBuildScope) PatchObject(ctx context.Context) error {
-	return s.patchHelper.Patch(
-		ctx,
-		s.ImageBuild)
+	if s.statusFieldOwner == "" {
+		return s.patchHelper.Patch(
+			ctx,
+			s.ImageBuild)
+	}
+
+	// Let the patch helper persist the metadata and spec only, then apply the status.
+	status := s.ImageBuild.Status.DeepCopy()
+	s.statusBefore.DeepCopyInto(&s.ImageBuild.Status)
+	err := s.patchHelper.Patch(ctx, s.ImageBuild)
+	status.DeepCopyInto(&s.ImageBuild.Status)
+	if err != nil {
+		return err
+	}
+	return s.applyStatus(ctx)
+}
+
+// applyStatus persists the status with server-side apply, forcing the ownership of the fields it
+// sets. Fields the controller owned and no longer sets are removed.
+func (s *ImageBuildScope) applyStatus(ctx context.Context) error {
+	if err := s.adoptClassicStatusFields(ctx); err != nil {
+		return err
+	}
+	status, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&s.ImageBuild.Status)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert the status")
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"status": status}}
+	obj.SetGroupVersionKind(bibv1alpha1.GroupVersion.WithKind("ImageBuild"))
+	obj.SetName(s.ImageBuild.Name)
+	obj.SetNamespace(s.ImageBuild.Namespace)
+	err = s.Client.Status().Patch(ctx, obj, client.Apply, client.FieldOwner(s.statusFieldOwner), client.ForceOwnership)
+	if apierrors.IsNotFound(err) && !s.ImageBuild.DeletionTimestamp.IsZero() {
+		// Removing the finalizer deleted the object.
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "failed to apply the status")
+	}
+	s.ImageBuild.ResourceVersion = obj.GetResourceVersion()
+	return nil
+}
+
+// adoptClassicStatusFields hands the status fields recorded for earlier patches of the controller,
+// e.g. before server-side apply was enabled, over to the status field owner. Server-side apply only
+// removes the fields its manager owns, so they could never be cleared otherwise.
+func (s *ImageBuildScope) adoptClassicStatusFields(ctx context.Context) error {
+	isStatusEntry := func(entry metav1.ManagedFieldsEntry, manager string, operation metav1.ManagedFieldsOperationType) bool {
+		return entry.Manager == manager && entry.Operation == operation && entry.Subresource == "status"
+	}
+	managedFields := s.ImageBuild.GetManagedFields()
+	adopted := slices.ContainsFunc(managedFields, func(entry metav1.ManagedFieldsEntry) bool {
+		return isStatusEntry(entry, s.statusFieldOwner, metav1.ManagedFieldsOperationApply)
+	})
+	var updated []metav1.ManagedFieldsEntry
+	changed := false
+	for _, entry := range managedFields {
+		if isStatusEntry(entry, s.statusFieldOwner, metav1.ManagedFieldsOperationUpdate) ||
+			isStatusEntry(entry, legacyFieldManager, metav1.ManagedFieldsOperationUpdate) {
+			changed = true
+			if adopted {
+				continue
+			}
+			entry.Manager = s.statusFieldOwner
+			entry.Operation = metav1.ManagedFieldsOperationApply
+			adopted = true
+		}
+		updated = append(updated, entry)
+	}
+	if !changed {
+		return nil
+	}
+	data, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"managedFields": updated}})
+	if err != nil {
+		return err
+	}
+	// Patch a copy, so the response does not overwrite the status about to be applied.
+	obj := s.ImageBuild.DeepCopy()
+	if err := s.Client.Patch(ctx, obj, client.RawPatch(types.MergePatchType, data)); err != nil {
+		return errors.Wrap(err, "failed to adopt the status fields of earlier patches")
+	}
+	s.ImageBuild.SetManagedFields(obj.GetManagedFields())
+	return nil
 }
 
 func (s *ImageBuildScope) InitializeConditions() {
@@ -0,0 +1,183 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing exports OpenTelemetry traces of the ImageBuild lifecycle. The lifecycle of an
+// object spans many reconciles, so its trace and span IDs are derived from the object's UID rather
+// than kept anywhere: spans recorded long after their parent started still join the same trace.
+package tracing
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// defaultServiceName is the service traces are reported as unless OTEL_SERVICE_NAME is set.
+const defaultServiceName = "bib-operator"
+
+// Enabled returns true if an OTLP endpoint is configured with the standard environment variables.
+func Enabled() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Setup installs the global tracer provider, exporting over OTLP/gRPC as configured by the
+// standard OTEL_EXPORTER_OTLP_* and OTEL_SERVICE_NAME variables, and the W3C trace context
+// propagator. Tracing stays disabled unless an endpoint is configured. The returned function
+// flushes the pending spans and stops the exporter.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	if !Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the OTLP trace exporter: %w", err)
+	}
+	res, err := serviceResource(resource.Default())
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe the trace resource: %w", err)
+	}
+	provider := NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// serviceResource returns the detected resource with the default service name, unless the service
+// name was configured. Without one, the detected resource names an unknown service, which would
+// override the default when merged after it.
+func serviceResource(detected *resource.Resource) (*resource.Resource, error) {
+	if name, ok := detected.Set().Value(semconv.ServiceNameKey); ok && !strings.HasPrefix(name.AsString(), "unknown_service") {
+		return detected, nil
+	}
+	return resource.Merge(detected, resource.NewSchemaless(semconv.ServiceName(defaultServiceName)))
+}
+
+// NewTracerProvider returns a tracer provider that honors the span IDs set with WithSpanID.
+func NewTracerProvider(opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(append(opts, sdktrace.WithIDGenerator(newIDGenerator()))...)
+}
+
+// TraceID returns the ID of the trace of the lifecycle of the object with the given UID.
+func TraceID(uid types.UID) trace.TraceID {
+	var id trace.TraceID
+	// A UUID has the size of a trace ID; other UIDs are hashed.
+	if decoded, err := hex.DecodeString(strings.ReplaceAll(string(uid), "-", "")); err == nil && len(decoded) == len(id) {
+		copy(id[:], decoded)
+		return id
+	}
+	hash := fnv.New128a()
+	_, _ = hash.Write([]byte(uid))
+	copy(id[:], hash.Sum(nil))
+	return id
+}
+
+// SpanID returns the ID of the named lifecycle span of the object with the given UID, e.g. of one
+// of its phases.
+func SpanID(uid types.UID, name string) trace.SpanID {
+	var id trace.SpanID
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(string(uid) + "/" + name))
+	copy(id[:], hash.Sum(nil))
+	return id
+}
+
+// SpanContext returns the context of the named lifecycle span of the object with the given UID.
+func SpanContext(uid types.UID, name string) trace.SpanContext {
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    TraceID(uid),
+		SpanID:     SpanID(uid, name),
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+}
+
+// ContextWithParent returns ctx with the named lifecycle span of the object as the parent of the
+// spans started from it, whether or not that span has been recorded yet.
+func ContextWithParent(ctx context.Context, uid types.UID, name string) context.Context {
+	return trace.ContextWithRemoteSpanContext(ctx, SpanContext(uid, name))
+}
+
+// Traceparent returns the named lifecycle span of the object as a W3C traceparent header value, for
+// processes outside the operator to continue the trace.
+func Traceparent(uid types.UID, name string) string {
+	sc := SpanContext(uid, name)
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID(), sc.SpanID(), sc.TraceFlags())
+}
+
+type spanIDKey struct{}
+
+// WithSpanID returns ctx making the next span started from it the named lifecycle span of the
+// object, so a span recorded after the fact has the ID its children already refer to. It requires
+// a tracer provider from NewTracerProvider.
+func WithSpanID(ctx context.Context, uid types.UID, name string) context.Context {
+	return context.WithValue(ctx, spanIDKey{}, SpanContext(uid, name))
+}
+
+// idGenerator generates random IDs, except for spans started from a context of WithSpanID.
+type idGenerator struct {
+	mu     sync.Mutex
+	random *rand.Rand
+}
+
+var _ sdktrace.IDGenerator = &idGenerator{}
+
+func newIDGenerator() *idGenerator {
+	var seed int64
+	_ = binary.Read(crand.Reader, binary.LittleEndian, &seed)
+	return &idGenerator{random: rand.New(rand.NewSource(seed))}
+}
+
+// NewIDs returns the IDs of a span without a parent.
+func (g *idGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	if sc, ok := ctx.Value(spanIDKey{}).(trace.SpanContext); ok {
+		return sc.TraceID(), sc.SpanID()
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var traceID trace.TraceID
+	var spanID trace.SpanID
+	_, _ = g.random.Read(traceID[:])
+	_, _ = g.random.Read(spanID[:])
+	return traceID, spanID
+}
+
+// NewSpanID returns the ID of a span of the given trace.
+func (g *idGenerator) NewSpanID(ctx context.Context, traceID trace.TraceID) trace.SpanID {
+	if sc, ok := ctx.Value(spanIDKey{}).(trace.SpanContext); ok && sc.TraceID() == traceID {
+		return sc.SpanID()
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var spanID trace.SpanID
+	_, _ = g.random.Read(spanID[:])
+	return spanID
+}
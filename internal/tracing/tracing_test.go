@@ -0,0 +1,87 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Lifecycle traces", func() {
+	ctx := context.Background()
+	uid := types.UID("7c9e6679-7425-40de-944b-e07fc1f90ae7")
+
+	It("derives the trace from the UID", func() {
+		Expect(TraceID(uid).String()).To(Equal("7c9e6679742540de944be07fc1f90ae7"))
+		Expect(TraceID("not-a-uuid").IsValid()).To(BeTrue())
+		Expect(SpanID(uid, "Building")).To(Equal(SpanID(uid, "Building")))
+		Expect(SpanID(uid, "Building")).NotTo(Equal(SpanID(uid, "Pending")))
+	})
+
+	It("renders a traceparent the W3C propagator accepts", func() {
+		carrier := propagation.MapCarrier{"traceparent": Traceparent(uid, "Building")}
+		sc := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(ctx, carrier))
+		Expect(sc.TraceID()).To(Equal(TraceID(uid)))
+		Expect(sc.SpanID()).To(Equal(SpanID(uid, "Building")))
+		Expect(sc.IsSampled()).To(BeTrue())
+	})
+
+	It("records spans after the fact with the IDs their children refer to", func() {
+		recorder := tracetest.NewSpanRecorder()
+		tracer := NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+		_, child := tracer.Start(WithSpanID(ContextWithParent(ctx, uid, "ImageBuild"), uid, "Building"), "Building")
+		child.End()
+		_, root := tracer.Start(WithSpanID(ctx, uid, "ImageBuild"), "ImageBuild")
+		root.End()
+		_, other := tracer.Start(ContextWithParent(ctx, uid, "ImageBuild"), "Reconcile")
+		other.End()
+
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(3))
+		Expect(spans[0].SpanContext().SpanID()).To(Equal(SpanID(uid, "Building")))
+		Expect(spans[0].Parent().SpanID()).To(Equal(spans[1].SpanContext().SpanID()))
+		Expect(spans[1].SpanContext().TraceID()).To(Equal(TraceID(uid)))
+		Expect(spans[1].SpanContext().SpanID()).To(Equal(SpanID(uid, "ImageBuild")))
+		Expect(spans[2].SpanContext().TraceID()).To(Equal(TraceID(uid)))
+		Expect(spans[2].SpanContext().SpanID()).NotTo(Equal(SpanID(uid, "ImageBuild")))
+	})
+
+	DescribeTable("names the service",
+		func(detected *resource.Resource, expected string) {
+			res, err := serviceResource(detected)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Set().ToSlice()).To(ConsistOf(
+				semconv.ServiceName(expected),
+				semconv.TelemetrySDKName("opentelemetry"),
+			))
+		},
+		Entry("bib-operator by default",
+			resource.NewSchemaless(semconv.ServiceName("unknown_service:manager"), semconv.TelemetrySDKName("opentelemetry")), "bib-operator"),
+		Entry("as configured",
+			resource.NewSchemaless(semconv.ServiceName("image-builds"), semconv.TelemetrySDKName("opentelemetry")), "image-builds"),
+	)
+})
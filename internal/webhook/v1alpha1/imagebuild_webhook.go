@@ -0,0 +1,128 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+// log is for logging in this package.
+var imagebuildlog = logf.Log.WithName("imagebuild-resource")
+
+// SetupImageBuildWebhookWithManager registers the webhook for ImageBuild in the manager.
+func SetupImageBuildWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&bibv1alpha1.ImageBuild{}).
+		WithDefaulter(&ImageBuildCustomDefaulter{}).
+		WithValidator(&ImageBuildCustomValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-bib-cluster-x-k8s-io-v1alpha1-imagebuild,mutating=true,failurePolicy=fail,sideEffects=None,groups=bib.cluster.x-k8s.io,resources=imagebuilds,verbs=create;update,versions=v1alpha1,name=mimagebuild-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ImageBuildCustomDefaulter normalizes the spelling of ImageBuild spec values with
+// ImageBuild.NormalizeSpec, e.g. spec.arch "x86_64" to "amd64", before the CRD schema checks them.
+type ImageBuildCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &ImageBuildCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the type ImageBuild.
+func (d *ImageBuildCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	imageBuild, ok := obj.(*bibv1alpha1.ImageBuild)
+	if !ok {
+		return fmt.Errorf("expected an ImageBuild object but got %T", obj)
+	}
+	imagebuildlog.V(1).Info("Defaulting for ImageBuild", "name", imageBuild.GetName())
+
+	imageBuild.NormalizeSpec()
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-bib-cluster-x-k8s-io-v1alpha1-imagebuild,mutating=false,failurePolicy=fail,sideEffects=None,groups=bib.cluster.x-k8s.io,resources=imagebuilds,verbs=create;update,versions=v1alpha1,name=vimagebuild-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ImageBuildCustomValidator rejects ImageBuilds whose spec fails ImageBuild.ValidateSpec.
+// The controller runs the same validation as a preflight check, so the webhook only moves
+// the failure from the ImageBuild conditions to admission time.
+type ImageBuildCustomValidator struct{}
+
+var _ webhook.CustomValidator = &ImageBuildCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type ImageBuild.
+func (v *ImageBuildCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	imageBuild, ok := obj.(*bibv1alpha1.ImageBuild)
+	if !ok {
+		return nil, fmt.Errorf("expected an ImageBuild object but got %T", obj)
+	}
+	imagebuildlog.V(1).Info("Validation for ImageBuild upon creation", "name", imageBuild.GetName())
+
+	return imageBuildWarnings(imageBuild), validateImageBuild(imageBuild, nil)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type ImageBuild.
+func (v *ImageBuildCustomValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldImageBuild, ok := oldObj.(*bibv1alpha1.ImageBuild)
+	if !ok {
+		return nil, fmt.Errorf("expected an ImageBuild object for the oldObj but got %T", oldObj)
+	}
+	imageBuild, ok := newObj.(*bibv1alpha1.ImageBuild)
+	if !ok {
+		return nil, fmt.Errorf("expected an ImageBuild object for the newObj but got %T", newObj)
+	}
+	imagebuildlog.V(1).Info("Validation for ImageBuild upon update", "name", imageBuild.GetName())
+
+	// Objects admitted before the webhook was enabled must still be able to have their
+	// finalizer removed, so only spec changes are validated.
+	if equality.Semantic.DeepEqual(oldImageBuild.Spec, imageBuild.Spec) {
+		return nil, nil
+	}
+	return imageBuildWarnings(imageBuild), validateImageBuild(imageBuild, oldImageBuild)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type ImageBuild.
+func (v *ImageBuildCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// imageBuildWarnings returns admission warnings about valid settings with caveats the webhook cannot
+// check, since it does not read other objects.
+func imageBuildWarnings(imageBuild *bibv1alpha1.ImageBuild) admission.Warnings {
+	var warnings admission.Warnings
+	if build := imageBuild.Spec.Build; build != nil && build.Cache != nil {
+		warnings = append(warnings, fmt.Sprintf("spec.build.cache.pvcName: unless PVC %s allows ReadWriteMany, "+
+			"concurrent builds sharing it can only run on the node it is attached to", build.Cache.PVCName))
+	}
+	return warnings
+}
+
+// validateImageBuild validates the spec of imageBuild, created if old is nil and updated from old otherwise.
+func validateImageBuild(imageBuild, old *bibv1alpha1.ImageBuild) error {
+	allErrs := append(imageBuild.ValidateSpec(), imageBuild.ValidateSpecChange(old)...)
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(bibv1alpha1.GroupVersion.WithKind("ImageBuild").GroupKind(), imageBuild.Name, allErrs)
+}
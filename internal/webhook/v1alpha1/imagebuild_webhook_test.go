@@ -0,0 +1,893 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	bibv1alpha1 "github.com/zarcen/bib-operator/api/v1alpha1"
+)
+
+var _ = Describe("ImageBuild Webhook", func() {
+	var (
+		ctx       context.Context
+		validator ImageBuildCustomValidator
+		obj       *bibv1alpha1.ImageBuild
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		validator = ImageBuildCustomValidator{}
+		obj = &bibv1alpha1.ImageBuild{
+			ObjectMeta: metav1.ObjectMeta{Name: "ami", Namespace: "default"},
+			Spec: bibv1alpha1.ImageBuildSpec{
+				Architecture: "amd64",
+				BaseImage:    "ubuntu:24.04",
+				Output: bibv1alpha1.OutputSpec{
+					ImageName: "ami",
+					PVC:       &bibv1alpha1.PVCOutput{Name: "artifacts"},
+				},
+				Publish: &bibv1alpha1.PublishSpec{AWS: &bibv1alpha1.AWSPublishSpec{
+					Region: "us-east-1", AMIName: "ami", InstanceType: "t3.small", SourceS3Bucket: "staging",
+					CredentialsSecretName: "aws-credentials",
+				}},
+			},
+		}
+	})
+
+	Context("When defaulting an ImageBuild", func() {
+		DescribeTable("normalizes the architecture",
+			func(arch, expected string) {
+				obj.Spec.Architecture = arch
+				Expect((&ImageBuildCustomDefaulter{}).Default(ctx, obj)).To(Succeed())
+				Expect(obj.Spec.Architecture).To(Equal(expected))
+			},
+			Entry("a canonical value", "arm64", "arm64"),
+			Entry("an upper-case value", "AMD64", "amd64"),
+			Entry("the uname name of amd64", "x86_64", "amd64"),
+			Entry("the uname name of arm64", "aarch64", "arm64"),
+			Entry("an upper-case alias", "AARCH64", "arm64"),
+			Entry("the default architecture", "", ""),
+			Entry("an upper-case riscv64", "RISCV64", "riscv64"),
+			Entry("an unsupported architecture, left to the schema to reject", "mips64", "mips64"),
+		)
+	})
+
+	Context("When mapping build architectures to AMI architectures", func() {
+		DescribeTable("maps supported architectures",
+			func(arch, expected string) {
+				amiArch, ok := bibv1alpha1.AMIArchitecture(arch)
+				Expect(ok).To(BeTrue())
+				Expect(amiArch).To(Equal(expected))
+			},
+			Entry("amd64", "amd64", "x86_64"),
+			Entry("arm64", "arm64", "arm64"),
+			Entry("the default architecture", "", "x86_64"),
+		)
+
+		It("does not map unsupported architectures", func() {
+			_, ok := bibv1alpha1.AMIArchitecture("riscv64")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("When creating an ImageBuild under the validating webhook", func() {
+		It("admits an AWS publish with a matching architecture", func() {
+			obj.Spec.Architecture = "arm64"
+			obj.Spec.Publish.AWS.Architecture = "arm64"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("rejects an AWS architecture that does not match spec.arch", func() {
+			obj.Spec.Publish.AWS.Architecture = "arm64"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.publish.aws.architecture"))
+		})
+
+		It("rejects a build architecture that cannot be imported as an AMI", func() {
+			obj.Spec.Architecture = "riscv64"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.arch"))
+		})
+
+		It("rejects a UEFI AMI without an EFI system partition", func() {
+			efi := false
+			obj.Spec.Publish.AWS.BootMode = bibv1alpha1.AWSBootModeUEFI
+			obj.Spec.Output.Partitioning = &bibv1alpha1.Partitioning{EFISystemPartition: &efi}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.publish.aws.bootMode"))
+
+			By("admitting it with the default layout")
+			obj.Spec.Output.Partitioning = nil
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("rejects an unsupported build architecture", func() {
+			obj.Spec.Architecture = "mips64"
+			obj.Spec.Publish = nil
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(`spec.arch: Unsupported value: "mips64"`))
+		})
+
+		It("does not restrict the architecture of non-AWS publishes", func() {
+			obj.Spec.Architecture = "riscv64"
+			obj.Spec.Publish = nil
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("When creating an ImageBuild on another ImageBuild", func() {
+		BeforeEach(func() {
+			obj.Spec.BaseImage = ""
+			obj.Spec.BaseImageFrom = &bibv1alpha1.ImageBuildReference{Name: "base"}
+		})
+
+		It("admits a reference to another ImageBuild", func() {
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("rejects setting both spec.baseImage and spec.baseImageFrom", func() {
+			obj.Spec.BaseImage = "ubuntu:24.04"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.baseImageFrom"))
+		})
+
+		It("rejects a build on itself", func() {
+			obj.Spec.BaseImageFrom.Name = obj.Name
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.baseImageFrom.name"))
+		})
+	})
+
+	Context("When creating an ImageBuild with pod metadata", func() {
+		It("admits valid labels and annotations", func() {
+			obj.Spec.PodMetadata = &bibv1alpha1.PodMetadata{
+				Labels:      map[string]string{"team": "platform"},
+				Annotations: map[string]string{"sidecar.istio.io/inject": "false"},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("rejects operator-managed labels", func() {
+			obj.Spec.PodMetadata = &bibv1alpha1.PodMetadata{
+				Labels: map[string]string{bibv1alpha1.ComponentLabel: "builder"},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.podMetadata.labels"))
+		})
+
+		It("rejects malformed label values", func() {
+			obj.Spec.PodMetadata = &bibv1alpha1.PodMetadata{
+				Labels: map[string]string{"team": "not a valid value"},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		})
+	})
+
+	Context("When creating an ImageBuild with builder env", func() {
+		It("rejects invalid and duplicate names", func() {
+			obj.Spec.BuilderEnv = []corev1.EnvVar{{Name: "GOOD", Value: "1"}, {Name: "1=BAD"}, {Name: "GOOD"}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.builderEnv[1].name"))
+			Expect(err.Error()).To(ContainSubstring("spec.builderEnv[2].name"))
+		})
+	})
+
+	Context("When creating an ImageBuild with secret files", func() {
+		It("rejects invalid keys, paths to directories, reserved and duplicate paths", func() {
+			obj.Spec.Build = &bibv1alpha1.BuildSpec{SecretFiles: []bibv1alpha1.SecretFileMount{
+				{SecretName: "vault", Key: "token", Path: "/run/secrets/vault-token"},
+				{SecretName: "vault", Key: "role id", Path: "/run/secrets/role-id"},
+				{SecretName: "vault", Key: "token", Path: "/run/secrets/"},
+				{SecretName: "vault", Key: "token", Path: "/run/secrets/../vault-token"},
+				{SecretName: "licenses", Key: "rhel.pem", Path: "/run/secrets/vault-token"},
+				{SecretName: "vault", Key: "token", Path: "/output/vault-token"},
+				{SecretName: "vault", Key: "token", Path: "/etc/registry-auth-1/config.json"},
+				{SecretName: "vault", Key: "token", Path: "/outputs/vault-token"},
+			}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).NotTo(ContainSubstring("spec.build.secretFiles[0]"))
+			Expect(err.Error()).To(ContainSubstring("spec.build.secretFiles[1].key"))
+			Expect(err.Error()).To(ContainSubstring("spec.build.secretFiles[2].path"))
+			Expect(err.Error()).To(ContainSubstring("spec.build.secretFiles[3].path"))
+			Expect(err.Error()).To(ContainSubstring("spec.build.secretFiles[4].path"))
+			Expect(err.Error()).To(ContainSubstring("spec.build.secretFiles[5].path: Invalid value: \"/output/vault-token\": must not be in /output"))
+			Expect(err.Error()).To(ContainSubstring("spec.build.secretFiles[6].path"))
+			Expect(err.Error()).NotTo(ContainSubstring("spec.build.secretFiles[7]"))
+		})
+	})
+
+	Context("When creating an ImageBuild with init containers", func() {
+		It("rejects invalid and duplicate names and missing images", func() {
+			obj.Spec.Build = &bibv1alpha1.BuildSpec{InitContainers: []corev1.Container{
+				{Name: "prefetch", Image: "curlimages/curl:8.10.1"},
+				{Name: "Prefetch_Assets", Image: "curlimages/curl:8.10.1"},
+				{Name: "prefetch", Image: "busybox:1.36"},
+				{Name: "unpack"},
+			}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).NotTo(ContainSubstring("spec.build.initContainers[0]"))
+			Expect(err.Error()).To(ContainSubstring("spec.build.initContainers[1].name"))
+			Expect(err.Error()).To(ContainSubstring("spec.build.initContainers[2].name"))
+			Expect(err.Error()).To(ContainSubstring("spec.build.initContainers[3].image"))
+		})
+
+		It("rejects the names of the operator's containers", func() {
+			obj.Spec.Build = &bibv1alpha1.BuildSpec{InitContainers: []corev1.Container{
+				{Name: "builder", Image: "busybox:1.36"},
+				{Name: "disk-space-check", Image: "busybox:1.36"},
+			}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(`spec.build.initContainers[0].name: Invalid value: "builder": is used by a container of the operator`))
+			Expect(err.Error()).To(ContainSubstring("spec.build.initContainers[1].name"))
+		})
+	})
+
+	Context("When creating an ImageBuild with a PVC output", func() {
+		DescribeTable("validates the subPath",
+			func(subPath string, valid bool) {
+				obj.Spec.Output.PVC.SubPath = subPath
+				_, err := validator.ValidateCreate(ctx, obj)
+				if valid {
+					Expect(err).NotTo(HaveOccurred())
+				} else {
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				}
+			},
+			Entry("a relative path", "pipelines/stage-1", true),
+			Entry("a name containing dots", "v1..2", true),
+			Entry("an absolute path", "/pipelines", false),
+			Entry("a path escaping the volume", "pipelines/../../etc", false),
+		)
+	})
+
+	Context("When creating an ImageBuild that assumes an AWS role", func() {
+		DescribeTable("validates the role",
+			func(roleARN, externalID string, valid bool) {
+				obj.Spec.Publish.AWS.RoleARN = roleARN
+				obj.Spec.Publish.AWS.ExternalID = externalID
+				_, err := validator.ValidateCreate(ctx, obj)
+				if valid {
+					Expect(err).NotTo(HaveOccurred())
+				} else {
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				}
+			},
+			Entry("a role", "arn:aws:iam::111122223333:role/ami-publisher", "", true),
+			Entry("a role with a path and an external ID", "arn:aws:iam::111122223333:role/ci/ami-publisher", "bib", true),
+			Entry("a role in another partition", "arn:aws-us-gov:iam::111122223333:role/ami-publisher", "", true),
+			Entry("a user ARN", "arn:aws:iam::111122223333:user/ci", "", false),
+			Entry("a malformed account ID", "arn:aws:iam::1111:role/ami-publisher", "", false),
+			Entry("an external ID without a role", "", "bib", false),
+		)
+
+		It("validates the object storage output role", func() {
+			obj.Spec.Output.ObjectStorage = &bibv1alpha1.ObjectStorageOutput{
+				Bucket: "archive", Region: "eu-west-1", CredentialsSecretName: "s3-credentials",
+				AWSAssumeRole: bibv1alpha1.AWSAssumeRole{RoleARN: "archive-writer"},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.output.objectStorage.roleARN"))
+		})
+	})
+
+	Context("When creating an ImageBuild with a minimum of free disk space", func() {
+		DescribeTable("validates the minimum",
+			func(minimum string, workVolume *bibv1alpha1.WorkVolume, valid bool) {
+				quantity := resource.MustParse(minimum)
+				obj.Spec.Build = &bibv1alpha1.BuildSpec{MinFreeDiskSpace: &quantity, WorkVolume: workVolume}
+				_, err := validator.ValidateCreate(ctx, obj)
+				if valid {
+					Expect(err).NotTo(HaveOccurred())
+				} else {
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+					Expect(err.Error()).To(ContainSubstring("spec.build.minFreeDiskSpace"))
+				}
+			},
+			Entry("a minimum", "50Gi", nil, true),
+			Entry("a disabled check", "0", nil, true),
+			Entry("a minimum within the work volume", "50Gi", &bibv1alpha1.WorkVolume{Size: resource.MustParse("200Gi")}, true),
+			Entry("a negative minimum", "-1Gi", nil, false),
+			Entry("a minimum exceeding the work volume", "50Gi", &bibv1alpha1.WorkVolume{Size: resource.MustParse("20Gi")}, false),
+		)
+	})
+
+	Context("When creating an ImageBuild with an object storage content type", func() {
+		DescribeTable("validates the MIME type",
+			func(contentType string, valid bool) {
+				obj.Spec.Output.ObjectStorage = &bibv1alpha1.ObjectStorageOutput{
+					Bucket: "archive", CredentialsSecretName: "s3-credentials", ContentType: contentType,
+				}
+				_, err := validator.ValidateCreate(ctx, obj)
+				if valid {
+					Expect(err).NotTo(HaveOccurred())
+				} else {
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+					Expect(err.Error()).To(ContainSubstring("spec.output.objectStorage.contentType"))
+				}
+			},
+			Entry("detected from the format", "", true),
+			Entry("a MIME type", "application/octet-stream", true),
+			Entry("a MIME type with parameters", "text/plain; charset=utf-8", true),
+			Entry("a type without a subtype", "application", false),
+			Entry("an empty subtype", "application/", false),
+			Entry("a malformed parameter", "text/plain; charset", false),
+		)
+
+		It("validates the build log content type", func() {
+			obj.Spec.Build = &bibv1alpha1.BuildSpec{LogsOutput: &bibv1alpha1.ObjectStorageOutput{
+				Bucket: "build-logs", CredentialsSecretName: "logs-credentials", ContentType: "text plain",
+			}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.build.logsOutput.contentType"))
+		})
+	})
+
+	Context("When creating an ImageBuild with an Ansible Python interpreter", func() {
+		DescribeTable("validates the interpreter and virtual environment",
+			func(ansible bibv1alpha1.AnsibleSpec, valid bool) {
+				ansible.Repo, ansible.Playbook = "https://example.com/org/playbooks.git", "site.yml"
+				obj.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Ansible: &ansible}
+				_, err := validator.ValidateCreate(ctx, obj)
+				if valid {
+					Expect(err).NotTo(HaveOccurred())
+				} else {
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				}
+			},
+			Entry("an absolute interpreter path", bibv1alpha1.AnsibleSpec{PythonInterpreter: "/usr/libexec/platform-python"}, true),
+			Entry("a discovery mode", bibv1alpha1.AnsibleSpec{PythonInterpreter: "auto_silent"}, true),
+			Entry("a relative interpreter path", bibv1alpha1.AnsibleSpec{PythonInterpreter: "python3"}, false),
+			Entry("a virtual environment", bibv1alpha1.AnsibleSpec{Virtualenv: &bibv1alpha1.AnsibleVirtualenv{
+				Path: "/opt/ansible", Packages: []string{"jmespath==1.0.1"},
+			}}, true),
+			Entry("a relative virtual environment path", bibv1alpha1.AnsibleSpec{Virtualenv: &bibv1alpha1.AnsibleVirtualenv{Path: "venv"}}, false),
+			Entry("a virtual environment at the root", bibv1alpha1.AnsibleSpec{Virtualenv: &bibv1alpha1.AnsibleVirtualenv{Path: "/"}}, false),
+			Entry("a pip option as a package", bibv1alpha1.AnsibleSpec{Virtualenv: &bibv1alpha1.AnsibleVirtualenv{
+				Path: "/opt/ansible", Packages: []string{"--index-url=https://evil.example.com"},
+			}}, false),
+		)
+	})
+
+	Context("When creating an ImageBuild with an Ansible connection", func() {
+		It("rejects unknown connection plugins", func() {
+			obj.Spec.Verify = &bibv1alpha1.VerifySpec{Ansible: &bibv1alpha1.AnsibleSpec{
+				Repo: "https://example.com/org/tests.git", Playbook: "smoke.yml", Connection: "winrm",
+			}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.verify.ansible.connection"))
+
+			obj.Spec.Verify.Ansible.Connection = "ssh"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+	})
+
+	Context("When creating an ImageBuild with git submodules", func() {
+		DescribeTable("validates the submodule credentials",
+			func(ansible bibv1alpha1.AnsibleSpec, valid bool) {
+				ansible.Repo, ansible.Playbook = "git@github.com:example/playbooks.git", "site.yml"
+				obj.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Ansible: &ansible}
+				_, err := validator.ValidateCreate(ctx, obj)
+				if valid {
+					Expect(err).NotTo(HaveOccurred())
+				} else {
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+					Expect(err.Error()).To(ContainSubstring("spec.provisioner.ansible.submoduleCredentials"))
+				}
+			},
+			Entry("submodules without credentials", bibv1alpha1.AnsibleSpec{Submodules: true}, true),
+			Entry("a deploy key per submodule", bibv1alpha1.AnsibleSpec{Submodules: true,
+				SubmoduleCredentials: []bibv1alpha1.GitSubmoduleCredentials{
+					{URL: "git@github.com:example/roles.git", SecretName: "roles-key"},
+					{URL: "git@github.com:example/files-*.git", SecretName: "files-key"},
+				}}, true),
+			Entry("credentials without submodules", bibv1alpha1.AnsibleSpec{
+				SubmoduleCredentials: []bibv1alpha1.GitSubmoduleCredentials{
+					{URL: "git@github.com:example/roles.git", SecretName: "roles-key"},
+				}}, false),
+			Entry("a duplicate URL", bibv1alpha1.AnsibleSpec{Submodules: true,
+				SubmoduleCredentials: []bibv1alpha1.GitSubmoduleCredentials{
+					{URL: "git@github.com:example/roles.git", SecretName: "roles-key"},
+					{URL: "git@github.com:example/roles.git", SecretName: "other-key"},
+				}}, false),
+			Entry("a URL with whitespace", bibv1alpha1.AnsibleSpec{Submodules: true,
+				SubmoduleCredentials: []bibv1alpha1.GitSubmoduleCredentials{
+					{URL: "git@github.com:example/roles.git /etc/passwd", SecretName: "roles-key"},
+				}}, false),
+			Entry("a missing secret name", bibv1alpha1.AnsibleSpec{Submodules: true,
+				SubmoduleCredentials: []bibv1alpha1.GitSubmoduleCredentials{
+					{URL: "git@github.com:example/roles.git"},
+				}}, false),
+		)
+	})
+
+	Context("When creating an ImageBuild with a build cache", func() {
+		It("warns about sharing a ReadWriteOnce cache", func() {
+			warnings, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+
+			obj.Spec.Build = &bibv1alpha1.BuildSpec{Cache: &bibv1alpha1.BuildCache{PVCName: "package-cache"}}
+			warnings, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(ConsistOf(ContainSubstring("unless PVC package-cache allows ReadWriteMany")))
+		})
+	})
+
+	Context("When creating an ImageBuild with a security profile", func() {
+		DescribeTable("validates the seccomp profile",
+			func(profile bibv1alpha1.Profile, valid bool) {
+				obj.Spec.Build = &bibv1alpha1.BuildSpec{SecurityProfile: &bibv1alpha1.SecurityProfile{SeccompProfile: &profile}}
+				_, err := validator.ValidateCreate(ctx, obj)
+				if valid {
+					Expect(err).NotTo(HaveOccurred())
+				} else {
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				}
+			},
+			Entry("the runtime default", bibv1alpha1.Profile{Type: bibv1alpha1.ProfileTypeRuntimeDefault}, true),
+			Entry("a localhost profile", bibv1alpha1.Profile{Type: bibv1alpha1.ProfileTypeLocalhost, LocalhostProfile: "profiles/builder.json"}, true),
+			Entry("an unconfined profile", bibv1alpha1.Profile{Type: "Unconfined"}, false),
+			Entry("a localhost profile without a path", bibv1alpha1.Profile{Type: bibv1alpha1.ProfileTypeLocalhost}, false),
+			Entry("a localhost profile escaping the seccomp directory", bibv1alpha1.Profile{Type: bibv1alpha1.ProfileTypeLocalhost, LocalhostProfile: "../builder.json"}, false),
+			Entry("a runtime default with a path", bibv1alpha1.Profile{Type: bibv1alpha1.ProfileTypeRuntimeDefault, LocalhostProfile: "builder.json"}, false),
+		)
+
+		It("rejects an AppArmor localhost profile without a name", func() {
+			obj.Spec.Build = &bibv1alpha1.BuildSpec{SecurityProfile: &bibv1alpha1.SecurityProfile{
+				AppArmorProfile: &bibv1alpha1.Profile{Type: bibv1alpha1.ProfileTypeLocalhost},
+			}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.build.securityProfile.appArmorProfile.localhostProfile"))
+		})
+	})
+
+	Context("When creating an ImageBuild with a custom disk layout", func() {
+		size := func(quantity string) *resource.Quantity {
+			q := resource.MustParse(quantity)
+			return &q
+		}
+		esp := bibv1alpha1.DiskPartition{Size: size("512Mi"), Filesystem: bibv1alpha1.PartitionFilesystemVFAT, MountPoint: "/boot/efi"}
+		root := bibv1alpha1.DiskPartition{Filesystem: bibv1alpha1.PartitionFilesystemExt4, MountPoint: "/"}
+
+		DescribeTable("validates the partitions",
+			func(mutate func(*bibv1alpha1.OutputSpec), invalidField string) {
+				obj.Spec.Output.DiskLayout = &bibv1alpha1.DiskLayout{Partitions: []bibv1alpha1.DiskPartition{esp, root}}
+				mutate(&obj.Spec.Output)
+				_, err := validator.ValidateCreate(ctx, obj)
+				if invalidField == "" {
+					Expect(err).NotTo(HaveOccurred())
+				} else {
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+					Expect(err.Error()).To(ContainSubstring(invalidField))
+				}
+			},
+			Entry("an EFI system and root partition", func(*bibv1alpha1.OutputSpec) {}, ""),
+			Entry("a disk size holding the partitions", func(o *bibv1alpha1.OutputSpec) {
+				o.DiskLayout.Size = size("10Gi")
+			}, ""),
+			Entry("a disk size too small for the partitions", func(o *bibv1alpha1.OutputSpec) {
+				o.DiskLayout.Size = size("513Mi")
+			}, "spec.output.diskLayout.size"),
+			Entry("a disk size too small for the partitions rounded up to MiB", func(o *bibv1alpha1.OutputSpec) {
+				// 1M is rounded up to 1Mi, so the partitions take 515Mi with the partition table.
+				boot := bibv1alpha1.DiskPartition{Size: size("1M"), Filesystem: bibv1alpha1.PartitionFilesystemExt4, MountPoint: "/boot"}
+				o.DiskLayout.Partitions = []bibv1alpha1.DiskPartition{esp, boot, root}
+				o.DiskLayout.Size = size("515Mi")
+			}, "spec.output.diskLayout.size"),
+			Entry("no root partition", func(o *bibv1alpha1.OutputSpec) {
+				o.DiskLayout.Partitions = []bibv1alpha1.DiskPartition{esp}
+			}, `spec.output.diskLayout.partitions: Required value: one partition must be mounted at "/"`),
+			Entry("a partition without a size before the last", func(o *bibv1alpha1.OutputSpec) {
+				o.DiskLayout.Partitions = []bibv1alpha1.DiskPartition{root, esp}
+			}, "spec.output.diskLayout.partitions[0].size"),
+			Entry("a duplicate mount point", func(o *bibv1alpha1.OutputSpec) {
+				o.DiskLayout.Partitions = []bibv1alpha1.DiskPartition{esp, esp, root}
+			}, "spec.output.diskLayout.partitions[1].mountPoint: Duplicate value"),
+			Entry("a relative mount point", func(o *bibv1alpha1.OutputSpec) {
+				o.DiskLayout.Partitions[0].MountPoint = "boot/efi"
+			}, "spec.output.diskLayout.partitions[0].mountPoint"),
+			Entry("a mounted swap partition", func(o *bibv1alpha1.OutputSpec) {
+				o.DiskLayout.Partitions[0].Filesystem = bibv1alpha1.PartitionFilesystemSwap
+			}, "spec.output.diskLayout.partitions[0].mountPoint: Forbidden"),
+			Entry("a vfat root partition", func(o *bibv1alpha1.OutputSpec) {
+				o.DiskLayout.Partitions[1].Filesystem = bibv1alpha1.PartitionFilesystemVFAT
+			}, "spec.output.diskLayout.partitions[1].filesystem"),
+			Entry("too many partitions for an MBR", func(o *bibv1alpha1.OutputSpec) {
+				boot := bibv1alpha1.DiskPartition{Size: size("1Gi"), Filesystem: bibv1alpha1.PartitionFilesystemExt4, MountPoint: "/boot"}
+				swap := bibv1alpha1.DiskPartition{Size: size("1Gi"), Filesystem: bibv1alpha1.PartitionFilesystemSwap}
+				varPartition := bibv1alpha1.DiskPartition{Size: size("4Gi"), Filesystem: bibv1alpha1.PartitionFilesystemXFS, MountPoint: "/var"}
+				o.DiskLayout.Table = bibv1alpha1.PartitionTableMBR
+				o.DiskLayout.Partitions = []bibv1alpha1.DiskPartition{esp, boot, swap, varPartition, root}
+			}, "spec.output.diskLayout.partitions: Too many"),
+			Entry("a partition layout", func(o *bibv1alpha1.OutputSpec) {
+				o.Partitioning = &bibv1alpha1.Partitioning{Table: bibv1alpha1.PartitionTableMBR}
+			}, "spec.output.partitioning: Forbidden"),
+			Entry("no disk image", func(o *bibv1alpha1.OutputSpec) {
+				o.Formats = []bibv1alpha1.OutputFormat{bibv1alpha1.FormatTGZ}
+			}, "spec.output.diskLayout: Forbidden"),
+		)
+
+		It("requires an EFI system partition for UEFI AMIs", func() {
+			obj.Spec.Publish.AWS.BootMode = bibv1alpha1.AWSBootModeUEFI
+			obj.Spec.Output.DiskLayout = &bibv1alpha1.DiskLayout{Partitions: []bibv1alpha1.DiskPartition{root}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.publish.aws.bootMode"))
+
+			By("admitting it with an EFI system partition")
+			obj.Spec.Output.DiskLayout.Partitions = []bibv1alpha1.DiskPartition{esp, root}
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("When creating an ImageBuild with incomplete outputs", func() {
+		DescribeTable("names the missing field",
+			func(output bibv1alpha1.OutputSpec, fields ...string) {
+				obj.Spec.Output = output
+				obj.Spec.Publish = nil
+				_, err := validator.ValidateCreate(ctx, obj)
+				Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				for _, f := range fields {
+					Expect(err.Error()).To(ContainSubstring(f))
+				}
+			},
+			Entry("no output", bibv1alpha1.OutputSpec{ImageName: "ami"},
+				"spec.output: Required value: at least one of pvc, volume, objectStorage or registry must be set"),
+			Entry("an empty registry", bibv1alpha1.OutputSpec{Registry: &bibv1alpha1.RegistryOutput{}},
+				"spec.output.registry.destination: Required value", "spec.output.registry.pullSecretName: Required value"),
+			Entry("an empty additional destination", bibv1alpha1.OutputSpec{Registry: &bibv1alpha1.RegistryOutput{
+				Destination: "quay.io/example/image:latest", PullSecretName: "quay-push",
+				AdditionalDestinations: []bibv1alpha1.RegistryDestination{{}},
+			}}, "spec.output.registry.additionalDestinations[0].destination: Required value"),
+			Entry("an empty pvc", bibv1alpha1.OutputSpec{PVC: &bibv1alpha1.PVCOutput{}},
+				"spec.output.pvc.name: Required value"),
+			Entry("an empty object storage", bibv1alpha1.OutputSpec{ObjectStorage: &bibv1alpha1.ObjectStorageOutput{}},
+				"spec.output.objectStorage.bucket: Required value", "spec.output.objectStorage.credentialsSecretName: Required value"),
+			Entry("an empty volume", bibv1alpha1.OutputSpec{Volume: &bibv1alpha1.VolumeOutput{}},
+				"spec.output.volume: Required value"),
+			Entry("an NFS export without a path", bibv1alpha1.OutputSpec{Volume: &bibv1alpha1.VolumeOutput{
+				NFS: &corev1.NFSVolumeSource{Server: "nfs.example.com"},
+			}}, "spec.output.volume.nfs.path: Required value"),
+		)
+	})
+
+	Context("When creating an ImageBuild with a registry output", func() {
+		BeforeEach(func() {
+			obj.Spec.Output.PVC = nil
+			obj.Spec.Output.Registry = &bibv1alpha1.RegistryOutput{Destination: "quay.io/example/image:latest", PullSecretName: "quay-push"}
+		})
+
+		DescribeTable("validates the file artifact settings",
+			func(mutate func(*bibv1alpha1.OutputSpec), valid bool) {
+				mutate(&obj.Spec.Output)
+				_, err := validator.ValidateCreate(ctx, obj)
+				if valid {
+					Expect(err).NotTo(HaveOccurred())
+				} else {
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				}
+			},
+			Entry("no formats", func(*bibv1alpha1.OutputSpec) {}, true),
+			Entry("a disk image format", func(o *bibv1alpha1.OutputSpec) {
+				o.Formats = []bibv1alpha1.OutputFormat{bibv1alpha1.FormatQCOW2}
+			}, false),
+			Entry("qcow2 options", func(o *bibv1alpha1.OutputSpec) {
+				o.QCOW2 = &bibv1alpha1.QCOW2Options{Compress: true}
+			}, false),
+			Entry("formats next to a pvc output", func(o *bibv1alpha1.OutputSpec) {
+				o.Formats = []bibv1alpha1.OutputFormat{bibv1alpha1.FormatQCOW2}
+				o.PVC = &bibv1alpha1.PVCOutput{Name: "artifacts"}
+			}, true),
+			Entry("a preallocated qcow2 image next to a pvc output", func(o *bibv1alpha1.OutputSpec) {
+				o.QCOW2 = &bibv1alpha1.QCOW2Options{Preallocation: bibv1alpha1.QCOW2PreallocationFull}
+				o.PVC = &bibv1alpha1.PVCOutput{Name: "artifacts"}
+			}, true),
+			Entry("a preallocation qemu-img does not support", func(o *bibv1alpha1.OutputSpec) {
+				o.QCOW2 = &bibv1alpha1.QCOW2Options{Preallocation: "sparse"}
+				o.PVC = &bibv1alpha1.PVCOutput{Name: "artifacts"}
+			}, false),
+			Entry("a preallocated compressed qcow2 image", func(o *bibv1alpha1.OutputSpec) {
+				o.QCOW2 = &bibv1alpha1.QCOW2Options{Compress: true, Preallocation: bibv1alpha1.QCOW2PreallocationMetadata}
+				o.PVC = &bibv1alpha1.PVCOutput{Name: "artifacts"}
+			}, false),
+			Entry("a compressed qcow2 image without preallocation", func(o *bibv1alpha1.OutputSpec) {
+				o.QCOW2 = &bibv1alpha1.QCOW2Options{Compress: true, Preallocation: bibv1alpha1.QCOW2PreallocationOff}
+				o.PVC = &bibv1alpha1.PVCOutput{Name: "artifacts"}
+			}, true),
+			Entry("a filesystem", func(o *bibv1alpha1.OutputSpec) {
+				o.Filesystem = bibv1alpha1.FilesystemXFS
+			}, false),
+			Entry("a filesystem next to a pvc output", func(o *bibv1alpha1.OutputSpec) {
+				o.Filesystem = bibv1alpha1.FilesystemXFS
+				o.PVC = &bibv1alpha1.PVCOutput{Name: "artifacts"}
+			}, true),
+			Entry("a filesystem without a disk image", func(o *bibv1alpha1.OutputSpec) {
+				o.Filesystem = bibv1alpha1.FilesystemXFS
+				o.Formats = []bibv1alpha1.OutputFormat{bibv1alpha1.FormatTGZ}
+				o.PVC = &bibv1alpha1.PVCOutput{Name: "artifacts"}
+			}, false),
+			Entry("an unsupported filesystem", func(o *bibv1alpha1.OutputSpec) {
+				o.Filesystem = "zfs"
+				o.PVC = &bibv1alpha1.PVCOutput{Name: "artifacts"}
+			}, false),
+			Entry("a partition layout", func(o *bibv1alpha1.OutputSpec) {
+				o.Partitioning = &bibv1alpha1.Partitioning{Table: bibv1alpha1.PartitionTableMBR}
+			}, false),
+			Entry("a partition layout next to a pvc output", func(o *bibv1alpha1.OutputSpec) {
+				o.Partitioning = &bibv1alpha1.Partitioning{Table: bibv1alpha1.PartitionTableMBR}
+				o.PVC = &bibv1alpha1.PVCOutput{Name: "artifacts"}
+			}, true),
+			Entry("a partition layout without a disk image", func(o *bibv1alpha1.OutputSpec) {
+				o.Partitioning = &bibv1alpha1.Partitioning{Table: bibv1alpha1.PartitionTableMBR}
+				o.Formats = []bibv1alpha1.OutputFormat{bibv1alpha1.FormatTGZ}
+				o.PVC = &bibv1alpha1.PVCOutput{Name: "artifacts"}
+			}, false),
+		)
+
+		It("rejects a destination pinned by digest", func() {
+			obj.Spec.Output.Registry.Destination = "quay.io/example/image@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.output.registry.destination"))
+		})
+
+		It("keeps the formats of ImageBuilds stored before the rule on update", func() {
+			obj.Spec.Output.Formats = []bibv1alpha1.OutputFormat{bibv1alpha1.FormatTGZ, bibv1alpha1.FormatQCOW2}
+			obj.Spec.Output.QCOW2 = &bibv1alpha1.QCOW2Options{Compress: true}
+			updated := obj.DeepCopy()
+			updated.Spec.BaseImage = "ubuntu:24.10"
+			_, err := validator.ValidateUpdate(ctx, obj, updated)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("rejecting changed formats")
+			updated.Spec.Output.Formats = []bibv1alpha1.OutputFormat{bibv1alpha1.FormatQCOW2}
+			_, err = validator.ValidateUpdate(ctx, obj, updated)
+			Expect(err).To(MatchError(ContainSubstring("spec.output.formats[0]")))
+			Expect(err).NotTo(MatchError(ContainSubstring("spec.output.qcow2")))
+		})
+
+		It("explains why a disk image format is rejected", func() {
+			obj.Spec.Output.Formats = []bibv1alpha1.OutputFormat{bibv1alpha1.FormatTGZ, bibv1alpha1.FormatQCOW2}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.output.formats[1]"))
+			Expect(err.Error()).To(ContainSubstring("disk images cannot be pushed to a registry"))
+		})
+
+		DescribeTable("validates repository creation",
+			func(destination, credentialsSecretName string, valid bool) {
+				obj.Spec.Output.Registry.Destination = destination
+				obj.Spec.Output.Registry.CreateRepository = true
+				obj.Spec.Output.Registry.RepositoryCredentialsSecretName = credentialsSecretName
+				_, err := validator.ValidateCreate(ctx, obj)
+				if valid {
+					Expect(err).NotTo(HaveOccurred())
+				} else {
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				}
+			},
+			Entry("an ECR repository with AWS credentials", "123456789012.dkr.ecr.us-west-2.amazonaws.com/app:1", "ecr-admin", true),
+			Entry("an ECR repository without AWS credentials", "123456789012.dkr.ecr.us-west-2.amazonaws.com/app:1", "", false),
+			Entry("an Artifact Registry repository", "us-docker.pkg.dev/my-project/images/app:1", "", true),
+			Entry("a registry without repositories to create", "quay.io/example/image:latest", "", false),
+		)
+
+		DescribeTable("validates the additional destinations",
+			func(destination string, valid bool) {
+				obj.Spec.Output.Registry.AdditionalDestinations = []bibv1alpha1.RegistryDestination{
+					{Destination: destination, PullSecretName: "ghcr-push"},
+				}
+				_, err := validator.ValidateCreate(ctx, obj)
+				if valid {
+					Expect(err).NotTo(HaveOccurred())
+				} else {
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+					Expect(err.Error()).To(ContainSubstring("spec.output.registry.additionalDestinations[0].destination"))
+				}
+			},
+			Entry("another registry", "ghcr.io/example/image:latest", true),
+			Entry("a destination pinned by digest", "ghcr.io/example/image@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", false),
+			Entry("the output's destination", "quay.io/example/image:latest", false),
+		)
+
+		DescribeTable("validates the image config",
+			func(config bibv1alpha1.ImageConfig, fieldPath string) {
+				obj.Spec.Output.Registry.Config = &config
+				_, err := validator.ValidateCreate(ctx, obj)
+				if fieldPath == "" {
+					Expect(err).NotTo(HaveOccurred())
+				} else {
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+					Expect(err.Error()).To(ContainSubstring(fieldPath))
+				}
+			},
+			Entry("a full config", bibv1alpha1.ImageConfig{
+				Entrypoint:   []string{"/usr/bin/app"},
+				Cmd:          []string{"--serve"},
+				Env:          []bibv1alpha1.ImageConfigEnvVar{{Name: "APP_MODE", Value: "production"}},
+				ExposedPorts: []string{"8080", "53/udp"},
+				Labels:       map[string]string{"org.opencontainers.image.source": "https://github.com/example/app"},
+			}, ""),
+			Entry("a variable name with '='", bibv1alpha1.ImageConfig{
+				Env: []bibv1alpha1.ImageConfigEnvVar{{Name: "A=B"}},
+			}, "spec.output.registry.config.env[0].name"),
+			Entry("a duplicate variable", bibv1alpha1.ImageConfig{
+				Env: []bibv1alpha1.ImageConfigEnvVar{{Name: "PATH"}, {Name: "PATH"}},
+			}, "spec.output.registry.config.env[1].name"),
+			Entry("a port out of range", bibv1alpha1.ImageConfig{
+				ExposedPorts: []string{"70000/tcp"},
+			}, "spec.output.registry.config.exposedPorts[0]"),
+			Entry("an unknown protocol", bibv1alpha1.ImageConfig{
+				ExposedPorts: []string{"8080/http"},
+			}, "spec.output.registry.config.exposedPorts[0]"),
+			Entry("the same port with and without protocol", bibv1alpha1.ImageConfig{
+				ExposedPorts: []string{"8080", "8080/tcp"},
+			}, "spec.output.registry.config.exposedPorts[1]"),
+			Entry("an empty label key", bibv1alpha1.ImageConfig{
+				Labels: map[string]string{"": "value"},
+			}, "spec.output.registry.config.labels"),
+		)
+
+		DescribeTable("validates the Containerfile path",
+			func(dockerfile bibv1alpha1.DockerfileSpec, valid bool) {
+				obj.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Dockerfile: &dockerfile}
+				_, err := validator.ValidateCreate(ctx, obj)
+				if valid {
+					Expect(err).NotTo(HaveOccurred())
+				} else {
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+					Expect(err.Error()).To(ContainSubstring("spec.provisioner.dockerfile.path"))
+				}
+			},
+			Entry("a path in the repository", bibv1alpha1.DockerfileSpec{Repo: "https://github.com/example/images.git", Path: "base/Containerfile"}, true),
+			Entry("a path leaving the repository", bibv1alpha1.DockerfileSpec{Repo: "https://github.com/example/images.git", Path: "../Containerfile"}, false),
+			Entry("a ConfigMap key", bibv1alpha1.DockerfileSpec{ConfigMapRef: &corev1.LocalObjectReference{Name: "hardening"}, Path: "Dockerfile"}, true),
+			Entry("a ConfigMap path", bibv1alpha1.DockerfileSpec{ConfigMapRef: &corev1.LocalObjectReference{Name: "hardening"}, Path: "base/Dockerfile"}, false),
+		)
+
+		DescribeTable("validates the kickstart path",
+			func(kickstart bibv1alpha1.KickstartSpec, valid bool) {
+				obj.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Kickstart: &kickstart}
+				_, err := validator.ValidateCreate(ctx, obj)
+				if valid {
+					Expect(err).NotTo(HaveOccurred())
+				} else {
+					Expect(apierrors.IsInvalid(err)).To(BeTrue())
+					Expect(err.Error()).To(ContainSubstring("spec.provisioner.kickstart.path"))
+				}
+			},
+			Entry("a path in the repository", bibv1alpha1.KickstartSpec{Repo: "https://github.com/example/kickstarts.git", Path: "rhel9/server.ks"}, true),
+			Entry("a path leaving the repository", bibv1alpha1.KickstartSpec{Repo: "https://github.com/example/kickstarts.git", Path: "../server.ks"}, false),
+			Entry("a ConfigMap key", bibv1alpha1.KickstartSpec{ConfigMapRef: &corev1.LocalObjectReference{Name: "rhel-ks"}, Path: "server.ks"}, true),
+			Entry("a path with an inline kickstart", bibv1alpha1.KickstartSpec{Inline: "lang en_US.UTF-8\n", Path: "server.ks"}, false),
+		)
+
+		It("leaves the disk layout of bootc images to bootc", func() {
+			obj.Spec.Output.PVC = &bibv1alpha1.PVCOutput{Name: "artifacts"}
+			obj.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{Bootc: &bibv1alpha1.BootcSpec{KernelArgs: []string{"console=ttyS0", ""}}}
+			obj.Spec.Output.Partitioning = &bibv1alpha1.Partitioning{Table: bibv1alpha1.PartitionTableMBR}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.output.partitioning"))
+			Expect(err.Error()).To(ContainSubstring("spec.provisioner.bootc.kernelArgs[1]"))
+
+			By("admitting it with the filesystem of the root partition")
+			obj.Spec.Provisioner.Bootc.KernelArgs = []string{"console=ttyS0"}
+			obj.Spec.Output.Partitioning = nil
+			obj.Spec.Output.Filesystem = bibv1alpha1.FilesystemXFS
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("rejects empty post steps", func() {
+			obj.Spec.Provisioner = &bibv1alpha1.ProvisionerSpec{PostSteps: []string{"truncate -s 0 /etc/machine-id", "  "}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.provisioner.postSteps[1]"))
+
+			By("admitting commands")
+			obj.Spec.Provisioner.PostSteps = obj.Spec.Provisioner.PostSteps[:1]
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("accepts keyless signing of the pushed image", func() {
+			obj.Spec.Signing = &bibv1alpha1.SigningSpec{Keyless: &bibv1alpha1.KeylessSigning{}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("rejecting it without a registry output")
+			obj.Spec.Output.Registry = nil
+			obj.Spec.Output.PVC = &bibv1alpha1.PVCOutput{Name: "artifacts"}
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.signing"))
+		})
+
+		It("rejects repository credentials without repository creation", func() {
+			obj.Spec.Output.Registry.RepositoryCredentialsSecretName = "ecr-admin"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.output.registry.repositoryCredentialsSecretName"))
+		})
+	})
+
+	Context("When creating an ImageBuild in a user namespace", func() {
+		It("rejects combining it with host networking", func() {
+			hostUsers := false
+			obj.Spec.Build = &bibv1alpha1.BuildSpec{HostUsers: &hostUsers}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+
+			obj.Spec.Build.HostNetwork = true
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.build.hostUsers"))
+		})
+	})
+
+	Context("When updating an ImageBuild under the validating webhook", func() {
+		It("rejects a spec change that introduces a mismatch", func() {
+			newObj := obj.DeepCopy()
+			newObj.Spec.Architecture = "arm64"
+			_, err := validator.ValidateUpdate(ctx, obj, newObj)
+			Expect(err).NotTo(HaveOccurred())
+
+			newObj.Spec.Publish.AWS.Architecture = "x86_64"
+			_, err = validator.ValidateUpdate(ctx, obj, newObj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		})
+
+		It("admits metadata-only updates of an already invalid object", func() {
+			obj.Spec.Publish.AWS.Architecture = "arm64"
+			newObj := obj.DeepCopy()
+			newObj.Finalizers = nil
+			_, err := validator.ValidateUpdate(ctx, obj, newObj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,177 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package imageref parses and normalizes container image references, such as base images and
+// registry output destinations.
+package imageref
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/distribution/reference"
+)
+
+var (
+	// ErrEmpty is returned for an empty reference.
+	ErrEmpty = errors.New("reference is empty")
+	// ErrDigestNotAllowed is returned for a push destination that has a digest, which is
+	// computed by the registry rather than chosen by the client.
+	ErrDigestNotAllowed = errors.New("digest is not allowed in a push destination")
+)
+
+// Error reports an image reference that cannot be used. Err is ErrEmpty, ErrDigestNotAllowed, or
+// one of the errors of github.com/distribution/reference, e.g. reference.ErrReferenceInvalidFormat.
+type Error struct {
+	Reference string
+	Err       error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid image reference %q: %v", e.Reference, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Reference is a normalized image reference. Short names are expanded the way container
+// runtimes resolve them, so "ubuntu:24.04" becomes "docker.io/library/ubuntu:24.04".
+type Reference struct {
+	// Domain is the registry host, e.g. "docker.io".
+	Domain string
+	// Path is the repository within the registry, e.g. "library/ubuntu".
+	Path string
+	// Tag is the tag, if any.
+	Tag string
+	// Digest is the content digest, e.g. "sha256:...", if any.
+	Digest string
+}
+
+// Parse parses and normalizes an image reference to pull. A reference with neither tag nor digest
+// is given the "latest" tag, as the container runtime would pull it.
+func Parse(ref string) (Reference, error) {
+	if strings.TrimSpace(ref) == "" {
+		return Reference{}, &Error{Reference: ref, Err: ErrEmpty}
+	}
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return Reference{}, &Error{Reference: ref, Err: err}
+	}
+	named = reference.TagNameOnly(named)
+
+	r := Reference{Domain: reference.Domain(named), Path: reference.Path(named)}
+	if tagged, ok := named.(reference.Tagged); ok {
+		r.Tag = tagged.Tag()
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		r.Digest = digested.Digest().String()
+	}
+	return r, nil
+}
+
+// ParseDestination parses and normalizes an image reference to push to. Unlike Parse it rejects
+// digests, which the registry assigns to the pushed image.
+func ParseDestination(ref string) (Reference, error) {
+	r, err := Parse(ref)
+	if err != nil {
+		return Reference{}, err
+	}
+	if r.Digest != "" {
+		return Reference{}, &Error{Reference: ref, Err: ErrDigestNotAllowed}
+	}
+	return r, nil
+}
+
+// Name returns the repository name including the registry, e.g. "docker.io/library/ubuntu".
+func (r Reference) Name() string {
+	return r.Domain + "/" + r.Path
+}
+
+// Pinned returns true if the reference identifies the image by digest, so it always resolves
+// to the same content.
+func (r Reference) Pinned() bool {
+	return r.Digest != ""
+}
+
+// WithDomain returns the reference served from another registry, or repository prefix within
+// a registry, e.g. a pull-through cache "harbor.example.com/dockerhub".
+func (r Reference) WithDomain(domain string) Reference {
+	r.Domain = strings.TrimSuffix(domain, "/")
+	return r
+}
+
+// String returns the normalized reference, e.g. "docker.io/library/ubuntu:24.04" or
+// "quay.io/org/image@sha256:...". A tag is kept next to the digest for readability.
+func (r Reference) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// RepositoryProvider is a cloud registry whose repositories must exist before images are pushed to them.
+type RepositoryProvider string
+
+const (
+	// ProviderECR is Amazon Elastic Container Registry.
+	ProviderECR RepositoryProvider = "ecr"
+	// ProviderGAR is Google Artifact Registry.
+	ProviderGAR RepositoryProvider = "gar"
+)
+
+var (
+	// ecrDomainRegexp matches "<account>.dkr.ecr[-fips].<region>.amazonaws.com[.cn]".
+	ecrDomainRegexp = regexp.MustCompile(`^([0-9]{12})\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+	// garDomainRegexp matches "<location>-docker.pkg.dev".
+	garDomainRegexp = regexp.MustCompile(`^([a-z0-9-]+)-docker\.pkg\.dev$`)
+)
+
+// CloudRepository identifies the repository of an image in a cloud registry.
+type CloudRepository struct {
+	Provider RepositoryProvider
+	// Location is the AWS region of an ECR registry, or the location of an Artifact Registry repository.
+	Location string
+	// Owner is the AWS account ID of an ECR registry, or the Google Cloud project of an Artifact
+	// Registry repository.
+	Owner string
+	// Repository is the name of the repository: the whole path for ECR, e.g. "team/app", and the
+	// first path component after the project for Artifact Registry, which holds the images.
+	Repository string
+}
+
+// CloudRepository returns the cloud registry repository the reference belongs to, or false if the
+// registry is not one whose repositories must be created.
+func (r Reference) CloudRepository() (CloudRepository, bool) {
+	if m := ecrDomainRegexp.FindStringSubmatch(r.Domain); m != nil {
+		return CloudRepository{Provider: ProviderECR, Location: m[2], Owner: m[1], Repository: r.Path}, true
+	}
+	if m := garDomainRegexp.FindStringSubmatch(r.Domain); m != nil {
+		// <project>/<repository>/<image>
+		parts := strings.SplitN(r.Path, "/", 3)
+		if len(parts) < 3 {
+			return CloudRepository{}, false
+		}
+		return CloudRepository{Provider: ProviderGAR, Location: m[1], Owner: parts[0], Repository: parts[1]}, true
+	}
+	return CloudRepository{}, false
+}
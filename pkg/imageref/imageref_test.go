@@ -0,0 +1,124 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imageref
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/distribution/reference"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+var _ = Describe("Parse", func() {
+	DescribeTable("normalizes valid references",
+		func(ref, normalized, tag, digestValue string) {
+			r, err := Parse(ref)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(r.String()).To(Equal(normalized))
+			Expect(r.Tag).To(Equal(tag))
+			Expect(r.Digest).To(Equal(digestValue))
+			Expect(r.Pinned()).To(Equal(digestValue != ""))
+		},
+		Entry("an official image", "ubuntu", "docker.io/library/ubuntu:latest", "latest", ""),
+		Entry("an official image with a tag", "ubuntu:24.04", "docker.io/library/ubuntu:24.04", "24.04", ""),
+		Entry("a Docker Hub user image", "myorg/base:1.0", "docker.io/myorg/base:1.0", "1.0", ""),
+		Entry("another registry", "quay.io/org/image:v2", "quay.io/org/image:v2", "v2", ""),
+		Entry("a registry with a port", "registry.local:5000/image", "registry.local:5000/image:latest", "latest", ""),
+		Entry("a localhost registry", "localhost/image:dev", "localhost/image:dev", "dev", ""),
+		Entry("a digest", "quay.io/org/image@"+digest, "quay.io/org/image@"+digest, "", digest),
+		Entry("a tag and a digest", "ubuntu:24.04@"+digest, "docker.io/library/ubuntu:24.04@"+digest, "24.04", digest),
+		Entry("a nested repository", "ghcr.io/org/team/image:1", "ghcr.io/org/team/image:1", "1", ""),
+	)
+
+	DescribeTable("rejects invalid references with a typed error",
+		func(ref string, cause error) {
+			_, err := Parse(ref)
+			var refErr *Error
+			Expect(errors.As(err, &refErr)).To(BeTrue())
+			Expect(refErr.Reference).To(Equal(ref))
+			Expect(errors.Is(err, cause)).To(BeTrue(), err.Error())
+		},
+		Entry("an empty reference", "", ErrEmpty),
+		Entry("whitespace", "  ", ErrEmpty),
+		Entry("an empty tag", "ubuntu:", reference.ErrReferenceInvalidFormat),
+		Entry("a malformed digest", "ubuntu@sha256:abc", reference.ErrReferenceInvalidFormat),
+		Entry("a name that is too long", "org/"+strings.Repeat("a", 256), reference.ErrNameTooLong),
+		Entry("a URL scheme", "docker://ubuntu:24.04", reference.ErrReferenceInvalidFormat),
+	)
+
+	It("explains why an upper case repository is rejected", func() {
+		_, err := Parse("Ubuntu:24.04")
+		var refErr *Error
+		Expect(errors.As(err, &refErr)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("must be lowercase"))
+	})
+})
+
+var _ = Describe("ParseDestination", func() {
+	It("accepts a tagged destination", func() {
+		r, err := ParseDestination("quay.io/org/image:latest")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Name()).To(Equal("quay.io/org/image"))
+	})
+
+	It("rejects a digest", func() {
+		_, err := ParseDestination("quay.io/org/image@" + digest)
+		Expect(errors.Is(err, ErrDigestNotAllowed)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Reference", func() {
+	It("moves the image to another registry prefix", func() {
+		r, err := Parse("ubuntu:24.04")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.WithDomain("harbor.example.com/dockerhub/").String()).To(Equal("harbor.example.com/dockerhub/library/ubuntu:24.04"))
+	})
+})
+
+var _ = Describe("CloudRepository", func() {
+	DescribeTable("detects the repository of cloud registries",
+		func(ref string, expected CloudRepository) {
+			r, err := Parse(ref)
+			Expect(err).NotTo(HaveOccurred())
+			repo, ok := r.CloudRepository()
+			Expect(ok).To(BeTrue())
+			Expect(repo).To(Equal(expected))
+		},
+		Entry("an ECR repository", "123456789012.dkr.ecr.us-west-2.amazonaws.com/team/app:1",
+			CloudRepository{Provider: ProviderECR, Location: "us-west-2", Owner: "123456789012", Repository: "team/app"}),
+		Entry("an ECR FIPS repository", "123456789012.dkr.ecr-fips.us-east-1.amazonaws.com/app:1",
+			CloudRepository{Provider: ProviderECR, Location: "us-east-1", Owner: "123456789012", Repository: "app"}),
+		Entry("an Artifact Registry repository", "europe-west1-docker.pkg.dev/my-project/images/app/base:1",
+			CloudRepository{Provider: ProviderGAR, Location: "europe-west1", Owner: "my-project", Repository: "images"}),
+	)
+
+	DescribeTable("ignores other registries",
+		func(ref string) {
+			r, err := Parse(ref)
+			Expect(err).NotTo(HaveOccurred())
+			_, ok := r.CloudRepository()
+			Expect(ok).To(BeFalse())
+		},
+		Entry("Docker Hub", "ubuntu:24.04"),
+		Entry("Quay", "quay.io/org/image:1"),
+		Entry("an Artifact Registry path without an image", "us-docker.pkg.dev/my-project/images:1"),
+	)
+})